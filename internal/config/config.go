@@ -1,21 +1,193 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// defaultJWTSecret is only acceptable in development; see Validate.
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
+// placeholderJWTSecrets are the values shipped in code and .env.example.
+var placeholderJWTSecrets = []string{defaultJWTSecret, "your-super-secret-jwt-key-change-in-production"}
+
 type Config struct {
+	// Environment is "production" unless APP_ENV says otherwise, so a
+	// deployment that forgets it still gets the production checks.
+	Environment string
 	Port        string
 	DatabaseURL string
-	JWTSecret   string
+	// AutoMigrate applies pending migrations at startup. It defaults to
+	// on in development only; elsewhere run "migrate up" when deploying.
+	AutoMigrate bool
+	// JWTSecret is the master secret other secrets default to.
+	JWTSecret string
+
+	// Tokens are signed with JWTAlgorithm (EdDSA or RS256) using keys that
+	// rotate every JWTKeyRotation. Instances reload the key set every
+	// JWTKeyRefreshInterval. Private keys are encrypted with
+	// JWTKeyEncryptionKey, which defaults to JWTSecret.
+	JWTAlgorithm          string
+	JWTKeyRotation        time.Duration
+	JWTKeyRefreshInterval time.Duration
+	JWTKeyEncryptionKey   string
+
+	// MedicalRecordLockWindow is how long after the visit a medical record
+	// can still be amended; 0 disables time-based locking.
+	MedicalRecordLockWindow time.Duration
+	// FollowUpInterval is how often the follow-up scheduler runs; 0
+	// disables it. FollowUpLookaheadDays is how far ahead it books.
+	FollowUpInterval      time.Duration
+	FollowUpLookaheadDays int
+
+	// Notification delivery. Outside development both SMTPHost and
+	// SMSGatewayURL are required; in development a channel without one is
+	// written to stdout, or to NotificationLogFile when set.
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	SMSGatewayURL           string
+	SMSGatewayAPIKey        string
+	SMSSender               string
+	NotificationLogFile     string
+	NotificationInterval    time.Duration
+	NotificationMaxAttempts int
+
+	// ReminderInterval is how often appointment reminders are checked; 0
+	// disables them. ReminderOffsets are how long before the visit they go
+	// out.
+	ReminderInterval time.Duration
+	ReminderOffsets  []time.Duration
+	// PublicBaseURL is where users reach this API; links in messages are
+	// built from it and signed with LinkSigningSecret.
+	PublicBaseURL     string
+	LinkSigningSecret string
+	// AppBaseURL is the web app, which hosts pages such as password reset.
+	AppBaseURL string
+
+	// PasswordResetMaxAttempts caps wrong guesses of one reset code.
+	// PasswordResetIPLimit requests per PasswordResetIPWindow are allowed
+	// from one IP on each password reset endpoint.
+	PasswordResetMaxAttempts int
+	PasswordResetIPLimit     int
+	PasswordResetIPWindow    time.Duration
+
+	// Login brute-force protection; see handlers.LoginSecuritySettings.
+	LoginIPMaxFailures      int
+	LoginIPWindow           time.Duration
+	LoginDelayAfterFailures int
+	LoginLockAfterFailures  int
+	LoginLockDuration       time.Duration
+
+	// TwoFactorRequiredRoles must use TOTP 2FA. TOTP secrets are encrypted
+	// with TwoFactorEncryptionKey, which defaults to JWTSecret.
+	TwoFactorRequiredRoles []string
+	TwoFactorIssuer        string
+	TwoFactorEncryptionKey string
+
+	// SessionLifetime is how long a session lasts without a token refresh.
+	// Session checks are cached for SessionCacheTTL, which bounds how long
+	// a revocation takes to reach other instances.
+	SessionLifetime time.Duration
+	SessionCacheTTL time.Duration
+
+	// StaffInvitationValidity is how long a staff invitation link works.
+	StaffInvitationValidity time.Duration
 }
 
 func Load() *Config {
-	return &Config{
-		Port:        getEnv("PORT", "8080"),
-		DatabaseURL: getEnv("DATABASE_URL", "sqlserver://localhost?database=ClinicManagement&trusted_connection=yes"),
-		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+	cfg := &Config{
+		Environment:              getEnv("APP_ENV", "production"),
+		Port:                     getEnv("PORT", "8080"),
+		DatabaseURL:              getEnv("DATABASE_URL", "sqlserver://localhost?database=ClinicManagement&trusted_connection=yes"),
+		JWTSecret:                getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTAlgorithm:             getEnv("JWT_ALGORITHM", "EdDSA"),
+		JWTKeyRotation:           time.Duration(getEnvInt("JWT_KEY_ROTATION_DAYS", 30)) * 24 * time.Hour,
+		JWTKeyRefreshInterval:    time.Duration(getEnvInt("JWT_KEY_REFRESH_MINUTES", 10)) * time.Minute,
+		JWTKeyEncryptionKey:      getEnv("JWT_KEY_ENCRYPTION_KEY", ""),
+		MedicalRecordLockWindow:  time.Duration(getEnvInt("MEDICAL_RECORD_LOCK_HOURS", 24)) * time.Hour,
+		FollowUpInterval:         time.Duration(getEnvInt("FOLLOW_UP_INTERVAL_MINUTES", 60)) * time.Minute,
+		FollowUpLookaheadDays:    getEnvInt("FOLLOW_UP_LOOKAHEAD_DAYS", 7),
+		SMTPHost:                 getEnv("SMTP_HOST", ""),
+		SMTPPort:                 getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:             getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:             getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                 getEnv("SMTP_FROM", "no-reply@clinic.local"),
+		SMSGatewayURL:            getEnv("SMS_GATEWAY_URL", ""),
+		SMSGatewayAPIKey:         getEnv("SMS_GATEWAY_API_KEY", ""),
+		SMSSender:                getEnv("SMS_SENDER", "CLINIC"),
+		NotificationLogFile:      getEnv("NOTIFICATION_LOG_FILE", ""),
+		NotificationInterval:     time.Duration(getEnvInt("NOTIFICATION_INTERVAL_SECONDS", 30)) * time.Second,
+		NotificationMaxAttempts:  getEnvInt("NOTIFICATION_MAX_ATTEMPTS", 5),
+		ReminderInterval:         time.Duration(getEnvInt("REMINDER_INTERVAL_MINUTES", 5)) * time.Minute,
+		ReminderOffsets:          getEnvDurations("REMINDER_OFFSETS", []time.Duration{24 * time.Hour, 2 * time.Hour}),
+		PublicBaseURL:            getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+		LinkSigningSecret:        getEnv("LINK_SIGNING_SECRET", ""),
+		AppBaseURL:               getEnv("APP_BASE_URL", "http://localhost:3000"),
+		PasswordResetMaxAttempts: getEnvInt("PASSWORD_RESET_MAX_ATTEMPTS", 5),
+		PasswordResetIPLimit:     getEnvInt("PASSWORD_RESET_IP_LIMIT", 10),
+		PasswordResetIPWindow:    time.Duration(getEnvInt("PASSWORD_RESET_IP_WINDOW_MINUTES", 15)) * time.Minute,
+		LoginIPMaxFailures:       getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginIPWindow:            time.Duration(getEnvInt("LOGIN_IP_WINDOW_MINUTES", 15)) * time.Minute,
+		LoginDelayAfterFailures:  getEnvInt("LOGIN_DELAY_AFTER_FAILURES", 3),
+		LoginLockAfterFailures:   getEnvInt("LOGIN_LOCK_AFTER_FAILURES", 10),
+		LoginLockDuration:        time.Duration(getEnvInt("LOGIN_LOCK_MINUTES", 15)) * time.Minute,
+		TwoFactorRequiredRoles:   getEnvList("TWO_FACTOR_REQUIRED_ROLES", []string{"DOCTOR", "ACCOUNTANT", "CLINIC_MANAGER", "OPERATION_MANAGER"}),
+		TwoFactorIssuer:          getEnv("TWO_FACTOR_ISSUER", "Clinic Management"),
+		TwoFactorEncryptionKey:   getEnv("TWO_FACTOR_ENCRYPTION_KEY", ""),
+		SessionLifetime:          time.Duration(getEnvInt("SESSION_LIFETIME_HOURS", 168)) * time.Hour,
+		SessionCacheTTL:          time.Duration(getEnvInt("SESSION_CACHE_SECONDS", 30)) * time.Second,
+		StaffInvitationValidity:  time.Duration(getEnvInt("STAFF_INVITATION_HOURS", 72)) * time.Hour,
+	}
+	cfg.AutoMigrate = getEnv("DB_AUTO_MIGRATE", strconv.FormatBool(cfg.IsDevelopment())) == "true"
+	if cfg.LinkSigningSecret == "" {
+		cfg.LinkSigningSecret = cfg.JWTSecret
+	}
+	if cfg.TwoFactorEncryptionKey == "" {
+		cfg.TwoFactorEncryptionKey = cfg.JWTSecret
 	}
+	if cfg.JWTKeyEncryptionKey == "" {
+		cfg.JWTKeyEncryptionKey = cfg.JWTSecret
+	}
+	return cfg
+}
+
+// IsDevelopment reports whether the server runs in development mode.
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == "development"
+}
+
+// Validate rejects settings that are only safe in development. Secrets
+// that default to JWTSecret make the default secret unsafe everywhere
+// else, and messages carry reset codes and signed links, so they must not
+// be written to the console or a log file.
+func (c *Config) Validate() error {
+	if c.JWTKeyRotation <= 0 {
+		return errors.New("JWT_KEY_ROTATION_DAYS must be positive")
+	}
+	if c.IsDevelopment() {
+		return nil
+	}
+	for _, placeholder := range placeholderJWTSecrets {
+		if c.JWTSecret == placeholder {
+			return errors.New("JWT_SECRET is still the default; set a random value or run with APP_ENV=development")
+		}
+	}
+	if len(c.JWTSecret) < 32 {
+		return errors.New("JWT_SECRET must be at least 32 characters when APP_ENV is not development")
+	}
+	if c.SMTPHost == "" {
+		return errors.New("SMTP_HOST is required when APP_ENV is not development")
+	}
+	if c.SMSGatewayURL == "" {
+		return errors.New("SMS_GATEWAY_URL is required when APP_ENV is not development")
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -24,3 +196,44 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDurations parses a comma-separated list such as "24h,2h,30m".
+// Invalid entries are skipped.
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		if d, err := time.ParseDuration(strings.TrimSpace(part)); err == nil {
+			durations = append(durations, d)
+		}
+	}
+	return durations
+}
+
+// getEnvList parses a comma-separated list. Set the variable to "-" for an
+// empty list.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	list := []string{}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" && part != "-" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Dialect is the SQL flavour of the database behind a *sql.DB. Queries in
+// this code base are written in T-SQL for SQL Server, which runs them
+// unchanged; other dialects translate each query before it reaches their
+// driver, so handlers and repositories work on any of them.
+type Dialect interface {
+	// Name identifies the dialect; its migrations live in
+	// migrations/<name>.
+	Name() string
+	// Rewrite translates a T-SQL query into the dialect, or returns an
+	// error naming the construct it cannot translate.
+	Rewrite(query string) (string, error)
+}
+
+// SQLServer is the production dialect.
+var SQLServer Dialect = sqlServerDialect{}
+
+// SQLite is the embedded dialect used for tests and local development.
+var SQLite Dialect = sqliteDialect{}
+
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) Name() string { return "sqlserver" }
+
+func (sqlServerDialect) Rewrite(query string) (string, error) { return query, nil }
+
+// DialectOf returns the dialect of a database opened by Open or Connect.
+func DialectOf(db *sql.DB) Dialect {
+	if d, ok := db.Driver().(*rewriteDriver); ok {
+		return d.dialect
+	}
+	return SQLServer
+}
+
+// Open opens the database named by a URL without connecting to it. A URL
+// starting with sqlite: names an SQLite file, for example
+// sqlite:clinic.db; anything else is a SQL Server URL.
+func Open(databaseURL string) (*sql.DB, error) {
+	if path, ok := strings.CutPrefix(databaseURL, "sqlite:"); ok {
+		return openSQLite(path)
+	}
+	return sql.Open("sqlserver", databaseURL)
+}
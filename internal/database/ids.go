@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"clinic-management/internal/utils"
+)
+
+type idColumn struct {
+	table, column string
+}
+
+// idColumns names where the IDs of each generated prefix are stored.
+var idColumns = map[string]idColumn{
+	"CUS": {"[USER]", "userID"},
+	"DOC": {"[USER]", "userID"},
+	"REC": {"[USER]", "userID"},
+	"ACC": {"[USER]", "userID"},
+	"CLM": {"[USER]", "userID"},
+	"OPM": {"[USER]", "userID"},
+	"USR": {"[USER]", "userID"},
+	"PK":  {"PHONGKHAM", "maPhongKham"},
+	"PH":  {"PHONG", "maPhong"},
+	"TB":  {"THIETBI", "maThietBi"},
+	"LK":  {"LICHKHAM", "maLichKham"},
+	"HS":  {"HOSO", "maHoSo"},
+	"BS":  {"HOSO_BOSUNG", "maBoSung"},
+	"DU":  {"DIUNG", "maDiUng"},
+	"BM":  {"BENHMANTINH", "maBenhManTinh"},
+	"TK":  {"TAIKHAM", "maTaiKham"},
+	"DT":  {"DONTHUOC", "maDonThuoc"},
+	"MDT": {"MAUDONTHUOC", "maMau"},
+	"XN":  {"XETNGHIEM", "maXetNghiem"},
+	"MED": {"THUOC", "maThuoc"},
+	"NCC": {"NHACUNGCAP", "maNhaCungCap"},
+	"PN":  {"PHIEUNHAP", "maPhieuNhap"},
+	"LLV": {"LICHLAMVIEC", "maLichLamViec"},
+	"PWR": {"PASSWORD_RESET", "ID"},
+}
+
+// InitIDs starts the ID counters past the IDs already stored, so a restart
+// does not hand out an existing key. On SQL Server the numbers are then
+// taken from SOTHUTU, shared by every instance using the database. An
+// SQLite database serves one process, whose counters are enough; taking
+// them from the database would wait on the write lock the caller's
+// transaction holds.
+func InitIDs(db *sql.DB) error {
+	utils.InitializeCounters()
+	for prefix, c := range idColumns {
+		n, err := maxStoredID(db, prefix, c)
+		if err != nil {
+			return fmt.Errorf("error reading %s IDs: %v", prefix, err)
+		}
+		utils.RaiseIDCounter(prefix, n)
+	}
+
+	if DialectOf(db) != SQLServer {
+		return nil
+	}
+	seq := &idSequence{db: db}
+	for prefix, n := range utils.IDCounters() {
+		if err := seq.raise(prefix, n); err != nil {
+			return fmt.Errorf("error starting %s IDs: %v", prefix, err)
+		}
+	}
+	utils.SetIDSource(seq.next)
+	return nil
+}
+
+// maxStoredID returns the highest number stored after prefix, 0 if none.
+// IDs not made of prefix and a number, e.g. imported with another scheme,
+// are skipped.
+func maxStoredID(db *sql.DB, prefix string, c idColumn) (int, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT %[2]s FROM %[1]s
+		WHERE %[2]s LIKE @p1
+		ORDER BY LEN(%[2]s) DESC, %[2]s DESC
+	`, c.table, c.column), prefix+"%")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(id[len(prefix):]); err == nil && n >= 0 {
+			return n, nil
+		}
+	}
+	return 0, rows.Err()
+}
+
+// idSequence hands out ID numbers from SOTHUTU.
+type idSequence struct {
+	db *sql.DB
+}
+
+func (s *idSequence) next(prefix string) (int, error) {
+	var n int
+	err := s.db.QueryRow(`
+		UPDATE SOTHUTU SET giaTri = giaTri + 1
+		OUTPUT inserted.giaTri
+		WHERE tienTo = @p1
+	`, prefix).Scan(&n)
+	if err == sql.ErrNoRows {
+		// A prefix without a row yet starts from its counter.
+		if err = s.raise(prefix, utils.IDCounters()[prefix]); err != nil {
+			return 0, err
+		}
+		return s.next(prefix)
+	}
+	return n, err
+}
+
+// raise makes the next number of prefix greater than n.
+func (s *idSequence) raise(prefix string, n int) error {
+	_, err := s.db.Exec(`
+		MERGE SOTHUTU WITH (HOLDLOCK) AS t
+		USING (SELECT @p1 AS tienTo) AS s ON t.tienTo = s.tienTo
+		WHEN MATCHED AND t.giaTri < @p2 THEN
+			UPDATE SET giaTri = @p2
+		WHEN NOT MATCHED THEN
+			INSERT (tienTo, giaTri) VALUES (@p1, @p2);
+	`, prefix, n)
+	return err
+}
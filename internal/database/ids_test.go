@@ -0,0 +1,49 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"clinic-management/internal/utils"
+)
+
+func TestInitIDs(t *testing.T) {
+	db, err := Open("sqlite:" + filepath.Join(t.TempDir(), "clinic.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// IDs stored by an earlier run, one past the starting counter.
+	for _, id := range []string{"BS000002", "BS000011", "BS-IMPORTED"} {
+		if _, err := db.Exec(`
+			INSERT INTO HOSO_BOSUNG (maBoSung, maHoSo, noiDung, nguoiTao, thoiGian)
+			VALUES (@p1, 'HS000001', N'Bổ sung', 'DOC001', GETDATE())
+		`, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`
+		INSERT INTO PASSWORD_RESET (ID, UserID, Email, ResetCode, IsUsed, ExpiresAt, CreatedAt)
+		VALUES ('PWR000042', 'CUS000001', 'a@b.c', 'x', 0, GETDATE(), GETDATE())
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InitIDs(db); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := utils.GenerateAddendumID(); err != nil || got != "BS000012" {
+		t.Errorf("addendum ID = %s, %v, want BS000012", got, err)
+	}
+	if got, err := utils.GeneratePasswordResetID(); err != nil || got != "PWR000043" {
+		t.Errorf("password reset ID = %s, %v, want PWR000043", got, err)
+	}
+	// Stored IDs below the starting counter leave it alone.
+	if got, err := utils.GenerateAppointmentID(); err != nil || got != "LK040001" {
+		t.Errorf("appointment ID = %s, %v, want LK040001", got, err)
+	}
+}
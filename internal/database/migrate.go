@@ -0,0 +1,286 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are the files migrations/<dialect>/NNNN_name.up.sql and
+// NNNN_name.down.sql, numbered from 1 without gaps; every dialect has the
+// same versions. A file holds batches separated by lines reading GO. The
+// SQL Server migrations that predate versioning are idempotent, so the
+// first migrate up adopts a database created before it.
+//
+//go:embed migrations/sqlserver/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	// Checksum is the SHA-256 of the up file; an applied migration whose
+	// file changed afterwards fails verification.
+	Checksum string
+	Up       []string
+	Down     []string
+}
+
+// MigrationStatus is a migration and when it was applied, nil if pending.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	// Modified is set when the applied checksum differs from the file.
+	Modified bool
+}
+
+// Migrations returns the embedded migrations of a dialect in version order.
+func Migrations(dialect Dialect) ([]Migration, error) {
+	dir := "migrations/" + dialect.Name()
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_name.up.sql", entry.Name())
+		}
+		content, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+			m.Up = splitBatches(string(content))
+		} else {
+			m.Down = splitBatches(string(content))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+// splitBatches splits a file on GO lines and drops batches that are only
+// comments.
+func splitBatches(content string) []string {
+	var batches []string
+	var current []string
+	flush := func() {
+		batch := strings.TrimSpace(strings.Join(current, "\n"))
+		current = nil
+		for _, line := range strings.Split(batch, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+				batches = append(batches, batch)
+				return
+			}
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "GO") {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return batches
+}
+
+const migrationTable = `IF OBJECT_ID(N'SCHEMA_MIGRATIONS', N'U') IS NULL
+CREATE TABLE SCHEMA_MIGRATIONS (
+	version   INT          NOT NULL PRIMARY KEY,
+	name      VARCHAR(100) NOT NULL,
+	checksum  CHAR(64)     NOT NULL,
+	appliedAt DATETIME     NOT NULL
+)`
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func appliedMigrations(db *sql.DB) (map[int]appliedMigration, error) {
+	if _, err := db.Exec(migrationTable); err != nil {
+		return nil, fmt.Errorf("error creating SCHEMA_MIGRATIONS: %v", err)
+	}
+	rows, err := db.Query("SELECT version, checksum, appliedAt FROM SCHEMA_MIGRATIONS")
+	if err != nil {
+		return nil, fmt.Errorf("error reading SCHEMA_MIGRATIONS: %v", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatuses lists every embedded migration with its state.
+func MigrationStatuses(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations(DialectOf(db))
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i].Migration = m
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.appliedAt
+			statuses[i].AppliedAt = &appliedAt
+			statuses[i].Modified = strings.TrimSpace(a.checksum) != m.Checksum
+		}
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies the pending migrations in order, each in its own
+// transaction, and returns those it applied.
+func MigrateUp(db *sql.DB) ([]Migration, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			continue
+		}
+		err := runMigration(db, s.Migration, s.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				INSERT INTO SCHEMA_MIGRATIONS (version, name, checksum, appliedAt)
+				VALUES (@p1, @p2, @p3, GETDATE())
+			`, s.Version, s.Name, s.Checksum)
+			return err
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and
+// returns those it reverted.
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		s := statuses[i]
+		if s.AppliedAt == nil {
+			continue
+		}
+		err := runMigration(db, s.Migration, s.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec("DELETE FROM SCHEMA_MIGRATIONS WHERE version = @p1", s.Version)
+			return err
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+func runMigration(db *sql.DB, m Migration, batches []string, record func(*sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, batch := range batches {
+		if _, err := tx.Exec(batch); err != nil {
+			return fmt.Errorf("migration %04d_%s, batch %d: %v", m.Version, m.Name, i+1, err)
+		}
+	}
+	if err := record(tx); err != nil {
+		return fmt.Errorf("migration %04d_%s: %v", m.Version, m.Name, err)
+	}
+	return tx.Commit()
+}
+
+// VerifySchema checks that the database is at the version this binary
+// expects: every embedded migration applied unchanged and none unknown.
+func VerifySchema(db *sql.DB) error {
+	statuses, err := MigrationStatuses(db)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, s := range statuses {
+		name := fmt.Sprintf("%04d_%s", s.Version, s.Name)
+		switch {
+		case s.AppliedAt == nil:
+			pending = append(pending, name)
+		case s.Modified:
+			return fmt.Errorf("migration %s was changed after it was applied", name)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is out of date, pending migrations: %s (run: migrate up)", strings.Join(pending, ", "))
+	}
+	for version := range applied {
+		if version > len(statuses) {
+			return fmt.Errorf("database has migration %d applied, newer than this binary (%d)", version, len(statuses))
+		}
+	}
+	return nil
+}
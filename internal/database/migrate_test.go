@@ -0,0 +1,88 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	want, err := Migrations(SQLServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for _, dialect := range []Dialect{SQLServer, SQLite} {
+		migrations, err := Migrations(dialect)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect.Name(), err)
+		}
+		if len(migrations) != len(want) {
+			t.Errorf("%s has %d migrations, sqlserver has %d", dialect.Name(), len(migrations), len(want))
+		}
+		for i, m := range migrations {
+			if i < len(want) && m.Name != want[i].Name {
+				t.Errorf("%s migration %d is %s, sqlserver has %s", dialect.Name(), m.Version, m.Name, want[i].Name)
+			}
+			if len(m.Up) == 0 {
+				t.Errorf("%s: %04d_%s has no up statements", dialect.Name(), m.Version, m.Name)
+			}
+			for _, batch := range append(m.Up, m.Down...) {
+				for _, line := range strings.Split(batch, "\n") {
+					if strings.EqualFold(strings.TrimSpace(line), "GO") {
+						t.Errorf("%s: %04d_%s: GO left inside a batch", dialect.Name(), m.Version, m.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	db, err := Open("sqlite:" + filepath.Join(t.TempDir(), "clinic.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	applied, err := MigrateUp(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, _ := Migrations(SQLite)
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	if err := VerifySchema(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every down must undo its up, so going down and up again works.
+	if _, err := MigrateDown(db, len(migrations)); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySchema(db); err == nil {
+		t.Error("VerifySchema passed with every migration reverted")
+	}
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySchema(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	batches := splitBatches("-- header\nCREATE TABLE A (x INT)\ngo\r\n\n  GO  \n-- only a comment\nGO\nDROP TABLE A\n")
+	want := []string{"-- header\nCREATE TABLE A (x INT)", "DROP TABLE A"}
+	if len(batches) != len(want) {
+		t.Fatalf("batches = %q, want %q", batches, want)
+	}
+	for i := range want {
+		if batches[i] != want[i] {
+			t.Errorf("batch %d = %q, want %q", i, batches[i], want[i])
+		}
+	}
+}
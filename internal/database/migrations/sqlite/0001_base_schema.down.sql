@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS CHITIETDONTHUOC;
+DROP TABLE IF EXISTS DONTHUOC;
+DROP TABLE IF EXISTS THUOC;
+DROP TABLE IF EXISTS XETNGHIEM;
+DROP TABLE IF EXISTS HOSO;
+DROP TABLE IF EXISTS LICHKHAM;
+DROP TABLE IF EXISTS LICHLAMVIEC;
+DROP TABLE IF EXISTS PHONGKHAM;
+DROP TABLE IF EXISTS BANDIEUHANH;
+DROP TABLE IF EXISTS QUANLYPHONGKHAM;
+DROP TABLE IF EXISTS KETOAN;
+DROP TABLE IF EXISTS LETAN;
+DROP TABLE IF EXISTS BACSI;
+DROP TABLE IF EXISTS CUSTOMER;
+DROP TABLE IF EXISTS [USER];
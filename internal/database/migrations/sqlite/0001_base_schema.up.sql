@@ -0,0 +1,131 @@
+-- Users and their role-specific profiles. USER.userID is the key every
+-- profile table refers to as maUser.
+CREATE TABLE IF NOT EXISTS [USER] (
+	userID      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	hoTen       NVARCHAR(100) NOT NULL,
+	username    VARCHAR(50)   NOT NULL CONSTRAINT UQ_USER_username UNIQUE,
+	password    VARCHAR(255)  NOT NULL,
+	soDienThoai VARCHAR(20)   NULL,
+	email       VARCHAR(100)  NULL,
+	role        VARCHAR(30)   NOT NULL,
+	status      VARCHAR(20)   NOT NULL DEFAULT 'ACTIVE',
+	createdAt   DATETIME      NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))
+);
+CREATE TABLE IF NOT EXISTS CUSTOMER (
+	maUser    VARCHAR(20)   NOT NULL PRIMARY KEY,
+	ngaySinh  DATE          NULL,
+	gioiTinh  NVARCHAR(10)  NULL,
+	diaChi    NVARCHAR(500) NULL,
+	maBaoHiem VARCHAR(50)   NULL,
+	createdAt DATETIME      NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))
+);
+CREATE TABLE IF NOT EXISTS BACSI (
+	maUser        VARCHAR(20)   NOT NULL PRIMARY KEY,
+	chuyenKhoa    NVARCHAR(100) NULL,
+	namKinhNghiem INT           NULL,
+	bangCap       NVARCHAR(200) NULL,
+	maGiayPhep    VARCHAR(50)   NULL
+);
+CREATE TABLE IF NOT EXISTS LETAN (
+	maUser      VARCHAR(20)    NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)    NOT NULL,
+	luongCoBan  DECIMAL(18, 2) NOT NULL DEFAULT 0,
+	ngayVaoLam  DATE           NULL
+);
+CREATE TABLE IF NOT EXISTS KETOAN (
+	maUser     VARCHAR(20)    NOT NULL PRIMARY KEY,
+	luongCoBan DECIMAL(18, 2) NOT NULL DEFAULT 0,
+	ngayVaoLam DATE           NULL,
+	chuyenMon  NVARCHAR(100)  NULL
+);
+CREATE TABLE IF NOT EXISTS QUANLYPHONGKHAM (
+	maUser      VARCHAR(20)    NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)    NOT NULL,
+	luongCoBan  DECIMAL(18, 2) NOT NULL DEFAULT 0,
+	ngayVaoLam  DATE           NULL
+);
+CREATE TABLE IF NOT EXISTS BANDIEUHANH (
+	maUser         VARCHAR(20)    NOT NULL PRIMARY KEY,
+	chucVu         NVARCHAR(100)  NULL,
+	khuVucPhuTrach NVARCHAR(200)  NULL,
+	luongCoBan     DECIMAL(18, 2) NOT NULL DEFAULT 0,
+	ngayVaoLam     DATE           NULL
+);
+-- Clinics and the work done in them
+CREATE TABLE IF NOT EXISTS PHONGKHAM (
+	maPhongKham  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	tenPhongKham NVARCHAR(200) NOT NULL,
+	diaChi       NVARCHAR(500) NULL,
+	soDienThoai  VARCHAR(20)   NULL,
+	email        VARCHAR(100)  NULL
+);
+CREATE TABLE IF NOT EXISTS LICHLAMVIEC (
+	maLichLamViec VARCHAR(20) NOT NULL PRIMARY KEY,
+	maBacSi       VARCHAR(20) NOT NULL,
+	maPhongKham   VARCHAR(20) NOT NULL,
+	ngayLamViec   DATE        NOT NULL,
+	gioBatDau     TEXT        NOT NULL,
+	gioKetThuc    TEXT        NOT NULL,
+	status        VARCHAR(20) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_LICHLAMVIEC_maBacSi ON LICHLAMVIEC (maBacSi, ngayLamViec);
+CREATE TABLE IF NOT EXISTS LICHKHAM (
+	maLichKham  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer  VARCHAR(20)   NOT NULL,
+	maBacSi     VARCHAR(20)   NOT NULL,
+	maPhongKham VARCHAR(20)   NOT NULL,
+	ngayGioKham DATETIME      NOT NULL,
+	trangThai   VARCHAR(20)   NOT NULL,
+	ghiChu      NVARCHAR(500) NULL,
+	createdAt   DATETIME      NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime'))
+);
+CREATE INDEX IF NOT EXISTS IX_LICHKHAM_maBacSi ON LICHKHAM (maBacSi, ngayGioKham);
+CREATE INDEX IF NOT EXISTS IX_LICHKHAM_maCustomer ON LICHKHAM (maCustomer, ngayGioKham);
+CREATE TABLE IF NOT EXISTS HOSO (
+	maHoSo          VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer      VARCHAR(20)   NOT NULL,
+	maBacSi         VARCHAR(20)   NOT NULL,
+	maPhongKham     VARCHAR(20)   NOT NULL,
+	ngayKham        DATETIME      NOT NULL,
+	trieuChung      TEXT NULL,
+	chanDoan        TEXT NULL,
+	huongDanDieuTri TEXT NULL,
+	maICD10         VARCHAR(10)   NULL,
+	ngayTaiKham     DATE          NULL
+);
+CREATE INDEX IF NOT EXISTS IX_HOSO_maCustomer ON HOSO (maCustomer, ngayKham);
+CREATE TABLE IF NOT EXISTS XETNGHIEM (
+	maXetNghiem   VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maHoSo        VARCHAR(20)   NOT NULL,
+	loaiXetNghiem NVARCHAR(200) NULL,
+	ngayXetNghiem DATETIME      NULL,
+	ketQua        TEXT NULL,
+	ghiChu        NVARCHAR(500) NULL,
+	fileDinhKem   NVARCHAR(500) NULL
+);
+-- Medicines and prescriptions. DONTHUOC.ngayKeDon is when the prescription
+-- was written and ngayHeHan when it expires.
+CREATE TABLE IF NOT EXISTS THUOC (
+	maThuoc   VARCHAR(20)    NOT NULL PRIMARY KEY,
+	tenThuoc  NVARCHAR(200)  NOT NULL,
+	soLuong   INT            NOT NULL DEFAULT 0,
+	gia       DECIMAL(18, 2) NOT NULL DEFAULT 0,
+	congDung  NVARCHAR(500)  NULL,
+	lieuLuong NVARCHAR(500)  NULL
+);
+CREATE TABLE IF NOT EXISTS DONTHUOC (
+	maDonThuoc VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maHoSo     VARCHAR(20)   NOT NULL,
+	ngayKeDon  DATETIME      NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now', 'localtime')),
+	ngayHeHan  DATETIME      NULL,
+	ghiChu     NVARCHAR(500) NULL
+);
+CREATE INDEX IF NOT EXISTS IX_DONTHUOC_maHoSo ON DONTHUOC (maHoSo);
+CREATE TABLE IF NOT EXISTS CHITIETDONTHUOC (
+	maDonThuoc VARCHAR(20)   NOT NULL,
+	maThuoc    VARCHAR(20)   NOT NULL,
+	soLuong    INT           NOT NULL,
+	cachDung   NVARCHAR(500) NOT NULL,
+	ghiChu     NVARCHAR(500) NULL
+);
+CREATE INDEX IF NOT EXISTS IX_CHITIETDONTHUOC_maDonThuoc ON CHITIETDONTHUOC (maDonThuoc);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS HOSO_ICD10PHU;
+DROP TABLE IF EXISTS ICD10;
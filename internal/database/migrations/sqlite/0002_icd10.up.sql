@@ -0,0 +1,17 @@
+-- ICD-10 catalogue
+CREATE TABLE IF NOT EXISTS ICD10 (
+	maICD10      VARCHAR(10)    NOT NULL PRIMARY KEY,
+	tenTiengAnh  NVARCHAR(500)  NOT NULL,
+	tenTiengViet NVARCHAR(500)  NULL,
+	chuong       VARCHAR(20)    NULL,
+	maCha        VARCHAR(10)    NULL,
+	tuKhoa       NVARCHAR(1200) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_ICD10_maCha ON ICD10 (maCha);
+-- Secondary diagnoses of a medical record
+CREATE TABLE IF NOT EXISTS HOSO_ICD10PHU (
+	maHoSo  VARCHAR(20) NOT NULL,
+	maICD10 VARCHAR(10) NOT NULL,
+	thuTu   INT         NOT NULL,
+	CONSTRAINT PK_HOSO_ICD10PHU PRIMARY KEY (maHoSo, maICD10)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS HOSO_BOSUNG;
+DROP TABLE IF EXISTS HOSO_PHIENBAN;
+ALTER TABLE HOSO DROP COLUMN phienBan;
+ALTER TABLE HOSO DROP COLUMN daKy;
+ALTER TABLE HOSO DROP COLUMN ngayKy;
+ALTER TABLE HOSO DROP COLUMN nguoiKy;
@@ -0,0 +1,26 @@
+-- Medical record amendment history
+ALTER TABLE HOSO ADD COLUMN phienBan INT NOT NULL DEFAULT 1;
+ALTER TABLE HOSO ADD COLUMN daKy BIT NOT NULL DEFAULT 0;
+ALTER TABLE HOSO ADD COLUMN ngayKy DATETIME NULL;
+ALTER TABLE HOSO ADD COLUMN nguoiKy VARCHAR(20) NULL;
+CREATE TABLE IF NOT EXISTS HOSO_PHIENBAN (
+	maHoSo          VARCHAR(20)   NOT NULL,
+	phienBan        INT           NOT NULL,
+	trieuChung      TEXT NULL,
+	chanDoan        TEXT NULL,
+	huongDanDieuTri TEXT NULL,
+	maICD10         VARCHAR(10)   NULL,
+	maICD10Phu      VARCHAR(500)  NULL,
+	ngayTaiKham     DATE          NULL,
+	nguoiSua        VARCHAR(20)   NOT NULL,
+	thoiGian        DATETIME      NOT NULL,
+	lyDo            NVARCHAR(500) NOT NULL,
+	CONSTRAINT PK_HOSO_PHIENBAN PRIMARY KEY (maHoSo, phienBan)
+);
+CREATE TABLE IF NOT EXISTS HOSO_BOSUNG (
+	maBoSung VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maHoSo   VARCHAR(20)   NOT NULL,
+	noiDung  TEXT NOT NULL,
+	nguoiTao VARCHAR(20)   NOT NULL,
+	thoiGian DATETIME      NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS BENHMANTINH;
+DROP TABLE IF EXISTS DIUNG;
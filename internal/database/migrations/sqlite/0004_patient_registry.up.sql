@@ -0,0 +1,27 @@
+-- Customer allergy and chronic condition registry
+CREATE TABLE IF NOT EXISTS DIUNG (
+	maDiUng      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer   VARCHAR(20)   NOT NULL,
+	tacNhan      NVARCHAR(200) NOT NULL,
+	loaiTacNhan  VARCHAR(20)   NOT NULL,
+	maThuoc      VARCHAR(20)   NULL,
+	phanUng      NVARCHAR(500) NULL,
+	mucDo        VARCHAR(20)   NOT NULL,
+	ghiChu       NVARCHAR(500) NULL,
+	trangThai    VARCHAR(20)   NOT NULL,
+	nguoiGhiNhan VARCHAR(20)   NOT NULL,
+	ngayGhiNhan  DATETIME      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_DIUNG_maCustomer ON DIUNG (maCustomer);
+CREATE TABLE IF NOT EXISTS BENHMANTINH (
+	maBenhManTinh VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer    VARCHAR(20)   NOT NULL,
+	tenBenh       NVARCHAR(200) NOT NULL,
+	maICD10       VARCHAR(10)   NULL,
+	ngayChanDoan  DATE          NULL,
+	trangThai     VARCHAR(20)   NOT NULL,
+	ghiChu        NVARCHAR(500) NULL,
+	nguoiGhiNhan  VARCHAR(20)   NOT NULL,
+	ngayGhiNhan   DATETIME      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_BENHMANTINH_maCustomer ON BENHMANTINH (maCustomer);
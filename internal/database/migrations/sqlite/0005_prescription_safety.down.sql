@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS CANHBAODONTHUOC;
+DROP TABLE IF EXISTS TUONGTACTHUOC;
+ALTER TABLE THUOC DROP COLUMN hoatChat;
@@ -0,0 +1,21 @@
+-- Prescription safety checks
+ALTER TABLE THUOC ADD COLUMN hoatChat NVARCHAR(500) NULL;
+CREATE TABLE IF NOT EXISTS TUONGTACTHUOC (
+	hoatChat1  NVARCHAR(200)  NOT NULL,
+	hoatChat2  NVARCHAR(200)  NOT NULL,
+	mucDo      VARCHAR(20)    NOT NULL,
+	moTa       NVARCHAR(1000) NULL,
+	khuyenNghi NVARCHAR(1000) NULL,
+	CONSTRAINT PK_TUONGTACTHUOC PRIMARY KEY (hoatChat1, hoatChat2)
+);
+CREATE TABLE IF NOT EXISTS CANHBAODONTHUOC (
+	maCanhBao  INTEGER PRIMARY KEY AUTOINCREMENT,
+	maDonThuoc VARCHAR(20)       NOT NULL,
+	loai       VARCHAR(30)       NOT NULL,
+	mucDo      VARCHAR(20)       NOT NULL,
+	maThuoc    VARCHAR(200)      NOT NULL,
+	noiDung    NVARCHAR(1000)    NOT NULL,
+	lyDoBoQua  NVARCHAR(500)     NOT NULL,
+	nguoiBoQua VARCHAR(20)       NOT NULL,
+	thoiGian   DATETIME          NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS GIATHUOC;
+ALTER TABLE THUOC DROP COLUMN hamLuong;
+ALTER TABLE THUOC DROP COLUMN dangBaoChe;
+ALTER TABLE THUOC DROP COLUMN donVi;
+ALTER TABLE THUOC DROP COLUMN trangThai;
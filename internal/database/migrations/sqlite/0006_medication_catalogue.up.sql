@@ -0,0 +1,14 @@
+-- Medication catalogue
+ALTER TABLE THUOC ADD COLUMN hamLuong NVARCHAR(100) NULL;
+ALTER TABLE THUOC ADD COLUMN dangBaoChe NVARCHAR(100) NULL;
+ALTER TABLE THUOC ADD COLUMN donVi NVARCHAR(50) NULL;
+ALTER TABLE THUOC ADD COLUMN trangThai VARCHAR(20) NOT NULL DEFAULT 'ACTIVE';
+CREATE TABLE IF NOT EXISTS GIATHUOC (
+	maGia        INTEGER PRIMARY KEY AUTOINCREMENT,
+	maThuoc      VARCHAR(20)       NOT NULL,
+	gia          DECIMAL(18, 2)    NOT NULL,
+	tuNgay       DATETIME          NOT NULL,
+	denNgay      DATETIME          NULL,
+	nguoiCapNhat VARCHAR(20)       NULL
+);
+CREATE INDEX IF NOT EXISTS IX_GIATHUOC_maThuoc ON GIATHUOC (maThuoc, tuNgay);
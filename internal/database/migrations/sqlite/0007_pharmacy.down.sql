@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS BIENDONGKHO;
+DROP TABLE IF EXISTS CHITIETPHIEUNHAP;
+DROP TABLE IF EXISTS PHIEUNHAP;
+DROP TABLE IF EXISTS TONKHO;
+DROP TABLE IF EXISTS NHACUNGCAP;
+ALTER TABLE DONTHUOC DROP COLUMN ngayPhat;
+ALTER TABLE DONTHUOC DROP COLUMN nguoiPhat;
+ALTER TABLE DONTHUOC DROP COLUMN maPhongKhamPhat;
@@ -0,0 +1,53 @@
+-- Pharmacy inventory
+CREATE TABLE IF NOT EXISTS NHACUNGCAP (
+	maNhaCungCap  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	tenNhaCungCap NVARCHAR(200) NOT NULL,
+	soDienThoai   VARCHAR(20)   NULL,
+	email         VARCHAR(100)  NULL,
+	diaChi        NVARCHAR(500) NULL
+);
+CREATE TABLE IF NOT EXISTS TONKHO (
+	maLo        INTEGER PRIMARY KEY AUTOINCREMENT,
+	maPhongKham VARCHAR(20)       NOT NULL,
+	maThuoc     VARCHAR(20)       NOT NULL,
+	soLo        VARCHAR(50)       NOT NULL,
+	hanSuDung   DATE              NOT NULL,
+	soLuong     INT               NOT NULL,
+	giaNhap     DECIMAL(18, 2)    NULL,
+	ngayNhap    DATETIME          NOT NULL,
+	CONSTRAINT UQ_TONKHO_lo UNIQUE (maPhongKham, maThuoc, soLo),
+	CONSTRAINT CK_TONKHO_soLuong CHECK (soLuong >= 0)
+);
+CREATE TABLE IF NOT EXISTS PHIEUNHAP (
+	maPhieuNhap  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maPhongKham  VARCHAR(20)   NOT NULL,
+	maNhaCungCap VARCHAR(20)   NOT NULL,
+	ngayNhap     DATETIME      NOT NULL,
+	nguoiNhap    VARCHAR(20)   NOT NULL,
+	ghiChu       NVARCHAR(500) NULL
+);
+CREATE TABLE IF NOT EXISTS CHITIETPHIEUNHAP (
+	maPhieuNhap VARCHAR(20)    NOT NULL,
+	maThuoc     VARCHAR(20)    NOT NULL,
+	soLo        VARCHAR(50)    NOT NULL,
+	hanSuDung   DATE           NOT NULL,
+	soLuong     INT            NOT NULL,
+	giaNhap     DECIMAL(18, 2) NOT NULL,
+	CONSTRAINT PK_CHITIETPHIEUNHAP PRIMARY KEY (maPhieuNhap, maThuoc, soLo)
+);
+CREATE TABLE IF NOT EXISTS BIENDONGKHO (
+	maBienDong    INTEGER PRIMARY KEY AUTOINCREMENT,
+	maPhongKham   VARCHAR(20)       NOT NULL,
+	maThuoc       VARCHAR(20)       NOT NULL,
+	maLo          INT               NOT NULL,
+	loai          VARCHAR(20)       NOT NULL,
+	soLuong       INT               NOT NULL,
+	soLuongSau    INT               NOT NULL,
+	thamChieu     VARCHAR(20)       NULL,
+	nguoiThucHien VARCHAR(20)       NOT NULL,
+	thoiGian      DATETIME          NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_BIENDONGKHO_maPhongKham ON BIENDONGKHO (maPhongKham, thoiGian);
+ALTER TABLE DONTHUOC ADD COLUMN ngayPhat DATETIME NULL;
+ALTER TABLE DONTHUOC ADD COLUMN nguoiPhat VARCHAR(20) NULL;
+ALTER TABLE DONTHUOC ADD COLUMN maPhongKhamPhat VARCHAR(20) NULL;
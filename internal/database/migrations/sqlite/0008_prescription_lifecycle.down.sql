@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS UX_DONTHUOC_maXacThuc;
+ALTER TABLE DONTHUOC DROP COLUMN trangThai;
+ALTER TABLE DONTHUOC DROP COLUMN ngayKy;
+ALTER TABLE DONTHUOC DROP COLUMN nguoiKy;
+ALTER TABLE DONTHUOC DROP COLUMN maXacThuc;
+ALTER TABLE DONTHUOC DROP COLUMN ngayHuy;
+ALTER TABLE DONTHUOC DROP COLUMN nguoiHuy;
+ALTER TABLE DONTHUOC DROP COLUMN lyDoHuy;
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN lieuDung;
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN donViLieu;
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN duongDung;
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN soLanMoiNgay;
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN thoiDiemDung;
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN soNgayDung;
@@ -0,0 +1,17 @@
+-- Prescriptions written before the lifecycle existed are treated as signed.
+ALTER TABLE DONTHUOC ADD COLUMN trangThai VARCHAR(20) NOT NULL DEFAULT 'SIGNED';
+ALTER TABLE DONTHUOC ADD COLUMN ngayKy DATETIME NULL;
+ALTER TABLE DONTHUOC ADD COLUMN nguoiKy VARCHAR(20) NULL;
+ALTER TABLE DONTHUOC ADD COLUMN maXacThuc VARCHAR(20) NULL;
+ALTER TABLE DONTHUOC ADD COLUMN ngayHuy DATETIME NULL;
+ALTER TABLE DONTHUOC ADD COLUMN nguoiHuy VARCHAR(20) NULL;
+ALTER TABLE DONTHUOC ADD COLUMN lyDoHuy NVARCHAR(500) NULL;
+UPDATE DONTHUOC SET trangThai = 'DISPENSED' WHERE trangThai = 'SIGNED' AND ngayPhat IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS UX_DONTHUOC_maXacThuc ON DONTHUOC (maXacThuc) WHERE maXacThuc IS NOT NULL;
+-- Structured directions; NULL on lines written as free text only.
+ALTER TABLE CHITIETDONTHUOC ADD COLUMN lieuDung DECIMAL(10, 3) NULL;
+ALTER TABLE CHITIETDONTHUOC ADD COLUMN donViLieu VARCHAR(20) NULL;
+ALTER TABLE CHITIETDONTHUOC ADD COLUMN duongDung VARCHAR(20) NULL;
+ALTER TABLE CHITIETDONTHUOC ADD COLUMN soLanMoiNgay INT NULL;
+ALTER TABLE CHITIETDONTHUOC ADD COLUMN thoiDiemDung VARCHAR(20) NULL;
+ALTER TABLE CHITIETDONTHUOC ADD COLUMN soNgayDung INT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS XETNGHIEMMAU;
+DROP TABLE IF EXISTS CHITIETMAUDONTHUOC;
+DROP TABLE IF EXISTS MAUDONTHUOC;
@@ -0,0 +1,36 @@
+-- Prescription templates
+CREATE TABLE IF NOT EXISTS MAUDONTHUOC (
+	maMau       VARCHAR(20)   NOT NULL PRIMARY KEY,
+	tenMau      NVARCHAR(200) NOT NULL,
+	phamVi      VARCHAR(20)   NOT NULL,
+	maBacSi     VARCHAR(20)   NULL,
+	maPhongKham VARCHAR(20)   NULL,
+	maICD10     VARCHAR(10)   NULL,
+	ghiChu      NVARCHAR(500) NULL,
+	nguoiTao    VARCHAR(20)   NOT NULL,
+	ngayTao     DATETIME      NOT NULL,
+	ngayCapNhat DATETIME      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_MAUDONTHUOC_maICD10 ON MAUDONTHUOC (maICD10);
+CREATE TABLE IF NOT EXISTS CHITIETMAUDONTHUOC (
+	maMau        VARCHAR(20)    NOT NULL,
+	thuTu        INT            NOT NULL,
+	maThuoc      VARCHAR(20)    NOT NULL,
+	soLuong      INT            NOT NULL,
+	cachDung     NVARCHAR(500)  NOT NULL,
+	ghiChu       NVARCHAR(500)  NULL,
+	lieuDung     DECIMAL(10, 3) NULL,
+	donViLieu    VARCHAR(20)    NULL,
+	duongDung    VARCHAR(20)    NULL,
+	soLanMoiNgay INT            NULL,
+	thoiDiemDung VARCHAR(20)    NULL,
+	soNgayDung   INT            NULL,
+	CONSTRAINT PK_CHITIETMAUDONTHUOC PRIMARY KEY (maMau, thuTu)
+);
+CREATE TABLE IF NOT EXISTS XETNGHIEMMAU (
+	maMau         VARCHAR(20)   NOT NULL,
+	thuTu         INT           NOT NULL,
+	loaiXetNghiem NVARCHAR(200) NOT NULL,
+	ghiChu        NVARCHAR(500) NULL,
+	CONSTRAINT PK_XETNGHIEMMAU PRIMARY KEY (maMau, thuTu)
+);
@@ -0,0 +1,14 @@
+-- Follow-up scheduling
+CREATE TABLE IF NOT EXISTS TAIKHAM (
+	maTaiKham   VARCHAR(20) NOT NULL PRIMARY KEY,
+	maHoSo      VARCHAR(20) NOT NULL CONSTRAINT UQ_TAIKHAM_maHoSo UNIQUE,
+	maCustomer  VARCHAR(20) NOT NULL,
+	maBacSi     VARCHAR(20) NOT NULL,
+	maPhongKham VARCHAR(20) NOT NULL,
+	ngayTaiKham DATE        NOT NULL,
+	maLichKham  VARCHAR(20) NULL,
+	trangThai   VARCHAR(20) NOT NULL,
+	ngayTao     DATETIME    NOT NULL,
+	ngayCapNhat DATETIME    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_TAIKHAM_ngayTaiKham ON TAIKHAM (ngayTaiKham, trangThai);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS TUYCHONTHONGBAO;
+DROP TABLE IF EXISTS THONGBAO;
@@ -0,0 +1,27 @@
+-- Notifications
+CREATE TABLE IF NOT EXISTS THONGBAO (
+	maThongBao INTEGER PRIMARY KEY AUTOINCREMENT,
+	maUser     VARCHAR(20)    NOT NULL,
+	kenh       VARCHAR(10)    NOT NULL,
+	nguoiNhan  NVARCHAR(255)  NOT NULL,
+	mauTin     VARCHAR(50)    NOT NULL,
+	tieuDe     NVARCHAR(255)  NOT NULL,
+	noiDung    TEXT  NOT NULL,
+	nhayCam    BIT            NOT NULL DEFAULT 0,
+	thamChieu  VARCHAR(50)    NULL,
+	trangThai  VARCHAR(20)    NOT NULL,
+	soLanThu   INT            NOT NULL DEFAULT 0,
+	ngayTao    DATETIME       NOT NULL,
+	thuLaiLuc  DATETIME       NOT NULL,
+	guiLuc     DATETIME       NULL,
+	loiCuoi    NVARCHAR(1000) NULL
+);
+CREATE INDEX IF NOT EXISTS IX_THONGBAO_trangThai ON THONGBAO (trangThai, thuLaiLuc);
+CREATE INDEX IF NOT EXISTS IX_THONGBAO_maUser ON THONGBAO (maUser, ngayTao);
+CREATE TABLE IF NOT EXISTS TUYCHONTHONGBAO (
+	maUser      VARCHAR(20) NOT NULL PRIMARY KEY,
+	nhanEmail   BIT         NOT NULL,
+	nhanSMS     BIT         NOT NULL,
+	ngonNgu     VARCHAR(5)  NOT NULL,
+	ngayCapNhat DATETIME    NOT NULL
+);
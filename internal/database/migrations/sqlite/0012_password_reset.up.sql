@@ -0,0 +1,12 @@
+-- Password reset codes
+CREATE TABLE IF NOT EXISTS PASSWORD_RESET (
+	ID        VARCHAR(20)   NOT NULL PRIMARY KEY,
+	UserID    VARCHAR(20)   NOT NULL,
+	Email     NVARCHAR(255) NOT NULL,
+	ResetCode VARCHAR(64)   NOT NULL,
+	IsUsed    BIT           NOT NULL DEFAULT 0,
+	ExpiresAt DATETIME      NOT NULL,
+	CreatedAt DATETIME      NOT NULL
+);
+-- Reset codes are stored hashed and allow a limited number of guesses.
+ALTER TABLE PASSWORD_RESET ADD COLUMN SoLanThu INT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Appointment reminders. A row claims one reminder of one appointment
+-- time; moc is the offset before the visit in minutes.
+CREATE TABLE IF NOT EXISTS NHACLICH (
+	maLichKham  VARCHAR(20) NOT NULL,
+	ngayGioKham DATETIME    NOT NULL,
+	moc         INT         NOT NULL,
+	ngayGui     DATETIME    NOT NULL,
+	CONSTRAINT PK_NHACLICH PRIMARY KEY (maLichKham, ngayGioKham, moc)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS LICHSUDANGNHAP;
+ALTER TABLE [USER] DROP COLUMN soLanSaiLienTiep;
+ALTER TABLE [USER] DROP COLUMN lanSaiCuoi;
+ALTER TABLE [USER] DROP COLUMN khoaDenLuc;
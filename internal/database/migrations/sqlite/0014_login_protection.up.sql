@@ -0,0 +1,15 @@
+-- Login protection
+ALTER TABLE [USER] ADD COLUMN soLanSaiLienTiep INT NOT NULL DEFAULT 0;
+ALTER TABLE [USER] ADD COLUMN lanSaiCuoi DATETIME NULL;
+ALTER TABLE [USER] ADD COLUMN khoaDenLuc DATETIME NULL;
+CREATE TABLE IF NOT EXISTS LICHSUDANGNHAP (
+	maLichSu    INTEGER PRIMARY KEY AUTOINCREMENT,
+	tenDangNhap NVARCHAR(100) NOT NULL,
+	maUser      VARCHAR(20)   NULL,
+	diaChiIP    VARCHAR(45)   NOT NULL,
+	userAgent   NVARCHAR(500) NULL,
+	ketQua      VARCHAR(20)   NOT NULL,
+	thoiGian    DATETIME      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_LICHSUDANGNHAP_diaChiIP ON LICHSUDANGNHAP (diaChiIP, thoiGian);
+CREATE INDEX IF NOT EXISTS IX_LICHSUDANGNHAP_maUser ON LICHSUDANGNHAP (maUser, thoiGian);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS MAKHOIPHUC;
+DROP TABLE IF EXISTS XACTHUC2LOP;
@@ -0,0 +1,18 @@
+-- Two-factor authentication. biMat and biMatCho (the secret awaiting
+-- confirmation) are encrypted; buocCuoi is the last TOTP step accepted.
+CREATE TABLE IF NOT EXISTS XACTHUC2LOP (
+	maUser      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	biMat       NVARCHAR(200) NULL,
+	biMatCho    NVARCHAR(200) NULL,
+	daBat       BIT           NOT NULL DEFAULT 0,
+	buocCuoi    BIGINT        NULL,
+	ngayBat     DATETIME      NULL,
+	ngayCapNhat DATETIME      NOT NULL
+);
+CREATE TABLE IF NOT EXISTS MAKHOIPHUC (
+	maUser   VARCHAR(20) NOT NULL,
+	maBam    VARCHAR(64) NOT NULL,
+	ngayTao  DATETIME    NOT NULL,
+	ngayDung DATETIME    NULL,
+	CONSTRAINT PK_MAKHOIPHUC PRIMARY KEY (maUser, maBam)
+);
@@ -0,0 +1,15 @@
+-- Login sessions. maPhien is the jti of the session's tokens and
+-- maRefreshBam the hash of its current refresh token.
+CREATE TABLE IF NOT EXISTS PHIENDANGNHAP (
+	maPhien         VARCHAR(32)   NOT NULL PRIMARY KEY,
+	maUser          VARCHAR(20)   NOT NULL,
+	thietBi         NVARCHAR(500) NULL,
+	diaChiIP        VARCHAR(45)   NOT NULL,
+	maRefreshBam    VARCHAR(64)   NULL,
+	ngayTao         DATETIME      NOT NULL,
+	lanCuoiHoatDong DATETIME      NOT NULL,
+	hetHanLuc       DATETIME      NOT NULL,
+	thuHoiLuc       DATETIME      NULL,
+	lyDoThuHoi      VARCHAR(30)   NULL
+);
+CREATE INDEX IF NOT EXISTS IX_PHIENDANGNHAP_maUser ON PHIENDANGNHAP (maUser, thuHoiLuc);
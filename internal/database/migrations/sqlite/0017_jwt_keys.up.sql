@@ -0,0 +1,10 @@
+-- Token signing keys. khoaRieng is the encrypted PKCS#8 private key; a
+-- key stops signing at ngungKyLuc and stops verifying at hetHanLuc.
+CREATE TABLE IF NOT EXISTS KHOAJWT (
+	maKhoa     VARCHAR(16)   NOT NULL PRIMARY KEY,
+	thuatToan  VARCHAR(10)   NOT NULL,
+	khoaRieng  TEXT NOT NULL,
+	ngayTao    DATETIME      NOT NULL,
+	ngungKyLuc DATETIME      NULL,
+	hetHanLuc  DATETIME      NULL
+);
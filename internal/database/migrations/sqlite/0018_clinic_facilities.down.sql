@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS THIETBI;
+DROP TABLE IF EXISTS PHONG;
+DROP TABLE IF EXISTS GIOMOCUA;
+ALTER TABLE LICHLAMVIEC DROP COLUMN maPhong;
+ALTER TABLE LICHKHAM DROP COLUMN maPhong;
@@ -0,0 +1,38 @@
+-- Clinic facilities. GIOMOCUA holds the opening hours of each clinic per
+-- weekday (thu 1 = Monday ... 7 = Sunday); a clinic without a row for a
+-- day has no restriction on that day. Schedules and appointments may be
+-- assigned to a room of their clinic.
+CREATE TABLE IF NOT EXISTS GIOMOCUA (
+	maPhongKham VARCHAR(20) NOT NULL,
+	thu         TINYINT     NOT NULL,
+	gioMo       TEXT        NULL,
+	gioDong     TEXT        NULL,
+	nghi        BIT         NOT NULL DEFAULT 0,
+	CONSTRAINT PK_GIOMOCUA PRIMARY KEY (maPhongKham, thu)
+);
+CREATE TABLE IF NOT EXISTS PHONG (
+	maPhong     VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)   NOT NULL,
+	tenPhong    NVARCHAR(100) NOT NULL,
+	loaiPhong   VARCHAR(20)   NOT NULL,
+	sucChua     INT           NULL,
+	trangThai   VARCHAR(20)   NOT NULL DEFAULT 'ACTIVE',
+	ghiChu      NVARCHAR(500) NULL,
+	ngayTao     DATETIME      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IX_PHONG_maPhongKham ON PHONG (maPhongKham);
+CREATE TABLE IF NOT EXISTS THIETBI (
+	maThietBi      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maPhongKham    VARCHAR(20)   NOT NULL,
+	maPhong        VARCHAR(20)   NULL,
+	tenThietBi     NVARCHAR(200) NOT NULL,
+	loaiThietBi    NVARCHAR(100) NULL,
+	soSeri         VARCHAR(100)  NULL,
+	trangThai      VARCHAR(20)   NOT NULL DEFAULT 'IN_USE',
+	ngayMua        DATE          NULL,
+	ngayBaoTriTiep DATE          NULL,
+	ghiChu         NVARCHAR(500) NULL,
+	ngayTao        DATETIME      NOT NULL
+);
+ALTER TABLE LICHLAMVIEC ADD COLUMN maPhong VARCHAR(20) NULL;
+ALTER TABLE LICHKHAM ADD COLUMN maPhong VARCHAR(20) NULL;
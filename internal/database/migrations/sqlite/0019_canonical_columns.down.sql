@@ -0,0 +1 @@
+-- Nothing to revert: 0019 only backfills prescription dates.
@@ -0,0 +1,4 @@
+-- The SQLite schema starts out with the canonical column names and with
+-- DONTHUOC.ngayKeDon, so there is nothing to rename or backfill; the
+-- version only keeps the dialects in step.
+SELECT 1;
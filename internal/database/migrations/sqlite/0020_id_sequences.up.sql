@@ -0,0 +1,7 @@
+-- Last number handed out for each ID prefix (CUS, LK, HS, ...). An SQLite
+-- database serves one process, which numbers IDs from counters started past
+-- the stored IDs; the table keeps the schema the same as on SQL Server.
+CREATE TABLE IF NOT EXISTS SOTHUTU (
+	tienTo VARCHAR(10) NOT NULL PRIMARY KEY,
+	giaTri INT         NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS CHITIETDONTHUOC, DONTHUOC, THUOC, XETNGHIEM, HOSO, LICHKHAM, LICHLAMVIEC, PHONGKHAM,
+	BANDIEUHANH, QUANLYPHONGKHAM, KETOAN, LETAN, BACSI, CUSTOMER, [USER]
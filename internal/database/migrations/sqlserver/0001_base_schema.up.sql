@@ -0,0 +1,174 @@
+-- Users and their role-specific profiles. USER.userID is the key every
+-- profile table refers to as maUser.
+IF OBJECT_ID(N'[USER]', N'U') IS NULL
+CREATE TABLE [USER] (
+	userID      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	hoTen       NVARCHAR(100) NOT NULL,
+	username    VARCHAR(50)   NOT NULL CONSTRAINT UQ_USER_username UNIQUE,
+	password    VARCHAR(255)  NOT NULL,
+	soDienThoai VARCHAR(20)   NULL,
+	email       VARCHAR(100)  NULL,
+	role        VARCHAR(30)   NOT NULL,
+	status      VARCHAR(20)   NOT NULL CONSTRAINT DF_USER_status DEFAULT 'ACTIVE',
+	createdAt   DATETIME      NOT NULL CONSTRAINT DF_USER_createdAt DEFAULT GETDATE()
+)
+GO
+IF OBJECT_ID(N'CUSTOMER', N'U') IS NULL
+CREATE TABLE CUSTOMER (
+	maUser    VARCHAR(20)   NOT NULL PRIMARY KEY,
+	ngaySinh  DATE          NULL,
+	gioiTinh  NVARCHAR(10)  NULL,
+	diaChi    NVARCHAR(500) NULL,
+	maBaoHiem VARCHAR(50)   NULL,
+	createdAt DATETIME      NOT NULL CONSTRAINT DF_CUSTOMER_createdAt DEFAULT GETDATE()
+)
+GO
+IF OBJECT_ID(N'BACSI', N'U') IS NULL
+CREATE TABLE BACSI (
+	maUser        VARCHAR(20)   NOT NULL PRIMARY KEY,
+	chuyenKhoa    NVARCHAR(100) NULL,
+	namKinhNghiem INT           NULL,
+	bangCap       NVARCHAR(200) NULL,
+	maGiayPhep    VARCHAR(50)   NULL
+)
+GO
+IF OBJECT_ID(N'LETAN', N'U') IS NULL
+CREATE TABLE LETAN (
+	maUser      VARCHAR(20)    NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)    NOT NULL,
+	luongCoBan  DECIMAL(18, 2) NOT NULL CONSTRAINT DF_LETAN_luongCoBan DEFAULT 0,
+	ngayVaoLam  DATE           NULL
+)
+GO
+IF OBJECT_ID(N'KETOAN', N'U') IS NULL
+CREATE TABLE KETOAN (
+	maUser     VARCHAR(20)    NOT NULL PRIMARY KEY,
+	luongCoBan DECIMAL(18, 2) NOT NULL CONSTRAINT DF_KETOAN_luongCoBan DEFAULT 0,
+	ngayVaoLam DATE           NULL,
+	chuyenMon  NVARCHAR(100)  NULL
+)
+GO
+IF OBJECT_ID(N'QUANLYPHONGKHAM', N'U') IS NULL
+CREATE TABLE QUANLYPHONGKHAM (
+	maUser      VARCHAR(20)    NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)    NOT NULL,
+	luongCoBan  DECIMAL(18, 2) NOT NULL CONSTRAINT DF_QUANLYPHONGKHAM_luongCoBan DEFAULT 0,
+	ngayVaoLam  DATE           NULL
+)
+GO
+IF OBJECT_ID(N'BANDIEUHANH', N'U') IS NULL
+CREATE TABLE BANDIEUHANH (
+	maUser         VARCHAR(20)    NOT NULL PRIMARY KEY,
+	chucVu         NVARCHAR(100)  NULL,
+	khuVucPhuTrach NVARCHAR(200)  NULL,
+	luongCoBan     DECIMAL(18, 2) NOT NULL CONSTRAINT DF_BANDIEUHANH_luongCoBan DEFAULT 0,
+	ngayVaoLam     DATE           NULL
+)
+GO
+
+-- Clinics and the work done in them
+IF OBJECT_ID(N'PHONGKHAM', N'U') IS NULL
+CREATE TABLE PHONGKHAM (
+	maPhongKham  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	tenPhongKham NVARCHAR(200) NOT NULL,
+	diaChi       NVARCHAR(500) NULL,
+	soDienThoai  VARCHAR(20)   NULL,
+	email        VARCHAR(100)  NULL
+)
+GO
+IF OBJECT_ID(N'LICHLAMVIEC', N'U') IS NULL
+CREATE TABLE LICHLAMVIEC (
+	maLichLamViec VARCHAR(20) NOT NULL PRIMARY KEY,
+	maBacSi       VARCHAR(20) NOT NULL,
+	maPhongKham   VARCHAR(20) NOT NULL,
+	ngayLamViec   DATE        NOT NULL,
+	gioBatDau     TIME        NOT NULL,
+	gioKetThuc    TIME        NOT NULL,
+	status        VARCHAR(20) NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_LICHLAMVIEC_maBacSi')
+CREATE INDEX IX_LICHLAMVIEC_maBacSi ON LICHLAMVIEC (maBacSi, ngayLamViec)
+GO
+IF OBJECT_ID(N'LICHKHAM', N'U') IS NULL
+CREATE TABLE LICHKHAM (
+	maLichKham  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer  VARCHAR(20)   NOT NULL,
+	maBacSi     VARCHAR(20)   NOT NULL,
+	maPhongKham VARCHAR(20)   NOT NULL,
+	ngayGioKham DATETIME      NOT NULL,
+	trangThai   VARCHAR(20)   NOT NULL,
+	ghiChu      NVARCHAR(500) NULL,
+	createdAt   DATETIME      NOT NULL CONSTRAINT DF_LICHKHAM_createdAt DEFAULT GETDATE()
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_LICHKHAM_maBacSi')
+CREATE INDEX IX_LICHKHAM_maBacSi ON LICHKHAM (maBacSi, ngayGioKham)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_LICHKHAM_maCustomer')
+CREATE INDEX IX_LICHKHAM_maCustomer ON LICHKHAM (maCustomer, ngayGioKham)
+GO
+IF OBJECT_ID(N'HOSO', N'U') IS NULL
+CREATE TABLE HOSO (
+	maHoSo          VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer      VARCHAR(20)   NOT NULL,
+	maBacSi         VARCHAR(20)   NOT NULL,
+	maPhongKham     VARCHAR(20)   NOT NULL,
+	ngayKham        DATETIME      NOT NULL,
+	trieuChung      NVARCHAR(MAX) NULL,
+	chanDoan        NVARCHAR(MAX) NULL,
+	huongDanDieuTri NVARCHAR(MAX) NULL,
+	maICD10         VARCHAR(10)   NULL,
+	ngayTaiKham     DATE          NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_HOSO_maCustomer')
+CREATE INDEX IX_HOSO_maCustomer ON HOSO (maCustomer, ngayKham)
+GO
+IF OBJECT_ID(N'XETNGHIEM', N'U') IS NULL
+CREATE TABLE XETNGHIEM (
+	maXetNghiem   VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maHoSo        VARCHAR(20)   NOT NULL,
+	loaiXetNghiem NVARCHAR(200) NULL,
+	ngayXetNghiem DATETIME      NULL,
+	ketQua        NVARCHAR(MAX) NULL,
+	ghiChu        NVARCHAR(500) NULL,
+	fileDinhKem   NVARCHAR(500) NULL
+)
+GO
+
+-- Medicines and prescriptions. DONTHUOC.ngayKeDon is when the prescription
+-- was written and ngayHeHan when it expires.
+IF OBJECT_ID(N'THUOC', N'U') IS NULL
+CREATE TABLE THUOC (
+	maThuoc   VARCHAR(20)    NOT NULL PRIMARY KEY,
+	tenThuoc  NVARCHAR(200)  NOT NULL,
+	soLuong   INT            NOT NULL CONSTRAINT DF_THUOC_soLuong DEFAULT 0,
+	gia       DECIMAL(18, 2) NOT NULL CONSTRAINT DF_THUOC_gia DEFAULT 0,
+	congDung  NVARCHAR(500)  NULL,
+	lieuLuong NVARCHAR(500)  NULL
+)
+GO
+IF OBJECT_ID(N'DONTHUOC', N'U') IS NULL
+CREATE TABLE DONTHUOC (
+	maDonThuoc VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maHoSo     VARCHAR(20)   NOT NULL,
+	ngayKeDon  DATETIME      NOT NULL CONSTRAINT DF_DONTHUOC_ngayKeDon DEFAULT GETDATE(),
+	ngayHeHan  DATETIME      NULL,
+	ghiChu     NVARCHAR(500) NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_DONTHUOC_maHoSo')
+CREATE INDEX IX_DONTHUOC_maHoSo ON DONTHUOC (maHoSo)
+GO
+IF OBJECT_ID(N'CHITIETDONTHUOC', N'U') IS NULL
+CREATE TABLE CHITIETDONTHUOC (
+	maDonThuoc VARCHAR(20)   NOT NULL,
+	maThuoc    VARCHAR(20)   NOT NULL,
+	soLuong    INT           NOT NULL,
+	cachDung   NVARCHAR(500) NOT NULL,
+	ghiChu     NVARCHAR(500) NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_CHITIETDONTHUOC_maDonThuoc')
+CREATE INDEX IX_CHITIETDONTHUOC_maDonThuoc ON CHITIETDONTHUOC (maDonThuoc)
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS HOSO_ICD10PHU, ICD10
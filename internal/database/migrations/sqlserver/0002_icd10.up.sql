@@ -0,0 +1,22 @@
+-- ICD-10 catalogue
+IF OBJECT_ID(N'ICD10', N'U') IS NULL
+CREATE TABLE ICD10 (
+	maICD10      VARCHAR(10)    NOT NULL PRIMARY KEY,
+	tenTiengAnh  NVARCHAR(500)  NOT NULL,
+	tenTiengViet NVARCHAR(500)  NULL,
+	chuong       VARCHAR(20)    NULL,
+	maCha        VARCHAR(10)    NULL,
+	tuKhoa       NVARCHAR(1200) NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_ICD10_maCha')
+CREATE INDEX IX_ICD10_maCha ON ICD10 (maCha)
+GO
+-- Secondary diagnoses of a medical record
+IF OBJECT_ID(N'HOSO_ICD10PHU', N'U') IS NULL
+CREATE TABLE HOSO_ICD10PHU (
+	maHoSo  VARCHAR(20) NOT NULL,
+	maICD10 VARCHAR(10) NOT NULL,
+	thuTu   INT         NOT NULL,
+	CONSTRAINT PK_HOSO_ICD10PHU PRIMARY KEY (maHoSo, maICD10)
+)
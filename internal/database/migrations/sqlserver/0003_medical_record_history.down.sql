@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS HOSO_BOSUNG, HOSO_PHIENBAN
+GO
+ALTER TABLE HOSO DROP CONSTRAINT IF EXISTS DF_HOSO_phienBan, DF_HOSO_daKy
+GO
+ALTER TABLE HOSO DROP COLUMN IF EXISTS phienBan, daKy, ngayKy, nguoiKy
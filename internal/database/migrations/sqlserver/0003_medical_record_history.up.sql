@@ -0,0 +1,37 @@
+-- Medical record amendment history
+IF COL_LENGTH('HOSO', 'phienBan') IS NULL
+ALTER TABLE HOSO ADD phienBan INT NOT NULL CONSTRAINT DF_HOSO_phienBan DEFAULT 1
+GO
+IF COL_LENGTH('HOSO', 'daKy') IS NULL
+ALTER TABLE HOSO ADD daKy BIT NOT NULL CONSTRAINT DF_HOSO_daKy DEFAULT 0
+GO
+IF COL_LENGTH('HOSO', 'ngayKy') IS NULL
+ALTER TABLE HOSO ADD ngayKy DATETIME NULL
+GO
+IF COL_LENGTH('HOSO', 'nguoiKy') IS NULL
+ALTER TABLE HOSO ADD nguoiKy VARCHAR(20) NULL
+GO
+IF OBJECT_ID(N'HOSO_PHIENBAN', N'U') IS NULL
+CREATE TABLE HOSO_PHIENBAN (
+	maHoSo          VARCHAR(20)   NOT NULL,
+	phienBan        INT           NOT NULL,
+	trieuChung      NVARCHAR(MAX) NULL,
+	chanDoan        NVARCHAR(MAX) NULL,
+	huongDanDieuTri NVARCHAR(MAX) NULL,
+	maICD10         VARCHAR(10)   NULL,
+	maICD10Phu      VARCHAR(500)  NULL,
+	ngayTaiKham     DATE          NULL,
+	nguoiSua        VARCHAR(20)   NOT NULL,
+	thoiGian        DATETIME      NOT NULL,
+	lyDo            NVARCHAR(500) NOT NULL,
+	CONSTRAINT PK_HOSO_PHIENBAN PRIMARY KEY (maHoSo, phienBan)
+)
+GO
+IF OBJECT_ID(N'HOSO_BOSUNG', N'U') IS NULL
+CREATE TABLE HOSO_BOSUNG (
+	maBoSung VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maHoSo   VARCHAR(20)   NOT NULL,
+	noiDung  NVARCHAR(MAX) NOT NULL,
+	nguoiTao VARCHAR(20)   NOT NULL,
+	thoiGian DATETIME      NOT NULL
+)
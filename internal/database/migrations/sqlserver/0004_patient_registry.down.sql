@@ -0,0 +1 @@
+DROP TABLE IF EXISTS BENHMANTINH, DIUNG
@@ -0,0 +1,34 @@
+-- Customer allergy and chronic condition registry
+IF OBJECT_ID(N'DIUNG', N'U') IS NULL
+CREATE TABLE DIUNG (
+	maDiUng      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer   VARCHAR(20)   NOT NULL,
+	tacNhan      NVARCHAR(200) NOT NULL,
+	loaiTacNhan  VARCHAR(20)   NOT NULL,
+	maThuoc      VARCHAR(20)   NULL,
+	phanUng      NVARCHAR(500) NULL,
+	mucDo        VARCHAR(20)   NOT NULL,
+	ghiChu       NVARCHAR(500) NULL,
+	trangThai    VARCHAR(20)   NOT NULL,
+	nguoiGhiNhan VARCHAR(20)   NOT NULL,
+	ngayGhiNhan  DATETIME      NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_DIUNG_maCustomer')
+CREATE INDEX IX_DIUNG_maCustomer ON DIUNG (maCustomer)
+GO
+IF OBJECT_ID(N'BENHMANTINH', N'U') IS NULL
+CREATE TABLE BENHMANTINH (
+	maBenhManTinh VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maCustomer    VARCHAR(20)   NOT NULL,
+	tenBenh       NVARCHAR(200) NOT NULL,
+	maICD10       VARCHAR(10)   NULL,
+	ngayChanDoan  DATE          NULL,
+	trangThai     VARCHAR(20)   NOT NULL,
+	ghiChu        NVARCHAR(500) NULL,
+	nguoiGhiNhan  VARCHAR(20)   NOT NULL,
+	ngayGhiNhan   DATETIME      NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_BENHMANTINH_maCustomer')
+CREATE INDEX IX_BENHMANTINH_maCustomer ON BENHMANTINH (maCustomer)
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS CANHBAODONTHUOC, TUONGTACTHUOC
+GO
+ALTER TABLE THUOC DROP COLUMN IF EXISTS hoatChat
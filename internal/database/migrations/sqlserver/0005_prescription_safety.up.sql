@@ -0,0 +1,26 @@
+-- Prescription safety checks
+IF COL_LENGTH('THUOC', 'hoatChat') IS NULL
+ALTER TABLE THUOC ADD hoatChat NVARCHAR(500) NULL
+GO
+IF OBJECT_ID(N'TUONGTACTHUOC', N'U') IS NULL
+CREATE TABLE TUONGTACTHUOC (
+	hoatChat1  NVARCHAR(200)  NOT NULL,
+	hoatChat2  NVARCHAR(200)  NOT NULL,
+	mucDo      VARCHAR(20)    NOT NULL,
+	moTa       NVARCHAR(1000) NULL,
+	khuyenNghi NVARCHAR(1000) NULL,
+	CONSTRAINT PK_TUONGTACTHUOC PRIMARY KEY (hoatChat1, hoatChat2)
+)
+GO
+IF OBJECT_ID(N'CANHBAODONTHUOC', N'U') IS NULL
+CREATE TABLE CANHBAODONTHUOC (
+	maCanhBao  INT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+	maDonThuoc VARCHAR(20)       NOT NULL,
+	loai       VARCHAR(30)       NOT NULL,
+	mucDo      VARCHAR(20)       NOT NULL,
+	maThuoc    VARCHAR(200)      NOT NULL,
+	noiDung    NVARCHAR(1000)    NOT NULL,
+	lyDoBoQua  NVARCHAR(500)     NOT NULL,
+	nguoiBoQua VARCHAR(20)       NOT NULL,
+	thoiGian   DATETIME          NOT NULL
+)
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS GIATHUOC
+GO
+ALTER TABLE THUOC DROP CONSTRAINT IF EXISTS DF_THUOC_trangThai
+GO
+ALTER TABLE THUOC DROP COLUMN IF EXISTS hamLuong, dangBaoChe, donVi, trangThai
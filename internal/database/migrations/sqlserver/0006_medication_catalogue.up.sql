@@ -0,0 +1,25 @@
+-- Medication catalogue
+IF COL_LENGTH('THUOC', 'hamLuong') IS NULL
+ALTER TABLE THUOC ADD hamLuong NVARCHAR(100) NULL
+GO
+IF COL_LENGTH('THUOC', 'dangBaoChe') IS NULL
+ALTER TABLE THUOC ADD dangBaoChe NVARCHAR(100) NULL
+GO
+IF COL_LENGTH('THUOC', 'donVi') IS NULL
+ALTER TABLE THUOC ADD donVi NVARCHAR(50) NULL
+GO
+IF COL_LENGTH('THUOC', 'trangThai') IS NULL
+ALTER TABLE THUOC ADD trangThai VARCHAR(20) NOT NULL CONSTRAINT DF_THUOC_trangThai DEFAULT 'ACTIVE'
+GO
+IF OBJECT_ID(N'GIATHUOC', N'U') IS NULL
+CREATE TABLE GIATHUOC (
+	maGia        INT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+	maThuoc      VARCHAR(20)       NOT NULL,
+	gia          DECIMAL(18, 2)    NOT NULL,
+	tuNgay       DATETIME          NOT NULL,
+	denNgay      DATETIME          NULL,
+	nguoiCapNhat VARCHAR(20)       NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_GIATHUOC_maThuoc')
+CREATE INDEX IX_GIATHUOC_maThuoc ON GIATHUOC (maThuoc, tuNgay)
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS BIENDONGKHO, CHITIETPHIEUNHAP, PHIEUNHAP, TONKHO, NHACUNGCAP
+GO
+ALTER TABLE DONTHUOC DROP COLUMN IF EXISTS ngayPhat, nguoiPhat, maPhongKhamPhat
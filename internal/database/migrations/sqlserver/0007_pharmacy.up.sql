@@ -0,0 +1,64 @@
+-- Pharmacy inventory
+IF OBJECT_ID(N'NHACUNGCAP', N'U') IS NULL
+CREATE TABLE NHACUNGCAP (
+	maNhaCungCap  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	tenNhaCungCap NVARCHAR(200) NOT NULL,
+	soDienThoai   VARCHAR(20)   NULL,
+	email         VARCHAR(100)  NULL,
+	diaChi        NVARCHAR(500) NULL
+)
+GO
+IF OBJECT_ID(N'TONKHO', N'U') IS NULL
+CREATE TABLE TONKHO (
+	maLo        INT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)       NOT NULL,
+	maThuoc     VARCHAR(20)       NOT NULL,
+	soLo        VARCHAR(50)       NOT NULL,
+	hanSuDung   DATE              NOT NULL,
+	soLuong     INT               NOT NULL,
+	giaNhap     DECIMAL(18, 2)    NULL,
+	ngayNhap    DATETIME          NOT NULL,
+	CONSTRAINT UQ_TONKHO_lo UNIQUE (maPhongKham, maThuoc, soLo),
+	CONSTRAINT CK_TONKHO_soLuong CHECK (soLuong >= 0)
+)
+GO
+IF OBJECT_ID(N'PHIEUNHAP', N'U') IS NULL
+CREATE TABLE PHIEUNHAP (
+	maPhieuNhap  VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maPhongKham  VARCHAR(20)   NOT NULL,
+	maNhaCungCap VARCHAR(20)   NOT NULL,
+	ngayNhap     DATETIME      NOT NULL,
+	nguoiNhap    VARCHAR(20)   NOT NULL,
+	ghiChu       NVARCHAR(500) NULL
+)
+GO
+IF OBJECT_ID(N'CHITIETPHIEUNHAP', N'U') IS NULL
+CREATE TABLE CHITIETPHIEUNHAP (
+	maPhieuNhap VARCHAR(20)    NOT NULL,
+	maThuoc     VARCHAR(20)    NOT NULL,
+	soLo        VARCHAR(50)    NOT NULL,
+	hanSuDung   DATE           NOT NULL,
+	soLuong     INT            NOT NULL,
+	giaNhap     DECIMAL(18, 2) NOT NULL,
+	CONSTRAINT PK_CHITIETPHIEUNHAP PRIMARY KEY (maPhieuNhap, maThuoc, soLo)
+)
+GO
+IF OBJECT_ID(N'BIENDONGKHO', N'U') IS NULL
+CREATE TABLE BIENDONGKHO (
+	maBienDong    INT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+	maPhongKham   VARCHAR(20)       NOT NULL,
+	maThuoc       VARCHAR(20)       NOT NULL,
+	maLo          INT               NOT NULL,
+	loai          VARCHAR(20)       NOT NULL,
+	soLuong       INT               NOT NULL,
+	soLuongSau    INT               NOT NULL,
+	thamChieu     VARCHAR(20)       NULL,
+	nguoiThucHien VARCHAR(20)       NOT NULL,
+	thoiGian      DATETIME          NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_BIENDONGKHO_maPhongKham')
+CREATE INDEX IX_BIENDONGKHO_maPhongKham ON BIENDONGKHO (maPhongKham, thoiGian)
+GO
+IF COL_LENGTH('DONTHUOC', 'ngayPhat') IS NULL
+ALTER TABLE DONTHUOC ADD ngayPhat DATETIME NULL, nguoiPhat VARCHAR(20) NULL, maPhongKhamPhat VARCHAR(20) NULL
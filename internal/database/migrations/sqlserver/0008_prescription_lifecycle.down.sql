@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS UX_DONTHUOC_maXacThuc ON DONTHUOC
+GO
+ALTER TABLE DONTHUOC DROP CONSTRAINT IF EXISTS DF_DONTHUOC_trangThai
+GO
+ALTER TABLE DONTHUOC DROP COLUMN IF EXISTS trangThai, ngayKy, nguoiKy, maXacThuc, ngayHuy, nguoiHuy, lyDoHuy
+GO
+ALTER TABLE CHITIETDONTHUOC DROP COLUMN IF EXISTS lieuDung, donViLieu, duongDung, soLanMoiNgay, thoiDiemDung, soNgayDung
@@ -0,0 +1,25 @@
+-- Prescriptions written before the lifecycle existed are treated as signed.
+IF COL_LENGTH('DONTHUOC', 'trangThai') IS NULL
+ALTER TABLE DONTHUOC ADD
+	trangThai VARCHAR(20)  NOT NULL CONSTRAINT DF_DONTHUOC_trangThai DEFAULT 'SIGNED',
+	ngayKy    DATETIME     NULL,
+	nguoiKy   VARCHAR(20)  NULL,
+	maXacThuc VARCHAR(20)  NULL,
+	ngayHuy   DATETIME     NULL,
+	nguoiHuy  VARCHAR(20)  NULL,
+	lyDoHuy   NVARCHAR(500) NULL
+GO
+UPDATE DONTHUOC SET trangThai = 'DISPENSED' WHERE trangThai = 'SIGNED' AND ngayPhat IS NOT NULL
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'UX_DONTHUOC_maXacThuc')
+CREATE UNIQUE INDEX UX_DONTHUOC_maXacThuc ON DONTHUOC (maXacThuc) WHERE maXacThuc IS NOT NULL
+GO
+-- Structured directions; NULL on lines written as free text only.
+IF COL_LENGTH('CHITIETDONTHUOC', 'lieuDung') IS NULL
+ALTER TABLE CHITIETDONTHUOC ADD
+	lieuDung     DECIMAL(10, 3) NULL,
+	donViLieu    VARCHAR(20)    NULL,
+	duongDung    VARCHAR(20)    NULL,
+	soLanMoiNgay INT            NULL,
+	thoiDiemDung VARCHAR(20)    NULL,
+	soNgayDung   INT            NULL
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS XETNGHIEMMAU, CHITIETMAUDONTHUOC, MAUDONTHUOC
@@ -0,0 +1,43 @@
+-- Prescription templates
+IF OBJECT_ID(N'MAUDONTHUOC', N'U') IS NULL
+CREATE TABLE MAUDONTHUOC (
+	maMau       VARCHAR(20)   NOT NULL PRIMARY KEY,
+	tenMau      NVARCHAR(200) NOT NULL,
+	phamVi      VARCHAR(20)   NOT NULL,
+	maBacSi     VARCHAR(20)   NULL,
+	maPhongKham VARCHAR(20)   NULL,
+	maICD10     VARCHAR(10)   NULL,
+	ghiChu      NVARCHAR(500) NULL,
+	nguoiTao    VARCHAR(20)   NOT NULL,
+	ngayTao     DATETIME      NOT NULL,
+	ngayCapNhat DATETIME      NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_MAUDONTHUOC_maICD10')
+CREATE INDEX IX_MAUDONTHUOC_maICD10 ON MAUDONTHUOC (maICD10)
+GO
+IF OBJECT_ID(N'CHITIETMAUDONTHUOC', N'U') IS NULL
+CREATE TABLE CHITIETMAUDONTHUOC (
+	maMau        VARCHAR(20)    NOT NULL,
+	thuTu        INT            NOT NULL,
+	maThuoc      VARCHAR(20)    NOT NULL,
+	soLuong      INT            NOT NULL,
+	cachDung     NVARCHAR(500)  NOT NULL,
+	ghiChu       NVARCHAR(500)  NULL,
+	lieuDung     DECIMAL(10, 3) NULL,
+	donViLieu    VARCHAR(20)    NULL,
+	duongDung    VARCHAR(20)    NULL,
+	soLanMoiNgay INT            NULL,
+	thoiDiemDung VARCHAR(20)    NULL,
+	soNgayDung   INT            NULL,
+	CONSTRAINT PK_CHITIETMAUDONTHUOC PRIMARY KEY (maMau, thuTu)
+)
+GO
+IF OBJECT_ID(N'XETNGHIEMMAU', N'U') IS NULL
+CREATE TABLE XETNGHIEMMAU (
+	maMau         VARCHAR(20)   NOT NULL,
+	thuTu         INT           NOT NULL,
+	loaiXetNghiem NVARCHAR(200) NOT NULL,
+	ghiChu        NVARCHAR(500) NULL,
+	CONSTRAINT PK_XETNGHIEMMAU PRIMARY KEY (maMau, thuTu)
+)
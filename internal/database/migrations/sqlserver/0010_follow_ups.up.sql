@@ -0,0 +1,17 @@
+-- Follow-up scheduling
+IF OBJECT_ID(N'TAIKHAM', N'U') IS NULL
+CREATE TABLE TAIKHAM (
+	maTaiKham   VARCHAR(20) NOT NULL PRIMARY KEY,
+	maHoSo      VARCHAR(20) NOT NULL CONSTRAINT UQ_TAIKHAM_maHoSo UNIQUE,
+	maCustomer  VARCHAR(20) NOT NULL,
+	maBacSi     VARCHAR(20) NOT NULL,
+	maPhongKham VARCHAR(20) NOT NULL,
+	ngayTaiKham DATE        NOT NULL,
+	maLichKham  VARCHAR(20) NULL,
+	trangThai   VARCHAR(20) NOT NULL,
+	ngayTao     DATETIME    NOT NULL,
+	ngayCapNhat DATETIME    NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_TAIKHAM_ngayTaiKham')
+CREATE INDEX IX_TAIKHAM_ngayTaiKham ON TAIKHAM (ngayTaiKham, trangThai)
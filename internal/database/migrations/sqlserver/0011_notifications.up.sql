@@ -0,0 +1,34 @@
+-- Notifications
+IF OBJECT_ID(N'THONGBAO', N'U') IS NULL
+CREATE TABLE THONGBAO (
+	maThongBao BIGINT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+	maUser     VARCHAR(20)    NOT NULL,
+	kenh       VARCHAR(10)    NOT NULL,
+	nguoiNhan  NVARCHAR(255)  NOT NULL,
+	mauTin     VARCHAR(50)    NOT NULL,
+	tieuDe     NVARCHAR(255)  NOT NULL,
+	noiDung    NVARCHAR(MAX)  NOT NULL,
+	nhayCam    BIT            NOT NULL DEFAULT 0,
+	thamChieu  VARCHAR(50)    NULL,
+	trangThai  VARCHAR(20)    NOT NULL,
+	soLanThu   INT            NOT NULL DEFAULT 0,
+	ngayTao    DATETIME       NOT NULL,
+	thuLaiLuc  DATETIME       NOT NULL,
+	guiLuc     DATETIME       NULL,
+	loiCuoi    NVARCHAR(1000) NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_THONGBAO_trangThai')
+CREATE INDEX IX_THONGBAO_trangThai ON THONGBAO (trangThai, thuLaiLuc)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_THONGBAO_maUser')
+CREATE INDEX IX_THONGBAO_maUser ON THONGBAO (maUser, ngayTao)
+GO
+IF OBJECT_ID(N'TUYCHONTHONGBAO', N'U') IS NULL
+CREATE TABLE TUYCHONTHONGBAO (
+	maUser      VARCHAR(20) NOT NULL PRIMARY KEY,
+	nhanEmail   BIT         NOT NULL,
+	nhanSMS     BIT         NOT NULL,
+	ngonNgu     VARCHAR(5)  NOT NULL,
+	ngayCapNhat DATETIME    NOT NULL
+)
@@ -0,0 +1,18 @@
+-- Password reset codes
+IF OBJECT_ID(N'PASSWORD_RESET', N'U') IS NULL
+CREATE TABLE PASSWORD_RESET (
+	ID        VARCHAR(20)   NOT NULL PRIMARY KEY,
+	UserID    VARCHAR(20)   NOT NULL,
+	Email     NVARCHAR(255) NOT NULL,
+	ResetCode VARCHAR(10)   NOT NULL,
+	IsUsed    BIT           NOT NULL DEFAULT 0,
+	ExpiresAt DATETIME      NOT NULL,
+	CreatedAt DATETIME      NOT NULL
+)
+GO
+-- Reset codes are stored hashed and allow a limited number of guesses.
+IF COL_LENGTH('PASSWORD_RESET', 'ResetCode') < 64
+ALTER TABLE PASSWORD_RESET ALTER COLUMN ResetCode VARCHAR(64) NOT NULL
+GO
+IF COL_LENGTH('PASSWORD_RESET', 'SoLanThu') IS NULL
+ALTER TABLE PASSWORD_RESET ADD SoLanThu INT NOT NULL DEFAULT 0
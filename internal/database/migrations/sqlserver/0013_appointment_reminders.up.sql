@@ -0,0 +1,10 @@
+-- Appointment reminders. A row claims one reminder of one appointment
+-- time; moc is the offset before the visit in minutes.
+IF OBJECT_ID(N'NHACLICH', N'U') IS NULL
+CREATE TABLE NHACLICH (
+	maLichKham  VARCHAR(20) NOT NULL,
+	ngayGioKham DATETIME    NOT NULL,
+	moc         INT         NOT NULL,
+	ngayGui     DATETIME    NOT NULL,
+	CONSTRAINT PK_NHACLICH PRIMARY KEY (maLichKham, ngayGioKham, moc)
+)
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS LICHSUDANGNHAP
+GO
+-- The default of soLanSaiLienTiep has a generated name.
+DECLARE @sql NVARCHAR(MAX) = N''
+SELECT @sql += N'ALTER TABLE [USER] DROP CONSTRAINT ' + QUOTENAME(name) + N';'
+FROM sys.default_constraints
+WHERE parent_object_id = OBJECT_ID(N'[USER]') AND COL_NAME(parent_object_id, parent_column_id) = N'soLanSaiLienTiep'
+EXEC sp_executesql @sql
+GO
+ALTER TABLE [USER] DROP COLUMN IF EXISTS soLanSaiLienTiep, lanSaiCuoi, khoaDenLuc
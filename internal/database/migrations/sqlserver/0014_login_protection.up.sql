@@ -0,0 +1,26 @@
+-- Login protection
+IF COL_LENGTH('USER', 'soLanSaiLienTiep') IS NULL
+ALTER TABLE [USER] ADD soLanSaiLienTiep INT NOT NULL DEFAULT 0
+GO
+IF COL_LENGTH('USER', 'lanSaiCuoi') IS NULL
+ALTER TABLE [USER] ADD lanSaiCuoi DATETIME NULL
+GO
+IF COL_LENGTH('USER', 'khoaDenLuc') IS NULL
+ALTER TABLE [USER] ADD khoaDenLuc DATETIME NULL
+GO
+IF OBJECT_ID(N'LICHSUDANGNHAP', N'U') IS NULL
+CREATE TABLE LICHSUDANGNHAP (
+	maLichSu    BIGINT IDENTITY(1,1) NOT NULL PRIMARY KEY,
+	tenDangNhap NVARCHAR(100) NOT NULL,
+	maUser      VARCHAR(20)   NULL,
+	diaChiIP    VARCHAR(45)   NOT NULL,
+	userAgent   NVARCHAR(500) NULL,
+	ketQua      VARCHAR(20)   NOT NULL,
+	thoiGian    DATETIME      NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_LICHSUDANGNHAP_diaChiIP')
+CREATE INDEX IX_LICHSUDANGNHAP_diaChiIP ON LICHSUDANGNHAP (diaChiIP, thoiGian)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_LICHSUDANGNHAP_maUser')
+CREATE INDEX IX_LICHSUDANGNHAP_maUser ON LICHSUDANGNHAP (maUser, thoiGian)
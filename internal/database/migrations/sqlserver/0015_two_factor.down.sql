@@ -0,0 +1 @@
+DROP TABLE IF EXISTS MAKHOIPHUC, XACTHUC2LOP
@@ -0,0 +1,21 @@
+-- Two-factor authentication. biMat and biMatCho (the secret awaiting
+-- confirmation) are encrypted; buocCuoi is the last TOTP step accepted.
+IF OBJECT_ID(N'XACTHUC2LOP', N'U') IS NULL
+CREATE TABLE XACTHUC2LOP (
+	maUser      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	biMat       NVARCHAR(200) NULL,
+	biMatCho    NVARCHAR(200) NULL,
+	daBat       BIT           NOT NULL DEFAULT 0,
+	buocCuoi    BIGINT        NULL,
+	ngayBat     DATETIME      NULL,
+	ngayCapNhat DATETIME      NOT NULL
+)
+GO
+IF OBJECT_ID(N'MAKHOIPHUC', N'U') IS NULL
+CREATE TABLE MAKHOIPHUC (
+	maUser   VARCHAR(20) NOT NULL,
+	maBam    VARCHAR(64) NOT NULL,
+	ngayTao  DATETIME    NOT NULL,
+	ngayDung DATETIME    NULL,
+	CONSTRAINT PK_MAKHOIPHUC PRIMARY KEY (maUser, maBam)
+)
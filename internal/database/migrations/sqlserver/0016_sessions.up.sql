@@ -0,0 +1,18 @@
+-- Login sessions. maPhien is the jti of the session's tokens and
+-- maRefreshBam the hash of its current refresh token.
+IF OBJECT_ID(N'PHIENDANGNHAP', N'U') IS NULL
+CREATE TABLE PHIENDANGNHAP (
+	maPhien         VARCHAR(32)   NOT NULL PRIMARY KEY,
+	maUser          VARCHAR(20)   NOT NULL,
+	thietBi         NVARCHAR(500) NULL,
+	diaChiIP        VARCHAR(45)   NOT NULL,
+	maRefreshBam    VARCHAR(64)   NULL,
+	ngayTao         DATETIME      NOT NULL,
+	lanCuoiHoatDong DATETIME      NOT NULL,
+	hetHanLuc       DATETIME      NOT NULL,
+	thuHoiLuc       DATETIME      NULL,
+	lyDoThuHoi      VARCHAR(30)   NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_PHIENDANGNHAP_maUser')
+CREATE INDEX IX_PHIENDANGNHAP_maUser ON PHIENDANGNHAP (maUser, thuHoiLuc)
@@ -0,0 +1,11 @@
+-- Token signing keys. khoaRieng is the encrypted PKCS#8 private key; a
+-- key stops signing at ngungKyLuc and stops verifying at hetHanLuc.
+IF OBJECT_ID(N'KHOAJWT', N'U') IS NULL
+CREATE TABLE KHOAJWT (
+	maKhoa     VARCHAR(16)   NOT NULL PRIMARY KEY,
+	thuatToan  VARCHAR(10)   NOT NULL,
+	khoaRieng  NVARCHAR(MAX) NOT NULL,
+	ngayTao    DATETIME      NOT NULL,
+	ngungKyLuc DATETIME      NULL,
+	hetHanLuc  DATETIME      NULL
+)
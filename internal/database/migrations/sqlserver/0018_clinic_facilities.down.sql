@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS THIETBI, PHONG, GIOMOCUA
+GO
+ALTER TABLE LICHLAMVIEC DROP COLUMN IF EXISTS maPhong
+GO
+ALTER TABLE LICHKHAM DROP COLUMN IF EXISTS maPhong
@@ -0,0 +1,49 @@
+-- Clinic facilities. GIOMOCUA holds the opening hours of each clinic per
+-- weekday (thu 1 = Monday ... 7 = Sunday); a clinic without a row for a
+-- day has no restriction on that day. Schedules and appointments may be
+-- assigned to a room of their clinic.
+IF OBJECT_ID(N'GIOMOCUA', N'U') IS NULL
+CREATE TABLE GIOMOCUA (
+	maPhongKham VARCHAR(20) NOT NULL,
+	thu         TINYINT     NOT NULL,
+	gioMo       TIME        NULL,
+	gioDong     TIME        NULL,
+	nghi        BIT         NOT NULL DEFAULT 0,
+	CONSTRAINT PK_GIOMOCUA PRIMARY KEY (maPhongKham, thu)
+)
+GO
+IF OBJECT_ID(N'PHONG', N'U') IS NULL
+CREATE TABLE PHONG (
+	maPhong     VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maPhongKham VARCHAR(20)   NOT NULL,
+	tenPhong    NVARCHAR(100) NOT NULL,
+	loaiPhong   VARCHAR(20)   NOT NULL,
+	sucChua     INT           NULL,
+	trangThai   VARCHAR(20)   NOT NULL DEFAULT 'ACTIVE',
+	ghiChu      NVARCHAR(500) NULL,
+	ngayTao     DATETIME      NOT NULL
+)
+GO
+IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'IX_PHONG_maPhongKham')
+CREATE INDEX IX_PHONG_maPhongKham ON PHONG (maPhongKham)
+GO
+IF OBJECT_ID(N'THIETBI', N'U') IS NULL
+CREATE TABLE THIETBI (
+	maThietBi      VARCHAR(20)   NOT NULL PRIMARY KEY,
+	maPhongKham    VARCHAR(20)   NOT NULL,
+	maPhong        VARCHAR(20)   NULL,
+	tenThietBi     NVARCHAR(200) NOT NULL,
+	loaiThietBi    NVARCHAR(100) NULL,
+	soSeri         VARCHAR(100)  NULL,
+	trangThai      VARCHAR(20)   NOT NULL DEFAULT 'IN_USE',
+	ngayMua        DATE          NULL,
+	ngayBaoTriTiep DATE          NULL,
+	ghiChu         NVARCHAR(500) NULL,
+	ngayTao        DATETIME      NOT NULL
+)
+GO
+IF COL_LENGTH('LICHLAMVIEC', 'maPhong') IS NULL
+ALTER TABLE LICHLAMVIEC ADD maPhong VARCHAR(20) NULL
+GO
+IF COL_LENGTH('LICHKHAM', 'maPhong') IS NULL
+ALTER TABLE LICHKHAM ADD maPhong VARCHAR(20) NULL
@@ -0,0 +1,2 @@
+-- The canonical names are kept: the legacy names were never part of a
+-- versioned schema, so there is nothing to go back to.
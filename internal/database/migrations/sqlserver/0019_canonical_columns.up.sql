@@ -0,0 +1,29 @@
+-- Databases created before the schema was versioned may still use the
+-- legacy column names. Rename them to the canonical ones the queries use.
+IF COL_LENGTH('HOSO', 'huongdan') IS NOT NULL AND COL_LENGTH('HOSO', 'huongDanDieuTri') IS NULL
+EXEC sp_rename N'HOSO.huongdan', N'huongDanDieuTri', N'COLUMN'
+GO
+IF COL_LENGTH('CHITIETDONTHUOC', 'cacDung') IS NOT NULL AND COL_LENGTH('CHITIETDONTHUOC', 'cachDung') IS NULL
+EXEC sp_rename N'CHITIETDONTHUOC.cacDung', N'cachDung', N'COLUMN'
+GO
+-- ngayHeHan is the expiry date. A database without ngayKeDon gets the
+-- column, with the visit date as the prescription date of existing rows;
+-- dates already stored in ngayKeDon are kept.
+IF COL_LENGTH('DONTHUOC', 'ngayKeDon') IS NULL
+ALTER TABLE DONTHUOC ADD ngayKeDon DATETIME NULL
+GO
+UPDATE dt SET ngayKeDon = h.ngayKham
+FROM DONTHUOC dt JOIN HOSO h ON dt.maHoSo = h.maHoSo
+WHERE dt.ngayKeDon IS NULL
+GO
+UPDATE DONTHUOC SET ngayKeDon = GETDATE() WHERE ngayKeDon IS NULL
+GO
+IF COLUMNPROPERTY(OBJECT_ID(N'DONTHUOC'), 'ngayKeDon', 'AllowsNull') = 1
+ALTER TABLE DONTHUOC ALTER COLUMN ngayKeDon DATETIME NOT NULL
+GO
+IF NOT EXISTS (
+	SELECT 1 FROM sys.default_constraints
+	WHERE parent_object_id = OBJECT_ID(N'DONTHUOC')
+	  AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(N'DONTHUOC'), 'ngayKeDon', 'ColumnId')
+)
+ALTER TABLE DONTHUOC ADD CONSTRAINT DF_DONTHUOC_ngayKeDon DEFAULT GETDATE() FOR ngayKeDon
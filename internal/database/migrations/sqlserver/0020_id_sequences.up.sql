@@ -0,0 +1,9 @@
+-- Last number handed out for each ID prefix (CUS, LK, HS, ...). Every
+-- server using the database takes its IDs from here, so two instances never
+-- generate the same one; database.InitIDs starts each prefix past the IDs
+-- already stored.
+IF OBJECT_ID(N'SOTHUTU', N'U') IS NULL
+CREATE TABLE SOTHUTU (
+	tienTo VARCHAR(10) NOT NULL PRIMARY KEY,
+	giaTri INT         NOT NULL
+)
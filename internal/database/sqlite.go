@@ -0,0 +1,343 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"modernc.org/sqlite"
+)
+
+// SQLite stores dates and times as text in one sortable format, the wall
+// clock like a SQL Server DATETIME, so they compare correctly as strings.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000"
+
+// Connections wait for a lock instead of failing at once, and transactions
+// take the write lock when they begin so two of them cannot deadlock
+// upgrading a read lock.
+const sqlitePragmas = "_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+func openSQLite(path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite: URL needs a file name, for example sqlite:clinic.db")
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return sql.OpenDB(&rewriteConnector{
+		dsn:    path + separator + sqlitePragmas,
+		driver: &rewriteDriver{dialect: SQLite, driver: sqliteDriver()},
+	}), nil
+}
+
+// sqliteDriver returns the driver registered as "sqlite"; the functions
+// registered below are only added to connections it opens.
+func sqliteDriver() driver.Driver {
+	db, _ := sql.Open("sqlite", "")
+	defer db.Close()
+	return db.Driver()
+}
+
+func init() {
+	// The T-SQL functions the rewritten queries still call.
+	sqlite.MustRegisterScalarFunction("GETDATE", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().Format(sqliteTimeFormat), nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("DATEADD", 3, sqliteDateAdd)
+	sqlite.MustRegisterDeterministicScalarFunction("DATEDIFF", 3, sqliteDateDiff)
+	sqlite.MustRegisterDeterministicScalarFunction("TODATE", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		t, ok := sqliteTime(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Format(sqliteTimeFormat), nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("TODATETIME", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		t, ok := sqliteTime(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return t.Format(sqliteTimeFormat), nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("TOTIME", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		t, ok := sqliteTime(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return t.Format("15:04:05"), nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("LEN", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, nil
+		}
+		return int64(utf8.RuneCountInString(strings.TrimRight(s, " "))), nil
+	})
+}
+
+var sqliteTimeLayouts = []string{
+	sqliteTimeFormat,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"15:04:05.999999999",
+	"15:04",
+}
+
+// sqliteTime parses a value stored by this package, a time literal written
+// in a query or a time of day; a time of day falls on 0000-01-01.
+func sqliteTime(v driver.Value) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range sqliteTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func sqliteNumber(v driver.Value) (int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func sqliteDateAdd(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	unit, _ := args[0].(string)
+	n, ok := sqliteNumber(args[1])
+	t, okTime := sqliteTime(args[2])
+	if !ok || !okTime {
+		return nil, nil
+	}
+	switch strings.ToLower(unit) {
+	case "year", "yy", "yyyy":
+		t = t.AddDate(n, 0, 0)
+	case "month", "mm", "m":
+		t = t.AddDate(0, n, 0)
+	case "week", "wk", "ww":
+		t = t.AddDate(0, 0, 7*n)
+	case "day", "dd", "d":
+		t = t.AddDate(0, 0, n)
+	case "hour", "hh":
+		t = t.Add(time.Duration(n) * time.Hour)
+	case "minute", "mi", "n":
+		t = t.Add(time.Duration(n) * time.Minute)
+	case "second", "ss", "s":
+		t = t.Add(time.Duration(n) * time.Second)
+	default:
+		return nil, fmt.Errorf("DATEADD: unsupported unit %q", unit)
+	}
+	return t.Format(sqliteTimeFormat), nil
+}
+
+// sqliteDateDiff counts the unit boundaries crossed between two times, as
+// DATEDIFF does.
+func sqliteDateDiff(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	unit, _ := args[0].(string)
+	from, ok := sqliteTime(args[1])
+	to, okTo := sqliteTime(args[2])
+	if !ok || !okTo {
+		return nil, nil
+	}
+	days := func(t time.Time) int64 {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+	}
+	switch strings.ToLower(unit) {
+	case "year", "yy", "yyyy":
+		return int64(to.Year() - from.Year()), nil
+	case "month", "mm", "m":
+		return int64((to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())), nil
+	case "day", "dd", "d":
+		return days(to) - days(from), nil
+	case "hour", "hh":
+		return to.Truncate(time.Hour).Unix()/3600 - from.Truncate(time.Hour).Unix()/3600, nil
+	case "minute", "mi", "n":
+		return to.Truncate(time.Minute).Unix()/60 - from.Truncate(time.Minute).Unix()/60, nil
+	case "second", "ss", "s":
+		return to.Unix() - from.Unix(), nil
+	}
+	return nil, fmt.Errorf("DATEDIFF: unsupported unit %q", unit)
+}
+
+// rewriteDriver wraps the driver of a dialect: it rewrites every query and
+// stores and reads times the way the dialect expects.
+type rewriteDriver struct {
+	dialect Dialect
+	driver  driver.Driver
+	queries sync.Map
+}
+
+func (d *rewriteDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &rewriteConn{Conn: conn, driver: d}, nil
+}
+
+// rewritten is a query translated by a dialect, or the reason it could
+// not be.
+type rewritten struct {
+	query string
+	err   error
+}
+
+func (d *rewriteDriver) rewrite(query string) (string, error) {
+	if r, ok := d.queries.Load(query); ok {
+		return r.(rewritten).query, r.(rewritten).err
+	}
+	r := rewritten{}
+	r.query, r.err = d.dialect.Rewrite(query)
+	d.queries.Store(query, r)
+	return r.query, r.err
+}
+
+type rewriteConnector struct {
+	dsn    string
+	driver *rewriteDriver
+}
+
+func (c *rewriteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *rewriteConnector) Driver() driver.Driver { return c.driver }
+
+type rewriteConn struct {
+	driver.Conn
+	driver *rewriteDriver
+}
+
+func (c *rewriteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query, err := c.driver.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &rewriteStmt{Stmt: stmt}, nil
+}
+
+func (c *rewriteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *rewriteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *rewriteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, err := c.driver.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, sqliteArgs(args))
+}
+
+func (c *rewriteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, err := c.driver.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, sqliteArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	return &rewriteRows{Rows: rows}, nil
+}
+
+func (c *rewriteConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rewriteConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+type rewriteStmt struct {
+	driver.Stmt
+}
+
+func (s *rewriteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, sqliteArgs(args))
+}
+
+func (s *rewriteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, sqliteArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	return &rewriteRows{Rows: rows}, nil
+}
+
+type rewriteRows struct {
+	driver.Rows
+}
+
+var sqliteStoredTime = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}$`)
+
+// Next returns stored times as time.Time, also where the driver cannot tell
+// from the column type, such as for GETDATE() or MAX(ngayKham).
+func (r *rewriteRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		if s, ok := v.(string); ok && sqliteStoredTime.MatchString(s) {
+			if t, err := time.Parse(sqliteTimeFormat, s); err == nil {
+				dest[i] = t
+			}
+		}
+	}
+	return nil
+}
+
+var sqliteDateArg = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?)?$`)
+
+// sqliteArgs stores times in sqliteTimeFormat. Dates and times passed as
+// strings are converted too, as SQL Server converts them when they are
+// compared with or stored in a date column.
+func sqliteArgs(args []driver.NamedValue) []driver.NamedValue {
+	converted := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		switch v := arg.Value.(type) {
+		case time.Time:
+			arg.Value = v.Format(sqliteTimeFormat)
+		case string:
+			if sqliteDateArg.MatchString(v) {
+				if t, ok := sqliteTime(v); ok {
+					arg.Value = t.Format(sqliteTimeFormat)
+				}
+			}
+		}
+		converted[i] = arg
+	}
+	return converted
+}
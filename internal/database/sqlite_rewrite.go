@@ -0,0 +1,432 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sqliteDialect translates the T-SQL constructs listed in sqliteRewrites,
+// each covered by TestSQLiteRewrite, and no others. A query that still
+// holds T-SQL SQLite would misread, or that uses a listed construct in a
+// form the translation does not handle, is refused with an error naming
+// the construct instead of being run with other semantics.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+var (
+	sqliteParam      = regexp.MustCompile(`@p(\d+)\b`)
+	sqliteUnicode    = regexp.MustCompile(`(^|[^\w'])N'`)
+	sqliteLockHints  = regexp.MustCompile(`(?i)\s+WITH\s*\(\s*(?:UPDLOCK|HOLDLOCK|ROWLOCK|READPAST|NOLOCK|XLOCK|PAGLOCK|TABLOCKX?|SERIALIZABLE)(?:\s*,\s*(?:UPDLOCK|HOLDLOCK|ROWLOCK|READPAST|NOLOCK|XLOCK|PAGLOCK|TABLOCKX?|SERIALIZABLE))*\s*\)`)
+	sqliteIfNoTable  = regexp.MustCompile(`(?i)IF\s+OBJECT_ID\(\s*N?'[^']+'\s*,\s*N?'U'\s*\)\s+IS\s+NULL\s+CREATE\s+TABLE\b`)
+	sqliteIsNull     = regexp.MustCompile(`(?i)\bISNULL\s*\(`)
+	sqliteDatePart   = regexp.MustCompile(`(?i)\b(DATEADD|DATEDIFF)\s*\(\s*(\w+)\s*,`)
+	sqliteTop        = regexp.MustCompile(`(?i)\bSELECT\s+TOP\s*(?:\(\s*([^()]+?)\s*\)|(\d+))\s+`)
+	sqliteTopOption  = regexp.MustCompile(`(?i)^(?:PERCENT|WITH\s+TIES)\b`)
+	sqliteOffset     = regexp.MustCompile(`(?i)\bOFFSET\s+(\S+)\s+ROWS\s+FETCH\s+(?:NEXT|FIRST)\s+(\S+)\s+ROWS\s+ONLY\b`)
+	sqliteOutput     = regexp.MustCompile(`(?is)\s+OUTPUT\s+(.+?)(\s+(?:VALUES|WHERE|FROM|SELECT)\b|\s*;?\s*$)`)
+	sqliteInserted   = regexp.MustCompile(`(?i)\binserted\.`)
+	sqliteDeleted    = regexp.MustCompile(`(?i)\bdeleted\.`)
+	sqliteUpdateTop  = regexp.MustCompile(`(?is)^\s*UPDATE\s+TOP\s*\(\s*([^()]+?)\s*\)\s+(\S+)\s+SET\s+(.+?)\s+WHERE\s+(.+?)\s*$`)
+	sqliteIsMerge    = regexp.MustCompile(`(?i)^\s*MERGE\b`)
+	sqliteMerge      = regexp.MustCompile(`(?is)^\s*MERGE\s+(?:INTO\s+)?(\S+)(?:\s+WITH\s*\([^)]*\))?\s+AS\s+(\w+)\s+USING\s*\(\s*SELECT\s+(.+?)\)\s*AS\s+(\w+)\s+ON\s+(.+?)\s+(WHEN\s.+?)\s*(?:OUTPUT\s+(.+?))?\s*;?\s*$`)
+	sqliteMergeWhen  = regexp.MustCompile(`(?is)WHEN\s+(NOT\s+)?MATCHED(?:\s+BY\s+TARGET)?\s+THEN\s+`)
+	sqliteMergeAny   = regexp.MustCompile(`(?i)\bWHEN\s+(?:NOT\s+)?MATCHED\b`)
+	sqliteMergeInto  = regexp.MustCompile(`(?is)^INSERT\s*\((.+?)\)\s*VALUES\s*\((.+)\)$`)
+	sqliteMergeSet   = regexp.MustCompile(`(?is)^UPDATE\s+SET\s+(.+)$`)
+	sqliteMergeOn    = regexp.MustCompile(`(?i)^\s*\w+\.(\w+)\s*=\s*\w+\.(\w+)\s*$`)
+	sqliteMergeAlias = regexp.MustCompile(`(?is)^(.+?)\s+AS\s+(\w+)$`)
+)
+
+// sqliteRewrites are the translations, in the order they apply.
+var sqliteRewrites = []struct {
+	construct string
+	rewrite   func(string) (string, error)
+}{
+	{"MERGE", rewriteMerge},
+	{"UPDATE ... FROM", rewriteUpdateFrom},
+	{"IF OBJECT_ID(...) IS NULL CREATE TABLE", replace(sqliteIfNoTable, "CREATE TABLE IF NOT EXISTS")},
+	{"N'...' literal", replace(sqliteUnicode, "$1'")},
+	{"table hint", replace(sqliteLockHints, "")},
+	{"CAST or CONVERT", rewriteCalls},
+	{"ISNULL", replace(sqliteIsNull, "IFNULL(")},
+	{"DATEADD or DATEDIFF", replace(sqliteDatePart, "$1('$2',")},
+	{"SELECT TOP", rewriteTop},
+	{"OFFSET ... FETCH", replace(sqliteOffset, "LIMIT $2 OFFSET $1")},
+	{"OUTPUT", rewriteOutput},
+	{"@pN parameter", replace(sqliteParam, "?$1")},
+}
+
+// sqliteUnsupported are the T-SQL constructs a translated query may not
+// contain. Each either has a translation that did not apply to this form
+// of it or has none, and SQLite would run it differently or fail on it
+// with an unhelpful message. String literals are not searched.
+var sqliteUnsupported = []struct {
+	construct string
+	pattern   *regexp.Regexp
+}{
+	{"TOP", regexp.MustCompile(`(?i)\bTOP\b`)},
+	{"OUTPUT", regexp.MustCompile(`(?i)\bOUTPUT\b`)},
+	{"MERGE", regexp.MustCompile(`(?i)\bMERGE\b`)},
+	{"OFFSET ... FETCH", regexp.MustCompile(`(?i)\bFETCH\b`)},
+	{"CONVERT", regexp.MustCompile(`(?i)\bCONVERT\s*\(`)},
+	{"table hint", regexp.MustCompile(`(?i)\bWITH\s*\(`)},
+	{"CROSS or OUTER APPLY", regexp.MustCompile(`(?i)\b(?:CROSS|OUTER)\s+APPLY\b`)},
+	{"variable", regexp.MustCompile(`@`)},
+	{"string concatenation with +", regexp.MustCompile(`'\s*\+|\+\s*'`)},
+}
+
+func (sqliteDialect) Rewrite(query string) (string, error) {
+	for _, r := range sqliteRewrites {
+		rewritten, err := r.rewrite(query)
+		if err != nil {
+			return "", fmt.Errorf("sqlite: cannot translate %s: %v in query: %s", r.construct, err, query)
+		}
+		query = rewritten
+	}
+
+	code := maskLiterals(query)
+	for _, u := range sqliteUnsupported {
+		if u.pattern.MatchString(code) {
+			return "", fmt.Errorf("sqlite: cannot translate %s in query: %s", u.construct, query)
+		}
+	}
+	return query, nil
+}
+
+// replace returns a translation that replaces every match of pattern.
+func replace(pattern *regexp.Regexp, replacement string) func(string) (string, error) {
+	return func(query string) (string, error) {
+		return pattern.ReplaceAllString(query, replacement), nil
+	}
+}
+
+// maskLiterals empties the string literals of query and drops its
+// comments, so only the code is searched.
+func maskLiterals(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '\'':
+			i = skipString(query, i)
+			b.WriteString("''")
+		case strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+				b.WriteByte('\n')
+			} else {
+				i = len(query)
+			}
+		default:
+			b.WriteByte(query[i])
+		}
+	}
+	return b.String()
+}
+
+// rewriteOutput turns an OUTPUT clause of inserted values into RETURNING
+// and UPDATE TOP (n) into an update of the first n matching rows.
+func rewriteOutput(query string) (string, error) {
+	var returning string
+	if m := sqliteOutput.FindStringSubmatchIndex(query); m != nil {
+		if sqliteDeleted.MatchString(query[m[2]:m[3]]) {
+			return "", fmt.Errorf("only inserted values can be returned")
+		}
+		returning = sqliteInserted.ReplaceAllString(query[m[2]:m[3]], "")
+		query = query[:m[0]] + query[m[4]:m[5]] + query[m[1]:]
+	}
+	if m := sqliteUpdateTop.FindStringSubmatch(query); m != nil {
+		query = "UPDATE " + m[2] + " SET " + m[3] +
+			" WHERE rowid IN (SELECT rowid FROM " + m[2] + " WHERE " + m[4] + " LIMIT " + m[1] + ")"
+	}
+	if returning != "" {
+		query = strings.TrimRight(query, "; \t\r\n") + " RETURNING " + returning
+	}
+	return query, nil
+}
+
+var (
+	sqliteUpdateAlias = regexp.MustCompile(`(?is)^\s*UPDATE\s+(\w+)\s+SET\s+(.+)$`)
+	sqliteFrom        = regexp.MustCompile(`(?i)^\s+FROM\s+`)
+	sqliteWhere       = regexp.MustCompile(`(?i)^\s+WHERE\s+`)
+	sqliteJoin        = regexp.MustCompile(`(?i)^\s+(?:INNER\s+)?JOIN\s+`)
+	sqliteOn          = regexp.MustCompile(`(?i)^\s+ON\s+`)
+	sqliteTableAlias  = regexp.MustCompile(`(?i)^(\S+)\s+(?:AS\s+)?(\w+)$`)
+)
+
+// rewriteUpdateFrom turns UPDATE alias SET ... FROM table alias JOIN ...
+// into UPDATE table AS alias SET ... FROM ...; SQLite leaves the target out
+// of the FROM clause, so the condition it was joined on moves to WHERE.
+// The target must be the first table of the FROM clause, joined to the
+// next one with an inner JOIN.
+func rewriteUpdateFrom(query string) (string, error) {
+	m := sqliteUpdateAlias.FindStringSubmatch(query)
+	if m == nil {
+		return query, nil
+	}
+	parts := splitTopLevel(m[2], sqliteFrom)
+	if len(parts) == 1 {
+		return query, nil
+	}
+	if len(parts) != 2 {
+		return "", fmt.Errorf("more than one FROM clause")
+	}
+	set := parts[0]
+	from := splitTopLevel(parts[1], sqliteWhere)
+	var conditions []string
+	if len(from) == 2 {
+		conditions = append(conditions, from[1])
+	}
+	joins := splitTopLevel(from[0], sqliteJoin)
+	target := sqliteTableAlias.FindStringSubmatch(joins[0])
+	if target == nil || !strings.EqualFold(target[2], m[1]) {
+		return "", fmt.Errorf("%s is not the first table of the FROM clause", m[1])
+	}
+
+	rewritten := "UPDATE " + target[1] + " AS " + target[2] + " SET " + set
+	if len(joins) > 1 {
+		first := splitTopLevel(joins[1], sqliteOn)
+		if len(first) != 2 {
+			return "", fmt.Errorf("the first join has no ON condition")
+		}
+		conditions = append([]string{first[1]}, conditions...)
+		rewritten += " FROM " + first[0]
+		for _, join := range joins[2:] {
+			rewritten += " JOIN " + join
+		}
+	}
+	if len(conditions) > 0 {
+		rewritten += " WHERE (" + strings.Join(conditions, ") AND (") + ")"
+	}
+	return rewritten, nil
+}
+
+// rewriteTop moves SELECT TOP n to a LIMIT at the end of that SELECT: the
+// end of the query or the parenthesis that closes the subquery.
+func rewriteTop(query string) (string, error) {
+	for {
+		m := sqliteTop.FindStringSubmatchIndex(query)
+		if m == nil {
+			return query, nil
+		}
+		var limit string
+		if m[2] >= 0 {
+			limit = query[m[2]:m[3]]
+		} else {
+			limit = query[m[4]:m[5]]
+		}
+		rest := query[m[1]:]
+		if option := sqliteTopOption.FindString(rest); option != "" {
+			return "", fmt.Errorf("TOP ... %s has no LIMIT equivalent", option)
+		}
+		end := scopeEnd(rest)
+		tail := strings.TrimRight(rest[:end], "; \t\r\n")
+		query = query[:m[0]] + "SELECT " + tail + " LIMIT " + limit + rest[len(tail):]
+	}
+}
+
+// scopeEnd returns the index of the parenthesis closing the scope s starts
+// in, or len(s).
+func scopeEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipString(s, i)
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return len(s)
+}
+
+// skipString returns the index of the quote closing the string literal
+// opened at i.
+func skipString(s string, i int) int {
+	for i++; i < len(s); i++ {
+		if s[i] == '\'' {
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s)
+}
+
+// splitTopLevel splits s on sep where it is outside parentheses and
+// string literals.
+func splitTopLevel(s string, sep *regexp.Regexp) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			i = skipString(s, i)
+			continue
+		case '(':
+			depth++
+			continue
+		case ')':
+			depth--
+			continue
+		}
+		if depth == 0 {
+			if loc := sep.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				i += loc[1] - 1
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+var (
+	sqliteCall  = regexp.MustCompile(`(?i)\b(CAST|CONVERT)\s*\(`)
+	sqliteComma = regexp.MustCompile(`^,`)
+	sqliteAs    = regexp.MustCompile(`(?i)^\s+AS\s+`)
+	sqliteAnd   = regexp.MustCompile(`(?i)^\s+AND\s+`)
+	sqliteText  = regexp.MustCompile(`(?i)^N?(?:VAR)?CHAR\s*\(\s*(\d+)\s*\)$`)
+)
+
+// rewriteCalls translates CAST and CONVERT to dates and times into the
+// functions registered in sqlite.go. CONVERT takes no style but 108, the
+// time of day, into a string.
+func rewriteCalls(query string) (string, error) {
+	var b strings.Builder
+	for {
+		m := sqliteCall.FindStringSubmatchIndex(query)
+		if m == nil {
+			b.WriteString(query)
+			return b.String(), nil
+		}
+		name := strings.ToUpper(query[m[2]:m[3]])
+		body := query[m[1]:]
+		end := scopeEnd(body)
+		inner, err := rewriteCalls(body[:end])
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(query[:m[0]])
+		if name == "CAST" {
+			parts := splitTopLevel(inner, sqliteAs)
+			if len(parts) != 2 {
+				return "", fmt.Errorf("CAST(%s) has no AS type", inner)
+			}
+			b.WriteString(castTo(parts[0], parts[1]))
+		} else {
+			parts := splitTopLevel(inner, sqliteComma)
+			switch {
+			case len(parts) == 3 && parts[2] == "108" && sqliteText.MatchString(parts[0]):
+				b.WriteString("SUBSTR(TOTIME(" + parts[1] + "), 1, " + sqliteText.FindStringSubmatch(parts[0])[1] + ")")
+			case len(parts) == 2:
+				b.WriteString(castTo(parts[1], parts[0]))
+			default:
+				return "", fmt.Errorf("CONVERT(%s) has a style other than 108 to a string", inner)
+			}
+		}
+		if end < len(body) {
+			end++
+		}
+		query = body[end:]
+	}
+}
+
+func castTo(expr, typ string) string {
+	switch strings.ToUpper(strings.TrimSpace(typ)) {
+	case "DATE":
+		return "TODATE(" + expr + ")"
+	case "TIME":
+		return "TOTIME(" + expr + ")"
+	case "DATETIME", "DATETIME2", "SMALLDATETIME":
+		return "TODATETIME(" + expr + ")"
+	}
+	return "CAST(" + expr + " AS " + typ + ")"
+}
+
+// rewriteMerge turns a single-row MERGE, one whose source is a SELECT of
+// values matched on the target key, into an INSERT with an upsert clause.
+// It inserts when not matched and may update when matched.
+func rewriteMerge(query string) (string, error) {
+	if !sqliteIsMerge.MatchString(query) {
+		return query, nil
+	}
+	m := sqliteMerge.FindStringSubmatch(query)
+	if m == nil {
+		return "", fmt.Errorf("the source is not a SELECT of values")
+	}
+	table, target, source, output := m[1], m[2], m[4], m[7]
+
+	values := map[string]string{}
+	for _, column := range splitTopLevel(m[3], sqliteComma) {
+		alias := sqliteMergeAlias.FindStringSubmatch(column)
+		if alias == nil {
+			return "", fmt.Errorf("source column %s has no alias", column)
+		}
+		values[alias[2]] = alias[1]
+	}
+	sourceColumn := regexp.MustCompile(`\b` + source + `\.(\w+)`)
+	fromSource := func(s string) string {
+		return sourceColumn.ReplaceAllStringFunc(s, func(ref string) string {
+			return values[sourceColumn.FindStringSubmatch(ref)[1]]
+		})
+	}
+
+	var keys []string
+	for _, condition := range splitTopLevel(m[5], sqliteAnd) {
+		on := sqliteMergeOn.FindStringSubmatch(condition)
+		if on == nil {
+			return "", fmt.Errorf("ON condition %s does not match a target column to a source column", condition)
+		}
+		keys = append(keys, on[1])
+	}
+
+	var insert, update string
+	clauses := sqliteMergeWhen.FindAllStringSubmatchIndex(m[6], -1)
+	for i, c := range clauses {
+		end := len(m[6])
+		if i+1 < len(clauses) {
+			end = clauses[i+1][0]
+		}
+		action := strings.TrimSpace(m[6][c[1]:end])
+		if c[2] >= 0 {
+			insert = action
+		} else {
+			update = action
+		}
+	}
+	if len(clauses) != len(sqliteMergeAny.FindAllStringIndex(m[6], -1)) {
+		return "", fmt.Errorf("only WHEN MATCHED and WHEN NOT MATCHED clauses without conditions are supported")
+	}
+	into := sqliteMergeInto.FindStringSubmatch(insert)
+	if into == nil {
+		return "", fmt.Errorf("WHEN NOT MATCHED does not INSERT a row of values")
+	}
+
+	rewritten := "INSERT INTO " + table + " (" + into[1] + ") VALUES (" + fromSource(into[2]) + ")" +
+		" ON CONFLICT (" + strings.Join(keys, ", ") + ") DO "
+	if set := sqliteMergeSet.FindStringSubmatch(update); set != nil {
+		targetColumn := regexp.MustCompile(`\b` + target + `\.`)
+		rewritten += "UPDATE SET " + targetColumn.ReplaceAllString(fromSource(set[1]), "")
+	} else if update != "" {
+		return "", fmt.Errorf("WHEN MATCHED does not UPDATE SET")
+	} else {
+		rewritten += "NOTHING"
+	}
+	if output != "" {
+		if sqliteDeleted.MatchString(output) {
+			return "", fmt.Errorf("only inserted values can be returned")
+		}
+		rewritten += " RETURNING " + sqliteInserted.ReplaceAllString(output, "")
+	}
+	return rewritten, nil
+}
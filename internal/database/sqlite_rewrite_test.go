@@ -0,0 +1,169 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSQLiteRewrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "parameters and unicode literals",
+			query: "SELECT hoTen FROM [USER] WHERE userID = @p1 AND hoTen <> N'x' AND role IN (@p2, @p12)",
+			want:  "SELECT hoTen FROM [USER] WHERE userID = ?1 AND hoTen <> 'x' AND role IN (?2, ?12)",
+		},
+		{
+			name:  "lock hints and ISNULL",
+			query: "SELECT ISNULL(gia, 0) FROM THUOC WITH (UPDLOCK, ROWLOCK) WHERE maThuoc = @p1",
+			want:  "SELECT IFNULL(gia, 0) FROM THUOC WHERE maThuoc = ?1",
+		},
+		{
+			name:  "TOP",
+			query: "SELECT TOP 1 maLichKham FROM LICHKHAM WHERE maBacSi = @p1 ORDER BY ngayGioKham",
+			want:  "SELECT maLichKham FROM LICHKHAM WHERE maBacSi = ?1 ORDER BY ngayGioKham LIMIT 1",
+		},
+		{
+			name:  "TOP in a subquery",
+			query: "SELECT a, (SELECT TOP (@p2) b FROM T WHERE c = 'x)') AS b FROM U WHERE d = @p1",
+			want:  "SELECT a, (SELECT b FROM T WHERE c = 'x)' LIMIT ?2) AS b FROM U WHERE d = ?1",
+		},
+		{
+			name:  "OFFSET FETCH",
+			query: "SELECT x FROM T ORDER BY x OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+			want:  "SELECT x FROM T ORDER BY x LIMIT 10 OFFSET 20",
+		},
+		{
+			name:  "dates and times",
+			query: "SELECT CONVERT(VARCHAR(5), gioMo, 108) FROM T WHERE CAST(ngay AS DATE) = @p1 AND d < DATEADD(day, 1, CAST(@p2 AS DATE)) AND CAST(x AS DECIMAL(19, 4)) > 0",
+			want:  "SELECT SUBSTR(TOTIME(gioMo), 1, 5) FROM T WHERE TODATE(ngay) = ?1 AND d < DATEADD('day', 1, TODATE(?2)) AND CAST(x AS DECIMAL(19, 4)) > 0",
+		},
+		{
+			name:  "nested casts",
+			query: "DATEADD(MINUTE, DATEDIFF(MINUTE, CAST('00:00' AS TIME), CAST(ll.gioBatDau AS TIME)), CAST(ll.ngayLamViec AS DATETIME2))",
+			want:  "DATEADD('MINUTE', DATEDIFF('MINUTE', TOTIME('00:00'), TOTIME(ll.gioBatDau)), TODATETIME(ll.ngayLamViec))",
+		},
+		{
+			name:  "OUTPUT in INSERT",
+			query: "INSERT INTO THONGBAO (a, b) OUTPUT INSERTED.maThongBao VALUES (@p1, GETDATE())",
+			want:  "INSERT INTO THONGBAO (a, b) VALUES (?1, GETDATE()) RETURNING maThongBao",
+		},
+		{
+			name:  "UPDATE TOP with OUTPUT",
+			query: "UPDATE TOP (@p1) THONGBAO WITH (READPAST) SET thuLaiLuc = @p2 OUTPUT INSERTED.maThongBao, INSERTED.kenh WHERE trangThai = 'PENDING'",
+			want:  "UPDATE THONGBAO SET thuLaiLuc = ?2 WHERE rowid IN (SELECT rowid FROM THONGBAO WHERE trangThai = 'PENDING' LIMIT ?1) RETURNING maThongBao, kenh",
+		},
+		{
+			name:  "UPDATE FROM a join",
+			query: "UPDATE p SET a = @p1 FROM PHIEN p JOIN [USER] u ON p.maUser = u.userID JOIN X x ON x.id = u.id WHERE p.maPhien = @p2",
+			want:  "UPDATE PHIEN AS p SET a = ?1 FROM [USER] u JOIN X x ON x.id = u.id WHERE (p.maUser = u.userID) AND (p.maPhien = ?2)",
+		},
+		{
+			name:  "UPDATE FROM with a subquery",
+			query: "UPDATE t SET s = CASE WHEN EXISTS (SELECT 1 FROM L l WHERE l.c = t.c) THEN 1 ELSE 0 END FROM TAIKHAM t WHERE t.s = 0",
+			want:  "UPDATE TAIKHAM AS t SET s = CASE WHEN EXISTS (SELECT 1 FROM L l WHERE l.c = t.c) THEN 1 ELSE 0 END WHERE (t.s = 0)",
+		},
+		{
+			name: "MERGE upsert",
+			query: `MERGE TONKHO WITH (HOLDLOCK) AS target
+				USING (SELECT @p1 AS maPhongKham, @p2 AS soLo) AS source
+				ON target.maPhongKham = source.maPhongKham AND target.soLo = source.soLo
+				WHEN MATCHED THEN UPDATE SET soLuong = target.soLuong + @p3
+				WHEN NOT MATCHED THEN INSERT (maPhongKham, soLo, soLuong) VALUES (@p1, @p2, @p3)
+				OUTPUT inserted.maLo, inserted.soLuong;`,
+			want: "INSERT INTO TONKHO (maPhongKham, soLo, soLuong) VALUES (?1, ?2, ?3) ON CONFLICT (maPhongKham, soLo) DO UPDATE SET soLuong = soLuong + ?3 RETURNING maLo, soLuong",
+		},
+		{
+			name: "MERGE insert only",
+			query: `MERGE NHACLICH WITH (HOLDLOCK) AS t
+				USING (SELECT @p1 AS maLichKham, @p2 AS moc) AS src
+				ON t.maLichKham = src.maLichKham AND t.moc = src.moc
+				WHEN NOT MATCHED THEN
+					INSERT (maLichKham, moc, ngayGui) VALUES (src.maLichKham, src.moc, GETDATE());`,
+			want: "INSERT INTO NHACLICH (maLichKham, moc, ngayGui) VALUES (?1, ?2, GETDATE()) ON CONFLICT (maLichKham, moc) DO NOTHING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewritten, err := SQLite.Rewrite(tt.query)
+			if err != nil {
+				t.Fatalf("Rewrite() error = %v", err)
+			}
+			if got := strings.Join(strings.Fields(rewritten), " "); got != tt.want {
+				t.Errorf("Rewrite() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteRewriteRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		construct string
+	}{
+		{"TOP PERCENT", "SELECT TOP 10 PERCENT x FROM T", "SELECT TOP"},
+		{"TOP WITH TIES", "SELECT TOP (5) WITH TIES x FROM T ORDER BY x", "SELECT TOP"},
+		{"TOP outside SELECT", "DELETE TOP (10) FROM T WHERE x = @p1", "TOP"},
+		{"CONVERT with a date style", "SELECT CONVERT(VARCHAR(10), ngay, 103) FROM T", "CAST or CONVERT"},
+		{"CAST without a type", "SELECT CAST(x) FROM T", "CAST or CONVERT"},
+		{"OUTPUT of deleted values", "UPDATE T SET x = @p1 OUTPUT DELETED.x WHERE y = @p2", "OUTPUT"},
+		{"OUTPUT INTO a variable", "DELETE FROM T OUTPUT deleted.x INTO @removed WHERE y = 1", "OUTPUT"},
+		{
+			"MERGE from a table",
+			"MERGE T AS t USING S AS s ON t.id = s.id WHEN MATCHED THEN UPDATE SET x = s.x;",
+			"MERGE",
+		},
+		{
+			"MERGE with a conditional clause",
+			`MERGE T AS t USING (SELECT @p1 AS id, @p2 AS x) AS s ON t.id = s.id
+				WHEN MATCHED AND t.x < s.x THEN UPDATE SET x = s.x
+				WHEN NOT MATCHED THEN INSERT (id, x) VALUES (s.id, s.x);`,
+			"MERGE",
+		},
+		{
+			"MERGE deleting rows",
+			`MERGE T AS t USING (SELECT @p1 AS id) AS s ON t.id = s.id
+				WHEN MATCHED THEN DELETE
+				WHEN NOT MATCHED THEN INSERT (id) VALUES (s.id);`,
+			"MERGE",
+		},
+		{"UPDATE FROM another first table", "UPDATE p SET a = 1 FROM [USER] u JOIN PHIEN p ON p.maUser = u.userID", "UPDATE ... FROM"},
+		{"other table hints", "SELECT x FROM T WITH (INDEX(ix_t)) WHERE y = @p1", "table hint"},
+		{"CROSS APPLY", "SELECT a.x, b.y FROM A a CROSS APPLY (SELECT TOP 1 y FROM B WHERE B.a = a.x) b", "CROSS or OUTER APPLY"},
+		{"named variables", "SELECT x FROM T WHERE y = @name", "variable"},
+		{"system functions", "SELECT @@ROWCOUNT", "variable"},
+		{"string concatenation", "SELECT x FROM T WHERE y LIKE '%' + @p1 + '%'", "string concatenation with +"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SQLite.Rewrite(tt.query)
+			if err == nil {
+				t.Fatalf("Rewrite() = %q, want an error", got)
+			}
+			if !strings.Contains(err.Error(), "cannot translate "+tt.construct) {
+				t.Errorf("Rewrite() error = %v, want it to name %s", err, tt.construct)
+			}
+		})
+	}
+}
+
+func TestSQLiteRewriteIgnoresLiterals(t *testing.T) {
+	query := "SELECT x FROM T WHERE note = 'TOP + @home' -- MERGE WITH (x)\nAND y = @p1"
+	want := "SELECT x FROM T WHERE note = 'TOP + @home' -- MERGE WITH (x)\nAND y = ?1"
+	if got, err := SQLite.Rewrite(query); err != nil || got != want {
+		t.Errorf("Rewrite() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestSQLServerRewriteIsIdentity(t *testing.T) {
+	query := "SELECT TOP 1 x FROM T WITH (UPDLOCK) WHERE y = @p1"
+	if got, err := SQLServer.Rewrite(query); err != nil || got != query {
+		t.Errorf("Rewrite() = %q, %v, want the query unchanged", got, err)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/scheduler"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AppointmentLinkHandler serves the confirm and cancel links sent in
+// appointment reminders. The signed token is the patient's authorisation,
+// so these routes do not require a login.
+type AppointmentLinkHandler struct {
+	db         *sql.DB
+	linkSecret string
+}
+
+func NewAppointmentLinkHandler(db *sql.DB, linkSecret string) *AppointmentLinkHandler {
+	return &AppointmentLinkHandler{db: db, linkSecret: linkSecret}
+}
+
+// GetLink shows what the link will do without changing anything, so link
+// previews and mail scanners that fetch it have no effect.
+func (h *AppointmentLinkHandler) GetLink(c *gin.Context) {
+	appointmentID, action, ok := h.parseToken(c)
+	if !ok {
+		return
+	}
+
+	var ngayGioKham time.Time
+	var trangThai, tenBacSi, tenPhongKham string
+	err := h.db.QueryRow(`
+		SELECT l.ngayGioKham, l.trangThai, u.hoTen, p.tenPhongKham
+		FROM LICHKHAM l
+		JOIN [USER] u ON l.maBacSi = u.userID
+		JOIN PHONGKHAM p ON l.maPhongKham = p.maPhongKham
+		WHERE l.maLichKham = @p1
+	`, appointmentID).Scan(&ngayGioKham, &trangThai, &tenBacSi, &tenPhongKham)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Appointment not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to find appointment",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Appointment retrieved successfully",
+		Data: AppointmentLinkResponse{
+			MaLichKham:   appointmentID,
+			NgayGioKham:  ngayGioKham,
+			TrangThai:    trangThai,
+			TenBacSi:     tenBacSi,
+			TenPhongKham: tenPhongKham,
+			HanhDong:     action,
+		},
+	})
+}
+
+// ApplyLink confirms or cancels the appointment named in the token.
+// Repeating the same action is harmless.
+func (h *AppointmentLinkHandler) ApplyLink(c *gin.Context) {
+	appointmentID, action, ok := h.parseToken(c)
+	if !ok {
+		return
+	}
+
+	query := `UPDATE LICHKHAM SET trangThai = 'CONFIRMED'
+		WHERE maLichKham = @p1 AND trangThai IN ('SCHEDULED', 'CONFIRMED')`
+	status, message := "CONFIRMED", "Appointment confirmed successfully"
+	if action == scheduler.ReminderActionCancel {
+		query = `UPDATE LICHKHAM SET trangThai = 'CANCELLED'
+			WHERE maLichKham = @p1 AND trangThai IN ('PENDING', 'SCHEDULED', 'CONFIRMED', 'CANCELLED')`
+		status, message = "CANCELLED", "Appointment cancelled successfully"
+	}
+
+	result, err := h.db.Exec(query, appointmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update appointment",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Appointment can no longer be changed from this link",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    AppointmentStatusResponse{MaLichKham: appointmentID, TrangThai: status},
+	})
+}
+
+func (h *AppointmentLinkHandler) parseToken(c *gin.Context) (string, string, bool) {
+	appointmentID, action, err := scheduler.ParseReminderToken(h.linkSecret, c.Param("token"))
+	if err == nil {
+		return appointmentID, action, true
+	}
+
+	message := "Invalid link"
+	if err == utils.ErrTokenExpired {
+		message = "This link has expired"
+	}
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+	return "", "", false
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAppointmentRouter serves the appointment handler on an in-memory store.
+// Requests authenticate as the user in the X-User-ID and X-User-Type
+// headers.
+func newAppointmentRouter(store *memory.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := &AppointmentHandler{appointments: services.NewAppointmentService(store.AppointmentRepository(), store.ScheduleRepository(), store.ClinicRepository())}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+		c.Set("user_type", c.GetHeader("X-User-Type"))
+	})
+	router.GET("/appointments/:id", h.GetAppointment)
+	router.DELETE("/appointments/:id", h.CancelAppointment)
+	return router
+}
+
+func serve(router *gin.Engine, method, path, userID, userType string) (*httptest.ResponseRecorder, models.APIResponse) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-User-ID", userID)
+	req.Header.Set("X-User-Type", userType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body models.APIResponse
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec, body
+}
+
+func TestAppointmentHandlers(t *testing.T) {
+	store := memory.NewStore()
+	store.Appointments["LK001"] = models.AppointmentDetail{
+		Appointment: models.Appointment{
+			MaLichKham:  "LK001",
+			MaCustomer:  "CUS001",
+			MaBacSi:     "BS001",
+			MaPhongKham: "PK001",
+			NgayGioKham: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+			TrangThai:   services.AppointmentScheduled,
+		},
+		TenBacSi: "Tran Thi B",
+	}
+	router := newAppointmentRouter(store)
+
+	tests := []struct {
+		name       string
+		method     string
+		userID     string
+		userType   string
+		wantStatus int
+		wantMsg    string
+	}{
+		{"owner reads", http.MethodGet, "CUS001", "CUSTOMER", http.StatusOK, "Appointment retrieved successfully"},
+		{"other customer reads", http.MethodGet, "CUS002", "CUSTOMER", http.StatusNotFound, "Appointment not found"},
+		{"other customer cancels", http.MethodDelete, "CUS002", "CUSTOMER", http.StatusForbidden, "You can only cancel your own appointments"},
+		{"owner cancels", http.MethodDelete, "CUS001", "CUSTOMER", http.StatusOK, "Appointment cancelled successfully"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, body := serve(router, tt.method, "/appointments/LK001", tt.userID, tt.userType)
+			if rec.Code != tt.wantStatus || body.Message != tt.wantMsg {
+				t.Errorf("got %d %q, want %d %q", rec.Code, body.Message, tt.wantStatus, tt.wantMsg)
+			}
+		})
+	}
+
+	if status := store.Appointments["LK001"].TrangThai; status != services.AppointmentCancelled {
+		t.Errorf("status = %s, want %s", status, services.AppointmentCancelled)
+	}
+
+	_, body := serve(router, http.MethodGet, "/appointments/LK001", "BS001", "DOCTOR")
+	data, _ := body.Data.(map[string]interface{})
+	if data["ma_lich_kham"] != "LK001" || data["ten_bac_si"] != "Tran Thi B" || data["trang_thai"] != services.AppointmentCancelled {
+		t.Errorf("data = %v", data)
+	}
+}
@@ -1,23 +1,56 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"clinic-management/internal/keys"
 	"clinic-management/internal/middleware"
 	"clinic-management/internal/models"
+	"clinic-management/internal/notification"
+	"clinic-management/internal/session"
 	"clinic-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	db        *sql.DB
-	jwtSecret string
+	db          *sql.DB
+	signingKeys *keys.Manager
+	notifier    notification.Notifier
+	sessions    *session.Store
+	reset       PasswordResetSettings
+	login       LoginSecuritySettings
+	twoFactor   TwoFactorSettings
 }
 
-func NewAuthHandler(db *sql.DB, jwtSecret string) *AuthHandler {
-	return &AuthHandler{db: db, jwtSecret: jwtSecret}
+// PasswordResetSettings configure ForgotPassword and ResetPassword. Reset
+// links point to LinkBaseURL/reset-password and are signed with LinkSecret.
+type PasswordResetSettings struct {
+	LinkSecret  string
+	LinkBaseURL string
+	MaxAttempts int
+}
+
+const (
+	passwordResetValidity    = time.Hour
+	passwordResetTokenPrefix = "password_reset:"
+)
+
+func NewAuthHandler(db *sql.DB, signingKeys *keys.Manager, notifier notification.Notifier, sessions *session.Store, reset PasswordResetSettings, login LoginSecuritySettings, twoFactor TwoFactorSettings) *AuthHandler {
+	if reset.MaxAttempts < 1 {
+		reset.MaxAttempts = 1
+	}
+	reset.LinkBaseURL = strings.TrimRight(reset.LinkBaseURL, "/")
+	return &AuthHandler{db: db, signingKeys: signingKeys, notifier: notifier, sessions: sessions, reset: reset, login: login, twoFactor: twoFactor}
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -39,17 +72,24 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	query := `SELECT userID, HoTen, SoDienThoai, Email, username, password, status, createdAt, role 
-			  FROM [USER] WHERE username = @p1 AND status = 'ACTIVE'`
-
-	err := h.db.QueryRow(query, req.TenDangNhap).Scan(
-		&user.MaUser, &user.HoTen, &user.SoDienThoai, &user.Email,
-		&user.TenDangNhap, &user.MatKhau, &user.TrangThai,
-		&user.NgayTao, &user.Role,
-	)
+	wait, err := h.ipRetryAfter(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check login attempts",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if wait > 0 {
+		h.recordLoginAttempt(c, req.TenDangNhap, nil, loginIPBlocked)
+		tooManyLoginAttempts(c, wait, "Too many failed logins from this address, please try again later")
+		return
+	}
 
+	user, state, twoFactorEnabled, err := h.findLoginUser("username", req.TenDangNhap)
 	if err != nil {
+		h.recordLoginAttempt(c, req.TenDangNhap, nil, loginUnknownUser)
 		c.JSON(http.StatusUnauthorized, models.APIResponse{
 			Success: false,
 			Message: "Invalid credentials",
@@ -57,7 +97,22 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if wait, locked := h.accountRetryAfter(state); wait > 0 {
+		if locked {
+			h.recordLoginAttempt(c, req.TenDangNhap, user.MaUser, loginLocked)
+			tooManyLoginAttempts(c, wait, "Account is temporarily locked after too many failed logins")
+		} else {
+			h.recordLoginAttempt(c, req.TenDangNhap, user.MaUser, loginThrottled)
+			tooManyLoginAttempts(c, wait, "Too many failed logins, please wait before trying again")
+		}
+		return
+	}
+
 	if !utils.CheckPasswordHash(req.MatKhau, user.MatKhau) {
+		h.recordLoginAttempt(c, req.TenDangNhap, user.MaUser, loginBadPassword)
+		if err := h.registerLoginFailure(c, user.MaUser); err != nil {
+			log.Printf("login failure for %s not counted: %v", user.MaUser, err)
+		}
 		c.JSON(http.StatusUnauthorized, models.APIResponse{
 			Success: false,
 			Message: "Invalid credentials",
@@ -65,21 +120,92 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.MaUser, user.TenDangNhap, user.Role, h.jwtSecret)
+	// Users with 2FA, or whose role requires it, get a challenge token and
+	// finish with VerifyTwoFactor or the enrolment endpoints.
+	if twoFactorEnabled || h.twoFactorRequired(user.Role) {
+		purpose := challengeVerify
+		message := "Enter the code from your authenticator app"
+		if !twoFactorEnabled {
+			purpose = challengeEnroll
+			message = "Two-factor authentication is required for your role, please set it up"
+		}
+		challenge, err := middleware.GenerateChallengeToken(user.MaUser, purpose, challengeTTL, h.signingKeys)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to generate token",
+				Error:   err.Error(),
+			})
+			return
+		}
+		h.recordLoginAttempt(c, req.TenDangNhap, user.MaUser, login2FARequired)
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: message,
+			Data: TwoFactorChallengeResponse{
+				YeuCau2FA:      true,
+				CanDangKy2FA:   !twoFactorEnabled,
+				ChallengeToken: challenge,
+				HetHanSau:      int(challengeTTL / time.Second),
+			},
+		})
+		return
+	}
+
+	h.completeLogin(c, user, nil)
+}
+
+// findLoginUser loads an active user by username or userID together with
+// their lockout state and whether 2FA is enabled.
+func (h *AuthHandler) findLoginUser(column, value string) (models.User, loginAccountState, bool, error) {
+	var user models.User
+	var state loginAccountState
+	var twoFactorEnabled bool
+	query := `SELECT userID, HoTen, SoDienThoai, Email, username, password, status, createdAt, role,
+			  soLanSaiLienTiep, lanSaiCuoi, khoaDenLuc, ISNULL(x.daBat, 0)
+			  FROM [USER] u LEFT JOIN XACTHUC2LOP x ON x.maUser = u.userID
+			  WHERE u.` + column + ` = @p1 AND status = 'ACTIVE'`
+
+	err := h.db.QueryRow(query, value).Scan(
+		&user.MaUser, &user.HoTen, &user.SoDienThoai, &user.Email,
+		&user.TenDangNhap, &user.MatKhau, &user.TrangThai,
+		&user.NgayTao, &user.Role,
+		&state.failures, &state.lastFailure, &state.lockedUntil, &twoFactorEnabled,
+	)
+	return user, state, twoFactorEnabled, err
+}
+
+// completeLogin records a successful login and issues the token pair.
+// recoveryCodes are included when the login also finished 2FA enrolment.
+func (h *AuthHandler) completeLogin(c *gin.Context, user models.User, recoveryCodes []string) {
+	h.recordLoginAttempt(c, user.TenDangNhap, user.MaUser, loginSuccess)
+	if err := h.clearLoginFailures(user.MaUser); err != nil {
+		log.Printf("login failures for %s not cleared: %v", user.MaUser, err)
+	}
+
+	sess, err := h.sessions.Create(user.MaUser, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Failed to generate token",
+			Message: "Failed to create session",
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(user.MaUser, h.jwtSecret)
+	token, refreshToken, err := h.issueTokens(user, sess.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Failed to generate refresh token",
+			Message: "Failed to generate token",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.sessions.SetRefreshToken(sess.ID, refreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create session",
 			Error:   err.Error(),
 		})
 		return
@@ -91,13 +217,26 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		Success: true,
 		Message: "Login successful",
 		Data: models.AuthResponse{
-			Token:        token,
-			RefreshToken: refreshToken,
-			User:         user,
+			Token:         token,
+			RefreshToken:  refreshToken,
+			User:          user,
+			RecoveryCodes: recoveryCodes,
 		},
 	})
 }
 
+func (h *AuthHandler) issueTokens(user models.User, sessionID string) (string, string, error) {
+	token, err := middleware.GenerateToken(user.MaUser, user.TenDangNhap, user.Role, sessionID, h.signingKeys)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := middleware.GenerateRefreshToken(user.MaUser, sessionID, h.sessions.Lifetime(), h.signingKeys)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -156,7 +295,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	userID := utils.GenerateUserID("CUSTOMER")
+	userID, err := utils.GenerateUserID("CUSTOMER")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create user account",
+			Error:   err.Error(),
+		})
+		return
+	}
 
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -217,10 +364,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Registration successful",
-		Data: gin.H{
-			"user_id":  userID,
-			"username": req.TenDangNhap,
-			"name":     req.HoTen,
+		Data: RegisterResponse{
+			UserID:   userID,
+			Username: req.TenDangNhap,
+			Name:     req.HoTen,
 		},
 	})
 }
@@ -253,7 +400,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	}
 
 	var user models.User
-	err := h.db.QueryRow("SELECT MaUser, HoTen, Email, TrangThai FROM [USER] WHERE Email = ? AND TrangThai = 'ACTIVE'", req.Email).Scan(
+	err := h.db.QueryRow("SELECT userID, hoTen, email, status FROM [USER] WHERE email = @p1 AND status = 'ACTIVE'", req.Email).Scan(
 		&user.MaUser, &user.HoTen, &user.Email, &user.TrangThai,
 	)
 
@@ -266,7 +413,16 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	}
 
 	resetCode := utils.GenerateResetCode()
-	resetID := utils.GeneratePasswordResetID()
+	resetID, err := utils.GeneratePasswordResetID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to process request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	expiresAt := time.Now().Add(passwordResetValidity)
 
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -280,8 +436,8 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	defer tx.Rollback()
 
 	_, err = tx.Exec(`
-		UPDATE PASSWORD_RESET SET IsUsed = 1 
-		WHERE UserID = ? AND IsUsed = 0 AND ExpiresAt > GETDATE()
+		UPDATE PASSWORD_RESET SET IsUsed = 1
+		WHERE UserID = @p1 AND IsUsed = 0 AND ExpiresAt > GETDATE()
 	`, user.MaUser)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -293,9 +449,9 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	}
 
 	_, err = tx.Exec(`
-		INSERT INTO PASSWORD_RESET (ID, UserID, Email, ResetCode, IsUsed, ExpiresAt, CreatedAt)
-		VALUES (?, ?, ?, ?, 0, DATEADD(HOUR, 1, GETDATE()), GETDATE())
-	`, resetID, user.MaUser, req.Email, resetCode)
+		INSERT INTO PASSWORD_RESET (ID, UserID, Email, ResetCode, SoLanThu, IsUsed, ExpiresAt, CreatedAt)
+		VALUES (@p1, @p2, @p3, @p4, 0, 0, @p5, GETDATE())
+	`, resetID, user.MaUser, req.Email, hashResetCode(resetID, resetCode), expiresAt)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -315,16 +471,36 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
+	// The code only ever leaves the server by email; the response is the
+	// same whether or not the address belongs to an account.
+	link := h.reset.LinkBaseURL + "/reset-password?token=" +
+		url.QueryEscape(utils.SignToken(h.reset.LinkSecret, passwordResetTokenPrefix+resetID, expiresAt))
+	err = h.notifier.Notify(c.Request.Context(), notification.Message{
+		UserID:   user.MaUser,
+		Template: notification.TemplatePasswordReset,
+		Data: map[string]string{
+			"ma_xac_nhan":  resetCode,
+			"so_phut":      strconv.Itoa(int(passwordResetValidity / time.Minute)),
+			"link_dat_lai": link,
+		},
+		Reference: resetID,
+		Channels:  []string{notification.ChannelEmail},
+	})
+	if err != nil {
+		log.Printf("password reset %s: %v", resetID, err)
+	}
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Password reset code has been sent to your email",
-		Data: gin.H{
-			"reset_code": resetCode,
-			"expires_in": "1 hour",
-		},
+		Message: "If the email exists in our system, a password reset code will be sent",
 	})
 }
 
+// ResetPassword sets a new password from either the emailed code or the
+// reset link. Each code attempt counts against the reset; once
+// MaxAttempts is reached the code stops working and a new one has to be
+// requested. On success every other reset is invalidated and all of the
+// user's existing sessions are revoked.
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req models.ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -336,15 +512,15 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	if req.Email == "" || req.ResetCode == "" || req.NewPassword == "" {
+	if req.NewPassword == "" || (req.Token == "" && (req.Email == "" || req.ResetCode == "")) {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Message: "Email, reset code, and new password are required",
+			Message: "New password and either a reset token or email and reset code are required",
 		})
 		return
 	}
 
-	if !utils.ValidateEmail(req.Email) {
+	if req.Token == "" && !utils.ValidateEmail(req.Email) {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid email format",
@@ -361,19 +537,22 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	var resetRecord models.PasswordReset
-	err := h.db.QueryRow(`
-		SELECT pr.ID, pr.UserID, pr.Email, pr.ResetCode, pr.IsUsed, pr.ExpiresAt, pr.CreatedAt
-		FROM PASSWORD_RESET pr
-		JOIN [USER] u ON pr.UserID = u.MaUser
-		WHERE pr.Email = ? AND pr.ResetCode = ? AND pr.IsUsed = 0 
-		AND pr.ExpiresAt > GETDATE() AND u.TrangThai = 'ACTIVE'
-	`, req.Email, req.ResetCode).Scan(
-		&resetRecord.ID, &resetRecord.UserID, &resetRecord.Email,
-		&resetRecord.ResetCode, &resetRecord.IsUsed, &resetRecord.ExpiresAt,
-		&resetRecord.CreatedAt,
-	)
-
+	var ok bool
+	var err error
+	if req.Token != "" {
+		resetRecord, ok, err = h.resetFromToken(req.Token)
+	} else {
+		resetRecord, ok, err = h.resetFromCode(req.Email, req.ResetCode)
+	}
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to verify reset code",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !ok {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid or expired reset code",
@@ -402,21 +581,56 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("UPDATE [USER] SET MatKhau = ? WHERE MaUser = ?", newHash, resetRecord.UserID)
+	// Marking the reset used first makes it single-use even when the same
+	// code or link is submitted twice at once.
+	result, err := tx.Exec("UPDATE PASSWORD_RESET SET IsUsed = 1 WHERE ID = @p1 AND IsUsed = 0", resetRecord.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Failed to update password",
+			Message: "Failed to mark reset code as used",
 			Error:   err.Error(),
 		})
 		return
 	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid or expired reset code",
+		})
+		return
+	}
 
-	_, err = tx.Exec("UPDATE PASSWORD_RESET SET IsUsed = 1 WHERE ID = ?", resetRecord.ID)
+	_, err = tx.Exec("UPDATE PASSWORD_RESET SET IsUsed = 1 WHERE UserID = @p1 AND IsUsed = 0", resetRecord.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Failed to mark reset code as used",
+			Message: "Failed to invalidate other reset codes",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Proving control of the mailbox also lifts a login lockout.
+	_, err = tx.Exec(`
+		UPDATE [USER] SET password = @p1,
+			soLanSaiLienTiep = 0, lanSaiCuoi = NULL, khoaDenLuc = NULL
+		WHERE userID = @p2
+	`, newHash, resetRecord.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update password",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Whoever knew the old password may still hold a token. The sessions
+	// end with the same commit, so the password never changes without it.
+	if _, err = h.sessions.RevokeUserTx(tx, resetRecord.UserID, "", session.RevokedPasswordReset); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to revoke existing sessions",
 			Error:   err.Error(),
 		})
 		return
@@ -437,42 +651,195 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	})
 }
 
+// resetFromCode finds the user's current reset and checks the code against
+// it. The attempt is counted before comparing, so concurrent guesses cannot
+// exceed the cap; after MaxAttempts the reset accepts no more codes.
+func (h *AuthHandler) resetFromCode(email, code string) (models.PasswordReset, bool, error) {
+	var r models.PasswordReset
+	err := h.db.QueryRow(`
+		SELECT TOP 1 pr.ID, pr.UserID, pr.ResetCode
+		FROM PASSWORD_RESET pr
+		JOIN [USER] u ON pr.UserID = u.userID
+		WHERE pr.Email = @p1 AND pr.IsUsed = 0 AND pr.ExpiresAt > GETDATE() AND u.status = 'ACTIVE'
+		ORDER BY pr.CreatedAt DESC
+	`, email).Scan(&r.ID, &r.UserID, &r.ResetCode)
+	if err == sql.ErrNoRows {
+		return r, false, nil
+	}
+	if err != nil {
+		return r, false, err
+	}
+
+	err = h.db.QueryRow(`
+		UPDATE PASSWORD_RESET SET SoLanThu = SoLanThu + 1
+		OUTPUT INSERTED.SoLanThu
+		WHERE ID = @p1 AND IsUsed = 0 AND SoLanThu < @p2
+	`, r.ID, h.reset.MaxAttempts).Scan(&r.Attempts)
+	if err == sql.ErrNoRows {
+		return r, false, nil
+	}
+	if err != nil {
+		return r, false, err
+	}
+
+	match := subtle.ConstantTimeCompare([]byte(hashResetCode(r.ID, code)), []byte(r.ResetCode)) == 1
+	return r, match, nil
+}
+
+func (h *AuthHandler) resetFromToken(token string) (models.PasswordReset, bool, error) {
+	var r models.PasswordReset
+	payload, err := utils.VerifyToken(h.reset.LinkSecret, token)
+	if err != nil || !strings.HasPrefix(payload, passwordResetTokenPrefix) {
+		return r, false, nil
+	}
+
+	err = h.db.QueryRow(`
+		SELECT pr.ID, pr.UserID
+		FROM PASSWORD_RESET pr
+		JOIN [USER] u ON pr.UserID = u.userID
+		WHERE pr.ID = @p1 AND pr.IsUsed = 0 AND pr.ExpiresAt > GETDATE() AND u.status = 'ACTIVE'
+	`, strings.TrimPrefix(payload, passwordResetTokenPrefix)).Scan(&r.ID, &r.UserID)
+	if err == sql.ErrNoRows {
+		return r, false, nil
+	}
+	return r, err == nil, err
+}
+
+// hashResetCode binds the code to its reset so equal codes of different
+// resets do not share a hash.
+func hashResetCode(resetID, code string) string {
+	sum := sha256.Sum256([]byte(resetID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Each
+// refresh token works once; presenting an already used one revokes the
+// session, since it means the token was copied.
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, models.APIResponse{
-		Success: false,
-		Message: "Refresh token not implemented yet",
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	userID, sessionID, ok := middleware.ParseRefreshToken(req.RefreshToken, h.signingKeys)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "Invalid refresh token",
+		})
+		return
+	}
+
+	user, _, _, err := h.findLoginUser("userID", userID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "Invalid refresh token",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to refresh token",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	token, refreshToken, err := h.issueTokens(user, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to generate token",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rotated, err := h.sessions.RotateRefreshToken(sessionID, userID, req.RefreshToken, refreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to refresh token",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !rotated {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "Session has been revoked, please log in again",
+		})
+		return
+	}
+
+	user.MatKhau = ""
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Token refreshed successfully",
+		Data: models.AuthResponse{
+			Token:        token,
+			RefreshToken: refreshToken,
+			User:         user,
+		},
+	})
+}
+
+// Logout ends the session of the token used for the request.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	if _, err := h.sessions.Revoke(sessionID.(string), userID.(string), session.RevokedLogout); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to log out",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged out successfully",
 	})
 }
 
 func (h *AuthHandler) getUserType(userID string) string {
 	var exists bool
 
-	h.db.QueryRow("SELECT 1 FROM CUSTOMER WHERE MaUser = ?", userID).Scan(&exists)
+	h.db.QueryRow("SELECT 1 FROM CUSTOMER WHERE maUser = @p1", userID).Scan(&exists)
 	if exists {
 		return "CUSTOMER"
 	}
 
-	h.db.QueryRow("SELECT 1 FROM BACSI WHERE MaUser = ?", userID).Scan(&exists)
+	h.db.QueryRow("SELECT 1 FROM BACSI WHERE maUser = @p1", userID).Scan(&exists)
 	if exists {
 		return "DOCTOR"
 	}
 
-	h.db.QueryRow("SELECT 1 FROM LETAN WHERE MaUser = ?", userID).Scan(&exists)
+	h.db.QueryRow("SELECT 1 FROM LETAN WHERE maUser = @p1", userID).Scan(&exists)
 	if exists {
 		return "RECEPTIONIST"
 	}
 
-	h.db.QueryRow("SELECT 1 FROM KETOAN WHERE MaUser = ?", userID).Scan(&exists)
+	h.db.QueryRow("SELECT 1 FROM KETOAN WHERE maUser = @p1", userID).Scan(&exists)
 	if exists {
 		return "ACCOUNTANT"
 	}
 
-	h.db.QueryRow("SELECT 1 FROM QUANLYPHONGKHAM WHERE MaUser = ?", userID).Scan(&exists)
+	h.db.QueryRow("SELECT 1 FROM QUANLYPHONGKHAM WHERE maUser = @p1", userID).Scan(&exists)
 	if exists {
 		return "CLINIC_MANAGER"
 	}
 
-	h.db.QueryRow("SELECT 1 FROM BANDIEUHANH WHERE MaUser = ?", userID).Scan(&exists)
+	h.db.QueryRow("SELECT 1 FROM BANDIEUHANH WHERE maUser = @p1", userID).Scan(&exists)
 	if exists {
 		return "OPERATION_MANAGER"
 	}
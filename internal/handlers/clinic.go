@@ -190,10 +190,10 @@ func (h *ClinicHandler) GetDoctors(c *gin.Context) {
 	// Sort previously visited doctors first, then other doctors
 	sortedDoctors := append(previouslyVisitedDoctors, otherDoctors...)
 
-	response := map[string]interface{}{
-		"all_doctors": sortedDoctors,
-		"previously_visited": previouslyVisitedDoctors,
-		"other_doctors": otherDoctors,
+	response := ClinicDoctorsResponse{
+		AllDoctors:        sortedDoctors,
+		PreviouslyVisited: previouslyVisitedDoctors,
+		OtherDoctors:      otherDoctors,
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -279,7 +279,7 @@ func (h *ClinicHandler) GetSchedules(c *gin.Context) {
 			c.JSON(http.StatusOK, models.APIResponse{
 				Success: true,
 				Message: "No work schedule found for this date",
-				Data:    []string{},
+				Data:    ClinicSlotsResponse{AvailableSlots: []string{}, BookedTimes: []string{}},
 			})
 		} else {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -329,13 +329,10 @@ func (h *ClinicHandler) GetSchedules(c *gin.Context) {
 
 	log.Printf("Generated %d available slots: %v", len(availableSlots), availableSlots)
 
-	response := map[string]interface{}{
-		"work_schedule": map[string]string{
-			"start_time": startTime,
-			"end_time":   endTime,
-		},
-		"available_slots": availableSlots,
-		"booked_times":    bookedTimes,
+	response := ClinicSlotsResponse{
+		WorkSchedule:   &WorkHoursResponse{StartTime: startTime, EndTime: endTime},
+		AvailableSlots: availableSlots,
+		BookedTimes:    bookedTimes,
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"clinic-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// csvFile reads a CSV file whose first row names the columns. Rows are
+// read like sql.Rows: call next until it returns false, then check err.
+// Problems with single rows are collected with fail, so the whole file can
+// be fixed in one pass.
+type csvFile struct {
+	reader   *csv.Reader
+	columns  map[string]int
+	record   []string
+	line     int
+	err      error
+	problems []string
+}
+
+// readCSV reads the header row of r and checks that the required columns
+// are present. Column names are matched case-insensitively.
+func readCSV(r io.Reader, required ...string) (*csvFile, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+	return &csvFile{reader: reader, columns: columns, line: 1}, nil
+}
+
+// next moves to the next row. It returns false at the end of the file or
+// when the file cannot be read, which err reports.
+func (f *csvFile) next() bool {
+	record, err := f.reader.Read()
+	f.line++
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		f.err = err
+		return false
+	}
+	f.record = record
+	return true
+}
+
+// field returns a column of the current row, trimmed; empty when the
+// column or the cell is missing.
+func (f *csvFile) field(name string) string {
+	if i, ok := f.columns[name]; ok && i < len(f.record) {
+		return strings.TrimSpace(f.record[i])
+	}
+	return ""
+}
+
+// fail records a problem with the current row.
+func (f *csvFile) fail(format string, args ...interface{}) {
+	f.problems = append(f.problems, fmt.Sprintf("line %d: ", f.line)+fmt.Sprintf(format, args...))
+}
+
+// uploadedCSV parses the CSV file uploaded in the "file" form field. It
+// writes a 400 response and returns false when the file is missing,
+// unreadable or has invalid rows.
+func uploadedCSV[T any](c *gin.Context, parse func(io.Reader) ([]T, []string, error)) ([]T, bool) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "CSV file is required in the 'file' field",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to open uploaded file",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+	defer file.Close()
+
+	items, rowErrors, err := parse(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid CSV file",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "CSV file contains invalid rows",
+			Error:   strings.Join(rowErrors, "; "),
+		})
+		return nil, false
+	}
+	return items, true
+}
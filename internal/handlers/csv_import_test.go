@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseInteractionCSV(t *testing.T) {
+	rules, problems, err := parseInteractionCSV(strings.NewReader(
+		"\ufeffIngredient_A,ingredient_b,severity,description\n" +
+			"Warfarin,Aspirin,major,Bleeding\n" +
+			"aspirin,warfarin,MAJOR,Duplicate\n" +
+			"Ibuprofen,Ibuprofen,MINOR,\n" +
+			"Paracetamol,Alcohol,sometimes,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].MucDo != "MAJOR" || rules[0].MoTa != "Bleeding" {
+		t.Errorf("rules = %+v", rules)
+	}
+	want := []string{"line 3: duplicate pair", "line 4: two different", "line 5: invalid severity"}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %q", problems)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(problems[i], prefix) {
+			t.Errorf("problem %d = %q, want %s...", i, problems[i], prefix)
+		}
+	}
+
+	if _, _, err := parseInteractionCSV(strings.NewReader("ingredient_a,severity\n")); err == nil {
+		t.Error("missing ingredient_b column accepted")
+	}
+}
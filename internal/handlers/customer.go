@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
 	"net/http"
 
 	"clinic-management/internal/models"
+	"clinic-management/internal/services"
 	"clinic-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -18,10 +20,35 @@ func NewCustomerHandler(db *sql.DB) *CustomerHandler {
 	return &CustomerHandler{db: db}
 }
 
-// GetCustomers - Get all customers for receptionist
+var customerList = listSpec{
+	sorts: map[string]services.SortKey{
+		"ngay_dang_ky": {Expr: "ISNULL(c.createdAt, '1900-01-01')", Kind: services.SortTime},
+		"ho_ten":       {Expr: "u.hoTen", Kind: services.SortText},
+	},
+	defaultSort: "-ngay_dang_ky",
+	idField:     "user_id",
+	idColumn:    "u.userID",
+}
+
+// CreateCustomerRequest is the body of POST /customers.
+type CreateCustomerRequest struct {
+	HoTen       string `json:"ho_ten" binding:"required"`
+	TenDangNhap string `json:"ten_dang_nhap" binding:"required"`
+	MatKhau     string `json:"mat_khau" binding:"required"`
+	SoDienThoai string `json:"so_dien_thoai"`
+	Email       string `json:"email"`
+	NgaySinh    string `json:"ngay_sinh"`
+	GioiTinh    string `json:"gioi_tinh"`
+	DiaChi      string `json:"dia_chi"`
+	MaBaoHiem   string `json:"ma_bao_hiem"`
+}
+
+// GetCustomers - Get customers for receptionist, one page at a time.
+// Filters are search (name, ID or phone), gioi_tinh and date_from/date_to
+// on the registration date.
 func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 	userRole, _ := c.Get("user_type")
-	
+
 	// Only allow receptionist and admin to view customers
 	if userRole != "RECEPTIONIST" && userRole != "ADMIN" {
 		c.JSON(http.StatusForbidden, models.APIResponse{
@@ -31,26 +58,44 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 		return
 	}
 
-	search := c.Query("search")
-	var query string
-	var args []interface{}
+	params, ok := customerList.parse(c)
+	if !ok {
+		return
+	}
 
-	query = `
-		SELECT u.userID, u.hoTen, u.soDienThoai, u.email, u.status,
-		       c.ngaySinh, c.gioiTinh, c.diaChi, c.createdAt, c.maBaoHiem
+	columns := `
+		u.userID, u.hoTen, u.soDienThoai, u.email, u.status,
+		c.ngaySinh, c.gioiTinh, c.diaChi, c.createdAt, c.maBaoHiem
+	`
+	from := `
 		FROM [USER] u
 		JOIN CUSTOMER c ON u.userID = c.maUser
 		WHERE u.role = 'CUSTOMER' AND u.status = 'ACTIVE'
 	`
+	var args []interface{}
 
-	if search != "" {
-		query += " AND (u.hoTen LIKE @p1 OR u.userID LIKE @p1 OR u.soDienThoai LIKE @p1)"
+	if search := c.Query("search"); search != "" {
+		from += " AND (u.hoTen LIKE @p1 OR u.userID LIKE @p1 OR u.soDienThoai LIKE @p1)"
 		args = append(args, "%"+search+"%")
 	}
+	if gender := c.Query("gioi_tinh"); gender != "" {
+		from += fmt.Sprintf(" AND c.gioiTinh = @p%d", len(args)+1)
+		args = append(args, gender)
+	}
+	dates, args, ok := dateRangeFilter(c, "c.createdAt", args)
+	if !ok {
+		return
+	}
+	from += dates
 
-	query += " ORDER BY c.createdAt DESC"
+	query, pageArgs, countQuery := customerList.queries(columns, from, args, params)
 
-	rows, err := h.db.Query(query, args...)
+	total, ok := countList(c, h.db, countQuery, args, params, "Failed to count customers")
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(query, pageArgs...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -61,14 +106,14 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var customers []map[string]interface{}
+	var customers []CustomerResponse
 	for rows.Next() {
-		var customer map[string]interface{} = make(map[string]interface{})
-		var userID, hoTen, soDienThoai, email, status string
-		var ngaySinh, gioiTinh, diaChi, createdAt, maBaoHiem sql.NullString
+		var customer CustomerResponse
+		var soDienThoai, email, gioiTinh, diaChi, maBaoHiem sql.NullString
+		var ngaySinh, ngayDangKy sql.NullTime
 
-		err := rows.Scan(&userID, &hoTen, &soDienThoai, &email, &status,
-			&ngaySinh, &gioiTinh, &diaChi, &createdAt, &maBaoHiem)
+		err := rows.Scan(&customer.UserID, &customer.HoTen, &soDienThoai, &email, &customer.Status,
+			&ngaySinh, &gioiTinh, &diaChi, &ngayDangKy, &maBaoHiem)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -78,35 +123,17 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 			return
 		}
 
-		customer["user_id"] = userID
-		customer["ho_ten"] = hoTen
-		customer["so_dien_thoai"] = soDienThoai
-		customer["email"] = email
-		customer["status"] = status
-		if ngaySinh.Valid {
-			customer["ngay_sinh"] = ngaySinh.String
-		}
-		if gioiTinh.Valid {
-			customer["gioi_tinh"] = gioiTinh.String
-		}
-		if diaChi.Valid {
-			customer["dia_chi"] = diaChi.String
-		}
-		if createdAt.Valid {
-			customer["ngay_dang_ky"] = createdAt.String
-		}
-		if maBaoHiem.Valid {
-			customer["ma_bao_hiem"] = maBaoHiem.String
-		}
-
+		customer.SoDienThoai = stringPointer(soDienThoai)
+		customer.Email = stringPointer(email)
+		customer.NgaySinh = timePointer(ngaySinh)
+		customer.GioiTinh = stringPointer(gioiTinh)
+		customer.DiaChi = stringPointer(diaChi)
+		customer.NgayDangKy = timePointer(ngayDangKy)
+		customer.MaBaoHiem = stringPointer(maBaoHiem)
 		customers = append(customers, customer)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Customers retrieved successfully",
-		Data:    customers,
-	})
+	respondList(c, customerList, "Customers retrieved successfully", customers, params, total)
 }
 
 // GetCustomer - Get customer details by ID
@@ -132,13 +159,13 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 		WHERE u.userID = @p1
 	`
 
-	var customer map[string]interface{} = make(map[string]interface{})
-	var userID, hoTen, soDienThoai, email, status string
-	var ngaySinh, gioiTinh, diaChi, createdAt, maBaoHiem sql.NullString
+	var customer CustomerResponse
+	var soDienThoai, email, gioiTinh, diaChi, maBaoHiem sql.NullString
+	var ngaySinh, ngayDangKy sql.NullTime
 
 	err := h.db.QueryRow(query, customerID).Scan(
-		&userID, &hoTen, &soDienThoai, &email, &status,
-		&ngaySinh, &gioiTinh, &diaChi, &createdAt, &maBaoHiem,
+		&customer.UserID, &customer.HoTen, &soDienThoai, &email, &customer.Status,
+		&ngaySinh, &gioiTinh, &diaChi, &ngayDangKy, &maBaoHiem,
 	)
 
 	if err != nil {
@@ -157,26 +184,13 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 		return
 	}
 
-	customer["user_id"] = userID
-	customer["ho_ten"] = hoTen
-	customer["so_dien_thoai"] = soDienThoai
-	customer["email"] = email
-	customer["status"] = status
-	if ngaySinh.Valid {
-		customer["ngay_sinh"] = ngaySinh.String
-	}
-	if gioiTinh.Valid {
-		customer["gioi_tinh"] = gioiTinh.String
-	}
-	if diaChi.Valid {
-		customer["dia_chi"] = diaChi.String
-	}
-	if createdAt.Valid {
-		customer["ngay_dang_ky"] = createdAt.String
-	}
-	if maBaoHiem.Valid {
-		customer["ma_bao_hiem"] = maBaoHiem.String
-	}
+	customer.SoDienThoai = stringPointer(soDienThoai)
+	customer.Email = stringPointer(email)
+	customer.NgaySinh = timePointer(ngaySinh)
+	customer.GioiTinh = stringPointer(gioiTinh)
+	customer.DiaChi = stringPointer(diaChi)
+	customer.NgayDangKy = timePointer(ngayDangKy)
+	customer.MaBaoHiem = stringPointer(maBaoHiem)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -198,17 +212,7 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		HoTen        string `json:"ho_ten" binding:"required"`
-		TenDangNhap  string `json:"ten_dang_nhap" binding:"required"`
-		MatKhau      string `json:"mat_khau" binding:"required"`
-		SoDienThoai  string `json:"so_dien_thoai"`
-		Email        string `json:"email"`
-		NgaySinh     string `json:"ngay_sinh"`
-		GioiTinh     string `json:"gioi_tinh"`
-		DiaChi       string `json:"dia_chi"`
-		MaBaoHiem    string `json:"ma_bao_hiem"`
-	}
+	var req CreateCustomerRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
@@ -251,7 +255,15 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 	}
 
 	// Generate IDs
-	userID := utils.GenerateCustomerID()
+	userID, err := utils.GenerateCustomerID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create customer",
+			Error:   err.Error(),
+		})
+		return
+	}
 	
 	// Start transaction
 	tx, err := h.db.Begin()
@@ -308,9 +320,9 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Customer created successfully",
-		Data: map[string]interface{}{
-			"user_id": userID,
-			"ho_ten":  req.HoTen,
+		Data: CreatedCustomerResponse{
+			UserID: userID,
+			HoTen:  req.HoTen,
 		},
 	})
 }
\ No newline at end of file
@@ -0,0 +1,1126 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClinicRequest struct {
+	TenPhongKham string `json:"ten_phong_kham" binding:"required"`
+	DiaChi       string `json:"dia_chi"`
+	SoDienThoai  string `json:"so_dien_thoai"`
+	Email        string `json:"email"`
+}
+
+type UpdateClinicRequest struct {
+	TenPhongKham *string `json:"ten_phong_kham"`
+	DiaChi       *string `json:"dia_chi"`
+	SoDienThoai  *string `json:"so_dien_thoai"`
+	Email        *string `json:"email"`
+}
+
+// OpeningHoursRequest replaces a clinic's weekly opening hours. Days that
+// are left out have no restriction.
+type OpeningHoursRequest struct {
+	Ngay []models.OpeningHours `json:"ngay" binding:"dive"`
+}
+
+type RoomRequest struct {
+	TenPhong  string `json:"ten_phong" binding:"required"`
+	LoaiPhong string `json:"loai_phong" binding:"omitempty,oneof=EXAM LAB IMAGING PROCEDURE PHARMACY OTHER"`
+	SucChua   *int   `json:"suc_chua" binding:"omitempty,min=1"`
+	GhiChu    string `json:"ghi_chu"`
+}
+
+type UpdateRoomRequest struct {
+	TenPhong  *string `json:"ten_phong"`
+	LoaiPhong *string `json:"loai_phong" binding:"omitempty,oneof=EXAM LAB IMAGING PROCEDURE PHARMACY OTHER"`
+	SucChua   *int    `json:"suc_chua" binding:"omitempty,min=1"`
+	TrangThai *string `json:"trang_thai" binding:"omitempty,oneof=ACTIVE MAINTENANCE CLOSED"`
+	GhiChu    *string `json:"ghi_chu"`
+}
+
+type EquipmentRequest struct {
+	TenThietBi     string `json:"ten_thiet_bi" binding:"required"`
+	LoaiThietBi    string `json:"loai_thiet_bi"`
+	SoSeri         string `json:"so_seri"`
+	MaPhong        string `json:"ma_phong"`
+	TrangThai      string `json:"trang_thai" binding:"omitempty,oneof=IN_USE MAINTENANCE BROKEN RETIRED"`
+	NgayMua        string `json:"ngay_mua"`          // YYYY-MM-DD
+	NgayBaoTriTiep string `json:"ngay_bao_tri_tiep"` // YYYY-MM-DD
+	GhiChu         string `json:"ghi_chu"`
+}
+
+type UpdateEquipmentRequest struct {
+	TenThietBi     *string `json:"ten_thiet_bi"`
+	LoaiThietBi    *string `json:"loai_thiet_bi"`
+	SoSeri         *string `json:"so_seri"`
+	MaPhong        *string `json:"ma_phong"` // empty to take the equipment out of its room
+	TrangThai      *string `json:"trang_thai" binding:"omitempty,oneof=IN_USE MAINTENANCE BROKEN RETIRED"`
+	NgayMua        *string `json:"ngay_mua"`
+	NgayBaoTriTiep *string `json:"ngay_bao_tri_tiep"`
+	GhiChu         *string `json:"ghi_chu"`
+}
+
+func (h *ClinicHandler) CreateClinic(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can create clinics") {
+		return
+	}
+
+	var req ClinicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.TenPhongKham)
+	if !validateClinicContact(c, req.SoDienThoai, req.Email) {
+		return
+	}
+	if h.clinicNameTaken(c, name, "") {
+		return
+	}
+
+	clinicID, err := utils.GenerateClinicID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create clinic",
+			Error:   err.Error(),
+		})
+		return
+	}
+	_, err = h.db.Exec(`
+		INSERT INTO PHONGKHAM (maPhongKham, tenPhongKham, diaChi, soDienThoai, email)
+		VALUES (@p1, @p2, @p3, @p4, @p5)
+	`, clinicID, name, services.NullIfEmpty(req.DiaChi), services.NullIfEmpty(req.SoDienThoai), services.NullIfEmpty(req.Email))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create clinic",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Clinic created successfully",
+		Data:    CreatedClinicResponse{MaPhongKham: clinicID},
+	})
+}
+
+func (h *ClinicHandler) UpdateClinic(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can edit clinics") {
+		return
+	}
+
+	clinicID := c.Param("id")
+	if !h.clinicExists(c, clinicID) {
+		return
+	}
+
+	var req UpdateClinicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var columns []string
+	var values []interface{}
+	if req.TenPhongKham != nil {
+		name := strings.TrimSpace(*req.TenPhongKham)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Clinic name cannot be empty",
+			})
+			return
+		}
+		if h.clinicNameTaken(c, name, clinicID) {
+			return
+		}
+		columns = append(columns, "tenPhongKham")
+		values = append(values, name)
+	}
+	if req.DiaChi != nil {
+		columns = append(columns, "diaChi")
+		values = append(values, services.NullIfEmpty(*req.DiaChi))
+	}
+	if req.SoDienThoai != nil {
+		if !validateClinicContact(c, *req.SoDienThoai, "") {
+			return
+		}
+		columns = append(columns, "soDienThoai")
+		values = append(values, services.NullIfEmpty(*req.SoDienThoai))
+	}
+	if req.Email != nil {
+		if !validateClinicContact(c, "", *req.Email) {
+			return
+		}
+		columns = append(columns, "email")
+		values = append(values, services.NullIfEmpty(*req.Email))
+	}
+	if len(columns) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := updateColumns(tx, "PHONGKHAM", "maPhongKham", clinicID, columns, values); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update clinic",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update clinic",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Clinic updated successfully",
+	})
+}
+
+func (h *ClinicHandler) GetOpeningHours(c *gin.Context) {
+	clinicID := c.Param("id")
+	if !h.clinicExists(c, clinicID) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT thu, CONVERT(VARCHAR(5), gioMo, 108), CONVERT(VARCHAR(5), gioDong, 108), nghi
+		FROM GIOMOCUA WHERE maPhongKham = @p1
+		ORDER BY thu
+	`, clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve opening hours",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	hours := []models.OpeningHours{}
+	for rows.Next() {
+		var day models.OpeningHours
+		var opens, closes sql.NullString
+		if err := rows.Scan(&day.Thu, &opens, &closes, &day.Nghi); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan opening hours",
+				Error:   err.Error(),
+			})
+			return
+		}
+		if opens.Valid {
+			day.GioMo = &opens.String
+		}
+		if closes.Valid {
+			day.GioDong = &closes.String
+		}
+		hours = append(hours, day)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Opening hours retrieved successfully",
+		Data:    hours,
+	})
+}
+
+func (h *ClinicHandler) SetOpeningHours(c *gin.Context) {
+	clinicID := c.Param("id")
+	if !h.canManageClinic(c, clinicID) {
+		return
+	}
+
+	var req OpeningHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	seen := map[int]bool{}
+	for i, day := range req.Ngay {
+		if seen[day.Thu] {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Day %d is listed more than once", day.Thu),
+			})
+			return
+		}
+		seen[day.Thu] = true
+
+		if day.Nghi {
+			req.Ngay[i].GioMo, req.Ngay[i].GioDong = nil, nil
+			continue
+		}
+		if day.GioMo == nil || day.GioDong == nil || !isValidTimeFormat(*day.GioMo) || !isValidTimeFormat(*day.GioDong) {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Day %d needs gio_mo and gio_dong in HH:MM format unless nghi is set", day.Thu),
+			})
+			return
+		}
+		if clockMinutes(*day.GioMo) >= clockMinutes(*day.GioDong) {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Day %d closes before it opens", day.Thu),
+			})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM GIOMOCUA WHERE maPhongKham = @p1", clinicID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update opening hours",
+			Error:   err.Error(),
+		})
+		return
+	}
+	for _, day := range req.Ngay {
+		_, err := tx.Exec(`
+			INSERT INTO GIOMOCUA (maPhongKham, thu, gioMo, gioDong, nghi)
+			VALUES (@p1, @p2, @p3, @p4, @p5)
+		`, clinicID, day.Thu, day.GioMo, day.GioDong, day.Nghi)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to update opening hours",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update opening hours",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Opening hours updated successfully",
+	})
+}
+
+func (h *ClinicHandler) GetRooms(c *gin.Context) {
+	clinicID := c.Param("id")
+	if !h.clinicExists(c, clinicID) {
+		return
+	}
+
+	query := `
+		SELECT maPhong, maPhongKham, tenPhong, loaiPhong, sucChua, trangThai, ghiChu
+		FROM PHONG WHERE maPhongKham = @p1
+	`
+	args := []interface{}{clinicID}
+	if status := c.Query("trang_thai"); status != "" {
+		query += fmt.Sprintf(" AND trangThai = @p%d", len(args)+1)
+		args = append(args, status)
+	}
+	if roomType := c.Query("loai_phong"); roomType != "" {
+		query += fmt.Sprintf(" AND loaiPhong = @p%d", len(args)+1)
+		args = append(args, roomType)
+	}
+	query += " ORDER BY tenPhong"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve rooms",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	rooms := []models.Room{}
+	for rows.Next() {
+		var room models.Room
+		if err := rows.Scan(&room.MaPhong, &room.MaPhongKham, &room.TenPhong, &room.LoaiPhong,
+			&room.SucChua, &room.TrangThai, &room.GhiChu); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan room data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		rooms = append(rooms, room)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Rooms retrieved successfully",
+		Data:    rooms,
+	})
+}
+
+func (h *ClinicHandler) CreateRoom(c *gin.Context) {
+	clinicID := c.Param("id")
+	if !h.canManageClinic(c, clinicID) {
+		return
+	}
+
+	var req RoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.TenPhong)
+	if h.roomNameTaken(c, clinicID, name, "") {
+		return
+	}
+	if req.LoaiPhong == "" {
+		req.LoaiPhong = "EXAM"
+	}
+
+	roomID, err := utils.GenerateRoomID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create room",
+			Error:   err.Error(),
+		})
+		return
+	}
+	_, err = h.db.Exec(`
+		INSERT INTO PHONG (maPhong, maPhongKham, tenPhong, loaiPhong, sucChua, trangThai, ghiChu, ngayTao)
+		VALUES (@p1, @p2, @p3, @p4, @p5, 'ACTIVE', @p6, GETDATE())
+	`, roomID, clinicID, name, req.LoaiPhong, req.SucChua, services.NullIfEmpty(req.GhiChu))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create room",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Room created successfully",
+		Data:    CreatedRoomResponse{MaPhong: roomID},
+	})
+}
+
+// UpdateRoom edits a room. A room with upcoming schedules or appointments
+// cannot be taken out of service until they are moved elsewhere.
+func (h *ClinicHandler) UpdateRoom(c *gin.Context) {
+	clinicID := c.Param("id")
+	roomID := c.Param("room_id")
+	if !h.canManageClinic(c, clinicID) || !h.roomExists(c, clinicID, roomID) {
+		return
+	}
+
+	var req UpdateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var columns []string
+	var values []interface{}
+	if req.TenPhong != nil {
+		name := strings.TrimSpace(*req.TenPhong)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Room name cannot be empty",
+			})
+			return
+		}
+		if h.roomNameTaken(c, clinicID, name, roomID) {
+			return
+		}
+		columns = append(columns, "tenPhong")
+		values = append(values, name)
+	}
+	if req.LoaiPhong != nil {
+		columns = append(columns, "loaiPhong")
+		values = append(values, *req.LoaiPhong)
+	}
+	if req.SucChua != nil {
+		columns = append(columns, "sucChua")
+		values = append(values, *req.SucChua)
+	}
+	if req.GhiChu != nil {
+		columns = append(columns, "ghiChu")
+		values = append(values, services.NullIfEmpty(*req.GhiChu))
+	}
+	if req.TrangThai != nil {
+		if *req.TrangThai != "ACTIVE" {
+			var schedules, appointments int
+			err := h.db.QueryRow(`
+				SELECT
+					(SELECT COUNT(*) FROM LICHLAMVIEC WHERE maPhong = @p1 AND ngayLamViec >= @p2),
+					(SELECT COUNT(*) FROM LICHKHAM WHERE maPhong = @p1 AND ngayGioKham >= GETDATE()
+					   AND trangThai NOT IN ('CANCELLED', 'COMPLETED', 'NO_SHOW'))
+			`, roomID, today()).Scan(&schedules, &appointments)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success: false,
+					Message: "Failed to check room usage",
+					Error:   err.Error(),
+				})
+				return
+			}
+			if schedules > 0 || appointments > 0 {
+				c.JSON(http.StatusConflict, models.APIResponse{
+					Success: false,
+					Message: fmt.Sprintf("Room has %d upcoming schedules and %d upcoming appointments; move them to another room first", schedules, appointments),
+				})
+				return
+			}
+		}
+		columns = append(columns, "trangThai")
+		values = append(values, *req.TrangThai)
+	}
+	if len(columns) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := updateColumns(tx, "PHONG", "maPhong", roomID, columns, values); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update room",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update room",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Room updated successfully",
+	})
+}
+
+// GetRoomUsage lists the schedules and appointments booked in a room on
+// one day (ngay, default today).
+func (h *ClinicHandler) GetRoomUsage(c *gin.Context) {
+	clinicID := c.Param("id")
+	roomID := c.Param("room_id")
+	if !isStaff(c) || !h.roomExists(c, clinicID, roomID) {
+		return
+	}
+
+	day := today()
+	if ngay := c.Query("ngay"); ngay != "" {
+		parsed, err := time.Parse("2006-01-02", ngay)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid date format. Use YYYY-MM-DD",
+			})
+			return
+		}
+		day = parsed
+	}
+
+	rows, err := h.db.Query(`
+		SELECT ll.maLichLamViec, ll.maBacSi, u.hoTen,
+		       CONVERT(VARCHAR(5), ll.gioBatDau, 108), CONVERT(VARCHAR(5), ll.gioKetThuc, 108), ll.status
+		FROM LICHLAMVIEC ll
+		JOIN [USER] u ON ll.maBacSi = u.userID
+		WHERE ll.maPhong = @p1 AND ll.ngayLamViec = @p2
+		ORDER BY ll.gioBatDau
+	`, roomID, day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve room schedules",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	schedules := []RoomScheduleResponse{}
+	for rows.Next() {
+		var scheduleID, doctorID, start, end, status string
+		var doctorName sql.NullString
+		if err := rows.Scan(&scheduleID, &doctorID, &doctorName, &start, &end, &status); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan room schedules",
+				Error:   err.Error(),
+			})
+			return
+		}
+		schedules = append(schedules, RoomScheduleResponse{
+			MaLichLamViec: scheduleID,
+			MaBacSi:       doctorID,
+			TenBacSi:      doctorName.String,
+			GioBatDau:     start,
+			GioKetThuc:    end,
+			Status:        status,
+		})
+	}
+	rows.Close()
+
+	rows, err = h.db.Query(`
+		SELECT maLichKham, maBacSi, ngayGioKham, trangThai
+		FROM LICHKHAM
+		WHERE maPhong = @p1 AND CAST(ngayGioKham AS DATE) = @p2
+		  AND trangThai NOT IN ('CANCELLED', 'NO_SHOW')
+		ORDER BY ngayGioKham
+	`, roomID, day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve room appointments",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	appointments := []RoomAppointmentResponse{}
+	for rows.Next() {
+		var appointmentID, doctorID, status string
+		var at time.Time
+		if err := rows.Scan(&appointmentID, &doctorID, &at, &status); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan room appointments",
+				Error:   err.Error(),
+			})
+			return
+		}
+		appointments = append(appointments, RoomAppointmentResponse{
+			MaLichKham:  appointmentID,
+			MaBacSi:     doctorID,
+			NgayGioKham: at,
+			TrangThai:   status,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Room usage retrieved successfully",
+		Data: RoomUsageResponse{
+			MaPhong:     roomID,
+			Ngay:        day.Format("2006-01-02"),
+			LichLamViec: schedules,
+			LichKham:    appointments,
+		},
+	})
+}
+
+func (h *ClinicHandler) GetEquipment(c *gin.Context) {
+	clinicID := c.Param("id")
+	if !isStaff(c) || !h.clinicExists(c, clinicID) {
+		return
+	}
+
+	query := `
+		SELECT t.maThietBi, t.maPhongKham, t.maPhong, p.tenPhong, t.tenThietBi, t.loaiThietBi,
+		       t.soSeri, t.trangThai, t.ngayMua, t.ngayBaoTriTiep, t.ghiChu
+		FROM THIETBI t
+		LEFT JOIN PHONG p ON t.maPhong = p.maPhong
+		WHERE t.maPhongKham = @p1
+	`
+	args := []interface{}{clinicID}
+	if roomID := c.Query("ma_phong"); roomID != "" {
+		query += fmt.Sprintf(" AND t.maPhong = @p%d", len(args)+1)
+		args = append(args, roomID)
+	}
+	if status := c.Query("trang_thai"); status != "" {
+		query += fmt.Sprintf(" AND t.trangThai = @p%d", len(args)+1)
+		args = append(args, status)
+	}
+	if c.Query("can_bao_tri") == "true" {
+		// Equipment whose scheduled maintenance falls within the next week.
+		query += fmt.Sprintf(" AND t.ngayBaoTriTiep <= @p%d AND t.trangThai <> 'RETIRED'", len(args)+1)
+		args = append(args, today().AddDate(0, 0, 7))
+	}
+	query += " ORDER BY t.tenThietBi"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve equipment",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	equipment := []models.Equipment{}
+	for rows.Next() {
+		var item models.Equipment
+		if err := rows.Scan(&item.MaThietBi, &item.MaPhongKham, &item.MaPhong, &item.TenPhong, &item.TenThietBi,
+			&item.LoaiThietBi, &item.SoSeri, &item.TrangThai, &item.NgayMua, &item.NgayBaoTriTiep, &item.GhiChu); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan equipment data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		equipment = append(equipment, item)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Equipment retrieved successfully",
+		Data:    equipment,
+	})
+}
+
+func (h *ClinicHandler) CreateEquipment(c *gin.Context) {
+	clinicID := c.Param("id")
+	if !h.canManageClinic(c, clinicID) {
+		return
+	}
+
+	var req EquipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.MaPhong != "" && !h.roomExists(c, clinicID, req.MaPhong) {
+		return
+	}
+	purchased, ok := optionalDate(c, req.NgayMua, "ngay_mua")
+	if !ok {
+		return
+	}
+	maintenance, ok := optionalDate(c, req.NgayBaoTriTiep, "ngay_bao_tri_tiep")
+	if !ok {
+		return
+	}
+	if req.TrangThai == "" {
+		req.TrangThai = "IN_USE"
+	}
+
+	equipmentID, err := utils.GenerateEquipmentID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create equipment",
+			Error:   err.Error(),
+		})
+		return
+	}
+	_, err = h.db.Exec(`
+		INSERT INTO THIETBI (maThietBi, maPhongKham, maPhong, tenThietBi, loaiThietBi, soSeri, trangThai,
+		                     ngayMua, ngayBaoTriTiep, ghiChu, ngayTao)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9, @p10, GETDATE())
+	`, equipmentID, clinicID, services.NullIfEmpty(req.MaPhong), strings.TrimSpace(req.TenThietBi), services.NullIfEmpty(req.LoaiThietBi),
+		services.NullIfEmpty(req.SoSeri), req.TrangThai, purchased, maintenance, services.NullIfEmpty(req.GhiChu))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create equipment",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Equipment created successfully",
+		Data:    CreatedEquipmentResponse{MaThietBi: equipmentID},
+	})
+}
+
+func (h *ClinicHandler) UpdateEquipment(c *gin.Context) {
+	clinicID := c.Param("id")
+	equipmentID := c.Param("equipment_id")
+	if !h.canManageClinic(c, clinicID) {
+		return
+	}
+
+	var exists int
+	err := h.db.QueryRow("SELECT COUNT(*) FROM THIETBI WHERE maThietBi = @p1 AND maPhongKham = @p2",
+		equipmentID, clinicID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to find equipment",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if exists == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Equipment not found",
+		})
+		return
+	}
+
+	var req UpdateEquipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var columns []string
+	var values []interface{}
+	if req.TenThietBi != nil {
+		name := strings.TrimSpace(*req.TenThietBi)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Equipment name cannot be empty",
+			})
+			return
+		}
+		columns = append(columns, "tenThietBi")
+		values = append(values, name)
+	}
+	if req.LoaiThietBi != nil {
+		columns = append(columns, "loaiThietBi")
+		values = append(values, services.NullIfEmpty(*req.LoaiThietBi))
+	}
+	if req.SoSeri != nil {
+		columns = append(columns, "soSeri")
+		values = append(values, services.NullIfEmpty(*req.SoSeri))
+	}
+	if req.MaPhong != nil {
+		if *req.MaPhong != "" && !h.roomExists(c, clinicID, *req.MaPhong) {
+			return
+		}
+		columns = append(columns, "maPhong")
+		values = append(values, services.NullIfEmpty(*req.MaPhong))
+	}
+	if req.TrangThai != nil {
+		columns = append(columns, "trangThai")
+		values = append(values, *req.TrangThai)
+	}
+	if req.NgayMua != nil {
+		purchased, ok := optionalDate(c, *req.NgayMua, "ngay_mua")
+		if !ok {
+			return
+		}
+		columns = append(columns, "ngayMua")
+		values = append(values, purchased)
+	}
+	if req.NgayBaoTriTiep != nil {
+		maintenance, ok := optionalDate(c, *req.NgayBaoTriTiep, "ngay_bao_tri_tiep")
+		if !ok {
+			return
+		}
+		columns = append(columns, "ngayBaoTriTiep")
+		values = append(values, maintenance)
+	}
+	if req.GhiChu != nil {
+		columns = append(columns, "ghiChu")
+		values = append(values, services.NullIfEmpty(*req.GhiChu))
+	}
+	if len(columns) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := updateColumns(tx, "THIETBI", "maThietBi", equipmentID, columns, values); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update equipment",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update equipment",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Equipment updated successfully",
+	})
+}
+
+// canManageClinic allows operation managers, and clinic managers of the
+// clinic itself, to change its facilities.
+func (h *ClinicHandler) canManageClinic(c *gin.Context, clinicID string) bool {
+	if !isManager(c, "Only clinic managers or operation managers can manage clinic facilities") {
+		return false
+	}
+	if !h.clinicExists(c, clinicID) {
+		return false
+	}
+
+	userType, _ := c.Get("user_type")
+	if userType.(string) == "OPERATION_MANAGER" {
+		return true
+	}
+
+	userID, _ := c.Get("user_id")
+	var managerClinic string
+	err := h.db.QueryRow("SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p1", userID).Scan(&managerClinic)
+	if err != nil || managerClinic != clinicID {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You can only manage facilities of your own clinic",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *ClinicHandler) clinicExists(c *gin.Context, clinicID string) bool {
+	var count int
+	err := h.db.QueryRow("SELECT COUNT(*) FROM PHONGKHAM WHERE maPhongKham = @p1", clinicID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to find clinic",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Clinic not found",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *ClinicHandler) roomExists(c *gin.Context, clinicID, roomID string) bool {
+	var count int
+	err := h.db.QueryRow("SELECT COUNT(*) FROM PHONG WHERE maPhong = @p1 AND maPhongKham = @p2", roomID, clinicID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to find room",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Room not found in this clinic",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *ClinicHandler) clinicNameTaken(c *gin.Context, name, exceptID string) bool {
+	var count int
+	err := h.db.QueryRow("SELECT COUNT(*) FROM PHONGKHAM WHERE tenPhongKham = @p1 AND maPhongKham <> @p2",
+		name, exceptID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check clinic name",
+			Error:   err.Error(),
+		})
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "A clinic with this name already exists",
+		})
+		return true
+	}
+	return false
+}
+
+func (h *ClinicHandler) roomNameTaken(c *gin.Context, clinicID, name, exceptID string) bool {
+	var count int
+	err := h.db.QueryRow("SELECT COUNT(*) FROM PHONG WHERE maPhongKham = @p1 AND tenPhong = @p2 AND maPhong <> @p3",
+		clinicID, name, exceptID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check room name",
+			Error:   err.Error(),
+		})
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "The clinic already has a room with this name",
+		})
+		return true
+	}
+	return false
+}
+
+func validateClinicContact(c *gin.Context, phone, email string) bool {
+	if phone != "" && !utils.ValidatePhoneNumber(phone) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid phone number format",
+		})
+		return false
+	}
+	if email != "" && !utils.ValidateEmail(email) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid email format",
+		})
+		return false
+	}
+	return true
+}
+
+func isStaff(c *gin.Context) bool {
+	userType, _ := c.Get("user_type")
+	if userType.(string) == "CUSTOMER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only clinic staff can view this information",
+		})
+		return false
+	}
+	return true
+}
+
+// optionalDate parses a YYYY-MM-DD field that may be empty.
+func optionalDate(c *gin.Context, value, field string) (interface{}, bool) {
+	if value == "" {
+		return nil, true
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("Invalid %s. Use YYYY-MM-DD", field),
+		})
+		return nil, false
+	}
+	return date, true
+}
+
+// clockMinutes converts an H:MM or HH:MM time to minutes after midnight.
+func clockMinutes(clock string) int {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0
+	}
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/scheduler"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowUpHandler lets patients act on the follow-ups booked by
+// scheduler.FollowUpScheduler and gives managers the missed follow-ups.
+type FollowUpHandler struct {
+	db *sql.DB
+}
+
+func NewFollowUpHandler(db *sql.DB) *FollowUpHandler {
+	return &FollowUpHandler{db: db}
+}
+
+type RescheduleFollowUpRequest struct {
+	NgayGioKham string `json:"ngay_gio_kham" binding:"required"` // YYYY-MM-DD HH:MM
+}
+
+type followUp struct {
+	MaTaiKham   string
+	MaHoSo      string
+	MaCustomer  string
+	MaBacSi     string
+	MaPhongKham string
+	MaLichKham  sql.NullString
+	TrangThai   string
+}
+
+func (h *FollowUpHandler) GetFollowUps(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	query := `
+		SELECT t.maTaiKham, t.maHoSo, t.ngayTaiKham, t.trangThai, t.maLichKham, l.ngayGioKham, l.trangThai,
+		       t.maCustomer, uc.hoTen, t.maBacSi, ud.hoTen, t.maPhongKham, p.tenPhongKham
+		FROM TAIKHAM t
+		JOIN [USER] uc ON t.maCustomer = uc.userID
+		JOIN [USER] ud ON t.maBacSi = ud.userID
+		JOIN PHONGKHAM p ON t.maPhongKham = p.maPhongKham
+		LEFT JOIN LICHKHAM l ON t.maLichKham = l.maLichKham
+		WHERE 1=1`
+	var args []interface{}
+
+	switch userType.(string) {
+	case "CUSTOMER":
+		args = append(args, userID)
+		query += " AND t.maCustomer = @p1"
+	case "DOCTOR":
+		args = append(args, userID)
+		query += " AND t.maBacSi = @p1"
+	case "CLINIC_MANAGER":
+		args = append(args, userID)
+		query += " AND t.maPhongKham IN (SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p1)"
+	case "OPERATION_MANAGER":
+	default:
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Access denied to follow-ups",
+		})
+		return
+	}
+
+	if status := c.Query("trang_thai"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND t.trangThai = @p%d", len(args))
+	}
+	query += " ORDER BY t.ngayTaiKham DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve follow-ups",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	followUps := []FollowUpResponse{}
+	for rows.Next() {
+		var maTaiKham, maHoSo, trangThai, maCustomer, tenKhachHang, maBacSi, tenBacSi, maPhongKham, tenPhongKham string
+		var maLichKham, trangThaiLichKham sql.NullString
+		var ngayTaiKham time.Time
+		var ngayGioKham sql.NullTime
+		if err := rows.Scan(&maTaiKham, &maHoSo, &ngayTaiKham, &trangThai, &maLichKham, &ngayGioKham, &trangThaiLichKham,
+			&maCustomer, &tenKhachHang, &maBacSi, &tenBacSi, &maPhongKham, &tenPhongKham); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan follow-up",
+				Error:   err.Error(),
+			})
+			return
+		}
+		followUps = append(followUps, FollowUpResponse{
+			MaTaiKham:         maTaiKham,
+			MaHoSo:            maHoSo,
+			NgayTaiKham:       ngayTaiKham.Format("2006-01-02"),
+			TrangThai:         trangThai,
+			MaLichKham:        maLichKham.String,
+			NgayGioKham:       timePointer(ngayGioKham),
+			TrangThaiLichKham: trangThaiLichKham.String,
+			MaCustomer:        maCustomer,
+			TenKhachHang:      tenKhachHang,
+			MaBacSi:           maBacSi,
+			TenBacSi:          tenBacSi,
+			MaPhongKham:       maPhongKham,
+			TenPhongKham:      tenPhongKham,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Follow-ups retrieved successfully",
+		Data:    followUps,
+	})
+}
+
+// ConfirmFollowUp accepts the suggested appointment as booked.
+func (h *FollowUpHandler) ConfirmFollowUp(c *gin.Context) {
+	f, ok := h.loadOwnFollowUp(c)
+	if !ok {
+		return
+	}
+
+	if f.TrangThai != scheduler.FollowUpSuggested || !f.MaLichKham.Valid {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Only suggested follow-ups can be confirmed",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE LICHKHAM SET trangThai = 'SCHEDULED'
+		WHERE maLichKham = @p1 AND trangThai = 'PENDING' AND ngayGioKham > GETDATE()
+	`, f.MaLichKham.String)
+	if err == nil {
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Message: "The suggested slot is no longer available, please pick another",
+			})
+			return
+		}
+		_, err = tx.Exec("UPDATE TAIKHAM SET trangThai = 'CONFIRMED', ngayCapNhat = GETDATE() WHERE maTaiKham = @p1", f.MaTaiKham)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to confirm follow-up",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Follow-up appointment confirmed",
+		Data:    FollowUpAppointmentResponse{MaTaiKham: f.MaTaiKham, MaLichKham: f.MaLichKham.String},
+	})
+}
+
+// RescheduleFollowUp moves the follow-up appointment to another slot with
+// the same doctor, booking one if the scheduler found none.
+func (h *FollowUpHandler) RescheduleFollowUp(c *gin.Context) {
+	var req RescheduleFollowUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	at, err := time.ParseInLocation("2006-01-02 15:04", req.NgayGioKham, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid date format. Use YYYY-MM-DD HH:MM",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	f, ok := h.loadOwnFollowUp(c)
+	if !ok {
+		return
+	}
+
+	switch f.TrangThai {
+	case scheduler.FollowUpSuggested, scheduler.FollowUpConfirmed, scheduler.FollowUpRescheduled, scheduler.FollowUpNoSlot:
+	default:
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "This follow-up can no longer be rescheduled",
+		})
+		return
+	}
+
+	available, err := scheduler.SlotAvailable(h.db, f.MaBacSi, f.MaPhongKham, at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check availability",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !available {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Time slot is not available",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	appointmentID := f.MaLichKham.String
+	var affected int64
+	if f.MaLichKham.Valid {
+		result, err := tx.Exec(`
+			UPDATE LICHKHAM SET ngayGioKham = @p1, trangThai = 'SCHEDULED'
+			WHERE maLichKham = @p2 AND trangThai IN ('PENDING', 'SCHEDULED', 'CONFIRMED')
+		`, at, appointmentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to reschedule appointment",
+				Error:   err.Error(),
+			})
+			return
+		}
+		affected, _ = result.RowsAffected()
+	}
+	if affected == 0 {
+		// No appointment yet, or the old one was cancelled: book a new one.
+		appointmentID, err = utils.GenerateAppointmentID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to create appointment",
+				Error:   err.Error(),
+			})
+			return
+		}
+		_, err = tx.Exec(`
+			INSERT INTO LICHKHAM (maLichKham, maCustomer, maBacSi, maPhongKham, ngayGioKham, trangThai, ghiChu, createdAt)
+			VALUES (@p1, @p2, @p3, @p4, @p5, 'SCHEDULED', @p6, GETDATE())
+		`, appointmentID, f.MaCustomer, f.MaBacSi, f.MaPhongKham, at, "Tái khám theo hồ sơ "+f.MaHoSo)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to create appointment",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE TAIKHAM SET maLichKham = @p1, trangThai = 'RESCHEDULED', ngayCapNhat = GETDATE()
+		WHERE maTaiKham = @p2
+	`, appointmentID, f.MaTaiKham)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to reschedule follow-up",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Follow-up appointment rescheduled",
+		Data:    FollowUpAppointmentResponse{MaTaiKham: f.MaTaiKham, MaLichKham: appointmentID, NgayGioKham: &at},
+	})
+}
+
+// GetMissedFollowUps reports follow-ups the patient did not attend, with
+// a per-doctor count. Clinic managers only see their own clinic.
+func (h *FollowUpHandler) GetMissedFollowUps(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	if !isManager(c, "Only managers can view missed follow-ups") {
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, -1, 0)
+	var err error
+	if v := c.Query("tu_ngay"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid tu_ngay. Use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+	if v := c.Query("den_ngay"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid den_ngay. Use YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	query := `
+		SELECT t.maTaiKham, t.maHoSo, t.ngayTaiKham, t.maLichKham,
+		       t.maCustomer, uc.hoTen, uc.soDienThoai, t.maBacSi, ud.hoTen, t.maPhongKham, p.tenPhongKham
+		FROM TAIKHAM t
+		JOIN [USER] uc ON t.maCustomer = uc.userID
+		JOIN [USER] ud ON t.maBacSi = ud.userID
+		JOIN PHONGKHAM p ON t.maPhongKham = p.maPhongKham
+		WHERE t.trangThai = 'MISSED' AND t.ngayTaiKham >= @p1 AND t.ngayTaiKham <= @p2`
+	args := []interface{}{from.Format("2006-01-02"), to.Format("2006-01-02")}
+
+	if userType.(string) == "CLINIC_MANAGER" {
+		args = append(args, userID)
+		query += " AND t.maPhongKham IN (SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p3)"
+	} else if clinicID := c.Query("ma_phong_kham"); clinicID != "" {
+		args = append(args, clinicID)
+		query += " AND t.maPhongKham = @p3"
+	}
+	query += " ORDER BY t.ngayTaiKham DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve missed follow-ups",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	missed := []MissedFollowUpResponse{}
+	byDoctor := map[string]*MissedByDoctorResponse{}
+	var doctorOrder []string
+	for rows.Next() {
+		var maTaiKham, maHoSo, maCustomer, tenKhachHang, maBacSi, tenBacSi, maPhongKham, tenPhongKham string
+		var maLichKham, soDienThoai sql.NullString
+		var ngayTaiKham time.Time
+		if err := rows.Scan(&maTaiKham, &maHoSo, &ngayTaiKham, &maLichKham,
+			&maCustomer, &tenKhachHang, &soDienThoai, &maBacSi, &tenBacSi, &maPhongKham, &tenPhongKham); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan missed follow-up",
+				Error:   err.Error(),
+			})
+			return
+		}
+		missed = append(missed, MissedFollowUpResponse{
+			MaTaiKham:    maTaiKham,
+			MaHoSo:       maHoSo,
+			NgayTaiKham:  ngayTaiKham.Format("2006-01-02"),
+			MaLichKham:   maLichKham.String,
+			MaCustomer:   maCustomer,
+			TenKhachHang: tenKhachHang,
+			SoDienThoai:  soDienThoai.String,
+			MaBacSi:      maBacSi,
+			TenBacSi:     tenBacSi,
+			MaPhongKham:  maPhongKham,
+			TenPhongKham: tenPhongKham,
+		})
+
+		if byDoctor[maBacSi] == nil {
+			byDoctor[maBacSi] = &MissedByDoctorResponse{MaBacSi: maBacSi, TenBacSi: tenBacSi}
+			doctorOrder = append(doctorOrder, maBacSi)
+		}
+		byDoctor[maBacSi].SoLuong++
+	}
+
+	perDoctor := make([]MissedByDoctorResponse, len(doctorOrder))
+	for i, id := range doctorOrder {
+		perDoctor[i] = *byDoctor[id]
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Missed follow-ups retrieved successfully",
+		Data: MissedFollowUpsResponse{
+			TuNgay:    from.Format("2006-01-02"),
+			DenNgay:   to.Format("2006-01-02"),
+			TongSo:    len(missed),
+			TheoBacSi: perDoctor,
+			DanhSach:  missed,
+		},
+	})
+}
+
+// loadOwnFollowUp loads the follow-up in the URL and checks that it belongs
+// to the calling customer.
+func (h *FollowUpHandler) loadOwnFollowUp(c *gin.Context) (followUp, bool) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	var f followUp
+	if userType.(string) != "CUSTOMER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only the patient can confirm or reschedule a follow-up",
+		})
+		return f, false
+	}
+
+	err := h.db.QueryRow(`
+		SELECT maTaiKham, maHoSo, maCustomer, maBacSi, maPhongKham, maLichKham, trangThai
+		FROM TAIKHAM WHERE maTaiKham = @p1
+	`, c.Param("id")).Scan(&f.MaTaiKham, &f.MaHoSo, &f.MaCustomer, &f.MaBacSi, &f.MaPhongKham, &f.MaLichKham, &f.TrangThai)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Follow-up not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve follow-up",
+				Error:   err.Error(),
+			})
+		}
+		return f, false
+	}
+
+	if f.MaCustomer != userID.(string) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Access denied to this follow-up",
+		})
+		return f, false
+	}
+	return f, true
+}
@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ICD10Handler struct {
+	db *sql.DB
+}
+
+func NewICD10Handler(db *sql.DB) *ICD10Handler {
+	return &ICD10Handler{db: db}
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// SearchICD10 serves doctors' autocomplete. A query matches codes by prefix
+// and titles by accent-insensitive keywords, exact and prefix code matches first.
+// When nothing matches, typos are tolerated (see searchICD10Fuzzy).
+func (h *ICD10Handler) SearchICD10(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	chapter := c.Query("chapter")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := `
+		SELECT TOP (@p1) maICD10, tenTiengAnh, tenTiengViet, chuong, maCha
+		FROM ICD10
+		WHERE 1=1
+	`
+	args := []interface{}{limit}
+	orderBy := " ORDER BY maICD10"
+
+	if q != "" {
+		code := utils.NormalizeICD10Code(q)
+		args = append(args, code, code+"%")
+		exactParam, prefixParam := len(args)-1, len(args)
+
+		var keywordClauses []string
+		for _, token := range strings.Fields(utils.NormalizeSearchText(q)) {
+			args = append(args, "%"+token+"%")
+			keywordClauses = append(keywordClauses, fmt.Sprintf("tuKhoa LIKE @p%d", len(args)))
+		}
+
+		query += fmt.Sprintf(" AND (maICD10 LIKE @p%d", prefixParam)
+		if len(keywordClauses) > 0 {
+			query += " OR (" + strings.Join(keywordClauses, " AND ") + ")"
+		}
+		query += ")"
+
+		orderBy = fmt.Sprintf(` ORDER BY CASE WHEN maICD10 = @p%d THEN 0
+		                                      WHEN maICD10 LIKE @p%d THEN 1
+		                                      ELSE 2 END, maICD10`, exactParam, prefixParam)
+	}
+
+	if chapter != "" {
+		query += fmt.Sprintf(" AND chuong = @p%d", len(args)+1)
+		args = append(args, chapter)
+	}
+
+	rows, err := h.db.Query(query+orderBy, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to search ICD-10 codes",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	codes := []models.ICD10Code{}
+	for rows.Next() {
+		var code models.ICD10Code
+		if err := rows.Scan(&code.MaICD10, &code.TenTiengAnh, &code.TenTiengViet, &code.Chuong, &code.MaCha); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan ICD-10 data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		codes = append(codes, code)
+	}
+
+	if len(codes) == 0 && q != "" {
+		codes, err = h.searchICD10Fuzzy(q, chapter, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to search ICD-10 codes",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "ICD-10 codes retrieved successfully",
+		Data:    codes,
+	})
+}
+
+// Limits of the fuzzy fallback. Longer keywords are cut to
+// fuzzyMaxKeywordLength letters and matched as a prefix; a query with more
+// than fuzzyMaxKeywords keywords gets no fuzzy matches. At most
+// fuzzyMaxCandidates rows pass the SQL prefilter.
+const (
+	fuzzyMaxKeywords      = 5
+	fuzzyMaxKeywordLength = 30
+	fuzzyMaxCandidates    = 500
+)
+
+// searchICD10Fuzzy is the fallback for a query with a typo, such as
+// "dai thao duog". Every keyword must be within a few edits of a word of
+// the entry, or of the start of one while the doctor is still typing.
+// Entries needing the fewest edits come first.
+func (h *ICD10Handler) searchICD10Fuzzy(q, chapter string, limit int) ([]models.ICD10Code, error) {
+	tokens := fuzzyKeywords(q)
+	if len(tokens) == 0 {
+		return []models.ICD10Code{}, nil
+	}
+
+	// A word within k edits of a keyword still contains one of k+1 pieces
+	// of it unchanged, so rows lacking all pieces of a keyword are skipped
+	// without computing edit distances.
+	args := []interface{}{fuzzyMaxCandidates}
+	var clauses []string
+	for _, token := range tokens {
+		var pieces []string
+		for _, piece := range fuzzyPieces(token) {
+			args = append(args, "%"+piece+"%")
+			pieces = append(pieces, fmt.Sprintf("tuKhoa LIKE @p%d", len(args)))
+		}
+		clauses = append(clauses, "("+strings.Join(pieces, " OR ")+")")
+	}
+	if chapter != "" {
+		args = append(args, chapter)
+		clauses = append(clauses, fmt.Sprintf("chuong = @p%d", len(args)))
+	}
+
+	rows, err := h.db.Query(`
+		SELECT TOP (@p1) maICD10, tenTiengAnh, tenTiengViet, chuong, maCha, tuKhoa
+		FROM ICD10
+		WHERE `+strings.Join(clauses, " AND ")+`
+		ORDER BY maICD10`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type match struct {
+		code  models.ICD10Code
+		edits int
+	}
+	var matches []match
+	for rows.Next() {
+		var code models.ICD10Code
+		var keywords string
+		if err := rows.Scan(&code.MaICD10, &code.TenTiengAnh, &code.TenTiengViet, &code.Chuong, &code.MaCha, &keywords); err != nil {
+			return nil, err
+		}
+		if edits, ok := fuzzyKeywordEdits(tokens, strings.Fields(keywords)); ok {
+			matches = append(matches, match{code, edits})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].edits != matches[j].edits {
+			return matches[i].edits < matches[j].edits
+		}
+		return matches[i].code.MaICD10 < matches[j].code.MaICD10
+	})
+
+	codes := []models.ICD10Code{}
+	for i := 0; i < len(matches) && i < limit; i++ {
+		codes = append(codes, matches[i].code)
+	}
+	return codes, nil
+}
+
+// fuzzyKeywords returns the keywords of a query within the fuzzy search
+// limits, or nil when there are too many.
+func fuzzyKeywords(q string) []string {
+	tokens := strings.Fields(utils.NormalizeSearchText(q))
+	if len(tokens) > fuzzyMaxKeywords {
+		return nil
+	}
+	for i, token := range tokens {
+		if r := []rune(token); len(r) > fuzzyMaxKeywordLength {
+			tokens[i] = string(r[:fuzzyMaxKeywordLength])
+		}
+	}
+	return tokens
+}
+
+// fuzzyAllowedEdits is how many edits a keyword of n letters may be from a
+// word: none under three letters, one up to five letters and two beyond.
+func fuzzyAllowedEdits(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	}
+	return 2
+}
+
+// fuzzyPieces splits a keyword into one more piece than the edits it
+// allows, each as long as possible.
+func fuzzyPieces(token string) []string {
+	r := []rune(token)
+	count := fuzzyAllowedEdits(len(r)) + 1
+	pieces := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		pieces = append(pieces, string(r[i*len(r)/count:(i+1)*len(r)/count]))
+	}
+	return pieces
+}
+
+// fuzzyKeywordEdits returns the edits needed to match every token to one of
+// words, and false when a token is too far from all of them; see
+// fuzzyAllowedEdits.
+func fuzzyKeywordEdits(tokens, words []string) (int, bool) {
+	total := 0
+	for _, token := range tokens {
+		n := len([]rune(token))
+		allowed := fuzzyAllowedEdits(n)
+
+		best := -1
+		for _, word := range words {
+			edits := utils.EditDistance(token, word)
+			if r := []rune(word); len(r) > n {
+				edits = min(edits, utils.EditDistance(token, string(r[:n])))
+			}
+			if edits <= allowed && (best < 0 || edits < best) {
+				best = edits
+			}
+		}
+		if best < 0 {
+			return 0, false
+		}
+		total += best
+	}
+	return total, len(tokens) > 0
+}
+
+func (h *ICD10Handler) GetChapters(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT chuong, COUNT(*) AS soMa
+		FROM ICD10
+		WHERE chuong IS NOT NULL
+		GROUP BY chuong
+		ORDER BY chuong
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve ICD-10 chapters",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	chapters := []ICD10ChapterResponse{}
+	for rows.Next() {
+		var chuong string
+		var soMa int
+		if err := rows.Scan(&chuong, &soMa); err == nil {
+			chapters = append(chapters, ICD10ChapterResponse{
+				Chuong: chuong,
+				SoMa:   soMa,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "ICD-10 chapters retrieved successfully",
+		Data:    chapters,
+	})
+}
+
+func (h *ICD10Handler) GetICD10(c *gin.Context) {
+	code := utils.NormalizeICD10Code(c.Param("code"))
+
+	var entry models.ICD10Code
+	err := h.db.QueryRow(`
+		SELECT maICD10, tenTiengAnh, tenTiengViet, chuong, maCha
+		FROM ICD10 WHERE maICD10 = @p1
+	`, code).Scan(&entry.MaICD10, &entry.TenTiengAnh, &entry.TenTiengViet, &entry.Chuong, &entry.MaCha)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "ICD-10 code not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve ICD-10 code",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT maICD10, tenTiengAnh, tenTiengViet, chuong, maCha
+		FROM ICD10 WHERE maCha = @p1
+		ORDER BY maICD10
+	`, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve ICD-10 sub-codes",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	children := []models.ICD10Code{}
+	for rows.Next() {
+		var child models.ICD10Code
+		if err := rows.Scan(&child.MaICD10, &child.TenTiengAnh, &child.TenTiengViet, &child.Chuong, &child.MaCha); err == nil {
+			children = append(children, child)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "ICD-10 code retrieved successfully",
+		Data: ICD10DetailResponse{
+			Ma:    entry,
+			MaCon: children,
+		},
+	})
+}
+
+// ImportICD10 loads the catalogue from an uploaded CSV file with the header
+// code,title_en,title_vi,chapter,parent. Existing codes are updated in place.
+func (h *ICD10Handler) ImportICD10(c *gin.Context) {
+	userType, _ := c.Get("user_type")
+	if userType.(string) != "OPERATION_MANAGER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only operation managers can import the ICD-10 catalogue",
+		})
+		return
+	}
+
+	codes, ok := uploadedCSV(c, parseICD10CSV)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	for _, code := range codes {
+		keywords := utils.NormalizeSearchText(code.MaICD10 + " " + code.TenTiengAnh + " " + stringValue(code.TenTiengViet))
+
+		_, err = tx.Exec(`
+			MERGE ICD10 AS target
+			USING (SELECT @p1 AS maICD10) AS source
+			ON target.maICD10 = source.maICD10
+			WHEN MATCHED THEN
+				UPDATE SET tenTiengAnh = @p2, tenTiengViet = @p3, chuong = @p4, maCha = @p5, tuKhoa = @p6
+			WHEN NOT MATCHED THEN
+				INSERT (maICD10, tenTiengAnh, tenTiengViet, chuong, maCha, tuKhoa)
+				VALUES (@p1, @p2, @p3, @p4, @p5, @p6);
+		`, code.MaICD10, code.TenTiengAnh, code.TenTiengViet, code.Chuong, code.MaCha, keywords)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to import ICD-10 code " + code.MaICD10,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to import ICD-10 catalogue",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "ICD-10 catalogue imported successfully",
+		Data: ICD10ImportResponse{
+			SoMa: len(codes),
+		},
+	})
+}
+
+// parseICD10CSV reads catalogue rows. Row-level problems are collected and
+// returned together so the whole file can be fixed in one pass.
+func parseICD10CSV(r io.Reader) ([]models.ICD10Code, []string, error) {
+	file, err := readCSV(r, "code", "title_en")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+
+	var codes []models.ICD10Code
+	seen := make(map[string]bool)
+	for file.next() {
+		code := utils.NormalizeICD10Code(file.field("code"))
+		if !utils.ValidateICD10Format(code) {
+			file.fail("invalid code %q", code)
+			continue
+		}
+		if seen[code] {
+			file.fail("duplicate code %s", code)
+			continue
+		}
+		seen[code] = true
+
+		title := file.field("title_en")
+		if title == "" {
+			file.fail("title_en is required")
+			continue
+		}
+
+		parent := utils.NormalizeICD10Code(file.field("parent"))
+		if parent == "" && strings.Contains(code, ".") {
+			parent = code[:3]
+		}
+
+		codes = append(codes, models.ICD10Code{
+			MaICD10:      code,
+			TenTiengAnh:  title,
+			TenTiengViet: optional(file.field("title_vi")),
+			Chuong:       optional(file.field("chapter")),
+			MaCha:        optional(parent),
+		})
+	}
+	if file.err != nil {
+		return nil, nil, file.err
+	}
+
+	return codes, file.problems, nil
+}
+
+// validateICD10Codes normalizes the codes in place, and writes a 400
+// response listing the unknown ones and returns false when any code is not
+// in the ICD-10 catalogue.
+func validateICD10Codes(c *gin.Context, q queryRower, codes []string) bool {
+	invalid, err := findInvalidICD10Codes(q, codes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to validate ICD10 codes",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid ICD10 codes",
+			Error:   strings.Join(invalid, ", "),
+		})
+		return false
+	}
+	return true
+}
+
+// findInvalidICD10Codes normalizes the given codes in place and returns those
+// that are malformed or missing from the catalogue.
+func findInvalidICD10Codes(q queryRower, codes []string) ([]string, error) {
+	var invalid []string
+	for i, code := range codes {
+		codes[i] = utils.NormalizeICD10Code(code)
+		if !utils.ValidateICD10Format(codes[i]) {
+			invalid = append(invalid, code)
+			continue
+		}
+
+		var exists int
+		err := q.QueryRow("SELECT COUNT(*) FROM ICD10 WHERE maICD10 = @p1", codes[i]).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			invalid = append(invalid, codes[i])
+		}
+	}
+	return invalid, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
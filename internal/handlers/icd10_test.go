@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"clinic-management/internal/utils"
+)
+
+func TestFuzzyKeywordEdits(t *testing.T) {
+	diabetes := strings.Fields(utils.NormalizeSearchText("E11.9 Type 2 diabetes mellitus without complications Đái tháo đường typ 2 không biến chứng"))
+
+	tests := []struct {
+		query string
+		edits int
+		ok    bool
+	}{
+		{"đái tháo đường", 0, true},
+		{"dai thao duog", 1, true},
+		{"dai thap duong", 1, true},
+		{"diabtes melitus", 2, true},
+		{"diabet", 0, true},  // still typing
+		{"diabtes", 1, true}, // typo in a word still being typed
+		{"e11.9", 0, true},
+		{"dai thao duong cap", 0, false}, // "cap" is not in the title
+		{"daj", 1, true},
+		{"dx", 0, false}, // words under three letters must match exactly
+		{"hypertension", 0, false},
+		{"", 0, false},
+	}
+	keywords := strings.Join(diabetes, " ")
+	for _, tt := range tests {
+		tokens := fuzzyKeywords(tt.query)
+		edits, ok := fuzzyKeywordEdits(tokens, diabetes)
+		if ok != tt.ok || (ok && edits != tt.edits) {
+			t.Errorf("fuzzyKeywordEdits(%q) = %d, %v; want %d, %v", tt.query, edits, ok, tt.edits, tt.ok)
+		}
+		if !ok {
+			continue
+		}
+		// Every match must also pass the SQL prefilter.
+		for _, token := range tokens {
+			found := false
+			for _, piece := range fuzzyPieces(token) {
+				found = found || strings.Contains(keywords, piece)
+			}
+			if !found {
+				t.Errorf("prefilter drops %q: no piece of %q in the keywords", tt.query, token)
+			}
+		}
+	}
+}
+
+func TestFuzzyKeywordsLimits(t *testing.T) {
+	if tokens := fuzzyKeywords("a b c d e f"); tokens != nil {
+		t.Errorf("fuzzyKeywords kept %d keywords, want none", len(tokens))
+	}
+	long := strings.Repeat("x", fuzzyMaxKeywordLength+10)
+	if tokens := fuzzyKeywords(long); len(tokens) != 1 || len(tokens[0]) != fuzzyMaxKeywordLength {
+		t.Errorf("fuzzyKeywords(%d letters) = %q", len(long), tokens)
+	}
+	if pieces := fuzzyPieces("duong"); len(pieces) != 2 || pieces[0]+pieces[1] != "duong" {
+		t.Errorf("fuzzyPieces(duong) = %q", pieces)
+	}
+}
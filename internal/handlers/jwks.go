@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"clinic-management/internal/keys"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	signingKeys *keys.Manager
+}
+
+func NewJWKSHandler(signingKeys *keys.Manager) *JWKSHandler {
+	return &JWKSHandler{signingKeys: signingKeys}
+}
+
+// GetJWKS serves the token verification keys as a JWK Set (RFC 7517).
+// It is not wrapped in APIResponse because JWT libraries read this format
+// directly.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": h.signingKeys.JWKS()})
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
 	"net/http"
 	"time"
 
 	"clinic-management/internal/models"
+	"clinic-management/internal/services"
 	"clinic-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -20,67 +22,160 @@ func NewLabTestHandler(db *sql.DB) *LabTestHandler {
 }
 
 type LabTestRequest struct {
-	MaHoSo          string `json:"ma_ho_so" binding:"required"`
-	LoaiXetNghiem   string `json:"loai_xet_nghiem" binding:"required"`
-	GhiChu          string `json:"ghi_chu"`
-	NgayXetNghiem   string `json:"ngay_xet_nghiem"` // optional, defaults to today
+	MaHoSo        string `json:"ma_ho_so" binding:"required"`
+	LoaiXetNghiem string `json:"loai_xet_nghiem" binding:"required"`
+	GhiChu        string `json:"ghi_chu"`
+	NgayXetNghiem string `json:"ngay_xet_nghiem"` // optional, defaults to today
 }
 
+// UpdateLabTestRequest records results. Omitted fields are kept; an empty
+// ket_qua or ghi_chu clears it.
+type UpdateLabTestRequest struct {
+	KetQua        *string `json:"ket_qua"`
+	GhiChu        *string `json:"ghi_chu"`
+	LoaiXetNghiem *string `json:"loai_xet_nghiem"`
+	NgayXetNghiem *string `json:"ngay_xet_nghiem"`
+}
+
+// LabTestResponse is a lab test with its visit, patient and doctor.
 type LabTestResponse struct {
-	MaXetNghiem   string    `json:"ma_xet_nghiem"`
-	MaHoSo        string    `json:"ma_ho_so"`
-	LoaiXetNghiem string    `json:"loai_xet_nghiem"`
-	NgayXetNghiem time.Time `json:"ngay_xet_nghiem"`
-	KetQua        string    `json:"ket_qua"`
-	GhiChu        string    `json:"ghi_chu"`
-	TenKhachHang  string    `json:"ten_khach_hang"`
-	TenBacSi      string    `json:"ten_bac_si"`
-	Status        string    `json:"status"` // ordered, collected, processing, completed
+	MaXetNghiem   string     `json:"ma_xet_nghiem"`
+	MaHoSo        string     `json:"ma_ho_so"`
+	LoaiXetNghiem string     `json:"loai_xet_nghiem"`
+	NgayXetNghiem *time.Time `json:"ngay_xet_nghiem"`
+	KetQua        string     `json:"ket_qua"`
+	GhiChu        string     `json:"ghi_chu"`
+	FileDinhKem   string     `json:"file_dinh_kem"`
+	MaCustomer    string     `json:"ma_customer"`
+	MaBacSi       string     `json:"ma_bac_si"`
+	NgayKham      time.Time  `json:"ngay_kham"`
+	TenKhachHang  string     `json:"ten_khach_hang"`
+	TenBacSi      string     `json:"ten_bac_si"`
+	Status        string     `json:"status"` // ordered, processing, completed
+}
+
+var labTestList = listSpec{
+	sorts: map[string]services.SortKey{
+		"ngay_xet_nghiem": {Expr: "ISNULL(xn.ngayXetNghiem, '1900-01-01')", Kind: services.SortTime},
+		"ngay_kham":       {Expr: "h.ngayKham", Kind: services.SortTime},
+	},
+	defaultSort: "-ngay_xet_nghiem",
+	idField:     "ma_xet_nghiem",
+	idColumn:    "xn.maXetNghiem",
+}
+
+const labTestColumns = `
+	xn.maXetNghiem, xn.maHoSo, xn.loaiXetNghiem, xn.ngayXetNghiem,
+	xn.ketQua, xn.ghiChu, xn.fileDinhKem,
+	h.maCustomer, h.maBacSi, h.ngayKham,
+	uc.hoTen as tenKhachHang, ud.hoTen as tenBacSi
+`
+
+const labTestFrom = `
+	FROM XETNGHIEM xn
+	JOIN HOSO h ON xn.maHoSo = h.maHoSo
+	JOIN [USER] uc ON h.maCustomer = uc.userID
+	JOIN [USER] ud ON h.maBacSi = ud.userID
+`
+
+func scanLabTest(row interface{ Scan(...interface{}) error }) (LabTestResponse, error) {
+	var t LabTestResponse
+	var loaiXetNghiem, ketQua, ghiChu, fileDinhKem, tenKhachHang, tenBacSi sql.NullString
+	var ngayXetNghiem sql.NullTime
+
+	err := row.Scan(&t.MaXetNghiem, &t.MaHoSo, &loaiXetNghiem, &ngayXetNghiem,
+		&ketQua, &ghiChu, &fileDinhKem,
+		&t.MaCustomer, &t.MaBacSi, &t.NgayKham,
+		&tenKhachHang, &tenBacSi)
+	if err != nil {
+		return t, err
+	}
+
+	t.LoaiXetNghiem = loaiXetNghiem.String
+	t.NgayXetNghiem = timePointer(ngayXetNghiem)
+	t.KetQua = ketQua.String
+	t.GhiChu = ghiChu.String
+	t.FileDinhKem = fileDinhKem.String
+	t.TenKhachHang = tenKhachHang.String
+	t.TenBacSi = tenBacSi.String
+
+	t.Status = "ordered"
+	if t.KetQua != "" {
+		t.Status = "completed"
+	} else if ngayXetNghiem.Valid && ngayXetNghiem.Time.Before(time.Now()) {
+		t.Status = "processing"
+	}
+	return t, nil
 }
 
+// GetLabTests lists lab tests one page at a time. With ma_customer, anyone
+// who may see the patient gets all of theirs; otherwise customers see their
+// own, doctors those they ordered, clinic managers their clinic's and
+// operation managers all. Other filters are ma_ho_so, status and
+// date_from/date_to on the test date.
 func (h *LabTestHandler) GetLabTests(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userType, _ := c.Get("user_type")
-	status := c.Query("status")
-	maHoSo := c.Query("ma_ho_so")
 
-	var query string
+	params, ok := labTestList.parse(c)
+	if !ok {
+		return
+	}
+
+	from := labTestFrom + " WHERE 1=1"
 	var args []interface{}
 
-	// Base query
-	baseQuery := `
-		SELECT xn.maXetNghiem, xn.maHoSo, xn.loaiXetNghiem, xn.ngayXetNghiem, 
-		       xn.ketQua, xn.ghiChu,
-		       h.maCustomer, h.maBacSi, h.ngayKham,
-		       uc.hoTen as tenKhachHang, ud.hoTen as tenBacSi
-		FROM XETNGHIEM xn
-		JOIN HOSO h ON xn.maHoSo = h.maHoSo
-		JOIN [USER] uc ON h.maCustomer = uc.userID
-		JOIN [USER] ud ON h.maBacSi = ud.userID
-		WHERE 1=1
-	`
+	if customerID := c.Query("ma_customer"); customerID != "" {
+		if !canViewPatient(c, h.db, customerID) {
+			return
+		}
+		from += " AND h.maCustomer = @p1"
+		args = append(args, customerID)
+	} else {
+		switch userType.(string) {
+		case "CUSTOMER":
+			from += " AND h.maCustomer = @p1"
+			args = append(args, userID)
+		case "DOCTOR":
+			from += " AND h.maBacSi = @p1"
+			args = append(args, userID)
+		case "CLINIC_MANAGER":
+			from += " AND h.maPhongKham IN (SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p1)"
+			args = append(args, userID)
+		case "OPERATION_MANAGER":
+		default:
+			c.JSON(http.StatusForbidden, models.APIResponse{
+				Success: false,
+				Message: "You do not have access to lab results",
+			})
+			return
+		}
+	}
 
-	// Filter by user role
-	switch userType.(string) {
-	case "CUSTOMER":
-		query = baseQuery + " AND h.maCustomer = ?"
-		args = append(args, userID)
-	case "DOCTOR":
-		query = baseQuery + " AND h.maBacSi = ?"
-		args = append(args, userID)
-	default:
-		query = baseQuery
-	}
-
-	// Additional filters
-	if maHoSo != "" {
-		query += " AND xn.maHoSo = ?"
+	if maHoSo := c.Query("ma_ho_so"); maHoSo != "" {
 		args = append(args, maHoSo)
+		from += fmt.Sprintf(" AND xn.maHoSo = @p%d", len(args))
+	}
+	switch c.Query("status") {
+	case "completed":
+		from += " AND xn.ketQua IS NOT NULL AND xn.ketQua <> ''"
+	case "ordered", "processing":
+		from += " AND (xn.ketQua IS NULL OR xn.ketQua = '')"
 	}
+	dates, args, ok := dateRangeFilter(c, "xn.ngayXetNghiem", args)
+	if !ok {
+		return
+	}
+	from += dates
 
-	query += " ORDER BY xn.ngayXetNghiem DESC"
+	query, pageArgs, countQuery := labTestList.queries(labTestColumns, from, args, params)
 
-	rows, err := h.db.Query(query, args...)
+	total, ok := countList(c, h.db, countQuery, args, params, "Failed to count lab tests")
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(query, pageArgs...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -91,15 +186,9 @@ func (h *LabTestHandler) GetLabTests(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var labTests []map[string]interface{}
+	var labTests []LabTestResponse
 	for rows.Next() {
-		var maXetNghiem, maHoSo, loaiXetNghiem, ketQua, ghiChu sql.NullString
-		var maCustomer, maBacSi, tenKhachHang, tenBacSi sql.NullString
-		var ngayXetNghiem, ngayKham sql.NullTime
-
-		err := rows.Scan(&maXetNghiem, &maHoSo, &loaiXetNghiem, &ngayXetNghiem,
-			&ketQua, &ghiChu, &maCustomer, &maBacSi, &ngayKham,
-			&tenKhachHang, &tenBacSi)
+		labTest, err := scanLabTest(rows)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -108,79 +197,15 @@ func (h *LabTestHandler) GetLabTests(c *gin.Context) {
 			})
 			return
 		}
-
-		// Determine status based on data
-		testStatus := "ordered"
-		if ketQua.String != "" {
-			testStatus = "completed"
-		} else if ngayXetNghiem.Valid && ngayXetNghiem.Time.Before(time.Now()) {
-			testStatus = "processing"
-		}
-
-		labTest := map[string]interface{}{
-			"ma_xet_nghiem":   maXetNghiem.String,
-			"ma_ho_so":        maHoSo.String,
-			"loai_xet_nghiem": loaiXetNghiem.String,
-			"ngay_xet_nghiem": ngayXetNghiem.Time,
-			"ket_qua":         ketQua.String,
-			"ghi_chu":         ghiChu.String,
-			"ma_customer":     maCustomer.String,
-			"ma_bac_si":       maBacSi.String,
-			"ten_khach_hang":  tenKhachHang.String,
-			"ten_bac_si":      tenBacSi.String,
-			"status":          testStatus,
-			"ngay_kham":       ngayKham.Time,
-		}
-
 		labTests = append(labTests, labTest)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Lab tests retrieved successfully",
-		Data:    labTests,
-	})
+	respondList(c, labTestList, "Lab tests retrieved successfully", labTests, params, total)
 }
 
+// GetLabTest returns a lab test to those who may see the patient.
 func (h *LabTestHandler) GetLabTest(c *gin.Context) {
-	labTestID := c.Param("id")
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-
-	query := `
-		SELECT xn.maXetNghiem, xn.maHoSo, xn.loaiXetNghiem, xn.ngayXetNghiem,
-		       xn.ketQua, xn.ghiChu, xn.fileName,
-		       h.maCustomer, h.maBacSi, h.ngayKham,
-		       uc.hoTen as tenKhachHang, ud.hoTen as tenBacSi
-		FROM XETNGHIEM xn
-		JOIN HOSO h ON xn.maHoSo = h.maHoSo
-		JOIN [USER] uc ON h.maCustomer = uc.userID
-		JOIN [USER] ud ON h.maBacSi = ud.userID
-		WHERE xn.maXetNghiem = ?
-	`
-	args := []interface{}{labTestID}
-
-	// Add role-based filtering
-	if userType.(string) == "CUSTOMER" {
-		query += " AND h.maCustomer = ?"
-		args = append(args, userID)
-	} else if userType.(string) == "DOCTOR" {
-		query += " AND h.maBacSi = ?"
-		args = append(args, userID)
-	}
-
-	var labTest map[string]interface{} = make(map[string]interface{})
-	var maXetNghiem, maHoSo, loaiXetNghiem, ketQua, ghiChu, fileName sql.NullString
-	var maCustomer, maBacSi, tenKhachHang, tenBacSi sql.NullString
-	var ngayXetNghiem, ngayKham sql.NullTime
-
-	err := h.db.QueryRow(query, args...).Scan(
-		&maXetNghiem, &maHoSo, &loaiXetNghiem, &ngayXetNghiem,
-		&ketQua, &ghiChu, &fileName,
-		&maCustomer, &maBacSi, &ngayKham,
-		&tenKhachHang, &tenBacSi,
-	)
-
+	labTest, err := scanLabTest(h.db.QueryRow("SELECT "+labTestColumns+labTestFrom+" WHERE xn.maXetNghiem = @p1", c.Param("id")))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, models.APIResponse{
@@ -197,27 +222,9 @@ func (h *LabTestHandler) GetLabTest(c *gin.Context) {
 		return
 	}
 
-	// Determine status
-	testStatus := "ordered"
-	if ketQua.String != "" {
-		testStatus = "completed"
-	} else if ngayXetNghiem.Valid && ngayXetNghiem.Time.Before(time.Now()) {
-		testStatus = "processing"
-	}
-
-	labTest["ma_xet_nghiem"] = maXetNghiem.String
-	labTest["ma_ho_so"] = maHoSo.String
-	labTest["loai_xet_nghiem"] = loaiXetNghiem.String
-	labTest["ngay_xet_nghiem"] = ngayXetNghiem.Time
-	labTest["ket_qua"] = ketQua.String
-	labTest["ghi_chu"] = ghiChu.String
-	labTest["file_name"] = fileName.String
-	labTest["ma_customer"] = maCustomer.String
-	labTest["ma_bac_si"] = maBacSi.String
-	labTest["ten_khach_hang"] = tenKhachHang.String
-	labTest["ten_bac_si"] = tenBacSi.String
-	labTest["status"] = testStatus
-	labTest["ngay_kham"] = ngayKham.Time
+	if !canViewPatient(c, h.db, labTest.MaCustomer) {
+		return
+	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -251,7 +258,7 @@ func (h *LabTestHandler) CreateLabOrder(c *gin.Context) {
 
 	// Verify the medical record belongs to this doctor
 	var doctorID string
-	err := h.db.QueryRow("SELECT maBacSi FROM HOSO WHERE maHoSo = ?", req.MaHoSo).Scan(&doctorID)
+	err := h.db.QueryRow("SELECT maBacSi FROM HOSO WHERE maHoSo = @p1", req.MaHoSo).Scan(&doctorID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, models.APIResponse{
@@ -277,7 +284,15 @@ func (h *LabTestHandler) CreateLabOrder(c *gin.Context) {
 	}
 
 	// Generate lab test ID
-	labTestID := utils.GenerateLabTestID()
+	labTestID, err := utils.GenerateLabTestID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create lab order",
+			Error:   err.Error(),
+		})
+		return
+	}
 
 	// Parse test date
 	var testDate time.Time
@@ -299,7 +314,7 @@ func (h *LabTestHandler) CreateLabOrder(c *gin.Context) {
 	// Insert lab test order
 	_, err = h.db.Exec(`
 		INSERT INTO XETNGHIEM (maXetNghiem, maHoSo, loaiXetNghiem, ngayXetNghiem, ghiChu)
-		VALUES (?, ?, ?, ?, ?)
+		VALUES (@p1, @p2, @p3, @p4, @p5)
 	`, labTestID, req.MaHoSo, req.LoaiXetNghiem, testDate, req.GhiChu)
 
 	if err != nil {
@@ -314,8 +329,8 @@ func (h *LabTestHandler) CreateLabOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Lab order created successfully",
-		Data: gin.H{
-			"ma_xet_nghiem": labTestID,
+		Data: CreatedLabTestResponse{
+			MaXetNghiem: labTestID,
 		},
 	})
 }
@@ -325,8 +340,8 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userType, _ := c.Get("user_type")
 
-	var updateData map[string]interface{}
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var req UpdateLabTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid request format",
@@ -335,12 +350,20 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 		return
 	}
 
-	// Verify lab test belongs to this doctor (for updates) or lab technician
+	// Only the doctor who ordered the test records its results
+	if userType.(string) != "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only the ordering doctor can update a lab test",
+		})
+		return
+	}
+
 	var doctorID string
 	err := h.db.QueryRow(`
 		SELECT h.maBacSi FROM XETNGHIEM xn
 		JOIN HOSO h ON xn.maHoSo = h.maHoSo
-		WHERE xn.maXetNghiem = ?
+		WHERE xn.maXetNghiem = @p1
 	`, labTestID).Scan(&doctorID)
 
 	if err != nil {
@@ -359,8 +382,7 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 		return
 	}
 
-	// Only doctors can update their own lab orders, or lab technicians can update results
-	if userType.(string) == "DOCTOR" && doctorID != userID.(string) {
+	if doctorID != userID.(string) {
 		c.JSON(http.StatusForbidden, models.APIResponse{
 			Success: false,
 			Message: "You can only update your own lab orders",
@@ -381,8 +403,8 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 	defer tx.Rollback()
 
 	// Update fields
-	if ketQua, exists := updateData["ket_qua"]; exists {
-		_, err = tx.Exec("UPDATE XETNGHIEM SET ketQua = ? WHERE maXetNghiem = ?", ketQua, labTestID)
+	if req.KetQua != nil {
+		_, err = tx.Exec("UPDATE XETNGHIEM SET ketQua = @p1 WHERE maXetNghiem = @p2", clearable(*req.KetQua), labTestID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -393,8 +415,8 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 		}
 	}
 
-	if ghiChu, exists := updateData["ghi_chu"]; exists {
-		_, err = tx.Exec("UPDATE XETNGHIEM SET ghiChu = ? WHERE maXetNghiem = ?", ghiChu, labTestID)
+	if req.GhiChu != nil {
+		_, err = tx.Exec("UPDATE XETNGHIEM SET ghiChu = @p1 WHERE maXetNghiem = @p2", clearable(*req.GhiChu), labTestID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -405,8 +427,8 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 		}
 	}
 
-	if loaiXetNghiem, exists := updateData["loai_xet_nghiem"]; exists {
-		_, err = tx.Exec("UPDATE XETNGHIEM SET loaiXetNghiem = ? WHERE maXetNghiem = ?", loaiXetNghiem, labTestID)
+	if req.LoaiXetNghiem != nil {
+		_, err = tx.Exec("UPDATE XETNGHIEM SET loaiXetNghiem = @p1 WHERE maXetNghiem = @p2", *req.LoaiXetNghiem, labTestID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -417,26 +439,24 @@ func (h *LabTestHandler) UpdateLabTest(c *gin.Context) {
 		}
 	}
 
-	if ngayXetNghiem, exists := updateData["ngay_xet_nghiem"]; exists {
-		if dateStr, ok := ngayXetNghiem.(string); ok {
-			parsedDate, err := time.Parse("2006-01-02", dateStr)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, models.APIResponse{
-					Success: false,
-					Message: "Invalid date format. Use YYYY-MM-DD",
-					Error:   err.Error(),
-				})
-				return
-			}
-			_, err = tx.Exec("UPDATE XETNGHIEM SET ngayXetNghiem = ? WHERE maXetNghiem = ?", parsedDate, labTestID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, models.APIResponse{
-					Success: false,
-					Message: "Failed to update lab test date",
-					Error:   err.Error(),
-				})
-				return
-			}
+	if req.NgayXetNghiem != nil {
+		parsedDate, err := time.Parse("2006-01-02", *req.NgayXetNghiem)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid date format. Use YYYY-MM-DD",
+				Error:   err.Error(),
+			})
+			return
+		}
+		_, err = tx.Exec("UPDATE XETNGHIEM SET ngayXetNghiem = @p1 WHERE maXetNghiem = @p2", parsedDate, labTestID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to update lab test date",
+				Error:   err.Error(),
+			})
+			return
 		}
 	}
 
@@ -475,7 +495,7 @@ func (h *LabTestHandler) DeleteLabTest(c *gin.Context) {
 	err := h.db.QueryRow(`
 		SELECT h.maBacSi FROM XETNGHIEM xn
 		JOIN HOSO h ON xn.maHoSo = h.maHoSo
-		WHERE xn.maXetNghiem = ?
+		WHERE xn.maXetNghiem = @p1
 	`, labTestID).Scan(&doctorID)
 
 	if err != nil {
@@ -503,7 +523,7 @@ func (h *LabTestHandler) DeleteLabTest(c *gin.Context) {
 	}
 
 	// Delete lab test
-	_, err = h.db.Exec("DELETE FROM XETNGHIEM WHERE maXetNghiem = ?", labTestID)
+	_, err = h.db.Exec("DELETE FROM XETNGHIEM WHERE maXetNghiem = @p1", labTestID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -577,4 +597,4 @@ func (h *LabTestHandler) GetLabTestTypes(c *gin.Context) {
 		Message: "Lab test types retrieved successfully",
 		Data:    testTypes,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listSpec describes how a list endpoint sorts and pages its rows. Rows
+// are ordered by the sort key and then by ID, so every row has a stable
+// position for cursors.
+type listSpec struct {
+	sorts       map[string]services.SortKey
+	defaultSort string
+	idField     string
+	idColumn    string
+}
+
+// parse reads the paging and sort parameters and answers 400 if they are
+// invalid.
+func (s listSpec) parse(c *gin.Context) (utils.ListParams, bool) {
+	fields := make([]string, 0, len(s.sorts))
+	for field := range s.sorts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	params, err := utils.ParseListParams(c, fields, s.defaultSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid list parameters",
+			Error:   err.Error(),
+		})
+		return params, false
+	}
+	return params, true
+}
+
+// queries builds the query for one page of the rows selected by columns
+// from from, and the query counting all of them; see services.ListQuery.
+func (s listSpec) queries(columns, from string, args []interface{}, params utils.ListParams) (string, []interface{}, string) {
+	return services.ListQuery{Sorts: s.sorts, IDColumn: s.idColumn}.Build(columns, from, args, params)
+}
+
+// respondList writes one page of items with its pagination metadata.
+// items may hold the extra row fetched by queries.
+func respondList[T any](c *gin.Context, s listSpec, message string, items []T, params utils.ListParams, total int) {
+	hasMore := len(items) > params.PageSize
+	if hasMore {
+		items = items[:params.PageSize]
+	}
+
+	var nextCursor string
+	if hasMore {
+		last := items[len(items)-1]
+		value := services.SortValue(s.sorts, params.SortField, last)
+		nextCursor = utils.EncodeCursor(params.Sort, services.CursorValue(value, s.sorts[params.SortField].Kind),
+			fmt.Sprint(services.JSONField(last, s.idField)))
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+	utils.PaginatedResponse(c, message, items, utils.NewPagination(params, hasMore, nextCursor, total))
+}
+
+// parseDateRange reads the dates date_from to date_to (YYYY-MM-DD, both
+// inclusive) and answers 400 if either is invalid.
+func parseDateRange(c *gin.Context) (services.DateRange, bool) {
+	var dates services.DateRange
+	for _, bound := range []struct {
+		param string
+		date  *time.Time
+	}{{"date_from", &dates.From}, {"date_to", &dates.To}} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid %s. Use YYYY-MM-DD", bound.param),
+			})
+			return dates, false
+		}
+		*bound.date = date
+	}
+	return dates, true
+}
+
+// dateRangeFilter restricts column to the dates date_from to date_to and
+// answers 400 if either is invalid.
+func dateRangeFilter(c *gin.Context, column string, args []interface{}) (string, []interface{}, bool) {
+	dates, ok := parseDateRange(c)
+	if !ok {
+		return "", args, false
+	}
+	clause, args := dates.Where(column, args)
+	return clause, args, true
+}
+
+// countList runs the count query of a list in page mode; cursor mode
+// reports no total and skips it.
+func countList(c *gin.Context, q queryRower, query string, args []interface{}, params utils.ListParams, message string) (int, bool) {
+	var total int
+	if params.Cursor != nil {
+		return total, true
+	}
+	if err := q.QueryRow(query, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error:   err.Error(),
+		})
+		return total, false
+	}
+	return total, true
+}
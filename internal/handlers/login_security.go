@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/notification"
+	"clinic-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginSecuritySettings configure brute-force protection in Login.
+//
+// An IP with IPMaxFailures failed logins within IPWindow is refused until
+// the oldest of them leaves the window. An account that fails
+// DelayAfterFailures times in a row must wait 1, 2, 4, ... seconds between
+// attempts, and after LockAfterFailures it is locked for LockDuration,
+// doubling with each further failure. A successful login or an unlock by
+// an operation manager clears the counter.
+type LoginSecuritySettings struct {
+	IPMaxFailures      int
+	IPWindow           time.Duration
+	DelayAfterFailures int
+	LockAfterFailures  int
+	LockDuration       time.Duration
+}
+
+const (
+	maxLoginDelay    = time.Minute
+	maxLockDuration  = 24 * time.Hour
+	userAgentMaxSize = 500
+)
+
+// Outcomes recorded in LICHSUDANGNHAP.ketQua.
+const (
+	loginSuccess     = "SUCCESS"
+	loginBadPassword = "BAD_PASSWORD"
+	loginUnknownUser = "UNKNOWN_USER"
+	loginLocked      = "LOCKED"
+	loginThrottled   = "THROTTLED"
+	loginIPBlocked   = "IP_BLOCKED"
+	loginBadOTP      = "BAD_OTP"
+	login2FARequired = "2FA_REQUIRED"
+)
+
+type loginAccountState struct {
+	failures    int
+	lastFailure sql.NullTime
+	lockedUntil sql.NullTime
+}
+
+// recordLoginAttempt writes an attempt to the login history. Failures are
+// logged rather than returned so they never block a login.
+func (h *AuthHandler) recordLoginAttempt(c *gin.Context, username string, userID interface{}, outcome string) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > userAgentMaxSize {
+		userAgent = userAgent[:userAgentMaxSize]
+	}
+	_, err := h.db.Exec(`
+		INSERT INTO LICHSUDANGNHAP (tenDangNhap, maUser, diaChiIP, userAgent, ketQua, thoiGian)
+		VALUES (@p1, @p2, @p3, @p4, @p5, GETDATE())
+	`, username, userID, c.ClientIP(), services.NullIfEmpty(userAgent), outcome)
+	if err != nil {
+		log.Printf("login attempt for %s not recorded: %v", username, err)
+	}
+}
+
+// ipRetryAfter returns how long the client's IP must wait, or 0 when it
+// is under the failure limit.
+func (h *AuthHandler) ipRetryAfter(c *gin.Context) (time.Duration, error) {
+	if h.login.IPMaxFailures <= 0 {
+		return 0, nil
+	}
+
+	windowStart := time.Now().Add(-h.login.IPWindow)
+	var failures int
+	var oldest sql.NullTime
+	err := h.db.QueryRow(`
+		SELECT COUNT(*), MIN(thoiGian) FROM LICHSUDANGNHAP
+		WHERE diaChiIP = @p1 AND ketQua IN ('BAD_PASSWORD', 'UNKNOWN_USER', 'BAD_OTP') AND thoiGian > @p2
+	`, c.ClientIP(), windowStart).Scan(&failures, &oldest)
+	if err != nil || failures < h.login.IPMaxFailures || !oldest.Valid {
+		return 0, err
+	}
+	return oldest.Time.Sub(windowStart), nil
+}
+
+// accountRetryAfter returns how long the account must wait before the
+// next attempt and whether that is because it is locked.
+func (h *AuthHandler) accountRetryAfter(state loginAccountState) (time.Duration, bool) {
+	now := time.Now()
+	if state.lockedUntil.Valid && state.lockedUntil.Time.After(now) {
+		return state.lockedUntil.Time.Sub(now), true
+	}
+	if h.login.DelayAfterFailures <= 0 || state.failures < h.login.DelayAfterFailures || !state.lastFailure.Valid {
+		return 0, false
+	}
+
+	delay := maxLoginDelay
+	if shift := state.failures - h.login.DelayAfterFailures; shift < 6 {
+		delay = time.Second << uint(shift)
+	}
+	if delay > maxLoginDelay {
+		delay = maxLoginDelay
+	}
+	if wait := state.lastFailure.Time.Add(delay).Sub(now); wait > 0 {
+		return wait, false
+	}
+	return 0, false
+}
+
+// registerLoginFailure counts a wrong password and locks the account once
+// the limit is reached. The owner is told when their account gets locked.
+func (h *AuthHandler) registerLoginFailure(c *gin.Context, userID string) error {
+	var failures int
+	err := h.db.QueryRow(`
+		UPDATE [USER] SET soLanSaiLienTiep = soLanSaiLienTiep + 1, lanSaiCuoi = GETDATE()
+		OUTPUT INSERTED.soLanSaiLienTiep
+		WHERE userID = @p1
+	`, userID).Scan(&failures)
+	if err != nil {
+		return err
+	}
+	if h.login.LockAfterFailures <= 0 || failures < h.login.LockAfterFailures {
+		return nil
+	}
+
+	lockFor := maxLockDuration
+	if shift := failures - h.login.LockAfterFailures; shift < 10 {
+		lockFor = h.login.LockDuration << uint(shift)
+	}
+	if lockFor > maxLockDuration {
+		lockFor = maxLockDuration
+	}
+	lockedUntil := time.Now().Add(lockFor)
+	if _, err := h.db.Exec("UPDATE [USER] SET khoaDenLuc = @p1 WHERE userID = @p2", lockedUntil, userID); err != nil {
+		return err
+	}
+
+	err = h.notifier.Notify(c.Request.Context(), notification.Message{
+		UserID:   userID,
+		Template: notification.TemplateAccountLocked,
+		Data: map[string]string{
+			"so_lan":     strconv.Itoa(failures),
+			"dia_chi_ip": c.ClientIP(),
+			"khoa_den":   lockedUntil.Format("15:04 02/01/2006"),
+		},
+		Reference: userID,
+		Channels:  []string{notification.ChannelEmail, notification.ChannelSMS},
+	})
+	if err != nil && err != notification.ErrNoChannel {
+		log.Printf("account lock notice for %s: %v", userID, err)
+	}
+	return nil
+}
+
+func (h *AuthHandler) clearLoginFailures(userID string) error {
+	_, err := h.db.Exec(`
+		UPDATE [USER] SET soLanSaiLienTiep = 0, lanSaiCuoi = NULL, khoaDenLuc = NULL
+		WHERE userID = @p1 AND (soLanSaiLienTiep > 0 OR khoaDenLuc IS NOT NULL)
+	`, userID)
+	return err
+}
+
+func tooManyLoginAttempts(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+	c.JSON(http.StatusTooManyRequests, models.APIResponse{
+		Success: false,
+		Message: message,
+	})
+}
+
+// LoginSecurityHandler lets operation managers review login attempts and
+// unlock accounts.
+type LoginSecurityHandler struct {
+	db *sql.DB
+}
+
+func NewLoginSecurityHandler(db *sql.DB) *LoginSecurityHandler {
+	return &LoginSecurityHandler{db: db}
+}
+
+func (h *LoginSecurityHandler) UnlockAccount(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can unlock accounts") {
+		return
+	}
+
+	result, err := h.db.Exec(`
+		UPDATE [USER] SET soLanSaiLienTiep = 0, lanSaiCuoi = NULL, khoaDenLuc = NULL
+		WHERE userID = @p1
+	`, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to unlock account",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "User not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Account unlocked successfully",
+	})
+}
+
+var loginAttemptList = listSpec{
+	sorts: map[string]services.SortKey{
+		"thoi_gian": {Expr: "thoiGian", Kind: services.SortTime},
+	},
+	defaultSort: "-thoi_gian",
+	idField:     "ma_lich_su",
+	idColumn:    "maLichSu",
+}
+
+// GetLoginAttempts lists attempts a page at a time, newest first,
+// optionally filtered by ma_user, ten_dang_nhap, dia_chi_ip, ket_qua and
+// a date_from/date_to range.
+func (h *LoginSecurityHandler) GetLoginAttempts(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can view login attempts") {
+		return
+	}
+
+	params, ok := loginAttemptList.parse(c)
+	if !ok {
+		return
+	}
+
+	from := " FROM LICHSUDANGNHAP WHERE 1=1"
+	var args []interface{}
+	filters := []struct{ param, column string }{
+		{"ma_user", "maUser"},
+		{"ten_dang_nhap", "tenDangNhap"},
+		{"dia_chi_ip", "diaChiIP"},
+		{"ket_qua", "ketQua"},
+	}
+	for _, f := range filters {
+		if value := c.Query(f.param); value != "" {
+			args = append(args, value)
+			from += fmt.Sprintf(" AND %s = @p%d", f.column, len(args))
+		}
+	}
+	dates, args, ok := dateRangeFilter(c, "thoiGian", args)
+	if !ok {
+		return
+	}
+	from += dates
+
+	query, pageArgs, countQuery := loginAttemptList.queries(
+		"maLichSu, tenDangNhap, maUser, diaChiIP, userAgent, ketQua, thoiGian", from, args, params)
+
+	total, ok := countList(c, h.db, countQuery, args, params, "Error counting login attempts")
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(query, pageArgs...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving login attempts",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	attempts := []LoginAttemptResponse{}
+	for rows.Next() {
+		var a LoginAttemptResponse
+		var userID, userAgent sql.NullString
+		if err := rows.Scan(&a.MaLichSu, &a.TenDangNhap, &userID, &a.DiaChiIP, &userAgent, &a.KetQua, &a.ThoiGian); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Error scanning login attempt",
+				Error:   err.Error(),
+			})
+			return
+		}
+		a.MaUser = stringPointer(userID)
+		a.UserAgent = stringPointer(userAgent)
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving login attempts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	respondList(c, loginAttemptList, "Login attempts retrieved successfully", attempts, params, total)
+}
+
+func isOperationManager(c *gin.Context, message string) bool {
+	userType, _ := c.Get("user_type")
+	if userType.(string) != "OPERATION_MANAGER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: message,
+		})
+		return false
+	}
+	return true
+}
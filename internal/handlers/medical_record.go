@@ -3,311 +3,218 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"clinic-management/internal/models"
+	"clinic-management/internal/services"
 	"clinic-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type MedicalRecordHandler struct {
-	db *sql.DB
+	db         *sql.DB
+	records    *services.MedicalRecordService
+	lockWindow time.Duration
 }
 
-func NewMedicalRecordHandler(db *sql.DB) *MedicalRecordHandler {
-	return &MedicalRecordHandler{db: db}
+// NewMedicalRecordHandler creates the handler. Records become read-only
+// lockWindow after the visit; later changes must be added as addenda.
+func NewMedicalRecordHandler(db *sql.DB, lockWindow time.Duration) *MedicalRecordHandler {
+	return &MedicalRecordHandler{
+		db:         db,
+		records:    services.NewMedicalRecordService(services.NewSQLMedicalRecordRepository(db), services.NewSQLUserRepository(db), lockWindow),
+		lockWindow: lockWindow,
+	}
+}
+
+var medicalRecordList = listSpec{
+	sorts:       services.MedicalRecordSorts,
+	defaultSort: "-ngay_kham",
+	idField:     "ma_ho_so",
+}
+
+// CreateMedicalRecordRequest is the body of POST /medical-records.
+type CreateMedicalRecordRequest struct {
+	MaCustomer      string   `json:"ma_customer" binding:"required"`
+	MaPhongKham     string   `json:"ma_phong_kham" binding:"required"`
+	TrieuChung      *string  `json:"trieu_chung"`
+	ChanDoan        *string  `json:"chan_doan"`
+	HuongDanDieuTri *string  `json:"huong_dan_dieu_tri"`
+	MaICD10         *string  `json:"ma_icd10"`
+	MaICD10Phu      []string `json:"ma_icd10_phu"`
+	NgayTaiKham     *string  `json:"ngay_tai_kham"`
+}
+
+// UpdateMedicalRecordRequest amends a record. Omitted fields are kept; an
+// empty string or list clears the field. LyDo is stored with the version.
+type UpdateMedicalRecordRequest struct {
+	TrieuChung      *string   `json:"trieu_chung"`
+	ChanDoan        *string   `json:"chan_doan"`
+	HuongDanDieuTri *string   `json:"huong_dan_dieu_tri"`
+	MaICD10         *string   `json:"ma_icd10"`
+	MaICD10Phu      *[]string `json:"ma_icd10_phu"`
+	NgayTaiKham     *string   `json:"ngay_tai_kham"`
+	LyDo            string    `json:"ly_do"`
 }
 
+// GetMedicalRecords lists medical records one page at a time, limited to
+// the patients the caller may see as in GetPatientSummary; filters are
+// customer_id, doctor_id, clinic_id, icd10 (code prefix) and
+// date_from/date_to on the visit date.
 func (h *MedicalRecordHandler) GetMedicalRecords(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-	customerID := c.Query("customer_id")
-
-	var query string
-	var args []interface{}
-
-	switch userType.(string) {
-	case "CUSTOMER":
-		query = `
-			SELECT h.MaHoSo, h.maCustomer, h.MaBacSi, h.MaPhongKham, 
-			       h.NgayKham, h.TrieuChung, h.ChanDoan, h.Huongdan, 
-			       h.MaICD10, h.NgayTaiKham,
-			       u.HoTen as TenBacSi, p.TenPhongKham
-			FROM HOSO h
-			JOIN [USER] u ON h.MaBacSi = u.userId
-			JOIN PHONGKHAM p ON h.MaPhongKham = p.MaPhongKham
-			WHERE h.MaCustomer = @p1
-			ORDER BY h.NgayKham DESC
-		`
-		args = append(args, userID)
-
-	case "DOCTOR":
-		query = `
-			SELECT h.MaHoSo, h.MaCustomer, h.MaBacSi, h.MaPhongKham, 
-			       h.NgayKham, h.TrieuChung, h.ChanDoan, h.huongdan, 
-			       h.MaICD10, h.NgayTaiKham,
-			       u.HoTen as TenKhachHang, p.TenPhongKham
-			FROM HOSO h
-			JOIN [USER] u ON h.MaCustomer = u.userID
-			JOIN PHONGKHAM p ON h.MaPhongKham = p.MaPhongKham
-			WHERE h.MaBacSi = @p1
-		`
-		args = append(args, userID)
-
-		if customerID != "" {
-			query += " AND h.MaCustomer = @p2"
-			args = append(args, customerID)
-		}
+	params, ok := medicalRecordList.parse(c)
+	if !ok {
+		return
+	}
+	dates, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
 
-		query += " ORDER BY h.NgayKham DESC"
-
-	default:
-		query = `
-			SELECT h.MaHoSo, h.MaCustomer, h.MaBacSi, h.MaPhongKham, 
-			       h.NgayKham, h.TrieuChung, h.ChanDoan, h.huongdan, 
-			       h.MaICD10, h.NgayTaiKham,
-			       uc.HoTen as TenKhachHang, ud.HoTen as TenBacSi, p.TenPhongKham
-			FROM HOSO h
-			JOIN [USER] uc ON h.MaCustomer = uc.userID
-			JOIN [USER] ud ON h.MaBacSi = ud.userID
-			JOIN PHONGKHAM p ON h.MaPhongKham = p.MaPhongKham
-			WHERE 1=1
-		`
-
-		if customerID != "" {
-			query += " AND h.MaCustomer = @p1"
-			args = append(args, customerID)
-		}
+	records, total, err := h.records.List(viewerOf(c), services.MedicalRecordFilter{
+		CustomerID: c.Query("customer_id"),
+		DoctorID:   c.Query("doctor_id"),
+		ClinicID:   c.Query("clinic_id"),
+		ICD10:      c.Query("icd10"),
+		Dates:      dates,
+	}, params)
+	if err != nil {
+		serviceFailed(c, err, "Failed to retrieve medical records")
+		return
+	}
 
-		query += " ORDER BY h.NgayKham DESC"
+	respondList(c, medicalRecordList, "Medical records retrieved successfully", records, params, total)
+}
+
+func (h *MedicalRecordHandler) GetMedicalRecord(c *gin.Context) {
+	recordID := c.Param("id")
+
+	detail, err := h.records.Get(viewerOf(c), recordID)
+	if err != nil {
+		serviceFailed(c, err, "Failed to retrieve medical record")
+		return
 	}
 
-	rows, err := h.db.Query(query, args...)
+	record := MedicalRecordResponse{
+		MaHoSo:          detail.MaHoSo,
+		MaCustomer:      detail.MaCustomer,
+		MaBacSi:         detail.MaBacSi,
+		MaPhongKham:     detail.MaPhongKham,
+		NgayKham:        detail.NgayKham,
+		TrieuChung:      detail.TrieuChung,
+		ChanDoan:        detail.ChanDoan,
+		HuongDanDieuTri: detail.HuongDanDieuTri,
+		MaICD10:         detail.MaICD10,
+		NgayTaiKham:     detail.NgayTaiKham,
+		TenKhachHang:    detail.TenKhachHang,
+		TenBacSi:        detail.TenBacSi,
+		TenPhongKham:    detail.TenPhongKham,
+	}
+
+	record.MaICD10Phu, err = h.describeICD10Codes(detail.MaICD10Phu)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve medical records",
+			Message: "Failed to retrieve secondary diagnoses",
 			Error:   err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
 
-	var records []map[string]interface{}
-	for rows.Next() {
-		record := make(map[string]interface{})
-		var maHoSo, maCustomer, maBacSi, maPhongKham string
-		var ngayKham interface{}
-		var trieuChung, chanDoan, huongDanDieuTri, maICD10, ngayTaiKham interface{}
-		var tenPerson, tenPhongKham interface{}
-
-		if userType.(string) == "CUSTOMER" || userType.(string) == "DOCTOR" {
-			err := rows.Scan(&maHoSo, &maCustomer, &maBacSi, &maPhongKham,
-				&ngayKham, &trieuChung, &chanDoan, &huongDanDieuTri, &maICD10, &ngayTaiKham,
-				&tenPerson, &tenPhongKham)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, models.APIResponse{
+	state, err := loadRecordState(h.db, recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve medical record state",
+			Error:   err.Error(),
+		})
+		return
+	}
+	record.PhienBan = state.PhienBan
+	record.PhienBanHienTai = state.PhienBan
+	record.DaKhoa = state.isLocked(h.lockWindow)
+	record.DaKy = state.DaKy
+	record.NgayKy = state.NgayKy
+	record.NguoiKy = state.NguoiKy
+
+	if versionParam := c.Query("version"); versionParam != "" {
+		version, err := strconv.Atoi(versionParam)
+		if err != nil || version < 1 {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "version must be a positive integer",
+			})
+			return
+		}
+
+		if err := h.applyRecordVersion(&record, version); err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, models.APIResponse{
 					Success: false,
-					Message: "Failed to scan medical record data",
-					Error:   err.Error(),
+					Message: "Medical record version not found",
 				})
-				return
-			}
-		} else {
-			var tenKhachHang, tenBacSi interface{}
-			err := rows.Scan(&maHoSo, &maCustomer, &maBacSi, &maPhongKham,
-				&ngayKham, &trieuChung, &chanDoan, &huongDanDieuTri, &maICD10, &ngayTaiKham,
-				&tenKhachHang, &tenBacSi, &tenPhongKham)
-			if err != nil {
+			} else {
 				c.JSON(http.StatusInternalServerError, models.APIResponse{
 					Success: false,
-					Message: "Failed to scan medical record data",
+					Message: "Failed to retrieve medical record version",
 					Error:   err.Error(),
 				})
-				return
 			}
-			record["ten_khach_hang"] = tenKhachHang
-			record["ten_bac_si"] = tenBacSi
-		}
-
-		record["ma_ho_so"] = maHoSo
-		record["ma_customer"] = maCustomer
-		record["ma_bac_si"] = maBacSi
-		record["ma_phong_kham"] = maPhongKham
-		record["ngay_kham"] = ngayKham
-		record["trieu_chung"] = trieuChung
-		record["chan_doan"] = chanDoan
-		record["huong_dan_dieu_tri"] = huongDanDieuTri
-		record["ma_icd10"] = maICD10
-		record["ngay_tai_kham"] = ngayTaiKham
-		record["ten_phong_kham"] = tenPhongKham
-
-		if userType.(string) == "CUSTOMER" {
-			record["ten_bac_si"] = tenPerson
-		} else if userType.(string) == "DOCTOR" {
-			record["ten_khach_hang"] = tenPerson
+			return
 		}
-
-		records = append(records, record)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Medical records retrieved successfully",
-		Data:    records,
-	})
-}
+	record.BoSung, err = h.getAddenda(recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve addenda",
+			Error:   err.Error(),
+		})
+		return
+	}
 
-func (h *MedicalRecordHandler) GetMedicalRecord(c *gin.Context) {
-	recordID := c.Param("id")
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-
-	query := `
-		SELECT h.MaHoSo, h.MaCustomer, h.MaBacSi, h.MaPhongKham, 
-		       h.NgayKham, h.TrieuChung, h.ChanDoan, h.HuongDanDieuTri, 
-		       h.MaICD10, h.NgayTaiKham,
-		       uc.HoTen as TenKhachHang, ud.HoTen as TenBacSi, p.TenPhongKham
-		FROM HOSO h
-		JOIN [USER] uc ON h.MaCustomer = uc.MaUser
-		JOIN [USER] ud ON h.MaBacSi = ud.MaUser
-		JOIN PHONGKHAM p ON h.MaPhongKham = p.MaPhongKham
-		WHERE h.MaHoSo = @p1
-	`
-	args := []interface{}{recordID}
-
-	if userType.(string) == "CUSTOMER" {
-		query += " AND h.MaCustomer = @p1"
-		args = append(args, userID)
-	} else if userType.(string) == "DOCTOR" {
-		query += " AND h.MaBacSi = @p1"
-		args = append(args, userID)
-	}
-
-	var record map[string]interface{} = make(map[string]interface{})
-	var maHoSo, maCustomer, maBacSi, maPhongKham string
-	var ngayKham interface{}
-	var trieuChung, chanDoan, huongDanDieuTri, maICD10, ngayTaiKham interface{}
-	var tenKhachHang, tenBacSi, tenPhongKham interface{}
-
-	err := h.db.QueryRow(query, args...).Scan(
-		&maHoSo, &maCustomer, &maBacSi, &maPhongKham,
-		&ngayKham, &trieuChung, &chanDoan, &huongDanDieuTri, &maICD10, &ngayTaiKham,
-		&tenKhachHang, &tenBacSi, &tenPhongKham,
-	)
+	record.DiUng, err = getPatientAllergies(h.db, record.MaCustomer, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve patient allergies",
+			Error:   err.Error(),
+		})
+		return
+	}
 
+	record.BenhManTinh, err = getPatientConditions(h.db, record.MaCustomer, false)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, models.APIResponse{
-				Success: false,
-				Message: "Medical record not found",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to retrieve medical record",
-				Error:   err.Error(),
-			})
-		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve chronic conditions",
+			Error:   err.Error(),
+		})
 		return
 	}
 
-	record["ma_ho_so"] = maHoSo
-	record["ma_customer"] = maCustomer
-	record["ma_bac_si"] = maBacSi
-	record["ma_phong_kham"] = maPhongKham
-	record["ngay_kham"] = ngayKham
-	record["trieu_chung"] = trieuChung
-	record["chan_doan"] = chanDoan
-	record["huong_dan_dieu_tri"] = huongDanDieuTri
-	record["ma_icd10"] = maICD10
-	record["ngay_tai_kham"] = ngayTaiKham
-	record["ten_khach_hang"] = tenKhachHang
-	record["ten_bac_si"] = tenBacSi
-	record["ten_phong_kham"] = tenPhongKham
-
-	prescriptionsQuery := `
-		SELECT d.MaDonThuoc, d.NgayKeDon, d.GhiChu
-		FROM DONTHUOC d
-		WHERE d.MaHoSo = @p1
-	`
-
-	prescRows, err := h.db.Query(prescriptionsQuery, recordID)
-	if err == nil {
-		defer prescRows.Close()
-		var prescriptions []map[string]interface{}
-
-		for prescRows.Next() {
-			var prescription map[string]interface{} = make(map[string]interface{})
-			var maDonThuoc, ngayKeDon, ghiChu interface{}
-
-			err := prescRows.Scan(&maDonThuoc, &ngayKeDon, &ghiChu)
-			if err == nil {
-				prescription["ma_don_thuoc"] = maDonThuoc
-				prescription["ngay_ke_don"] = ngayKeDon
-				prescription["ghi_chu"] = ghiChu
-
-				medicinesQuery := `
-					SELECT t.TenThuoc, ct.SoLuong, ct.CachDung, ct.GhiChu
-					FROM CHITIETDONTHUOC ct
-					JOIN THUOC t ON ct.MaThuoc = t.MaThuoc
-					WHERE ct.MaDonThuoc = @p1
-				`
-
-				medRows, medErr := h.db.Query(medicinesQuery, maDonThuoc)
-				if medErr == nil {
-					defer medRows.Close()
-					var medicines []map[string]interface{}
-
-					for medRows.Next() {
-						var medicine map[string]interface{} = make(map[string]interface{})
-						var tenThuoc, soLuong, cachDung, ghiChuThuoc interface{}
-
-						err := medRows.Scan(&tenThuoc, &soLuong, &cachDung, &ghiChuThuoc)
-						if err == nil {
-							medicine["ten_thuoc"] = tenThuoc
-							medicine["so_luong"] = soLuong
-							medicine["cach_dung"] = cachDung
-							medicine["ghi_chu"] = ghiChuThuoc
-							medicines = append(medicines, medicine)
-						}
-					}
-					prescription["thuoc"] = medicines
-				}
-
-				prescriptions = append(prescriptions, prescription)
-			}
-		}
-		record["don_thuoc"] = prescriptions
+	record.DonThuoc, err = h.getRecordPrescriptions(recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve prescriptions",
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	testResultsQuery := `
-		SELECT MaXetNghiem, LoaiXetNghiem, NgayXetNghiem, KetQua, GhiChu, FileDinhKem
-		FROM XETNGHIEM
-		WHERE MaHoSo = @p1
-	`
-
-	testRows, err := h.db.Query(testResultsQuery, recordID)
-	if err == nil {
-		defer testRows.Close()
-		var testResults []map[string]interface{}
-
-		for testRows.Next() {
-			var testResult map[string]interface{} = make(map[string]interface{})
-			var maXetNghiem, loaiXetNghiem, ngayXetNghiem, ketQua, ghiChu, fileDinhKem interface{}
-
-			err := testRows.Scan(&maXetNghiem, &loaiXetNghiem, &ngayXetNghiem, &ketQua, &ghiChu, &fileDinhKem)
-			if err == nil {
-				testResult["ma_xet_nghiem"] = maXetNghiem
-				testResult["loai_xet_nghiem"] = loaiXetNghiem
-				testResult["ngay_xet_nghiem"] = ngayXetNghiem
-				testResult["ket_qua"] = ketQua
-				testResult["ghi_chu"] = ghiChu
-				testResult["file_dinh_kem"] = fileDinhKem
-				testResults = append(testResults, testResult)
-			}
-		}
-		record["ket_qua_xet_nghiem"] = testResults
+	record.KetQuaXetNghiem, err = h.getRecordLabTests(recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve lab tests",
+			Error:   err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -317,28 +224,103 @@ func (h *MedicalRecordHandler) GetMedicalRecord(c *gin.Context) {
 	})
 }
 
-func (h *MedicalRecordHandler) CreateMedicalRecord(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
+// getRecordPrescriptions lists the prescriptions written on a record with
+// their medicines.
+func (h *MedicalRecordHandler) getRecordPrescriptions(recordID string) ([]RecordPrescriptionResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT maDonThuoc, ngayKeDon, ghiChu
+		FROM DONTHUOC
+		WHERE maHoSo = @p1
+		ORDER BY ngayKeDon
+	`, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	if userType.(string) != "DOCTOR" {
-		c.JSON(http.StatusForbidden, models.APIResponse{
-			Success: false,
-			Message: "Only doctors can create medical records",
-		})
-		return
+	prescriptions := []RecordPrescriptionResponse{}
+	for rows.Next() {
+		var p RecordPrescriptionResponse
+		var ngayKeDon sql.NullTime
+		var ghiChu sql.NullString
+		if err := rows.Scan(&p.MaDonThuoc, &ngayKeDon, &ghiChu); err != nil {
+			return nil, err
+		}
+		p.NgayKeDon = timePointer(ngayKeDon)
+		p.GhiChu = ghiChu.String
+		prescriptions = append(prescriptions, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range prescriptions {
+		if prescriptions[i].Thuoc, err = h.getRecordPrescriptionItems(prescriptions[i].MaDonThuoc); err != nil {
+			return nil, err
+		}
+	}
+	return prescriptions, nil
+}
+
+func (h *MedicalRecordHandler) getRecordPrescriptionItems(prescriptionID string) ([]RecordPrescriptionItemResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT t.tenThuoc, ct.soLuong, ct.cachDung, ct.ghiChu
+		FROM CHITIETDONTHUOC ct
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE ct.maDonThuoc = @p1
+	`, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []RecordPrescriptionItemResponse{}
+	for rows.Next() {
+		var item RecordPrescriptionItemResponse
+		var soLuong sql.NullInt32
+		var cachDung, ghiChu sql.NullString
+		if err := rows.Scan(&item.TenThuoc, &soLuong, &cachDung, &ghiChu); err != nil {
+			return nil, err
+		}
+		item.SoLuong = int(soLuong.Int32)
+		item.CachDung = cachDung.String
+		item.GhiChu = ghiChu.String
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
 
-	var req struct {
-		MaCustomer      string  `json:"ma_customer" binding:"required"`
-		MaPhongKham     string  `json:"ma_phong_kham" binding:"required"`
-		TrieuChung      *string `json:"trieu_chung"`
-		ChanDoan        *string `json:"chan_doan"`
-		HuongDanDieuTri *string `json:"huong_dan_dieu_tri"`
-		MaICD10         *string `json:"ma_icd10"`
-		NgayTaiKham     *string `json:"ngay_tai_kham"`
+func (h *MedicalRecordHandler) getRecordLabTests(recordID string) ([]RecordLabTestResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT maXetNghiem, loaiXetNghiem, ngayXetNghiem, ketQua, ghiChu, fileDinhKem
+		FROM XETNGHIEM
+		WHERE maHoSo = @p1
+	`, recordID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
+	tests := []RecordLabTestResponse{}
+	for rows.Next() {
+		var t RecordLabTestResponse
+		var ngayXetNghiem sql.NullTime
+		var loaiXetNghiem, ketQua, ghiChu, fileDinhKem sql.NullString
+		if err := rows.Scan(&t.MaXetNghiem, &loaiXetNghiem, &ngayXetNghiem, &ketQua, &ghiChu, &fileDinhKem); err != nil {
+			return nil, err
+		}
+		t.LoaiXetNghiem = loaiXetNghiem.String
+		t.NgayXetNghiem = timePointer(ngayXetNghiem)
+		t.KetQua = ketQua.String
+		t.GhiChu = ghiChu.String
+		t.FileDinhKem = fileDinhKem.String
+		tests = append(tests, t)
+	}
+	return tests, rows.Err()
+}
+
+func (h *MedicalRecordHandler) CreateMedicalRecord(c *gin.Context) {
+	var req CreateMedicalRecordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
@@ -348,75 +330,55 @@ func (h *MedicalRecordHandler) CreateMedicalRecord(c *gin.Context) {
 		return
 	}
 
-	recordID := utils.GenerateMedicalRecordID()
+	if req.MaICD10 != nil && strings.TrimSpace(*req.MaICD10) == "" {
+		req.MaICD10 = nil
+	}
+
+	if req.MaICD10 != nil {
+		if !validateICD10Codes(c, h.db, []string{*req.MaICD10}) {
+			return
+		}
+		*req.MaICD10 = utils.NormalizeICD10Code(*req.MaICD10)
+	}
+	if !validateICD10Codes(c, h.db, req.MaICD10Phu) {
+		return
+	}
 
-	_, err := h.db.Exec(`
-		INSERT INTO HOSO (MaHoSo, MaCustomer, MaBacSi, MaPhongKham, NgayKham, 
-		                      TrieuChung, ChanDoan, HuongDanDieuTri, MaICD10, NgayTaiKham)
-		VALUES (?, ?, ?, ?, GETDATE(), ?, ?, ?, ?, ?)
-	`, recordID, req.MaCustomer, userID, req.MaPhongKham,
-		req.TrieuChung, req.ChanDoan, req.HuongDanDieuTri, req.MaICD10, req.NgayTaiKham)
+	var ngayTaiKham *time.Time
+	if req.NgayTaiKham != nil {
+		var ok bool
+		if ngayTaiKham, ok = parseFollowUpDate(c, *req.NgayTaiKham); !ok {
+			return
+		}
+	}
 
+	recordID, err := h.records.Create(viewerOf(c), models.MedicalRecord{
+		MaCustomer:      req.MaCustomer,
+		MaPhongKham:     req.MaPhongKham,
+		TrieuChung:      req.TrieuChung,
+		ChanDoan:        req.ChanDoan,
+		HuongDanDieuTri: req.HuongDanDieuTri,
+		MaICD10:         req.MaICD10,
+		MaICD10Phu:      req.MaICD10Phu,
+		NgayTaiKham:     ngayTaiKham,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to create medical record",
-			Error:   err.Error(),
-		})
+		serviceFailed(c, err, "Failed to create medical record")
 		return
 	}
 
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Medical record created successfully",
-		Data: gin.H{
-			"record_id": recordID,
+		Data: CreatedMedicalRecordResponse{
+			RecordID: recordID,
 		},
 	})
 }
 
 func (h *MedicalRecordHandler) UpdateMedicalRecord(c *gin.Context) {
-	recordID := c.Param("id")
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-
-	if userType.(string) != "DOCTOR" {
-		c.JSON(http.StatusForbidden, models.APIResponse{
-			Success: false,
-			Message: "Only doctors can update medical records",
-		})
-		return
-	}
-
-	var currentDoctorID string
-	err := h.db.QueryRow("SELECT MaBacSi FROM HOSO WHERE MaHoSo = @p1", recordID).Scan(&currentDoctorID)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, models.APIResponse{
-				Success: false,
-				Message: "Medical record not found",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to find medical record",
-				Error:   err.Error(),
-			})
-		}
-		return
-	}
-
-	if currentDoctorID != userID.(string) {
-		c.JSON(http.StatusForbidden, models.APIResponse{
-			Success: false,
-			Message: "You can only update your own medical records",
-		})
-		return
-	}
-
-	var updateData map[string]interface{}
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var req UpdateMedicalRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid request format",
@@ -425,88 +387,82 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(c *gin.Context) {
 		return
 	}
 
-	tx, err := h.db.Begin()
+	viewer := viewerOf(c)
+	current, version, err := h.records.Editable(viewer, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to begin transaction",
-			Error:   err.Error(),
-		})
+		serviceFailed(c, err, "Failed to find medical record")
 		return
 	}
-	defer tx.Rollback()
+	next := current.MedicalRecord
 
-	if trieuChung, exists := updateData["trieu_chung"]; exists {
-		_, err = tx.Exec("UPDATE HOSO SET TrieuChung = @p1 WHERE MaHoSo = @p1", trieuChung, recordID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to update symptoms",
-				Error:   err.Error(),
-			})
-			return
-		}
+	if req.TrieuChung != nil {
+		next.TrieuChung = optionalString(*req.TrieuChung)
 	}
 
-	if chanDoan, exists := updateData["chan_doan"]; exists {
-		_, err = tx.Exec("UPDATE HOSO SET ChanDoan = @p1 WHERE MaHoSo = @p1", chanDoan, recordID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to update diagnosis",
-				Error:   err.Error(),
-			})
-			return
-		}
+	if req.ChanDoan != nil {
+		next.ChanDoan = optionalString(*req.ChanDoan)
 	}
 
-	if huongDanDieuTri, exists := updateData["huong_dan_dieu_tri"]; exists {
-		_, err = tx.Exec("UPDATE HOSO SET HuongDanDieuTri = @p1 WHERE MaHoSo = @p1", huongDanDieuTri, recordID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to update treatment instructions",
-				Error:   err.Error(),
-			})
-			return
+	if req.HuongDanDieuTri != nil {
+		next.HuongDanDieuTri = optionalString(*req.HuongDanDieuTri)
+	}
+
+	if req.MaICD10 != nil {
+		next.MaICD10 = nil
+		if code := strings.TrimSpace(*req.MaICD10); code != "" {
+			if !validateICD10Codes(c, h.db, []string{code}) {
+				return
+			}
+			code = utils.NormalizeICD10Code(code)
+			next.MaICD10 = &code
 		}
 	}
 
-	if maICD10, exists := updateData["ma_icd10"]; exists {
-		_, err = tx.Exec("UPDATE HOSO SET MaICD10 = @p1 WHERE MaHoSo = @p1", maICD10, recordID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to update ICD10 code",
-				Error:   err.Error(),
-			})
+	if req.MaICD10Phu != nil {
+		if !validateICD10Codes(c, h.db, *req.MaICD10Phu) {
 			return
 		}
+		next.MaICD10Phu = *req.MaICD10Phu
 	}
 
-	if ngayTaiKham, exists := updateData["ngay_tai_kham"]; exists {
-		_, err = tx.Exec("UPDATE HOSO SET NgayTaiKham = @p1 WHERE MaHoSo = @p1", ngayTaiKham, recordID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to update follow-up date",
-				Error:   err.Error(),
-			})
-			return
+	if req.NgayTaiKham != nil {
+		next.NgayTaiKham = nil
+		if *req.NgayTaiKham != "" {
+			var ok bool
+			if next.NgayTaiKham, ok = parseFollowUpDate(c, *req.NgayTaiKham); !ok {
+				return
+			}
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to update medical record",
-			Error:   err.Error(),
-		})
+	newVersion, err := h.records.Amend(viewer, next, version, req.LyDo)
+	if err != nil {
+		serviceFailed(c, err, "Failed to update medical record")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Medical record updated successfully",
+		Data: RecordVersionResponse{
+			PhienBan: newVersion,
+		},
 	})
 }
+
+// parseFollowUpDate reads ngay_tai_kham, a date or an RFC 3339 time.
+func parseFollowUpDate(c *gin.Context, value string) (*time.Time, bool) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid ngay_tai_kham. Use YYYY-MM-DD",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+	return &date, true
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sqlQueryer is satisfied by both *sql.DB and *sql.Tx.
+type sqlQueryer interface {
+	queryRower
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type recordState struct {
+	MaCustomer string
+	MaBacSi    string
+	NgayKham   time.Time
+	PhienBan   int
+	DaKy       bool
+	NgayKy     *time.Time
+	NguoiKy    *string
+}
+
+// AddendumRequest is the body of POST /medical-records/:id/addenda.
+type AddendumRequest struct {
+	NoiDung string `json:"noi_dung" binding:"required"`
+}
+
+// isLocked reports whether the record was signed or is past the edit window.
+// A zero window disables time-based locking.
+func (s recordState) isLocked(window time.Duration) bool {
+	return s.DaKy || (window > 0 && time.Now().After(s.NgayKham.Add(window)))
+}
+
+func loadRecordState(q queryRower, recordID string) (recordState, error) {
+	var state recordState
+	err := q.QueryRow(`
+		SELECT MaCustomer, MaBacSi, NgayKham, phienBan, daKy, ngayKy, nguoiKy
+		FROM HOSO WHERE MaHoSo = @p1
+	`, recordID).Scan(
+		&state.MaCustomer, &state.MaBacSi, &state.NgayKham,
+		&state.PhienBan, &state.DaKy, &state.NgayKy, &state.NguoiKy,
+	)
+	return state, err
+}
+
+// checkRecordAccess loads a record and writes an error response when the
+// caller may not see it, by the same rules as GetMedicalRecord.
+func (h *MedicalRecordHandler) checkRecordAccess(c *gin.Context, recordID string) (recordState, bool) {
+	if _, err := h.records.Get(viewerOf(c), recordID); err != nil {
+		serviceFailed(c, err, "Failed to find medical record")
+		return recordState{}, false
+	}
+
+	state, err := loadRecordState(h.db, recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to find medical record",
+			Error:   err.Error(),
+		})
+		return state, false
+	}
+	return state, true
+}
+
+func (h *MedicalRecordHandler) GetMedicalRecordVersions(c *gin.Context) {
+	recordID := c.Param("id")
+
+	if _, ok := h.checkRecordAccess(c, recordID); !ok {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT v.phienBan, v.nguoiSua, u.hoTen, v.thoiGian, v.lyDo
+		FROM HOSO_PHIENBAN v
+		LEFT JOIN [USER] u ON v.nguoiSua = u.userID
+		WHERE v.maHoSo = @p1
+		ORDER BY v.phienBan DESC
+	`, recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve medical record versions",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	versions := []RecordVersionSummaryResponse{}
+	for rows.Next() {
+		var phienBan int
+		var nguoiSua, tenNguoiSua, lyDo sql.NullString
+		var thoiGian time.Time
+
+		if err := rows.Scan(&phienBan, &nguoiSua, &tenNguoiSua, &thoiGian, &lyDo); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan medical record version",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		versions = append(versions, RecordVersionSummaryResponse{
+			PhienBan:    phienBan,
+			NguoiSua:    nguoiSua.String,
+			TenNguoiSua: tenNguoiSua.String,
+			ThoiGian:    thoiGian,
+			LyDo:        lyDo.String,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Medical record versions retrieved successfully",
+		Data:    versions,
+	})
+}
+
+// applyRecordVersion replaces the clinical fields of a record response with
+// the content of an earlier version.
+func (h *MedicalRecordHandler) applyRecordVersion(record *MedicalRecordResponse, version int) error {
+	var trieuChung, chanDoan, huongDanDieuTri, maICD10, maICD10Phu, nguoiSua, lyDo sql.NullString
+	var ngayTaiKham sql.NullTime
+	var thoiGian time.Time
+
+	err := h.db.QueryRow(`
+		SELECT trieuChung, chanDoan, huongDanDieuTri, maICD10, maICD10Phu,
+		       ngayTaiKham, nguoiSua, thoiGian, lyDo
+		FROM HOSO_PHIENBAN
+		WHERE maHoSo = @p1 AND phienBan = @p2
+	`, record.MaHoSo, version).Scan(&trieuChung, &chanDoan, &huongDanDieuTri, &maICD10, &maICD10Phu,
+		&ngayTaiKham, &nguoiSua, &thoiGian, &lyDo)
+	if err != nil {
+		return err
+	}
+
+	var secondaryCodes []string
+	if maICD10Phu.String != "" {
+		secondaryCodes = strings.Split(maICD10Phu.String, ",")
+	}
+	secondary, err := h.describeICD10Codes(secondaryCodes)
+	if err != nil {
+		return err
+	}
+
+	record.TrieuChung = stringPointer(trieuChung)
+	record.ChanDoan = stringPointer(chanDoan)
+	record.HuongDanDieuTri = stringPointer(huongDanDieuTri)
+	record.MaICD10 = stringPointer(maICD10)
+	record.MaICD10Phu = secondary
+	record.NgayTaiKham = timePointer(ngayTaiKham)
+	record.PhienBan = version
+	record.NguoiSua = nguoiSua.String
+	record.ThoiGianSua = &thoiGian
+	record.LyDoSua = lyDo.String
+	return nil
+}
+
+func (h *MedicalRecordHandler) describeICD10Codes(codes []string) ([]ICD10DiagnosisResponse, error) {
+	diagnoses := []ICD10DiagnosisResponse{}
+	for _, code := range codes {
+		var tenTiengAnh, tenTiengViet sql.NullString
+		err := h.db.QueryRow("SELECT tenTiengAnh, tenTiengViet FROM ICD10 WHERE maICD10 = @p1", code).
+			Scan(&tenTiengAnh, &tenTiengViet)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		diagnoses = append(diagnoses, ICD10DiagnosisResponse{
+			MaICD10:      code,
+			TenTiengAnh:  tenTiengAnh.String,
+			TenTiengViet: tenTiengViet.String,
+		})
+	}
+	return diagnoses, nil
+}
+
+func (h *MedicalRecordHandler) getAddenda(recordID string) ([]AddendumResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT b.maBoSung, b.noiDung, b.nguoiTao, u.hoTen, b.thoiGian
+		FROM HOSO_BOSUNG b
+		LEFT JOIN [USER] u ON b.nguoiTao = u.userID
+		WHERE b.maHoSo = @p1
+		ORDER BY b.thoiGian
+	`, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addenda := []AddendumResponse{}
+	for rows.Next() {
+		var a AddendumResponse
+		var tenNguoiTao sql.NullString
+		if err := rows.Scan(&a.MaBoSung, &a.NoiDung, &a.NguoiTao, &tenNguoiTao, &a.ThoiGian); err != nil {
+			return nil, err
+		}
+		a.TenNguoiTao = tenNguoiTao.String
+		addenda = append(addenda, a)
+	}
+	return addenda, rows.Err()
+}
+
+// AddMedicalRecordAddendum appends a note to a record. Addenda are allowed
+// on locked records and never change the original content.
+func (h *MedicalRecordHandler) AddMedicalRecordAddendum(c *gin.Context) {
+	recordID := c.Param("id")
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	if userType.(string) != "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only doctors can add addenda to medical records",
+		})
+		return
+	}
+
+	var req AddendumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if _, ok := h.checkRecordAccess(c, recordID); !ok {
+		return
+	}
+
+	addendumID, err := utils.GenerateAddendumID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to add addendum",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO HOSO_BOSUNG (maBoSung, maHoSo, noiDung, nguoiTao, thoiGian)
+		VALUES (@p1, @p2, @p3, @p4, GETDATE())
+	`, addendumID, recordID, req.NoiDung, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to add addendum",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Addendum added successfully",
+		Data: CreatedAddendumResponse{
+			MaBoSung: addendumID,
+		},
+	})
+}
+
+// SignMedicalRecord locks a record before its edit window ends.
+func (h *MedicalRecordHandler) SignMedicalRecord(c *gin.Context) {
+	recordID := c.Param("id")
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	if userType.(string) != "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only doctors can sign medical records",
+		})
+		return
+	}
+
+	state, ok := h.checkRecordAccess(c, recordID)
+	if !ok {
+		return
+	}
+
+	if state.DaKy {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Medical record is already signed",
+		})
+		return
+	}
+
+	_, err := h.db.Exec(`
+		UPDATE HOSO SET daKy = 1, ngayKy = GETDATE(), nguoiKy = @p1
+		WHERE MaHoSo = @p2 AND daKy = 0
+	`, userID, recordID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to sign medical record",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Medical record signed successfully",
+	})
+}
+
+// optionalString stores an empty string as NULL.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
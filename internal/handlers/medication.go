@@ -0,0 +1,553 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MedicationHandler manages the formulary (THUOC). Only managers can change
+// it; prescriptions may only reference active medicines from it.
+type MedicationHandler struct {
+	db *sql.DB
+}
+
+func NewMedicationHandler(db *sql.DB) *MedicationHandler {
+	return &MedicationHandler{db: db}
+}
+
+type MedicationRequest struct {
+	TenThuoc   string   `json:"ten_thuoc" binding:"required"`
+	HoatChat   string   `json:"hoat_chat" binding:"required"`
+	HamLuong   string   `json:"ham_luong"`
+	DangBaoChe string   `json:"dang_bao_che"`
+	DonVi      string   `json:"don_vi" binding:"required"`
+	Gia        *float64 `json:"gia" binding:"required,min=0"`
+	CongDung   string   `json:"cong_dung"`
+	LieuLuong  string   `json:"lieu_luong"`
+}
+
+type MedicationUpdateRequest struct {
+	TenThuoc   *string  `json:"ten_thuoc"`
+	HoatChat   *string  `json:"hoat_chat"`
+	HamLuong   *string  `json:"ham_luong"`
+	DangBaoChe *string  `json:"dang_bao_che"`
+	DonVi      *string  `json:"don_vi"`
+	Gia        *float64 `json:"gia" binding:"omitempty,min=0"`
+	CongDung   *string  `json:"cong_dung"`
+	LieuLuong  *string  `json:"lieu_luong"`
+	TrangThai  *string  `json:"trang_thai" binding:"omitempty,oneof=ACTIVE DISCONTINUED"`
+}
+
+const medicationColumns = `
+	maThuoc, tenThuoc, hoatChat, hamLuong, dangBaoChe, donVi, gia, congDung, lieuLuong, trangThai
+`
+
+func scanMedication(row interface{ Scan(...interface{}) error }) (MedicationResponse, error) {
+	var m MedicationResponse
+	var hoatChat, hamLuong, dangBaoChe, donVi, congDung, lieuLuong, trangThai sql.NullString
+	var gia sql.NullFloat64
+
+	err := row.Scan(&m.MaThuoc, &m.TenThuoc, &hoatChat, &hamLuong, &dangBaoChe, &donVi, &gia,
+		&congDung, &lieuLuong, &trangThai)
+	if err != nil {
+		return m, err
+	}
+
+	m.HoatChat = hoatChat.String
+	m.HamLuong = hamLuong.String
+	m.DangBaoChe = dangBaoChe.String
+	m.DonVi = donVi.String
+	m.Gia = gia.Float64
+	m.CongDung = congDung.String
+	m.LieuLuong = lieuLuong.String
+	m.TrangThai = trangThai.String
+	return m, nil
+}
+
+var medicationList = listSpec{
+	sorts: map[string]services.SortKey{
+		"ten_thuoc": {Expr: "tenThuoc", Kind: services.SortText},
+		"ma_thuoc":  {Expr: "maThuoc", Kind: services.SortText},
+		"gia":       {Expr: "ISNULL(gia, 0)", Kind: services.SortNumber},
+	},
+	defaultSort: "ten_thuoc",
+	idField:     "ma_thuoc",
+	idColumn:    "maThuoc",
+}
+
+// GetMedications searches the catalogue by name, code or active ingredient,
+// one page at a time. Discontinued medicines are hidden unless
+// include_discontinued=true; dang_bao_che and hoat_chat filter further.
+func (h *MedicationHandler) GetMedications(c *gin.Context) {
+	params, ok := medicationList.parse(c)
+	if !ok {
+		return
+	}
+
+	from := "FROM THUOC WHERE 1=1"
+	var args []interface{}
+
+	if search := c.Query("q"); search != "" {
+		from += " AND (tenThuoc LIKE @p1 OR maThuoc LIKE @p1 OR hoatChat LIKE @p1)"
+		args = append(args, "%"+search+"%")
+	}
+	if form := c.Query("dang_bao_che"); form != "" {
+		from += fmt.Sprintf(" AND dangBaoChe = @p%d", len(args)+1)
+		args = append(args, form)
+	}
+	if ingredient := c.Query("hoat_chat"); ingredient != "" {
+		from += fmt.Sprintf(" AND hoatChat LIKE @p%d", len(args)+1)
+		args = append(args, "%"+ingredient+"%")
+	}
+	if c.Query("include_discontinued") != "true" {
+		from += " AND trangThai = 'ACTIVE'"
+	}
+
+	query, pageArgs, countQuery := medicationList.queries(medicationColumns, from, args, params)
+	total, ok := countList(c, h.db, countQuery, args, params, "Failed to count medications")
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(query, pageArgs...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve medications",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	var medications []MedicationResponse
+	for rows.Next() {
+		medication, err := scanMedication(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan medication data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		medications = append(medications, medication)
+	}
+
+	respondList(c, medicationList, "Medications retrieved successfully", medications, params, total)
+}
+
+func (h *MedicationHandler) GetMedication(c *gin.Context) {
+	medicationID := c.Param("id")
+
+	medication, err := scanMedication(h.db.QueryRow("SELECT "+medicationColumns+" FROM THUOC WHERE maThuoc = @p1", medicationID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Medication not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve medication",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	prices, err := h.getPriceHistory(medicationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve price history",
+			Error:   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Medication retrieved successfully",
+		Data:    MedicationDetailResponse{MedicationResponse: medication, LichSuGia: prices},
+	})
+}
+
+func (h *MedicationHandler) CreateMedication(c *gin.Context) {
+	if !isManager(c, "Only managers can manage the medication catalogue") {
+		return
+	}
+
+	var req MedicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// The same product (name, strength and form) must not be listed twice.
+	var duplicate string
+	err := h.db.QueryRow(`
+		SELECT TOP 1 maThuoc FROM THUOC
+		WHERE tenThuoc = @p1 AND ISNULL(hamLuong, '') = @p2 AND ISNULL(dangBaoChe, '') = @p3
+	`, strings.TrimSpace(req.TenThuoc), strings.TrimSpace(req.HamLuong), strings.TrimSpace(req.DangBaoChe)).Scan(&duplicate)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check medication catalogue",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err == nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Medication already exists in the catalogue as " + duplicate,
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	medicationID, err := utils.GenerateMedicineID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create medication",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO THUOC (maThuoc, tenThuoc, soLuong, gia, congDung, lieuLuong,
+		                   hoatChat, hamLuong, dangBaoChe, donVi, trangThai)
+		VALUES (@p1, @p2, 0, @p3, @p4, @p5, @p6, @p7, @p8, @p9, 'ACTIVE')
+	`, medicationID, strings.TrimSpace(req.TenThuoc), *req.Gia, req.CongDung, req.LieuLuong,
+		strings.TrimSpace(req.HoatChat), services.NullIfEmpty(req.HamLuong), services.NullIfEmpty(req.DangBaoChe), strings.TrimSpace(req.DonVi))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create medication",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = recordPriceChange(tx, medicationID, *req.Gia, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to record medication price",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create medication",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Medication created successfully",
+		Data: CreatedMedicationResponse{
+			MaThuoc: medicationID,
+		},
+	})
+}
+
+func (h *MedicationHandler) UpdateMedication(c *gin.Context) {
+	if !isManager(c, "Only managers can manage the medication catalogue") {
+		return
+	}
+
+	medicationID := c.Param("id")
+
+	var req MedicationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var currentPrice sql.NullFloat64
+	err = tx.QueryRow("SELECT gia FROM THUOC WITH (UPDLOCK) WHERE maThuoc = @p1", medicationID).Scan(&currentPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Medication not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve medication",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	var setParts []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		setParts = append(setParts, column+" = @p"+strconv.Itoa(len(args)))
+	}
+
+	if req.TenThuoc != nil {
+		set("tenThuoc", strings.TrimSpace(*req.TenThuoc))
+	}
+	if req.HoatChat != nil {
+		set("hoatChat", strings.TrimSpace(*req.HoatChat))
+	}
+	if req.HamLuong != nil {
+		set("hamLuong", services.NullIfEmpty(*req.HamLuong))
+	}
+	if req.DangBaoChe != nil {
+		set("dangBaoChe", services.NullIfEmpty(*req.DangBaoChe))
+	}
+	if req.DonVi != nil {
+		set("donVi", strings.TrimSpace(*req.DonVi))
+	}
+	if req.CongDung != nil {
+		set("congDung", *req.CongDung)
+	}
+	if req.LieuLuong != nil {
+		set("lieuLuong", *req.LieuLuong)
+	}
+	if req.TrangThai != nil {
+		set("trangThai", *req.TrangThai)
+	}
+	priceChanged := req.Gia != nil && (!currentPrice.Valid || *req.Gia != currentPrice.Float64)
+	if priceChanged {
+		set("gia", *req.Gia)
+	}
+
+	if len(setParts) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+		})
+		return
+	}
+
+	args = append(args, medicationID)
+	_, err = tx.Exec("UPDATE THUOC SET "+strings.Join(setParts, ", ")+" WHERE maThuoc = @p"+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update medication",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if priceChanged {
+		userID, _ := c.Get("user_id")
+		if err = recordPriceChange(tx, medicationID, *req.Gia, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to record medication price",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update medication",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Medication updated successfully",
+	})
+}
+
+// DiscontinueMedication hides a medicine from new prescriptions. Rows are
+// kept because past prescriptions still reference them.
+func (h *MedicationHandler) DiscontinueMedication(c *gin.Context) {
+	if !isManager(c, "Only managers can manage the medication catalogue") {
+		return
+	}
+
+	result, err := h.db.Exec("UPDATE THUOC SET trangThai = 'DISCONTINUED' WHERE maThuoc = @p1", c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to discontinue medication",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Medication not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Medication discontinued successfully",
+	})
+}
+
+func (h *MedicationHandler) GetPriceHistory(c *gin.Context) {
+	prices, err := h.getPriceHistory(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve price history",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Price history retrieved successfully",
+		Data:    prices,
+	})
+}
+
+func (h *MedicationHandler) getPriceHistory(medicationID string) ([]MedicationPriceResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT gia, tuNgay, denNgay, nguoiCapNhat
+		FROM GIATHUOC
+		WHERE maThuoc = @p1
+		ORDER BY tuNgay DESC
+	`, medicationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []MedicationPriceResponse{}
+	for rows.Next() {
+		var price MedicationPriceResponse
+		var denNgay sql.NullTime
+		var nguoiCapNhat sql.NullString
+		if err := rows.Scan(&price.Gia, &price.TuNgay, &denNgay, &nguoiCapNhat); err != nil {
+			return nil, err
+		}
+
+		price.DenNgay = timePointer(denNgay)
+		price.NguoiCapNhat = nguoiCapNhat.String
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+// recordPriceChange closes the current price period and opens a new one.
+func recordPriceChange(tx *sql.Tx, medicationID string, price float64, userID interface{}) error {
+	now := time.Now()
+	if _, err := tx.Exec("UPDATE GIATHUOC SET denNgay = @p1 WHERE maThuoc = @p2 AND denNgay IS NULL", now, medicationID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		INSERT INTO GIATHUOC (maThuoc, gia, tuNgay, nguoiCapNhat)
+		VALUES (@p1, @p2, @p3, @p4)
+	`, medicationID, price, now, userID)
+	return err
+}
+
+// validateCatalogueMedications rejects prescriptions that reference
+// medicines missing from the catalogue or discontinued.
+func validateCatalogueMedications(c *gin.Context, q sqlQueryer, medications []PrescriptionMedication) bool {
+	var unknown, discontinued []string
+	for _, med := range medications {
+		var trangThai sql.NullString
+		err := q.QueryRow("SELECT trangThai FROM THUOC WHERE maThuoc = @p1", med.MaThuoc).Scan(&trangThai)
+		if err == sql.ErrNoRows {
+			unknown = append(unknown, med.MaThuoc)
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to check medication",
+				Error:   err.Error(),
+			})
+			return false
+		}
+		if trangThai.String == "DISCONTINUED" {
+			discontinued = append(discontinued, med.MaThuoc)
+		}
+	}
+
+	if len(unknown) > 0 || len(discontinued) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Prescription contains medications that are not available in the catalogue",
+			Data: UnavailableMedicationsResponse{
+				KhongCoTrongDanhMuc: unknown,
+				NgungSuDung:         discontinued,
+			},
+		})
+		return false
+	}
+	return true
+}
+
+func isManager(c *gin.Context, message string) bool {
+	userType, _ := c.Get("user_type")
+	if userType.(string) != "CLINIC_MANAGER" && userType.(string) != "OPERATION_MANAGER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: message,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/notification"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler exposes a user's contact preferences and the
+// messages sent to them through notification.Service.
+type NotificationHandler struct {
+	db *sql.DB
+}
+
+func NewNotificationHandler(db *sql.DB) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	NhanEmail *bool  `json:"nhan_email"`
+	NhanSMS   *bool  `json:"nhan_sms"`
+	NgonNgu   string `json:"ngon_ngu"`
+}
+
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	prefs, err := notification.LoadPreferences(h.db, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving notification preferences",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Notification preferences retrieved successfully",
+		Data:    preferencesResponse(prefs),
+	})
+}
+
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if req.NgonNgu != "" && !notification.ValidLanguage(req.NgonNgu) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "ngon_ngu must be vi or en",
+		})
+		return
+	}
+
+	prefs, err := notification.LoadPreferences(h.db, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving notification preferences",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.NhanEmail != nil {
+		prefs.Email = *req.NhanEmail
+	}
+	if req.NhanSMS != nil {
+		prefs.SMS = *req.NhanSMS
+	}
+	if req.NgonNgu != "" {
+		prefs.Language = req.NgonNgu
+	}
+
+	_, err = h.db.Exec(`
+		MERGE TUYCHONTHONGBAO AS t
+		USING (SELECT @p1 AS maUser) AS s ON t.maUser = s.maUser
+		WHEN MATCHED THEN
+			UPDATE SET nhanEmail = @p2, nhanSMS = @p3, ngonNgu = @p4, ngayCapNhat = GETDATE()
+		WHEN NOT MATCHED THEN
+			INSERT (maUser, nhanEmail, nhanSMS, ngonNgu, ngayCapNhat)
+			VALUES (@p1, @p2, @p3, @p4, GETDATE());
+	`, userID, prefs.Email, prefs.SMS, prefs.Language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error updating notification preferences",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Notification preferences updated successfully",
+		Data:    preferencesResponse(prefs),
+	})
+}
+
+// GetNotifications lists the latest messages sent to the caller. Bodies of
+// sensitive messages are already redacted once delivered.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	rows, err := h.db.Query(`
+		SELECT TOP 50 maThongBao, kenh, nguoiNhan, mauTin, tieuDe, noiDung, thamChieu, trangThai,
+		       soLanThu, ngayTao, guiLuc
+		FROM THONGBAO
+		WHERE maUser = @p1
+		ORDER BY ngayTao DESC, maThongBao DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving notifications",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []NotificationResponse{}
+	for rows.Next() {
+		var id int64
+		var channel, to, template, subject, body, status string
+		var reference sql.NullString
+		var attempts int
+		var createdAt sql.NullTime
+		var sentAt sql.NullTime
+		if err := rows.Scan(&id, &channel, &to, &template, &subject, &body, &reference, &status,
+			&attempts, &createdAt, &sentAt); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Error scanning notification",
+				Error:   err.Error(),
+			})
+			return
+		}
+		notifications = append(notifications, NotificationResponse{
+			MaThongBao: id,
+			Kenh:       channel,
+			NguoiNhan:  to,
+			MauTin:     template,
+			TieuDe:     subject,
+			NoiDung:    body,
+			ThamChieu:  stringPointer(reference),
+			TrangThai:  status,
+			SoLanThu:   attempts,
+			NgayTao:    timePointer(createdAt),
+			GuiLuc:     timePointer(sentAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Notifications retrieved successfully",
+		Data:    notifications,
+	})
+}
+
+func preferencesResponse(prefs notification.Preferences) NotificationPreferencesResponse {
+	return NotificationPreferencesResponse{
+		NhanEmail: prefs.Email,
+		NhanSMS:   prefs.SMS,
+		NgonNgu:   prefs.Language,
+	}
+}
@@ -0,0 +1,836 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/openapi"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiEndpoints describe every route under /api/v1 except the document
+// itself. Paths are relative to /api/v1; NewOpenAPIDocument matches them to
+// the routes and the contract tests check the bodies.
+var apiEndpoints = []openapi.Endpoint{
+	{
+		Method: http.MethodPost, Path: "/auth/login", Tag: "auth", Public: true,
+		Summary:  "Log in. Accounts with 2FA get a challenge token instead of tokens.",
+		Request:  models.AuthRequest{},
+		Response: openapi.AnyOf{models.AuthResponse{}, TwoFactorChallengeResponse{}},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/register", Tag: "auth", Public: true, Status: http.StatusCreated,
+		Summary:  "Register a customer account",
+		Request:  models.RegisterRequest{},
+		Response: RegisterResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/forgot-password", Tag: "auth", Public: true,
+		Summary: "Email a password reset code and link, if the address belongs to an account",
+		Request: models.ForgotPasswordRequest{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/reset-password", Tag: "auth", Public: true,
+		Summary: "Set a new password with the emailed code or the token of the reset link",
+		Request: models.ResetPasswordRequest{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/refresh", Tag: "auth", Public: true,
+		Summary:  "Exchange a refresh token for new tokens",
+		Request:  models.RefreshTokenRequest{},
+		Response: models.AuthResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/accept-invitation", Tag: "auth", Public: true,
+		Summary: "Set the password of an invited staff account from the invitation link",
+		Request: models.AcceptInvitationRequest{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/2fa/verify", Tag: "auth", Public: true,
+		Summary:  "Finish a login with an authenticator or recovery code",
+		Request:  TwoFactorChallengeRequest{},
+		Response: models.AuthResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/2fa/enroll", Tag: "auth", Public: true,
+		Summary:  "Start 2FA enrolment during a login that requires it",
+		Request:  TwoFactorChallengeRequest{},
+		Response: TwoFactorSecretResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/2fa/enroll/confirm", Tag: "auth", Public: true,
+		Summary:  "Confirm 2FA enrolment with a first code and finish the login",
+		Request:  TwoFactorChallengeRequest{},
+		Response: models.AuthResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/appointment-links/:token", Tag: "appointments", Public: true,
+		Summary:  "Show the appointment and action of a reminder link without applying it",
+		Response: AppointmentLinkResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/appointment-links/:token", Tag: "appointments", Public: true,
+		Summary:  "Confirm or cancel the appointment of a reminder link",
+		Response: AppointmentStatusResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/logout", Tag: "auth",
+		Summary: "End the current session",
+	},
+	{
+		Method: http.MethodGet, Path: "/users/profile", Tag: "users",
+		Summary:  "Get the profile of the logged-in user, with the fields of their role",
+		Response: openapi.AnyOf{models.Customer{}, models.Doctor{}, models.Receptionist{}, models.Accountant{}, models.ClinicManager{}, models.OperationManager{}},
+	},
+	{
+		Method: http.MethodPut, Path: "/users/profile", Tag: "users",
+		Summary: "Update the profile of the logged-in user",
+		Request: UpdateProfileRequest{},
+	},
+	{
+		Method: http.MethodPut, Path: "/users/password", Tag: "users",
+		Summary: "Change the password of the logged-in user",
+		Request: ChangePasswordRequest{},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/notification-preferences", Tag: "users",
+		Summary:  "Get how the logged-in user wants to be notified",
+		Response: NotificationPreferencesResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/users/notification-preferences", Tag: "users",
+		Summary:  "Change the notification channels and language (vi or en)",
+		Request:  UpdateNotificationPreferencesRequest{},
+		Response: NotificationPreferencesResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/notifications", Tag: "users",
+		Summary:  "List the latest 50 messages sent to the logged-in user",
+		Response: []NotificationResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/2fa", Tag: "users",
+		Summary:  "Get the 2FA status of the logged-in user",
+		Response: TwoFactorStatusResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/users/2fa/setup", Tag: "users",
+		Summary:  "Start turning 2FA on",
+		Response: TwoFactorSecretResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/users/2fa/enable", Tag: "users",
+		Summary:  "Turn 2FA on with a code from the new secret",
+		Request:  TwoFactorCodeRequest{},
+		Response: RecoveryCodesResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/users/2fa/disable", Tag: "users",
+		Summary: "Turn 2FA off, unless the role requires it",
+		Request: DisableTwoFactorRequest{},
+	},
+	{
+		Method: http.MethodPost, Path: "/users/2fa/recovery-codes", Tag: "users",
+		Summary:  "Replace the recovery codes",
+		Request:  TwoFactorCodeRequest{},
+		Response: RecoveryCodesResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/sessions", Tag: "users",
+		Summary:  "List the devices the user is signed in on",
+		Response: []SessionResponse{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/users/sessions/:id", Tag: "users",
+		Summary: "Sign a device out",
+	},
+	{
+		Method: http.MethodPost, Path: "/users/sessions/revoke-others", Tag: "users",
+		Summary:  "Sign out every device but this one",
+		Response: RevokedSessionsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/login-attempts", Tag: "admin", List: true,
+		Summary:  "List sign-in attempts (operation managers)",
+		Query:    listQuery(loginAttemptList, "ma_user", "ten_dang_nhap", "dia_chi_ip", "ket_qua"),
+		Response: LoginAttemptResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/users/:id/unlock", Tag: "admin",
+		Summary: "Clear an account's failed logins and lock (operation managers)",
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/staff", Tag: "admin",
+		Summary: "List staff accounts (operation managers)",
+		Query: []openapi.Param{
+			{Name: "role"},
+			{Name: "trang_thai", Description: "INVITED, ACTIVE or INACTIVE"},
+			{Name: "ma_phong_kham"},
+			{Name: "tu_khoa", Description: "Part of the name, username or email"},
+		},
+		Response: []StaffSummaryResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/staff", Tag: "admin", Status: http.StatusCreated,
+		Summary:  "Create a staff account and email the invitation (operation managers)",
+		Request:  models.CreateStaffRequest{},
+		Response: CreatedStaffResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/staff/:id", Tag: "admin",
+		Summary:  "Get a staff account with the fields of its role (operation managers)",
+		Response: staffMember,
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/staff/:id", Tag: "admin",
+		Summary:  "Update a staff account (operation managers)",
+		Request:  models.UpdateStaffRequest{},
+		Response: staffMember,
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/staff/:id/reassign", Tag: "admin",
+		Summary:  "Move a receptionist or clinic manager to another clinic (operation managers)",
+		Request:  ReassignStaffRequest{},
+		Response: StaffAssignmentResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/staff/:id/deactivate", Tag: "admin",
+		Summary: "Block a staff account and sign it out (operation managers)",
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/staff/:id/activate", Tag: "admin",
+		Summary: "Reactivate a staff account (operation managers)",
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/staff/:id/invitation", Tag: "admin",
+		Summary: "Send a new invitation to a staff member who has not accepted theirs (operation managers)",
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics", Tag: "clinics",
+		Summary:  "List clinics",
+		Response: []models.Clinic{},
+	},
+	{
+		Method: http.MethodPost, Path: "/clinics", Tag: "clinics", Status: http.StatusCreated,
+		Summary:  "Create a clinic (operation managers)",
+		Request:  ClinicRequest{},
+		Response: CreatedClinicResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/specialties", Tag: "clinics",
+		Summary:  "List the specialties of active doctors",
+		Response: []string{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id", Tag: "clinics",
+		Summary:  "Get a clinic",
+		Response: models.Clinic{},
+	},
+	{
+		Method: http.MethodPut, Path: "/clinics/:id", Tag: "clinics",
+		Summary: "Update a clinic (operation managers)",
+		Request: UpdateClinicRequest{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id/doctors", Tag: "clinics",
+		Summary:  "List the doctors working at a clinic",
+		Query:    []openapi.Param{{Name: "chuyen_khoa", Description: "Part of the specialty"}},
+		Response: ClinicDoctorsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id/schedules", Tag: "clinics",
+		Summary: "Get a doctor's free slots on a day",
+		Query: []openapi.Param{
+			{Name: "doctor_id", Description: "Required"},
+			{Name: "date", Description: "YYYY-MM-DD, required"},
+		},
+		Response: ClinicSlotsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id/opening-hours", Tag: "clinics",
+		Summary:  "Get a clinic's weekly opening hours",
+		Response: []models.OpeningHours{},
+	},
+	{
+		Method: http.MethodPut, Path: "/clinics/:id/opening-hours", Tag: "clinics",
+		Summary: "Replace a clinic's weekly opening hours (managers)",
+		Request: OpeningHoursRequest{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id/rooms", Tag: "clinics",
+		Summary: "List a clinic's rooms",
+		Query: []openapi.Param{
+			{Name: "trang_thai", Enum: []string{"ACTIVE", "MAINTENANCE", "CLOSED"}},
+			{Name: "loai_phong", Enum: []string{"EXAM", "LAB", "IMAGING", "PROCEDURE", "PHARMACY", "OTHER"}},
+		},
+		Response: []models.Room{},
+	},
+	{
+		Method: http.MethodPost, Path: "/clinics/:id/rooms", Tag: "clinics", Status: http.StatusCreated,
+		Summary:  "Add a room to a clinic (managers)",
+		Request:  RoomRequest{},
+		Response: CreatedRoomResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/clinics/:id/rooms/:room_id", Tag: "clinics",
+		Summary: "Update a room (managers)",
+		Request: UpdateRoomRequest{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id/rooms/:room_id/usage", Tag: "clinics",
+		Summary:  "Get the schedules and appointments in a room on a day (staff)",
+		Query:    []openapi.Param{{Name: "ngay", Description: "YYYY-MM-DD (default today)"}},
+		Response: RoomUsageResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/clinics/:id/equipment", Tag: "clinics",
+		Summary: "List a clinic's equipment (staff)",
+		Query: []openapi.Param{
+			{Name: "ma_phong"},
+			{Name: "trang_thai", Enum: []string{"IN_USE", "MAINTENANCE", "BROKEN", "RETIRED"}},
+			{Name: "can_bao_tri", Description: "true for equipment due for maintenance within a week"},
+		},
+		Response: []models.Equipment{},
+	},
+	{
+		Method: http.MethodPost, Path: "/clinics/:id/equipment", Tag: "clinics", Status: http.StatusCreated,
+		Summary:  "Add equipment to a clinic (managers)",
+		Request:  EquipmentRequest{},
+		Response: CreatedEquipmentResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/clinics/:id/equipment/:equipment_id", Tag: "clinics",
+		Summary: "Update equipment (managers)",
+		Request: UpdateEquipmentRequest{},
+	},
+	{
+		Method: http.MethodGet, Path: "/appointments", Tag: "appointments", List: true,
+		Summary:  "List appointments. Customers and doctors see their own.",
+		Query:    listQuery(appointmentList, "status", "doctor_id", "clinic_id", "customer_id", "room_id"),
+		Response: models.AppointmentDetail{},
+	},
+	{
+		Method: http.MethodPost, Path: "/appointments", Tag: "appointments", Status: http.StatusCreated,
+		Summary:  "Book an appointment",
+		Request:  CreateAppointmentRequest{},
+		Response: CreatedAppointmentResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/appointments/:id", Tag: "appointments",
+		Summary:  "Get an appointment",
+		Response: models.AppointmentDetail{},
+	},
+	{
+		Method: http.MethodPut, Path: "/appointments/:id", Tag: "appointments",
+		Summary: "Reschedule an appointment, change its status, note or room",
+		Request: UpdateAppointmentRequest{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/appointments/:id", Tag: "appointments",
+		Summary: "Cancel an appointment",
+	},
+	{
+		Method: http.MethodGet, Path: "/follow-ups", Tag: "follow-ups",
+		Summary:  "List follow-up visits. Customers and doctors see their own, clinic managers their clinic's.",
+		Query:    []openapi.Param{{Name: "trang_thai"}},
+		Response: []FollowUpResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/follow-ups/missed", Tag: "follow-ups",
+		Summary: "Report missed follow-ups per doctor (managers)",
+		Query: []openapi.Param{
+			{Name: "tu_ngay", Description: "YYYY-MM-DD (default a month ago)"},
+			{Name: "den_ngay", Description: "YYYY-MM-DD (default today)"},
+			{Name: "ma_phong_kham", Description: "Operation managers only"},
+		},
+		Response: MissedFollowUpsResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/follow-ups/:id/confirm", Tag: "follow-ups",
+		Summary:  "Accept the appointment booked for a follow-up (the patient)",
+		Response: FollowUpAppointmentResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/follow-ups/:id/reschedule", Tag: "follow-ups",
+		Summary:  "Move a follow-up appointment to another slot with the same doctor (the patient)",
+		Request:  RescheduleFollowUpRequest{},
+		Response: FollowUpAppointmentResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/medical-records", Tag: "medical-records", List: true,
+		Summary:  "List medical records. Customers and doctors see their own.",
+		Query:    listQuery(medicalRecordList, "customer_id", "doctor_id", "clinic_id", "icd10"),
+		Response: models.MedicalRecordDetail{},
+	},
+	{
+		Method: http.MethodGet, Path: "/medical-records/:id", Tag: "medical-records",
+		Summary:  "Get a medical record with its addenda, prescriptions, lab tests and the patient's allergies and conditions",
+		Query:    []openapi.Param{{Name: "version", Description: "Show the clinical fields of an earlier version"}},
+		Response: MedicalRecordResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/medical-records", Tag: "medical-records", Status: http.StatusCreated,
+		Summary:  "Create a medical record for a patient the doctor has seen",
+		Request:  CreateMedicalRecordRequest{},
+		Response: CreatedMedicalRecordResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/medical-records/:id", Tag: "medical-records",
+		Summary:  "Amend a record within its edit window, storing the previous content as a version",
+		Request:  UpdateMedicalRecordRequest{},
+		Response: RecordVersionResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/medical-records/:id/versions", Tag: "medical-records",
+		Summary:  "List the stored versions of a record, newest first",
+		Response: []RecordVersionSummaryResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/medical-records/:id/addenda", Tag: "medical-records", Status: http.StatusCreated,
+		Summary:  "Append a note to a record, including a locked one (doctors)",
+		Request:  AddendumRequest{},
+		Response: CreatedAddendumResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/medical-records/:id/sign", Tag: "medical-records",
+		Summary: "Sign and lock a record before its edit window ends (the record's doctor)",
+	},
+	{
+		Method: http.MethodGet, Path: "/customers", Tag: "customers", List: true,
+		Summary:  "List customers (receptionists)",
+		Query:    listQuery(customerList, "search", "gioi_tinh"),
+		Response: CustomerResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/customers", Tag: "customers", Status: http.StatusCreated,
+		Summary:  "Create a customer account (receptionists)",
+		Request:  CreateCustomerRequest{},
+		Response: CreatedCustomerResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/customers/:id", Tag: "customers",
+		Summary:  "Get a customer (receptionists)",
+		Response: CustomerResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/customers/:id/summary", Tag: "customers",
+		Summary:  "Summarize a patient's history: allergies, conditions, visits, recurring diagnoses, medications, lab results and follow-ups",
+		Response: PatientSummaryResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/customers/:id/allergies", Tag: "customers",
+		Summary:  "List a patient's allergies, most severe first",
+		Query:    []openapi.Param{{Name: "include_inactive", Description: "true to include removed allergies"}},
+		Response: []AllergyResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/customers/:id/allergies", Tag: "customers", Status: http.StatusCreated,
+		Summary:  "Record an allergy",
+		Request:  AllergyRequest{},
+		Response: CreatedAllergyResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/customers/:id/allergies/:allergy_id", Tag: "customers",
+		Summary: "Update an allergy",
+		Request: AllergyRequest{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/customers/:id/allergies/:allergy_id", Tag: "customers",
+		Summary: "Mark an allergy inactive",
+	},
+	{
+		Method: http.MethodGet, Path: "/customers/:id/conditions", Tag: "customers",
+		Summary:  "List a patient's chronic conditions",
+		Query:    []openapi.Param{{Name: "include_resolved", Description: "true to include resolved and removed conditions"}},
+		Response: []ChronicConditionResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/customers/:id/conditions", Tag: "customers", Status: http.StatusCreated,
+		Summary:  "Record a chronic condition",
+		Request:  ConditionRequest{},
+		Response: CreatedConditionResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/customers/:id/conditions/:condition_id", Tag: "customers",
+		Summary: "Update a chronic condition",
+		Request: ConditionRequest{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/customers/:id/conditions/:condition_id", Tag: "customers",
+		Summary: "Mark a chronic condition inactive",
+	},
+	{
+		Method: http.MethodGet, Path: "/prescriptions", Tag: "prescriptions", List: true,
+		Summary:  "List prescriptions. Customers see their own once signed, doctors their own.",
+		Query:    listQuery(prescriptionList, "status", "ma_ho_so", "customer_id", "doctor_id"),
+		Response: PrescriptionResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/prescriptions/:id", Tag: "prescriptions",
+		Summary:  "Get a prescription with the patient's allergies, overridden warnings and dispensed batches",
+		Response: PrescriptionDetailResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/prescriptions", Tag: "prescriptions", Status: http.StatusCreated,
+		Summary:  "Write a draft prescription for a medical record (its doctor). Safety warnings need ly_do_bo_qua.",
+		Request:  PrescriptionRequest{},
+		Response: CreatedPrescriptionResponse{},
+		Errors:   prescriptionErrors,
+	},
+	{
+		Method: http.MethodPost, Path: "/prescriptions/check", Tag: "prescriptions",
+		Summary:  "Check a prescription for allergies, interactions and duplicate ingredients without saving it",
+		Query:    []openapi.Param{{Name: "ma_don_thuoc", Description: "The prescription being edited, left out of the active medicines"}},
+		Request:  PrescriptionRequest{},
+		Response: PrescriptionCheckResponse{},
+		Errors:   map[int]interface{}{http.StatusBadRequest: UnavailableMedicationsResponse{}},
+	},
+	{
+		Method: http.MethodPut, Path: "/prescriptions/:id", Tag: "prescriptions",
+		Summary:  "Replace the medicines of a draft prescription (its doctor)",
+		Request:  PrescriptionRequest{},
+		Response: PrescriptionWarningsResponse{},
+		Errors:   prescriptionErrors,
+	},
+	{
+		Method: http.MethodPost, Path: "/prescriptions/:id/sign", Tag: "prescriptions",
+		Summary:  "Sign a draft prescription and issue its e-prescription code (its doctor)",
+		Response: SignedPrescriptionResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/prescriptions/:id/cancel", Tag: "prescriptions",
+		Summary: "Cancel a draft or signed prescription (prescribing doctor)",
+		Request: CancelPrescriptionRequest{},
+	},
+	{
+		Method: http.MethodGet, Path: "/prescription-templates", Tag: "prescription-templates",
+		Summary: "List the prescription templates the caller may use, most specific diagnosis first when ma_icd10 is given",
+		Query: []openapi.Param{
+			{Name: "pham_vi", Enum: []string{"PERSONAL", "CLINIC"}},
+			{Name: "q", Description: "Part of the template name"},
+			{Name: "ma_icd10", Description: "Templates for this code or one of its parents"},
+		},
+		Response: []TemplateSummaryResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/prescription-templates/:id", Tag: "prescription-templates",
+		Summary:  "Get a prescription template with its medicines and lab orders",
+		Response: TemplateDetailResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/prescription-templates", Tag: "prescription-templates", Status: http.StatusCreated,
+		Summary:  "Create a personal template (doctors) or a clinic template (managers)",
+		Request:  PrescriptionTemplateRequest{},
+		Response: CreatedTemplateResponse{},
+		Errors:   map[int]interface{}{http.StatusBadRequest: UnavailableMedicationsResponse{}},
+	},
+	{
+		Method: http.MethodPut, Path: "/prescription-templates/:id", Tag: "prescription-templates",
+		Summary: "Replace the name, diagnosis code and lines of a template",
+		Request: PrescriptionTemplateRequest{},
+		Errors:  map[int]interface{}{http.StatusBadRequest: UnavailableMedicationsResponse{}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/prescription-templates/:id", Tag: "prescription-templates",
+		Summary: "Delete a template",
+	},
+	{
+		Method: http.MethodPost, Path: "/prescription-templates/:id/apply", Tag: "prescription-templates",
+		Summary:  "Fill in a template for a medical record without saving it; discontinued medicines are left out (doctors)",
+		Request:  ApplyTemplateRequest{},
+		Response: AppliedTemplateResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/prescriptions/verify/:code", Tag: "prescriptions", Public: true,
+		Summary:  "Verify the code printed on a prescription (pharmacies, no login)",
+		Response: PrescriptionVerificationResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/medications", Tag: "medications", List: true,
+		Summary:  "Search the formulary",
+		Query:    listQuery(medicationList, "q", "dang_bao_che", "hoat_chat", "include_discontinued"),
+		Response: MedicationResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/medications", Tag: "medications", Status: http.StatusCreated,
+		Summary:  "Add a medicine to the formulary (managers)",
+		Request:  MedicationRequest{},
+		Response: CreatedMedicationResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/medications/interactions", Tag: "medications",
+		Summary:  "List drug interaction rules",
+		Query:    []openapi.Param{{Name: "hoat_chat", Description: "Only rules involving this ingredient"}},
+		Response: []InteractionResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/medications/interactions/import", Tag: "medications",
+		Summary:  "Import drug interactions from a CSV file with the header ingredient_a,ingredient_b,severity,description,recommendation (operation managers)",
+		Request:  openapi.Upload{Field: "file"},
+		Response: InteractionImportResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/medications/:id", Tag: "medications",
+		Summary:  "Get a medicine with its price history",
+		Response: MedicationDetailResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/medications/:id", Tag: "medications",
+		Summary: "Update a medicine; a price change starts a new price period (managers)",
+		Request: MedicationUpdateRequest{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/medications/:id", Tag: "medications",
+		Summary: "Discontinue a medicine so it can no longer be prescribed (managers)",
+	},
+	{
+		Method: http.MethodGet, Path: "/medications/:id/prices", Tag: "medications",
+		Summary:  "Get the price history of a medicine",
+		Response: []MedicationPriceResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/pharmacy/suppliers", Tag: "pharmacy",
+		Summary:  "List medicine suppliers",
+		Response: []SupplierResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/pharmacy/suppliers", Tag: "pharmacy", Status: http.StatusCreated,
+		Summary:  "Add a supplier (managers)",
+		Request:  SupplierRequest{},
+		Response: CreatedSupplierResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/pharmacy/stock", Tag: "pharmacy",
+		Summary: "List a clinic's stock by medicine and batch, earliest expiry first",
+		Query: []openapi.Param{
+			{Name: "ma_phong_kham", Description: clinicParam},
+			{Name: "ma_thuoc"},
+		},
+		Response: []StockResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/pharmacy/receipts", Tag: "pharmacy",
+		Summary:  "List a clinic's goods receipts, newest first",
+		Query:    []openapi.Param{{Name: "ma_phong_kham", Description: clinicParam}},
+		Response: []GoodsReceiptResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/pharmacy/receipts", Tag: "pharmacy", Status: http.StatusCreated,
+		Summary:  "Receive medicines from a supplier into stock (managers)",
+		Request:  GoodsReceiptRequest{},
+		Response: CreatedGoodsReceiptResponse{},
+		Errors:   map[int]interface{}{http.StatusBadRequest: UnavailableMedicationsResponse{}},
+	},
+	{
+		Method: http.MethodGet, Path: "/pharmacy/receipts/:id", Tag: "pharmacy",
+		Summary:  "Get a goods receipt with its items",
+		Query:    []openapi.Param{{Name: "ma_phong_kham", Description: clinicParam}},
+		Response: GoodsReceiptDetailResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/pharmacy/dispense/:id", Tag: "pharmacy",
+		Summary:  "Dispense a signed prescription from the clinic's stock, earliest expiry first; all or nothing (clinic staff)",
+		Response: []DispensedLotResponse{},
+		Errors:   map[int]interface{}{http.StatusConflict: []StockShortageResponse{}},
+	},
+	{
+		Method: http.MethodGet, Path: "/pharmacy/alerts", Tag: "pharmacy",
+		Summary: "Report low stock, batches expiring soon and expired batches (managers)",
+		Query: []openapi.Param{
+			{Name: "ma_phong_kham", Description: clinicParam},
+			{Name: "nguong", Description: "Low-stock threshold in units (default 10)"},
+			{Name: "so_ngay", Description: "Days ahead for expiring batches (default 90)"},
+		},
+		Response: PharmacyAlertsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/pharmacy/ledger", Tag: "pharmacy",
+		Summary: "List stock movements, newest first (managers)",
+		Query: []openapi.Param{
+			{Name: "ma_phong_kham", Description: clinicParam},
+			{Name: "ma_thuoc"},
+			{Name: "tu_ngay", Description: "YYYY-MM-DD"},
+			{Name: "den_ngay", Description: "YYYY-MM-DD"},
+		},
+		Response: []StockMovementResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/icd10", Tag: "icd10",
+		Summary: "Search ICD-10 codes by code prefix or title, tolerating typos when nothing matches",
+		Query: []openapi.Param{
+			{Name: "q"},
+			{Name: "chapter"},
+			{Name: "limit", Description: "1 to 100 (default 20)"},
+		},
+		Response: []models.ICD10Code{},
+	},
+	{
+		Method: http.MethodGet, Path: "/icd10/chapters", Tag: "icd10",
+		Summary:  "List ICD-10 chapters with their number of codes",
+		Response: []ICD10ChapterResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/icd10/:code", Tag: "icd10",
+		Summary:  "Get an ICD-10 code with its sub-codes",
+		Response: ICD10DetailResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/icd10/import", Tag: "icd10",
+		Summary:  "Import the ICD-10 catalogue from a CSV file with the header code,title_en,title_vi,chapter,parent (operation managers)",
+		Request:  openapi.Upload{Field: "file"},
+		Response: ICD10ImportResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/lab-tests", Tag: "lab-tests", List: true,
+		Summary:  "List lab tests. Customers see their own, doctors those they ordered, clinic managers their clinic's.",
+		Query:    listQuery(labTestList, "ma_customer", "ma_ho_so", "status"),
+		Response: LabTestResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/lab-tests", Tag: "lab-tests", Status: http.StatusCreated,
+		Summary:  "Order a lab test for a medical record (doctors)",
+		Request:  LabTestRequest{},
+		Response: CreatedLabTestResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/lab-tests/:id", Tag: "lab-tests",
+		Summary:  "Get a lab test",
+		Response: LabTestResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/lab-tests/:id", Tag: "lab-tests",
+		Summary: "Record the results of a lab test (the ordering doctor)",
+		Request: UpdateLabTestRequest{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/lab-tests/:id", Tag: "lab-tests",
+		Summary: "Delete a lab order (the ordering doctor)",
+	},
+	{
+		Method: http.MethodGet, Path: "/lab-test-types", Tag: "lab-tests",
+		Summary:  "List the lab test types in use, or common ones when there are none",
+		Response: []string{},
+	},
+	{
+		Method: http.MethodGet, Path: "/schedules", Tag: "schedules", List: true,
+		Summary:  "List work schedules. Doctors see their own, clinic managers their clinic's.",
+		Query:    listQuery(scheduleList, "doctor_id", "clinic_id", "room_id", "status"),
+		Response: models.WorkScheduleDetail{},
+	},
+	{
+		Method: http.MethodPost, Path: "/schedules", Tag: "schedules", Status: http.StatusCreated,
+		Summary:  "Create a work schedule (clinic managers)",
+		Request:  ScheduleRequest{},
+		Response: CreatedScheduleResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/schedules/:id", Tag: "schedules",
+		Summary:  "Get a work schedule",
+		Response: models.WorkScheduleDetail{},
+	},
+	{
+		Method: http.MethodPut, Path: "/schedules/:id", Tag: "schedules",
+		Summary: "Update a work schedule (clinic managers)",
+		Request: UpdateScheduleRequest{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/schedules/:id", Tag: "schedules",
+		Summary: "Delete a work schedule without active appointments",
+	},
+}
+
+// clinicParam describes ma_phong_kham on the pharmacy endpoints.
+const clinicParam = "Required for operation managers; others use their own clinic"
+
+// prescriptionErrors are the error responses of saving a prescription that
+// carry data: unavailable medicines and unacknowledged safety warnings.
+var prescriptionErrors = map[int]interface{}{
+	http.StatusBadRequest: UnavailableMedicationsResponse{},
+	http.StatusConflict:   PrescriptionWarningsResponse{},
+}
+
+// staffMember is a staff account as the model of its role.
+var staffMember = openapi.AnyOf{models.Doctor{}, models.Receptionist{}, models.Accountant{}, models.ClinicManager{}, models.OperationManager{}}
+
+// listQuery documents the paging, sort and date parameters of a list and
+// its filters.
+func listQuery(spec listSpec, filters ...string) []openapi.Param {
+	fields := make([]string, 0, len(spec.sorts))
+	for field := range spec.sorts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	sorts := append([]string{}, fields...)
+	for _, field := range fields {
+		sorts = append(sorts, "-"+field)
+	}
+
+	params := []openapi.Param{
+		{Name: "page", Description: "Page number, from 1"},
+		{Name: "page_size", Description: "Items per page, 1 to 100 (default 20)"},
+		{Name: "cursor", Description: "next_cursor of the previous page; not with page"},
+		{Name: "sort", Description: "Sort key, - for descending (default " + spec.defaultSort + ")", Enum: sorts},
+		{Name: "date_from", Description: "YYYY-MM-DD, inclusive"},
+		{Name: "date_to", Description: "YYYY-MM-DD, inclusive"},
+	}
+	for _, filter := range filters {
+		params = append(params, openapi.Param{Name: filter})
+	}
+	return params
+}
+
+// NewOpenAPIDocument generates the OpenAPI document of the routes under
+// /api/v1 from apiEndpoints. It fails when a route is not described or a
+// description has no route, so no route can be left out of the document.
+func NewOpenAPIDocument(routes gin.RoutesInfo) (*openapi.Document, error) {
+	const base = "/api/v1"
+
+	described := make(map[string]openapi.Endpoint, len(apiEndpoints))
+	for _, e := range apiEndpoints {
+		described[e.Method+" "+e.Path] = e
+	}
+
+	var endpoints []openapi.Endpoint
+	var problems []string
+	for _, route := range routes {
+		path, ok := strings.CutPrefix(route.Path, base)
+		if !ok || path == "/openapi.json" {
+			continue
+		}
+		key := route.Method + " " + path
+		e, ok := described[key]
+		if !ok {
+			problems = append(problems, route.Method+" "+route.Path+" is not described")
+			continue
+		}
+		endpoints = append(endpoints, e)
+		delete(described, key)
+	}
+	for key := range described {
+		problems = append(problems, key+" is described but not routed")
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("OpenAPI document does not match the routes: %s", strings.Join(problems, "; "))
+	}
+
+	return openapi.Build(openapi.Info{Title: "Clinic Management API", Version: "1.0"}, base, utils.Pagination{}, endpoints), nil
+}
+
+type OpenAPIHandler struct {
+	document *openapi.Document
+}
+
+func NewOpenAPIHandler(document *openapi.Document) *OpenAPIHandler {
+	return &OpenAPIHandler{document: document}
+}
+
+// GetOpenAPI serves the OpenAPI 3 document.
+func (h *OpenAPIHandler) GetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.document)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/openapi"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// describedRoutes is a route table serving exactly the described
+// endpoints.
+func describedRoutes() gin.RoutesInfo {
+	routes := gin.RoutesInfo{{Method: http.MethodGet, Path: "/api/v1/openapi.json"}}
+	for _, e := range apiEndpoints {
+		routes = append(routes, gin.RouteInfo{Method: e.Method, Path: "/api/v1" + e.Path})
+	}
+	return routes
+}
+
+func testDocument(t *testing.T) *openapi.Document {
+	t.Helper()
+	doc, err := NewOpenAPIDocument(describedRoutes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/openapi.json", NewOpenAPIHandler(testDocument(t)).GetOpenAPI)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q, want %q", doc.OpenAPI, openapi.Version)
+	}
+	for _, e := range apiEndpoints {
+		path := e.Path
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				path = strings.Replace(path, segment, "{"+segment[1:]+"}", 1)
+			}
+		}
+		if doc.Paths[path][strings.ToLower(e.Method)] == nil {
+			t.Errorf("%s %s is missing from the document", e.Method, path)
+		}
+	}
+}
+
+// TestHandlersMatchOpenAPI runs the handlers backed by services on an
+// in-memory store and checks every response against the document.
+func TestHandlersMatchOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc := testDocument(t)
+
+	store := memory.NewStore()
+	room := "PH00001"
+	store.Appointments["LK001"] = models.AppointmentDetail{
+		Appointment: models.Appointment{
+			MaLichKham: "LK001", MaCustomer: "CUS001", MaBacSi: "BS001", MaPhongKham: "PK001", MaPhong: &room,
+			NgayGioKham: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), TrangThai: services.AppointmentScheduled,
+		},
+		TenKhachHang: "Nguyen Van A", TenBacSi: "Tran Thi B", TenPhongKham: "Phong kham 1",
+	}
+	store.Schedules["LLV001"] = models.WorkScheduleDetail{WorkSchedule: models.WorkSchedule{
+		MaLichLamViec: "LLV001", MaBacSi: "BS001", MaPhongKham: "PK001",
+		NgayLamViec: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), GioBatDau: "08:00", GioKetThuc: "12:00", TrangThai: "AVAILABLE",
+	}}
+	store.Prescriptions["DT001"] = models.Prescription{MaDonThuoc: "DT001", MaBacSi: "BS001", TrangThai: services.PrescriptionSigned}
+
+	appointments := &AppointmentHandler{appointments: services.NewAppointmentService(store.AppointmentRepository(), store.ScheduleRepository(), store.ClinicRepository())}
+	schedules := &ScheduleHandler{schedules: services.NewScheduleService(store.ScheduleRepository(), store.UserRepository(), store.ClinicRepository())}
+	prescriptions := &PrescriptionHandler{prescriptions: services.NewPrescriptionService(store.PrescriptionRepository(), store.MedicalRecordRepository())}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+		c.Set("user_type", c.GetHeader("X-User-Type"))
+	})
+	router.GET("/appointments/:id", appointments.GetAppointment)
+	router.DELETE("/appointments/:id", appointments.CancelAppointment)
+	router.GET("/schedules/:id", schedules.GetSchedule)
+	router.DELETE("/schedules/:id", schedules.DeleteSchedule)
+	router.POST("/prescriptions/:id/cancel", prescriptions.CancelPrescription)
+
+	tests := []struct {
+		method, path, userID, userType, body string
+		wantStatus                           int
+	}{
+		{http.MethodGet, "/appointments/LK001", "CUS001", "CUSTOMER", "", http.StatusOK},
+		{http.MethodGet, "/appointments/LK999", "CUS001", "CUSTOMER", "", http.StatusNotFound},
+		{http.MethodDelete, "/appointments/LK001", "CUS002", "CUSTOMER", "", http.StatusForbidden},
+		{http.MethodGet, "/schedules/LLV001", "BS001", "DOCTOR", "", http.StatusOK},
+		{http.MethodDelete, "/schedules/LLV001", "BS001", "DOCTOR", "", http.StatusConflict},
+		{http.MethodPost, "/prescriptions/DT001/cancel", "BS001", "DOCTOR", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/prescriptions/DT001/cancel", "BS001", "DOCTOR", `{"ly_do":"Sai liều"}`, http.StatusOK},
+		{http.MethodDelete, "/appointments/LK001", "CUS001", "CUSTOMER", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-ID", tt.userID)
+			req.Header.Set("X-User-Type", tt.userType)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if err := doc.ValidateResponse(tt.method, tt.path, rec.Code, rec.Body.Bytes()); err != nil {
+				t.Errorf("response does not match the document: %v\n%s", err, rec.Body)
+			}
+		})
+	}
+}
+
+// TestListResponsesMatchOpenAPI checks the list envelope of every
+// documented list, in page and cursor mode, with items of its documented
+// type.
+func TestListResponsesMatchOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc := testDocument(t)
+	specs := map[string]listSpec{
+		"/appointments":         appointmentList,
+		"/medical-records":      medicalRecordList,
+		"/customers":            customerList,
+		"/prescriptions":        prescriptionList,
+		"/medications":          medicationList,
+		"/lab-tests":            labTestList,
+		"/schedules":            scheduleList,
+		"/admin/login-attempts": loginAttemptList,
+	}
+
+	for _, e := range apiEndpoints {
+		if !e.List {
+			continue
+		}
+		spec, ok := specs[e.Path]
+		if !ok {
+			t.Errorf("%s has no list spec in this test", e.Path)
+			continue
+		}
+		for _, cursor := range []*utils.Cursor{nil, {Sort: spec.defaultSort, Value: "x", ID: "1"}} {
+			params := utils.ListParams{Page: 1, PageSize: 1, Cursor: cursor, Sort: spec.defaultSort}
+			params.SortField = strings.TrimPrefix(spec.defaultSort, "-")
+
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			respondList(c, spec, "ok", []interface{}{e.Response, e.Response}, params, 2)
+
+			if err := doc.ValidateResponse(e.Method, e.Path, rec.Code, rec.Body.Bytes()); err != nil {
+				t.Errorf("%s (cursor %v): %v\n%s", e.Path, cursor != nil, err, rec.Body)
+			}
+		}
+	}
+}
+
+// TestOpenAPIDocumentMatchesRoutes checks that the document cannot be
+// generated while a route is undescribed or a description unrouted.
+func TestOpenAPIDocumentMatchesRoutes(t *testing.T) {
+	undescribed := append(describedRoutes(), gin.RouteInfo{Method: http.MethodGet, Path: "/api/v1/undescribed"})
+	if _, err := NewOpenAPIDocument(undescribed); err == nil || !strings.Contains(err.Error(), "GET /api/v1/undescribed is not described") {
+		t.Errorf("undescribed route: err = %v", err)
+	}
+
+	routes := describedRoutes()
+	unrouted := routes[len(routes)-1]
+	if _, err := NewOpenAPIDocument(routes[:len(routes)-1]); err == nil || !strings.Contains(err.Error(), "is described but not routed") {
+		t.Errorf("%s %s left unrouted: err = %v", unrouted.Method, unrouted.Path, err)
+	}
+
+	outside := append(describedRoutes(), gin.RouteInfo{Method: http.MethodGet, Path: "/.well-known/jwks.json"})
+	if _, err := NewOpenAPIDocument(outside); err != nil {
+		t.Errorf("routes outside /api/v1 are not described: %v", err)
+	}
+}
+
+func TestValidateResponseRejectsDrift(t *testing.T) {
+	doc := testDocument(t)
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Schedule retrieved successfully",
+		"data": map[string]interface{}{
+			"ma_lich_lam_viec": "LLV001",
+			"ten_khach_hang":   "not a schedule field",
+		},
+	})
+
+	if err := doc.ValidateResponse(http.MethodGet, "/schedules/LLV001", http.StatusOK, body.Bytes()); err == nil {
+		t.Error("a body with undocumented and missing fields passed validation")
+	}
+}
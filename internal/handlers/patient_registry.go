@@ -0,0 +1,524 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PatientRegistryHandler manages a customer's allergy list and chronic
+// condition (problem) list.
+type PatientRegistryHandler struct {
+	db *sql.DB
+}
+
+func NewPatientRegistryHandler(db *sql.DB) *PatientRegistryHandler {
+	return &PatientRegistryHandler{db: db}
+}
+
+type AllergyRequest struct {
+	TacNhan     string `json:"tac_nhan" binding:"required"`
+	LoaiTacNhan string `json:"loai_tac_nhan" binding:"omitempty,oneof=THUOC THUC_PHAM MOI_TRUONG KHAC"`
+	MaThuoc     string `json:"ma_thuoc"`
+	PhanUng     string `json:"phan_ung"`
+	MucDo       string `json:"muc_do" binding:"required,oneof=MILD MODERATE SEVERE LIFE_THREATENING"`
+	GhiChu      string `json:"ghi_chu"`
+}
+
+type ConditionRequest struct {
+	TenBenh      string `json:"ten_benh" binding:"required"`
+	MaICD10      string `json:"ma_icd10"`
+	NgayChanDoan string `json:"ngay_chan_doan"`
+	TrangThai    string `json:"trang_thai" binding:"omitempty,oneof=ACTIVE RESOLVED"`
+	GhiChu       string `json:"ghi_chu"`
+}
+
+// allergyEntry is an active allergy as consulted when prescribing.
+type allergyEntry struct {
+	MaDiUng     string
+	TacNhan     string
+	LoaiTacNhan string
+	MaThuoc     sql.NullString
+	PhanUng     sql.NullString
+	MucDo       string
+}
+
+func (h *PatientRegistryHandler) GetAllergies(c *gin.Context) {
+	customerID := c.Param("id")
+	if !canViewPatient(c, h.db, customerID) {
+		return
+	}
+
+	allergies, err := getPatientAllergies(h.db, customerID, c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve allergies",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Allergies retrieved successfully",
+		Data:    allergies,
+	})
+}
+
+func (h *PatientRegistryHandler) CreateAllergy(c *gin.Context) {
+	customerID := c.Param("id")
+	if !h.canEditPatient(c, customerID) {
+		return
+	}
+
+	var req AllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !h.validateAllergyMedication(c, &req) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	allergyID, err := utils.GenerateAllergyID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to record allergy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO DIUNG (maDiUng, maCustomer, tacNhan, loaiTacNhan, maThuoc, phanUng, mucDo,
+		                   ghiChu, trangThai, nguoiGhiNhan, ngayGhiNhan)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, 'ACTIVE', @p9, @p10)
+	`, allergyID, customerID, strings.TrimSpace(req.TacNhan), req.LoaiTacNhan, services.NullIfEmpty(req.MaThuoc),
+		services.NullIfEmpty(req.PhanUng), req.MucDo, services.NullIfEmpty(req.GhiChu), userID, time.Now())
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to record allergy",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Allergy recorded successfully",
+		Data: CreatedAllergyResponse{
+			MaDiUng: allergyID,
+		},
+	})
+}
+
+func (h *PatientRegistryHandler) UpdateAllergy(c *gin.Context) {
+	customerID := c.Param("id")
+	if !h.canEditPatient(c, customerID) {
+		return
+	}
+
+	var req AllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !h.validateAllergyMedication(c, &req) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.db.Exec(`
+		UPDATE DIUNG
+		SET tacNhan = @p1, loaiTacNhan = @p2, maThuoc = @p3, phanUng = @p4, mucDo = @p5,
+		    ghiChu = @p6, nguoiGhiNhan = @p7, ngayGhiNhan = @p8
+		WHERE maDiUng = @p9 AND maCustomer = @p10
+	`, strings.TrimSpace(req.TacNhan), req.LoaiTacNhan, services.NullIfEmpty(req.MaThuoc), services.NullIfEmpty(req.PhanUng),
+		req.MucDo, services.NullIfEmpty(req.GhiChu), userID, time.Now(), c.Param("allergy_id"), customerID)
+
+	h.respondRegistryChange(c, result, err, "Allergy not found", "Failed to update allergy", "Allergy updated successfully")
+}
+
+// DeleteAllergy marks an allergy inactive instead of removing it so the
+// history of what was recorded stays available.
+func (h *PatientRegistryHandler) DeleteAllergy(c *gin.Context) {
+	customerID := c.Param("id")
+	if !h.canEditPatient(c, customerID) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.db.Exec(`
+		UPDATE DIUNG SET trangThai = 'INACTIVE', nguoiGhiNhan = @p1, ngayGhiNhan = @p2
+		WHERE maDiUng = @p3 AND maCustomer = @p4
+	`, userID, time.Now(), c.Param("allergy_id"), customerID)
+
+	h.respondRegistryChange(c, result, err, "Allergy not found", "Failed to remove allergy", "Allergy removed successfully")
+}
+
+func (h *PatientRegistryHandler) GetConditions(c *gin.Context) {
+	customerID := c.Param("id")
+	if !canViewPatient(c, h.db, customerID) {
+		return
+	}
+
+	conditions, err := getPatientConditions(h.db, customerID, c.Query("include_resolved") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve chronic conditions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Chronic conditions retrieved successfully",
+		Data:    conditions,
+	})
+}
+
+func (h *PatientRegistryHandler) CreateCondition(c *gin.Context) {
+	customerID := c.Param("id")
+	if !h.canEditPatient(c, customerID) {
+		return
+	}
+
+	var req ConditionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	ngayChanDoan, ok := h.validateCondition(c, &req)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	conditionID, err := utils.GenerateChronicConditionID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to record chronic condition",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO BENHMANTINH (maBenhManTinh, maCustomer, tenBenh, maICD10, ngayChanDoan,
+		                         trangThai, ghiChu, nguoiGhiNhan, ngayGhiNhan)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9)
+	`, conditionID, customerID, strings.TrimSpace(req.TenBenh), services.NullIfEmpty(req.MaICD10), ngayChanDoan,
+		req.TrangThai, services.NullIfEmpty(req.GhiChu), userID, time.Now())
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to record chronic condition",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Chronic condition recorded successfully",
+		Data: CreatedConditionResponse{
+			MaBenhManTinh: conditionID,
+		},
+	})
+}
+
+func (h *PatientRegistryHandler) UpdateCondition(c *gin.Context) {
+	customerID := c.Param("id")
+	if !h.canEditPatient(c, customerID) {
+		return
+	}
+
+	var req ConditionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	ngayChanDoan, ok := h.validateCondition(c, &req)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.db.Exec(`
+		UPDATE BENHMANTINH
+		SET tenBenh = @p1, maICD10 = @p2, ngayChanDoan = @p3, trangThai = @p4, ghiChu = @p5,
+		    nguoiGhiNhan = @p6, ngayGhiNhan = @p7
+		WHERE maBenhManTinh = @p8 AND maCustomer = @p9
+	`, strings.TrimSpace(req.TenBenh), services.NullIfEmpty(req.MaICD10), ngayChanDoan, req.TrangThai,
+		services.NullIfEmpty(req.GhiChu), userID, time.Now(), c.Param("condition_id"), customerID)
+
+	h.respondRegistryChange(c, result, err, "Chronic condition not found",
+		"Failed to update chronic condition", "Chronic condition updated successfully")
+}
+
+// DeleteCondition marks a chronic condition inactive, like DeleteAllergy,
+// so the clinical history keeps what was recorded.
+func (h *PatientRegistryHandler) DeleteCondition(c *gin.Context) {
+	customerID := c.Param("id")
+	if !h.canEditPatient(c, customerID) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.db.Exec(`
+		UPDATE BENHMANTINH SET trangThai = 'INACTIVE', nguoiGhiNhan = @p1, ngayGhiNhan = @p2
+		WHERE maBenhManTinh = @p3 AND maCustomer = @p4
+	`, userID, time.Now(), c.Param("condition_id"), customerID)
+
+	h.respondRegistryChange(c, result, err, "Chronic condition not found",
+		"Failed to remove chronic condition", "Chronic condition removed successfully")
+}
+
+// canEditPatient allows only doctors who have seen or are booked with the
+// patient to change the registry.
+func (h *PatientRegistryHandler) canEditPatient(c *gin.Context, customerID string) bool {
+	userType, _ := c.Get("user_type")
+	if userType.(string) != "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only doctors can edit allergies and chronic conditions",
+		})
+		return false
+	}
+	return canViewPatient(c, h.db, customerID)
+}
+
+func (h *PatientRegistryHandler) validateAllergyMedication(c *gin.Context, req *AllergyRequest) bool {
+	if req.LoaiTacNhan == "" {
+		req.LoaiTacNhan = "THUOC"
+	}
+	if req.MaThuoc == "" {
+		return true
+	}
+
+	var exists int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM THUOC WHERE maThuoc = @p1", req.MaThuoc).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check medication",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	if exists == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Unknown medication: " + req.MaThuoc,
+		})
+		return false
+	}
+	return true
+}
+
+func (h *PatientRegistryHandler) validateCondition(c *gin.Context, req *ConditionRequest) (interface{}, bool) {
+	if req.TrangThai == "" {
+		req.TrangThai = "ACTIVE"
+	}
+
+	if req.MaICD10 != "" {
+		codes := []string{req.MaICD10}
+		if !validateICD10Codes(c, h.db, codes) {
+			return nil, false
+		}
+		req.MaICD10 = codes[0]
+	}
+
+	if req.NgayChanDoan == "" {
+		return nil, true
+	}
+	ngayChanDoan, err := time.Parse("2006-01-02", req.NgayChanDoan)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid ngay_chan_doan format. Use YYYY-MM-DD",
+		})
+		return nil, false
+	}
+	return ngayChanDoan, true
+}
+
+func (h *PatientRegistryHandler) respondRegistryChange(c *gin.Context, result sql.Result, err error, notFound, failed, success string) {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: failed,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: notFound,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: success,
+	})
+}
+
+// getPatientAllergies lists a customer's allergies, most severe first, for
+// the record, prescription and summary views.
+func getPatientAllergies(q sqlQueryer, customerID string, includeInactive bool) ([]AllergyResponse, error) {
+	query := `
+		SELECT d.maDiUng, d.tacNhan, d.loaiTacNhan, d.maThuoc, d.phanUng, d.mucDo, d.ghiChu,
+		       d.trangThai, d.nguoiGhiNhan, u.hoTen, d.ngayGhiNhan
+		FROM DIUNG d
+		LEFT JOIN [USER] u ON d.nguoiGhiNhan = u.userID
+		WHERE d.maCustomer = @p1
+	`
+	if !includeInactive {
+		query += " AND d.trangThai = 'ACTIVE'"
+	}
+	query += `
+		ORDER BY CASE d.mucDo WHEN 'LIFE_THREATENING' THEN 0 WHEN 'SEVERE' THEN 1
+		                      WHEN 'MODERATE' THEN 2 ELSE 3 END, d.tacNhan
+	`
+
+	rows, err := q.Query(query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allergies := []AllergyResponse{}
+	for rows.Next() {
+		var a AllergyResponse
+		var maThuoc, phanUng, ghiChu, tenNguoiGhiNhan sql.NullString
+
+		if err := rows.Scan(&a.MaDiUng, &a.TacNhan, &a.LoaiTacNhan, &maThuoc, &phanUng, &a.MucDo, &ghiChu,
+			&a.TrangThai, &a.NguoiGhiNhan, &tenNguoiGhiNhan, &a.NgayGhiNhan); err != nil {
+			return nil, err
+		}
+		a.MaThuoc = maThuoc.String
+		a.PhanUng = phanUng.String
+		a.GhiChu = ghiChu.String
+		a.TenNguoiGhiNhan = tenNguoiGhiNhan.String
+		allergies = append(allergies, a)
+	}
+	return allergies, rows.Err()
+}
+
+func getPatientConditions(q sqlQueryer, customerID string, includeResolved bool) ([]ChronicConditionResponse, error) {
+	query := `
+		SELECT b.maBenhManTinh, b.tenBenh, b.maICD10, i.tenTiengViet, b.ngayChanDoan, b.trangThai,
+		       b.ghiChu, b.nguoiGhiNhan, u.hoTen, b.ngayGhiNhan
+		FROM BENHMANTINH b
+		LEFT JOIN ICD10 i ON b.maICD10 = i.maICD10
+		LEFT JOIN [USER] u ON b.nguoiGhiNhan = u.userID
+		WHERE b.maCustomer = @p1
+	`
+	if !includeResolved {
+		query += " AND b.trangThai = 'ACTIVE'"
+	}
+	query += " ORDER BY b.trangThai, b.ngayChanDoan DESC"
+
+	rows, err := q.Query(query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conditions := []ChronicConditionResponse{}
+	for rows.Next() {
+		var b ChronicConditionResponse
+		var maICD10, tenICD10, ghiChu, tenNguoiGhiNhan sql.NullString
+		var ngayChanDoan sql.NullTime
+
+		if err := rows.Scan(&b.MaBenhManTinh, &b.TenBenh, &maICD10, &tenICD10, &ngayChanDoan, &b.TrangThai,
+			&ghiChu, &b.NguoiGhiNhan, &tenNguoiGhiNhan, &b.NgayGhiNhan); err != nil {
+			return nil, err
+		}
+		b.MaICD10 = maICD10.String
+		b.TenICD10 = tenICD10.String
+		b.NgayChanDoan = timePointer(ngayChanDoan)
+		b.GhiChu = ghiChu.String
+		b.TenNguoiGhiNhan = tenNguoiGhiNhan.String
+		conditions = append(conditions, b)
+	}
+	return conditions, rows.Err()
+}
+
+func loadActiveAllergies(q sqlQueryer, customerID string) ([]allergyEntry, error) {
+	rows, err := q.Query(`
+		SELECT maDiUng, tacNhan, loaiTacNhan, maThuoc, phanUng, mucDo
+		FROM DIUNG
+		WHERE maCustomer = @p1 AND trangThai = 'ACTIVE'
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allergies []allergyEntry
+	for rows.Next() {
+		var a allergyEntry
+		if err := rows.Scan(&a.MaDiUng, &a.TacNhan, &a.LoaiTacNhan, &a.MaThuoc, &a.PhanUng, &a.MucDo); err != nil {
+			return nil, err
+		}
+		allergies = append(allergies, a)
+	}
+	return allergies, rows.Err()
+}
+
+// matchesMedication reports whether an allergy applies to a medicine: by
+// its code, by one of its active ingredients, or by the substance name
+// appearing in the medicine name.
+func (a allergyEntry) matchesMedication(maThuoc, tenThuoc string, hoatChat []string) bool {
+	if a.MaThuoc.Valid && a.MaThuoc.String == maThuoc {
+		return true
+	}
+	if a.LoaiTacNhan != "THUOC" {
+		return false
+	}
+
+	substance := utils.NormalizeSearchText(a.TacNhan)
+	if substance == "" {
+		return false
+	}
+	if containsString(hoatChat, substance) {
+		return true
+	}
+	name := utils.NormalizeSearchText(tenThuoc)
+	return name != "" && (strings.Contains(name, substance) || strings.Contains(substance, name))
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPatientSummary aggregates a patient's history into one view: allergies
+// and chronic conditions, visit timeline, recurring diagnoses, medications,
+// latest lab results and upcoming follow-ups.
+func (h *MedicalRecordHandler) GetPatientSummary(c *gin.Context) {
+	customerID := c.Param("id")
+
+	if !canViewPatient(c, h.db, customerID) {
+		return
+	}
+
+	summary := PatientSummaryResponse{BenhNhan: SummaryPatientResponse{MaCustomer: customerID}}
+	patient := &summary.BenhNhan
+	err := h.db.QueryRow(`
+		SELECT u.hoTen, c.ngaySinh, c.gioiTinh, c.maBaoHiem
+		FROM [USER] u
+		JOIN CUSTOMER c ON u.userID = c.maUser
+		WHERE u.userID = @p1
+	`, customerID).Scan(&patient.HoTen, &patient.NgaySinh, &patient.GioiTinh, &patient.MaBaoHiem)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Customer not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve customer",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	summary.DiUng, err = getPatientAllergies(h.db, customerID, false)
+	if err == nil {
+		summary.BenhManTinh, err = getPatientConditions(h.db, customerID, false)
+	}
+	if err == nil {
+		summary.LichSuKham, err = h.summaryVisits(customerID)
+	}
+	if err == nil {
+		summary.ChanDoanTaiDien, err = h.summaryRecurringDiagnoses(customerID)
+	}
+	if err == nil {
+		summary.ThuocDangDung, err = h.summaryMedications(customerID, "dt.ngayHeHan >= GETDATE()")
+	}
+	if err == nil {
+		summary.ThuocDaDung, err = h.summaryMedications(customerID, "dt.ngayHeHan < GETDATE()")
+	}
+	if err == nil {
+		summary.XetNghiemGanNhat, err = h.summaryLatestLabResults(customerID)
+	}
+	if err == nil {
+		summary.TaiKhamSapToi, err = h.summaryUpcomingFollowUps(customerID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to build patient summary",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Patient summary retrieved successfully",
+		Data:    summary,
+	})
+}
+
+// canViewPatient writes a 403 response unless the patient is in the
+// caller's services.PatientScope, the rule medical records follow too.
+func canViewPatient(c *gin.Context, db *sql.DB, customerID string) bool {
+	users := services.NewUserService(services.NewSQLUserRepository(db))
+	if err := users.CanViewPatient(viewerOf(c), customerID); err != nil {
+		serviceFailed(c, err, "Failed to verify patient access")
+		return false
+	}
+	return true
+}
+
+func (h *MedicalRecordHandler) summaryVisits(customerID string) ([]SummaryVisitResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT h.maHoSo, h.ngayKham, h.maBacSi, u.hoTen, p.tenPhongKham,
+		       h.chanDoan, h.maICD10, h.ngayTaiKham
+		FROM HOSO h
+		JOIN [USER] u ON h.maBacSi = u.userID
+		JOIN PHONGKHAM p ON h.maPhongKham = p.maPhongKham
+		WHERE h.maCustomer = @p1
+		ORDER BY h.ngayKham DESC
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	visits := []SummaryVisitResponse{}
+	for rows.Next() {
+		var v SummaryVisitResponse
+		var chanDoan, maICD10 sql.NullString
+		var ngayTaiKham sql.NullTime
+
+		if err := rows.Scan(&v.MaHoSo, &v.NgayKham, &v.MaBacSi, &v.TenBacSi, &v.TenPhongKham,
+			&chanDoan, &maICD10, &ngayTaiKham); err != nil {
+			return nil, err
+		}
+		v.ChanDoan = chanDoan.String
+		v.MaICD10 = maICD10.String
+		v.NgayTaiKham = timePointer(ngayTaiKham)
+		visits = append(visits, v)
+	}
+	return visits, rows.Err()
+}
+
+// summaryRecurringDiagnoses lists ICD-10 codes, primary or secondary, that
+// appear in more than one visit.
+func (h *MedicalRecordHandler) summaryRecurringDiagnoses(customerID string) ([]RecurringDiagnosisResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT d.maICD10, i.tenTiengAnh, i.tenTiengViet,
+		       COUNT(DISTINCT d.maHoSo) AS soLan, MAX(d.ngayKham) AS lanCuoi
+		FROM (
+			SELECT h.maHoSo, h.maICD10, h.ngayKham
+			FROM HOSO h
+			WHERE h.maCustomer = @p1 AND h.maICD10 IS NOT NULL
+			UNION ALL
+			SELECT h.maHoSo, p.maICD10, h.ngayKham
+			FROM HOSO h
+			JOIN HOSO_ICD10PHU p ON h.maHoSo = p.maHoSo
+			WHERE h.maCustomer = @p1
+		) d
+		LEFT JOIN ICD10 i ON d.maICD10 = i.maICD10
+		GROUP BY d.maICD10, i.tenTiengAnh, i.tenTiengViet
+		HAVING COUNT(DISTINCT d.maHoSo) > 1
+		ORDER BY soLan DESC, lanCuoi DESC
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	diagnoses := []RecurringDiagnosisResponse{}
+	for rows.Next() {
+		var d RecurringDiagnosisResponse
+		var tenTiengAnh, tenTiengViet sql.NullString
+
+		if err := rows.Scan(&d.MaICD10, &tenTiengAnh, &tenTiengViet, &d.SoLan, &d.LanCuoi); err != nil {
+			return nil, err
+		}
+		d.TenTiengAnh = tenTiengAnh.String
+		d.TenTiengViet = tenTiengViet.String
+		diagnoses = append(diagnoses, d)
+	}
+	return diagnoses, rows.Err()
+}
+
+// summaryMedications lists prescribed medicines; a prescription counts as
+// current until its DONTHUOC.ngayHeHan expiry date.
+func (h *MedicalRecordHandler) summaryMedications(customerID, condition string) ([]SummaryMedicationResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT dt.maDonThuoc, dt.ngayHeHan, ct.maThuoc, t.tenThuoc, ct.soLuong, ct.cachDung,
+		       h.maHoSo, h.ngayKham, u.hoTen
+		FROM DONTHUOC dt
+		JOIN HOSO h ON dt.maHoSo = h.maHoSo
+		JOIN CHITIETDONTHUOC ct ON dt.maDonThuoc = ct.maDonThuoc
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		JOIN [USER] u ON h.maBacSi = u.userID
+		WHERE h.maCustomer = @p1 AND dt.trangThai IN ('SIGNED', 'DISPENSED') AND `+condition+`
+		ORDER BY h.ngayKham DESC, t.tenThuoc
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	medications := []SummaryMedicationResponse{}
+	for rows.Next() {
+		var m SummaryMedicationResponse
+		var ngayHeHan sql.NullTime
+		var soLuong sql.NullInt32
+		var cachDung sql.NullString
+
+		if err := rows.Scan(&m.MaDonThuoc, &ngayHeHan, &m.MaThuoc, &m.TenThuoc, &soLuong, &cachDung,
+			&m.MaHoSo, &m.NgayKeDon, &m.TenBacSi); err != nil {
+			return nil, err
+		}
+		m.NgayHetHan = timePointer(ngayHeHan)
+		m.SoLuong = int(soLuong.Int32)
+		m.CachDung = cachDung.String
+		medications = append(medications, m)
+	}
+	return medications, rows.Err()
+}
+
+// summaryLatestLabResults returns the most recent result of each test type.
+func (h *MedicalRecordHandler) summaryLatestLabResults(customerID string) ([]SummaryLabResultResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT maXetNghiem, maHoSo, loaiXetNghiem, ngayXetNghiem, ketQua
+		FROM (
+			SELECT xn.maXetNghiem, xn.maHoSo, xn.loaiXetNghiem, xn.ngayXetNghiem, xn.ketQua,
+			       ROW_NUMBER() OVER (PARTITION BY xn.loaiXetNghiem ORDER BY xn.ngayXetNghiem DESC) AS thuTu
+			FROM XETNGHIEM xn
+			JOIN HOSO h ON xn.maHoSo = h.maHoSo
+			WHERE h.maCustomer = @p1 AND xn.ketQua IS NOT NULL AND xn.ketQua <> ''
+		) latest
+		WHERE thuTu = 1
+		ORDER BY ngayXetNghiem DESC
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SummaryLabResultResponse{}
+	for rows.Next() {
+		var r SummaryLabResultResponse
+		var loaiXetNghiem, ketQua sql.NullString
+		var ngayXetNghiem sql.NullTime
+
+		if err := rows.Scan(&r.MaXetNghiem, &r.MaHoSo, &loaiXetNghiem, &ngayXetNghiem, &ketQua); err != nil {
+			return nil, err
+		}
+		r.LoaiXetNghiem = loaiXetNghiem.String
+		r.NgayXetNghiem = timePointer(ngayXetNghiem)
+		r.KetQua = ketQua.String
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// summaryUpcomingFollowUps combines follow-up dates set by doctors with
+// appointments already booked.
+func (h *MedicalRecordHandler) summaryUpcomingFollowUps(customerID string) ([]UpcomingFollowUpResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT 'TAI_KHAM' AS loai, h.maHoSo AS ma, CAST(h.ngayTaiKham AS DATETIME) AS ngay,
+		       h.maBacSi, u.hoTen
+		FROM HOSO h
+		JOIN [USER] u ON h.maBacSi = u.userID
+		WHERE h.maCustomer = @p1 AND h.ngayTaiKham >= CAST(GETDATE() AS DATE)
+		UNION ALL
+		SELECT 'LICH_KHAM', l.maLichKham, l.ngayGioKham, l.maBacSi, u.hoTen
+		FROM LICHKHAM l
+		JOIN [USER] u ON l.maBacSi = u.userID
+		WHERE l.maCustomer = @p1 AND l.trangThai IN ('SCHEDULED', 'CONFIRMED') AND l.ngayGioKham >= GETDATE()
+		ORDER BY ngay
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	followUps := []UpcomingFollowUpResponse{}
+	for rows.Next() {
+		var f UpcomingFollowUpResponse
+		if err := rows.Scan(&f.Loai, &f.Ma, &f.Ngay, &f.MaBacSi, &f.TenBacSi); err != nil {
+			return nil, err
+		}
+		followUps = append(followUps, f)
+	}
+	return followUps, rows.Err()
+}
@@ -0,0 +1,1029 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PharmacyHandler tracks medicine stock per clinic in batches (TONKHO),
+// goods receipts from suppliers and dispensing against prescriptions. Every
+// stock change is written to the BIENDONGKHO ledger.
+type PharmacyHandler struct {
+	db *sql.DB
+}
+
+func NewPharmacyHandler(db *sql.DB) *PharmacyHandler {
+	return &PharmacyHandler{db: db}
+}
+
+type SupplierRequest struct {
+	TenNhaCungCap string `json:"ten_nha_cung_cap" binding:"required"`
+	SoDienThoai   string `json:"so_dien_thoai"`
+	Email         string `json:"email"`
+	DiaChi        string `json:"dia_chi"`
+}
+
+type GoodsReceiptRequest struct {
+	MaPhongKham  string             `json:"ma_phong_kham"`
+	MaNhaCungCap string             `json:"ma_nha_cung_cap" binding:"required"`
+	GhiChu       string             `json:"ghi_chu"`
+	Items        []GoodsReceiptItem `json:"items" binding:"required,min=1,dive"`
+}
+
+type GoodsReceiptItem struct {
+	MaThuoc   string  `json:"ma_thuoc" binding:"required"`
+	SoLo      string  `json:"so_lo" binding:"required"`
+	HanSuDung string  `json:"han_su_dung" binding:"required"`
+	SoLuong   int     `json:"so_luong" binding:"required,min=1"`
+	GiaNhap   float64 `json:"gia_nhap" binding:"min=0"`
+}
+
+// stockLot is one batch of a medicine on hand at a clinic.
+type stockLot struct {
+	MaLo      int
+	SoLo      string
+	HanSuDung time.Time
+	SoLuong   int
+}
+
+type lotAllocation struct {
+	Lot     stockLot
+	SoLuong int
+}
+
+// allocateFEFO takes quantity from the lots that expire first. lots must be
+// ordered by expiry date. The returned shortfall is what could not be
+// covered.
+func allocateFEFO(lots []stockLot, quantity int) ([]lotAllocation, int) {
+	var allocations []lotAllocation
+	for _, lot := range lots {
+		if quantity == 0 {
+			break
+		}
+		if lot.SoLuong <= 0 {
+			continue
+		}
+		take := lot.SoLuong
+		if take > quantity {
+			take = quantity
+		}
+		allocations = append(allocations, lotAllocation{Lot: lot, SoLuong: take})
+		quantity -= take
+	}
+	return allocations, quantity
+}
+
+// pharmacyClinic resolves the clinic a pharmacy request acts on. Clinic
+// managers and receptionists are bound to their own clinic; operation
+// managers must name one with ma_phong_kham.
+func (h *PharmacyHandler) pharmacyClinic(c *gin.Context, requested string) (string, bool) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	var table string
+	switch userType.(string) {
+	case "CLINIC_MANAGER":
+		table = "QUANLYPHONGKHAM"
+	case "RECEPTIONIST":
+		table = "LETAN"
+	case "OPERATION_MANAGER":
+		if requested == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "ma_phong_kham is required",
+			})
+			return "", false
+		}
+		return requested, true
+	default:
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Access denied to pharmacy",
+		})
+		return "", false
+	}
+
+	var clinicID string
+	err := h.db.QueryRow("SELECT maPhongKham FROM "+table+" WHERE maUser = @p1", userID).Scan(&clinicID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You are not assigned to a clinic",
+		})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get staff clinic",
+			Error:   err.Error(),
+		})
+		return "", false
+	}
+	if requested != "" && requested != clinicID {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You can only access your own clinic's pharmacy",
+		})
+		return "", false
+	}
+	return clinicID, true
+}
+
+func (h *PharmacyHandler) GetSuppliers(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT maNhaCungCap, tenNhaCungCap, soDienThoai, email, diaChi
+		FROM NHACUNGCAP
+		ORDER BY tenNhaCungCap
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve suppliers",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	suppliers := []SupplierResponse{}
+	for rows.Next() {
+		var maNhaCungCap, tenNhaCungCap string
+		var soDienThoai, email, diaChi sql.NullString
+		if err := rows.Scan(&maNhaCungCap, &tenNhaCungCap, &soDienThoai, &email, &diaChi); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan supplier data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		suppliers = append(suppliers, SupplierResponse{
+			MaNhaCungCap:  maNhaCungCap,
+			TenNhaCungCap: tenNhaCungCap,
+			SoDienThoai:   soDienThoai.String,
+			Email:         email.String,
+			DiaChi:        diaChi.String,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Suppliers retrieved successfully",
+		Data:    suppliers,
+	})
+}
+
+func (h *PharmacyHandler) CreateSupplier(c *gin.Context) {
+	if !isManager(c, "Only managers can manage suppliers") {
+		return
+	}
+
+	var req SupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	supplierID, err := utils.GenerateSupplierID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create supplier",
+			Error:   err.Error(),
+		})
+		return
+	}
+	_, err = h.db.Exec(`
+		INSERT INTO NHACUNGCAP (maNhaCungCap, tenNhaCungCap, soDienThoai, email, diaChi)
+		VALUES (@p1, @p2, @p3, @p4, @p5)
+	`, supplierID, strings.TrimSpace(req.TenNhaCungCap), services.NullIfEmpty(req.SoDienThoai),
+		services.NullIfEmpty(req.Email), services.NullIfEmpty(req.DiaChi))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create supplier",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Supplier created successfully",
+		Data: CreatedSupplierResponse{
+			MaNhaCungCap: supplierID,
+		},
+	})
+}
+
+// GetStock lists the batches on hand at a clinic with per-medicine totals.
+func (h *PharmacyHandler) GetStock(c *gin.Context) {
+	clinicID, ok := h.pharmacyClinic(c, c.Query("ma_phong_kham"))
+	if !ok {
+		return
+	}
+
+	query := `
+		SELECT k.maLo, k.maThuoc, t.tenThuoc, t.donVi, k.soLo, k.hanSuDung, k.soLuong, k.giaNhap, k.ngayNhap
+		FROM TONKHO k
+		JOIN THUOC t ON k.maThuoc = t.maThuoc
+		WHERE k.maPhongKham = @p1 AND k.soLuong > 0
+	`
+	args := []interface{}{clinicID}
+	if maThuoc := c.Query("ma_thuoc"); maThuoc != "" {
+		query += " AND k.maThuoc = @p2"
+		args = append(args, maThuoc)
+	}
+	query += " ORDER BY t.tenThuoc, k.hanSuDung"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve stock",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	stock := []*StockResponse{}
+	byMedication := make(map[string]*StockResponse)
+	for rows.Next() {
+		var maLo, soLuong int
+		var maThuoc, tenThuoc, soLo string
+		var donVi sql.NullString
+		var hanSuDung, ngayNhap time.Time
+		var giaNhap sql.NullFloat64
+
+		if err := rows.Scan(&maLo, &maThuoc, &tenThuoc, &donVi, &soLo, &hanSuDung, &soLuong, &giaNhap, &ngayNhap); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan stock data",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		entry, exists := byMedication[maThuoc]
+		if !exists {
+			entry = &StockResponse{
+				MaThuoc:  maThuoc,
+				TenThuoc: tenThuoc,
+				DonVi:    donVi.String,
+				Lo:       []StockLotResponse{},
+			}
+			byMedication[maThuoc] = entry
+			stock = append(stock, entry)
+		}
+		entry.TongSoLuong += soLuong
+		entry.Lo = append(entry.Lo, StockLotResponse{
+			MaLo:      maLo,
+			SoLo:      soLo,
+			HanSuDung: hanSuDung,
+			SoLuong:   soLuong,
+			GiaNhap:   giaNhap.Float64,
+			NgayNhap:  ngayNhap,
+			DaHetHan:  hanSuDung.Before(today()),
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Stock retrieved successfully",
+		Data:    stock,
+	})
+}
+
+// CreateGoodsReceipt records medicines received from a supplier and adds
+// them to stock, merging into an existing batch with the same lot number.
+func (h *PharmacyHandler) CreateGoodsReceipt(c *gin.Context) {
+	if !isManager(c, "Only managers can receive goods") {
+		return
+	}
+
+	var req GoodsReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clinicID, ok := h.pharmacyClinic(c, req.MaPhongKham)
+	if !ok {
+		return
+	}
+
+	expiries := make([]time.Time, len(req.Items))
+	for i, item := range req.Items {
+		expiry, err := time.Parse("2006-01-02", item.HanSuDung)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid han_su_dung format for " + item.MaThuoc + ". Use YYYY-MM-DD",
+			})
+			return
+		}
+		if expiry.Before(today()) {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Batch " + item.SoLo + " of " + item.MaThuoc + " is already expired",
+			})
+			return
+		}
+		expiries[i] = expiry
+	}
+
+	var exists int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM NHACUNGCAP WHERE maNhaCungCap = @p1", req.MaNhaCungCap).Scan(&exists); err != nil || exists == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Unknown supplier: " + req.MaNhaCungCap,
+		})
+		return
+	}
+
+	medications := make([]PrescriptionMedication, len(req.Items))
+	for i, item := range req.Items {
+		medications[i] = PrescriptionMedication{MaThuoc: item.MaThuoc}
+	}
+	if !validateCatalogueMedications(c, h.db, medications) {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	receiptID, err := utils.GenerateGoodsReceiptID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create goods receipt",
+			Error:   err.Error(),
+		})
+		return
+	}
+	now := time.Now()
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO PHIEUNHAP (maPhieuNhap, maPhongKham, maNhaCungCap, ngayNhap, nguoiNhap, ghiChu)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6)
+	`, receiptID, clinicID, req.MaNhaCungCap, now, userID, services.NullIfEmpty(req.GhiChu))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create goods receipt",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	for i, item := range req.Items {
+		_, err = tx.Exec(`
+			INSERT INTO CHITIETPHIEUNHAP (maPhieuNhap, maThuoc, soLo, hanSuDung, soLuong, giaNhap)
+			VALUES (@p1, @p2, @p3, @p4, @p5, @p6)
+		`, receiptID, item.MaThuoc, item.SoLo, expiries[i], item.SoLuong, item.GiaNhap)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to add item to goods receipt",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		var lotID, balance int
+		err = tx.QueryRow(`
+			MERGE TONKHO WITH (HOLDLOCK) AS target
+			USING (SELECT @p1 AS maPhongKham, @p2 AS maThuoc, @p3 AS soLo) AS source
+			ON target.maPhongKham = source.maPhongKham AND target.maThuoc = source.maThuoc AND target.soLo = source.soLo
+			WHEN MATCHED THEN
+				UPDATE SET soLuong = target.soLuong + @p5
+			WHEN NOT MATCHED THEN
+				INSERT (maPhongKham, maThuoc, soLo, hanSuDung, soLuong, giaNhap, ngayNhap)
+				VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7)
+			OUTPUT inserted.maLo, inserted.soLuong;
+		`, clinicID, item.MaThuoc, item.SoLo, expiries[i], item.SoLuong, item.GiaNhap, now).Scan(&lotID, &balance)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to update stock",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if err = recordStockMovement(tx, clinicID, item.MaThuoc, lotID, "NHAP", item.SoLuong, balance, receiptID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to record stock movement",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create goods receipt",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Goods receipt created successfully",
+		Data: CreatedGoodsReceiptResponse{
+			MaPhieuNhap: receiptID,
+		},
+	})
+}
+
+func (h *PharmacyHandler) GetGoodsReceipts(c *gin.Context) {
+	clinicID, ok := h.pharmacyClinic(c, c.Query("ma_phong_kham"))
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT p.maPhieuNhap, p.maNhaCungCap, n.tenNhaCungCap, p.ngayNhap, p.nguoiNhap, p.ghiChu,
+		       (SELECT SUM(ct.soLuong * ct.giaNhap) FROM CHITIETPHIEUNHAP ct WHERE ct.maPhieuNhap = p.maPhieuNhap)
+		FROM PHIEUNHAP p
+		JOIN NHACUNGCAP n ON p.maNhaCungCap = n.maNhaCungCap
+		WHERE p.maPhongKham = @p1
+		ORDER BY p.ngayNhap DESC
+	`, clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve goods receipts",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	receipts := []GoodsReceiptResponse{}
+	for rows.Next() {
+		var maPhieuNhap, maNhaCungCap, tenNhaCungCap, nguoiNhap string
+		var ngayNhap time.Time
+		var ghiChu sql.NullString
+		var tongTien sql.NullFloat64
+		if err := rows.Scan(&maPhieuNhap, &maNhaCungCap, &tenNhaCungCap, &ngayNhap, &nguoiNhap, &ghiChu, &tongTien); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan goods receipt data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		receipts = append(receipts, GoodsReceiptResponse{
+			MaPhieuNhap:   maPhieuNhap,
+			MaNhaCungCap:  maNhaCungCap,
+			TenNhaCungCap: tenNhaCungCap,
+			NgayNhap:      ngayNhap,
+			NguoiNhap:     nguoiNhap,
+			GhiChu:        ghiChu.String,
+			TongTien:      tongTien.Float64,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Goods receipts retrieved successfully",
+		Data:    receipts,
+	})
+}
+
+func (h *PharmacyHandler) GetGoodsReceipt(c *gin.Context) {
+	clinicID, ok := h.pharmacyClinic(c, c.Query("ma_phong_kham"))
+	if !ok {
+		return
+	}
+	receiptID := c.Param("id")
+
+	var maNhaCungCap, tenNhaCungCap, nguoiNhap string
+	var ngayNhap time.Time
+	var ghiChu sql.NullString
+	err := h.db.QueryRow(`
+		SELECT p.maNhaCungCap, n.tenNhaCungCap, p.ngayNhap, p.nguoiNhap, p.ghiChu
+		FROM PHIEUNHAP p
+		JOIN NHACUNGCAP n ON p.maNhaCungCap = n.maNhaCungCap
+		WHERE p.maPhieuNhap = @p1 AND p.maPhongKham = @p2
+	`, receiptID, clinicID).Scan(&maNhaCungCap, &tenNhaCungCap, &ngayNhap, &nguoiNhap, &ghiChu)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Goods receipt not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve goods receipt",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT ct.maThuoc, t.tenThuoc, ct.soLo, ct.hanSuDung, ct.soLuong, ct.giaNhap
+		FROM CHITIETPHIEUNHAP ct
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE ct.maPhieuNhap = @p1
+		ORDER BY t.tenThuoc
+	`, receiptID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve goods receipt items",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := []GoodsReceiptItemResponse{}
+	for rows.Next() {
+		var maThuoc, tenThuoc, soLo string
+		var hanSuDung time.Time
+		var soLuong int
+		var giaNhap float64
+		if err := rows.Scan(&maThuoc, &tenThuoc, &soLo, &hanSuDung, &soLuong, &giaNhap); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan goods receipt item",
+				Error:   err.Error(),
+			})
+			return
+		}
+		items = append(items, GoodsReceiptItemResponse{
+			MaThuoc:   maThuoc,
+			TenThuoc:  tenThuoc,
+			SoLo:      soLo,
+			HanSuDung: hanSuDung,
+			SoLuong:   soLuong,
+			GiaNhap:   giaNhap,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Goods receipt retrieved successfully",
+		Data: GoodsReceiptDetailResponse{
+			MaPhieuNhap:   receiptID,
+			MaPhongKham:   clinicID,
+			MaNhaCungCap:  maNhaCungCap,
+			TenNhaCungCap: tenNhaCungCap,
+			NgayNhap:      ngayNhap,
+			NguoiNhap:     nguoiNhap,
+			GhiChu:        ghiChu.String,
+			Items:         items,
+		},
+	})
+}
+
+// DispensePrescription hands out every medicine on a prescription from the
+// clinic's stock, earliest expiry first. Dispensing is all or nothing: if
+// any medicine is short, nothing is taken and the shortages are returned.
+func (h *PharmacyHandler) DispensePrescription(c *gin.Context) {
+	userType, _ := c.Get("user_type")
+	if userType.(string) != "RECEPTIONIST" && userType.(string) != "CLINIC_MANAGER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only clinic staff can dispense prescriptions",
+		})
+		return
+	}
+
+	clinicID, ok := h.pharmacyClinic(c, "")
+	if !ok {
+		return
+	}
+	prescriptionID := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// Claim the prescription first so two counters cannot dispense it twice.
+	result, err := tx.Exec(`
+		UPDATE DONTHUOC SET trangThai = 'DISPENSED', ngayPhat = @p1, nguoiPhat = @p2, maPhongKhamPhat = @p3
+		WHERE maDonThuoc = @p4 AND trangThai = 'SIGNED'
+	`, time.Now(), userID, clinicID, prescriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to dispense prescription",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var status string
+		err := tx.QueryRow("SELECT trangThai FROM DONTHUOC WHERE maDonThuoc = @p1", prescriptionID).Scan(&status)
+		message := "Prescription must be signed before it can be dispensed"
+		switch {
+		case err == sql.ErrNoRows:
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Prescription not found",
+			})
+			return
+		case status == prescriptionDispensed:
+			message = "Prescription has already been dispensed"
+		case status == prescriptionCancelled:
+			message = "Prescription has been cancelled"
+		}
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: message,
+		})
+		return
+	}
+
+	rows, err := tx.Query("SELECT maThuoc, soLuong FROM CHITIETDONTHUOC WHERE maDonThuoc = @p1", prescriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get prescription medications",
+			Error:   err.Error(),
+		})
+		return
+	}
+	type line struct {
+		maThuoc string
+		soLuong int
+	}
+	var lines []line
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.maThuoc, &l.soLuong); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan prescription medication",
+				Error:   err.Error(),
+			})
+			return
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+
+	var shortages []StockShortageResponse
+	dispensed := []DispensedLotResponse{}
+	for _, l := range lines {
+		lots, err := lockStockLots(tx, clinicID, l.maThuoc)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to read stock",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		allocations, shortfall := allocateFEFO(lots, l.soLuong)
+		if shortfall > 0 {
+			shortages = append(shortages, StockShortageResponse{
+				MaThuoc:  l.maThuoc,
+				Can:      l.soLuong,
+				ConThieu: shortfall,
+			})
+			continue
+		}
+
+		for _, a := range allocations {
+			balance := a.Lot.SoLuong - a.SoLuong
+			if _, err := tx.Exec("UPDATE TONKHO SET soLuong = @p1 WHERE maLo = @p2", balance, a.Lot.MaLo); err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success: false,
+					Message: "Failed to update stock",
+					Error:   err.Error(),
+				})
+				return
+			}
+			if err := recordStockMovement(tx, clinicID, l.maThuoc, a.Lot.MaLo, "XUAT", -a.SoLuong, balance, prescriptionID, userID); err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success: false,
+					Message: "Failed to record stock movement",
+					Error:   err.Error(),
+				})
+				return
+			}
+			dispensed = append(dispensed, DispensedLotResponse{
+				MaThuoc:   l.maThuoc,
+				SoLo:      a.Lot.SoLo,
+				HanSuDung: a.Lot.HanSuDung,
+				SoLuong:   a.SoLuong,
+			})
+		}
+	}
+
+	if len(shortages) > 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Insufficient stock to dispense prescription",
+			Data:    shortages,
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to dispense prescription",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription dispensed successfully",
+		Data:    dispensed,
+	})
+}
+
+// GetAlerts reports medicines below the low-stock threshold (nguong, default
+// 10 units) and batches expiring within so_ngay days (default 90).
+func (h *PharmacyHandler) GetAlerts(c *gin.Context) {
+	if !isManager(c, "Only managers can view pharmacy alerts") {
+		return
+	}
+	clinicID, ok := h.pharmacyClinic(c, c.Query("ma_phong_kham"))
+	if !ok {
+		return
+	}
+
+	threshold, err := strconv.Atoi(c.DefaultQuery("nguong", "10"))
+	if err != nil || threshold < 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "nguong must be a non-negative integer",
+		})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("so_ngay", "90"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "so_ngay must be a non-negative integer",
+		})
+		return
+	}
+
+	lowStock, err := h.queryLowStock(`
+		SELECT t.maThuoc, t.tenThuoc, ISNULL(SUM(k.soLuong), 0) AS tonKho
+		FROM THUOC t
+		LEFT JOIN TONKHO k ON k.maThuoc = t.maThuoc AND k.maPhongKham = @p1
+		                  AND k.hanSuDung >= CAST(GETDATE() AS DATE)
+		WHERE t.trangThai = 'ACTIVE'
+		GROUP BY t.maThuoc, t.tenThuoc
+		HAVING ISNULL(SUM(k.soLuong), 0) < @p2
+		ORDER BY tonKho, t.tenThuoc
+	`, clinicID, threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve low-stock alerts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	expiring, err := h.queryStockBatches(`
+		SELECT k.maThuoc, t.tenThuoc, k.soLo, k.hanSuDung, k.soLuong
+		FROM TONKHO k
+		JOIN THUOC t ON k.maThuoc = t.maThuoc
+		WHERE k.maPhongKham = @p1 AND k.soLuong > 0
+		  AND k.hanSuDung >= CAST(GETDATE() AS DATE)
+		  AND k.hanSuDung <= DATEADD(day, @p2, CAST(GETDATE() AS DATE))
+		ORDER BY k.hanSuDung
+	`, clinicID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve near-expiry alerts",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	expired, err := h.queryStockBatches(`
+		SELECT k.maThuoc, t.tenThuoc, k.soLo, k.hanSuDung, k.soLuong
+		FROM TONKHO k
+		JOIN THUOC t ON k.maThuoc = t.maThuoc
+		WHERE k.maPhongKham = @p1 AND k.soLuong > 0 AND k.hanSuDung < CAST(GETDATE() AS DATE)
+		ORDER BY k.hanSuDung
+	`, clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve expired stock",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Pharmacy alerts retrieved successfully",
+		Data: PharmacyAlertsResponse{
+			TonThap:   lowStock,
+			SapHetHan: expiring,
+			DaHetHan:  expired,
+		},
+	})
+}
+
+// GetLedger lists stock movements, newest first.
+func (h *PharmacyHandler) GetLedger(c *gin.Context) {
+	if !isManager(c, "Only managers can view the stock ledger") {
+		return
+	}
+	clinicID, ok := h.pharmacyClinic(c, c.Query("ma_phong_kham"))
+	if !ok {
+		return
+	}
+
+	query := `
+		SELECT b.thoiGian, b.maThuoc, t.tenThuoc, k.soLo, b.loai, b.soLuong, b.soLuongSau,
+		       b.thamChieu, b.nguoiThucHien
+		FROM BIENDONGKHO b
+		JOIN THUOC t ON b.maThuoc = t.maThuoc
+		JOIN TONKHO k ON b.maLo = k.maLo
+		WHERE b.maPhongKham = @p1
+	`
+	args := []interface{}{clinicID}
+	if maThuoc := c.Query("ma_thuoc"); maThuoc != "" {
+		args = append(args, maThuoc)
+		query += " AND b.maThuoc = @p" + strconv.Itoa(len(args))
+	}
+	if from := c.Query("tu_ngay"); from != "" {
+		args = append(args, from)
+		query += " AND b.thoiGian >= @p" + strconv.Itoa(len(args))
+	}
+	if to := c.Query("den_ngay"); to != "" {
+		args = append(args, to)
+		query += " AND b.thoiGian < DATEADD(day, 1, CAST(@p" + strconv.Itoa(len(args)) + " AS DATE))"
+	}
+	query += " ORDER BY b.thoiGian DESC, b.maBienDong DESC"
+
+	movements, err := h.queryStockMovements(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve stock ledger",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Stock ledger retrieved successfully",
+		Data:    movements,
+	})
+}
+
+// queryLowStock scans a report of medicines and their usable stock.
+func (h *PharmacyHandler) queryLowStock(query string, args ...interface{}) ([]LowStockResponse, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []LowStockResponse{}
+	for rows.Next() {
+		var item LowStockResponse
+		if err := rows.Scan(&item.MaThuoc, &item.TenThuoc, &item.TonKho); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, rows.Err()
+}
+
+// queryStockBatches scans a report of batches: medicine, name, lot number,
+// expiry and quantity.
+func (h *PharmacyHandler) queryStockBatches(query string, args ...interface{}) ([]StockBatchResponse, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []StockBatchResponse{}
+	for rows.Next() {
+		var item StockBatchResponse
+		if err := rows.Scan(&item.MaThuoc, &item.TenThuoc, &item.SoLo, &item.HanSuDung, &item.SoLuong); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, rows.Err()
+}
+
+// queryStockMovements scans ledger rows as selected by GetLedger.
+func (h *PharmacyHandler) queryStockMovements(query string, args ...interface{}) ([]StockMovementResponse, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []StockMovementResponse{}
+	for rows.Next() {
+		var item StockMovementResponse
+		var thamChieu sql.NullString
+		if err := rows.Scan(&item.ThoiGian, &item.MaThuoc, &item.TenThuoc, &item.SoLo, &item.Loai,
+			&item.SoLuong, &item.SoLuongSau, &thamChieu, &item.NguoiThucHien); err != nil {
+			return nil, err
+		}
+		if thamChieu.Valid {
+			item.ThamChieu = &thamChieu.String
+		}
+		result = append(result, item)
+	}
+	return result, rows.Err()
+}
+
+// lockStockLots reads unexpired batches of a medicine ordered for FEFO and
+// locks them until the transaction ends.
+func lockStockLots(tx *sql.Tx, clinicID, medicationID string) ([]stockLot, error) {
+	rows, err := tx.Query(`
+		SELECT maLo, soLo, hanSuDung, soLuong
+		FROM TONKHO WITH (UPDLOCK, ROWLOCK)
+		WHERE maPhongKham = @p1 AND maThuoc = @p2 AND soLuong > 0
+		  AND hanSuDung >= CAST(GETDATE() AS DATE)
+		ORDER BY hanSuDung, maLo
+	`, clinicID, medicationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []stockLot
+	for rows.Next() {
+		var lot stockLot
+		if err := rows.Scan(&lot.MaLo, &lot.SoLo, &lot.HanSuDung, &lot.SoLuong); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+func recordStockMovement(tx *sql.Tx, clinicID, medicationID string, lotID int, kind string, quantity, balance int, reference string, userID interface{}) error {
+	_, err := tx.Exec(`
+		INSERT INTO BIENDONGKHO (maPhongKham, maThuoc, maLo, loai, soLuong, soLuongSau, thamChieu, nguoiThucHien, thoiGian)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9)
+	`, clinicID, medicationID, lotID, kind, quantity, balance, reference, userID, time.Now())
+	return err
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
@@ -3,114 +3,96 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
-	"time"
+	"strings"
 
 	"clinic-management/internal/models"
-	"clinic-management/internal/utils"
+	"clinic-management/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PrescriptionHandler struct {
-	db *sql.DB
+	db            *sql.DB
+	prescriptions *services.PrescriptionService
 }
 
 func NewPrescriptionHandler(db *sql.DB) *PrescriptionHandler {
-	return &PrescriptionHandler{db: db}
+	return &PrescriptionHandler{
+		db: db,
+		prescriptions: services.NewPrescriptionService(
+			services.NewSQLPrescriptionRepository(db),
+			services.NewSQLMedicalRecordRepository(db),
+		),
+	}
 }
 
 type PrescriptionRequest struct {
 	MaHoSo      string                   `json:"ma_ho_so" binding:"required"`
 	Medications []PrescriptionMedication `json:"medications" binding:"required,min=1"`
 	GhiChu      string                   `json:"ghi_chu"`
+	LyDoBoQua   string                   `json:"ly_do_bo_qua"`
 }
 
+// PrescriptionMedication is one line of a prescription. Either LieuDung or
+// both SoLuong and CachDung must be given; see applyDosageInstructions.
 type PrescriptionMedication struct {
-	MaThuoc  string `json:"ma_thuoc" binding:"required"`
-	TenThuoc string `json:"ten_thuoc" binding:"required"`
-	SoLuong  int    `json:"so_luong" binding:"required,min=1"`
-	CachDung string `json:"cach_dung" binding:"required"`
-	GhiChu   string `json:"ghi_chu"`
+	MaThuoc  string             `json:"ma_thuoc" binding:"required"`
+	TenThuoc string             `json:"ten_thuoc"`
+	SoLuong  int                `json:"so_luong" binding:"min=0"`
+	CachDung string             `json:"cach_dung"`
+	LieuDung *DosageInstruction `json:"lieu_dung"`
+	GhiChu   string             `json:"ghi_chu"`
 }
 
-type PrescriptionResponse struct {
-	MaDonThuoc  string                   `json:"ma_don_thuoc"`
-	MaHoSo      string                   `json:"ma_ho_so"`
-	NgayKeDon   time.Time                `json:"ngay_ke_don"`
-	GhiChu      string                   `json:"ghi_chu"`
-	Medications []PrescriptionMedication `json:"medications"`
+var prescriptionList = listSpec{
+	sorts:       services.PrescriptionSorts,
+	defaultSort: "-ngay_ke_don",
+	idField:     "ma_don_thuoc",
 }
 
+// GetPrescriptions lists prescriptions one page at a time. Filters are
+// status, ma_ho_so, customer_id, doctor_id and date_from/date_to on the
+// prescription date.
 func (h *PrescriptionHandler) GetPrescriptions(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-	// status := c.Query("status")
-	maHoSo := c.Query("ma_ho_so")
-
-	var query string
-	var args []interface{}
-
-	// Base query with joins
-	baseQuery := `
-		SELECT DISTINCT dt.maDonThuoc, dt.maHoSo, dt.ngayHeHan as ngayKeDon, dt.ghiChu,
-		       h.maCustomer, h.maBacSi, 
-		       uc.hoTen as tenKhachHang, ud.hoTen as tenBacSi
-		FROM DONTHUOC dt
-		JOIN HOSO h ON dt.maHoSo = h.maHoSo
-		JOIN [USER] uc ON h.maCustomer = uc.userID  
-		JOIN [USER] ud ON h.maBacSi = ud.userID
-		WHERE 1=1
-	`
-
-	// Filter by user role
-	switch userType.(string) {
-	case "CUSTOMER":
-		query = baseQuery + " AND h.maCustomer = ?"
-		args = append(args, userID)
-	case "DOCTOR":
-		query = baseQuery + " AND h.maBacSi = ?"
-		args = append(args, userID)
-	default:
-		query = baseQuery
+	params, ok := prescriptionList.parse(c)
+	if !ok {
+		return
 	}
-
-	// Additional filters
-	if maHoSo != "" {
-		query += " AND dt.maHoSo = ?"
-		args = append(args, maHoSo)
+	dates, ok := parseDateRange(c)
+	if !ok {
+		return
 	}
 
-	query += " ORDER BY dt.ngayHeHan DESC"
-
-	rows, err := h.db.Query(query, args...)
+	summaries, total, err := h.prescriptions.List(viewerOf(c), services.PrescriptionFilter{
+		RecordID:   c.Query("ma_ho_so"),
+		Status:     c.Query("status"),
+		CustomerID: c.Query("customer_id"),
+		DoctorID:   c.Query("doctor_id"),
+		Dates:      dates,
+	}, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to retrieve prescriptions",
-			Error:   err.Error(),
-		})
+		serviceFailed(c, err, "Failed to retrieve prescriptions")
 		return
 	}
-	defer rows.Close()
-
-	var prescriptions []map[string]interface{}
-	for rows.Next() {
-		var maDonThuoc, maHoSo, ghiChu, maCustomer, maBacSi, tenKhachHang, tenBacSi sql.NullString
-		var ngayKeDon sql.NullTime
 
-		err := rows.Scan(&maDonThuoc, &maHoSo, &ngayKeDon, &ghiChu,
-			&maCustomer, &maBacSi, &tenKhachHang, &tenBacSi)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to scan prescription data",
-				Error:   err.Error(),
-			})
-			return
+	var prescriptions []PrescriptionResponse
+	for _, summary := range summaries {
+		p := PrescriptionResponse{
+			MaDonThuoc:   summary.MaDonThuoc,
+			MaHoSo:       summary.MaHoSo,
+			NgayKeDon:    summary.NgayKeDon,
+			TrangThai:    summary.TrangThai,
+			MaXacThuc:    summary.MaXacThuc,
+			MaCustomer:   summary.MaCustomer,
+			MaBacSi:      summary.MaBacSi,
+			TenKhachHang: summary.TenKhachHang,
+			TenBacSi:     summary.TenBacSi,
+		}
+		if summary.GhiChu != nil {
+			p.GhiChu = *summary.GhiChu
 		}
 
-		// Get medications for this prescription
-		medications, err := h.getPrescriptionMedications(maDonThuoc.String)
+		p.Medications, err = h.getPrescriptionMedications(p.MaDonThuoc)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -119,70 +101,44 @@ func (h *PrescriptionHandler) GetPrescriptions(c *gin.Context) {
 			})
 			return
 		}
-
-		prescription := map[string]interface{}{
-			"ma_don_thuoc":   maDonThuoc.String,
-			"ma_ho_so":       maHoSo.String,
-			"ngay_ke_don":    ngayKeDon.Time,
-			"ghi_chu":        ghiChu.String,
-			"ma_customer":    maCustomer.String,
-			"ma_bac_si":      maBacSi.String,
-			"ten_khach_hang": tenKhachHang.String,
-			"ten_bac_si":     tenBacSi.String,
-			"medications":    medications,
-		}
-
-		prescriptions = append(prescriptions, prescription)
+		prescriptions = append(prescriptions, p)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Prescriptions retrieved successfully",
-		Data:    prescriptions,
-	})
+	respondList(c, prescriptionList, "Prescriptions retrieved successfully", prescriptions, params, total)
 }
 
-func (h *PrescriptionHandler) getPrescriptionMedications(maDonThuoc string) ([]map[string]interface{}, error) {
-	query := `
-		SELECT ct.maThuoc, ct.soLuong, ct.cacDung, ct.ghiChu,
-		       t.tenThuoc, t.gia, t.congDung, t.lieuLuong
-		FROM CHITIETDONTHUOC ct
-		JOIN THUOC t ON ct.maThuoc = t.maThuoc
-		WHERE ct.maDonThuoc = ?
-	`
-
-	rows, err := h.db.Query(query, maDonThuoc)
+// getPrescriptionMedications returns the lines of a prescription as
+// response items.
+func (h *PrescriptionHandler) getPrescriptionMedications(maDonThuoc string) ([]PrescriptionItemResponse, error) {
+	medicines, err := h.prescriptions.Medicines(maDonThuoc)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var medications []map[string]interface{}
-	for rows.Next() {
-		var maThuoc, tenThuoc, cacDung, ghiChu, congDung, lieuLuong sql.NullString
-		var soLuong sql.NullInt32
-		var gia sql.NullFloat64
-
-		err := rows.Scan(&maThuoc, &soLuong, &cacDung, &ghiChu,
-			&tenThuoc, &gia, &congDung, &lieuLuong)
-		if err != nil {
-			return nil, err
+	medications := []PrescriptionItemResponse{}
+	for _, m := range medicines {
+		medication := PrescriptionItemResponse{
+			MaThuoc:   m.MaThuoc,
+			TenThuoc:  m.TenThuoc,
+			SoLuong:   m.SoLuong,
+			CachDung:  m.CachDung,
+			GhiChu:    m.GhiChu,
+			Gia:       m.Gia,
+			CongDung:  m.CongDung,
+			LieuLuong: m.LieuLuong,
 		}
-
-		medication := map[string]interface{}{
-			"ma_thuoc":   maThuoc.String,
-			"ten_thuoc":  tenThuoc.String,
-			"so_luong":   soLuong.Int32,
-			"cach_dung":  cacDung.String,
-			"ghi_chu":    ghiChu.String,
-			"gia":        gia.Float64,
-			"cong_dung":  congDung.String,
-			"lieu_luong": lieuLuong.String,
+		if m.Dose != nil {
+			medication.LieuDung = &DosageInstruction{
+				Lieu:         m.Dose.Lieu,
+				DonVi:        m.Dose.DonVi,
+				DuongDung:    m.Dose.DuongDung,
+				SoLanMoiNgay: m.Dose.SoLanMoiNgay,
+				ThoiDiem:     m.Dose.ThoiDiem,
+				SoNgay:       m.Dose.SoNgay,
+			}
 		}
-
 		medications = append(medications, medication)
 	}
-
 	return medications, nil
 }
 
@@ -192,33 +148,38 @@ func (h *PrescriptionHandler) GetPrescription(c *gin.Context) {
 	userType, _ := c.Get("user_type")
 
 	query := `
-		SELECT dt.maDonThuoc, dt.maHoSo, dt.ngayHeHan, dt.ghiChu,
+		SELECT dt.maDonThuoc, dt.maHoSo, dt.ngayKeDon, dt.ghiChu,
+		       dt.trangThai, dt.maXacThuc, dt.ngayKy, dt.ngayHuy, dt.nguoiHuy, dt.lyDoHuy,
+		       dt.ngayPhat, dt.nguoiPhat, dt.maPhongKhamPhat,
 		       h.maCustomer, h.maBacSi,
 		       uc.hoTen as tenKhachHang, ud.hoTen as tenBacSi
 		FROM DONTHUOC dt
 		JOIN HOSO h ON dt.maHoSo = h.maHoSo
 		JOIN [USER] uc ON h.maCustomer = uc.userID
 		JOIN [USER] ud ON h.maBacSi = ud.userID
-		WHERE dt.maDonThuoc = ?
+		WHERE dt.maDonThuoc = @p1
 	`
 	args := []interface{}{prescriptionID}
 
 	// Add role-based filtering
 	if userType.(string) == "CUSTOMER" {
-		query += " AND h.maCustomer = ?"
+		query += " AND h.maCustomer = @p2 AND dt.trangThai <> 'DRAFT'"
 		args = append(args, userID)
 	} else if userType.(string) == "DOCTOR" {
-		query += " AND h.maBacSi = ?"
+		query += " AND h.maBacSi = @p2"
 		args = append(args, userID)
 	}
 
-	var prescription map[string]interface{} = make(map[string]interface{})
-	var maDonThuoc, maHoSo, ghiChu, maCustomer, maBacSi, tenKhachHang, tenBacSi sql.NullString
-	var ngayKeDon sql.NullTime
+	var p PrescriptionDetailResponse
+	var ghiChu, tenKhachHang, tenBacSi sql.NullString
+	var trangThai, maXacThuc, nguoiHuy, lyDoHuy, nguoiPhat, maPhongKhamPhat sql.NullString
+	var ngayKeDon, ngayKy, ngayHuy, ngayPhat sql.NullTime
 
 	err := h.db.QueryRow(query, args...).Scan(
-		&maDonThuoc, &maHoSo, &ngayKeDon, &ghiChu,
-		&maCustomer, &maBacSi, &tenKhachHang, &tenBacSi,
+		&p.MaDonThuoc, &p.MaHoSo, &ngayKeDon, &ghiChu,
+		&trangThai, &maXacThuc, &ngayKy, &ngayHuy, &nguoiHuy, &lyDoHuy,
+		&ngayPhat, &nguoiPhat, &maPhongKhamPhat,
+		&p.MaCustomer, &p.MaBacSi, &tenKhachHang, &tenBacSi,
 	)
 
 	if err != nil {
@@ -237,8 +198,22 @@ func (h *PrescriptionHandler) GetPrescription(c *gin.Context) {
 		return
 	}
 
+	p.NgayKeDon = timePointer(ngayKeDon)
+	p.GhiChu = ghiChu.String
+	p.TrangThai = trangThai.String
+	p.MaXacThuc = maXacThuc.String
+	p.TenKhachHang = tenKhachHang.String
+	p.TenBacSi = tenBacSi.String
+	p.NgayKy = timePointer(ngayKy)
+	p.NgayHuy = timePointer(ngayHuy)
+	p.NguoiHuy = nguoiHuy.String
+	p.LyDoHuy = lyDoHuy.String
+	p.NgayPhat = timePointer(ngayPhat)
+	p.NguoiPhat = nguoiPhat.String
+	p.MaPhongKhamPhat = maPhongKhamPhat.String
+
 	// Get medications
-	medications, err := h.getPrescriptionMedications(maDonThuoc.String)
+	p.Medications, err = h.getPrescriptionMedications(p.MaDonThuoc)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -248,182 +223,93 @@ func (h *PrescriptionHandler) GetPrescription(c *gin.Context) {
 		return
 	}
 
-	prescription["ma_don_thuoc"] = maDonThuoc.String
-	prescription["ma_ho_so"] = maHoSo.String
-	prescription["ngay_ke_don"] = ngayKeDon.Time
-	prescription["ghi_chu"] = ghiChu.String
-	prescription["ma_customer"] = maCustomer.String
-	prescription["ma_bac_si"] = maBacSi.String
-	prescription["ten_khach_hang"] = tenKhachHang.String
-	prescription["ten_bac_si"] = tenBacSi.String
-	prescription["medications"] = medications
-
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Prescription retrieved successfully",
-		Data:    prescription,
-	})
-}
-
-func (h *PrescriptionHandler) CreatePrescription(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-
-	// Only doctors can create prescriptions
-	if userType.(string) != "DOCTOR" {
-		c.JSON(http.StatusForbidden, models.APIResponse{
-			Success: false,
-			Message: "Only doctors can create prescriptions",
-		})
-		return
-	}
-
-	var req PrescriptionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+	p.DiUng, err = getPatientAllergies(h.db, p.MaCustomer, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Invalid request format",
+			Message: "Failed to retrieve patient allergies",
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	// Verify the medical record belongs to this doctor
-	var doctorID string
-	err := h.db.QueryRow("SELECT maBacSi FROM HOSO WHERE maHoSo = ?", req.MaHoSo).Scan(&doctorID)
+	p.CanhBao, err = h.getOverriddenWarnings(p.MaDonThuoc)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, models.APIResponse{
-				Success: false,
-				Message: "Medical record not found",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to verify medical record",
-				Error:   err.Error(),
-			})
-		}
-		return
-	}
-
-	if doctorID != userID.(string) {
-		c.JSON(http.StatusForbidden, models.APIResponse{
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "You can only create prescriptions for your own patients",
+			Message: "Failed to retrieve prescription warnings",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	// Start transaction
-	tx, err := h.db.Begin()
+	p.CapPhat, err = h.getDispensedItems(p.MaDonThuoc)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Failed to begin transaction",
+			Message: "Failed to retrieve dispensing details",
 			Error:   err.Error(),
 		})
 		return
 	}
-	defer tx.Rollback()
-
-	// Generate prescription ID
-	prescriptionID := utils.GeneratePrescriptionID()
 
-	// Insert prescription
-	_, err = tx.Exec(`
-		INSERT INTO DONTHUOC (maDonThuoc, maHoSo, ngayHeHan, ghiChu)
-		VALUES (?, ?, DATEADD(month, 3, GETDATE()), ?)
-	`, prescriptionID, req.MaHoSo, req.GhiChu)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription retrieved successfully",
+		Data:    p,
+	})
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+func (h *PrescriptionHandler) CreatePrescription(c *gin.Context) {
+	var req PrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Message: "Failed to create prescription",
+			Message: "Invalid request format",
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	// Insert medication details
-	for _, med := range req.Medications {
-		// Check if medication exists, if not create it
-		var exists int
-		err = tx.QueryRow("SELECT COUNT(*) FROM THUOC WHERE maThuoc = ?", med.MaThuoc).Scan(&exists)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to check medication",
-				Error:   err.Error(),
-			})
-			return
-		}
+	viewer := viewerOf(c)
+	record, err := h.prescriptions.RecordToPrescribe(viewer, req.MaHoSo)
+	if err != nil {
+		serviceFailed(c, err, "Failed to verify medical record")
+		return
+	}
 
-		if exists == 0 {
-			// Create medication if it doesn't exist
-			_, err = tx.Exec(`
-				INSERT INTO THUOC (maThuoc, tenThuoc, soLuong, gia, congDung, lieuLuong)
-				VALUES (?, ?, 100, 0, '', '')
-			`, med.MaThuoc, med.TenThuoc)
-
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, models.APIResponse{
-					Success: false,
-					Message: "Failed to create medication",
-					Error:   err.Error(),
-				})
-				return
-			}
-		}
+	if !rejectDuplicateMedications(c, req.Medications) || !applyDosageInstructions(c, req.Medications) {
+		return
+	}
 
-		// Insert prescription detail
-		_, err = tx.Exec(`
-			INSERT INTO CHITIETDONTHUOC (maDonThuoc, maThuoc, soLuong, cacDung, ghiChu)
-			VALUES (?, ?, ?, ?, ?)
-		`, prescriptionID, med.MaThuoc, med.SoLuong, med.CachDung, med.GhiChu)
+	if !validateCatalogueMedications(c, h.db, req.Medications) {
+		return
+	}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to add medication to prescription",
-				Error:   err.Error(),
-			})
-			return
-		}
+	warnings, ok := h.checkPrescriptionSafety(c, record.MaCustomer, "", req)
+	if !ok {
+		return
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to create prescription",
-			Error:   err.Error(),
-		})
+	prescriptionID, err := h.prescriptions.Create(viewer, draftPrescription(req, warnings))
+	if err != nil {
+		serviceFailed(c, err, "Failed to create prescription")
 		return
 	}
 
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Message: "Prescription created successfully",
-		Data: gin.H{
-			"ma_don_thuoc": prescriptionID,
+		Data: CreatedPrescriptionResponse{
+			MaDonThuoc: prescriptionID,
+			TrangThai:  prescriptionDraft,
+			CanhBao:    warnings,
 		},
 	})
 }
 
 func (h *PrescriptionHandler) UpdatePrescription(c *gin.Context) {
 	prescriptionID := c.Param("id")
-	userID, _ := c.Get("user_id")
-	userType, _ := c.Get("user_type")
-
-	// Only doctors can update prescriptions
-	if userType.(string) != "DOCTOR" {
-		c.JSON(http.StatusForbidden, models.APIResponse{
-			Success: false,
-			Message: "Only doctors can update prescriptions",
-		})
-		return
-	}
 
 	var req PrescriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -435,197 +321,88 @@ func (h *PrescriptionHandler) UpdatePrescription(c *gin.Context) {
 		return
 	}
 
-	// Verify prescription belongs to this doctor
-	var doctorID string
-	err := h.db.QueryRow(`
-		SELECT h.maBacSi FROM DONTHUOC dt
-		JOIN HOSO h ON dt.maHoSo = h.maHoSo
-		WHERE dt.maDonThuoc = ?
-	`, prescriptionID).Scan(&doctorID)
-
+	viewer := viewerOf(c)
+	prescription, err := h.prescriptions.Editable(viewer, prescriptionID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, models.APIResponse{
-				Success: false,
-				Message: "Prescription not found",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to verify prescription",
-				Error:   err.Error(),
-			})
-		}
+		serviceFailed(c, err, "Failed to verify prescription")
 		return
 	}
 
-	if doctorID != userID.(string) {
-		c.JSON(http.StatusForbidden, models.APIResponse{
-			Success: false,
-			Message: "You can only update your own prescriptions",
-		})
+	if !rejectDuplicateMedications(c, req.Medications) || !applyDosageInstructions(c, req.Medications) {
 		return
 	}
 
-	// Start transaction
-	tx, err := h.db.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to begin transaction",
-			Error:   err.Error(),
-		})
+	if !validateCatalogueMedications(c, h.db, req.Medications) {
 		return
 	}
-	defer tx.Rollback()
 
-	// Update prescription
-	_, err = tx.Exec("UPDATE DONTHUOC SET ghiChu = ? WHERE maDonThuoc = ?", req.GhiChu, prescriptionID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to update prescription",
-			Error:   err.Error(),
-		})
-		return
-	}
-
-	// Delete existing medication details
-	_, err = tx.Exec("DELETE FROM CHITIETDONTHUOC WHERE maDonThuoc = ?", prescriptionID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to update prescription medications",
-			Error:   err.Error(),
-		})
+	warnings, ok := h.checkPrescriptionSafety(c, prescription.MaCustomer, prescriptionID, req)
+	if !ok {
 		return
 	}
 
-	// Insert updated medication details
-	for _, med := range req.Medications {
-		// Check if medication exists, if not create it
-		var exists int
-		err = tx.QueryRow("SELECT COUNT(*) FROM THUOC WHERE maThuoc = ?", med.MaThuoc).Scan(&exists)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to check medication",
-				Error:   err.Error(),
-			})
-			return
-		}
-
-		if exists == 0 {
-			_, err = tx.Exec(`
-				INSERT INTO THUOC (maThuoc, tenThuoc, soLuong, gia, congDung, lieuLuong)
-				VALUES (?, ?, 100, 0, '', '')
-			`, med.MaThuoc, med.TenThuoc)
-
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, models.APIResponse{
-					Success: false,
-					Message: "Failed to create medication",
-					Error:   err.Error(),
-				})
-				return
-			}
-		}
-
-		// Insert prescription detail
-		_, err = tx.Exec(`
-			INSERT INTO CHITIETDONTHUOC (maDonThuoc, maThuoc, soLuong, cacDung, ghiChu)
-			VALUES (?, ?, ?, ?, ?)
-		`, prescriptionID, med.MaThuoc, med.SoLuong, med.CachDung, med.GhiChu)
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to update medication in prescription",
-				Error:   err.Error(),
-			})
-			return
-		}
-	}
-
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to update prescription",
-			Error:   err.Error(),
-		})
+	if err := h.prescriptions.Update(viewer, prescriptionID, draftPrescription(req, warnings)); err != nil {
+		serviceFailed(c, err, "Failed to update prescription")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Prescription updated successfully",
+		Data: PrescriptionWarningsResponse{
+			CanhBao: warnings,
+		},
 	})
 }
 
-func (h *PrescriptionHandler) GetMedications(c *gin.Context) {
-	query := c.Query("q") // search query
-
-	var sqlQuery string
-	var args []interface{}
-
-	if query != "" {
-		sqlQuery = `
-			SELECT maThuoc, tenThuoc, gia, congDung, lieuLuong
-			FROM THUOC
-			WHERE tenThuoc LIKE ? OR maThuoc LIKE ?
-			ORDER BY tenThuoc
-		`
-		searchTerm := "%" + query + "%"
-		args = []interface{}{searchTerm, searchTerm}
-	} else {
-		sqlQuery = `
-			SELECT maThuoc, tenThuoc, gia, congDung, lieuLuong
-			FROM THUOC
-			ORDER BY tenThuoc
-		`
+// rejectDuplicateMedications writes a 400 response and returns false when a
+// medicine appears on more than one line; a prescription holds one line
+// per medicine.
+func rejectDuplicateMedications(c *gin.Context, medications []PrescriptionMedication) bool {
+	seen := make(map[string]bool)
+	var duplicates []string
+	for _, med := range medications {
+		if seen[med.MaThuoc] {
+			duplicates = append(duplicates, med.MaThuoc)
+		}
+		seen[med.MaThuoc] = true
 	}
 
-	rows, err := h.db.Query(sqlQuery, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+	if len(duplicates) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve medications",
-			Error:   err.Error(),
+			Message: "Each medication may appear only once in a prescription",
+			Error:   strings.Join(duplicates, ", "),
 		})
-		return
+		return false
 	}
-	defer rows.Close()
-
-	var medications []map[string]interface{}
-	for rows.Next() {
-		var maThuoc, tenThuoc, congDung, lieuLuong sql.NullString
-		var gia sql.NullFloat64
-
-		err := rows.Scan(&maThuoc, &tenThuoc, &gia, &congDung, &lieuLuong)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.APIResponse{
-				Success: false,
-				Message: "Failed to scan medication data",
-				Error:   err.Error(),
-			})
-			return
-		}
-
-		medication := map[string]interface{}{
-			"ma_thuoc":   maThuoc.String,
-			"ten_thuoc":  tenThuoc.String,
-			"gia":        gia.Float64,
-			"cong_dung":  congDung.String,
-			"lieu_luong": lieuLuong.String,
-		}
+	return true
+}
 
-		medications = append(medications, medication)
+// draftPrescription converts a checked request and the warnings the doctor
+// overrode into what the prescription service stores.
+func draftPrescription(req PrescriptionRequest, warnings []PrescriptionWarning) services.DraftPrescription {
+	draft := services.DraftPrescription{
+		MaHoSo:    req.MaHoSo,
+		GhiChu:    req.GhiChu,
+		LyDoBoQua: req.LyDoBoQua,
 	}
-
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Medications retrieved successfully",
-		Data:    medications,
-	})
+	for _, med := range req.Medications {
+		draft.Lines = append(draft.Lines, services.PrescriptionLine{
+			MaThuoc:  med.MaThuoc,
+			SoLuong:  med.SoLuong,
+			CachDung: med.CachDung,
+			GhiChu:   med.GhiChu,
+			Dose:     med.LieuDung.dose(),
+		})
+	}
+	for _, w := range warnings {
+		draft.Warnings = append(draft.Warnings, services.OverriddenWarning{
+			Loai:    w.Loai,
+			MucDo:   w.MucDo,
+			MaThuoc: w.MaThuoc,
+			NoiDung: w.NoiDung,
+		})
+	}
+	return draft
 }
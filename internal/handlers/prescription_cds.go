@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"clinic-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckPrescription runs the safety checks without saving anything so the
+// doctor can review warnings while writing the prescription.
+func (h *PrescriptionHandler) CheckPrescription(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	if userType.(string) != "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only doctors can check prescriptions",
+		})
+		return
+	}
+
+	var req PrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !applyDosageInstructions(c, req.Medications) {
+		return
+	}
+
+	var doctorID, customerID string
+	err := h.db.QueryRow("SELECT maBacSi, maCustomer FROM HOSO WHERE maHoSo = @p1", req.MaHoSo).Scan(&doctorID, &customerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Medical record not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to verify medical record",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	if doctorID != userID.(string) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You can only check prescriptions for your own patients",
+		})
+		return
+	}
+
+	if !validateCatalogueMedications(c, h.db, req.Medications) {
+		return
+	}
+
+	warnings, err := h.runPrescriptionChecks(customerID, c.Query("ma_don_thuoc"), req.Medications)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check prescription",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription checked successfully",
+		Data: PrescriptionCheckResponse{
+			CanhBao:      warnings,
+			CanLyDoBoQua: len(warnings) > 0,
+		},
+	})
+}
+
+// checkPrescriptionSafety runs the checks and, when there are warnings that
+// the doctor has not acknowledged with ly_do_bo_qua, responds with 409 and
+// the list of warnings.
+func (h *PrescriptionHandler) checkPrescriptionSafety(c *gin.Context, customerID, prescriptionID string, req PrescriptionRequest) ([]PrescriptionWarning, bool) {
+	warnings, err := h.runPrescriptionChecks(customerID, prescriptionID, req.Medications)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to check prescription",
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	if len(warnings) > 0 && strings.TrimSpace(req.LyDoBoQua) == "" {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Prescription has safety warnings; provide ly_do_bo_qua to proceed",
+			Data: PrescriptionWarningsResponse{
+				CanhBao: warnings,
+			},
+		})
+		return nil, false
+	}
+	return warnings, true
+}
+
+func (h *PrescriptionHandler) runPrescriptionChecks(customerID, prescriptionID string, medications []PrescriptionMedication) ([]PrescriptionWarning, error) {
+	current, err := loadCheckMedications(h.db, medications)
+	if err != nil {
+		return nil, err
+	}
+	active, err := loadActiveMedications(h.db, customerID, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	allergies, err := loadActiveAllergies(h.db, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ingredients []string
+	for _, med := range append(append([]checkMedication{}, current...), active...) {
+		ingredients = append(ingredients, med.HoatChat...)
+	}
+	rules, err := loadInteractionRules(h.db, ingredients)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkPrescription(current, active, rules, allergies), nil
+}
+
+// loadCheckMedications fills in catalogue data for the prescribed medicines.
+func loadCheckMedications(q sqlQueryer, medications []PrescriptionMedication) ([]checkMedication, error) {
+	var meds []checkMedication
+	for _, m := range medications {
+		med := checkMedication{MaThuoc: m.MaThuoc, TenThuoc: m.TenThuoc, CachDung: m.CachDung}
+
+		var tenThuoc, hoatChat, lieuLuong sql.NullString
+		err := q.QueryRow("SELECT tenThuoc, hoatChat, lieuLuong FROM THUOC WHERE maThuoc = @p1", m.MaThuoc).
+			Scan(&tenThuoc, &hoatChat, &lieuLuong)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if tenThuoc.Valid {
+			med.TenThuoc = tenThuoc.String
+		}
+		med.HoatChat = splitIngredients(hoatChat.String)
+		med.LieuLuong = lieuLuong.String
+
+		meds = append(meds, med)
+	}
+	return meds, nil
+}
+
+// loadActiveMedications returns medicines on the patient's other unexpired
+// prescriptions.
+func loadActiveMedications(q sqlQueryer, customerID, excludePrescriptionID string) ([]checkMedication, error) {
+	rows, err := q.Query(`
+		SELECT dt.maDonThuoc, ct.maThuoc, t.tenThuoc, t.hoatChat, t.lieuLuong, ct.cachDung
+		FROM DONTHUOC dt
+		JOIN HOSO h ON dt.maHoSo = h.maHoSo
+		JOIN CHITIETDONTHUOC ct ON dt.maDonThuoc = ct.maDonThuoc
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE h.maCustomer = @p1 AND dt.ngayHeHan >= GETDATE() AND dt.maDonThuoc <> @p2
+		  AND dt.trangThai IN ('SIGNED', 'DISPENSED')
+	`, customerID, excludePrescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var meds []checkMedication
+	for rows.Next() {
+		var med checkMedication
+		var hoatChat, lieuLuong, cachDung sql.NullString
+		if err := rows.Scan(&med.MaDonThuoc, &med.MaThuoc, &med.TenThuoc, &hoatChat, &lieuLuong, &cachDung); err != nil {
+			return nil, err
+		}
+		med.HoatChat = splitIngredients(hoatChat.String)
+		med.LieuLuong = lieuLuong.String
+		med.CachDung = cachDung.String
+		meds = append(meds, med)
+	}
+	return meds, rows.Err()
+}
+
+// loadInteractionRules fetches the rules involving any of the ingredients.
+func loadInteractionRules(q sqlQueryer, ingredients []string) ([]interactionRule, error) {
+	if len(ingredients) < 2 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(ingredients))
+	args := make([]interface{}, len(ingredients))
+	for i, ingredient := range ingredients {
+		placeholders[i] = fmt.Sprintf("@p%d", i+1)
+		args[i] = ingredient
+	}
+	in := strings.Join(placeholders, ", ")
+
+	rows, err := q.Query(`
+		SELECT hoatChat1, hoatChat2, mucDo, moTa, khuyenNghi
+		FROM TUONGTACTHUOC
+		WHERE hoatChat1 IN (`+in+`) AND hoatChat2 IN (`+in+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []interactionRule
+	for rows.Next() {
+		var rule interactionRule
+		var moTa, khuyenNghi sql.NullString
+		if err := rows.Scan(&rule.HoatChat1, &rule.HoatChat2, &rule.MucDo, &moTa, &khuyenNghi); err != nil {
+			return nil, err
+		}
+		rule.MoTa = moTa.String
+		rule.KhuyenNghi = khuyenNghi.String
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (h *PrescriptionHandler) getOverriddenWarnings(prescriptionID string) ([]OverriddenWarningResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT loai, mucDo, maThuoc, noiDung, lyDoBoQua, nguoiBoQua, thoiGian
+		FROM CANHBAODONTHUOC
+		WHERE maDonThuoc = @p1
+		ORDER BY thoiGian DESC
+	`, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warnings := []OverriddenWarningResponse{}
+	for rows.Next() {
+		var w OverriddenWarningResponse
+		var maThuoc string
+		if err := rows.Scan(&w.Loai, &w.MucDo, &maThuoc, &w.NoiDung, &w.LyDoBoQua, &w.NguoiBoQua, &w.ThoiGian); err != nil {
+			return nil, err
+		}
+		w.MaThuoc = strings.Split(maThuoc, ",")
+		warnings = append(warnings, w)
+	}
+	return warnings, rows.Err()
+}
+
+// GetInteractions lists interaction rules, optionally for one ingredient.
+func (h *PrescriptionHandler) GetInteractions(c *gin.Context) {
+	query := `
+		SELECT hoatChat1, hoatChat2, mucDo, moTa, khuyenNghi
+		FROM TUONGTACTHUOC
+	`
+	var args []interface{}
+	if ingredient := c.Query("hoat_chat"); ingredient != "" {
+		query += " WHERE hoatChat1 = @p1 OR hoatChat2 = @p1"
+		args = append(args, ingredientKey(ingredient))
+	}
+	query += " ORDER BY hoatChat1, hoatChat2"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve drug interactions",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	interactions := []InteractionResponse{}
+	for rows.Next() {
+		var hoatChat1, hoatChat2, mucDo string
+		var moTa, khuyenNghi sql.NullString
+		if err := rows.Scan(&hoatChat1, &hoatChat2, &mucDo, &moTa, &khuyenNghi); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan drug interaction data",
+				Error:   err.Error(),
+			})
+			return
+		}
+		interactions = append(interactions, InteractionResponse{
+			HoatChat1:  hoatChat1,
+			HoatChat2:  hoatChat2,
+			MucDo:      mucDo,
+			MoTa:       moTa.String,
+			KhuyenNghi: khuyenNghi.String,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Drug interactions retrieved successfully",
+		Data:    interactions,
+	})
+}
+
+// ImportInteractions loads interaction rules from an uploaded CSV file with
+// the header ingredient_a,ingredient_b,severity,description,recommendation.
+// Existing pairs are updated in place.
+func (h *PrescriptionHandler) ImportInteractions(c *gin.Context) {
+	userType, _ := c.Get("user_type")
+	if userType.(string) != "OPERATION_MANAGER" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only operation managers can import drug interactions",
+		})
+		return
+	}
+
+	rules, ok := uploadedCSV(c, parseInteractionCSV)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	for _, rule := range rules {
+		_, err = tx.Exec(`
+			MERGE TUONGTACTHUOC AS target
+			USING (SELECT @p1 AS hoatChat1, @p2 AS hoatChat2) AS source
+			ON target.hoatChat1 = source.hoatChat1 AND target.hoatChat2 = source.hoatChat2
+			WHEN MATCHED THEN
+				UPDATE SET mucDo = @p3, moTa = @p4, khuyenNghi = @p5
+			WHEN NOT MATCHED THEN
+				INSERT (hoatChat1, hoatChat2, mucDo, moTa, khuyenNghi)
+				VALUES (@p1, @p2, @p3, @p4, @p5);
+		`, rule.HoatChat1, rule.HoatChat2, rule.MucDo, rule.MoTa, rule.KhuyenNghi)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to import interaction " + rule.HoatChat1 + " - " + rule.HoatChat2,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to import drug interactions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Drug interactions imported successfully",
+		Data: InteractionImportResponse{
+			SoTuongTac: len(rules),
+		},
+	})
+}
+
+// parseInteractionCSV reads interaction rows, normalizing ingredient names
+// and ordering each pair. Row-level problems are returned together.
+func parseInteractionCSV(r io.Reader) ([]interactionRule, []string, error) {
+	file, err := readCSV(r, "ingredient_a", "ingredient_b", "severity")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rules []interactionRule
+	seen := make(map[string]bool)
+	for file.next() {
+		a, b := interactionPair(file.field("ingredient_a"), file.field("ingredient_b"))
+		if a == "" || b == "" || a == b {
+			file.fail("two different ingredients are required")
+			continue
+		}
+
+		severity := strings.ToUpper(file.field("severity"))
+		if _, ok := severityRank[severity]; !ok {
+			file.fail("invalid severity %q", severity)
+			continue
+		}
+
+		key := a + "|" + b
+		if seen[key] {
+			file.fail("duplicate pair %s - %s", a, b)
+			continue
+		}
+		seen[key] = true
+
+		rules = append(rules, interactionRule{
+			HoatChat1:  a,
+			HoatChat2:  b,
+			MucDo:      severity,
+			MoTa:       file.field("description"),
+			KhuyenNghi: file.field("recommendation"),
+		})
+	}
+	if file.err != nil {
+		return nil, nil, file.err
+	}
+
+	return rules, file.problems, nil
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"clinic-management/internal/utils"
+)
+
+// Severity levels shared by every prescription warning, lowest first.
+const (
+	severityMinor           = "MINOR"
+	severityModerate        = "MODERATE"
+	severityMajor           = "MAJOR"
+	severityContraindicated = "CONTRAINDICATED"
+)
+
+var severityRank = map[string]int{
+	severityMinor:           1,
+	severityModerate:        2,
+	severityMajor:           3,
+	severityContraindicated: 4,
+}
+
+// checkMedication is a medicine taking part in a prescription check, either
+// from the prescription being written or from one the patient is still on.
+type checkMedication struct {
+	MaThuoc    string
+	TenThuoc   string
+	HoatChat   []string
+	LieuLuong  string
+	CachDung   string
+	MaDonThuoc string // set for medicines from other active prescriptions
+}
+
+type interactionRule struct {
+	HoatChat1  string
+	HoatChat2  string
+	MucDo      string
+	MoTa       string
+	KhuyenNghi string
+}
+
+// PrescriptionWarning is a safety problem found in a prescription.
+type PrescriptionWarning struct {
+	Loai    string   `json:"loai"`
+	MucDo   string   `json:"muc_do"`
+	MaThuoc []string `json:"ma_thuoc"`
+	NoiDung string   `json:"noi_dung"`
+}
+
+// splitIngredients parses THUOC.hoatChat, a comma or semicolon separated
+// list such as "Paracetamol; Caffeine", into normalized ingredient keys.
+func splitIngredients(hoatChat string) []string {
+	var ingredients []string
+	for _, part := range strings.FieldsFunc(hoatChat, func(r rune) bool { return r == ',' || r == ';' || r == '+' }) {
+		if key := ingredientKey(part); key != "" {
+			ingredients = append(ingredients, key)
+		}
+	}
+	return ingredients
+}
+
+func ingredientKey(name string) string {
+	return utils.NormalizeSearchText(name)
+}
+
+// interactionPair orders two ingredient keys so each pair is stored once.
+func interactionPair(a, b string) (string, string) {
+	a, b = ingredientKey(a), ingredientKey(b)
+	if b < a {
+		return b, a
+	}
+	return a, b
+}
+
+// checkPrescription runs every check and returns warnings, most severe
+// first. current holds the medicines being prescribed; active holds those
+// from the patient's other unexpired prescriptions.
+func checkPrescription(current, active []checkMedication, rules []interactionRule, allergies []allergyEntry) []PrescriptionWarning {
+	var warnings []PrescriptionWarning
+	warnings = append(warnings, checkAllergies(current, allergies)...)
+	warnings = append(warnings, checkInteractions(current, active, rules)...)
+	warnings = append(warnings, checkDuplicateIngredients(current, active)...)
+	warnings = append(warnings, checkMaxDose(current)...)
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return severityRank[warnings[i].MucDo] > severityRank[warnings[j].MucDo]
+	})
+	return warnings
+}
+
+func checkAllergies(meds []checkMedication, allergies []allergyEntry) []PrescriptionWarning {
+	var warnings []PrescriptionWarning
+	for _, med := range meds {
+		for _, a := range allergies {
+			if !a.matchesMedication(med.MaThuoc, med.TenThuoc, med.HoatChat) {
+				continue
+			}
+
+			severity := severityMajor
+			if a.MucDo == "SEVERE" || a.MucDo == "LIFE_THREATENING" {
+				severity = severityContraindicated
+			}
+			message := fmt.Sprintf("%s: bệnh nhân dị ứng với %s", med.TenThuoc, a.TacNhan)
+			if a.PhanUng.Valid && a.PhanUng.String != "" {
+				message += " (" + a.PhanUng.String + ")"
+			}
+			warnings = append(warnings, PrescriptionWarning{
+				Loai:    "DI_UNG",
+				MucDo:   severity,
+				MaThuoc: []string{med.MaThuoc},
+				NoiDung: message,
+			})
+		}
+	}
+	return warnings
+}
+
+// checkInteractions looks up every pair of ingredients among the new
+// medicines, and between new and active ones, in the interaction table.
+func checkInteractions(current, active []checkMedication, rules []interactionRule) []PrescriptionWarning {
+	index := make(map[[2]string]interactionRule)
+	for _, rule := range rules {
+		a, b := interactionPair(rule.HoatChat1, rule.HoatChat2)
+		index[[2]string{a, b}] = rule
+	}
+
+	var warnings []PrescriptionWarning
+	seen := make(map[string]bool)
+	all := append(append([]checkMedication{}, current...), active...)
+
+	for i, first := range current {
+		for j := i + 1; j < len(all); j++ {
+			second := all[j]
+			if second.MaThuoc == first.MaThuoc {
+				continue
+			}
+			for _, x := range first.HoatChat {
+				for _, y := range second.HoatChat {
+					a, b := interactionPair(x, y)
+					rule, ok := index[[2]string{a, b}]
+					key := first.MaThuoc + "|" + second.MaThuoc + "|" + a + "|" + b
+					if !ok || seen[key] {
+						continue
+					}
+					seen[key] = true
+
+					message := fmt.Sprintf("Tương tác %s - %s", first.TenThuoc, second.TenThuoc)
+					if second.MaDonThuoc != "" {
+						message += " (đơn " + second.MaDonThuoc + " đang dùng)"
+					}
+					if rule.MoTa != "" {
+						message += ": " + rule.MoTa
+					}
+					if rule.KhuyenNghi != "" {
+						message += ". " + rule.KhuyenNghi
+					}
+					warnings = append(warnings, PrescriptionWarning{
+						Loai:    "TUONG_TAC",
+						MucDo:   rule.MucDo,
+						MaThuoc: []string{first.MaThuoc, second.MaThuoc},
+						NoiDung: message,
+					})
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// checkDuplicateIngredients flags the same active ingredient prescribed
+// twice, within the prescription or alongside an active one.
+func checkDuplicateIngredients(current, active []checkMedication) []PrescriptionWarning {
+	var warnings []PrescriptionWarning
+	all := append(append([]checkMedication{}, current...), active...)
+
+	for i, first := range current {
+		for j := i + 1; j < len(all); j++ {
+			second := all[j]
+			for _, x := range first.HoatChat {
+				if !containsString(second.HoatChat, x) {
+					continue
+				}
+
+				message := fmt.Sprintf("Trùng hoạt chất %s: %s và %s", x, first.TenThuoc, second.TenThuoc)
+				if second.MaDonThuoc != "" {
+					message += " (đơn " + second.MaDonThuoc + " đang dùng)"
+				}
+				warnings = append(warnings, PrescriptionWarning{
+					Loai:    "TRUNG_HOAT_CHAT",
+					MucDo:   severityModerate,
+					MaThuoc: []string{first.MaThuoc, second.MaThuoc},
+					NoiDung: message,
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// checkMaxDose compares the daily amount implied by the directions with the
+// limit written in THUOC.lieuLuong. Only same-unit amounts are compared;
+// text that cannot be parsed is skipped rather than guessed.
+func checkMaxDose(meds []checkMedication) []PrescriptionWarning {
+	var warnings []PrescriptionWarning
+	for _, med := range meds {
+		limit, limitUnit, ok := parseMaxDailyDose(med.LieuLuong)
+		if !ok {
+			continue
+		}
+		daily, unit, ok := parseDailyDose(med.CachDung)
+		if !ok || unit != limitUnit || daily <= limit {
+			continue
+		}
+
+		warnings = append(warnings, PrescriptionWarning{
+			Loai:    "VUOT_LIEU",
+			MucDo:   severityMajor,
+			MaThuoc: []string{med.MaThuoc},
+			NoiDung: fmt.Sprintf("%s: liều %s %s/ngày vượt liều tối đa %s %s/ngày",
+				med.TenThuoc, formatAmount(daily), unit, formatAmount(limit), limitUnit),
+		})
+	}
+	return warnings
+}
+
+const doseUnits = `mg|mcg|µg|g|ml|vien|goi|ong|giot|nang|tablets?|tabs?|capsules?|drops?`
+
+var (
+	maxDosePattern   = regexp.MustCompile(`(?:toi da|khong qua|max(?:imum)?)\s*:?\s*(\d+(?:[.,]\d+)?)\s*(` + doseUnits + `)\b\s*(?:/|mot|moi|per|a)\s*(?:ngay|day|24 ?h)`)
+	amountPattern    = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(` + doseUnits + `)\b`)
+	perDayPattern    = regexp.MustCompile(`^\s*(?:/|mot|moi|per|a)\s*(?:ngay|day)`)
+	frequencyPattern = regexp.MustCompile(`(\d+)\s*(?:lan|times?)\s*(?:/|mot|moi|per|a)\s*(?:ngay|day)|ngay\s*(\d+)\s*lan`)
+)
+
+// parseMaxDailyDose extracts a limit such as "Tối đa 4 g/ngày" or
+// "max 8 tablets/day".
+func parseMaxDailyDose(lieuLuong string) (float64, string, bool) {
+	m := maxDosePattern.FindStringSubmatch(utils.NormalizeSearchText(lieuLuong))
+	if m == nil {
+		return 0, "", false
+	}
+	return normalizeAmount(m[1], m[2])
+}
+
+// parseDailyDose reads directions such as "Uống 2 viên x 3 lần/ngày",
+// "500mg, 2 times/day" or "4 viên/ngày" into a daily amount.
+func parseDailyDose(cachDung string) (float64, string, bool) {
+	text := utils.NormalizeSearchText(cachDung)
+	loc := amountPattern.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return 0, "", false
+	}
+	amount, unit, ok := normalizeAmount(text[loc[2]:loc[3]], text[loc[4]:loc[5]])
+	if !ok {
+		return 0, "", false
+	}
+
+	if perDayPattern.MatchString(text[loc[1]:]) {
+		return amount, unit, true
+	}
+
+	f := frequencyPattern.FindStringSubmatch(text)
+	if f == nil {
+		return 0, "", false
+	}
+	times := f[1]
+	if times == "" {
+		times = f[2]
+	}
+	n, err := strconv.Atoi(times)
+	if err != nil || n <= 0 {
+		return 0, "", false
+	}
+	return amount * float64(n), unit, true
+}
+
+// normalizeAmount converts mass to milligrams and maps English dose forms
+// onto their Vietnamese equivalents so amounts can be compared.
+func normalizeAmount(value, unit string) (float64, string, bool) {
+	amount, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	switch {
+	case unit == "g":
+		return amount * 1000, "mg", true
+	case unit == "mcg" || unit == "µg":
+		return amount / 1000, "mg", true
+	case strings.HasPrefix(unit, "tab"):
+		return amount, "vien", true
+	case strings.HasPrefix(unit, "capsule"):
+		return amount, "nang", true
+	case strings.HasPrefix(unit, "drop"):
+		return amount, "giot", true
+	}
+	return amount, unit, true
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
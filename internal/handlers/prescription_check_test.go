@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestParseMaxDailyDose(t *testing.T) {
+	tests := []struct {
+		lieuLuong string
+		amount    float64
+		unit      string
+		ok        bool
+	}{
+		{"Tối đa 4 g/ngày", 4000, "mg", true},
+		{"Người lớn: 500mg/lần, không quá 3000 mg mỗi ngày", 3000, "mg", true},
+		{"max 8 tablets/day", 8, "vien", true},
+		{"Maximum: 1,5 g per day", 1500, "mg", true},
+		{"Không quá 3 viên một ngày", 3, "vien", true},
+		{"Tối đa 2 ống/24h", 2, "ong", true},
+		{"500 mg mỗi 6 giờ", 0, "", false},
+		{"Theo chỉ định của bác sĩ", 0, "", false},
+		{"Tối đa 4 g", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		amount, unit, ok := parseMaxDailyDose(tt.lieuLuong)
+		if amount != tt.amount || unit != tt.unit || ok != tt.ok {
+			t.Errorf("parseMaxDailyDose(%q) = %v, %q, %v; want %v, %q, %v",
+				tt.lieuLuong, amount, unit, ok, tt.amount, tt.unit, tt.ok)
+		}
+	}
+}
+
+func TestParseDailyDose(t *testing.T) {
+	tests := []struct {
+		cachDung string
+		amount   float64
+		unit     string
+		ok       bool
+	}{
+		{"Uống 2 viên x 3 lần/ngày", 6, "vien", true},
+		{"4 viên/ngày", 4, "vien", true},
+		{"500mg, 2 times/day", 1000, "mg", true},
+		{"Ngày 3 lần, mỗi lần 1 viên", 3, "vien", true},
+		{"Nhỏ 2 giọt mỗi ngày", 2, "giot", true},
+		{"1 capsule 2 times a day", 2, "nang", true},
+		{"0,5 g x 2 lần/ngày", 1000, "mg", true},
+		{"Uống 2 viên khi đau", 0, "", false},
+		{"Uống 2 viên x 0 lần/ngày", 0, "", false},
+		{"3 lần/ngày", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		amount, unit, ok := parseDailyDose(tt.cachDung)
+		if amount != tt.amount || unit != tt.unit || ok != tt.ok {
+			t.Errorf("parseDailyDose(%q) = %v, %q, %v; want %v, %q, %v",
+				tt.cachDung, amount, unit, ok, tt.amount, tt.unit, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeAmount(t *testing.T) {
+	tests := []struct {
+		value, unit string
+		amount      float64
+		normalized  string
+		ok          bool
+	}{
+		{"2", "g", 2000, "mg", true},
+		{"1,5", "g", 1500, "mg", true},
+		{"250", "mcg", 0.25, "mg", true},
+		{"500", "µg", 0.5, "mg", true},
+		{"2", "tabs", 2, "vien", true},
+		{"1", "capsules", 1, "nang", true},
+		{"3", "drop", 3, "giot", true},
+		{"10", "ml", 10, "ml", true},
+		{"x", "mg", 0, "", false},
+	}
+	for _, tt := range tests {
+		amount, unit, ok := normalizeAmount(tt.value, tt.unit)
+		if amount != tt.amount || unit != tt.normalized || ok != tt.ok {
+			t.Errorf("normalizeAmount(%q, %q) = %v, %q, %v; want %v, %q, %v",
+				tt.value, tt.unit, amount, unit, ok, tt.amount, tt.normalized, tt.ok)
+		}
+	}
+}
+
+func TestCheckMaxDose(t *testing.T) {
+	tests := []struct {
+		name      string
+		lieuLuong string
+		cachDung  string
+		warn      bool
+	}{
+		{"over the limit", "Tối đa 4 g/ngày", "1000 mg x 6 lần/ngày", true},
+		{"at the limit", "Tối đa 4 g/ngày", "1 g x 4 lần/ngày", false},
+		{"different units", "Tối đa 4 g/ngày", "Uống 10 viên/ngày", false},
+		{"unparseable limit", "Theo chỉ định", "10 viên x 3 lần/ngày", false},
+		{"unparseable directions", "max 8 tablets/day", "Uống khi đau", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := checkMaxDose([]checkMedication{{
+				MaThuoc: "MED001", TenThuoc: "Paracetamol", LieuLuong: tt.lieuLuong, CachDung: tt.cachDung,
+			}})
+			if got := len(warnings) == 1; got != tt.warn {
+				t.Fatalf("warnings = %v, want warning %v", warnings, tt.warn)
+			}
+			if tt.warn && (warnings[0].Loai != "VUOT_LIEU" || warnings[0].MucDo != severityMajor) {
+				t.Errorf("warning = %+v, want VUOT_LIEU %s", warnings[0], severityMajor)
+			}
+		})
+	}
+}
+
+func TestCheckInteractions(t *testing.T) {
+	rules := []interactionRule{
+		{HoatChat1: "Warfarin", HoatChat2: "Aspirin", MucDo: severityMajor, MoTa: "Tăng nguy cơ chảy máu"},
+		{HoatChat1: "ibuprofen", HoatChat2: "aspirin", MucDo: severityModerate},
+	}
+	warfarin := checkMedication{MaThuoc: "MED001", TenThuoc: "Warfarin 5mg", HoatChat: splitIngredients("Warfarin")}
+	aspirin := checkMedication{MaThuoc: "MED002", TenThuoc: "Aspirin 81mg", HoatChat: splitIngredients("Aspirin")}
+	ibuprofen := checkMedication{MaThuoc: "MED003", TenThuoc: "Ibuprofen", HoatChat: splitIngredients("Ibuprofen")}
+	paracetamol := checkMedication{MaThuoc: "MED004", TenThuoc: "Panadol", HoatChat: splitIngredients("Paracetamol; Caffeine")}
+	activeAspirin := aspirin
+	activeAspirin.MaDonThuoc = "DT000001"
+
+	tests := []struct {
+		name     string
+		current  []checkMedication
+		active   []checkMedication
+		severity []string
+	}{
+		{"pair in the prescription", []checkMedication{warfarin, aspirin}, nil, []string{severityMajor}},
+		{"rule stored in either order", []checkMedication{aspirin, warfarin}, nil, []string{severityMajor}},
+		{"pair with an active prescription", []checkMedication{ibuprofen}, []checkMedication{activeAspirin}, []string{severityModerate}},
+		{"every pair checked", []checkMedication{warfarin, ibuprofen, aspirin}, nil, []string{severityMajor, severityModerate}},
+		{"active medicines not checked against each other", []checkMedication{paracetamol}, []checkMedication{warfarin, activeAspirin}, nil},
+		{"no rule", []checkMedication{paracetamol, aspirin}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, w := range checkInteractions(tt.current, tt.active, rules) {
+				if w.Loai != "TUONG_TAC" {
+					t.Errorf("warning type = %s, want TUONG_TAC", w.Loai)
+				}
+				got = append(got, w.MucDo)
+			}
+			if !reflect.DeepEqual(got, tt.severity) {
+				t.Errorf("severities = %v, want %v", got, tt.severity)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateIngredients(t *testing.T) {
+	panadol := checkMedication{MaThuoc: "MED001", TenThuoc: "Panadol Extra", HoatChat: splitIngredients("Paracetamol; Caffeine")}
+	efferalgan := checkMedication{MaThuoc: "MED002", TenThuoc: "Efferalgan", HoatChat: splitIngredients("paracetamol")}
+	aspirin := checkMedication{MaThuoc: "MED003", TenThuoc: "Aspirin", HoatChat: splitIngredients("Aspirin")}
+	activeEfferalgan := efferalgan
+	activeEfferalgan.MaDonThuoc = "DT000001"
+
+	tests := []struct {
+		name    string
+		current []checkMedication
+		active  []checkMedication
+		want    int
+	}{
+		{"same ingredient twice", []checkMedication{panadol, efferalgan}, nil, 1},
+		{"same ingredient in an active prescription", []checkMedication{panadol}, []checkMedication{activeEfferalgan}, 1},
+		{"different ingredients", []checkMedication{panadol, aspirin}, nil, 0},
+		{"only active medicines overlap", []checkMedication{aspirin}, []checkMedication{panadol, activeEfferalgan}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := checkDuplicateIngredients(tt.current, tt.active)
+			if len(warnings) != tt.want {
+				t.Fatalf("warnings = %v, want %d", warnings, tt.want)
+			}
+			for _, w := range warnings {
+				if w.Loai != "TRUNG_HOAT_CHAT" || w.MucDo != severityModerate {
+					t.Errorf("warning = %+v, want TRUNG_HOAT_CHAT %s", w, severityModerate)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAllergies(t *testing.T) {
+	med := checkMedication{MaThuoc: "MED001", TenThuoc: "Augmentin 625mg", HoatChat: splitIngredients("Amoxicillin; Clavulanic acid")}
+
+	tests := []struct {
+		name     string
+		allergy  allergyEntry
+		severity string
+	}{
+		{"by medicine ID", allergyEntry{TacNhan: "Augmentin", LoaiTacNhan: "THUOC", MaThuoc: sql.NullString{String: "MED001", Valid: true}, MucDo: "MILD"}, severityMajor},
+		{"by ingredient", allergyEntry{TacNhan: "AMOXICILLIN", LoaiTacNhan: "THUOC", MucDo: "MODERATE"}, severityMajor},
+		{"by name", allergyEntry{TacNhan: "augmentin", LoaiTacNhan: "THUOC", MucDo: "SEVERE"}, severityContraindicated},
+		{"life threatening", allergyEntry{TacNhan: "Amoxicillin", LoaiTacNhan: "THUOC", MucDo: "LIFE_THREATENING"}, severityContraindicated},
+		{"other medicine", allergyEntry{TacNhan: "Penicillin G", LoaiTacNhan: "THUOC", MucDo: "SEVERE"}, ""},
+		{"food allergy", allergyEntry{TacNhan: "Amoxicillin", LoaiTacNhan: "THUC_PHAM", MucDo: "SEVERE"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := checkAllergies([]checkMedication{med}, []allergyEntry{tt.allergy})
+			if tt.severity == "" {
+				if len(warnings) != 0 {
+					t.Errorf("warnings = %v, want none", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || warnings[0].Loai != "DI_UNG" || warnings[0].MucDo != tt.severity {
+				t.Errorf("warnings = %v, want one DI_UNG %s", warnings, tt.severity)
+			}
+		})
+	}
+}
+
+func TestCheckPrescriptionOrdersBySeverity(t *testing.T) {
+	current := []checkMedication{
+		{MaThuoc: "MED001", TenThuoc: "Panadol", HoatChat: splitIngredients("Paracetamol"), LieuLuong: "Tối đa 4 g/ngày", CachDung: "1 g x 6 lần/ngày"},
+		{MaThuoc: "MED002", TenThuoc: "Efferalgan", HoatChat: splitIngredients("Paracetamol")},
+		{MaThuoc: "MED003", TenThuoc: "Aspirin", HoatChat: splitIngredients("Aspirin")},
+	}
+	rules := []interactionRule{{HoatChat1: "paracetamol", HoatChat2: "aspirin", MucDo: severityMinor}}
+	allergies := []allergyEntry{{TacNhan: "Aspirin", LoaiTacNhan: "THUOC", MucDo: "SEVERE"}}
+
+	var got []string
+	for _, w := range checkPrescription(current, nil, rules, allergies) {
+		got = append(got, w.Loai+" "+w.MucDo)
+	}
+	want := []string{
+		"DI_UNG CONTRAINDICATED",
+		"VUOT_LIEU MAJOR",
+		"TRUNG_HOAT_CHAT MODERATE",
+		"TUONG_TAC MINOR",
+		"TUONG_TAC MINOR",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+}
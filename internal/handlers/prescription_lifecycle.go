@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prescription statuses; see services.PrescriptionService for the
+// lifecycle.
+const (
+	prescriptionDraft     = services.PrescriptionDraft
+	prescriptionSigned    = services.PrescriptionSigned
+	prescriptionDispensed = services.PrescriptionDispensed
+	prescriptionCancelled = services.PrescriptionCancelled
+)
+
+type CancelPrescriptionRequest struct {
+	LyDo string `json:"ly_do" binding:"required"`
+}
+
+// loadOwnedPrescription writes an error response unless the prescription
+// exists and belongs to the calling doctor, and returns its status.
+func (h *PrescriptionHandler) loadOwnedPrescription(c *gin.Context, prescriptionID string) (string, bool) {
+	prescription, err := h.prescriptions.Owned(viewerOf(c), prescriptionID)
+	if err != nil {
+		serviceFailed(c, err, "Failed to verify prescription")
+		return "", false
+	}
+	return prescription.TrangThai, true
+}
+
+// SignPrescription freezes a draft and issues its e-prescription code.
+func (h *PrescriptionHandler) SignPrescription(c *gin.Context) {
+	prescriptionID := c.Param("id")
+	userID, _ := c.Get("user_id")
+
+	status, ok := h.loadOwnedPrescription(c, prescriptionID)
+	if !ok {
+		return
+	}
+	if status != prescriptionDraft {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Only draft prescriptions can be signed",
+		})
+		return
+	}
+
+	// Codes are random; retry on the unlikely chance of a collision.
+	var code string
+	for attempt := 0; attempt < 5 && code == ""; attempt++ {
+		candidate := utils.GeneratePrescriptionCode()
+		var exists int
+		if err := h.db.QueryRow("SELECT COUNT(*) FROM DONTHUOC WHERE maXacThuc = @p1", candidate).Scan(&exists); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to generate prescription code",
+				Error:   err.Error(),
+			})
+			return
+		}
+		if exists == 0 {
+			code = candidate
+		}
+	}
+	if code == "" {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to generate a unique prescription code",
+		})
+		return
+	}
+
+	now := time.Now()
+	result, err := h.db.Exec(`
+		UPDATE DONTHUOC SET trangThai = 'SIGNED', ngayKy = @p1, nguoiKy = @p2, maXacThuc = @p3
+		WHERE maDonThuoc = @p4 AND trangThai = 'DRAFT'
+	`, now, userID, code, prescriptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to sign prescription",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Only draft prescriptions can be signed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription signed successfully",
+		Data: SignedPrescriptionResponse{
+			MaDonThuoc: prescriptionID,
+			TrangThai:  prescriptionSigned,
+			MaXacThuc:  code,
+			NgayKy:     now,
+		},
+	})
+}
+
+// CancelPrescription withdraws a draft or signed prescription. Dispensed
+// prescriptions cannot be cancelled.
+func (h *PrescriptionHandler) CancelPrescription(c *gin.Context) {
+	var req CancelPrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err := h.prescriptions.Cancel(viewerOf(c), c.Param("id"), strings.TrimSpace(req.LyDo), time.Now())
+	if err != nil {
+		serviceFailed(c, err, "Failed to cancel prescription")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription cancelled successfully",
+	})
+}
+
+// VerifyPrescription is a public endpoint for pharmacies to check the code
+// printed on a prescription. It reveals only what is needed to confirm the
+// paper copy: status, prescriber, clinic and medicines.
+func (h *PrescriptionHandler) VerifyPrescription(c *gin.Context) {
+	code := utils.NormalizePrescriptionCode(c.Param("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid prescription code",
+		})
+		return
+	}
+
+	var maDonThuoc, trangThai, tenBacSi, tenKhachHang string
+	var tenPhongKham sql.NullString
+	var ngayKy, ngayHeHan, ngayPhat sql.NullTime
+	err := h.db.QueryRow(`
+		SELECT dt.maDonThuoc, dt.trangThai, dt.ngayKy, dt.ngayHeHan, dt.ngayPhat,
+		       ud.hoTen, uc.hoTen, p.tenPhongKham
+		FROM DONTHUOC dt
+		JOIN HOSO h ON dt.maHoSo = h.maHoSo
+		JOIN [USER] ud ON h.maBacSi = ud.userID
+		JOIN [USER] uc ON h.maCustomer = uc.userID
+		LEFT JOIN PHONGKHAM p ON h.maPhongKham = p.maPhongKham
+		WHERE dt.maXacThuc = @p1
+	`, code).Scan(&maDonThuoc, &trangThai, &ngayKy, &ngayHeHan, &ngayPhat, &tenBacSi, &tenKhachHang, &tenPhongKham)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Prescription code not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to verify prescription",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT t.tenThuoc, t.hamLuong, ct.soLuong
+		FROM CHITIETDONTHUOC ct
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE ct.maDonThuoc = @p1
+	`, maDonThuoc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get prescription medications",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	medications := []VerifiedMedicationResponse{}
+	for rows.Next() {
+		var tenThuoc string
+		var hamLuong sql.NullString
+		var soLuong int
+		if err := rows.Scan(&tenThuoc, &hamLuong, &soLuong); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan prescription medication",
+				Error:   err.Error(),
+			})
+			return
+		}
+		medications = append(medications, VerifiedMedicationResponse{
+			TenThuoc: tenThuoc,
+			HamLuong: hamLuong.String,
+			SoLuong:  soLuong,
+		})
+	}
+
+	valid := trangThai == prescriptionSigned && (!ngayHeHan.Valid || !ngayHeHan.Time.Before(time.Now()))
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription verified",
+		Data: PrescriptionVerificationResponse{
+			MaXacThuc:    code,
+			ConHieuLuc:   valid,
+			TrangThai:    trangThai,
+			NgayKy:       timePointer(ngayKy),
+			NgayHetHan:   timePointer(ngayHeHan),
+			NgayPhat:     timePointer(ngayPhat),
+			TenBacSi:     tenBacSi,
+			TenPhongKham: tenPhongKham.String,
+			TenBenhNhan:  maskName(tenKhachHang),
+			Medications:  medications,
+		},
+	})
+}
+
+// getDispensedItems lists the batches handed out for a prescription, taken
+// from the stock ledger.
+func (h *PrescriptionHandler) getDispensedItems(prescriptionID string) ([]DispensedItemResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT b.maThuoc, t.tenThuoc, k.soLo, k.hanSuDung, -b.soLuong, b.nguoiThucHien, b.thoiGian
+		FROM BIENDONGKHO b
+		JOIN THUOC t ON b.maThuoc = t.maThuoc
+		JOIN TONKHO k ON b.maLo = k.maLo
+		WHERE b.thamChieu = @p1 AND b.loai = 'XUAT'
+		ORDER BY b.maBienDong
+	`, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []DispensedItemResponse{}
+	for rows.Next() {
+		var item DispensedItemResponse
+		if err := rows.Scan(&item.MaThuoc, &item.TenThuoc, &item.SoLo, &item.HanSuDung, &item.SoLuong, &item.NguoiPhat, &item.ThoiGian); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// maskName keeps the given name (the last word of a Vietnamese name) and
+// reduces the rest to initials, e.g. "Nguyễn Văn An" becomes "N. V. An".
+func maskName(name string) string {
+	parts := strings.Fields(name)
+	for i := 0; i < len(parts)-1; i++ {
+		parts[i] = string([]rune(parts[i])[0]) + "."
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DosageInstruction is the structured form of a prescription line's
+// directions. When it is given, the quantity and the cach_dung text are
+// derived from it instead of being typed by hand.
+type DosageInstruction struct {
+	Lieu         float64 `json:"lieu"`
+	DonVi        string  `json:"don_vi"`
+	DuongDung    string  `json:"duong_dung"`
+	SoLanMoiNgay int     `json:"so_lan_moi_ngay"`
+	ThoiDiem     string  `json:"thoi_diem"`
+	SoNgay       int     `json:"so_ngay"`
+}
+
+type sigUnit struct {
+	label string
+	// countable units are dispensed one for one, so the quantity can be
+	// calculated; amounts like mg or ml need the pack size and are not.
+	countable bool
+}
+
+var sigUnits = map[string]sigUnit{
+	"VIEN":     {"viên", true},
+	"NANG":     {"viên nang", true},
+	"GOI":      {"gói", true},
+	"ONG":      {"ống", true},
+	"MIENG":    {"miếng", true},
+	"GIOT":     {"giọt", false},
+	"NHAT_XIT": {"nhát xịt", false},
+	"ML":       {"ml", false},
+	"MG":       {"mg", false},
+}
+
+var sigRoutes = map[string]string{
+	"UONG": "Uống",
+	"NGAM": "Ngậm",
+	"TIEM": "Tiêm",
+	"NHO":  "Nhỏ",
+	"BOI":  "Bôi",
+	"XIT":  "Xịt",
+	"DAT":  "Đặt",
+	"DAN":  "Dán",
+}
+
+var sigTimings = map[string]string{
+	"TRUOC_AN":  "trước ăn",
+	"SAU_AN":    "sau ăn",
+	"TRONG_AN":  "trong bữa ăn",
+	"KHI_DOI":   "lúc đói",
+	"TRUOC_NGU": "trước khi ngủ",
+}
+
+// validate checks the fields against the supported codes.
+func (d DosageInstruction) validate() error {
+	if d.Lieu <= 0 {
+		return fmt.Errorf("lieu must be greater than 0")
+	}
+	if _, ok := sigUnits[d.DonVi]; !ok {
+		return fmt.Errorf("unknown don_vi %q", d.DonVi)
+	}
+	if _, ok := sigRoutes[d.DuongDung]; !ok {
+		return fmt.Errorf("unknown duong_dung %q", d.DuongDung)
+	}
+	if d.SoLanMoiNgay <= 0 || d.SoLanMoiNgay > 24 {
+		return fmt.Errorf("so_lan_moi_ngay must be between 1 and 24")
+	}
+	if _, ok := sigTimings[d.ThoiDiem]; d.ThoiDiem != "" && !ok {
+		return fmt.Errorf("unknown thoi_diem %q", d.ThoiDiem)
+	}
+	if d.SoNgay <= 0 || d.SoNgay > 365 {
+		return fmt.Errorf("so_ngay must be between 1 and 365")
+	}
+	return nil
+}
+
+// quantity is the number of units needed for the whole course, rounded up.
+// It reports false for units that are not dispensed one for one.
+func (d DosageInstruction) quantity() (int, bool) {
+	if !sigUnits[d.DonVi].countable {
+		return 0, false
+	}
+	return int(math.Ceil(d.Lieu*float64(d.SoLanMoiNgay)*float64(d.SoNgay) - 1e-9)), true
+}
+
+// text renders the directions in Vietnamese, e.g.
+// "Uống 2 viên/lần, 3 lần/ngày, sau ăn, trong 5 ngày". The "x/lần, n
+// lần/ngày" form is also what parseDailyDose understands.
+func (d DosageInstruction) text() string {
+	parts := []string{
+		fmt.Sprintf("%s %s %s/lần", sigRoutes[d.DuongDung], formatSigAmount(d.Lieu), sigUnits[d.DonVi].label),
+		fmt.Sprintf("%d lần/ngày", d.SoLanMoiNgay),
+	}
+	if timing, ok := sigTimings[d.ThoiDiem]; ok {
+		parts = append(parts, timing)
+	}
+	parts = append(parts, fmt.Sprintf("trong %d ngày", d.SoNgay))
+	return strings.Join(parts, ", ")
+}
+
+// formatSigAmount prints half tablets as "0,5" in the Vietnamese style.
+func formatSigAmount(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", ",", 1)
+}
+
+// applyDosageInstructions fills in so_luong and cach_dung for lines that
+// carry structured directions and checks that the remaining lines still
+// have the free-text fields. It writes a 400 response and returns false
+// when a line is invalid.
+func applyDosageInstructions(c *gin.Context, medications []PrescriptionMedication) bool {
+	var problems []string
+	for i := range medications {
+		med := &medications[i]
+		med.MaThuoc = strings.TrimSpace(med.MaThuoc)
+
+		if med.LieuDung == nil {
+			if med.SoLuong < 1 || strings.TrimSpace(med.CachDung) == "" {
+				problems = append(problems, fmt.Sprintf("%s: so_luong and cach_dung are required without lieu_dung", med.MaThuoc))
+			}
+			continue
+		}
+
+		sig := med.LieuDung
+		sig.DonVi = strings.ToUpper(strings.TrimSpace(sig.DonVi))
+		sig.DuongDung = strings.ToUpper(strings.TrimSpace(sig.DuongDung))
+		sig.ThoiDiem = strings.ToUpper(strings.TrimSpace(sig.ThoiDiem))
+		if err := sig.validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", med.MaThuoc, err))
+			continue
+		}
+
+		if qty, ok := sig.quantity(); ok {
+			if med.SoLuong != 0 && med.SoLuong != qty {
+				problems = append(problems, fmt.Sprintf("%s: so_luong %d does not match the %d %s needed for the course",
+					med.MaThuoc, med.SoLuong, qty, sigUnits[sig.DonVi].label))
+				continue
+			}
+			med.SoLuong = qty
+		} else if med.SoLuong < 1 {
+			problems = append(problems, fmt.Sprintf("%s: so_luong is required when don_vi is %s", med.MaThuoc, sig.DonVi))
+			continue
+		}
+
+		med.CachDung = sig.text()
+	}
+
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid dosage instructions",
+			Error:   strings.Join(problems, "; "),
+		})
+		return false
+	}
+	return true
+}
+
+// dose converts structured instructions for the services; nil stays nil
+// for free-text lines.
+func (sig *DosageInstruction) dose() *services.Dose {
+	if sig == nil {
+		return nil
+	}
+	return &services.Dose{
+		Lieu:         sig.Lieu,
+		DonVi:        sig.DonVi,
+		DuongDung:    sig.DuongDung,
+		SoLanMoiNgay: sig.SoLanMoiNgay,
+		ThoiDiem:     sig.ThoiDiem,
+		SoNgay:       sig.SoNgay,
+	}
+}
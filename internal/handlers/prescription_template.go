@@ -0,0 +1,793 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Template scopes. Personal templates belong to one doctor; clinic
+// templates are maintained by managers and shared with every doctor who
+// works at the clinic.
+const (
+	templatePersonal = "PERSONAL"
+	templateClinic   = "CLINIC"
+)
+
+type PrescriptionTemplateHandler struct {
+	db *sql.DB
+}
+
+func NewPrescriptionTemplateHandler(db *sql.DB) *PrescriptionTemplateHandler {
+	return &PrescriptionTemplateHandler{db: db}
+}
+
+type PrescriptionTemplateRequest struct {
+	TenMau      string                   `json:"ten_mau" binding:"required"`
+	PhamVi      string                   `json:"pham_vi"`
+	MaPhongKham string                   `json:"ma_phong_kham"`
+	MaICD10     string                   `json:"ma_icd10"`
+	GhiChu      string                   `json:"ghi_chu"`
+	Medications []PrescriptionMedication `json:"medications" binding:"dive"`
+	XetNghiem   []TemplateLabOrder       `json:"xet_nghiem" binding:"dive"`
+}
+
+type TemplateLabOrder struct {
+	LoaiXetNghiem string `json:"loai_xet_nghiem" binding:"required"`
+	GhiChu        string `json:"ghi_chu"`
+}
+
+type ApplyTemplateRequest struct {
+	MaHoSo string `json:"ma_ho_so" binding:"required"`
+}
+
+type prescriptionTemplate struct {
+	MaMau       string
+	TenMau      string
+	PhamVi      string
+	MaBacSi     sql.NullString
+	MaPhongKham sql.NullString
+	MaICD10     sql.NullString
+	GhiChu      sql.NullString
+}
+
+func (h *PrescriptionTemplateHandler) GetTemplates(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	query := `
+		SELECT m.maMau, m.tenMau, m.phamVi, m.maBacSi, m.maPhongKham, m.maICD10, m.ghiChu, m.ngayCapNhat,
+		       (SELECT COUNT(*) FROM CHITIETMAUDONTHUOC ct WHERE ct.maMau = m.maMau),
+		       (SELECT COUNT(*) FROM XETNGHIEMMAU xm WHERE xm.maMau = m.maMau)
+		FROM MAUDONTHUOC m
+		WHERE `
+	var args []interface{}
+
+	switch userType.(string) {
+	case "DOCTOR":
+		query += `((m.phamVi = 'PERSONAL' AND m.maBacSi = @p1)
+		   OR (m.phamVi = 'CLINIC' AND m.maPhongKham IN (SELECT maPhongKham FROM LICHLAMVIEC WHERE maBacSi = @p1)))`
+		args = append(args, userID)
+	case "CLINIC_MANAGER":
+		query += `m.phamVi = 'CLINIC' AND m.maPhongKham IN (SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p1)`
+		args = append(args, userID)
+	case "OPERATION_MANAGER":
+		query += `m.phamVi = 'CLINIC'`
+	default:
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Access denied to prescription templates",
+		})
+		return
+	}
+
+	if phamVi := strings.ToUpper(c.Query("pham_vi")); phamVi != "" {
+		args = append(args, phamVi)
+		query += fmt.Sprintf(" AND m.phamVi = @p%d", len(args))
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		args = append(args, "%"+q+"%")
+		query += fmt.Sprintf(" AND m.tenMau LIKE @p%d", len(args))
+	}
+
+	order := " ORDER BY m.phamVi DESC, m.tenMau"
+	if code := utils.NormalizeICD10Code(c.Query("ma_icd10")); code != "" {
+		// A template for J02 also applies to J02.9; the most specific
+		// template is listed first.
+		args = append(args, code)
+		query += fmt.Sprintf(" AND m.maICD10 IS NOT NULL AND @p%d LIKE CONCAT(m.maICD10, '%%')", len(args))
+		order = " ORDER BY LEN(m.maICD10) DESC, m.phamVi DESC, m.tenMau"
+	}
+
+	rows, err := h.db.Query(query+order, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve prescription templates",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	templates := []TemplateSummaryResponse{}
+	for rows.Next() {
+		var t prescriptionTemplate
+		var ngayCapNhat time.Time
+		var soThuoc, soXetNghiem int
+		if err := rows.Scan(&t.MaMau, &t.TenMau, &t.PhamVi, &t.MaBacSi, &t.MaPhongKham, &t.MaICD10, &t.GhiChu,
+			&ngayCapNhat, &soThuoc, &soXetNghiem); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan prescription template",
+				Error:   err.Error(),
+			})
+			return
+		}
+		templates = append(templates, TemplateSummaryResponse{
+			TemplateResponse: t.response(),
+			NgayCapNhat:      ngayCapNhat,
+			SoThuoc:          soThuoc,
+			SoXetNghiem:      soXetNghiem,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription templates retrieved successfully",
+		Data:    templates,
+	})
+}
+
+func (h *PrescriptionTemplateHandler) GetTemplate(c *gin.Context) {
+	t, ok := h.loadTemplate(c, c.Param("id"))
+	if !ok || !h.canUseTemplate(c, t) {
+		return
+	}
+
+	medications, err := h.getTemplateMedications(t.MaMau)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get template medications",
+			Error:   err.Error(),
+		})
+		return
+	}
+	labOrders, err := h.getTemplateLabOrders(t.MaMau)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get template lab orders",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription template retrieved successfully",
+		Data: TemplateDetailResponse{
+			TemplateResponse: t.response(),
+			Medications:      medications,
+			XetNghiem:        labOrders,
+		},
+	})
+}
+
+func (h *PrescriptionTemplateHandler) CreateTemplate(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req PrescriptionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	t, ok := h.templateOwner(c, &req)
+	if !ok || !h.validateTemplate(c, &req) {
+		return
+	}
+
+	id, err := utils.GeneratePrescriptionTemplateID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create prescription template",
+			Error:   err.Error(),
+		})
+		return
+	}
+	t.MaMau = id
+	now := time.Now()
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO MAUDONTHUOC (maMau, tenMau, phamVi, maBacSi, maPhongKham, maICD10, ghiChu, nguoiTao, ngayTao, ngayCapNhat)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9, @p9)
+	`, t.MaMau, req.TenMau, req.PhamVi, nullString(t.MaBacSi), nullString(t.MaPhongKham),
+		services.NullIfEmpty(req.MaICD10), services.NullIfEmpty(req.GhiChu), userID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create prescription template",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = insertTemplateLines(tx, t.MaMau, &req); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save template lines",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create prescription template",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Prescription template created successfully",
+		Data: CreatedTemplateResponse{
+			MaMau:  t.MaMau,
+			PhamVi: req.PhamVi,
+		},
+	})
+}
+
+// UpdateTemplate replaces the name, diagnosis code and lines of a template.
+// The scope and owner cannot be changed.
+func (h *PrescriptionTemplateHandler) UpdateTemplate(c *gin.Context) {
+	t, ok := h.loadTemplate(c, c.Param("id"))
+	if !ok || !h.canEditTemplate(c, t) {
+		return
+	}
+
+	var req PrescriptionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	req.PhamVi = t.PhamVi
+	if !h.validateTemplate(c, &req) {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE MAUDONTHUOC SET tenMau = @p1, maICD10 = @p2, ghiChu = @p3, ngayCapNhat = @p4
+		WHERE maMau = @p5
+	`, req.TenMau, services.NullIfEmpty(req.MaICD10), services.NullIfEmpty(req.GhiChu), time.Now(), t.MaMau)
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM CHITIETMAUDONTHUOC WHERE maMau = @p1", t.MaMau)
+	}
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM XETNGHIEMMAU WHERE maMau = @p1", t.MaMau)
+	}
+	if err == nil {
+		err = insertTemplateLines(tx, t.MaMau, &req)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update prescription template",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription template updated successfully",
+	})
+}
+
+func (h *PrescriptionTemplateHandler) DeleteTemplate(c *gin.Context) {
+	t, ok := h.loadTemplate(c, c.Param("id"))
+	if !ok || !h.canEditTemplate(c, t) {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []string{
+		"DELETE FROM CHITIETMAUDONTHUOC WHERE maMau = @p1",
+		"DELETE FROM XETNGHIEMMAU WHERE maMau = @p1",
+		"DELETE FROM MAUDONTHUOC WHERE maMau = @p1",
+	} {
+		if _, err = tx.Exec(stmt, t.MaMau); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to delete prescription template",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription template deleted successfully",
+	})
+}
+
+// ApplyTemplate builds a prescription request and lab orders for a medical
+// record from a template. Nothing is saved: the doctor reviews and edits the
+// result, then submits it to POST /prescriptions and POST /lab-tests.
+// Medicines discontinued since the template was written are left out and
+// listed separately.
+func (h *PrescriptionTemplateHandler) ApplyTemplate(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	if userType.(string) != "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only doctors can apply prescription templates",
+		})
+		return
+	}
+
+	var req ApplyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	t, ok := h.loadTemplate(c, c.Param("id"))
+	if !ok || !h.canUseTemplate(c, t) {
+		return
+	}
+
+	var doctorID string
+	err := h.db.QueryRow("SELECT maBacSi FROM HOSO WHERE maHoSo = @p1", req.MaHoSo).Scan(&doctorID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Medical record not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to verify medical record",
+				Error:   err.Error(),
+			})
+		}
+		return
+	}
+	if doctorID != userID.(string) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You can only prescribe for your own patients",
+		})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT ct.maThuoc, t.tenThuoc, t.trangThai, ct.soLuong, ct.cachDung, ct.ghiChu,
+		       ct.lieuDung, ct.donViLieu, ct.duongDung, ct.soLanMoiNgay, ct.thoiDiemDung, ct.soNgayDung
+		FROM CHITIETMAUDONTHUOC ct
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE ct.maMau = @p1
+		ORDER BY ct.thuTu
+	`, t.MaMau)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get template medications",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	medications := []PrescriptionMedication{}
+	skipped := []SkippedMedicationResponse{}
+	for rows.Next() {
+		var med PrescriptionMedication
+		var trangThai string
+		if err := scanTemplateMedication(rows, &med, &trangThai); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to scan template medication",
+				Error:   err.Error(),
+			})
+			return
+		}
+		if trangThai != "ACTIVE" {
+			skipped = append(skipped, SkippedMedicationResponse{
+				MaThuoc:  med.MaThuoc,
+				TenThuoc: med.TenThuoc,
+			})
+			continue
+		}
+		medications = append(medications, med)
+	}
+
+	labOrders, err := h.getTemplateLabOrders(t.MaMau)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get template lab orders",
+			Error:   err.Error(),
+		})
+		return
+	}
+	labRequests := make([]LabTestRequest, len(labOrders))
+	for i, order := range labOrders {
+		labRequests[i] = LabTestRequest{
+			MaHoSo:        req.MaHoSo,
+			LoaiXetNghiem: order.LoaiXetNghiem,
+			GhiChu:        order.GhiChu,
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Prescription template applied",
+		Data: AppliedTemplateResponse{
+			MaMau: t.MaMau,
+			Prescription: PrescriptionRequest{
+				MaHoSo:      req.MaHoSo,
+				Medications: medications,
+				GhiChu:      t.GhiChu.String,
+			},
+			XetNghiem:        labRequests,
+			ThuocNgungSuDung: skipped,
+		},
+	})
+}
+
+// templateOwner works out the scope, owner and clinic of a new template
+// from the caller's role.
+func (h *PrescriptionTemplateHandler) templateOwner(c *gin.Context, req *PrescriptionTemplateRequest) (prescriptionTemplate, bool) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	var t prescriptionTemplate
+	req.PhamVi = strings.ToUpper(strings.TrimSpace(req.PhamVi))
+	if req.PhamVi == "" {
+		req.PhamVi = templatePersonal
+	}
+
+	switch req.PhamVi {
+	case templatePersonal:
+		if userType.(string) != "DOCTOR" {
+			c.JSON(http.StatusForbidden, models.APIResponse{
+				Success: false,
+				Message: "Only doctors can create personal templates",
+			})
+			return t, false
+		}
+		t.MaBacSi = sql.NullString{String: userID.(string), Valid: true}
+	case templateClinic:
+		if !isManager(c, "Only managers can create clinic templates") {
+			return t, false
+		}
+		clinicID := req.MaPhongKham
+		if userType.(string) == "CLINIC_MANAGER" {
+			err := h.db.QueryRow("SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p1", userID).Scan(&clinicID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success: false,
+					Message: "Failed to get manager clinic",
+					Error:   err.Error(),
+				})
+				return t, false
+			}
+		}
+		if clinicID == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "ma_phong_kham is required for clinic templates",
+			})
+			return t, false
+		}
+		t.MaPhongKham = sql.NullString{String: clinicID, Valid: true}
+	default:
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "pham_vi must be PERSONAL or CLINIC",
+		})
+		return t, false
+	}
+	return t, true
+}
+
+// validateTemplate checks the lines the same way a prescription is checked
+// and normalises the ICD-10 code.
+func (h *PrescriptionTemplateHandler) validateTemplate(c *gin.Context, req *PrescriptionTemplateRequest) bool {
+	req.TenMau = strings.TrimSpace(req.TenMau)
+	if len(req.Medications) == 0 && len(req.XetNghiem) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "A template needs at least one medication or lab order",
+		})
+		return false
+	}
+
+	if len(req.Medications) > 0 {
+		if !applyDosageInstructions(c, req.Medications) || !validateCatalogueMedications(c, h.db, req.Medications) {
+			return false
+		}
+	}
+
+	if req.MaICD10 != "" {
+		codes := []string{req.MaICD10}
+		if !validateICD10Codes(c, h.db, codes) {
+			return false
+		}
+		req.MaICD10 = codes[0]
+	}
+	return true
+}
+
+func insertTemplateLines(tx *sql.Tx, templateID string, req *PrescriptionTemplateRequest) error {
+	for i, med := range req.Medications {
+		_, err := tx.Exec(`
+			INSERT INTO CHITIETMAUDONTHUOC (maMau, thuTu, maThuoc, soLuong, cachDung, ghiChu,
+				lieuDung, donViLieu, duongDung, soLanMoiNgay, thoiDiemDung, soNgayDung)
+			VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9, @p10, @p11, @p12)
+		`, append([]interface{}{templateID, i + 1, med.MaThuoc, med.SoLuong, med.CachDung, services.NullIfEmpty(med.GhiChu)},
+			services.DoseValues(med.LieuDung.dose())...)...)
+		if err != nil {
+			return err
+		}
+	}
+	for i, order := range req.XetNghiem {
+		_, err := tx.Exec(`
+			INSERT INTO XETNGHIEMMAU (maMau, thuTu, loaiXetNghiem, ghiChu)
+			VALUES (@p1, @p2, @p3, @p4)
+		`, templateID, i+1, strings.TrimSpace(order.LoaiXetNghiem), services.NullIfEmpty(order.GhiChu))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *PrescriptionTemplateHandler) loadTemplate(c *gin.Context, templateID string) (prescriptionTemplate, bool) {
+	var t prescriptionTemplate
+	err := h.db.QueryRow(`
+		SELECT maMau, tenMau, phamVi, maBacSi, maPhongKham, maICD10, ghiChu
+		FROM MAUDONTHUOC WHERE maMau = @p1
+	`, templateID).Scan(&t.MaMau, &t.TenMau, &t.PhamVi, &t.MaBacSi, &t.MaPhongKham, &t.MaICD10, &t.GhiChu)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Message: "Prescription template not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Failed to retrieve prescription template",
+				Error:   err.Error(),
+			})
+		}
+		return t, false
+	}
+	return t, true
+}
+
+// canUseTemplate allows the owner of a personal template, and for clinic
+// templates the doctors working at the clinic and its managers.
+func (h *PrescriptionTemplateHandler) canUseTemplate(c *gin.Context, t prescriptionTemplate) bool {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	allowed := false
+	if t.PhamVi == templatePersonal {
+		allowed = userType.(string) == "DOCTOR" && t.MaBacSi.String == userID.(string)
+	} else {
+		var table, column string
+		switch userType.(string) {
+		case "OPERATION_MANAGER":
+			allowed = true
+		case "CLINIC_MANAGER":
+			table, column = "QUANLYPHONGKHAM", "maUser"
+		case "DOCTOR":
+			table, column = "LICHLAMVIEC", "maBacSi"
+		}
+		if table != "" {
+			var count int
+			err := h.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+column+" = @p1 AND maPhongKham = @p2",
+				userID, t.MaPhongKham.String).Scan(&count)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success: false,
+					Message: "Failed to verify template access",
+					Error:   err.Error(),
+				})
+				return false
+			}
+			allowed = count > 0
+		}
+	}
+
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Access denied to this prescription template",
+		})
+	}
+	return allowed
+}
+
+// canEditTemplate allows the owner of a personal template and the managers
+// of a clinic template's clinic.
+func (h *PrescriptionTemplateHandler) canEditTemplate(c *gin.Context, t prescriptionTemplate) bool {
+	userType, _ := c.Get("user_type")
+	if t.PhamVi == templateClinic && userType.(string) == "DOCTOR" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Only managers can change clinic templates",
+		})
+		return false
+	}
+	return h.canUseTemplate(c, t)
+}
+
+func (h *PrescriptionTemplateHandler) getTemplateMedications(templateID string) ([]TemplateMedicationResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT ct.maThuoc, t.tenThuoc, t.trangThai, ct.soLuong, ct.cachDung, ct.ghiChu,
+		       ct.lieuDung, ct.donViLieu, ct.duongDung, ct.soLanMoiNgay, ct.thoiDiemDung, ct.soNgayDung
+		FROM CHITIETMAUDONTHUOC ct
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE ct.maMau = @p1
+		ORDER BY ct.thuTu
+	`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	medications := []TemplateMedicationResponse{}
+	for rows.Next() {
+		var med PrescriptionMedication
+		var trangThai string
+		if err := scanTemplateMedication(rows, &med, &trangThai); err != nil {
+			return nil, err
+		}
+		medications = append(medications, TemplateMedicationResponse{
+			MaThuoc:   med.MaThuoc,
+			TenThuoc:  med.TenThuoc,
+			TrangThai: trangThai,
+			SoLuong:   med.SoLuong,
+			CachDung:  med.CachDung,
+			LieuDung:  med.LieuDung,
+			GhiChu:    med.GhiChu,
+		})
+	}
+	return medications, rows.Err()
+}
+
+func (h *PrescriptionTemplateHandler) getTemplateLabOrders(templateID string) ([]TemplateLabOrder, error) {
+	rows, err := h.db.Query("SELECT loaiXetNghiem, ghiChu FROM XETNGHIEMMAU WHERE maMau = @p1 ORDER BY thuTu", templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []TemplateLabOrder{}
+	for rows.Next() {
+		var order TemplateLabOrder
+		var ghiChu sql.NullString
+		if err := rows.Scan(&order.LoaiXetNghiem, &ghiChu); err != nil {
+			return nil, err
+		}
+		order.GhiChu = ghiChu.String
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func scanTemplateMedication(rows *sql.Rows, med *PrescriptionMedication, trangThai *string) error {
+	var ghiChu, donViLieu, duongDung, thoiDiemDung sql.NullString
+	var lieuDung sql.NullFloat64
+	var soLanMoiNgay, soNgayDung sql.NullInt32
+	err := rows.Scan(&med.MaThuoc, &med.TenThuoc, trangThai, &med.SoLuong, &med.CachDung, &ghiChu,
+		&lieuDung, &donViLieu, &duongDung, &soLanMoiNgay, &thoiDiemDung, &soNgayDung)
+	if err != nil {
+		return err
+	}
+	med.GhiChu = ghiChu.String
+	if lieuDung.Valid {
+		med.LieuDung = &DosageInstruction{
+			Lieu:         lieuDung.Float64,
+			DonVi:        donViLieu.String,
+			DuongDung:    duongDung.String,
+			SoLanMoiNgay: int(soLanMoiNgay.Int32),
+			ThoiDiem:     thoiDiemDung.String,
+			SoNgay:       int(soNgayDung.Int32),
+		}
+	}
+	return nil
+}
+
+func (t prescriptionTemplate) response() TemplateResponse {
+	return TemplateResponse{
+		MaMau:       t.MaMau,
+		TenMau:      t.TenMau,
+		PhamVi:      t.PhamVi,
+		MaBacSi:     t.MaBacSi.String,
+		MaPhongKham: t.MaPhongKham.String,
+		MaICD10:     t.MaICD10.String,
+		GhiChu:      t.GhiChu.String,
+	}
+}
+
+func nullString(s sql.NullString) interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
@@ -0,0 +1,913 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	"clinic-management/internal/models"
+)
+
+// Response bodies of the endpoints described in the OpenAPI document. The
+// document is generated from these types, so a field added here shows up
+// in it; see openapi.go.
+
+// CustomerResponse is a customer, as listed for receptionists and as
+// returned by GET /customers/:id.
+type CustomerResponse struct {
+	UserID      string     `json:"user_id"`
+	HoTen       string     `json:"ho_ten"`
+	SoDienThoai *string    `json:"so_dien_thoai"`
+	Email       *string    `json:"email"`
+	Status      string     `json:"status"`
+	NgaySinh    *time.Time `json:"ngay_sinh"`
+	GioiTinh    *string    `json:"gioi_tinh"`
+	DiaChi      *string    `json:"dia_chi"`
+	NgayDangKy  *time.Time `json:"ngay_dang_ky"`
+	MaBaoHiem   *string    `json:"ma_bao_hiem"`
+}
+
+// PrescriptionResponse is a prescription with its medicines, as listed.
+type PrescriptionResponse struct {
+	MaDonThuoc   string                     `json:"ma_don_thuoc"`
+	MaHoSo       string                     `json:"ma_ho_so"`
+	NgayKeDon    *time.Time                 `json:"ngay_ke_don"`
+	GhiChu       string                     `json:"ghi_chu"`
+	TrangThai    string                     `json:"trang_thai"`
+	MaXacThuc    string                     `json:"ma_xac_thuc"`
+	MaCustomer   string                     `json:"ma_customer"`
+	MaBacSi      string                     `json:"ma_bac_si"`
+	TenKhachHang string                     `json:"ten_khach_hang"`
+	TenBacSi     string                     `json:"ten_bac_si"`
+	Medications  []PrescriptionItemResponse `json:"medications"`
+}
+
+// PrescriptionItemResponse is one medicine of a prescription. lieu_dung is
+// set when the dose was given in structured form.
+type PrescriptionItemResponse struct {
+	MaThuoc   string             `json:"ma_thuoc"`
+	TenThuoc  string             `json:"ten_thuoc"`
+	SoLuong   int                `json:"so_luong"`
+	CachDung  string             `json:"cach_dung"`
+	LieuDung  *DosageInstruction `json:"lieu_dung"`
+	GhiChu    string             `json:"ghi_chu"`
+	Gia       float64            `json:"gia"`
+	CongDung  string             `json:"cong_dung"`
+	LieuLuong string             `json:"lieu_luong"`
+}
+
+// PrescriptionDetailResponse is a prescription with its lifecycle, the
+// patient's allergies, the warnings the doctor overrode and the batches
+// dispensed.
+type PrescriptionDetailResponse struct {
+	PrescriptionResponse
+	NgayKy          *time.Time                  `json:"ngay_ky"`
+	NgayHuy         *time.Time                  `json:"ngay_huy"`
+	NguoiHuy        string                      `json:"nguoi_huy"`
+	LyDoHuy         string                      `json:"ly_do_huy"`
+	NgayPhat        *time.Time                  `json:"ngay_phat"`
+	NguoiPhat       string                      `json:"nguoi_phat"`
+	MaPhongKhamPhat string                      `json:"ma_phong_kham_phat"`
+	DiUng           []AllergyResponse           `json:"di_ung"`
+	CanhBao         []OverriddenWarningResponse `json:"canh_bao"`
+	CapPhat         []DispensedItemResponse     `json:"cap_phat"`
+}
+
+// CreatedPrescriptionResponse identifies a new draft prescription and the
+// warnings the doctor overrode.
+type CreatedPrescriptionResponse struct {
+	MaDonThuoc string                `json:"ma_don_thuoc"`
+	TrangThai  string                `json:"trang_thai"`
+	CanhBao    []PrescriptionWarning `json:"canh_bao"`
+}
+
+// PrescriptionWarningsResponse lists the safety warnings of a prescription:
+// those overridden on a save, or those blocking it without ly_do_bo_qua.
+type PrescriptionWarningsResponse struct {
+	CanhBao []PrescriptionWarning `json:"canh_bao"`
+}
+
+// PrescriptionCheckResponse is the result of checking a prescription
+// without saving it.
+type PrescriptionCheckResponse struct {
+	CanhBao      []PrescriptionWarning `json:"canh_bao"`
+	CanLyDoBoQua bool                  `json:"can_ly_do_bo_qua"`
+}
+
+// SignedPrescriptionResponse is a prescription just signed with its
+// e-prescription code.
+type SignedPrescriptionResponse struct {
+	MaDonThuoc string    `json:"ma_don_thuoc"`
+	TrangThai  string    `json:"trang_thai"`
+	MaXacThuc  string    `json:"ma_xac_thuc"`
+	NgayKy     time.Time `json:"ngay_ky"`
+}
+
+// PrescriptionVerificationResponse is what a pharmacy learns from a
+// prescription code; the patient name is masked.
+type PrescriptionVerificationResponse struct {
+	MaXacThuc    string                       `json:"ma_xac_thuc"`
+	ConHieuLuc   bool                         `json:"con_hieu_luc"`
+	TrangThai    string                       `json:"trang_thai"`
+	NgayKy       *time.Time                   `json:"ngay_ky"`
+	NgayHetHan   *time.Time                   `json:"ngay_het_han"`
+	NgayPhat     *time.Time                   `json:"ngay_phat"`
+	TenBacSi     string                       `json:"ten_bac_si"`
+	TenPhongKham string                       `json:"ten_phong_kham"`
+	TenBenhNhan  string                       `json:"ten_benh_nhan"`
+	Medications  []VerifiedMedicationResponse `json:"medications"`
+}
+
+// VerifiedMedicationResponse is a medicine on a verified prescription.
+type VerifiedMedicationResponse struct {
+	TenThuoc string `json:"ten_thuoc"`
+	HamLuong string `json:"ham_luong"`
+	SoLuong  int    `json:"so_luong"`
+}
+
+// TemplateResponse is a prescription template without its lines.
+type TemplateResponse struct {
+	MaMau       string `json:"ma_mau"`
+	TenMau      string `json:"ten_mau"`
+	PhamVi      string `json:"pham_vi"`
+	MaBacSi     string `json:"ma_bac_si"`
+	MaPhongKham string `json:"ma_phong_kham"`
+	MaICD10     string `json:"ma_icd10"`
+	GhiChu      string `json:"ghi_chu"`
+}
+
+// TemplateSummaryResponse is a template as listed, with its line counts.
+type TemplateSummaryResponse struct {
+	TemplateResponse
+	NgayCapNhat time.Time `json:"ngay_cap_nhat"`
+	SoThuoc     int       `json:"so_thuoc"`
+	SoXetNghiem int       `json:"so_xet_nghiem"`
+}
+
+// TemplateDetailResponse is a template with its medicines and lab orders.
+type TemplateDetailResponse struct {
+	TemplateResponse
+	Medications []TemplateMedicationResponse `json:"medications"`
+	XetNghiem   []TemplateLabOrder           `json:"xet_nghiem"`
+}
+
+// TemplateMedicationResponse is a medicine line of a template with the
+// medicine's current status.
+type TemplateMedicationResponse struct {
+	MaThuoc   string             `json:"ma_thuoc"`
+	TenThuoc  string             `json:"ten_thuoc"`
+	TrangThai string             `json:"trang_thai"`
+	SoLuong   int                `json:"so_luong"`
+	CachDung  string             `json:"cach_dung"`
+	LieuDung  *DosageInstruction `json:"lieu_dung"`
+	GhiChu    string             `json:"ghi_chu"`
+}
+
+// CreatedTemplateResponse identifies a new template and its scope.
+type CreatedTemplateResponse struct {
+	MaMau  string `json:"ma_mau"`
+	PhamVi string `json:"pham_vi"`
+}
+
+// AppliedTemplateResponse is a template filled in for a medical record,
+// ready to submit to POST /prescriptions and POST /lab-tests.
+type AppliedTemplateResponse struct {
+	MaMau            string                      `json:"ma_mau"`
+	Prescription     PrescriptionRequest         `json:"prescription"`
+	XetNghiem        []LabTestRequest            `json:"xet_nghiem"`
+	ThuocNgungSuDung []SkippedMedicationResponse `json:"thuoc_ngung_su_dung"`
+}
+
+// SkippedMedicationResponse is a discontinued medicine left out of an
+// applied template.
+type SkippedMedicationResponse struct {
+	MaThuoc  string `json:"ma_thuoc"`
+	TenThuoc string `json:"ten_thuoc"`
+}
+
+// OverriddenWarningResponse is a prescription warning the doctor chose to
+// override, with the reason given.
+type OverriddenWarningResponse struct {
+	Loai       string    `json:"loai"`
+	MucDo      string    `json:"muc_do"`
+	MaThuoc    []string  `json:"ma_thuoc"`
+	NoiDung    string    `json:"noi_dung"`
+	LyDoBoQua  string    `json:"ly_do_bo_qua"`
+	NguoiBoQua string    `json:"nguoi_bo_qua"`
+	ThoiGian   time.Time `json:"thoi_gian"`
+}
+
+// DispensedItemResponse is a batch handed out for a prescription.
+type DispensedItemResponse struct {
+	MaThuoc   string    `json:"ma_thuoc"`
+	TenThuoc  string    `json:"ten_thuoc"`
+	SoLo      string    `json:"so_lo"`
+	HanSuDung time.Time `json:"han_su_dung"`
+	SoLuong   int       `json:"so_luong"`
+	NguoiPhat string    `json:"nguoi_phat"`
+	ThoiGian  time.Time `json:"thoi_gian"`
+}
+
+// NotificationPreferencesResponse is how a user wants to be contacted.
+type NotificationPreferencesResponse struct {
+	NhanEmail bool   `json:"nhan_email"`
+	NhanSMS   bool   `json:"nhan_sms"`
+	NgonNgu   string `json:"ngon_ngu"`
+}
+
+// NotificationResponse is a message sent to a user. NoiDung is redacted
+// for sensitive messages once delivered.
+type NotificationResponse struct {
+	MaThongBao int64      `json:"ma_thong_bao"`
+	Kenh       string     `json:"kenh"`
+	NguoiNhan  string     `json:"nguoi_nhan"`
+	MauTin     string     `json:"mau_tin"`
+	TieuDe     string     `json:"tieu_de"`
+	NoiDung    string     `json:"noi_dung"`
+	ThamChieu  *string    `json:"tham_chieu"`
+	TrangThai  string     `json:"trang_thai"`
+	SoLanThu   int        `json:"so_lan_thu"`
+	NgayTao    *time.Time `json:"ngay_tao"`
+	GuiLuc     *time.Time `json:"gui_luc"`
+}
+
+// SupplierResponse is a medicine supplier.
+type SupplierResponse struct {
+	MaNhaCungCap  string `json:"ma_nha_cung_cap"`
+	TenNhaCungCap string `json:"ten_nha_cung_cap"`
+	SoDienThoai   string `json:"so_dien_thoai"`
+	Email         string `json:"email"`
+	DiaChi        string `json:"dia_chi"`
+}
+
+// CreatedSupplierResponse identifies a newly added supplier.
+type CreatedSupplierResponse struct {
+	MaNhaCungCap string `json:"ma_nha_cung_cap"`
+}
+
+// StockResponse is the stock of one medicine at a clinic, by batch.
+type StockResponse struct {
+	MaThuoc     string             `json:"ma_thuoc"`
+	TenThuoc    string             `json:"ten_thuoc"`
+	DonVi       string             `json:"don_vi"`
+	TongSoLuong int                `json:"tong_so_luong"`
+	Lo          []StockLotResponse `json:"lo"`
+}
+
+// StockLotResponse is one batch on hand.
+type StockLotResponse struct {
+	MaLo      int       `json:"ma_lo"`
+	SoLo      string    `json:"so_lo"`
+	HanSuDung time.Time `json:"han_su_dung"`
+	SoLuong   int       `json:"so_luong"`
+	GiaNhap   float64   `json:"gia_nhap"`
+	NgayNhap  time.Time `json:"ngay_nhap"`
+	DaHetHan  bool      `json:"da_het_han"`
+}
+
+// CreatedGoodsReceiptResponse identifies a newly recorded goods receipt.
+type CreatedGoodsReceiptResponse struct {
+	MaPhieuNhap string `json:"ma_phieu_nhap"`
+}
+
+// GoodsReceiptResponse is a goods receipt as listed, with its total cost.
+type GoodsReceiptResponse struct {
+	MaPhieuNhap   string    `json:"ma_phieu_nhap"`
+	MaNhaCungCap  string    `json:"ma_nha_cung_cap"`
+	TenNhaCungCap string    `json:"ten_nha_cung_cap"`
+	NgayNhap      time.Time `json:"ngay_nhap"`
+	NguoiNhap     string    `json:"nguoi_nhap"`
+	GhiChu        string    `json:"ghi_chu"`
+	TongTien      float64   `json:"tong_tien"`
+}
+
+// GoodsReceiptDetailResponse is a goods receipt with its items.
+type GoodsReceiptDetailResponse struct {
+	MaPhieuNhap   string                     `json:"ma_phieu_nhap"`
+	MaPhongKham   string                     `json:"ma_phong_kham"`
+	MaNhaCungCap  string                     `json:"ma_nha_cung_cap"`
+	TenNhaCungCap string                     `json:"ten_nha_cung_cap"`
+	NgayNhap      time.Time                  `json:"ngay_nhap"`
+	NguoiNhap     string                     `json:"nguoi_nhap"`
+	GhiChu        string                     `json:"ghi_chu"`
+	Items         []GoodsReceiptItemResponse `json:"items"`
+}
+
+// GoodsReceiptItemResponse is a batch received on a goods receipt.
+type GoodsReceiptItemResponse struct {
+	MaThuoc   string    `json:"ma_thuoc"`
+	TenThuoc  string    `json:"ten_thuoc"`
+	SoLo      string    `json:"so_lo"`
+	HanSuDung time.Time `json:"han_su_dung"`
+	SoLuong   int       `json:"so_luong"`
+	GiaNhap   float64   `json:"gia_nhap"`
+}
+
+// DispensedLotResponse is the quantity of a batch handed out when
+// dispensing a prescription.
+type DispensedLotResponse struct {
+	MaThuoc   string    `json:"ma_thuoc"`
+	SoLo      string    `json:"so_lo"`
+	HanSuDung time.Time `json:"han_su_dung"`
+	SoLuong   int       `json:"so_luong"`
+}
+
+// StockShortageResponse is a medicine the clinic cannot fully supply.
+type StockShortageResponse struct {
+	MaThuoc  string `json:"ma_thuoc"`
+	Can      int    `json:"can"`
+	ConThieu int    `json:"con_thieu"`
+}
+
+// PharmacyAlertsResponse lists low stock, batches about to expire and
+// expired batches still on the shelf.
+type PharmacyAlertsResponse struct {
+	TonThap   []LowStockResponse   `json:"ton_thap"`
+	SapHetHan []StockBatchResponse `json:"sap_het_han"`
+	DaHetHan  []StockBatchResponse `json:"da_het_han"`
+}
+
+// LowStockResponse is a medicine below the low-stock threshold.
+type LowStockResponse struct {
+	MaThuoc  string `json:"ma_thuoc"`
+	TenThuoc string `json:"ten_thuoc"`
+	TonKho   int    `json:"ton_kho"`
+}
+
+// StockBatchResponse is a batch in a pharmacy alert.
+type StockBatchResponse struct {
+	MaThuoc   string    `json:"ma_thuoc"`
+	TenThuoc  string    `json:"ten_thuoc"`
+	SoLo      string    `json:"so_lo"`
+	HanSuDung time.Time `json:"han_su_dung"`
+	SoLuong   int       `json:"so_luong"`
+}
+
+// StockMovementResponse is an entry of the stock ledger. ThamChieu is the
+// goods receipt or prescription behind the movement.
+type StockMovementResponse struct {
+	ThoiGian      time.Time `json:"thoi_gian"`
+	MaThuoc       string    `json:"ma_thuoc"`
+	TenThuoc      string    `json:"ten_thuoc"`
+	SoLo          string    `json:"so_lo"`
+	Loai          string    `json:"loai"`
+	SoLuong       int       `json:"so_luong"`
+	SoLuongSau    int       `json:"so_luong_sau"`
+	ThamChieu     *string   `json:"tham_chieu"`
+	NguoiThucHien string    `json:"nguoi_thuc_hien"`
+}
+
+// MedicalRecordResponse is a medical record with everything shown on its
+// page. With ?version=N the clinical fields are those of version N and
+// nguoi_sua, thoi_gian_sua and ly_do_sua describe that edit.
+type MedicalRecordResponse struct {
+	MaHoSo          string                       `json:"ma_ho_so"`
+	MaCustomer      string                       `json:"ma_customer"`
+	MaBacSi         string                       `json:"ma_bac_si"`
+	MaPhongKham     string                       `json:"ma_phong_kham"`
+	NgayKham        time.Time                    `json:"ngay_kham"`
+	TrieuChung      *string                      `json:"trieu_chung"`
+	ChanDoan        *string                      `json:"chan_doan"`
+	HuongDanDieuTri *string                      `json:"huong_dan_dieu_tri"`
+	MaICD10         *string                      `json:"ma_icd10"`
+	MaICD10Phu      []ICD10DiagnosisResponse     `json:"ma_icd10_phu"`
+	NgayTaiKham     *time.Time                   `json:"ngay_tai_kham"`
+	TenKhachHang    string                       `json:"ten_khach_hang"`
+	TenBacSi        string                       `json:"ten_bac_si"`
+	TenPhongKham    string                       `json:"ten_phong_kham"`
+	PhienBan        int                          `json:"phien_ban"`
+	PhienBanHienTai int                          `json:"phien_ban_hien_tai"`
+	DaKhoa          bool                         `json:"da_khoa"`
+	DaKy            bool                         `json:"da_ky"`
+	NgayKy          *time.Time                   `json:"ngay_ky"`
+	NguoiKy         *string                      `json:"nguoi_ky"`
+	NguoiSua        string                       `json:"nguoi_sua,omitempty"`
+	ThoiGianSua     *time.Time                   `json:"thoi_gian_sua,omitempty"`
+	LyDoSua         string                       `json:"ly_do_sua,omitempty"`
+	BoSung          []AddendumResponse           `json:"bo_sung"`
+	DiUng           []AllergyResponse            `json:"di_ung"`
+	BenhManTinh     []ChronicConditionResponse   `json:"benh_man_tinh"`
+	DonThuoc        []RecordPrescriptionResponse `json:"don_thuoc"`
+	KetQuaXetNghiem []RecordLabTestResponse      `json:"ket_qua_xet_nghiem"`
+}
+
+// ICD10DiagnosisResponse is a secondary diagnosis of a record.
+type ICD10DiagnosisResponse struct {
+	MaICD10      string `json:"ma_icd10"`
+	TenTiengAnh  string `json:"ten_tieng_anh"`
+	TenTiengViet string `json:"ten_tieng_viet"`
+}
+
+// ICD10DetailResponse is an ICD-10 code with its sub-codes.
+type ICD10DetailResponse struct {
+	Ma    models.ICD10Code   `json:"ma"`
+	MaCon []models.ICD10Code `json:"ma_con"`
+}
+
+// ICD10ChapterResponse is a catalogue chapter with its number of codes.
+type ICD10ChapterResponse struct {
+	Chuong string `json:"chuong"`
+	SoMa   int    `json:"so_ma"`
+}
+
+// ICD10ImportResponse is the number of codes an import loaded.
+type ICD10ImportResponse struct {
+	SoMa int `json:"so_ma"`
+}
+
+// CreatedLabTestResponse identifies a newly ordered lab test.
+type CreatedLabTestResponse struct {
+	MaXetNghiem string `json:"ma_xet_nghiem"`
+}
+
+// AddendumResponse is a note appended to a medical record.
+type AddendumResponse struct {
+	MaBoSung    string    `json:"ma_bo_sung"`
+	NoiDung     string    `json:"noi_dung"`
+	NguoiTao    string    `json:"nguoi_tao"`
+	TenNguoiTao string    `json:"ten_nguoi_tao"`
+	ThoiGian    time.Time `json:"thoi_gian"`
+}
+
+// CreatedAddendumResponse identifies a newly added addendum.
+type CreatedAddendumResponse struct {
+	MaBoSung string `json:"ma_bo_sung"`
+}
+
+// CreatedMedicalRecordResponse identifies a newly created medical record.
+type CreatedMedicalRecordResponse struct {
+	RecordID string `json:"record_id"`
+}
+
+// RecordVersionResponse is the version a record update produced.
+type RecordVersionResponse struct {
+	PhienBan int `json:"phien_ban"`
+}
+
+// RecordVersionSummaryResponse describes one stored version of a record.
+type RecordVersionSummaryResponse struct {
+	PhienBan    int       `json:"phien_ban"`
+	NguoiSua    string    `json:"nguoi_sua"`
+	TenNguoiSua string    `json:"ten_nguoi_sua"`
+	ThoiGian    time.Time `json:"thoi_gian"`
+	LyDo        string    `json:"ly_do"`
+}
+
+// CreatedCustomerResponse identifies a newly created customer account.
+type CreatedCustomerResponse struct {
+	UserID string `json:"user_id"`
+	HoTen  string `json:"ho_ten"`
+}
+
+// CreatedAllergyResponse identifies a newly recorded allergy.
+type CreatedAllergyResponse struct {
+	MaDiUng string `json:"ma_di_ung"`
+}
+
+// CreatedConditionResponse identifies a newly recorded chronic condition.
+type CreatedConditionResponse struct {
+	MaBenhManTinh string `json:"ma_benh_man_tinh"`
+}
+
+// RecordPrescriptionResponse is a prescription as summarized on a record.
+type RecordPrescriptionResponse struct {
+	MaDonThuoc string                           `json:"ma_don_thuoc"`
+	NgayKeDon  *time.Time                       `json:"ngay_ke_don"`
+	GhiChu     string                           `json:"ghi_chu"`
+	Thuoc      []RecordPrescriptionItemResponse `json:"thuoc"`
+}
+
+type RecordPrescriptionItemResponse struct {
+	TenThuoc string `json:"ten_thuoc"`
+	SoLuong  int    `json:"so_luong"`
+	CachDung string `json:"cach_dung"`
+	GhiChu   string `json:"ghi_chu"`
+}
+
+// RecordLabTestResponse is a lab test as summarized on a record.
+type RecordLabTestResponse struct {
+	MaXetNghiem   string     `json:"ma_xet_nghiem"`
+	LoaiXetNghiem string     `json:"loai_xet_nghiem"`
+	NgayXetNghiem *time.Time `json:"ngay_xet_nghiem"`
+	KetQua        string     `json:"ket_qua"`
+	GhiChu        string     `json:"ghi_chu"`
+	FileDinhKem   string     `json:"file_dinh_kem"`
+}
+
+// AllergyResponse is an allergy of a patient.
+type AllergyResponse struct {
+	MaDiUng         string    `json:"ma_di_ung"`
+	TacNhan         string    `json:"tac_nhan"`
+	LoaiTacNhan     string    `json:"loai_tac_nhan"`
+	MaThuoc         string    `json:"ma_thuoc"`
+	PhanUng         string    `json:"phan_ung"`
+	MucDo           string    `json:"muc_do"`
+	GhiChu          string    `json:"ghi_chu"`
+	TrangThai       string    `json:"trang_thai"`
+	NguoiGhiNhan    string    `json:"nguoi_ghi_nhan"`
+	TenNguoiGhiNhan string    `json:"ten_nguoi_ghi_nhan"`
+	NgayGhiNhan     time.Time `json:"ngay_ghi_nhan"`
+}
+
+// ChronicConditionResponse is a chronic condition of a patient.
+type ChronicConditionResponse struct {
+	MaBenhManTinh   string     `json:"ma_benh_man_tinh"`
+	TenBenh         string     `json:"ten_benh"`
+	MaICD10         string     `json:"ma_icd10"`
+	TenICD10        string     `json:"ten_icd10"`
+	NgayChanDoan    *time.Time `json:"ngay_chan_doan"`
+	TrangThai       string     `json:"trang_thai"`
+	GhiChu          string     `json:"ghi_chu"`
+	NguoiGhiNhan    string     `json:"nguoi_ghi_nhan"`
+	TenNguoiGhiNhan string     `json:"ten_nguoi_ghi_nhan"`
+	NgayGhiNhan     time.Time  `json:"ngay_ghi_nhan"`
+}
+
+// MedicationResponse is a medicine of the formulary.
+type MedicationResponse struct {
+	MaThuoc    string  `json:"ma_thuoc"`
+	TenThuoc   string  `json:"ten_thuoc"`
+	HoatChat   string  `json:"hoat_chat"`
+	HamLuong   string  `json:"ham_luong"`
+	DangBaoChe string  `json:"dang_bao_che"`
+	DonVi      string  `json:"don_vi"`
+	Gia        float64 `json:"gia"`
+	CongDung   string  `json:"cong_dung"`
+	LieuLuong  string  `json:"lieu_luong"`
+	TrangThai  string  `json:"trang_thai"`
+}
+
+// MedicationDetailResponse is a medicine with its price history.
+type MedicationDetailResponse struct {
+	MedicationResponse
+	LichSuGia []MedicationPriceResponse `json:"lich_su_gia"`
+}
+
+// MedicationPriceResponse is the price of a medicine from tu_ngay until
+// den_ngay; the current price has no den_ngay.
+type MedicationPriceResponse struct {
+	Gia          float64    `json:"gia"`
+	TuNgay       time.Time  `json:"tu_ngay"`
+	DenNgay      *time.Time `json:"den_ngay"`
+	NguoiCapNhat string     `json:"nguoi_cap_nhat"`
+}
+
+// CreatedMedicationResponse identifies a medicine added to the formulary.
+type CreatedMedicationResponse struct {
+	MaThuoc string `json:"ma_thuoc"`
+}
+
+// UnavailableMedicationsResponse lists the medicines of a rejected
+// prescription that are unknown or discontinued.
+type UnavailableMedicationsResponse struct {
+	KhongCoTrongDanhMuc []string `json:"khong_co_trong_danh_muc"`
+	NgungSuDung         []string `json:"ngung_su_dung"`
+}
+
+// InteractionResponse is a drug interaction rule between two ingredients.
+type InteractionResponse struct {
+	HoatChat1  string `json:"hoat_chat_1"`
+	HoatChat2  string `json:"hoat_chat_2"`
+	MucDo      string `json:"muc_do"`
+	MoTa       string `json:"mo_ta"`
+	KhuyenNghi string `json:"khuyen_nghi"`
+}
+
+// InteractionImportResponse is the number of rules an import loaded.
+type InteractionImportResponse struct {
+	SoTuongTac int `json:"so_tuong_tac"`
+}
+
+// PatientSummaryResponse is a patient's history at a glance.
+type PatientSummaryResponse struct {
+	BenhNhan         SummaryPatientResponse       `json:"benh_nhan"`
+	DiUng            []AllergyResponse            `json:"di_ung"`
+	BenhManTinh      []ChronicConditionResponse   `json:"benh_man_tinh"`
+	LichSuKham       []SummaryVisitResponse       `json:"lich_su_kham"`
+	ChanDoanTaiDien  []RecurringDiagnosisResponse `json:"chan_doan_tai_dien"`
+	ThuocDangDung    []SummaryMedicationResponse  `json:"thuoc_dang_dung"`
+	ThuocDaDung      []SummaryMedicationResponse  `json:"thuoc_da_dung"`
+	XetNghiemGanNhat []SummaryLabResultResponse   `json:"xet_nghiem_gan_nhat"`
+	TaiKhamSapToi    []UpcomingFollowUpResponse   `json:"tai_kham_sap_toi"`
+}
+
+// SummaryPatientResponse identifies the patient of a summary.
+type SummaryPatientResponse struct {
+	MaCustomer string     `json:"ma_customer"`
+	HoTen      string     `json:"ho_ten"`
+	NgaySinh   *time.Time `json:"ngay_sinh"`
+	GioiTinh   *string    `json:"gioi_tinh"`
+	MaBaoHiem  *string    `json:"ma_bao_hiem"`
+}
+
+// SummaryVisitResponse is one visit of a patient's timeline.
+type SummaryVisitResponse struct {
+	MaHoSo       string     `json:"ma_ho_so"`
+	NgayKham     time.Time  `json:"ngay_kham"`
+	MaBacSi      string     `json:"ma_bac_si"`
+	TenBacSi     string     `json:"ten_bac_si"`
+	TenPhongKham string     `json:"ten_phong_kham"`
+	ChanDoan     string     `json:"chan_doan"`
+	MaICD10      string     `json:"ma_icd10"`
+	NgayTaiKham  *time.Time `json:"ngay_tai_kham"`
+}
+
+// RecurringDiagnosisResponse is an ICD-10 code diagnosed at so_lan visits,
+// the last one at lan_cuoi.
+type RecurringDiagnosisResponse struct {
+	MaICD10      string    `json:"ma_icd10"`
+	TenTiengAnh  string    `json:"ten_tieng_anh"`
+	TenTiengViet string    `json:"ten_tieng_viet"`
+	SoLan        int       `json:"so_lan"`
+	LanCuoi      time.Time `json:"lan_cuoi"`
+}
+
+// SummaryMedicationResponse is a medicine prescribed to the patient.
+type SummaryMedicationResponse struct {
+	MaDonThuoc string     `json:"ma_don_thuoc"`
+	NgayHetHan *time.Time `json:"ngay_het_han"`
+	MaThuoc    string     `json:"ma_thuoc"`
+	TenThuoc   string     `json:"ten_thuoc"`
+	SoLuong    int        `json:"so_luong"`
+	CachDung   string     `json:"cach_dung"`
+	MaHoSo     string     `json:"ma_ho_so"`
+	NgayKeDon  time.Time  `json:"ngay_ke_don"`
+	TenBacSi   string     `json:"ten_bac_si"`
+}
+
+// SummaryLabResultResponse is the latest result of a test type.
+type SummaryLabResultResponse struct {
+	MaXetNghiem   string     `json:"ma_xet_nghiem"`
+	MaHoSo        string     `json:"ma_ho_so"`
+	LoaiXetNghiem string     `json:"loai_xet_nghiem"`
+	NgayXetNghiem *time.Time `json:"ngay_xet_nghiem"`
+	KetQua        string     `json:"ket_qua"`
+}
+
+// UpcomingFollowUpResponse is a follow-up date set on record ma (loai
+// TAI_KHAM) or a booked appointment ma (loai LICH_KHAM).
+type UpcomingFollowUpResponse struct {
+	Loai     string    `json:"loai"`
+	Ma       string    `json:"ma"`
+	Ngay     time.Time `json:"ngay"`
+	MaBacSi  string    `json:"ma_bac_si"`
+	TenBacSi string    `json:"ten_bac_si"`
+}
+
+// LoginAttemptResponse is one sign-in attempt; ma_user is empty when the
+// username matched no account.
+type LoginAttemptResponse struct {
+	MaLichSu    int64     `json:"ma_lich_su"`
+	TenDangNhap string    `json:"ten_dang_nhap"`
+	MaUser      *string   `json:"ma_user"`
+	DiaChiIP    string    `json:"dia_chi_ip"`
+	UserAgent   *string   `json:"user_agent"`
+	KetQua      string    `json:"ket_qua"`
+	ThoiGian    time.Time `json:"thoi_gian"`
+}
+
+// TwoFactorChallengeResponse is returned by login when the password was
+// right but a second factor is needed: a code for challenge_token, or
+// enrolment first when can_dang_ky_2fa is set. het_han_sau is in seconds.
+type TwoFactorChallengeResponse struct {
+	YeuCau2FA      bool   `json:"yeu_cau_2fa"`
+	CanDangKy2FA   bool   `json:"can_dang_ky_2fa"`
+	ChallengeToken string `json:"challenge_token"`
+	HetHanSau      int    `json:"het_han_sau"`
+}
+
+// RegisterResponse identifies a newly registered customer.
+type RegisterResponse struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// TwoFactorStatusResponse is whether a user has 2FA on, must have it on,
+// and how many unused recovery codes are left.
+type TwoFactorStatusResponse struct {
+	DaBat              bool `json:"da_bat"`
+	BatBuoc            bool `json:"bat_buoc"`
+	SoMaKhoiPhucConLai int  `json:"so_ma_khoi_phuc_con_lai"`
+}
+
+// TwoFactorSecretResponse is a new TOTP secret to add to an authenticator
+// app, as text or as an otpauth URI for a QR code.
+type TwoFactorSecretResponse struct {
+	BiMat      string `json:"bi_mat"`
+	OtpauthURI string `json:"otpauth_uri"`
+	SoChuSo    int    `json:"so_chu_so"`
+	ChuKyGiay  int    `json:"chu_ky_giay"`
+}
+
+// RecoveryCodesResponse holds recovery codes, shown only once.
+type RecoveryCodesResponse struct {
+	MaKhoiPhuc []string `json:"ma_khoi_phuc"`
+}
+
+// SessionResponse is a device the user is signed in on; hien_tai marks the
+// one making the request.
+type SessionResponse struct {
+	MaPhien         string    `json:"ma_phien"`
+	ThietBi         string    `json:"thiet_bi"`
+	DiaChiIP        string    `json:"dia_chi_ip"`
+	NgayTao         time.Time `json:"ngay_tao"`
+	LanCuoiHoatDong time.Time `json:"lan_cuoi_hoat_dong"`
+	HetHanLuc       time.Time `json:"het_han_luc"`
+	HienTai         bool      `json:"hien_tai"`
+}
+
+// RevokedSessionsResponse is how many sessions were signed out.
+type RevokedSessionsResponse struct {
+	SoPhienDaThuHoi int64 `json:"so_phien_da_thu_hoi"`
+}
+
+// StaffSummaryResponse is a staff account as listed; ma_phong_kham is set
+// for receptionists and clinic managers.
+type StaffSummaryResponse struct {
+	MaUser      string    `json:"ma_user"`
+	HoTen       string    `json:"ho_ten"`
+	SoDienThoai *string   `json:"so_dien_thoai"`
+	Email       *string   `json:"email"`
+	TenDangNhap string    `json:"ten_dang_nhap"`
+	TrangThai   string    `json:"trang_thai"`
+	NgayTao     time.Time `json:"ngay_tao"`
+	Role        string    `json:"role"`
+	MaPhongKham *string   `json:"ma_phong_kham"`
+}
+
+// CreatedStaffResponse is a new staff account, as GET /admin/staff/:id
+// returns it, and whether the invitation email went out.
+type CreatedStaffResponse struct {
+	NhanVien    interface{} `json:"nhan_vien"`
+	DaGuiLoiMoi bool        `json:"da_gui_loi_moi"`
+}
+
+// StaffAssignmentResponse is the clinic a staff member now works at.
+type StaffAssignmentResponse struct {
+	MaUser      string `json:"ma_user"`
+	MaPhongKham string `json:"ma_phong_kham"`
+}
+
+// ClinicDoctorsResponse lists the doctors working at a clinic. For a
+// customer, those they have seen there before come first in all_doctors.
+type ClinicDoctorsResponse struct {
+	AllDoctors        []models.Doctor `json:"all_doctors"`
+	PreviouslyVisited []models.Doctor `json:"previously_visited"`
+	OtherDoctors      []models.Doctor `json:"other_doctors"`
+}
+
+// ClinicSlotsResponse is a doctor's free hourly slots on a day. The work
+// schedule is null when the doctor does not work that day.
+type ClinicSlotsResponse struct {
+	WorkSchedule   *WorkHoursResponse `json:"work_schedule"`
+	AvailableSlots []string           `json:"available_slots"`
+	BookedTimes    []string           `json:"booked_times"`
+}
+
+// WorkHoursResponse is the start and end of a work schedule.
+type WorkHoursResponse struct {
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// CreatedClinicResponse identifies a new clinic.
+type CreatedClinicResponse struct {
+	MaPhongKham string `json:"ma_phong_kham"`
+}
+
+// CreatedRoomResponse identifies a new room.
+type CreatedRoomResponse struct {
+	MaPhong string `json:"ma_phong"`
+}
+
+// CreatedEquipmentResponse identifies new equipment.
+type CreatedEquipmentResponse struct {
+	MaThietBi string `json:"ma_thiet_bi"`
+}
+
+// RoomUsageResponse is what is booked in a room on one day (YYYY-MM-DD).
+type RoomUsageResponse struct {
+	MaPhong     string                    `json:"ma_phong"`
+	Ngay        string                    `json:"ngay"`
+	LichLamViec []RoomScheduleResponse    `json:"lich_lam_viec"`
+	LichKham    []RoomAppointmentResponse `json:"lich_kham"`
+}
+
+// RoomScheduleResponse is a work schedule in a room; times are HH:MM.
+type RoomScheduleResponse struct {
+	MaLichLamViec string `json:"ma_lich_lam_viec"`
+	MaBacSi       string `json:"ma_bac_si"`
+	TenBacSi      string `json:"ten_bac_si"`
+	GioBatDau     string `json:"gio_bat_dau"`
+	GioKetThuc    string `json:"gio_ket_thuc"`
+	Status        string `json:"status"`
+}
+
+// RoomAppointmentResponse is an active appointment in a room.
+type RoomAppointmentResponse struct {
+	MaLichKham  string    `json:"ma_lich_kham"`
+	MaBacSi     string    `json:"ma_bac_si"`
+	NgayGioKham time.Time `json:"ngay_gio_kham"`
+	TrangThai   string    `json:"trang_thai"`
+}
+
+// CreatedAppointmentResponse identifies a new appointment.
+type CreatedAppointmentResponse struct {
+	AppointmentID string `json:"appointment_id"`
+}
+
+// CreatedScheduleResponse identifies a new work schedule.
+type CreatedScheduleResponse struct {
+	MaLichLamViec string `json:"ma_lich_lam_viec"`
+}
+
+// AppointmentLinkResponse is the appointment a reminder link points to
+// and what the link does to it (hanh_dong).
+type AppointmentLinkResponse struct {
+	MaLichKham   string    `json:"ma_lich_kham"`
+	NgayGioKham  time.Time `json:"ngay_gio_kham"`
+	TrangThai    string    `json:"trang_thai"`
+	TenBacSi     string    `json:"ten_bac_si"`
+	TenPhongKham string    `json:"ten_phong_kham"`
+	HanhDong     string    `json:"hanh_dong"`
+}
+
+// AppointmentStatusResponse is the new status of an appointment.
+type AppointmentStatusResponse struct {
+	MaLichKham string `json:"ma_lich_kham"`
+	TrangThai  string `json:"trang_thai"`
+}
+
+// FollowUpResponse is a follow-up visit set on a record, with the
+// appointment booked for it; ma_lich_kham is empty when none is.
+// ngay_tai_kham is YYYY-MM-DD.
+type FollowUpResponse struct {
+	MaTaiKham         string     `json:"ma_tai_kham"`
+	MaHoSo            string     `json:"ma_ho_so"`
+	NgayTaiKham       string     `json:"ngay_tai_kham"`
+	TrangThai         string     `json:"trang_thai"`
+	MaLichKham        string     `json:"ma_lich_kham"`
+	NgayGioKham       *time.Time `json:"ngay_gio_kham"`
+	TrangThaiLichKham string     `json:"trang_thai_lich_kham"`
+	MaCustomer        string     `json:"ma_customer"`
+	TenKhachHang      string     `json:"ten_khach_hang"`
+	MaBacSi           string     `json:"ma_bac_si"`
+	TenBacSi          string     `json:"ten_bac_si"`
+	MaPhongKham       string     `json:"ma_phong_kham"`
+	TenPhongKham      string     `json:"ten_phong_kham"`
+}
+
+// FollowUpAppointmentResponse is the appointment of a follow-up after it
+// was confirmed, or rescheduled to ngay_gio_kham.
+type FollowUpAppointmentResponse struct {
+	MaTaiKham   string     `json:"ma_tai_kham"`
+	MaLichKham  string     `json:"ma_lich_kham"`
+	NgayGioKham *time.Time `json:"ngay_gio_kham,omitempty"`
+}
+
+// MissedFollowUpsResponse reports the follow-ups missed between tu_ngay
+// and den_ngay (YYYY-MM-DD), with a count per doctor.
+type MissedFollowUpsResponse struct {
+	TuNgay    string                   `json:"tu_ngay"`
+	DenNgay   string                   `json:"den_ngay"`
+	TongSo    int                      `json:"tong_so"`
+	TheoBacSi []MissedByDoctorResponse `json:"theo_bac_si"`
+	DanhSach  []MissedFollowUpResponse `json:"danh_sach"`
+}
+
+// MissedByDoctorResponse is how many follow-ups of a doctor were missed.
+type MissedByDoctorResponse struct {
+	MaBacSi  string `json:"ma_bac_si"`
+	TenBacSi string `json:"ten_bac_si"`
+	SoLuong  int    `json:"so_luong"`
+}
+
+// MissedFollowUpResponse is a missed follow-up with the patient's phone
+// number, to call them back.
+type MissedFollowUpResponse struct {
+	MaTaiKham    string `json:"ma_tai_kham"`
+	MaHoSo       string `json:"ma_ho_so"`
+	NgayTaiKham  string `json:"ngay_tai_kham"`
+	MaLichKham   string `json:"ma_lich_kham"`
+	MaCustomer   string `json:"ma_customer"`
+	TenKhachHang string `json:"ten_khach_hang"`
+	SoDienThoai  string `json:"so_dien_thoai"`
+	MaBacSi      string `json:"ma_bac_si"`
+	TenBacSi     string `json:"ten_bac_si"`
+	MaPhongKham  string `json:"ma_phong_kham"`
+	TenPhongKham string `json:"ten_phong_kham"`
+}
+
+func stringPointer(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func timePointer(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
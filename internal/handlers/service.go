@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// viewerOf is the authenticated user of the request as services see it.
+func viewerOf(c *gin.Context) services.Viewer {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+	id, _ := userID.(string)
+	role, _ := userType.(string)
+	return services.Viewer{UserID: id, Role: role}
+}
+
+// serviceFailed writes the response for an error returned by a service:
+// the rule's own message for violations, message and the error otherwise.
+func serviceFailed(c *gin.Context, err error, message string) {
+	var ruleErr *services.Error
+	if errors.As(err, &ruleErr) {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, services.ErrConflict):
+			status = http.StatusConflict
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: ruleErr.Message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
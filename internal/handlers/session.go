@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler lets users see the devices they are signed in on and
+// sign them out.
+type SessionHandler struct {
+	sessions *session.Store
+}
+
+func NewSessionHandler(sessions *session.Store) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentID, _ := c.Get("session_id")
+
+	sessions, err := h.sessions.List(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving sessions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	result := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, SessionResponse{
+			MaPhien:         s.ID,
+			ThietBi:         s.Device,
+			DiaChiIP:        s.IP,
+			NgayTao:         s.CreatedAt,
+			LanCuoiHoatDong: s.LastActiveAt,
+			HetHanLuc:       s.ExpiresAt,
+			HienTai:         s.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    result,
+	})
+}
+
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	revoked, err := h.sessions.Revoke(c.Param("id"), userID.(string), session.RevokedByUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to revoke session",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Session not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Session revoked successfully",
+	})
+}
+
+// RevokeOtherSessions signs out every device except the current one.
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentID, _ := c.Get("session_id")
+
+	count, err := h.sessions.RevokeUser(userID.(string), currentID.(string), session.RevokedByUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to revoke sessions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Other sessions revoked successfully",
+		Data:    RevokedSessionsResponse{SoPhienDaThuHoi: count},
+	})
+}
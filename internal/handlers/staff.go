@@ -0,0 +1,1128 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/notification"
+	"clinic-management/internal/services"
+	"clinic-management/internal/session"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StaffHandler lets operation managers administer the accounts of every
+// role but customers. A new staff member is INVITED: they get an email
+// with a link to set their password, and cannot log in until they do.
+type StaffHandler struct {
+	db       *sql.DB
+	notifier notification.Notifier
+	sessions *session.Store
+	invite   InvitationSettings
+}
+
+// InvitationSettings configure staff invitations. Links point to
+// LinkBaseURL/accept-invitation, are signed with LinkSecret and expire
+// after Validity.
+type InvitationSettings struct {
+	LinkSecret  string
+	LinkBaseURL string
+	Validity    time.Duration
+}
+
+const invitationTokenPrefix = "invitation:"
+
+type ReassignStaffRequest struct {
+	MaPhongKham string `json:"ma_phong_kham" binding:"required"`
+}
+
+// staffRole describes the table holding a role's details. columns maps the
+// JSON fields of models.StaffFields the role uses to their columns; clinic
+// roles also belong to one clinic through maPhongKham.
+type staffRole struct {
+	table   string
+	clinic  bool
+	columns map[string]string
+}
+
+var staffRoles = map[string]staffRole{
+	"DOCTOR": {table: "BACSI", columns: map[string]string{
+		"chuyen_khoa":            "chuyenKhoa",
+		"nam_kinh_nghiem":        "namKinhNghiem",
+		"bang_cap":               "bangCap",
+		"so_giay_phep_hanh_nghe": "maGiayPhep",
+	}},
+	"RECEPTIONIST": {table: "LETAN", clinic: true, columns: map[string]string{
+		"luong_co_ban": "luongCoBan",
+		"ngay_vao_lam": "ngayVaoLam",
+	}},
+	"ACCOUNTANT": {table: "KETOAN", columns: map[string]string{
+		"luong_co_ban": "luongCoBan",
+		"ngay_vao_lam": "ngayVaoLam",
+		"chuyen_mon":   "chuyenMon",
+	}},
+	"CLINIC_MANAGER": {table: "QUANLYPHONGKHAM", clinic: true, columns: map[string]string{
+		"luong_co_ban": "luongCoBan",
+		"ngay_vao_lam": "ngayVaoLam",
+	}},
+	"OPERATION_MANAGER": {table: "BANDIEUHANH", columns: map[string]string{
+		"chuc_vu":           "chucVu",
+		"khu_vuc_phu_trach": "khuVucPhuTrach",
+		"luong_co_ban":      "luongCoBan",
+		"ngay_vao_lam":      "ngayVaoLam",
+	}},
+}
+
+func NewStaffHandler(db *sql.DB, notifier notification.Notifier, sessions *session.Store, invite InvitationSettings) *StaffHandler {
+	invite.LinkBaseURL = strings.TrimRight(invite.LinkBaseURL, "/")
+	return &StaffHandler{db: db, notifier: notifier, sessions: sessions, invite: invite}
+}
+
+// GetStaff lists staff accounts, optionally filtered by role, trang_thai,
+// ma_phong_kham or tu_khoa (name, username or email).
+func (h *StaffHandler) GetStaff(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	query := `
+		SELECT u.userID, u.hoTen, u.soDienThoai, u.email, u.username, u.status, u.createdAt, u.role,
+		       COALESCE(l.maPhongKham, q.maPhongKham)
+		FROM [USER] u
+		LEFT JOIN LETAN l ON l.maUser = u.userID
+		LEFT JOIN QUANLYPHONGKHAM q ON q.maUser = u.userID
+		WHERE u.role <> 'CUSTOMER'`
+	var args []interface{}
+	if role := strings.ToUpper(c.Query("role")); role != "" {
+		args = append(args, role)
+		query += fmt.Sprintf(" AND u.role = @p%d", len(args))
+	}
+	if status := strings.ToUpper(c.Query("trang_thai")); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND u.status = @p%d", len(args))
+	}
+	if clinicID := c.Query("ma_phong_kham"); clinicID != "" {
+		args = append(args, clinicID)
+		query += fmt.Sprintf(" AND COALESCE(l.maPhongKham, q.maPhongKham) = @p%d", len(args))
+	}
+	if keyword := strings.TrimSpace(c.Query("tu_khoa")); keyword != "" {
+		args = append(args, "%"+keyword+"%")
+		query += fmt.Sprintf(" AND (u.hoTen LIKE @p%[1]d OR u.username LIKE @p%[1]d OR u.email LIKE @p%[1]d)", len(args))
+	}
+	query += " ORDER BY u.role, u.hoTen"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving staff",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	staff := []StaffSummaryResponse{}
+	for rows.Next() {
+		var user models.User
+		var clinicID sql.NullString
+		if err := rows.Scan(&user.MaUser, &user.HoTen, &user.SoDienThoai, &user.Email, &user.TenDangNhap,
+			&user.TrangThai, &user.NgayTao, &user.Role, &clinicID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Message: "Error scanning staff",
+				Error:   err.Error(),
+			})
+			return
+		}
+		staff = append(staff, StaffSummaryResponse{
+			MaUser:      user.MaUser,
+			HoTen:       user.HoTen,
+			SoDienThoai: user.SoDienThoai,
+			Email:       user.Email,
+			TenDangNhap: user.TenDangNhap,
+			TrangThai:   user.TrangThai,
+			NgayTao:     user.NgayTao,
+			Role:        user.Role,
+			MaPhongKham: stringPointer(clinicID),
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Staff retrieved successfully",
+		Data:    staff,
+	})
+}
+
+func (h *StaffHandler) GetStaffMember(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	staff, err := h.loadStaff(c.Param("id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Staff member not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Staff member retrieved successfully",
+		Data:    staff,
+	})
+}
+
+// CreateStaff creates the account and its role details in one transaction
+// and emails the invitation.
+func (h *StaffHandler) CreateStaff(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	var req models.CreateStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	roleName := strings.ToUpper(strings.TrimSpace(req.Role))
+	role, ok := staffRoles[roleName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Role must be one of DOCTOR, RECEPTIONIST, ACCOUNTANT, CLINIC_MANAGER, OPERATION_MANAGER",
+		})
+		return
+	}
+
+	req.HoTen = strings.TrimSpace(req.HoTen)
+	req.TenDangNhap = strings.TrimSpace(req.TenDangNhap)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.HoTen == "" || req.TenDangNhap == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Name and username are required",
+		})
+		return
+	}
+	if !utils.ValidateEmail(req.Email) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid email format",
+		})
+		return
+	}
+	if req.SoDienThoai != nil && *req.SoDienThoai != "" && !utils.ValidatePhoneNumber(*req.SoDienThoai) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid phone number format",
+		})
+		return
+	}
+
+	columns, values, err := staffColumns(role, req.StaffFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	// Salaried roles start with a base salary of 0 and today as their
+	// first day unless told otherwise.
+	defaults := map[string]interface{}{"luongCoBan": 0.0, "ngayVaoLam": today()}
+	for _, column := range []string{"luongCoBan", "ngayVaoLam"} {
+		if roleHasColumn(role, column) && !containsString(columns, column) {
+			columns = append(columns, column)
+			values = append(values, defaults[column])
+		}
+	}
+
+	if role.clinic {
+		if req.MaPhongKham == nil || *req.MaPhongKham == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "ma_phong_kham is required for this role",
+			})
+			return
+		}
+		if !h.checkClinic(c, *req.MaPhongKham) {
+			return
+		}
+		columns = append(columns, "maPhongKham")
+		values = append(values, *req.MaPhongKham)
+	} else if req.MaPhongKham != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "ma_phong_kham does not apply to this role",
+		})
+		return
+	}
+
+	var existing string
+	err = h.db.QueryRow("SELECT userID FROM [USER] WHERE username = @p1", req.TenDangNhap).Scan(&existing)
+	if err == nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Username already exists",
+		})
+		return
+	}
+	err = h.db.QueryRow("SELECT userID FROM [USER] WHERE email = @p1", req.Email).Scan(&existing)
+	if err == nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Email already exists",
+		})
+		return
+	}
+
+	// The account gets a random password nobody knows; the invitation is
+	// the only way to set a usable one.
+	unusable := make([]byte, 32)
+	if _, err := rand.Read(unusable); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create staff account",
+			Error:   err.Error(),
+		})
+		return
+	}
+	passwordHash, err := utils.HashPassword(hex.EncodeToString(unusable))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create staff account",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	userID, err := utils.GenerateUserID(roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create staff account",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var phone interface{}
+	if req.SoDienThoai != nil {
+		phone = services.NullIfEmpty(*req.SoDienThoai)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO [USER] (userID, HoTen, SoDienThoai, Email, username, password, status, createdAt, role)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, 'INVITED', GETDATE(), @p7)
+	`, userID, req.HoTen, phone, req.Email, req.TenDangNhap, passwordHash, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create user account",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	placeholders := []string{"@p1"}
+	for i := range columns {
+		placeholders = append(placeholders, fmt.Sprintf("@p%d", i+2))
+	}
+	_, err = tx.Exec(
+		fmt.Sprintf("INSERT INTO %s (maUser, %s) VALUES (%s)", role.table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")),
+		append([]interface{}{userID}, values...)...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create staff profile",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	resetID, expiresAt, err := h.createInvitation(tx, userID, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create invitation",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create staff account",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	sent := h.sendInvitation(c, userID, roleName, req.TenDangNhap, resetID, expiresAt)
+
+	staff, err := h.loadStaff(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Staff account created but could not be loaded",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Staff account created successfully",
+		Data:    CreatedStaffResponse{NhanVien: staff, DaGuiLoiMoi: sent},
+	})
+}
+
+// UpdateStaff changes contact details and role-specific fields. Clinic
+// assignment is changed with ReassignStaff.
+func (h *StaffHandler) UpdateStaff(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	userID := c.Param("id")
+	roleName, _, ok := h.findStaff(c, userID)
+	if !ok {
+		return
+	}
+	role := staffRoles[roleName]
+
+	var req models.UpdateStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	columns, values, err := staffColumns(role, req.StaffFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var userColumns []string
+	var userValues []interface{}
+	if req.HoTen != nil {
+		if strings.TrimSpace(*req.HoTen) == "" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Name cannot be empty",
+			})
+			return
+		}
+		userColumns = append(userColumns, "hoTen")
+		userValues = append(userValues, strings.TrimSpace(*req.HoTen))
+	}
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if !utils.ValidateEmail(email) {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid email format",
+			})
+			return
+		}
+		var existing string
+		err := h.db.QueryRow("SELECT userID FROM [USER] WHERE email = @p1 AND userID <> @p2", email, userID).Scan(&existing)
+		if err == nil {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Message: "Email already exists",
+			})
+			return
+		}
+		userColumns = append(userColumns, "email")
+		userValues = append(userValues, email)
+	}
+	if req.SoDienThoai != nil {
+		if *req.SoDienThoai != "" && !utils.ValidatePhoneNumber(*req.SoDienThoai) {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid phone number format",
+			})
+			return
+		}
+		userColumns = append(userColumns, "soDienThoai")
+		userValues = append(userValues, services.NullIfEmpty(*req.SoDienThoai))
+	}
+
+	if len(columns) == 0 && len(userColumns) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := updateColumns(tx, "[USER]", "userID", userID, userColumns, userValues); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update user account",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := updateColumns(tx, role.table, "maUser", userID, columns, values); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update staff profile",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	staff, err := h.loadStaff(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Staff member updated but could not be loaded",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Staff member updated successfully",
+		Data:    staff,
+	})
+}
+
+// ReassignStaff moves a receptionist or clinic manager to another clinic.
+// Doctors are assigned to clinics through their work schedules.
+func (h *StaffHandler) ReassignStaff(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	userID := c.Param("id")
+	roleName, _, ok := h.findStaff(c, userID)
+	if !ok {
+		return
+	}
+	role := staffRoles[roleName]
+	if !role.clinic {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Only receptionists and clinic managers are assigned to a clinic",
+		})
+		return
+	}
+
+	var req ReassignStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !h.checkClinic(c, req.MaPhongKham) {
+		return
+	}
+
+	_, err := h.db.Exec(fmt.Sprintf("UPDATE %s SET maPhongKham = @p1 WHERE maUser = @p2", role.table), req.MaPhongKham, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to reassign staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Staff member reassigned successfully",
+		Data:    StaffAssignmentResponse{MaUser: userID, MaPhongKham: req.MaPhongKham},
+	})
+}
+
+// DeactivateStaff blocks the account, cancels its pending invitation and
+// signs it out everywhere.
+func (h *StaffHandler) DeactivateStaff(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	userID := c.Param("id")
+	currentUser, _ := c.Get("user_id")
+	if userID == currentUser.(string) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "You cannot deactivate your own account",
+		})
+		return
+	}
+	_, status, ok := h.findStaff(c, userID)
+	if !ok {
+		return
+	}
+	if status == "INACTIVE" {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Staff member is already inactive",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE [USER] SET status = 'INACTIVE' WHERE userID = @p1", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to deactivate staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := tx.Exec("UPDATE PASSWORD_RESET SET IsUsed = 1 WHERE UserID = @p1 AND IsUsed = 0", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to cancel pending invitation",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if _, err := h.sessions.RevokeUserTx(tx, userID, "", session.RevokedDeactivated); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to revoke sessions",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to deactivate staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Staff member deactivated successfully",
+	})
+}
+
+// ActivateStaff reactivates a deactivated account. Someone who never
+// accepted their invitation can set a password with forgot-password.
+func (h *StaffHandler) ActivateStaff(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	userID := c.Param("id")
+	_, status, ok := h.findStaff(c, userID)
+	if !ok {
+		return
+	}
+	if status != "INACTIVE" {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Staff member is not inactive",
+		})
+		return
+	}
+
+	if _, err := h.db.Exec("UPDATE [USER] SET status = 'ACTIVE' WHERE userID = @p1", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to activate staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Staff member activated successfully",
+	})
+}
+
+// ResendInvitation replaces a pending invitation with a new one.
+func (h *StaffHandler) ResendInvitation(c *gin.Context) {
+	if !isOperationManager(c, "Only operation managers can manage staff") {
+		return
+	}
+
+	userID := c.Param("id")
+	var roleName, username, status string
+	var email sql.NullString
+	err := h.db.QueryRow(`
+		SELECT role, username, email, status FROM [USER] WHERE userID = @p1 AND role <> 'CUSTOMER'
+	`, userID).Scan(&roleName, &username, &email, &status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Staff member not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving staff member",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if status != "INVITED" || !email.Valid {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Staff member has no pending invitation",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	resetID, expiresAt, err := h.createInvitation(tx, userID, email.String)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create invitation",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !h.sendInvitation(c, userID, roleName, username, resetID, expiresAt) {
+		c.JSON(http.StatusBadGateway, models.APIResponse{
+			Success: false,
+			Message: "Invitation created but could not be sent",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Invitation sent successfully",
+	})
+}
+
+// AcceptInvitation sets the password of an invited account from the
+// invitation link and activates it.
+func (h *StaffHandler) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if len(req.MatKhau) < 6 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Password must be at least 6 characters long",
+		})
+		return
+	}
+
+	payload, err := utils.VerifyToken(h.invite.LinkSecret, req.Token)
+	if err != nil || !strings.HasPrefix(payload, invitationTokenPrefix) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid or expired invitation",
+		})
+		return
+	}
+
+	var resetID, userID string
+	err = h.db.QueryRow(`
+		SELECT pr.ID, pr.UserID
+		FROM PASSWORD_RESET pr
+		JOIN [USER] u ON pr.UserID = u.userID
+		WHERE pr.ID = @p1 AND pr.IsUsed = 0 AND pr.ExpiresAt > GETDATE() AND u.status = 'INVITED'
+	`, strings.TrimPrefix(payload, invitationTokenPrefix)).Scan(&resetID, &userID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid or expired invitation",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to verify invitation",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.MatKhau)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to process password",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE PASSWORD_RESET SET IsUsed = 1 WHERE ID = @p1 AND IsUsed = 0", resetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to accept invitation",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid or expired invitation",
+		})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE [USER] SET password = @p1, status = 'ACTIVE'
+		WHERE userID = @p2 AND status = 'INVITED'
+	`, passwordHash, userID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to accept invitation",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Account activated, you can now log in",
+	})
+}
+
+// createInvitation replaces any pending invitation of the user. Invitations
+// are stored as password resets with a random code nobody is told, so only
+// the signed link can use them.
+func (h *StaffHandler) createInvitation(tx *sql.Tx, userID, email string) (string, time.Time, error) {
+	_, err := tx.Exec("UPDATE PASSWORD_RESET SET IsUsed = 1 WHERE UserID = @p1 AND IsUsed = 0", userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	resetID, err := utils.GeneratePasswordResetID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(h.invite.Validity)
+	_, err = tx.Exec(`
+		INSERT INTO PASSWORD_RESET (ID, UserID, Email, ResetCode, SoLanThu, IsUsed, ExpiresAt, CreatedAt)
+		VALUES (@p1, @p2, @p3, @p4, 0, 0, @p5, GETDATE())
+	`, resetID, userID, email, hashResetCode(resetID, utils.GenerateResetCode()), expiresAt)
+	return resetID, expiresAt, err
+}
+
+// sendInvitation emails the set-password link and reports whether it was
+// handed to the notification service.
+func (h *StaffHandler) sendInvitation(c *gin.Context, userID, role, username, resetID string, expiresAt time.Time) bool {
+	link := h.invite.LinkBaseURL + "/accept-invitation?token=" +
+		url.QueryEscape(utils.SignToken(h.invite.LinkSecret, invitationTokenPrefix+resetID, expiresAt))
+	err := h.notifier.Notify(c.Request.Context(), notification.Message{
+		UserID:   userID,
+		Template: notification.TemplateStaffInvitation,
+		Data: map[string]string{
+			"vai_tro":        role,
+			"ten_dang_nhap":  username,
+			"link_kich_hoat": link,
+			"so_gio":         strconv.Itoa(int(h.invite.Validity / time.Hour)),
+		},
+		Reference: resetID,
+		Channels:  []string{notification.ChannelEmail},
+	})
+	if err != nil {
+		log.Printf("invitation for %s not sent: %v", userID, err)
+		return false
+	}
+	return true
+}
+
+// findStaff returns the role and status of a staff account, writing a 404
+// when there is none.
+func (h *StaffHandler) findStaff(c *gin.Context, userID string) (string, string, bool) {
+	var role, status string
+	err := h.db.QueryRow("SELECT role, status FROM [USER] WHERE userID = @p1 AND role <> 'CUSTOMER'", userID).Scan(&role, &status)
+	if err == sql.ErrNoRows || (err == nil && staffRoles[role].table == "") {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Staff member not found",
+		})
+		return "", "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving staff member",
+			Error:   err.Error(),
+		})
+		return "", "", false
+	}
+	return role, status, true
+}
+
+func (h *StaffHandler) checkClinic(c *gin.Context, clinicID string) bool {
+	var exists int
+	err := h.db.QueryRow("SELECT 1 FROM PHONGKHAM WHERE maPhongKham = @p1", clinicID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Clinic not found",
+		})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error checking clinic",
+			Error:   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// loadStaff returns the account with its role details as the model of its
+// role.
+func (h *StaffHandler) loadStaff(userID string) (interface{}, error) {
+	var user models.User
+	err := h.db.QueryRow(`
+		SELECT userID, hoTen, soDienThoai, email, username, status, createdAt, role
+		FROM [USER] WHERE userID = @p1 AND role <> 'CUSTOMER'
+	`, userID).Scan(&user.MaUser, &user.HoTen, &user.SoDienThoai, &user.Email,
+		&user.TenDangNhap, &user.TrangThai, &user.NgayTao, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	switch user.Role {
+	case "DOCTOR":
+		doctor := models.Doctor{User: user}
+		err = h.db.QueryRow("SELECT chuyenKhoa, namKinhNghiem, bangCap, maGiayPhep FROM BACSI WHERE maUser = @p1", userID).Scan(
+			&doctor.ChuyenKhoa, &doctor.NamKinhNghiem, &doctor.BangCap, &doctor.SoGiayPhepHanhNghe)
+		return doctor, err
+	case "RECEPTIONIST":
+		receptionist := models.Receptionist{User: user}
+		err = h.db.QueryRow("SELECT maPhongKham, luongCoBan, ngayVaoLam FROM LETAN WHERE maUser = @p1", userID).Scan(
+			&receptionist.MaPhongKham, &receptionist.LuongCoBan, &receptionist.NgayVaoLam)
+		return receptionist, err
+	case "ACCOUNTANT":
+		accountant := models.Accountant{User: user}
+		err = h.db.QueryRow("SELECT luongCoBan, ngayVaoLam, chuyenMon FROM KETOAN WHERE maUser = @p1", userID).Scan(
+			&accountant.LuongCoBan, &accountant.NgayVaoLam, &accountant.ChuyenMon)
+		return accountant, err
+	case "CLINIC_MANAGER":
+		manager := models.ClinicManager{User: user}
+		err = h.db.QueryRow("SELECT maPhongKham, luongCoBan, ngayVaoLam FROM QUANLYPHONGKHAM WHERE maUser = @p1", userID).Scan(
+			&manager.MaPhongKham, &manager.LuongCoBan, &manager.NgayVaoLam)
+		return manager, err
+	case "OPERATION_MANAGER":
+		opManager := models.OperationManager{User: user}
+		err = h.db.QueryRow("SELECT chucVu, khuVucPhuTrach, luongCoBan, ngayVaoLam FROM BANDIEUHANH WHERE maUser = @p1", userID).Scan(
+			&opManager.ChucVu, &opManager.KhuVucPhuTrach, &opManager.LuongCoBan, &opManager.NgayVaoLam)
+		return opManager, err
+	}
+	return user, nil
+}
+
+// staffColumns returns the columns and values of the fields set in f,
+// rejecting fields that do not belong to the role.
+func staffColumns(role staffRole, f models.StaffFields) ([]string, []interface{}, error) {
+	values := map[string]interface{}{}
+	if f.ChuyenKhoa != nil {
+		values["chuyen_khoa"] = services.NullIfEmpty(*f.ChuyenKhoa)
+	}
+	if f.NamKinhNghiem != nil {
+		if *f.NamKinhNghiem < 0 {
+			return nil, nil, errors.New("nam_kinh_nghiem cannot be negative")
+		}
+		values["nam_kinh_nghiem"] = *f.NamKinhNghiem
+	}
+	if f.BangCap != nil {
+		values["bang_cap"] = services.NullIfEmpty(*f.BangCap)
+	}
+	if f.SoGiayPhepHanhNghe != nil {
+		values["so_giay_phep_hanh_nghe"] = services.NullIfEmpty(*f.SoGiayPhepHanhNghe)
+	}
+	if f.LuongCoBan != nil {
+		if *f.LuongCoBan < 0 {
+			return nil, nil, errors.New("luong_co_ban cannot be negative")
+		}
+		values["luong_co_ban"] = *f.LuongCoBan
+	}
+	if f.NgayVaoLam != nil {
+		date, err := time.Parse("2006-01-02", *f.NgayVaoLam)
+		if err != nil {
+			return nil, nil, errors.New("ngay_vao_lam must be in YYYY-MM-DD format")
+		}
+		values["ngay_vao_lam"] = date
+	}
+	if f.ChuyenMon != nil {
+		values["chuyen_mon"] = services.NullIfEmpty(*f.ChuyenMon)
+	}
+	if f.ChucVu != nil {
+		values["chuc_vu"] = services.NullIfEmpty(*f.ChucVu)
+	}
+	if f.KhuVucPhuTrach != nil {
+		values["khu_vuc_phu_trach"] = services.NullIfEmpty(*f.KhuVucPhuTrach)
+	}
+
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	columns := make([]string, 0, len(fields))
+	args := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		column, ok := role.columns[field]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s does not apply to this role", field)
+		}
+		columns = append(columns, column)
+		args = append(args, values[field])
+	}
+	return columns, args, nil
+}
+
+func roleHasColumn(role staffRole, column string) bool {
+	for _, c := range role.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// updateColumns sets columns of one row; column names come from code,
+// never from the request.
+func updateColumns(tx *sql.Tx, table, keyColumn, key string, columns []string, values []interface{}) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%s = @p%d", column, i+1)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = @p%d", table, strings.Join(assignments, ", "), keyColumn, len(columns)+1)
+	_, err := tx.Exec(query, append(values, key)...)
+	return err
+}
@@ -0,0 +1,620 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+
+	"clinic-management/internal/middleware"
+	"clinic-management/internal/models"
+	"clinic-management/internal/totp"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorSettings configure TOTP two-factor authentication. Users with
+// one of RequiredRoles must enrol before they can finish logging in; other
+// users may turn it on themselves. Secrets are encrypted with
+// EncryptionKey and shown in authenticator apps under Issuer.
+type TwoFactorSettings struct {
+	Issuer        string
+	RequiredRoles []string
+	EncryptionKey string
+}
+
+const (
+	// Challenge token purposes: the user has passed the password step and
+	// must either enter a code or enrol first.
+	challengeVerify = "2fa_verify"
+	challengeEnroll = "2fa_enroll"
+	challengeTTL    = 5 * time.Minute
+
+	recoveryCodeCount = 10
+)
+
+type TwoFactorCodeRequest struct {
+	MaOTP string `json:"ma_otp" binding:"required"`
+}
+
+// TwoFactorChallengeRequest completes a login started by Login. Either the
+// code from the authenticator app or a recovery code is required when
+// verifying; enrolment only uses ma_otp.
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	MaOTP          string `json:"ma_otp"`
+	MaKhoiPhuc     string `json:"ma_khoi_phuc"`
+}
+
+type DisableTwoFactorRequest struct {
+	MatKhau string `json:"mat_khau" binding:"required"`
+	MaOTP   string `json:"ma_otp" binding:"required"`
+}
+
+// VerifyTwoFactor is the second step of Login for users with 2FA enabled.
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.MaOTP == "" && req.MaKhoiPhuc == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "ma_otp or ma_khoi_phuc is required",
+		})
+		return
+	}
+
+	user, ok := h.challengeUser(c, req.ChallengeToken, challengeVerify)
+	if !ok {
+		return
+	}
+
+	valid, err := h.checkSecondFactor(user.MaUser, req.MaOTP, req.MaKhoiPhuc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to verify code",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !valid {
+		h.rejectSecondFactor(c, user)
+		return
+	}
+
+	h.completeLogin(c, user, nil)
+}
+
+// StartTwoFactorEnrollment gives a user who must use 2FA but has not set
+// it up a new secret, during login.
+func (h *AuthHandler) StartTwoFactorEnrollment(c *gin.Context) {
+	var req TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, ok := h.challengeUser(c, req.ChallengeToken, challengeEnroll)
+	if !ok {
+		return
+	}
+	h.respondWithNewSecret(c, user.MaUser, user.TenDangNhap)
+}
+
+// ConfirmTwoFactorEnrollment turns 2FA on with the first code from the
+// new secret and finishes the login, returning the recovery codes once.
+func (h *AuthHandler) ConfirmTwoFactorEnrollment(c *gin.Context) {
+	var req TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.MaOTP == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "ma_otp is required",
+		})
+		return
+	}
+
+	user, ok := h.challengeUser(c, req.ChallengeToken, challengeEnroll)
+	if !ok {
+		return
+	}
+
+	codes, valid, err := h.confirmTwoFactorSetup(user.MaUser, req.MaOTP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to enable two-factor authentication",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !valid {
+		h.rejectSecondFactor(c, user)
+		return
+	}
+
+	h.completeLogin(c, user, codes)
+}
+
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	var enabled bool
+	var remaining int
+	err := h.db.QueryRow(`
+		SELECT ISNULL((SELECT daBat FROM XACTHUC2LOP WHERE maUser = @p1), 0),
+		       (SELECT COUNT(*) FROM MAKHOIPHUC WHERE maUser = @p1 AND ngayDung IS NULL)
+	`, userID).Scan(&enabled, &remaining)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving two-factor status",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor status retrieved successfully",
+		Data: TwoFactorStatusResponse{
+			DaBat:              enabled,
+			BatBuoc:            h.twoFactorRequired(userType.(string)),
+			SoMaKhoiPhucConLai: remaining,
+		},
+	})
+}
+
+// SetupTwoFactor starts enrolment for a logged-in user; EnableTwoFactor
+// completes it.
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+
+	enabled, err := h.twoFactorEnabled(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving two-factor status",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if enabled {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: "Two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	h.respondWithNewSecret(c, userID.(string), username.(string))
+}
+
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	codes, valid, err := h.confirmTwoFactorSetup(userID.(string), req.MaOTP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to enable two-factor authentication",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid verification code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication enabled; store the recovery codes safely, they are shown only once",
+		Data:    RecoveryCodesResponse{MaKhoiPhuc: codes},
+	})
+}
+
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+
+	if h.twoFactorRequired(userType.(string)) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Two-factor authentication is required for your role",
+		})
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var passwordHash string
+	if err := h.db.QueryRow("SELECT password FROM [USER] WHERE userID = @p1", userID).Scan(&passwordHash); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Error retrieving user",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !utils.CheckPasswordHash(req.MatKhau, passwordHash) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Password is incorrect",
+		})
+		return
+	}
+
+	valid, err := h.checkSecondFactor(userID.(string), req.MaOTP, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to verify code",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid verification code",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM MAKHOIPHUC WHERE maUser = @p1", userID); err == nil {
+		_, err = tx.Exec("DELETE FROM XACTHUC2LOP WHERE maUser = @p1", userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to disable two-factor authentication",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	valid, err := h.checkSecondFactor(userID.(string), req.MaOTP, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to verify code",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid verification code",
+		})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, userID.(string))
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to generate recovery codes",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Recovery codes regenerated; previous codes no longer work",
+		Data:    RecoveryCodesResponse{MaKhoiPhuc: codes},
+	})
+}
+
+func (h *AuthHandler) twoFactorRequired(role string) bool {
+	return containsString(h.twoFactor.RequiredRoles, role)
+}
+
+func (h *AuthHandler) twoFactorEnabled(userID string) (bool, error) {
+	var enabled bool
+	err := h.db.QueryRow("SELECT daBat FROM XACTHUC2LOP WHERE maUser = @p1", userID).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// challengeUser resolves a challenge token to its user and applies the
+// same lockout as the password step.
+func (h *AuthHandler) challengeUser(c *gin.Context, token, purpose string) (models.User, bool) {
+	userID, ok := middleware.ParseChallengeToken(token, purpose, h.signingKeys)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "Invalid or expired challenge, please log in again",
+		})
+		return models.User{}, false
+	}
+
+	user, state, _, err := h.findLoginUser("userID", userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "Invalid or expired challenge, please log in again",
+		})
+		return models.User{}, false
+	}
+
+	if wait, locked := h.accountRetryAfter(state); wait > 0 {
+		if locked {
+			tooManyLoginAttempts(c, wait, "Account is temporarily locked after too many failed logins")
+		} else {
+			tooManyLoginAttempts(c, wait, "Too many failed logins, please wait before trying again")
+		}
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// rejectSecondFactor counts a wrong code like a wrong password, so codes
+// cannot be guessed faster than passwords.
+func (h *AuthHandler) rejectSecondFactor(c *gin.Context, user models.User) {
+	h.recordLoginAttempt(c, user.TenDangNhap, user.MaUser, loginBadOTP)
+	if err := h.registerLoginFailure(c, user.MaUser); err != nil {
+		log.Printf("login failure for %s not counted: %v", user.MaUser, err)
+	}
+	c.JSON(http.StatusUnauthorized, models.APIResponse{
+		Success: false,
+		Message: "Invalid verification code",
+	})
+}
+
+// respondWithNewSecret stores a pending secret, replacing any earlier
+// one, and returns it with its provisioning URI for the QR code.
+func (h *AuthHandler) respondWithNewSecret(c *gin.Context, userID, username string) {
+	secret, err := totp.GenerateSecret()
+	if err == nil {
+		var sealed string
+		if sealed, err = utils.EncryptSecret(h.twoFactor.EncryptionKey, secret); err == nil {
+			_, err = h.db.Exec(`
+				MERGE XACTHUC2LOP AS t
+				USING (SELECT @p1 AS maUser) AS s ON t.maUser = s.maUser
+				WHEN MATCHED THEN
+					UPDATE SET biMatCho = @p2, ngayCapNhat = GETDATE()
+				WHEN NOT MATCHED THEN
+					INSERT (maUser, biMatCho, daBat, ngayCapNhat) VALUES (@p1, @p2, 0, GETDATE());
+			`, userID, sealed)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to start two-factor setup",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Scan the QR code in an authenticator app, then confirm with a code",
+		Data: TwoFactorSecretResponse{
+			BiMat:      secret,
+			OtpauthURI: totp.ProvisioningURI(h.twoFactor.Issuer, username, secret),
+			SoChuSo:    totp.Digits,
+			ChuKyGiay:  int(totp.Period / time.Second),
+		},
+	})
+}
+
+// confirmTwoFactorSetup activates the pending secret if code matches it
+// and issues a fresh set of recovery codes.
+func (h *AuthHandler) confirmTwoFactorSetup(userID, code string) ([]string, bool, error) {
+	var sealed sql.NullString
+	err := h.db.QueryRow("SELECT biMatCho FROM XACTHUC2LOP WHERE maUser = @p1", userID).Scan(&sealed)
+	if err == sql.ErrNoRows || (err == nil && !sealed.Valid) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	secret, err := utils.DecryptSecret(h.twoFactor.EncryptionKey, sealed.String)
+	if err != nil {
+		return nil, false, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE XACTHUC2LOP SET biMat = biMatCho, biMatCho = NULL, daBat = 1, buocCuoi = @p2,
+			ngayBat = GETDATE(), ngayCapNhat = GETDATE()
+		WHERE maUser = @p1 AND biMatCho = @p3
+	`, userID, step, sealed.String)
+	if err != nil {
+		return nil, false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		// Another setup replaced the pending secret meanwhile.
+		return nil, false, nil
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// checkSecondFactor accepts a current TOTP code that has not been used yet,
+// or an unused recovery code, which is then spent.
+func (h *AuthHandler) checkSecondFactor(userID, code, recoveryCode string) (bool, error) {
+	if code == "" {
+		if recoveryCode == "" {
+			return false, nil
+		}
+		result, err := h.db.Exec(`
+			UPDATE MAKHOIPHUC SET ngayDung = GETDATE()
+			WHERE maUser = @p1 AND maBam = @p2 AND ngayDung IS NULL
+		`, userID, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			return false, err
+		}
+		affected, _ := result.RowsAffected()
+		return affected == 1, nil
+	}
+
+	var sealed sql.NullString
+	err := h.db.QueryRow("SELECT biMat FROM XACTHUC2LOP WHERE maUser = @p1 AND daBat = 1", userID).Scan(&sealed)
+	if err == sql.ErrNoRows || (err == nil && !sealed.Valid) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	secret, err := utils.DecryptSecret(h.twoFactor.EncryptionKey, sealed.String)
+	if err != nil {
+		return false, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	// Recording the step rejects a replay of the same or an older code.
+	result, err := h.db.Exec(`
+		UPDATE XACTHUC2LOP SET buocCuoi = @p2
+		WHERE maUser = @p1 AND (buocCuoi IS NULL OR buocCuoi < @p2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected == 1, nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID string) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM MAKHOIPHUC WHERE maUser = @p1", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code := utils.GenerateRecoveryCode()
+		if containsString(codes, code) {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO MAKHOIPHUC (maUser, maBam, ngayTao) VALUES (@p1, @p2, GETDATE())
+		`, userID, hashRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(utils.NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -5,17 +5,37 @@ import (
 	"net/http"
 
 	"clinic-management/internal/models"
+	"clinic-management/internal/session"
 	"clinic-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	db *sql.DB
+	db       *sql.DB
+	sessions *session.Store
 }
 
-func NewUserHandler(db *sql.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *sql.DB, sessions *session.Store) *UserHandler {
+	return &UserHandler{db: db, sessions: sessions}
+}
+
+// UpdateProfileRequest changes the fields that are set. An empty string
+// clears a field, except ho_ten; ngay_sinh (YYYY-MM-DD), gioi_tinh, dia_chi
+// and ma_bao_hiem apply to customers only.
+type UpdateProfileRequest struct {
+	HoTen       *string `json:"ho_ten"`
+	Email       *string `json:"email"`
+	SoDienThoai *string `json:"so_dien_thoai"`
+	NgaySinh    *string `json:"ngay_sinh"`
+	GioiTinh    *string `json:"gioi_tinh"`
+	DiaChi      *string `json:"dia_chi"`
+	MaBaoHiem   *string `json:"ma_bao_hiem"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -143,7 +163,7 @@ func (h *UserHandler) getAccountantProfile(c *gin.Context, userID string) {
 	var accountant models.Accountant
 	query := `
 		SELECT u.userID, u.hoTen, u.soDienThoai, u.email, u.username, u.status, u.createdAt, u.role,
-		       a.luongCoBan, a.chuyenMon
+		       a.luongCoBan, a.ngayVaoLam, a.chuyenMon
 		FROM [USER] u 
 		JOIN KETOAN a ON u.userID = a.maUser 
 		WHERE u.userID = @p1
@@ -239,8 +259,8 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userType, _ := c.Get("user_type")
 
-	var updateData map[string]interface{}
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Invalid request format",
@@ -249,7 +269,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	if len(updateData) == 0 {
+	if req == (UpdateProfileRequest{}) {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "No data provided for update",
@@ -257,9 +277,8 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	if email, exists := updateData["email"]; exists && email != "" {
-		emailStr, ok := email.(string)
-		if !ok || !utils.ValidateEmail(emailStr) {
+	if req.Email != nil && *req.Email != "" {
+		if !utils.ValidateEmail(*req.Email) {
 			c.JSON(http.StatusBadRequest, models.APIResponse{
 				Success: false,
 				Message: "Invalid email format",
@@ -268,7 +287,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		}
 
 		var existingUserID string
-		err := h.db.QueryRow("SELECT userID FROM [USER] WHERE email = @p1 AND userID != ?", emailStr, userID).Scan(&existingUserID)
+		err := h.db.QueryRow("SELECT userID FROM [USER] WHERE email = @p1 AND userID != @p2", *req.Email, userID).Scan(&existingUserID)
 		if err == nil {
 			c.JSON(http.StatusConflict, models.APIResponse{
 				Success: false,
@@ -289,8 +308,8 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	if hoTen, exists := updateData["ho_ten"]; exists && hoTen != "" {
-		_, err = tx.Exec("UPDATE [USER] SET hoTen = @p1 WHERE userID = @p1", hoTen, userID)
+	if req.HoTen != nil && *req.HoTen != "" {
+		_, err = tx.Exec("UPDATE [USER] SET hoTen = @p1 WHERE userID = @p2", *req.HoTen, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -301,14 +320,8 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		}
 	}
 
-	if soDienThoai, exists := updateData["so_dien_thoai"]; exists {
-		var phoneValue interface{}
-		if soDienThoai == "" {
-			phoneValue = nil
-		} else {
-			phoneValue = soDienThoai
-		}
-		_, err = tx.Exec("UPDATE [USER] SET soDienThoai = @p1 WHERE userID = @p1", phoneValue, userID)
+	if req.SoDienThoai != nil {
+		_, err = tx.Exec("UPDATE [USER] SET soDienThoai = @p1 WHERE userID = @p2", clearable(*req.SoDienThoai), userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -319,14 +332,8 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		}
 	}
 
-	if email, exists := updateData["email"]; exists {
-		var emailValue interface{}
-		if email == "" {
-			emailValue = nil
-		} else {
-			emailValue = email
-		}
-		_, err = tx.Exec("UPDATE [USER] SET email = @p1 WHERE userID = @p1", emailValue, userID)
+	if req.Email != nil {
+		_, err = tx.Exec("UPDATE [USER] SET email = @p1 WHERE userID = @p2", clearable(*req.Email), userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -337,14 +344,8 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		}
 	}
 
-	if maBaoHiem, exists := updateData["ma_bao_hiem"]; exists {
-		var insuranceValue interface{}
-		if maBaoHiem == "" {
-			insuranceValue = nil
-		} else {
-			insuranceValue = maBaoHiem
-		}
-		_, err := tx.Exec("UPDATE CUSTOMER SET maBaoHiem = @p1 WHERE maUser = @p2", insuranceValue, userID)
+	if req.MaBaoHiem != nil {
+		_, err := tx.Exec("UPDATE CUSTOMER SET maBaoHiem = @p1 WHERE maUser = @p2", clearable(*req.MaBaoHiem), userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -357,7 +358,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 	switch userType.(string) {
 	case "CUSTOMER":
-		err = h.updateCustomerSpecificFields(tx, userID.(string), updateData)
+		err = h.updateCustomerSpecificFields(tx, userID.(string), req)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success: false,
@@ -383,54 +384,30 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	})
 }
 
-func (h *UserHandler) updateCustomerSpecificFields(tx *sql.Tx, userID string, updateData map[string]interface{}) error {
-	if ngaySinh, exists := updateData["ngay_sinh"]; exists {
-		var dateValue interface{}
-		if ngaySinh == "" {
-			dateValue = nil
-		} else {
-			dateValue = ngaySinh
-		}
-		_, err := tx.Exec("UPDATE CUSTOMER SET ngaySinh = @p1 WHERE maUser = @p1", dateValue, userID)
+func (h *UserHandler) updateCustomerSpecificFields(tx *sql.Tx, userID string, req UpdateProfileRequest) error {
+	if req.NgaySinh != nil {
+		_, err := tx.Exec("UPDATE CUSTOMER SET ngaySinh = @p1 WHERE maUser = @p2", clearable(*req.NgaySinh), userID)
 		if err != nil {
 			return err
 		}
 	}
 
-	if gioiTinh, exists := updateData["gioi_tinh"]; exists {
-		var genderValue interface{}
-		if gioiTinh == "" {
-			genderValue = nil
-		} else {
-			genderValue = gioiTinh
-		}
-		_, err := tx.Exec("UPDATE CUSTOMER SET gioiTinh = @p1 WHERE maUser = @p1", genderValue, userID)
+	if req.GioiTinh != nil {
+		_, err := tx.Exec("UPDATE CUSTOMER SET gioiTinh = @p1 WHERE maUser = @p2", clearable(*req.GioiTinh), userID)
 		if err != nil {
 			return err
 		}
 	}
 
-	if diaChi, exists := updateData["dia_chi"]; exists {
-		var addressValue interface{}
-		if diaChi == "" {
-			addressValue = nil
-		} else {
-			addressValue = diaChi
-		}
-		_, err := tx.Exec("UPDATE CUSTOMER SET diaChi = @p1 WHERE maUser = @p1", addressValue, userID)
+	if req.DiaChi != nil {
+		_, err := tx.Exec("UPDATE CUSTOMER SET diaChi = @p1 WHERE maUser = @p2", clearable(*req.DiaChi), userID)
 		if err != nil {
 			return err
 		}
 	}
 
-	if maBaoHiem, exists := updateData["ma_bao_hiem"]; exists {
-		var insuranceValue interface{}
-		if maBaoHiem == "" {
-			insuranceValue = nil
-		} else {
-			insuranceValue = maBaoHiem
-		}
-		_, err := tx.Exec("UPDATE CUSTOMER SET maBaoHiem = @p1 WHERE maUser = @p1", insuranceValue, userID)
+	if req.MaBaoHiem != nil {
+		_, err := tx.Exec("UPDATE CUSTOMER SET maBaoHiem = @p1 WHERE maUser = @p2", clearable(*req.MaBaoHiem), userID)
 		if err != nil {
 			return err
 		}
@@ -442,11 +419,7 @@ func (h *UserHandler) updateCustomerSpecificFields(tx *sql.Tx, userID string, up
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	var req struct {
-		OldPassword string `json:"old_password" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required"`
-	}
-
+	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
@@ -517,7 +490,18 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	_, err = h.db.Exec("UPDATE [USER] SET password = @p1 WHERE userID = @p1", newHash, userID)
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to begin transaction",
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE [USER] SET password = @p1 WHERE userID = @p2", newHash, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -527,8 +511,37 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// Other devices signed in with the old password are logged out; the
+	// current one stays signed in.
+	sessionID, _ := c.Get("session_id")
+	if _, err = h.sessions.RevokeUserTx(tx, userID.(string), sessionID.(string), session.RevokedPasswordChange); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to revoke other sessions",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to update password",
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Message: "Password changed successfully",
 	})
 }
+
+// clearable stores an empty string as NULL.
+func clearable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,366 @@
+// Package keys manages the asymmetric keys tokens are signed with. Keys
+// live in KHOAJWT so every server instance signs and verifies with the
+// same set; private keys are stored encrypted.
+//
+// One key signs at a time. When it is older than the rotation period a
+// new key takes over, and the old one is kept for verification until every
+// token it signed has expired.
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"clinic-management/internal/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Supported signing algorithms.
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+const (
+	rsaKeyBits = 2048
+	// reloadCooldown limits reloads triggered by tokens with an unknown kid.
+	reloadCooldown = 30 * time.Second
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type key struct {
+	id        string
+	algorithm string
+	private   crypto.Signer
+	createdAt time.Time
+	retired   bool
+}
+
+// Manager signs tokens with the current key and verifies them with any
+// key that has not expired.
+type Manager struct {
+	db            *sql.DB
+	algorithm     string
+	rotateEvery   time.Duration
+	verifyFor     time.Duration
+	encryptionKey string
+
+	mu       sync.RWMutex
+	signing  *key
+	verify   map[string]*key
+	loadedAt time.Time
+}
+
+// NewManager signs with algorithm, rotates keys every rotateEvery and
+// keeps a retired key for verifyFor, which must cover the longest lived
+// token. Private keys are encrypted with encryptionKey.
+func NewManager(db *sql.DB, algorithm string, rotateEvery, verifyFor time.Duration, encryptionKey string) (*Manager, error) {
+	if signingMethod(algorithm) == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	return &Manager{
+		db:            db,
+		algorithm:     algorithm,
+		rotateEvery:   rotateEvery,
+		verifyFor:     verifyFor,
+		encryptionKey: encryptionKey,
+		verify:        map[string]*key{},
+	}, nil
+}
+
+// Run rotates and reloads keys every interval until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(); err != nil {
+				log.Printf("signing key refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// Refresh loads the keys and creates a new signing key when there is
+// none, it is due for rotation or it uses a different algorithm.
+func (m *Manager) Refresh() error {
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	current := m.signing
+	m.mu.RUnlock()
+	if current != nil && current.algorithm == m.algorithm && time.Since(current.createdAt) < m.rotateEvery {
+		return nil
+	}
+
+	if err := m.rotate(); err != nil {
+		return err
+	}
+	return m.load()
+}
+
+// Sign signs claims with the current key, naming it in the kid header.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	k := m.signing
+	m.mu.RUnlock()
+	if k == nil {
+		return "", errors.New("no signing key loaded")
+	}
+
+	token := jwt.NewWithClaims(signingMethod(k.algorithm), claims)
+	token.Header["kid"] = k.id
+	return token.SignedString(k.private)
+}
+
+// Keyfunc returns the public key named by a token's kid, for use with
+// jwt.Parse. A kid this instance does not know yet, e.g. one just created
+// by another instance, triggers a reload.
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrUnknownKey
+	}
+
+	k := m.lookup(kid)
+	if k == nil {
+		m.mu.RLock()
+		stale := time.Since(m.loadedAt) > reloadCooldown
+		m.mu.RUnlock()
+		if stale {
+			if err := m.load(); err != nil {
+				return nil, err
+			}
+			k = m.lookup(kid)
+		}
+	}
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != k.algorithm {
+		return nil, fmt.Errorf("token algorithm %s does not match key %s", token.Method.Alg(), kid)
+	}
+	return k.private.Public(), nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS returns the public keys tokens may currently be signed with,
+// newest first.
+func (m *Manager) JWKS() []JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jwks := make([]JWK, 0, len(m.verify))
+	if m.signing != nil {
+		jwks = append(jwks, m.signing.jwk())
+	}
+	for _, k := range m.verify {
+		if k != m.signing {
+			jwks = append(jwks, k.jwk())
+		}
+	}
+	return jwks
+}
+
+func (k *key) jwk() JWK {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{KeyID: k.id, Algorithm: k.algorithm, Use: "sig"}
+	switch pub := k.private.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(pub)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(pub.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return jwk
+}
+
+func (m *Manager) lookup(kid string) *key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.verify[kid]
+}
+
+func (m *Manager) load() error {
+	rows, err := m.db.Query(`
+		SELECT maKhoa, thuatToan, khoaRieng, ngayTao, ngungKyLuc
+		FROM KHOAJWT
+		WHERE hetHanLuc IS NULL OR hetHanLuc > GETDATE()
+		ORDER BY ngayTao DESC
+	`)
+	if err != nil {
+		return fmt.Errorf("error loading signing keys: %v", err)
+	}
+	defer rows.Close()
+
+	verify := map[string]*key{}
+	var signing *key
+	for rows.Next() {
+		var k key
+		var encrypted string
+		var retiredAt sql.NullTime
+		if err := rows.Scan(&k.id, &k.algorithm, &encrypted, &k.createdAt, &retiredAt); err != nil {
+			return fmt.Errorf("error loading signing keys: %v", err)
+		}
+		if signingMethod(k.algorithm) == nil {
+			continue
+		}
+		k.private, err = m.decryptPrivateKey(encrypted)
+		if err != nil {
+			return fmt.Errorf("error loading signing key %s: %v", k.id, err)
+		}
+		k.retired = retiredAt.Valid
+		verify[k.id] = &k
+		if signing == nil && !k.retired {
+			signing = &k
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error loading signing keys: %v", err)
+	}
+
+	m.mu.Lock()
+	m.signing = signing
+	m.verify = verify
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// rotate adds a signing key and retires the others. The insert is skipped
+// when another instance has just rotated, so concurrent instances do not
+// each add a key.
+func (m *Manager) rotate() error {
+	private, err := generateKey(m.algorithm)
+	if err != nil {
+		return err
+	}
+	encrypted, err := m.encryptPrivateKey(private)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	kid := hex.EncodeToString(id)
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO KHOAJWT (maKhoa, thuatToan, khoaRieng, ngayTao)
+		SELECT @p1, @p2, @p3, GETDATE()
+		WHERE NOT EXISTS (
+			SELECT 1 FROM KHOAJWT WITH (UPDLOCK, HOLDLOCK)
+			WHERE ngungKyLuc IS NULL AND thuatToan = @p2 AND ngayTao > @p4
+		)
+	`, kid, m.algorithm, encrypted, time.Now().Add(-m.rotateEvery))
+	if err != nil {
+		return fmt.Errorf("error creating signing key: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE KHOAJWT SET ngungKyLuc = GETDATE(), hetHanLuc = @p1
+		WHERE ngungKyLuc IS NULL AND maKhoa <> @p2
+	`, time.Now().Add(m.verifyFor), kid)
+	if err != nil {
+		return fmt.Errorf("error retiring signing keys: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("rotated token signing key, new kid %s (%s)", kid, m.algorithm)
+	return nil
+}
+
+func (m *Manager) encryptPrivateKey(private crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	return utils.EncryptSecret(m.encryptionKey, base64.StdEncoding.EncodeToString(der))
+}
+
+func (m *Manager) decryptPrivateKey(encrypted string) (crypto.Signer, error) {
+	encoded, err := utils.DecryptSecret(m.encryptionKey, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("stored key cannot sign")
+	}
+	return signer, nil
+}
+
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	}
+	return nil
+}
@@ -1,16 +1,23 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"strings"
 	"time"
 
+	"clinic-management/internal/keys"
+	"clinic-management/internal/session"
 	"clinic-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// AccessTokenTTL is how long an access token is valid.
+const AccessTokenTTL = 24 * time.Hour
+
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
@@ -18,33 +25,94 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, username, userType, jwtSecret string) (string, error) {
+// GenerateToken issues an access token for a session; the session ID is
+// carried as the jti claim.
+func GenerateToken(userID, username, userType, sessionID string, signingKeys *keys.Manager) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
 		UserType: userType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signingKeys.Sign(claims)
+}
+
+// RefreshClaims identify the session a refresh token belongs to. Nonce
+// makes every refresh token of a session distinct, so an old one can be
+// told apart from the current one.
+type RefreshClaims struct {
+	Nonce string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+func GenerateRefreshToken(userID, sessionID string, ttl time.Duration, signingKeys *keys.Manager) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	claims := RefreshClaims{
+		Nonce: hex.EncodeToString(nonce),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
+	return signingKeys.Sign(claims)
 }
 
-func GenerateRefreshToken(userID, jwtSecret string) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:   userID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+// ParseRefreshToken returns the user and session of a valid refresh token.
+func ParseRefreshToken(tokenString string, signingKeys *keys.Manager) (string, string, bool) {
+	claims := &RefreshClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, signingKeys.Keyfunc)
+	if err != nil || !token.Valid || claims.Subject == "" || claims.ID == "" || claims.Nonce == "" {
+		return "", "", false
 	}
+	return claims.Subject, claims.ID, true
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
+// ChallengeClaims identify a user who has passed the password step of a
+// login but still has to complete a second step named by Purpose. They are
+// not accepted by AuthMiddleware.
+type ChallengeClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
 }
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func GenerateChallengeToken(userID, purpose string, ttl time.Duration, signingKeys *keys.Manager) (string, error) {
+	claims := ChallengeClaims{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signingKeys.Sign(claims)
+}
+
+// ParseChallengeToken returns the user of a valid challenge token issued
+// for purpose.
+func ParseChallengeToken(tokenString, purpose string, signingKeys *keys.Manager) (string, bool) {
+	claims := &ChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, signingKeys.Keyfunc)
+	if err != nil || !token.Valid || claims.Purpose != purpose || claims.Subject == "" {
+		return "", false
+	}
+	return claims.Subject, true
+}
+
+// AuthMiddleware accepts a valid token only while its session is active:
+// not logged out or revoked, and the user not deactivated.
+func AuthMiddleware(signingKeys *keys.Manager, sessions *session.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -63,19 +131,30 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		tokenString := bearerToken[1]
 		claims := &Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, signingKeys.Keyfunc)
 
-		if err != nil || !token.Valid {
+		if err != nil || !token.Valid || claims.UserID == "" || claims.ID == "" {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid token", "")
 			c.Abort()
 			return
 		}
 
+		valid, err := sessions.Valid(claims.ID, claims.UserID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify session", err.Error())
+			c.Abort()
+			return
+		}
+		if !valid {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Session has been revoked, please log in again", "")
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("user_type", claims.UserType)
+		c.Set("session_id", claims.ID)
 		c.Next()
 	}
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter counts requests per client IP in a sliding window. Counts
+// are kept in memory, so with several instances each enforces its own
+// limit.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+	swept  time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, hits: map[string][]time.Time{}}
+}
+
+// Allow records a request from key and reports whether it is within the
+// limit; if not, it also returns how long until the next request is allowed.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+	if now.Sub(l.swept) > l.window {
+		for k, times := range l.hits {
+			if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+		l.swept = now
+	}
+
+	times := l.hits[key]
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	times = times[i:]
+
+	if len(times) >= l.limit {
+		l.hits[key] = times
+		return false, times[0].Sub(cutoff)
+	}
+	l.hits[key] = append(times, now)
+	return true, 0
+}
+
+// RateLimit rejects requests over the limiter's budget with 429 and a
+// Retry-After header. A limit of 0 or less disables it.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter.limit <= 0 {
+			c.Next()
+			return
+		}
+		if ok, retryAfter := limiter.Allow(c.ClientIP()); !ok {
+			seconds := int(retryAfter/time.Second) + 1
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests, please try again later", "")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
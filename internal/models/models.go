@@ -62,6 +62,47 @@ type OperationManager struct {
 	NgayVaoLam     *time.Time `json:"ngay_vao_lam" db:"ngayVaoLam"`
 }
 
+// StaffFields are the role-specific details of a staff account. Only the
+// fields belonging to the account's role may be set: chuyen_khoa,
+// nam_kinh_nghiem, bang_cap and so_giay_phep_hanh_nghe for doctors,
+// chuyen_mon for accountants, chuc_vu and khu_vuc_phu_trach for operation
+// managers, and luong_co_ban and ngay_vao_lam (YYYY-MM-DD) for every role
+// but doctors.
+type StaffFields struct {
+	ChuyenKhoa         *string  `json:"chuyen_khoa"`
+	NamKinhNghiem      *int     `json:"nam_kinh_nghiem"`
+	BangCap            *string  `json:"bang_cap"`
+	SoGiayPhepHanhNghe *string  `json:"so_giay_phep_hanh_nghe"`
+	LuongCoBan         *float64 `json:"luong_co_ban"`
+	NgayVaoLam         *string  `json:"ngay_vao_lam"`
+	ChuyenMon          *string  `json:"chuyen_mon"`
+	ChucVu             *string  `json:"chuc_vu"`
+	KhuVucPhuTrach     *string  `json:"khu_vuc_phu_trach"`
+}
+
+type CreateStaffRequest struct {
+	Role        string  `json:"role" binding:"required"`
+	HoTen       string  `json:"ho_ten" binding:"required"`
+	TenDangNhap string  `json:"ten_dang_nhap" binding:"required"`
+	Email       string  `json:"email" binding:"required"`
+	SoDienThoai *string `json:"so_dien_thoai"`
+	// MaPhongKham is required for receptionists and clinic managers.
+	MaPhongKham *string `json:"ma_phong_kham"`
+	StaffFields
+}
+
+type UpdateStaffRequest struct {
+	HoTen       *string `json:"ho_ten"`
+	Email       *string `json:"email"`
+	SoDienThoai *string `json:"so_dien_thoai"`
+	StaffFields
+}
+
+type AcceptInvitationRequest struct {
+	Token   string `json:"token" binding:"required"`
+	MatKhau string `json:"mat_khau" binding:"required"`
+}
+
 type Clinic struct {
 	MaPhongKham  string  `json:"ma_phong_kham" db:"maPhongKham"`
 	TenPhongKham string  `json:"ten_phong_kham" db:"tenPhongKham"`
@@ -70,25 +111,81 @@ type Clinic struct {
 	Email        *string `json:"email" db:"email"`
 }
 
+// OpeningHours are a clinic's hours on one weekday, thu 1 (Monday) to 7
+// (Sunday). gio_mo and gio_dong are HH:MM and unset when nghi is true.
+type OpeningHours struct {
+	Thu     int     `json:"thu" db:"thu" binding:"min=1,max=7"`
+	GioMo   *string `json:"gio_mo" db:"gioMo"`
+	GioDong *string `json:"gio_dong" db:"gioDong"`
+	Nghi    bool    `json:"nghi" db:"nghi"`
+}
+
+// Room is a room of a clinic that schedules and appointments can be
+// assigned to.
+type Room struct {
+	MaPhong     string  `json:"ma_phong" db:"maPhong"`
+	MaPhongKham string  `json:"ma_phong_kham" db:"maPhongKham"`
+	TenPhong    string  `json:"ten_phong" db:"tenPhong"`
+	LoaiPhong   string  `json:"loai_phong" db:"loaiPhong"`
+	SucChua     *int    `json:"suc_chua" db:"sucChua"`
+	TrangThai   string  `json:"trang_thai" db:"trangThai"`
+	GhiChu      *string `json:"ghi_chu" db:"ghiChu"`
+}
+
+type Equipment struct {
+	MaThietBi      string     `json:"ma_thiet_bi" db:"maThietBi"`
+	MaPhongKham    string     `json:"ma_phong_kham" db:"maPhongKham"`
+	MaPhong        *string    `json:"ma_phong" db:"maPhong"`
+	TenPhong       *string    `json:"ten_phong"`
+	TenThietBi     string     `json:"ten_thiet_bi" db:"tenThietBi"`
+	LoaiThietBi    *string    `json:"loai_thiet_bi" db:"loaiThietBi"`
+	SoSeri         *string    `json:"so_seri" db:"soSeri"`
+	TrangThai      string     `json:"trang_thai" db:"trangThai"`
+	NgayMua        *time.Time `json:"ngay_mua" db:"ngayMua"`
+	NgayBaoTriTiep *time.Time `json:"ngay_bao_tri_tiep" db:"ngayBaoTriTiep"`
+	GhiChu         *string    `json:"ghi_chu" db:"ghiChu"`
+}
+
 type Appointment struct {
-	MaLichKham  string    `json:"ma_lich_kham" db:"maLichKham"`
-	MaCustomer  string    `json:"ma_customer" db:"maCustomer"`
-	MaBacSi     string    `json:"ma_bac_si" db:"maBacSi"`
-	MaPhongKham string    `json:"ma_phong_kham" db:"maPhongKham"`
-	NgayGioKham time.Time `json:"ngay_gio_kham" db:"maGioKham"`
-	TrangThai   string    `json:"trang_thai" db:"trangThai"`
-	GhiChu      *string   `json:"ghi_chu" db:"ghiChu"`
-	NgayDat     time.Time `json:"ngay_dat" db:"createdAt"`
+	MaLichKham  string     `json:"ma_lich_kham" db:"maLichKham"`
+	MaCustomer  string     `json:"ma_customer" db:"maCustomer"`
+	MaBacSi     string     `json:"ma_bac_si" db:"maBacSi"`
+	MaPhongKham string     `json:"ma_phong_kham" db:"maPhongKham"`
+	MaPhong     *string    `json:"ma_phong" db:"maPhong"`
+	NgayGioKham time.Time  `json:"ngay_gio_kham" db:"ngayGioKham"`
+	TrangThai   string     `json:"trang_thai" db:"trangThai"`
+	GhiChu      *string    `json:"ghi_chu" db:"ghiChu"`
+	NgayDat     *time.Time `json:"ngay_dat" db:"createdAt"`
+}
+
+// AppointmentDetail is an appointment with the names of the people, clinic
+// and room it refers to.
+type AppointmentDetail struct {
+	Appointment
+	TenKhachHang string  `json:"ten_khach_hang"`
+	TenBacSi     string  `json:"ten_bac_si"`
+	TenPhongKham string  `json:"ten_phong_kham"`
+	TenPhong     *string `json:"ten_phong"`
 }
 
 type WorkSchedule struct {
 	MaLichLamViec string    `json:"ma_lich_lam_viec" db:"maLichLamViec"`
 	MaBacSi       string    `json:"ma_bac_si" db:"maBacSi"`
 	MaPhongKham   string    `json:"ma_phong_kham" db:"maPhongKham"`
+	MaPhong       *string   `json:"ma_phong" db:"maPhong"`
 	NgayLamViec   time.Time `json:"ngay_lam_viec" db:"ngayLamViec"`
 	GioBatDau     string    `json:"gio_bat_dau" db:"gioBatDau"`
 	GioKetThuc    string    `json:"gio_ket_thuc" db:"gioKetThuc"`
-	TrangThai     string    `json:"trang_thai" db:"trangThai"`
+	TrangThai     string    `json:"status" db:"status"`
+}
+
+// WorkScheduleDetail is a work schedule with the names of its doctor,
+// clinic and room.
+type WorkScheduleDetail struct {
+	WorkSchedule
+	TenBacSi     string  `json:"ten_bac_si"`
+	TenPhongKham string  `json:"ten_phong_kham"`
+	TenPhong     *string `json:"ten_phong"`
 }
 
 type MedicalRecord struct {
@@ -101,9 +198,27 @@ type MedicalRecord struct {
 	ChanDoan        *string    `json:"chan_doan" db:"ChanDoan"`
 	HuongDanDieuTri *string    `json:"huong_dan_dieu_tri" db:"huongDanDieuTri"`
 	MaICD10         *string    `json:"ma_icd10" db:"maICD10"`
+	MaICD10Phu      []string   `json:"ma_icd10_phu,omitempty" db:"-"`
 	NgayTaiKham     *time.Time `json:"ngay_tai_kham" db:"ngayTaiKham"`
 }
 
+// MedicalRecordDetail is a medical record with the names of its patient,
+// doctor and clinic.
+type MedicalRecordDetail struct {
+	MedicalRecord
+	TenKhachHang string `json:"ten_khach_hang"`
+	TenBacSi     string `json:"ten_bac_si"`
+	TenPhongKham string `json:"ten_phong_kham"`
+}
+
+type ICD10Code struct {
+	MaICD10      string  `json:"ma_icd10" db:"maICD10"`
+	TenTiengAnh  string  `json:"ten_tieng_anh" db:"tenTiengAnh"`
+	TenTiengViet *string `json:"ten_tieng_viet" db:"tenTiengViet"`
+	Chuong       *string `json:"chuong" db:"chuong"`
+	MaCha        *string `json:"ma_cha" db:"maCha"`
+}
+
 type Medicine struct {
 	MaThuoc   string  `json:"ma_thuoc" db:"maThuoc"`
 	TenThuoc  string  `json:"ten_thuoc" db:"tenThuoc"`
@@ -114,10 +229,24 @@ type Medicine struct {
 }
 
 type Prescription struct {
-	MaDonThuoc string    `json:"ma_don_thuoc" db:"maDonThuoc"`
-	MaHoSo     string    `json:"ma_ho_so" db:"maHoSo"`
-	NgayKeDon  time.Time `json:"ngay_ke_don" db:"ngayKeDon"`
-	GhiChu     *string   `json:"ghi_chu" db:"ghiChu"`
+	MaDonThuoc string     `json:"ma_don_thuoc" db:"maDonThuoc"`
+	MaHoSo     string     `json:"ma_ho_so" db:"maHoSo"`
+	NgayKeDon  *time.Time `json:"ngay_ke_don" db:"ngayKeDon"`
+	GhiChu     *string    `json:"ghi_chu" db:"ghiChu"`
+	TrangThai  string     `json:"trang_thai" db:"trangThai"`
+	// MaCustomer and MaBacSi are the patient and doctor of the record the
+	// prescription belongs to.
+	MaCustomer string `json:"ma_customer"`
+	MaBacSi    string `json:"ma_bac_si"`
+}
+
+// PrescriptionSummary is a prescription with its verification code and the
+// names of its patient and doctor.
+type PrescriptionSummary struct {
+	Prescription
+	MaXacThuc    string `json:"ma_xac_thuc"`
+	TenKhachHang string `json:"ten_khach_hang"`
+	TenBacSi     string `json:"ten_bac_si"`
 }
 
 type PrescriptionDetail struct {
@@ -191,10 +320,16 @@ type RegisterRequest struct {
 	SoDienThoai string `json:"so_dien_thoai"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type AuthResponse struct {
 	Token        string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 	User         User   `json:"user"`
+	// RecoveryCodes are returned once, when a login completes 2FA enrolment.
+	RecoveryCodes []string `json:"ma_khoi_phuc,omitempty"`
 }
 
 type APIResponse struct {
@@ -208,17 +343,23 @@ type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required"`
 }
 
+// ResetPasswordRequest takes either the emailed code with the email
+// address, or the token from the reset link.
 type ResetPasswordRequest struct {
-	Email       string `json:"email" binding:"required"`
-	ResetCode   string `json:"reset_code" binding:"required"`
+	Email       string `json:"email"`
+	ResetCode   string `json:"reset_code"`
+	Token       string `json:"token"`
 	NewPassword string `json:"new_password" binding:"required"`
 }
 
+// PasswordReset is a pending reset. ResetCode holds a SHA-256 hash of the
+// code, never the code itself.
 type PasswordReset struct {
 	ID        string    `json:"id" db:"ID"`
 	UserID    string    `json:"user_id" db:"UserID"`
 	Email     string    `json:"email" db:"email"`
-	ResetCode string    `json:"reset_code" db:"ResetCode"`
+	ResetCode string    `json:"-" db:"ResetCode"`
+	Attempts  int       `json:"attempts" db:"SoLanThu"`
 	IsUsed    bool      `json:"is_used" db:"IsUsed"`
 	ExpiresAt time.Time `json:"expires_at" db:"ExpiresAt"`
 	CreatedAt time.Time `json:"created_at" db:"CreatedAt"`
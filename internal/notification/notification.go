@@ -0,0 +1,47 @@
+// Package notification delivers templated messages to users by email and
+// SMS through a persisted outbox.
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Delivery channels.
+const (
+	ChannelEmail = "EMAIL"
+	ChannelSMS   = "SMS"
+)
+
+// ErrNoChannel is returned when a user cannot be reached on any channel,
+// because of missing contact details, preferences or providers.
+var ErrNoChannel = errors.New("no delivery channel available for user")
+
+// Message is a notification addressed to a user of the system.
+type Message struct {
+	UserID string
+	// Template is a key of the built-in templates, e.g. TemplatePasswordReset.
+	Template string
+	Data     map[string]string
+	// Reference identifies the record the message is about, e.g. a
+	// follow-up or appointment ID.
+	Reference string
+	// Channels, when set, forces delivery on these channels regardless of
+	// the user's preferences; used for account security messages.
+	Channels []string
+}
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// LogNotifier renders nothing and writes messages to the application log.
+// It is used where no database-backed Service is available.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, msg Message) error {
+	log.Printf("notify %s [%s] %s: %v", msg.UserID, msg.Reference, msg.Template, msg.Data)
+	return nil
+}
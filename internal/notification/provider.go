@@ -0,0 +1,134 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider sends a rendered message on one channel.
+type Provider interface {
+	Channel() string
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPProvider sends email through an SMTP server using PLAIN auth when a
+// username is set.
+type SMTPProvider struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (p *SMTPProvider) Channel() string { return ChannelEmail }
+
+func (p *SMTPProvider) Send(ctx context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if p.Username != "" {
+		auth = smtp.PlainAuth("", p.Username, p.Password, p.Host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", p.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := fmt.Sprintf("%s:%d", p.Host, p.Port)
+	return smtp.SendMail(addr, auth, p.From, []string{to}, msg.Bytes())
+}
+
+// SMSGatewayProvider posts messages to an HTTP SMS gateway as JSON
+// {"to", "from", "message"} with a bearer API key. Any 2xx response counts
+// as accepted.
+type SMSGatewayProvider struct {
+	URL    string
+	APIKey string
+	Sender string
+	Client *http.Client
+}
+
+func (p *SMSGatewayProvider) Channel() string { return ChannelSMS }
+
+func (p *SMSGatewayProvider) Send(ctx context.Context, to, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"to":      to,
+		"from":    p.Sender,
+		"message": body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// WriterProvider is the development stand-in: it writes every message to
+// a writer, stdout by default, instead of delivering it.
+type WriterProvider struct {
+	channel string
+	mu      sync.Mutex
+	w       io.Writer
+}
+
+// NewConsoleProvider writes messages for the channel to stdout.
+func NewConsoleProvider(channel string) *WriterProvider {
+	return &WriterProvider{channel: channel, w: os.Stdout}
+}
+
+// NewFileProvider appends messages for the channel to the file at path.
+func NewFileProvider(channel, path string) (*WriterProvider, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterProvider{channel: channel, w: f}, nil
+}
+
+func (p *WriterProvider) Channel() string { return p.channel }
+
+func (p *WriterProvider) Send(ctx context.Context, to, subject, body string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "=== %s %s to %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), p.channel, to, subject, body)
+	return err
+}
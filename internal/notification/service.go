@@ -0,0 +1,238 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// leaseDuration keeps a message claimed by one sender; if the sender
+	// dies the message becomes due again afterwards.
+	leaseDuration = 5 * time.Minute
+	batchSize     = 20
+	redactedBody  = "[đã ẩn]"
+)
+
+// Service renders messages, stores them in the THONGBAO outbox and
+// delivers them through the configured providers. Messages that fail are
+// retried with exponential backoff up to maxAttempts.
+type Service struct {
+	db          *sql.DB
+	providers   map[string]Provider
+	maxAttempts int
+}
+
+func NewService(db *sql.DB, maxAttempts int, providers ...Provider) *Service {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	s := &Service{db: db, providers: map[string]Provider{}, maxAttempts: maxAttempts}
+	for _, p := range providers {
+		s.providers[p.Channel()] = p
+	}
+	return s
+}
+
+// Preferences are a user's contact settings. Users without a row in
+// TUYCHONTHONGBAO get DefaultPreferences.
+type Preferences struct {
+	Email    bool
+	SMS      bool
+	Language string
+}
+
+var DefaultPreferences = Preferences{Email: true, SMS: true, Language: DefaultLanguage}
+
+// LoadPreferences reads a user's contact preferences.
+func LoadPreferences(db *sql.DB, userID string) (Preferences, error) {
+	prefs := DefaultPreferences
+	err := db.QueryRow("SELECT nhanEmail, nhanSMS, ngonNgu FROM TUYCHONTHONGBAO WHERE maUser = @p1", userID).
+		Scan(&prefs.Email, &prefs.SMS, &prefs.Language)
+	if err != nil && err != sql.ErrNoRows {
+		return prefs, err
+	}
+	return prefs, nil
+}
+
+type outboxMessage struct {
+	id       int64
+	channel  string
+	to       string
+	subject  string
+	body     string
+	attempts int
+}
+
+// Notify queues the message on every channel the user can be reached on
+// and tries to deliver it straight away. Delivery errors are not returned:
+// the message stays in the outbox and is retried by Run.
+func (s *Service) Notify(ctx context.Context, msg Message) error {
+	var name string
+	var email, phone sql.NullString
+	err := s.db.QueryRow("SELECT hoTen, email, soDienThoai FROM [USER] WHERE userID = @p1", msg.UserID).
+		Scan(&name, &email, &phone)
+	if err != nil {
+		return fmt.Errorf("error loading recipient: %v", err)
+	}
+
+	prefs, err := LoadPreferences(s.db, msg.UserID)
+	if err != nil {
+		return fmt.Errorf("error loading preferences: %v", err)
+	}
+
+	channels := msg.Channels
+	if len(channels) == 0 {
+		if prefs.Email {
+			channels = append(channels, ChannelEmail)
+		}
+		if prefs.SMS {
+			channels = append(channels, ChannelSMS)
+		}
+	}
+
+	data := map[string]string{"ho_ten": name}
+	for k, v := range msg.Data {
+		data[k] = v
+	}
+
+	var queued []outboxMessage
+	for _, channel := range channels {
+		to := email.String
+		if channel == ChannelSMS {
+			to = phone.String
+		}
+		if to == "" || s.providers[channel] == nil {
+			continue
+		}
+
+		subject, body, sensitive, err := render(msg.Template, prefs.Language, channel, data)
+		if err != nil {
+			return err
+		}
+
+		// The new row is leased to this call so the outbox worker does not
+		// pick it up while it is being sent below.
+		var id int64
+		err = s.db.QueryRow(`
+			INSERT INTO THONGBAO (maUser, kenh, nguoiNhan, mauTin, tieuDe, noiDung, nhayCam, thamChieu,
+				trangThai, soLanThu, ngayTao, thuLaiLuc)
+			OUTPUT INSERTED.maThongBao
+			VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, 'PENDING', 0, GETDATE(), @p9)
+		`, msg.UserID, channel, to, msg.Template, subject, body, sensitive, nullable(msg.Reference),
+			time.Now().Add(leaseDuration)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error queueing notification: %v", err)
+		}
+		queued = append(queued, outboxMessage{id: id, channel: channel, to: to, subject: subject, body: body})
+	}
+
+	if len(queued) == 0 {
+		return ErrNoChannel
+	}
+	for _, m := range queued {
+		s.deliver(ctx, m)
+	}
+	return nil
+}
+
+// Run delivers due outbox messages every interval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ProcessOutbox(ctx); err != nil {
+			log.Printf("notification outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessOutbox claims a batch of due messages and sends them.
+func (s *Service) ProcessOutbox(ctx context.Context) error {
+	rows, err := s.db.Query(`
+		UPDATE TOP (@p1) THONGBAO WITH (READPAST)
+		SET thuLaiLuc = @p2
+		OUTPUT INSERTED.maThongBao, INSERTED.kenh, INSERTED.nguoiNhan, INSERTED.tieuDe, INSERTED.noiDung, INSERTED.soLanThu
+		WHERE trangThai = 'PENDING' AND thuLaiLuc <= GETDATE()
+	`, batchSize, time.Now().Add(leaseDuration))
+	if err != nil {
+		return fmt.Errorf("error claiming messages: %v", err)
+	}
+
+	var batch []outboxMessage
+	for rows.Next() {
+		var m outboxMessage
+		if err := rows.Scan(&m.id, &m.channel, &m.to, &m.subject, &m.body, &m.attempts); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning message: %v", err)
+		}
+		batch = append(batch, m)
+	}
+	rows.Close()
+
+	for _, m := range batch {
+		s.deliver(ctx, m)
+	}
+	return nil
+}
+
+func (s *Service) deliver(ctx context.Context, m outboxMessage) {
+	provider := s.providers[m.channel]
+	if provider == nil {
+		s.recordFailure(m, fmt.Errorf("no provider for channel %s", m.channel))
+		return
+	}
+
+	if err := provider.Send(ctx, m.to, m.subject, m.body); err != nil {
+		s.recordFailure(m, err)
+		return
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE THONGBAO SET trangThai = 'SENT', soLanThu = soLanThu + 1, guiLuc = GETDATE(), loiCuoi = NULL,
+			noiDung = CASE WHEN nhayCam = 1 THEN @p1 ELSE noiDung END
+		WHERE maThongBao = @p2
+	`, redactedBody, m.id)
+	if err != nil {
+		log.Printf("notification %d sent but not marked: %v", m.id, err)
+	}
+}
+
+// recordFailure schedules another attempt after 1, 2, 4, ... minutes, or
+// gives up once maxAttempts is reached.
+func (s *Service) recordFailure(m outboxMessage, sendErr error) {
+	attempts := m.attempts + 1
+	status := "PENDING"
+	if attempts >= s.maxAttempts {
+		status = "FAILED"
+	}
+	retryAt := time.Now().Add(time.Duration(1<<uint(attempts-1)) * time.Minute)
+
+	errText := sendErr.Error()
+	if len(errText) > 1000 {
+		errText = errText[:1000]
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE THONGBAO SET trangThai = @p1, soLanThu = @p2, thuLaiLuc = @p3, loiCuoi = @p4
+		WHERE maThongBao = @p5
+	`, status, attempts, retryAt, errText, m.id)
+	if err != nil {
+		log.Printf("notification %d failed (%v) and could not be updated: %v", m.id, sendErr, err)
+	}
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// Template keys.
+const (
+	TemplatePasswordReset       = "password_reset"
+	TemplateFollowUpSuggested   = "follow_up_suggested"
+	TemplateFollowUpNoSlot      = "follow_up_no_slot"
+	TemplateAppointmentReminder = "appointment_reminder"
+	TemplateAccountLocked       = "account_locked"
+	TemplateStaffInvitation     = "staff_invitation"
+)
+
+// Supported languages; DefaultLanguage is used when the user has no
+// preference or the template lacks a translation.
+const (
+	LanguageVietnamese = "vi"
+	LanguageEnglish    = "en"
+	DefaultLanguage    = LanguageVietnamese
+)
+
+type messageTemplate struct {
+	Subject string
+	Body    string
+	// SMS is the short form sent by text message; Body is used if empty.
+	SMS string
+}
+
+type templateSet struct {
+	// Sensitive messages, such as ones carrying a one-time code, have
+	// their body removed from the outbox once delivered.
+	Sensitive bool
+	Languages map[string]messageTemplate
+}
+
+// Templates use the keys of Message.Data; ho_ten is always filled in with
+// the recipient's name.
+var templates = map[string]templateSet{
+	TemplatePasswordReset: {
+		Sensitive: true,
+		Languages: map[string]messageTemplate{
+			LanguageVietnamese: {
+				Subject: "Mã đặt lại mật khẩu",
+				Body:    "Xin chào {{.ho_ten}},\n\nMã đặt lại mật khẩu của bạn là: {{.ma_xac_nhan}}\nMã có hiệu lực trong {{.so_phut}} phút.\nHoặc đặt lại mật khẩu tại: {{.link_dat_lai}}\n\nNếu bạn không yêu cầu đặt lại mật khẩu, hãy bỏ qua thư này.",
+				SMS:     "Ma dat lai mat khau cua ban la {{.ma_xac_nhan}}, hieu luc {{.so_phut}} phut. Khong chia se ma nay.",
+			},
+			LanguageEnglish: {
+				Subject: "Your password reset code",
+				Body:    "Hello {{.ho_ten}},\n\nYour password reset code is: {{.ma_xac_nhan}}\nThe code is valid for {{.so_phut}} minutes.\nOr reset your password here: {{.link_dat_lai}}\n\nIf you did not ask to reset your password, you can ignore this email.",
+				SMS:     "Your password reset code is {{.ma_xac_nhan}}, valid for {{.so_phut}} minutes. Do not share it.",
+			},
+		},
+	},
+	TemplateFollowUpSuggested: {
+		Languages: map[string]messageTemplate{
+			LanguageVietnamese: {
+				Subject: "Lịch tái khám",
+				Body:    "Xin chào {{.ho_ten}},\n\nBác sĩ {{.ten_bac_si}} hẹn bạn tái khám. Lịch gợi ý: {{.thoi_gian}} tại {{.ten_phong_kham}}.\nVui lòng xác nhận hoặc chọn giờ khác trong ứng dụng.",
+				SMS:     "Lich tai kham goi y voi BS {{.ten_bac_si}}: {{.thoi_gian}} tai {{.ten_phong_kham}}. Vui long xac nhan trong ung dung.",
+			},
+			LanguageEnglish: {
+				Subject: "Follow-up appointment",
+				Body:    "Hello {{.ho_ten}},\n\nDr. {{.ten_bac_si}} asked to see you again. Suggested appointment: {{.thoi_gian}} at {{.ten_phong_kham}}.\nPlease confirm it or pick another time in the app.",
+				SMS:     "Suggested follow-up with Dr. {{.ten_bac_si}}: {{.thoi_gian}} at {{.ten_phong_kham}}. Please confirm in the app.",
+			},
+		},
+	},
+	TemplateFollowUpNoSlot: {
+		Languages: map[string]messageTemplate{
+			LanguageVietnamese: {
+				Subject: "Lịch tái khám",
+				Body:    "Xin chào {{.ho_ten}},\n\nBạn có lịch tái khám ngày {{.ngay_tai_kham}} với bác sĩ {{.ten_bac_si}} tại {{.ten_phong_kham}}. Hiện chưa có giờ trống, vui lòng đặt lịch khám.",
+			},
+			LanguageEnglish: {
+				Subject: "Follow-up appointment",
+				Body:    "Hello {{.ho_ten}},\n\nYou are due for a follow-up on {{.ngay_tai_kham}} with Dr. {{.ten_bac_si}} at {{.ten_phong_kham}}. No slot is free yet, please book an appointment.",
+			},
+		},
+	},
+	TemplateAppointmentReminder: {
+		Languages: map[string]messageTemplate{
+			LanguageVietnamese: {
+				Subject: "Nhắc lịch khám",
+				Body:    "Xin chào {{.ho_ten}},\n\nBạn có lịch khám với bác sĩ {{.ten_bac_si}} lúc {{.thoi_gian}} tại {{.ten_phong_kham}}.\n\nXác nhận: {{.link_xac_nhan}}\nHủy lịch: {{.link_huy}}",
+				SMS:     "Nhac lich kham voi BS {{.ten_bac_si}} luc {{.thoi_gian}} tai {{.ten_phong_kham}}. Xac nhan: {{.link_xac_nhan}} Huy: {{.link_huy}}",
+			},
+			LanguageEnglish: {
+				Subject: "Appointment reminder",
+				Body:    "Hello {{.ho_ten}},\n\nYou have an appointment with Dr. {{.ten_bac_si}} at {{.thoi_gian}} at {{.ten_phong_kham}}.\n\nConfirm: {{.link_xac_nhan}}\nCancel: {{.link_huy}}",
+				SMS:     "Reminder: appointment with Dr. {{.ten_bac_si}} at {{.thoi_gian}}, {{.ten_phong_kham}}. Confirm: {{.link_xac_nhan}} Cancel: {{.link_huy}}",
+			},
+		},
+	},
+	TemplateAccountLocked: {
+		Languages: map[string]messageTemplate{
+			LanguageVietnamese: {
+				Subject: "Tài khoản tạm thời bị khóa",
+				Body:    "Xin chào {{.ho_ten}},\n\nTài khoản của bạn đã bị khóa đến {{.khoa_den}} sau {{.so_lan}} lần đăng nhập sai liên tiếp, lần gần nhất từ địa chỉ IP {{.dia_chi_ip}}.\nNếu đó không phải là bạn, hãy đổi mật khẩu ngay khi đăng nhập lại hoặc liên hệ phòng khám.",
+				SMS:     "Tai khoan cua ban bi khoa den {{.khoa_den}} sau {{.so_lan}} lan dang nhap sai tu IP {{.dia_chi_ip}}. Neu khong phai ban, hay doi mat khau.",
+			},
+			LanguageEnglish: {
+				Subject: "Your account is temporarily locked",
+				Body:    "Hello {{.ho_ten}},\n\nYour account is locked until {{.khoa_den}} after {{.so_lan}} failed logins in a row, the latest from IP address {{.dia_chi_ip}}.\nIf this was not you, change your password as soon as you can log in again or contact the clinic.",
+				SMS:     "Your account is locked until {{.khoa_den}} after {{.so_lan}} failed logins from IP {{.dia_chi_ip}}. If this was not you, change your password.",
+			},
+		},
+	},
+	TemplateStaffInvitation: {
+		Sensitive: true,
+		Languages: map[string]messageTemplate{
+			LanguageVietnamese: {
+				Subject: "Lời mời tham gia hệ thống phòng khám",
+				Body:    "Xin chào {{.ho_ten}},\n\nBạn đã được tạo tài khoản {{.vai_tro}} với tên đăng nhập {{.ten_dang_nhap}}.\nHãy đặt mật khẩu để kích hoạt tài khoản tại: {{.link_kich_hoat}}\nLink có hiệu lực trong {{.so_gio}} giờ.",
+			},
+			LanguageEnglish: {
+				Subject: "Your clinic staff account",
+				Body:    "Hello {{.ho_ten}},\n\nA {{.vai_tro}} account with username {{.ten_dang_nhap}} has been created for you.\nSet your password to activate it here: {{.link_kich_hoat}}\nThe link is valid for {{.so_gio}} hours.",
+			},
+		},
+	},
+}
+
+// render fills in a template for a channel and language.
+func render(key, language, channel string, data map[string]string) (subject, body string, sensitive bool, err error) {
+	set, ok := templates[key]
+	if !ok {
+		return "", "", false, fmt.Errorf("unknown notification template %q", key)
+	}
+	tmpl, ok := set.Languages[language]
+	if !ok {
+		tmpl = set.Languages[DefaultLanguage]
+	}
+
+	text := tmpl.Body
+	if channel == ChannelSMS && tmpl.SMS != "" {
+		text = tmpl.SMS
+	}
+
+	if subject, err = execute(tmpl.Subject, data); err != nil {
+		return "", "", false, err
+	}
+	if body, err = execute(text, data); err != nil {
+		return "", "", false, err
+	}
+	return subject, body, set.Sensitive, nil
+}
+
+func execute(text string, data map[string]string) (string, error) {
+	t, err := template.New("").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ValidLanguage reports whether templates are available in a language.
+func ValidLanguage(language string) bool {
+	return language == LanguageVietnamese || language == LanguageEnglish
+}
@@ -0,0 +1,409 @@
+// Package openapi builds an OpenAPI 3 document from the Go types of the
+// request and response bodies, and checks responses against it. Schemas
+// follow encoding/json: a field is named by its json tag, embedded structs
+// are flattened, pointers, slices and maps may be null, and a field without
+// omitempty is always present.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema is the subset of JSON Schema the generated document uses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+// Param is a query parameter of an endpoint.
+type Param struct {
+	Name        string
+	Description string
+	Enum        []string
+}
+
+// Endpoint describes one route. Path uses gin's :name syntax. Request and
+// Response are values of the request body and data types, nil if there is
+// none; Request may be an Upload and Response an AnyOf. A List endpoint returns a page of Response
+// items with pagination. Status is the status of a successful response, 200
+// when zero. Errors are the data types of the error responses that may
+// carry data, by status.
+type Endpoint struct {
+	Method   string
+	Path     string
+	Summary  string
+	Tag      string
+	Public   bool
+	Query    []Param
+	Request  interface{}
+	Response interface{}
+	List     bool
+	Status   int
+	Errors   map[int]interface{}
+}
+
+// AnyOf is a Response that is a value of one of the types, e.g. of the
+// role-specific profile types.
+type AnyOf []interface{}
+
+// Upload is a Request sent as multipart/form-data with one file in Field.
+type Upload struct {
+	Field string
+}
+
+const bearerAuth = "bearerAuth"
+
+// Build generates the document for endpoints under the server URL base.
+// pagination is a value of the type of the pagination field of list
+// responses.
+func Build(info Info, base string, pagination interface{}, endpoints []Endpoint) *Document {
+	g := &generator{schemas: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []Server{{URL: base}},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	paginationSchema := g.schema(reflect.TypeOf(pagination))
+	g.schemas["Error"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+			"error":   {Type: "string"},
+		},
+		Required:             []string{"success", "message"},
+		AdditionalProperties: false,
+	}
+
+	for _, e := range endpoints {
+		path, pathParams := templatePath(e.Path)
+		op := &Operation{
+			OperationID: operationID(e.Method, path),
+			Summary:     e.Summary,
+			Responses: map[string]Response{
+				"default": {
+					Description: "Error",
+					Content:     jsonContent(&Schema{Ref: "#/components/schemas/Error"}),
+				},
+			},
+		}
+		if e.Tag != "" {
+			op.Tags = []string{e.Tag}
+		}
+		if !e.Public {
+			op.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+		for _, name := range pathParams {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		for _, q := range e.Query {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: q.Name, In: "query", Description: q.Description, Schema: &Schema{Type: "string", Enum: q.Enum},
+			})
+		}
+		if upload, ok := e.Request.(Upload); ok {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{"multipart/form-data": {Schema: &Schema{
+					Type:       "object",
+					Properties: map[string]*Schema{upload.Field: {Type: "string", Format: "binary"}},
+					Required:   []string{upload.Field},
+				}}},
+			}
+		} else if e.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(g.schema(reflect.TypeOf(e.Request))),
+			}
+		}
+
+		for status, data := range e.Errors {
+			failure := &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"success": {Type: "boolean"},
+					"message": {Type: "string"},
+					"error":   {Type: "string"},
+					"data":    g.schema(reflect.TypeOf(data)),
+				},
+				Required:             []string{"success", "message"},
+				AdditionalProperties: false,
+			}
+			op.Responses[strconv.Itoa(status)] = Response{Description: http.StatusText(status), Content: jsonContent(failure)}
+		}
+
+		envelope := &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+				"message": {Type: "string"},
+			},
+			Required:             []string{"success", "message"},
+			AdditionalProperties: false,
+		}
+		if e.Response != nil {
+			data := g.data(e.Response)
+			if e.List {
+				data = &Schema{Type: "array", Items: data}
+				envelope.Properties["pagination"] = paginationSchema
+				envelope.Required = append(envelope.Required, "pagination")
+			}
+			envelope.Properties["data"] = data
+			envelope.Required = append(envelope.Required, "data")
+		}
+		status := e.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		op.Responses[strconv.Itoa(status)] = Response{Description: http.StatusText(status), Content: jsonContent(envelope)}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(e.Method)] = op
+	}
+	return doc
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// templatePath turns /a/:id into /a/{id} and returns the parameter names.
+func templatePath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID names an operation after its method and path, e.g.
+// getAppointmentsById for GET /appointments/{id}.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		by := strings.HasPrefix(segment, "{")
+		segment = strings.Trim(segment, "{}")
+		if segment == "" {
+			continue
+		}
+		if by {
+			id += "By"
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// data returns the schema of a Response value.
+func (g *generator) data(response interface{}) *Schema {
+	if types, ok := response.(AnyOf); ok {
+		s := &Schema{}
+		for _, t := range types {
+			s.AnyOf = append(s.AnyOf, g.schema(reflect.TypeOf(t)))
+		}
+		return s
+	}
+	return g.schema(reflect.TypeOf(response))
+}
+
+// schema returns the schema of t. Named structs are added to the
+// components and referenced.
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(marshalerType):
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = nil // reserve the name for recursive types
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	// interface{} and anything else may hold any value.
+	return &Schema{}
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(s, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nullable allows null besides what s allows. A reference cannot carry
+// other keywords, so it is wrapped in allOf.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AllOf: []*Schema{s}, Nullable: true}
+	}
+	if s.Type == "" {
+		return s
+	}
+	s.Nullable = true
+	return s
+}
@@ -0,0 +1,203 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks a response body against the document: the
+// operation for method and path (relative to the server URL), and its
+// response for status, or the default response.
+func (d *Document) ValidateResponse(method, path string, status int, body []byte) error {
+	op := d.operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+		}
+	}
+	media, ok := response.Content["application/json"]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d has no JSON body", method, path, status)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return d.validate(media.Schema, value, "body")
+}
+
+// operation finds the operation whose path template matches path. As in
+// the router, a literal segment wins over a parameter, so /a/b is not taken
+// for /a/{id}.
+func (d *Document) operation(method, path string) *Operation {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var best *Operation
+	bestLiterals := -1
+	for template, item := range d.Paths {
+		op := item[strings.ToLower(method)]
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if op == nil || len(parts) != len(segments) {
+			continue
+		}
+		literals := 0
+		match := true
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") {
+				continue
+			}
+			if part != segments[i] {
+				match = false
+				break
+			}
+			literals++
+		}
+		if match && literals > bestLiterals {
+			best, bestLiterals = op, literals
+		}
+	}
+	return best
+}
+
+func (d *Document) resolve(s *Schema) (*Schema, error) {
+	for s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		target, ok := d.Components.Schemas[name]
+		if !ok || target == nil {
+			return nil, fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		s = target
+	}
+	return s, nil
+}
+
+func (d *Document) validate(s *Schema, value interface{}, at string) error {
+	s, err := d.resolve(s)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if s.Nullable || (s.Type == "" && len(s.AllOf) == 0 && len(s.AnyOf) == 0) {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+	for _, part := range s.AllOf {
+		if err := d.validate(part, value, at); err != nil {
+			return err
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		var errs []string
+		for _, part := range s.AnyOf {
+			err := d.validate(part, value, at)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if errs != nil {
+			return fmt.Errorf("%s: matches none of its schemas: %s", at, strings.Join(errs, "; "))
+		}
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want an object, got %T", at, value)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing %s", at, name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			if !ok {
+				switch extra := s.AdditionalProperties.(type) {
+				case *Schema:
+					property = extra
+				case bool:
+					if !extra {
+						return fmt.Errorf("%s: undocumented field %s", at, name)
+					}
+					continue
+				default:
+					continue
+				}
+			}
+			if err := d.validate(property, object[name], at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want an array, got %T", at, value)
+		}
+		for i, item := range array {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a string, got %T", at, value)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, str)
+			}
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q is not one of %v", at, str, s.Enum)
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: want an integer, got %T", at, value)
+		}
+		if _, err := number.Int64(); err != nil {
+			return fmt.Errorf("%s: %s is not an integer", at, number)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s: want a number, got %T", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: want a boolean, got %T", at, value)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
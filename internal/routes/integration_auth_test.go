@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"clinic-management/internal/totp"
+)
+
+func authFlow(t *testing.T, s *integrationServer) {
+	s.call(t, "", http.MethodGet, "/openapi.json", nil, http.StatusOK)
+	s.call(t, "", http.MethodGet, "/.well-known/jwks.json", nil, http.StatusOK)
+
+	s.call(t, "", http.MethodPost, "/auth/register", map[string]string{
+		"ho_ten": "Nguyễn Văn Mới", "ten_dang_nhap": "newpatient", "mat_khau": testPassword,
+		"email": "newpatient@clinic.local", "so_dien_thoai": "0911111111",
+	}, http.StatusCreated)
+	s.call(t, "", http.MethodPost, "/auth/register", map[string]string{
+		"ho_ten": "Trùng", "ten_dang_nhap": "newpatient", "mat_khau": testPassword,
+	}, http.StatusConflict)
+
+	s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": "newpatient", "mat_khau": "wrong",
+	}, http.StatusUnauthorized)
+	login := s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": "newpatient", "mat_khau": testPassword,
+	}, http.StatusOK)
+	s.tokens["newpatient"] = login.str(t, "token")
+
+	refreshed := s.call(t, "", http.MethodPost, "/auth/refresh", map[string]string{
+		"refresh_token": login.str(t, "refresh_token"),
+	}, http.StatusOK)
+	s.tokens["newpatient"] = refreshed.str(t, "token")
+
+	s.call(t, "", http.MethodPost, "/auth/forgot-password", map[string]string{
+		"email": "newpatient@clinic.local",
+	}, http.StatusOK)
+	code := field(t, s.outbox.last(t, "newpatient@clinic.local"), "là: ", "\n")
+	s.call(t, "", http.MethodPost, "/auth/reset-password", map[string]string{
+		"email": "newpatient@clinic.local", "reset_code": "000000", "new_password": "Another123!",
+	}, http.StatusBadRequest)
+	s.call(t, "", http.MethodPost, "/auth/reset-password", map[string]string{
+		"email": "newpatient@clinic.local", "reset_code": code, "new_password": "Another123!",
+	}, http.StatusOK)
+	// Resetting the password ends the other sessions.
+	s.call(t, "newpatient", http.MethodGet, "/users/profile", nil, http.StatusUnauthorized)
+	s.tokens["newpatient"] = s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": "newpatient", "mat_khau": "Another123!",
+	}, http.StatusOK).str(t, "token")
+
+	s.call(t, "newpatient", http.MethodPost, "/auth/logout", nil, http.StatusOK)
+	s.call(t, "newpatient", http.MethodGet, "/users/profile", nil, http.StatusUnauthorized)
+	delete(s.tokens, "newpatient")
+}
+
+// otp returns the code of a TOTP secret offset steps from now; each step
+// can be used once.
+func otp(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func twoFactorFlow(t *testing.T, s *integrationServer) {
+	s.call(t, customer, http.MethodGet, "/users/2fa", nil, http.StatusOK)
+	secret := s.call(t, customer, http.MethodPost, "/users/2fa/setup", nil, http.StatusOK).str(t, "bi_mat")
+	s.call(t, customer, http.MethodPost, "/users/2fa/enable", map[string]string{"ma_otp": "000000"}, http.StatusBadRequest)
+	enabled := s.call(t, customer, http.MethodPost, "/users/2fa/enable", map[string]string{
+		"ma_otp": otp(t, secret, -1),
+	}, http.StatusOK)
+	recoveryCodes := enabled.object(t)["ma_khoi_phuc"].([]interface{})
+
+	// Logging in now takes a second factor; a recovery code works once.
+	challenge := s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": customer, "mat_khau": testPassword,
+	}, http.StatusOK).str(t, "challenge_token")
+	verified := s.call(t, "", http.MethodPost, "/auth/2fa/verify", map[string]string{
+		"challenge_token": challenge, "ma_khoi_phuc": recoveryCodes[0].(string),
+	}, http.StatusOK)
+	s.tokens[customer] = verified.str(t, "token")
+
+	s.call(t, customer, http.MethodPost, "/users/2fa/recovery-codes", map[string]string{
+		"ma_otp": otp(t, secret, 0),
+	}, http.StatusOK)
+	s.call(t, customer, http.MethodPost, "/users/2fa/disable", map[string]string{
+		"mat_khau": testPassword, "ma_otp": otp(t, secret, 1),
+	}, http.StatusOK)
+	delete(s.tokens, customer)
+
+	// Accountants must enrol when they first log in.
+	login := s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": accountant, "mat_khau": testPassword,
+	}, http.StatusOK)
+	challenge = login.str(t, "challenge_token")
+	secret = s.call(t, "", http.MethodPost, "/auth/2fa/enroll", map[string]string{
+		"challenge_token": challenge,
+	}, http.StatusOK).str(t, "bi_mat")
+	enrolled := s.call(t, "", http.MethodPost, "/auth/2fa/enroll/confirm", map[string]string{
+		"challenge_token": challenge, "ma_otp": otp(t, secret, 0),
+	}, http.StatusOK)
+	s.tokens[accountant] = enrolled.str(t, "token")
+}
+
+func userFlow(t *testing.T, s *integrationServer) {
+	for _, user := range []string{operationManager, clinicManager, doctor, receptionist, accountant, customer} {
+		profile := s.call(t, user, http.MethodGet, "/users/profile", nil, http.StatusOK)
+		if got := profile.str(t, "ma_user"); got != s.userIDs[user] {
+			t.Errorf("profile of %s is %s", user, got)
+		}
+	}
+
+	s.call(t, customer, http.MethodPut, "/users/profile", map[string]interface{}{
+		"ho_ten": "Trần Thị Khách", "so_dien_thoai": "0912345678", "dia_chi": "12 Nguyễn Huệ",
+		"ngay_sinh": "1991-02-03", "gioi_tinh": "Nữ",
+	}, http.StatusOK)
+	s.call(t, doctor, http.MethodPut, "/users/profile", map[string]interface{}{
+		"ho_ten": "BS. Lê Văn Khám",
+	}, http.StatusOK)
+
+	s.call(t, receptionist, http.MethodPut, "/users/password", map[string]string{
+		"old_password": "wrong", "new_password": "Changed123!",
+	}, http.StatusBadRequest)
+	s.call(t, receptionist, http.MethodPut, "/users/password", map[string]string{
+		"old_password": testPassword, "new_password": "Changed123!",
+	}, http.StatusOK)
+	s.call(t, receptionist, http.MethodPut, "/users/password", map[string]string{
+		"old_password": "Changed123!", "new_password": testPassword,
+	}, http.StatusOK)
+}
+
+func notificationFlow(t *testing.T, s *integrationServer) {
+	s.call(t, customer, http.MethodGet, "/users/notification-preferences", nil, http.StatusOK)
+	s.call(t, customer, http.MethodPut, "/users/notification-preferences", map[string]interface{}{
+		"ngon_ngu": "fr",
+	}, http.StatusBadRequest)
+	prefs := s.call(t, customer, http.MethodPut, "/users/notification-preferences", map[string]interface{}{
+		"nhan_email": true, "nhan_sms": false, "ngon_ngu": "en",
+	}, http.StatusOK).object(t)
+	if prefs["ngon_ngu"] != "en" || prefs["nhan_sms"] != false {
+		t.Errorf("preferences = %v", prefs)
+	}
+	if sent := s.call(t, customer, http.MethodGet, "/users/notifications", nil, http.StatusOK).list(t); len(sent) == 0 {
+		t.Error("customer has no notifications")
+	}
+
+	// The doctor signs in on two more devices, signs one out from the
+	// other and then signs out everywhere else.
+	first := s.login(t, doctor)
+	s.login(t, doctor)
+	var other string
+	for _, session := range s.call(t, doctor, http.MethodGet, "/users/sessions", nil, http.StatusOK).list(t) {
+		if session["hien_tai"] != true {
+			other, _ = session["ma_phien"].(string)
+		}
+	}
+	if other == "" {
+		t.Fatal("doctor has no other session")
+	}
+	s.call(t, doctor, http.MethodDelete, "/users/sessions/"+other, nil, http.StatusOK)
+	s.call(t, doctor, http.MethodDelete, "/users/sessions/"+other, nil, http.StatusNotFound)
+	s.login(t, doctor)
+	revoked := s.call(t, doctor, http.MethodPost, "/users/sessions/revoke-others", nil, http.StatusOK).object(t)
+	if revoked["so_phien_da_thu_hoi"].(float64) < 1 {
+		t.Errorf("revoked = %v", revoked)
+	}
+	current := s.tokens[doctor]
+	s.tokens[doctor] = first
+	s.call(t, doctor, http.MethodGet, "/users/profile", nil, http.StatusUnauthorized)
+	s.tokens[doctor] = current
+
+	s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": receptionist, "mat_khau": "wrong",
+	}, http.StatusUnauthorized)
+	s.call(t, receptionist, http.MethodGet, "/admin/login-attempts", nil, http.StatusForbidden)
+	attempts := s.call(t, operationManager, http.MethodGet,
+		"/admin/login-attempts?ten_dang_nhap="+receptionist+"&ket_qua=BAD_PASSWORD", nil, http.StatusOK).list(t)
+	if len(attempts) == 0 {
+		t.Error("failed login was not recorded")
+	}
+	if page := s.call(t, operationManager, http.MethodGet, "/admin/login-attempts?page_size=1", nil, http.StatusOK); len(page.list(t)) != 1 || page.Pagination["has_more"] != true {
+		t.Errorf("login attempts are not paged: %v", page.Pagination)
+	}
+	if future := s.call(t, operationManager, http.MethodGet, "/admin/login-attempts?date_from=2999-01-01", nil, http.StatusOK).list(t); len(future) != 0 {
+		t.Errorf("date_from did not filter login attempts: %v", future)
+	}
+	s.call(t, operationManager, http.MethodGet, "/admin/login-attempts?sort=dia_chi_ip", nil, http.StatusBadRequest)
+	s.call(t, receptionist, http.MethodPost, "/admin/users/"+s.userIDs[receptionist]+"/unlock", nil, http.StatusForbidden)
+	s.call(t, operationManager, http.MethodPost, "/admin/users/"+s.userIDs[receptionist]+"/unlock", nil, http.StatusOK)
+	s.call(t, operationManager, http.MethodPost, "/admin/users/U-MISSING/unlock", nil, http.StatusNotFound)
+}
@@ -0,0 +1,201 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"clinic-management/internal/scheduler"
+)
+
+func clinicFlow(t *testing.T, s *integrationServer) {
+	s.call(t, customer, http.MethodGet, "/clinics", nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, "/clinics/specialties", nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, "/clinics/"+testClinic, nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, "/clinics/PK-NONE", nil, http.StatusNotFound)
+	s.call(t, customer, http.MethodGet, "/clinics/"+testClinic+"/doctors", nil, http.StatusOK)
+
+	clinic := map[string]string{
+		"ten_phong_kham": "Phòng khám Quận 3", "dia_chi": "20 Võ Văn Tần",
+		"so_dien_thoai": "0987654321", "email": "q3@clinic.local",
+	}
+	s.call(t, clinicManager, http.MethodPost, "/clinics", clinic, http.StatusForbidden)
+	s.ids["clinic"] = s.call(t, operationManager, http.MethodPost, "/clinics", clinic, http.StatusCreated).str(t, "ma_phong_kham")
+	s.call(t, operationManager, http.MethodPost, "/clinics", clinic, http.StatusConflict)
+	s.call(t, operationManager, http.MethodPut, "/clinics/"+s.ids["clinic"], map[string]string{
+		"dia_chi": "22 Võ Văn Tần",
+	}, http.StatusOK)
+
+	// Open every day so that appointments on any date fall within hours.
+	var days []map[string]interface{}
+	for thu := 1; thu <= 7; thu++ {
+		days = append(days, map[string]interface{}{"thu": thu, "gio_mo": "06:00", "gio_dong": "22:00"})
+	}
+	s.call(t, clinicManager, http.MethodPut, "/clinics/"+s.ids["clinic"]+"/opening-hours",
+		map[string]interface{}{"ngay": days}, http.StatusForbidden)
+	s.call(t, clinicManager, http.MethodPut, "/clinics/"+testClinic+"/opening-hours",
+		map[string]interface{}{"ngay": days}, http.StatusOK)
+	if hours := s.call(t, customer, http.MethodGet, "/clinics/"+testClinic+"/opening-hours", nil, http.StatusOK); len(hours.list(t)) != 7 {
+		t.Errorf("opening hours = %s", hours.Data)
+	}
+
+	rooms := "/clinics/" + testClinic + "/rooms"
+	s.ids["room"] = s.call(t, clinicManager, http.MethodPost, rooms, map[string]interface{}{
+		"ten_phong": "Phòng khám 1", "loai_phong": "EXAM", "suc_chua": 2,
+	}, http.StatusCreated).str(t, "ma_phong")
+	lab := s.call(t, clinicManager, http.MethodPost, rooms, map[string]interface{}{
+		"ten_phong": "Phòng xét nghiệm", "loai_phong": "LAB",
+	}, http.StatusCreated).str(t, "ma_phong")
+	s.call(t, clinicManager, http.MethodPost, rooms, map[string]interface{}{
+		"ten_phong": "Phòng khám 1",
+	}, http.StatusConflict)
+	s.call(t, clinicManager, http.MethodPut, rooms+"/"+lab, map[string]string{
+		"trang_thai": "MAINTENANCE", "ghi_chu": "Sơn lại tường",
+	}, http.StatusOK)
+	s.call(t, receptionist, http.MethodGet, rooms, nil, http.StatusOK)
+	s.call(t, receptionist, http.MethodGet, rooms+"/"+s.ids["room"]+"/usage?ngay="+day(1), nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, rooms+"/"+s.ids["room"]+"/usage", nil, http.StatusForbidden)
+
+	equipment := "/clinics/" + testClinic + "/equipment"
+	id := s.call(t, clinicManager, http.MethodPost, equipment, map[string]string{
+		"ten_thiet_bi": "Máy đo huyết áp", "ma_phong": s.ids["room"],
+		"ngay_mua": day(-30), "ngay_bao_tri_tiep": day(30),
+	}, http.StatusCreated).str(t, "ma_thiet_bi")
+	s.call(t, clinicManager, http.MethodPut, equipment+"/"+id, map[string]string{
+		"trang_thai": "MAINTENANCE", "ngay_bao_tri_tiep": day(2),
+	}, http.StatusOK)
+	if list := s.call(t, clinicManager, http.MethodGet, equipment, nil, http.StatusOK); len(list.list(t)) != 1 {
+		t.Errorf("equipment = %s", list.Data)
+	}
+}
+
+func appointmentFlow(t *testing.T, s *integrationServer) {
+	doctorID := s.userIDs[doctor]
+	schedule := func(n int) map[string]string {
+		return map[string]string{
+			"ma_bac_si": doctorID, "ma_phong_kham": testClinic, "ngay_lam_viec": day(n),
+			"gio_bat_dau": "08:00", "gio_ket_thuc": "17:00", "ma_phong": s.ids["room"],
+		}
+	}
+	s.call(t, receptionist, http.MethodPost, "/schedules", schedule(1), http.StatusForbidden)
+	// The doctor works every day of the next two weeks, which the
+	// follow-up flow relies on to find slots.
+	for n := 1; n <= 14; n++ {
+		id := s.call(t, clinicManager, http.MethodPost, "/schedules", schedule(n), http.StatusCreated).str(t, "ma_lich_lam_viec")
+		if n == 1 {
+			s.ids["schedule"] = id
+		}
+	}
+	spare := s.call(t, doctor, http.MethodPost, "/schedules", schedule(20), http.StatusCreated).str(t, "ma_lich_lam_viec")
+	s.call(t, doctor, http.MethodGet, "/schedules", nil, http.StatusOK)
+	s.call(t, clinicManager, http.MethodGet, "/schedules/"+s.ids["schedule"], nil, http.StatusOK)
+	s.call(t, clinicManager, http.MethodPut, "/schedules/"+s.ids["schedule"], map[string]string{
+		"gio_ket_thuc": "18:00",
+	}, http.StatusOK)
+	s.call(t, doctor, http.MethodDelete, "/schedules/"+spare, nil, http.StatusOK)
+	s.call(t, doctor, http.MethodGet, "/schedules/"+spare, nil, http.StatusNotFound)
+
+	slots := s.call(t, customer, http.MethodGet,
+		"/clinics/"+testClinic+"/schedules?doctor_id="+doctorID+"&date="+day(1), nil, http.StatusOK)
+	if len(slots.Data) < 3 {
+		t.Errorf("no free slots on %s: %s", day(1), slots.Data)
+	}
+
+	booking := map[string]string{
+		"ma_bac_si": doctorID, "ma_phong_kham": testClinic,
+		"ngay_gio_kham": day(1) + " 09:00", "ghi_chu": "Đau đầu kéo dài",
+	}
+	s.call(t, doctor, http.MethodPost, "/appointments", booking, http.StatusForbidden)
+	s.ids["appointment"] = s.call(t, customer, http.MethodPost, "/appointments", booking, http.StatusCreated).str(t, "appointment_id")
+	s.call(t, customer, http.MethodPost, "/appointments", booking, http.StatusConflict)
+
+	booking["ngay_gio_kham"] = day(1) + " 10:00"
+	cancelled := s.call(t, customer, http.MethodPost, "/appointments", booking, http.StatusCreated).str(t, "appointment_id")
+	s.call(t, customer, http.MethodDelete, "/appointments/"+cancelled, nil, http.StatusOK)
+
+	appointment := s.call(t, customer, http.MethodGet, "/appointments/"+s.ids["appointment"], nil, http.StatusOK).object(t)
+	if appointment["ma_phong"] != s.ids["room"] {
+		t.Errorf("appointment is not in the room of the schedule: %v", appointment)
+	}
+	s.call(t, customer, http.MethodPut, "/appointments/"+s.ids["appointment"], map[string]string{
+		"ghi_chu": "Đau đầu và chóng mặt",
+	}, http.StatusOK)
+	s.call(t, customer, http.MethodPut, "/appointments/"+s.ids["appointment"], map[string]string{
+		"ma_phong": s.ids["room"],
+	}, http.StatusForbidden)
+	s.call(t, doctor, http.MethodPut, "/appointments/"+s.ids["appointment"], map[string]string{
+		"trang_thai": "CONFIRMED",
+	}, http.StatusOK)
+	for _, user := range []string{customer, doctor, receptionist} {
+		s.call(t, user, http.MethodGet, "/appointments?sort=ngay_gio_kham", nil, http.StatusOK)
+	}
+
+	s.call(t, doctor, http.MethodPost, "/customers", map[string]string{
+		"ho_ten": "Khách Vãng Lai", "ten_dang_nhap": "walkin", "mat_khau": testPassword,
+	}, http.StatusForbidden)
+	walkIn := s.call(t, receptionist, http.MethodPost, "/customers", map[string]string{
+		"ho_ten": "Khách Vãng Lai", "ten_dang_nhap": "walkin", "mat_khau": testPassword,
+		"so_dien_thoai": "0912000000", "ngay_sinh": "1985-07-15", "gioi_tinh": "Nữ",
+	}, http.StatusCreated).str(t, "user_id")
+	s.call(t, receptionist, http.MethodGet, "/customers?tu_khoa=walkin", nil, http.StatusOK)
+	s.call(t, receptionist, http.MethodGet, "/customers/"+walkIn, nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, "/customers/"+walkIn, nil, http.StatusForbidden)
+}
+
+func followUpFlow(t *testing.T, s *integrationServer) {
+	// The medical record asked for a follow-up in a week; the patient
+	// already has an appointment with the doctor, so the scheduler links it.
+	followUps := scheduler.NewFollowUpScheduler(s.db, s.notifier, 10)
+	if err := followUps.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	suggested := s.call(t, customer, http.MethodGet, "/follow-ups", nil, http.StatusOK).list(t)
+	if len(suggested) != 1 {
+		t.Fatalf("follow-ups = %v", suggested)
+	}
+	id, _ := suggested[0]["ma_tai_kham"].(string)
+	if suggested[0]["trang_thai"] != scheduler.FollowUpConfirmed {
+		t.Errorf("follow-up = %v", suggested[0])
+	}
+	s.call(t, doctor, http.MethodGet, "/follow-ups", nil, http.StatusOK)
+
+	s.call(t, doctor, http.MethodPost, "/follow-ups/"+id+"/confirm", nil, http.StatusForbidden)
+	s.call(t, customer, http.MethodPost, "/follow-ups/"+id+"/confirm", nil, http.StatusConflict)
+	s.call(t, customer, http.MethodPost, "/follow-ups/"+id+"/reschedule", map[string]string{
+		"ngay_gio_kham": day(9),
+	}, http.StatusBadRequest)
+	s.call(t, customer, http.MethodPost, "/follow-ups/"+id+"/reschedule", map[string]string{
+		"ngay_gio_kham": day(9) + " 14:00",
+	}, http.StatusOK)
+	if err := followUps.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s.call(t, customer, http.MethodGet, "/follow-ups/missed", nil, http.StatusForbidden)
+	s.call(t, clinicManager, http.MethodGet, "/follow-ups/missed?tu_ngay="+day(-30), nil, http.StatusOK)
+
+	// An appointment soon is reminded with links to confirm or cancel it.
+	soon := time.Now().Add(90 * time.Minute).Format("2006-01-02 15:04")
+	s.call(t, customer, http.MethodPost, "/appointments", map[string]string{
+		"ma_bac_si": s.userIDs[doctor], "ma_phong_kham": testClinic, "ngay_gio_kham": soon,
+	}, http.StatusCreated)
+	reminders := scheduler.NewReminderScheduler(s.db, s.notifier, s.cfg.ReminderOffsets, "https://clinic.local", s.cfg.LinkSigningSecret)
+	for i := 0; i < 2; i++ {
+		if err := reminders.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reminder := s.outbox.last(t, "customer@clinic.local")
+	confirm := field(t, reminder, "/api/v1/appointment-links/", "\n")
+	cancel := field(t, reminder[strings.Index(reminder, confirm)+len(confirm):], "/api/v1/appointment-links/", "\n")
+
+	link := s.call(t, "", http.MethodGet, "/appointment-links/"+confirm, nil, http.StatusOK).object(t)
+	if link["hanh_dong"] != scheduler.ReminderActionConfirm {
+		t.Errorf("link = %v", link)
+	}
+	s.call(t, "", http.MethodPost, "/appointment-links/"+confirm, nil, http.StatusOK)
+	s.call(t, "", http.MethodPost, "/appointment-links/"+cancel, nil, http.StatusOK)
+	s.call(t, "", http.MethodPost, "/appointment-links/"+confirm, nil, http.StatusConflict)
+	s.call(t, "", http.MethodGet, "/appointment-links/"+confirm+"x", nil, http.StatusBadRequest)
+}
@@ -0,0 +1,334 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+)
+
+const icd10CSV = `code,title_en,title_vi,chapter,parent
+J06,Acute upper respiratory infections,Nhiễm trùng hô hấp trên cấp,X,
+J06.9,"Acute upper respiratory infection, unspecified",Nhiễm trùng hô hấp trên cấp không đặc hiệu,X,J06
+I10,Essential (primary) hypertension,Tăng huyết áp vô căn,IX,
+E11,Type 2 diabetes mellitus,Đái tháo đường typ 2,IV,
+E11.9,Type 2 diabetes mellitus without complications,Đái tháo đường typ 2 không biến chứng,IV,E11
+`
+
+const interactionsCSV = `ingredient_a,ingredient_b,severity,description,recommendation
+warfarin,aspirin,MAJOR,Tăng nguy cơ chảy máu,Tránh phối hợp
+ibuprofen,aspirin,MODERATE,Giảm tác dụng chống kết tập tiểu cầu,Uống cách nhau
+`
+
+func catalogueFlow(t *testing.T, s *integrationServer) {
+	s.call(t, clinicManager, http.MethodPost, "/icd10/import", upload{"icd10.csv", icd10CSV}, http.StatusForbidden)
+	s.call(t, operationManager, http.MethodPost, "/icd10/import", upload{"icd10.csv", "code\nJ06\n"}, http.StatusBadRequest)
+	s.call(t, operationManager, http.MethodPost, "/icd10/import", upload{"icd10.csv", icd10CSV}, http.StatusOK)
+	if found := s.call(t, doctor, http.MethodGet, "/icd10?q=J06", nil, http.StatusOK); len(found.list(t)) != 2 {
+		t.Errorf("search for J06 = %s", found.Data)
+	}
+	s.call(t, doctor, http.MethodGet, "/icd10?q=huy%E1%BA%BFt+%C3%A1p", nil, http.StatusOK)
+	if found := s.call(t, doctor, http.MethodGet, "/icd10?q=dai+thao+duog", nil, http.StatusOK).list(t); len(found) != 2 || found[0]["ma_icd10"] != "E11" {
+		t.Errorf("search with a typo = %v", found)
+	}
+	if found := s.call(t, doctor, http.MethodGet, "/icd10?q=hypertensoin", nil, http.StatusOK).list(t); len(found) != 1 || found[0]["ma_icd10"] != "I10" {
+		t.Errorf("search with a typo = %v", found)
+	}
+	s.call(t, doctor, http.MethodGet, "/icd10/chapters", nil, http.StatusOK)
+	s.call(t, doctor, http.MethodGet, "/icd10/j06", nil, http.StatusOK)
+	s.call(t, doctor, http.MethodGet, "/icd10/Z99", nil, http.StatusNotFound)
+
+	medications := []struct{ key, name, ingredient string }{
+		{"paracetamol", "Paracetamol 500mg", "paracetamol"},
+		{"ibuprofen", "Ibuprofen 400mg", "ibuprofen"},
+		{"warfarin", "Warfarin 5mg", "warfarin"},
+		{"aspirin", "Aspirin 81mg", "aspirin"},
+	}
+	s.call(t, doctor, http.MethodPost, "/medications", map[string]interface{}{
+		"ten_thuoc": "Paracetamol 500mg", "hoat_chat": "paracetamol", "don_vi": "viên", "gia": 1000,
+	}, http.StatusForbidden)
+	for _, m := range medications {
+		s.ids[m.key] = s.call(t, clinicManager, http.MethodPost, "/medications", map[string]interface{}{
+			"ten_thuoc": m.name, "hoat_chat": m.ingredient, "ham_luong": "500mg",
+			"dang_bao_che": "Viên nén", "don_vi": "viên", "gia": 1000,
+		}, http.StatusCreated).str(t, "ma_thuoc")
+	}
+	s.call(t, clinicManager, http.MethodPut, "/medications/"+s.ids["paracetamol"], map[string]interface{}{
+		"gia": 1200, "cong_dung": "Hạ sốt, giảm đau",
+	}, http.StatusOK)
+	if prices := s.call(t, doctor, http.MethodGet, "/medications/"+s.ids["paracetamol"]+"/prices", nil, http.StatusOK); len(prices.list(t)) != 2 {
+		t.Errorf("price history = %s", prices.Data)
+	}
+	s.call(t, doctor, http.MethodGet, "/medications/"+s.ids["paracetamol"], nil, http.StatusOK)
+
+	retired := s.call(t, clinicManager, http.MethodPost, "/medications", map[string]interface{}{
+		"ten_thuoc": "Thuốc ngừng", "hoat_chat": "cũ", "don_vi": "viên", "gia": 0,
+	}, http.StatusCreated).str(t, "ma_thuoc")
+	s.call(t, clinicManager, http.MethodDelete, "/medications/"+retired, nil, http.StatusOK)
+	if list := s.call(t, doctor, http.MethodGet, "/medications?sort=ten_thuoc", nil, http.StatusOK); len(list.list(t)) != len(medications) {
+		t.Errorf("medications = %s", list.Data)
+	}
+	s.call(t, doctor, http.MethodGet, "/medications?include_discontinued=true&q=ng%E1%BB%ABng", nil, http.StatusOK)
+
+	s.call(t, clinicManager, http.MethodPost, "/medications/interactions/import", upload{"interactions.csv", interactionsCSV}, http.StatusForbidden)
+	s.call(t, operationManager, http.MethodPost, "/medications/interactions/import", upload{"interactions.csv", interactionsCSV}, http.StatusOK)
+	// Importing again replaces the rules rather than adding duplicates.
+	s.call(t, operationManager, http.MethodPost, "/medications/interactions/import", upload{"interactions.csv", interactionsCSV}, http.StatusOK)
+	if rules := s.call(t, doctor, http.MethodGet, "/medications/interactions?hoat_chat=aspirin", nil, http.StatusOK); len(rules.list(t)) != 2 {
+		t.Errorf("interactions = %s", rules.Data)
+	}
+
+	s.call(t, doctor, http.MethodGet, "/lab-test-types", nil, http.StatusOK)
+}
+
+func medicalRecordFlow(t *testing.T, s *integrationServer) {
+	patient := "/customers/" + s.userIDs[customer]
+
+	// The doctor may edit the registry of a patient booked with them.
+	allergy := map[string]string{
+		"tac_nhan": "Aspirin", "loai_tac_nhan": "THUOC", "ma_thuoc": s.ids["aspirin"],
+		"phan_ung": "Nổi mề đay", "muc_do": "SEVERE",
+	}
+	s.call(t, receptionist, http.MethodPost, patient+"/allergies", allergy, http.StatusForbidden)
+	id := s.call(t, doctor, http.MethodPost, patient+"/allergies", allergy, http.StatusCreated).str(t, "ma_di_ung")
+	s.call(t, doctor, http.MethodPut, patient+"/allergies/"+id, map[string]string{
+		"tac_nhan": "Aspirin", "loai_tac_nhan": "THUOC", "ma_thuoc": s.ids["aspirin"], "muc_do": "MODERATE",
+	}, http.StatusOK)
+	pollen := s.call(t, doctor, http.MethodPost, patient+"/allergies", map[string]string{
+		"tac_nhan": "Phấn hoa", "loai_tac_nhan": "MOI_TRUONG", "muc_do": "MILD",
+	}, http.StatusCreated).str(t, "ma_di_ung")
+	s.call(t, doctor, http.MethodDelete, patient+"/allergies/"+pollen, nil, http.StatusOK)
+	if allergies := s.call(t, customer, http.MethodGet, patient+"/allergies", nil, http.StatusOK); len(allergies.list(t)) != 1 {
+		t.Errorf("allergies = %s", allergies.Data)
+	}
+
+	id = s.call(t, doctor, http.MethodPost, patient+"/conditions", map[string]string{
+		"ten_benh": "Tăng huyết áp", "ma_icd10": "I10", "ngay_chan_doan": "2020-01-15",
+	}, http.StatusCreated).str(t, "ma_benh_man_tinh")
+	s.call(t, doctor, http.MethodPut, patient+"/conditions/"+id, map[string]string{
+		"ten_benh": "Tăng huyết áp", "ma_icd10": "I10", "ngay_chan_doan": "2020-01-15", "ghi_chu": "Kiểm soát tốt",
+	}, http.StatusOK)
+	wrong := s.call(t, doctor, http.MethodPost, patient+"/conditions", map[string]string{
+		"ten_benh": "Nhập nhầm",
+	}, http.StatusCreated).str(t, "ma_benh_man_tinh")
+	s.call(t, doctor, http.MethodDelete, patient+"/conditions/"+wrong, nil, http.StatusOK)
+	if conditions := s.call(t, doctor, http.MethodGet, patient+"/conditions", nil, http.StatusOK); len(conditions.list(t)) != 1 {
+		t.Errorf("conditions = %s", conditions.Data)
+	}
+	if history := s.call(t, doctor, http.MethodGet, patient+"/conditions?include_resolved=true", nil, http.StatusOK); len(history.list(t)) != 2 {
+		t.Errorf("condition history = %s", history.Data)
+	}
+	invalid := s.call(t, doctor, http.MethodPost, patient+"/conditions", map[string]string{
+		"ten_benh": "Không rõ", "ma_icd10": "Z99.99",
+	}, http.StatusBadRequest)
+	if invalid.Error != "Z99.99" {
+		t.Errorf("invalid ICD-10 error = %q", invalid.Error)
+	}
+
+	record := map[string]interface{}{
+		"ma_customer": s.userIDs[customer], "ma_phong_kham": testClinic,
+		"trieu_chung": "Sốt, ho, đau họng", "chan_doan": "Viêm đường hô hấp trên",
+		"huong_dan_dieu_tri": "Nghỉ ngơi, uống nhiều nước", "ma_icd10": "J06.9",
+		"ma_icd10_phu": []string{"I10"}, "ngay_tai_kham": day(7),
+	}
+	s.call(t, customer, http.MethodPost, "/medical-records", record, http.StatusForbidden)
+	record["ma_icd10"] = "Z99.9"
+	s.call(t, doctor, http.MethodPost, "/medical-records", record, http.StatusBadRequest)
+	record["ma_icd10"] = "J06.9"
+	s.ids["record"] = s.call(t, doctor, http.MethodPost, "/medical-records", record, http.StatusCreated).str(t, "record_id")
+	records := "/medical-records/" + s.ids["record"]
+
+	s.call(t, doctor, http.MethodPut, records, map[string]string{"chan_doan": "Viêm họng cấp"}, http.StatusBadRequest)
+	s.call(t, doctor, http.MethodPut, records, map[string]string{
+		"chan_doan": "Viêm họng cấp", "ly_do": "Bổ sung sau khi khám họng",
+	}, http.StatusOK)
+	if versions := s.call(t, doctor, http.MethodGet, records+"/versions", nil, http.StatusOK); len(versions.list(t)) != 2 {
+		t.Errorf("versions = %s", versions.Data)
+	}
+	s.call(t, customer, http.MethodGet, records, nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, "/medical-records", nil, http.StatusOK)
+	s.call(t, doctor, http.MethodGet, "/medical-records?ma_customer="+s.userIDs[customer], nil, http.StatusOK)
+	for _, user := range []string{receptionist, accountant} {
+		s.call(t, user, http.MethodGet, records, nil, http.StatusNotFound)
+		s.call(t, user, http.MethodGet, records+"/versions", nil, http.StatusNotFound)
+		s.call(t, user, http.MethodGet, patient+"/summary", nil, http.StatusForbidden)
+		if listed := s.call(t, user, http.MethodGet, "/medical-records", nil, http.StatusOK).list(t); len(listed) != 0 {
+			t.Errorf("%s sees records %v", user, listed)
+		}
+	}
+	if listed := s.call(t, clinicManager, http.MethodGet, "/medical-records", nil, http.StatusOK).list(t); len(listed) == 0 {
+		t.Error("clinic manager does not see the records of their clinic")
+	}
+
+	// A signed record only takes addenda.
+	delete(record, "ngay_tai_kham")
+	signed := "/medical-records/" + s.call(t, doctor, http.MethodPost, "/medical-records", record, http.StatusCreated).str(t, "record_id")
+	s.call(t, doctor, http.MethodPost, signed+"/sign", nil, http.StatusOK)
+	s.call(t, doctor, http.MethodPost, signed+"/sign", nil, http.StatusConflict)
+	s.call(t, doctor, http.MethodPut, signed, map[string]string{
+		"chan_doan": "Khác", "ly_do": "Sửa",
+	}, http.StatusConflict)
+	s.call(t, doctor, http.MethodPost, signed+"/addenda", map[string]string{
+		"noi_dung": "Bệnh nhân báo hết sốt sau 2 ngày",
+	}, http.StatusCreated)
+
+	s.ids["labtest"] = s.call(t, doctor, http.MethodPost, "/lab-tests", map[string]string{
+		"ma_ho_so": s.ids["record"], "loai_xet_nghiem": "Công thức máu", "ngay_xet_nghiem": day(0),
+	}, http.StatusCreated).str(t, "ma_xet_nghiem")
+	for _, user := range []string{customer, receptionist, clinicManager} {
+		s.call(t, user, http.MethodPut, "/lab-tests/"+s.ids["labtest"], map[string]string{
+			"ket_qua": "Bình thường",
+		}, http.StatusForbidden)
+	}
+	s.call(t, doctor, http.MethodPut, "/lab-tests/"+s.ids["labtest"], map[string]string{
+		"ket_qua": "Bạch cầu 11 G/L",
+	}, http.StatusOK)
+	extra := s.call(t, doctor, http.MethodPost, "/lab-tests", map[string]string{
+		"ma_ho_so": s.ids["record"], "loai_xet_nghiem": "X-quang phổi",
+	}, http.StatusCreated).str(t, "ma_xet_nghiem")
+	s.call(t, customer, http.MethodDelete, "/lab-tests/"+extra, nil, http.StatusForbidden)
+	s.call(t, doctor, http.MethodDelete, "/lab-tests/"+extra, nil, http.StatusOK)
+	if result := s.call(t, customer, http.MethodGet, "/lab-tests/"+s.ids["labtest"], nil, http.StatusOK).object(t); result["ket_qua"] != "Bạch cầu 11 G/L" {
+		t.Errorf("lab test = %v", result)
+	}
+	s.call(t, receptionist, http.MethodGet, "/lab-tests/"+s.ids["labtest"], nil, http.StatusForbidden)
+	s.call(t, accountant, http.MethodGet, "/lab-tests", nil, http.StatusForbidden)
+	s.call(t, receptionist, http.MethodGet, "/lab-tests?ma_customer="+s.userIDs[customer], nil, http.StatusForbidden)
+	s.call(t, clinicManager, http.MethodGet, "/lab-tests?ma_customer="+s.userIDs[customer], nil, http.StatusOK)
+	if page := s.call(t, doctor, http.MethodGet, "/lab-tests?page_size=1", nil, http.StatusOK); len(page.list(t)) != 1 || page.Pagination["total"] != 1.0 {
+		t.Errorf("lab tests = %s %v", page.Data, page.Pagination)
+	}
+	if types := s.call(t, doctor, http.MethodGet, "/lab-test-types", nil, http.StatusOK); len(types.Data) < 5 {
+		t.Errorf("lab test types = %s", types.Data)
+	}
+
+	summary := s.call(t, doctor, http.MethodGet, patient+"/summary", nil, http.StatusOK).object(t)
+	if visits, _ := summary["lich_su_kham"].([]interface{}); len(visits) != 2 {
+		t.Errorf("summary = %v", summary)
+	}
+}
+
+func prescriptionFlow(t *testing.T, s *integrationServer) {
+	medications := []map[string]interface{}{
+		{"ma_thuoc": s.ids["paracetamol"], "lieu_dung": map[string]interface{}{
+			"lieu": 1, "don_vi": "VIEN", "duong_dung": "UONG", "so_lan_moi_ngay": 3, "thoi_diem": "SAU_AN", "so_ngay": 5,
+		}},
+		{"ma_thuoc": s.ids["ibuprofen"], "so_luong": 10, "cach_dung": "Uống 1 viên khi đau"},
+	}
+	prescription := map[string]interface{}{"ma_ho_so": s.ids["record"], "medications": medications}
+
+	check := s.call(t, doctor, http.MethodPost, "/prescriptions/check", prescription, http.StatusOK).object(t)
+	if check["can_ly_do_bo_qua"] != false {
+		t.Errorf("check = %v", check)
+	}
+	s.call(t, customer, http.MethodPost, "/prescriptions", prescription, http.StatusForbidden)
+	s.call(t, doctor, http.MethodPost, "/prescriptions", map[string]interface{}{
+		"ma_ho_so": s.ids["record"], "medications": append(medications, medications[1]),
+	}, http.StatusBadRequest)
+	s.ids["prescription"] = s.call(t, doctor, http.MethodPost, "/prescriptions", prescription, http.StatusCreated).str(t, "ma_don_thuoc")
+	prescriptions := "/prescriptions/" + s.ids["prescription"]
+
+	prescription["ghi_chu"] = "Tái khám nếu còn sốt"
+	s.call(t, doctor, http.MethodPut, prescriptions, prescription, http.StatusOK)
+	code := s.call(t, doctor, http.MethodPost, prescriptions+"/sign", nil, http.StatusOK).str(t, "ma_xac_thuc")
+	s.call(t, doctor, http.MethodPost, prescriptions+"/sign", nil, http.StatusConflict)
+	s.call(t, doctor, http.MethodPut, prescriptions, prescription, http.StatusConflict)
+	s.call(t, "", http.MethodGet, "/prescriptions/verify/"+code, nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, prescriptions, nil, http.StatusOK)
+	s.call(t, customer, http.MethodGet, "/prescriptions", nil, http.StatusOK)
+	s.call(t, doctor, http.MethodGet, "/prescriptions?trang_thai=SIGNED", nil, http.StatusOK)
+
+	// The patient is allergic to aspirin, which needs a reason to override.
+	aspirin := map[string]interface{}{"ma_ho_so": s.ids["record"], "medications": []map[string]interface{}{
+		{"ma_thuoc": s.ids["aspirin"], "so_luong": 30, "cach_dung": "Uống 1 viên mỗi sáng"},
+	}}
+	check = s.call(t, doctor, http.MethodPost, "/prescriptions/check", aspirin, http.StatusOK).object(t)
+	if check["can_ly_do_bo_qua"] != true {
+		t.Errorf("check = %v", check)
+	}
+	s.call(t, doctor, http.MethodPost, "/prescriptions", aspirin, http.StatusConflict)
+	aspirin["ly_do_bo_qua"] = "Dị ứng nhẹ, đã theo dõi"
+	overridden := s.call(t, doctor, http.MethodPost, "/prescriptions", aspirin, http.StatusCreated).str(t, "ma_don_thuoc")
+	s.call(t, doctor, http.MethodPost, "/prescriptions/"+overridden+"/cancel", map[string]string{}, http.StatusBadRequest)
+	s.call(t, doctor, http.MethodPost, "/prescriptions/"+overridden+"/cancel", map[string]string{
+		"ly_do": "Đổi sang thuốc khác",
+	}, http.StatusOK)
+
+	template := map[string]interface{}{
+		"ten_mau": "Viêm họng cấp", "pham_vi": "PERSONAL", "ma_icd10": "J06",
+		"medications": medications,
+		"xet_nghiem":  []map[string]string{{"loai_xet_nghiem": "Công thức máu"}},
+	}
+	s.call(t, clinicManager, http.MethodPost, "/prescription-templates", template, http.StatusForbidden)
+	personal := s.call(t, doctor, http.MethodPost, "/prescription-templates", template, http.StatusCreated).str(t, "ma_mau")
+	template["ten_mau"] = "Viêm họng cấp người lớn"
+	s.call(t, doctor, http.MethodPut, "/prescription-templates/"+personal, template, http.StatusOK)
+	s.call(t, doctor, http.MethodGet, "/prescription-templates/"+personal, nil, http.StatusOK)
+	applied := s.call(t, doctor, http.MethodPost, "/prescription-templates/"+personal+"/apply", map[string]string{
+		"ma_ho_so": s.ids["record"],
+	}, http.StatusOK).object(t)
+	if _, ok := applied["prescription"].(map[string]interface{}); !ok {
+		t.Errorf("applied template = %v", applied)
+	}
+
+	template["pham_vi"], template["ma_phong_kham"] = "CLINIC", testClinic
+	shared := s.call(t, clinicManager, http.MethodPost, "/prescription-templates", template, http.StatusCreated).str(t, "ma_mau")
+	s.call(t, doctor, http.MethodPut, "/prescription-templates/"+shared, template, http.StatusForbidden)
+	if list := s.call(t, doctor, http.MethodGet, "/prescription-templates", nil, http.StatusOK); len(list.list(t)) != 2 {
+		t.Errorf("templates = %s", list.Data)
+	}
+	s.call(t, clinicManager, http.MethodDelete, "/prescription-templates/"+shared, nil, http.StatusOK)
+}
+
+func pharmacyFlow(t *testing.T, s *integrationServer) {
+	supplier := map[string]string{"ten_nha_cung_cap": "Dược phẩm Sài Gòn", "so_dien_thoai": "0909123456"}
+	s.call(t, receptionist, http.MethodPost, "/pharmacy/suppliers", supplier, http.StatusForbidden)
+	supplierID := s.call(t, clinicManager, http.MethodPost, "/pharmacy/suppliers", supplier, http.StatusCreated).str(t, "ma_nha_cung_cap")
+	s.call(t, clinicManager, http.MethodGet, "/pharmacy/suppliers", nil, http.StatusOK)
+
+	receive := func(items ...map[string]interface{}) string {
+		return s.call(t, clinicManager, http.MethodPost, "/pharmacy/receipts", map[string]interface{}{
+			"ma_nha_cung_cap": supplierID, "items": items,
+		}, http.StatusCreated).str(t, "ma_phieu_nhap")
+	}
+	lot := func(key, number, expiry string, quantity int) map[string]interface{} {
+		return map[string]interface{}{
+			"ma_thuoc": s.ids[key], "so_lo": number, "han_su_dung": expiry, "so_luong": quantity, "gia_nhap": 800,
+		}
+	}
+	receipt := receive(
+		lot("paracetamol", "P-001", day(60), 20),
+		lot("paracetamol", "P-002", day(400), 50),
+		lot("ibuprofen", "I-001", day(400), 5),
+	)
+
+	dispense := "/pharmacy/dispense/" + s.ids["prescription"]
+	s.call(t, doctor, http.MethodPost, dispense, nil, http.StatusForbidden)
+	s.call(t, receptionist, http.MethodPost, dispense, nil, http.StatusConflict)
+	// Receiving the same lot again adds to it.
+	receive(lot("ibuprofen", "I-001", day(400), 20))
+	dispensed := s.call(t, receptionist, http.MethodPost, dispense, nil, http.StatusOK)
+	s.call(t, receptionist, http.MethodPost, dispense, nil, http.StatusConflict)
+
+	stock := s.call(t, receptionist, http.MethodGet, "/pharmacy/stock", nil, http.StatusOK).list(t)
+	remaining := map[string]float64{}
+	for _, medication := range stock {
+		remaining[medication["ma_thuoc"].(string)], _ = medication["tong_so_luong"].(float64)
+	}
+	// The 15 paracetamol tablets come from the lot expiring first.
+	if remaining[s.ids["paracetamol"]] != 55 || remaining[s.ids["ibuprofen"]] != 15 {
+		t.Errorf("stock after dispensing %s = %v", dispensed.Data, stock)
+	}
+	s.call(t, customer, http.MethodGet, "/pharmacy/stock", nil, http.StatusForbidden)
+	s.call(t, operationManager, http.MethodGet, "/pharmacy/stock", nil, http.StatusBadRequest)
+	s.call(t, operationManager, http.MethodGet, "/pharmacy/stock?ma_phong_kham="+testClinic+"&ma_thuoc="+s.ids["ibuprofen"], nil, http.StatusOK)
+
+	s.call(t, clinicManager, http.MethodGet, "/pharmacy/receipts", nil, http.StatusOK)
+	s.call(t, clinicManager, http.MethodGet, "/pharmacy/receipts/"+receipt, nil, http.StatusOK)
+	alerts := s.call(t, clinicManager, http.MethodGet, "/pharmacy/alerts", nil, http.StatusOK).object(t)
+	if expiring, _ := alerts["sap_het_han"].([]interface{}); len(expiring) != 1 {
+		t.Errorf("alerts = %v", alerts)
+	}
+	s.call(t, receptionist, http.MethodGet, "/pharmacy/alerts", nil, http.StatusForbidden)
+	if ledger := s.call(t, clinicManager, http.MethodGet, "/pharmacy/ledger?tu_ngay="+day(0), nil, http.StatusOK); len(ledger.list(t)) != 6 {
+		t.Errorf("ledger = %s", ledger.Data)
+	}
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func staffFlow(t *testing.T, s *integrationServer) {
+	s.call(t, clinicManager, http.MethodGet, "/admin/staff", nil, http.StatusForbidden)
+	s.call(t, operationManager, http.MethodGet, "/admin/staff?role=DOCTOR", nil, http.StatusOK)
+
+	s.call(t, operationManager, http.MethodPost, "/admin/staff", map[string]string{
+		"role": "RECEPTIONIST", "ho_ten": "Lễ Tân Hai", "ten_dang_nhap": "reception2",
+		"email": "reception2@clinic.local",
+	}, http.StatusBadRequest)
+	created := s.call(t, operationManager, http.MethodPost, "/admin/staff", map[string]string{
+		"role": "RECEPTIONIST", "ho_ten": "Lễ Tân Hai", "ten_dang_nhap": "reception2",
+		"email": "reception2@clinic.local", "ma_phong_kham": testClinic,
+	}, http.StatusCreated)
+	id, _ := created.object(t)["nhan_vien"].(map[string]interface{})["ma_user"].(string)
+	if id == "" {
+		t.Fatalf("created staff has no ma_user: %s", created.Data)
+	}
+
+	s.call(t, operationManager, http.MethodGet, "/admin/staff/"+id, nil, http.StatusOK)
+	s.call(t, operationManager, http.MethodPut, "/admin/staff/"+id, map[string]interface{}{
+		"so_dien_thoai": "0900000010", "luong_co_ban": 9000000,
+	}, http.StatusOK)
+	s.call(t, operationManager, http.MethodPost, "/admin/staff/"+id+"/reassign", map[string]string{
+		"ma_phong_kham": s.ids["clinic"],
+	}, http.StatusOK)
+
+	// The invitation link carries the token that sets the password.
+	s.call(t, operationManager, http.MethodPost, "/admin/staff/"+id+"/invitation", nil, http.StatusOK)
+	token, err := url.QueryUnescape(field(t, s.outbox.last(t, "reception2@clinic.local"), "token=", " \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.call(t, "", http.MethodPost, "/auth/accept-invitation", map[string]string{
+		"token": token, "mat_khau": testPassword,
+	}, http.StatusOK)
+	s.call(t, "", http.MethodPost, "/auth/accept-invitation", map[string]string{
+		"token": token, "mat_khau": testPassword,
+	}, http.StatusBadRequest)
+	s.call(t, "reception2", http.MethodGet, "/users/profile", nil, http.StatusOK)
+
+	s.call(t, operationManager, http.MethodPost, "/admin/staff/"+id+"/deactivate", nil, http.StatusOK)
+	s.call(t, "reception2", http.MethodGet, "/users/profile", nil, http.StatusUnauthorized)
+	s.call(t, operationManager, http.MethodPost, "/admin/staff/"+id+"/activate", nil, http.StatusOK)
+	s.call(t, operationManager, http.MethodPost, "/admin/staff/"+id+"/invitation", nil, http.StatusConflict)
+}
@@ -0,0 +1,393 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"clinic-management/internal/config"
+	"clinic-management/internal/database"
+	"clinic-management/internal/handlers"
+	"clinic-management/internal/keys"
+	"clinic-management/internal/notification"
+	"clinic-management/internal/openapi"
+	"clinic-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestIntegration runs every endpoint through the router against an
+// embedded SQLite database migrated like production, so the handlers'
+// queries are executed for real. The flows share one database and run in
+// order; it fails if an endpoint answers 500 or is not exercised at all.
+func TestIntegration(t *testing.T) {
+	s := newIntegrationServer(t)
+
+	flows := []struct {
+		name string
+		run  func(t *testing.T, s *integrationServer)
+	}{
+		{"auth", authFlow},
+		{"two factor", twoFactorFlow},
+		{"users", userFlow},
+		{"clinics", clinicFlow},
+		{"staff", staffFlow},
+		{"schedules and appointments", appointmentFlow},
+		{"catalogues", catalogueFlow},
+		{"medical records", medicalRecordFlow},
+		{"prescriptions", prescriptionFlow},
+		{"pharmacy", pharmacyFlow},
+		{"follow-ups and reminders", followUpFlow},
+		{"notifications and sessions", notificationFlow},
+	}
+	for _, flow := range flows {
+		if !t.Run(flow.name, func(t *testing.T) { flow.run(t, s) }) {
+			// Later flows build on the data of earlier ones.
+			break
+		}
+	}
+
+	if t.Failed() {
+		return
+	}
+	var missing []string
+	for _, route := range s.router.Routes() {
+		if !s.covered[route.Method+" "+route.Path] {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	for _, route := range missing {
+		t.Errorf("%s is not exercised by the integration test", route)
+	}
+}
+
+const testPassword = "Secret123!"
+
+// Seeded accounts, by username; every one logs in with testPassword.
+const (
+	operationManager = "opm"
+	clinicManager    = "clm"
+	doctor           = "doctor"
+	receptionist     = "reception"
+	accountant       = "accountant"
+	customer         = "customer"
+	testClinic       = "PK-TEST"
+)
+
+type integrationServer struct {
+	cfg      *config.Config
+	db       *sql.DB
+	router   *gin.Engine
+	notifier *notification.Service
+	outbox   *outbox
+	userIDs  map[string]string
+	tokens   map[string]string
+	covered  map[string]bool
+	// doc is the OpenAPI document; responses of the routes it describes
+	// are checked against it.
+	doc *openapi.Document
+	// route is the route that served the last request.
+	route string
+	// ids holds the IDs created by earlier flows for later ones.
+	ids map[string]string
+	// failed is set by the route recorder when a handler answers 5xx.
+	failed func(format string, args ...interface{})
+}
+
+func newIntegrationServer(t *testing.T) *integrationServer {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.Open("sqlite:" + filepath.Join(t.TempDir(), "clinic.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.InitIDs(db); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Load()
+	cfg.TwoFactorRequiredRoles = []string{"ACCOUNTANT"}
+	cfg.LoginDelayAfterFailures = 100
+
+	signingKeys, err := keys.NewManager(db, cfg.JWTAlgorithm, cfg.JWTKeyRotation, cfg.SessionLifetime, cfg.JWTKeyEncryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signingKeys.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &integrationServer{
+		cfg:     cfg,
+		db:      db,
+		router:  gin.New(),
+		outbox:  &outbox{},
+		userIDs: map[string]string{},
+		tokens:  map[string]string{},
+		covered: map[string]bool{},
+		ids:     map[string]string{},
+	}
+	s.notifier = notification.NewService(db, cfg.NotificationMaxAttempts,
+		s.outbox.provider(notification.ChannelEmail), s.outbox.provider(notification.ChannelSMS))
+	s.router.Use(func(c *gin.Context) {
+		c.Next()
+		if c.FullPath() == "" {
+			return
+		}
+		s.covered[c.Request.Method+" "+c.FullPath()] = true
+		s.route = c.FullPath()
+		if c.Writer.Status() >= http.StatusInternalServerError && s.failed != nil {
+			s.failed("%s %s answered %d", c.Request.Method, c.Request.URL, c.Writer.Status())
+		}
+	})
+	if err := SetupRoutes(s.router, db, cfg, signingKeys, s.notifier); err != nil {
+		t.Fatal(err)
+	}
+	if s.doc, err = handlers.NewOpenAPIDocument(s.router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.seed(t)
+	return s
+}
+
+// seed creates the clinic and one account of every role.
+func (s *integrationServer) seed(t *testing.T) {
+	hash, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO PHONGKHAM (maPhongKham, tenPhongKham, diaChi, soDienThoai, email)
+		  VALUES (@p1, N'Phòng khám Trung tâm', N'1 Lê Lợi', '0281234567', 'pk@clinic.local')`, []interface{}{testClinic}},
+		{`INSERT INTO BANDIEUHANH (maUser, chucVu) VALUES ('T-OPM', N'Giám đốc')`, nil},
+		{`INSERT INTO QUANLYPHONGKHAM (maUser, maPhongKham) VALUES ('T-CLM', @p1)`, []interface{}{testClinic}},
+		{`INSERT INTO BACSI (maUser, chuyenKhoa, namKinhNghiem) VALUES ('T-DOC', N'Nội tổng quát', 10)`, nil},
+		{`INSERT INTO LETAN (maUser, maPhongKham) VALUES ('T-REC', @p1)`, []interface{}{testClinic}},
+		{`INSERT INTO KETOAN (maUser) VALUES ('T-ACC')`, nil},
+		{`INSERT INTO CUSTOMER (maUser, ngaySinh, gioiTinh) VALUES ('T-CUS', '1990-05-01', N'Nam')`, nil},
+	}
+	users := []struct{ id, username, role, phone string }{
+		{"T-OPM", operationManager, "OPERATION_MANAGER", "0900000001"},
+		{"T-CLM", clinicManager, "CLINIC_MANAGER", "0900000002"},
+		{"T-DOC", doctor, "DOCTOR", "0900000003"},
+		{"T-REC", receptionist, "RECEPTIONIST", "0900000004"},
+		{"T-ACC", accountant, "ACCOUNTANT", "0900000005"},
+		{"T-CUS", customer, "CUSTOMER", "0900000006"},
+	}
+	for _, u := range users {
+		statements = append(statements, struct {
+			query string
+			args  []interface{}
+		}{`INSERT INTO [USER] (userID, hoTen, username, password, soDienThoai, email, role)
+		   VALUES (@p1, @p2, @p2, @p3, @p4, @p5, @p6)`, []interface{}{u.id, u.username, hash, u.phone, u.username + "@clinic.local", u.role}})
+		s.userIDs[u.username] = u.id
+	}
+	for _, st := range statements {
+		if _, err := s.db.Exec(st.query, st.args...); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+}
+
+// response is a decoded APIResponse.
+type response struct {
+	Status     int
+	Success    bool                   `json:"success"`
+	Message    string                 `json:"message"`
+	Error      string                 `json:"error"`
+	Data       json.RawMessage        `json:"data"`
+	Pagination map[string]interface{} `json:"pagination"`
+}
+
+// object returns the data as a JSON object.
+func (r response) object(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal(r.Data, &data); err != nil {
+		t.Fatalf("data is not an object: %s", r.Data)
+	}
+	return data
+}
+
+// list returns the data as a JSON array.
+func (r response) list(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	var data []map[string]interface{}
+	if err := json.Unmarshal(r.Data, &data); err != nil {
+		t.Fatalf("data is not a list: %s", r.Data)
+	}
+	return data
+}
+
+// str returns a string field of the data object.
+func (r response) str(t *testing.T, field string) string {
+	t.Helper()
+	value, ok := r.object(t)[field].(string)
+	if !ok || value == "" {
+		t.Fatalf("data has no %s: %s", field, r.Data)
+	}
+	return value
+}
+
+// call sends a request below /api/v1 as a user, anonymously when user is
+// empty, and checks the status. An upload body is sent as a form.
+func (s *integrationServer) call(t *testing.T, user, method, path string, body interface{}, want int) response {
+	t.Helper()
+	s.failed = t.Errorf
+
+	var reader io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case upload:
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		part, _ := form.CreateFormFile("file", b.name)
+		part.Write([]byte(b.content))
+		form.Close()
+		reader, contentType = &buf, form.FormDataContentType()
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	if !strings.HasPrefix(path, "/.well-known/") {
+		path = "/api/v1" + path
+	}
+	s.route = ""
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", contentType)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if user != "" {
+		token, ok := s.tokens[user]
+		if !ok {
+			token = s.login(t, user)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	r := response{Status: w.Code}
+	json.Unmarshal(w.Body.Bytes(), &r)
+	if w.Code != want {
+		t.Fatalf("%s %s as %q = %d, want %d: %s", method, path, user, w.Code, want, w.Body.String())
+	}
+	if route := strings.TrimPrefix(s.route, "/api/v1"); s.documented(method, route) {
+		if err := s.doc.ValidateResponse(method, req.URL.Path[len("/api/v1"):], w.Code, w.Body.Bytes()); err != nil {
+			t.Errorf("%s %s as %q does not match the OpenAPI document: %v\n%s", method, path, user, err, w.Body.String())
+		}
+	}
+	return r
+}
+
+// documented reports whether the OpenAPI document describes a route, given
+// in gin's :name syntax.
+func (s *integrationServer) documented(method, route string) bool {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return s.doc.Paths[strings.Join(segments, "/")][strings.ToLower(method)] != nil
+}
+
+// upload is a CSV file sent as the multipart field "file".
+type upload struct {
+	name, content string
+}
+
+// login logs a seeded user in with testPassword and keeps the token.
+func (s *integrationServer) login(t *testing.T, user string) string {
+	t.Helper()
+	r := s.call(t, "", http.MethodPost, "/auth/login", map[string]string{
+		"ten_dang_nhap": user, "mat_khau": testPassword,
+	}, http.StatusOK)
+	s.tokens[user] = r.str(t, "token")
+	return s.tokens[user]
+}
+
+// outbox records the messages the notification service sends.
+type outbox struct {
+	mu       sync.Mutex
+	messages []sentMessage
+}
+
+type sentMessage struct {
+	channel, to, subject, body string
+}
+
+type outboxProvider struct {
+	channel string
+	outbox  *outbox
+}
+
+func (o *outbox) provider(channel string) notification.Provider {
+	return &outboxProvider{channel: channel, outbox: o}
+}
+
+func (p *outboxProvider) Channel() string { return p.channel }
+
+func (p *outboxProvider) Send(_ context.Context, to, subject, body string) error {
+	p.outbox.mu.Lock()
+	defer p.outbox.mu.Unlock()
+	p.outbox.messages = append(p.outbox.messages, sentMessage{p.channel, to, subject, body})
+	return nil
+}
+
+// last returns the body of the last message sent to an address.
+func (o *outbox) last(t *testing.T, to string) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		if o.messages[i].to == to {
+			return o.messages[i].body
+		}
+	}
+	t.Fatalf("no message sent to %s", to)
+	return ""
+}
+
+// day returns the date n days from today as YYYY-MM-DD.
+func day(n int) string {
+	return time.Now().AddDate(0, 0, n).Format("2006-01-02")
+}
+
+// field returns the text of s between prefix and the next terminator.
+func field(t *testing.T, s, prefix, terminators string) string {
+	t.Helper()
+	i := strings.Index(s, prefix)
+	if i < 0 {
+		t.Fatalf("%q not found in %q", prefix, s)
+	}
+	rest := s[i+len(prefix):]
+	if j := strings.IndexAny(rest, terminators); j >= 0 {
+		rest = rest[:j]
+	}
+	return rest
+}
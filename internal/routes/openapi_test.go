@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clinic-management/internal/config"
+	"clinic-management/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRoutesAreDocumented fails when a route under /api/v1 is missing from
+// the served OpenAPI document or the document describes a route the router
+// does not serve.
+func TestRoutesAreDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := SetupRoutes(router, nil, config.Load(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("the document itself is not served: status = %d", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	base := doc.Servers[0].URL
+
+	routed := map[string]bool{}
+	for _, route := range router.Routes() {
+		path, ok := strings.CutPrefix(route.Path, base)
+		if !ok || path == "/openapi.json" {
+			continue
+		}
+		routed[route.Method+" "+path] = true
+
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+		if doc.Paths[strings.Join(segments, "/")][strings.ToLower(route.Method)] == nil {
+			t.Errorf("%s %s is routed but not documented", route.Method, route.Path)
+		}
+	}
+
+	for path, item := range doc.Paths {
+		ginPath := path
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, "{") {
+				ginPath = strings.Replace(ginPath, segment, ":"+strings.Trim(segment, "{}"), 1)
+			}
+		}
+		for method := range item {
+			if !routed[strings.ToUpper(method)+" "+ginPath] {
+				t.Errorf("%s %s%s is documented but not routed", strings.ToUpper(method), base, ginPath)
+			}
+		}
+	}
+}
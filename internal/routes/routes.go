@@ -3,55 +3,149 @@ package routes
 import (
 	"database/sql"
 
+	"clinic-management/internal/config"
 	"clinic-management/internal/handlers"
+	"clinic-management/internal/keys"
 	"clinic-management/internal/middleware"
+	"clinic-management/internal/notification"
+	"clinic-management/internal/session"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, db *sql.DB, jwtSecret string) {
+// SetupRoutes registers every route, then serves the OpenAPI document
+// generated from them. It fails when a route under /api/v1 is not described
+// in the document.
+func SetupRoutes(router *gin.Engine, db *sql.DB, cfg *config.Config, signingKeys *keys.Manager, notifier notification.Notifier) error {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORS())
 
+	// Other services verify our tokens with these public keys.
+	router.GET("/.well-known/jwks.json", handlers.NewJWKSHandler(signingKeys).GetJWKS)
+
 	api := router.Group("/api/v1")
 
-	authHandler := handlers.NewAuthHandler(db, jwtSecret)
-	userHandler := handlers.NewUserHandler(db)
+	sessions := session.NewStore(db, cfg.SessionLifetime, cfg.SessionCacheTTL)
+
+	authHandler := handlers.NewAuthHandler(db, signingKeys, notifier, sessions, handlers.PasswordResetSettings{
+		LinkSecret:  cfg.LinkSigningSecret,
+		LinkBaseURL: cfg.AppBaseURL,
+		MaxAttempts: cfg.PasswordResetMaxAttempts,
+	}, handlers.LoginSecuritySettings{
+		IPMaxFailures:      cfg.LoginIPMaxFailures,
+		IPWindow:           cfg.LoginIPWindow,
+		DelayAfterFailures: cfg.LoginDelayAfterFailures,
+		LockAfterFailures:  cfg.LoginLockAfterFailures,
+		LockDuration:       cfg.LoginLockDuration,
+	}, handlers.TwoFactorSettings{
+		Issuer:        cfg.TwoFactorIssuer,
+		RequiredRoles: cfg.TwoFactorRequiredRoles,
+		EncryptionKey: cfg.TwoFactorEncryptionKey,
+	})
+	userHandler := handlers.NewUserHandler(db, sessions)
 	clinicHandler := handlers.NewClinicHandler(db)
 	appointmentHandler := handlers.NewAppointmentHandler(db)
-	medicalRecordHandler := handlers.NewMedicalRecordHandler(db)
+	medicalRecordHandler := handlers.NewMedicalRecordHandler(db, cfg.MedicalRecordLockWindow)
 	prescriptionHandler := handlers.NewPrescriptionHandler(db)
 	customerHandler := handlers.NewCustomerHandler(db)
-	// labTestHandler := handlers.NewLabTestHandler(db)
+	labTestHandler := handlers.NewLabTestHandler(db)
 	scheduleHandler := handlers.NewScheduleHandler(db)
+	icd10Handler := handlers.NewICD10Handler(db)
+	patientRegistryHandler := handlers.NewPatientRegistryHandler(db)
+	medicationHandler := handlers.NewMedicationHandler(db)
+	pharmacyHandler := handlers.NewPharmacyHandler(db)
+	prescriptionTemplateHandler := handlers.NewPrescriptionTemplateHandler(db)
+	followUpHandler := handlers.NewFollowUpHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(db)
+	appointmentLinkHandler := handlers.NewAppointmentLinkHandler(db, cfg.LinkSigningSecret)
+	loginSecurityHandler := handlers.NewLoginSecurityHandler(db)
+	sessionHandler := handlers.NewSessionHandler(sessions)
+	staffHandler := handlers.NewStaffHandler(db, notifier, sessions, handlers.InvitationSettings{
+		LinkSecret:  cfg.LinkSigningSecret,
+		LinkBaseURL: cfg.AppBaseURL,
+		Validity:    cfg.StaffInvitationValidity,
+	})
 
 	auth := api.Group("/auth")
 	{
 		auth.POST("/login", authHandler.Login)       //check
 		auth.POST("/register", authHandler.Register) //check
-		auth.POST("/forgot-password", authHandler.ForgotPassword)
-		auth.POST("/reset-password", authHandler.ResetPassword)
+		auth.POST("/forgot-password",
+			middleware.RateLimit(middleware.NewRateLimiter(cfg.PasswordResetIPLimit, cfg.PasswordResetIPWindow)),
+			authHandler.ForgotPassword)
+		auth.POST("/reset-password",
+			middleware.RateLimit(middleware.NewRateLimiter(cfg.PasswordResetIPLimit, cfg.PasswordResetIPWindow)),
+			authHandler.ResetPassword)
 		auth.POST("/refresh", authHandler.RefreshToken)
+		auth.POST("/accept-invitation", staffHandler.AcceptInvitation)
+		auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+		auth.POST("/2fa/enroll", authHandler.StartTwoFactorEnrollment)
+		auth.POST("/2fa/enroll/confirm", authHandler.ConfirmTwoFactorEnrollment)
 	}
 
+	// Pharmacies verify the code printed on a prescription without logging in.
+	api.GET("/prescriptions/verify/:code", prescriptionHandler.VerifyPrescription)
+
+	// Confirm and cancel links from appointment reminders carry a signed token.
+	api.GET("/appointment-links/:token", appointmentLinkHandler.GetLink)
+	api.POST("/appointment-links/:token", appointmentLinkHandler.ApplyLink)
+
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware(jwtSecret))
+	protected.Use(middleware.AuthMiddleware(signingKeys, sessions))
 	{
+		protected.POST("/auth/logout", authHandler.Logout)
+
 		users := protected.Group("/users")
 		{
 			users.GET("/profile", userHandler.GetProfile)
 			users.PUT("/profile", userHandler.UpdateProfile)
 			users.PUT("/password", userHandler.ChangePassword)
+			users.GET("/notification-preferences", notificationHandler.GetPreferences)
+			users.PUT("/notification-preferences", notificationHandler.UpdatePreferences)
+			users.GET("/notifications", notificationHandler.GetNotifications)
+			users.GET("/2fa", authHandler.GetTwoFactorStatus)
+			users.POST("/2fa/setup", authHandler.SetupTwoFactor)
+			users.POST("/2fa/enable", authHandler.EnableTwoFactor)
+			users.POST("/2fa/disable", authHandler.DisableTwoFactor)
+			users.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			users.GET("/sessions", sessionHandler.GetSessions)
+			users.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			users.POST("/sessions/revoke-others", sessionHandler.RevokeOtherSessions)
+		}
+
+		admin := protected.Group("/admin")
+		{
+			admin.GET("/login-attempts", loginSecurityHandler.GetLoginAttempts)
+			admin.POST("/users/:id/unlock", loginSecurityHandler.UnlockAccount)
+			admin.GET("/staff", staffHandler.GetStaff)
+			admin.POST("/staff", staffHandler.CreateStaff)
+			admin.GET("/staff/:id", staffHandler.GetStaffMember)
+			admin.PUT("/staff/:id", staffHandler.UpdateStaff)
+			admin.POST("/staff/:id/reassign", staffHandler.ReassignStaff)
+			admin.POST("/staff/:id/deactivate", staffHandler.DeactivateStaff)
+			admin.POST("/staff/:id/activate", staffHandler.ActivateStaff)
+			admin.POST("/staff/:id/invitation", staffHandler.ResendInvitation)
 		}
 
 		clinics := protected.Group("/clinics")
 		{
 			clinics.GET("", clinicHandler.GetClinics)
+			clinics.POST("", clinicHandler.CreateClinic)
 			clinics.GET("/specialties", clinicHandler.GetSpecialties)
 			clinics.GET("/:id", clinicHandler.GetClinic)
+			clinics.PUT("/:id", clinicHandler.UpdateClinic)
 			clinics.GET("/:id/doctors", clinicHandler.GetDoctors)
 			clinics.GET("/:id/schedules", clinicHandler.GetSchedules)
+			clinics.GET("/:id/opening-hours", clinicHandler.GetOpeningHours)
+			clinics.PUT("/:id/opening-hours", clinicHandler.SetOpeningHours)
+			clinics.GET("/:id/rooms", clinicHandler.GetRooms)
+			clinics.POST("/:id/rooms", clinicHandler.CreateRoom)
+			clinics.PUT("/:id/rooms/:room_id", clinicHandler.UpdateRoom)
+			clinics.GET("/:id/rooms/:room_id/usage", clinicHandler.GetRoomUsage)
+			clinics.GET("/:id/equipment", clinicHandler.GetEquipment)
+			clinics.POST("/:id/equipment", clinicHandler.CreateEquipment)
+			clinics.PUT("/:id/equipment/:equipment_id", clinicHandler.UpdateEquipment)
 		}
 
 		appointments := protected.Group("/appointments")
@@ -63,12 +157,31 @@ func SetupRoutes(router *gin.Engine, db *sql.DB, jwtSecret string) {
 			appointments.DELETE("/:id", appointmentHandler.CancelAppointment)
 		}
 
+		followUps := protected.Group("/follow-ups")
+		{
+			followUps.GET("", followUpHandler.GetFollowUps)
+			followUps.GET("/missed", followUpHandler.GetMissedFollowUps)
+			followUps.POST("/:id/confirm", followUpHandler.ConfirmFollowUp)
+			followUps.POST("/:id/reschedule", followUpHandler.RescheduleFollowUp)
+		}
+
 		medicalRecords := protected.Group("/medical-records")
 		{
 			medicalRecords.GET("", medicalRecordHandler.GetMedicalRecords)
 			medicalRecords.GET("/:id", medicalRecordHandler.GetMedicalRecord)
 			medicalRecords.POST("", medicalRecordHandler.CreateMedicalRecord)
 			medicalRecords.PUT("/:id", medicalRecordHandler.UpdateMedicalRecord)
+			medicalRecords.GET("/:id/versions", medicalRecordHandler.GetMedicalRecordVersions)
+			medicalRecords.POST("/:id/addenda", medicalRecordHandler.AddMedicalRecordAddendum)
+			medicalRecords.POST("/:id/sign", medicalRecordHandler.SignMedicalRecord)
+		}
+
+		icd10 := protected.Group("/icd10")
+		{
+			icd10.GET("", icd10Handler.SearchICD10)
+			icd10.GET("/chapters", icd10Handler.GetChapters)
+			icd10.GET("/:code", icd10Handler.GetICD10)
+			icd10.POST("/import", icd10Handler.ImportICD10)
 		}
 
 		prescriptions := protected.Group("/prescriptions")
@@ -76,34 +189,76 @@ func SetupRoutes(router *gin.Engine, db *sql.DB, jwtSecret string) {
 			prescriptions.GET("", prescriptionHandler.GetPrescriptions)
 			prescriptions.GET("/:id", prescriptionHandler.GetPrescription)
 			prescriptions.POST("", prescriptionHandler.CreatePrescription)
+			prescriptions.POST("/check", prescriptionHandler.CheckPrescription)
 			prescriptions.PUT("/:id", prescriptionHandler.UpdatePrescription)
+			prescriptions.POST("/:id/sign", prescriptionHandler.SignPrescription)
+			prescriptions.POST("/:id/cancel", prescriptionHandler.CancelPrescription)
+		}
+
+		templates := protected.Group("/prescription-templates")
+		{
+			templates.GET("", prescriptionTemplateHandler.GetTemplates)
+			templates.GET("/:id", prescriptionTemplateHandler.GetTemplate)
+			templates.POST("", prescriptionTemplateHandler.CreateTemplate)
+			templates.PUT("/:id", prescriptionTemplateHandler.UpdateTemplate)
+			templates.DELETE("/:id", prescriptionTemplateHandler.DeleteTemplate)
+			templates.POST("/:id/apply", prescriptionTemplateHandler.ApplyTemplate)
 		}
 
 		medications := protected.Group("/medications")
 		{
-			medications.GET("", prescriptionHandler.GetMedications)
+			medications.GET("", medicationHandler.GetMedications)
+			medications.POST("", medicationHandler.CreateMedication)
+			medications.GET("/interactions", prescriptionHandler.GetInteractions)
+			medications.POST("/interactions/import", prescriptionHandler.ImportInteractions)
+			medications.GET("/:id", medicationHandler.GetMedication)
+			medications.PUT("/:id", medicationHandler.UpdateMedication)
+			medications.DELETE("/:id", medicationHandler.DiscontinueMedication)
+			medications.GET("/:id/prices", medicationHandler.GetPriceHistory)
+		}
+
+		pharmacy := protected.Group("/pharmacy")
+		{
+			pharmacy.GET("/suppliers", pharmacyHandler.GetSuppliers)
+			pharmacy.POST("/suppliers", pharmacyHandler.CreateSupplier)
+			pharmacy.GET("/stock", pharmacyHandler.GetStock)
+			pharmacy.GET("/receipts", pharmacyHandler.GetGoodsReceipts)
+			pharmacy.POST("/receipts", pharmacyHandler.CreateGoodsReceipt)
+			pharmacy.GET("/receipts/:id", pharmacyHandler.GetGoodsReceipt)
+			pharmacy.POST("/dispense/:id", pharmacyHandler.DispensePrescription)
+			pharmacy.GET("/alerts", pharmacyHandler.GetAlerts)
+			pharmacy.GET("/ledger", pharmacyHandler.GetLedger)
 		}
 
 		customers := protected.Group("/customers")
 		{
 			customers.GET("", customerHandler.GetCustomers)
 			customers.GET("/:id", customerHandler.GetCustomer)
+			customers.GET("/:id/summary", medicalRecordHandler.GetPatientSummary)
+			customers.GET("/:id/allergies", patientRegistryHandler.GetAllergies)
+			customers.POST("/:id/allergies", patientRegistryHandler.CreateAllergy)
+			customers.PUT("/:id/allergies/:allergy_id", patientRegistryHandler.UpdateAllergy)
+			customers.DELETE("/:id/allergies/:allergy_id", patientRegistryHandler.DeleteAllergy)
+			customers.GET("/:id/conditions", patientRegistryHandler.GetConditions)
+			customers.POST("/:id/conditions", patientRegistryHandler.CreateCondition)
+			customers.PUT("/:id/conditions/:condition_id", patientRegistryHandler.UpdateCondition)
+			customers.DELETE("/:id/conditions/:condition_id", patientRegistryHandler.DeleteCondition)
 			customers.POST("", customerHandler.CreateCustomer)
 		}
 
-		// labTests := protected.Group("/lab-tests")
-		// {
-		// 	labTests.GET("", labTestHandler.GetLabTests)
-		// 	labTests.GET("/:id", labTestHandler.GetLabTest)
-		// 	labTests.POST("", labTestHandler.CreateLabOrder)
-		// 	labTests.PUT("/:id", labTestHandler.UpdateLabTest)
-		// 	labTests.DELETE("/:id", labTestHandler.DeleteLabTest)
-		// }
+		labTests := protected.Group("/lab-tests")
+		{
+			labTests.GET("", labTestHandler.GetLabTests)
+			labTests.GET("/:id", labTestHandler.GetLabTest)
+			labTests.POST("", labTestHandler.CreateLabOrder)
+			labTests.PUT("/:id", labTestHandler.UpdateLabTest)
+			labTests.DELETE("/:id", labTestHandler.DeleteLabTest)
+		}
 
-		// labTestTypes := protected.Group("/lab-test-types")
-		// {
-		// 	labTestTypes.GET("", labTestHandler.GetLabTestTypes)
-		// }
+		labTestTypes := protected.Group("/lab-test-types")
+		{
+			labTestTypes.GET("", labTestHandler.GetLabTestTypes)
+		}
 
 		schedules := protected.Group("/schedules")
 		{
@@ -114,4 +269,11 @@ func SetupRoutes(router *gin.Engine, db *sql.DB, jwtSecret string) {
 			schedules.DELETE("/:id", scheduleHandler.DeleteSchedule)
 		}
 	}
+
+	document, err := handlers.NewOpenAPIDocument(router.Routes())
+	if err != nil {
+		return err
+	}
+	api.GET("/openapi.json", handlers.NewOpenAPIHandler(document).GetOpenAPI)
+	return nil
 }
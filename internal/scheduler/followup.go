@@ -0,0 +1,373 @@
+// Package scheduler runs background jobs that act on dates stored in the
+// clinic records.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"clinic-management/internal/notification"
+	"clinic-management/internal/utils"
+)
+
+// Follow-up statuses stored in TAIKHAM.
+const (
+	FollowUpSuggested   = "SUGGESTED"
+	FollowUpNoSlot      = "NO_SLOT"
+	FollowUpConfirmed   = "CONFIRMED"
+	FollowUpRescheduled = "RESCHEDULED"
+	FollowUpCompleted   = "COMPLETED"
+	FollowUpMissed      = "MISSED"
+)
+
+// slotSearchDays is how many days after the requested follow-up date are
+// searched for a free slot with the same doctor.
+const slotSearchDays = 7
+
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// FollowUpScheduler turns HOSO.ngayTaiKham into suggested appointments. For
+// each follow-up due within the lookahead window it books a PENDING
+// LICHKHAM with the same doctor and clinic and notifies the patient, who
+// then confirms it or picks another slot.
+type FollowUpScheduler struct {
+	db        *sql.DB
+	notifier  notification.Notifier
+	lookahead int
+}
+
+func NewFollowUpScheduler(db *sql.DB, notifier notification.Notifier, lookaheadDays int) *FollowUpScheduler {
+	if notifier == nil {
+		notifier = notification.LogNotifier{}
+	}
+	return &FollowUpScheduler{db: db, notifier: notifier, lookahead: lookaheadDays}
+}
+
+// Run calls RunOnce every interval until ctx is cancelled.
+func (s *FollowUpScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			log.Printf("follow-up scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type dueFollowUp struct {
+	maTaiKham    sql.NullString
+	maHoSo       string
+	maCustomer   string
+	maBacSi      string
+	maPhongKham  string
+	ngayTaiKham  time.Time
+	tenBacSi     string
+	tenPhongKham string
+}
+
+// RunOnce updates the outcome of past follow-ups and schedules the ones
+// coming up.
+func (s *FollowUpScheduler) RunOnce(ctx context.Context) error {
+	if err := s.closeFollowUps(); err != nil {
+		return err
+	}
+
+	today := truncateDay(time.Now())
+	rows, err := s.db.Query(`
+		SELECT t.maTaiKham, h.maHoSo, h.maCustomer, h.maBacSi, h.maPhongKham, h.ngayTaiKham,
+		       u.hoTen, p.tenPhongKham
+		FROM HOSO h
+		JOIN [USER] u ON h.maBacSi = u.userID
+		JOIN PHONGKHAM p ON h.maPhongKham = p.maPhongKham
+		LEFT JOIN TAIKHAM t ON t.maHoSo = h.maHoSo
+		WHERE h.ngayTaiKham >= @p1 AND h.ngayTaiKham <= @p2
+		  AND (t.maTaiKham IS NULL OR t.trangThai = 'NO_SLOT')
+	`, today, today.AddDate(0, 0, s.lookahead))
+	if err != nil {
+		return fmt.Errorf("error finding due follow-ups: %v", err)
+	}
+
+	var due []dueFollowUp
+	for rows.Next() {
+		var f dueFollowUp
+		if err := rows.Scan(&f.maTaiKham, &f.maHoSo, &f.maCustomer, &f.maBacSi, &f.maPhongKham, &f.ngayTaiKham,
+			&f.tenBacSi, &f.tenPhongKham); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning follow-up: %v", err)
+		}
+		due = append(due, f)
+	}
+	rows.Close()
+
+	for _, f := range due {
+		if err := s.schedule(ctx, f); err != nil {
+			log.Printf("follow-up scheduler: record %s: %v", f.maHoSo, err)
+		}
+	}
+	return nil
+}
+
+// schedule links a follow-up to an appointment the patient already has
+// with the doctor, or books a suggested one in the first free slot.
+func (s *FollowUpScheduler) schedule(ctx context.Context, f dueFollowUp) error {
+	var existing string
+	err := s.db.QueryRow(`
+		SELECT TOP 1 maLichKham FROM LICHKHAM
+		WHERE maCustomer = @p1 AND maBacSi = @p2 AND trangThai IN ('SCHEDULED', 'CONFIRMED', 'PENDING') AND ngayGioKham >= @p3
+		ORDER BY ngayGioKham
+	`, f.maCustomer, f.maBacSi, truncateDay(time.Now())).Scan(&existing)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if existing != "" {
+		return s.saveFollowUp(nil, f, existing, FollowUpConfirmed)
+	}
+
+	from := f.ngayTaiKham
+	if today := truncateDay(time.Now()); from.Before(today) {
+		from = today
+	}
+	slot, found, err := FindSlot(s.db, f.maBacSi, f.maPhongKham, from, slotSearchDays)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		if f.maTaiKham.Valid {
+			// Already recorded and the patient told; try again next run.
+			return nil
+		}
+		if err := s.saveFollowUp(nil, f, "", FollowUpNoSlot); err != nil {
+			return err
+		}
+		return s.notify(ctx, notification.Message{
+			UserID:   f.maCustomer,
+			Template: notification.TemplateFollowUpNoSlot,
+			Data: map[string]string{
+				"ngay_tai_kham":  f.ngayTaiKham.Format("02/01/2006"),
+				"ten_bac_si":     f.tenBacSi,
+				"ten_phong_kham": f.tenPhongKham,
+			},
+			Reference: f.maHoSo,
+		})
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	appointmentID, err := utils.GenerateAppointmentID()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO LICHKHAM (maLichKham, maCustomer, maBacSi, maPhongKham, ngayGioKham, trangThai, ghiChu, createdAt)
+		VALUES (@p1, @p2, @p3, @p4, @p5, 'PENDING', @p6, GETDATE())
+	`, appointmentID, f.maCustomer, f.maBacSi, f.maPhongKham, slot, "Tái khám theo hồ sơ "+f.maHoSo)
+	if err != nil {
+		return err
+	}
+	if err = s.saveFollowUp(tx, f, appointmentID, FollowUpSuggested); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return s.notify(ctx, notification.Message{
+		UserID:   f.maCustomer,
+		Template: notification.TemplateFollowUpSuggested,
+		Data: map[string]string{
+			"thoi_gian":      slot.Format("15:04 02/01/2006"),
+			"ten_bac_si":     f.tenBacSi,
+			"ten_phong_kham": f.tenPhongKham,
+		},
+		Reference: appointmentID,
+	})
+}
+
+// notify treats a patient without usable contact details as reachable only
+// through the app, where the follow-up is listed anyway.
+func (s *FollowUpScheduler) notify(ctx context.Context, msg notification.Message) error {
+	if err := s.notifier.Notify(ctx, msg); err != nil && err != notification.ErrNoChannel {
+		return err
+	}
+	return nil
+}
+
+func (s *FollowUpScheduler) saveFollowUp(tx *sql.Tx, f dueFollowUp, appointmentID, status string) error {
+	exec := s.db.Exec
+	if tx != nil {
+		exec = tx.Exec
+	}
+
+	var appointment interface{}
+	if appointmentID != "" {
+		appointment = appointmentID
+	}
+
+	if f.maTaiKham.Valid {
+		_, err := exec(`
+			UPDATE TAIKHAM SET maLichKham = @p1, trangThai = @p2, ngayCapNhat = GETDATE()
+			WHERE maTaiKham = @p3
+		`, appointment, status, f.maTaiKham.String)
+		return err
+	}
+
+	id, err := utils.GenerateFollowUpID()
+	if err != nil {
+		return err
+	}
+	_, err = exec(`
+		INSERT INTO TAIKHAM (maTaiKham, maHoSo, maCustomer, maBacSi, maPhongKham, ngayTaiKham, maLichKham, trangThai, ngayTao, ngayCapNhat)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, GETDATE(), GETDATE())
+	`, id, f.maHoSo, f.maCustomer, f.maBacSi, f.maPhongKham, f.ngayTaiKham, appointment, status)
+	return err
+}
+
+// closeFollowUps records the outcome of follow-ups whose day has passed:
+// completed if the linked appointment took place, missed otherwise.
+// Suggestions the patient never confirmed are cancelled to free the slot.
+func (s *FollowUpScheduler) closeFollowUps() error {
+	today := truncateDay(time.Now())
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE t SET trangThai = 'COMPLETED', ngayCapNhat = GETDATE()
+		  FROM TAIKHAM t JOIN LICHKHAM l ON t.maLichKham = l.maLichKham
+		  WHERE l.trangThai = 'COMPLETED' AND t.trangThai IN ('SUGGESTED', 'CONFIRMED', 'RESCHEDULED')`, nil},
+		{`UPDATE t SET trangThai = 'MISSED', ngayCapNhat = GETDATE()
+		  FROM TAIKHAM t JOIN LICHKHAM l ON t.maLichKham = l.maLichKham
+		  WHERE l.ngayGioKham < @p1 AND t.trangThai IN ('SUGGESTED', 'CONFIRMED', 'RESCHEDULED')`, []interface{}{today}},
+		{`UPDATE LICHKHAM SET trangThai = 'CANCELLED'
+		  WHERE trangThai = 'PENDING' AND ngayGioKham < @p1`, []interface{}{today}},
+		// Follow-ups that never got a slot count as attended if the patient
+		// booked the doctor on their own around the follow-up date.
+		{`UPDATE t SET trangThai = CASE WHEN EXISTS (
+		      SELECT 1 FROM LICHKHAM l
+		      WHERE l.maCustomer = t.maCustomer AND l.maBacSi = t.maBacSi AND l.trangThai = 'COMPLETED'
+		        AND l.ngayGioKham >= DATEADD(day, -@p2, t.ngayTaiKham)
+		    ) THEN 'COMPLETED' ELSE 'MISSED' END,
+		    ngayCapNhat = GETDATE()
+		  FROM TAIKHAM t
+		  WHERE t.trangThai = 'NO_SLOT' AND t.ngayTaiKham < DATEADD(day, -@p2, @p1)`, []interface{}{today, slotSearchDays}},
+	}
+
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("error closing follow-ups: %v", err)
+		}
+	}
+	return nil
+}
+
+// FindSlot returns the first free hourly slot of a doctor at a clinic,
+// starting on the given day and searching the following days. Slots follow
+// the doctor's AVAILABLE work schedule, as in the clinic schedule endpoint.
+func FindSlot(q queryer, doctorID, clinicID string, from time.Time, days int) (time.Time, bool, error) {
+	now := time.Now()
+	for d := 0; d <= days; d++ {
+		day := truncateDay(from).AddDate(0, 0, d)
+
+		booked, err := bookedHours(q, doctorID, day)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		rows, err := q.Query(`
+			SELECT gioBatDau, gioKetThuc FROM LICHLAMVIEC
+			WHERE maBacSi = @p1 AND maPhongKham = @p2 AND ngayLamViec = @p3 AND status = 'AVAILABLE'
+			ORDER BY gioBatDau
+		`, doctorID, clinicID, day)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		var slot time.Time
+		for rows.Next() && slot.IsZero() {
+			var start, end time.Time
+			if err := rows.Scan(&start, &end); err != nil {
+				rows.Close()
+				return time.Time{}, false, err
+			}
+			for hour := start.Hour(); hour < end.Hour(); hour++ {
+				candidate := time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, day.Location())
+				if candidate.After(now) && !booked[hour] {
+					slot = candidate
+					break
+				}
+			}
+		}
+		rows.Close()
+
+		if !slot.IsZero() {
+			return slot, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// SlotAvailable reports whether a doctor works at the clinic at the given
+// time and has no other appointment then.
+func SlotAvailable(q queryer, doctorID, clinicID string, at time.Time) (bool, error) {
+	if !at.After(time.Now()) {
+		return false, nil
+	}
+
+	var working int
+	err := q.QueryRow(`
+		SELECT COUNT(*) FROM LICHLAMVIEC
+		WHERE maBacSi = @p1 AND maPhongKham = @p2 AND ngayLamViec = @p3 AND status = 'AVAILABLE'
+		  AND CAST(gioBatDau AS TIME) <= @p4 AND CAST(gioKetThuc AS TIME) > @p4
+	`, doctorID, clinicID, truncateDay(at), at.Format("15:04:05")).Scan(&working)
+	if err != nil || working == 0 {
+		return false, err
+	}
+
+	booked, err := bookedHours(q, doctorID, truncateDay(at))
+	if err != nil {
+		return false, err
+	}
+	return !booked[at.Hour()], nil
+}
+
+func bookedHours(q queryer, doctorID string, day time.Time) (map[int]bool, error) {
+	rows, err := q.Query(`
+		SELECT ngayGioKham FROM LICHKHAM
+		WHERE maBacSi = @p1 AND CAST(ngayGioKham AS DATE) = @p2
+		  AND trangThai NOT IN ('CANCELLED', 'NO_SHOW')
+	`, doctorID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	booked := map[int]bool{}
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return nil, err
+		}
+		booked[at.Hour()] = true
+	}
+	return booked, rows.Err()
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
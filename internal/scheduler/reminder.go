@@ -0,0 +1,204 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"clinic-management/internal/notification"
+	"clinic-management/internal/utils"
+)
+
+// Actions a patient can take from the links in a reminder.
+const (
+	ReminderActionConfirm = "CONFIRM"
+	ReminderActionCancel  = "CANCEL"
+)
+
+// ReminderScheduler reminds patients of upcoming SCHEDULED or CONFIRMED
+// appointments at each configured offset before the visit. Every reminder
+// is claimed in NHACLICH before it is sent, so several server instances
+// can run the scheduler without sending the same reminder twice.
+type ReminderScheduler struct {
+	db         *sql.DB
+	notifier   notification.Notifier
+	offsets    []time.Duration
+	baseURL    string
+	linkSecret string
+}
+
+// NewReminderScheduler builds a scheduler for the given offsets, e.g. 24h
+// and 2h. Links in the reminders point to baseURL and are signed with
+// linkSecret.
+func NewReminderScheduler(db *sql.DB, notifier notification.Notifier, offsets []time.Duration, baseURL, linkSecret string) *ReminderScheduler {
+	if notifier == nil {
+		notifier = notification.LogNotifier{}
+	}
+	sorted := make([]time.Duration, 0, len(offsets))
+	for _, o := range offsets {
+		if o > 0 {
+			sorted = append(sorted, o)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &ReminderScheduler{
+		db:         db,
+		notifier:   notifier,
+		offsets:    sorted,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		linkSecret: linkSecret,
+	}
+}
+
+// Run calls RunOnce every interval until ctx is cancelled.
+func (s *ReminderScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			log.Printf("reminder scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type upcomingAppointment struct {
+	maLichKham   string
+	maCustomer   string
+	ngayGioKham  time.Time
+	tenBacSi     string
+	tenPhongKham string
+}
+
+// RunOnce sends the reminders that are due. An appointment only gets the
+// reminder of the smallest offset it has reached, so one booked two hours
+// ahead is not sent the 24h reminder as well.
+func (s *ReminderScheduler) RunOnce(ctx context.Context) error {
+	if len(s.offsets) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows, err := s.db.Query(`
+		SELECT l.maLichKham, l.maCustomer, l.ngayGioKham, u.hoTen, p.tenPhongKham
+		FROM LICHKHAM l
+		JOIN [USER] u ON l.maBacSi = u.userID
+		JOIN PHONGKHAM p ON l.maPhongKham = p.maPhongKham
+		WHERE l.trangThai IN ('SCHEDULED', 'CONFIRMED') AND l.ngayGioKham > @p1 AND l.ngayGioKham <= @p2
+	`, now, now.Add(s.offsets[len(s.offsets)-1]))
+	if err != nil {
+		return fmt.Errorf("error finding upcoming appointments: %v", err)
+	}
+
+	var upcoming []upcomingAppointment
+	for rows.Next() {
+		var a upcomingAppointment
+		if err := rows.Scan(&a.maLichKham, &a.maCustomer, &a.ngayGioKham, &a.tenBacSi, &a.tenPhongKham); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning appointment: %v", err)
+		}
+		upcoming = append(upcoming, a)
+	}
+	rows.Close()
+
+	for _, a := range upcoming {
+		offset, ok := s.dueOffset(a.ngayGioKham.Sub(now))
+		if !ok {
+			continue
+		}
+		if err := s.remind(ctx, a, offset); err != nil {
+			log.Printf("reminder scheduler: appointment %s: %v", a.maLichKham, err)
+		}
+	}
+	return nil
+}
+
+func (s *ReminderScheduler) dueOffset(remaining time.Duration) (time.Duration, bool) {
+	for _, o := range s.offsets {
+		if remaining <= o {
+			return o, true
+		}
+	}
+	return 0, false
+}
+
+func (s *ReminderScheduler) remind(ctx context.Context, a upcomingAppointment, offset time.Duration) error {
+	minutes := int(offset / time.Minute)
+
+	// HOLDLOCK makes the existence check and insert atomic, so exactly one
+	// instance claims each reminder. The appointment time is part of the key
+	// so a rescheduled appointment is reminded again.
+	result, err := s.db.Exec(`
+		MERGE NHACLICH WITH (HOLDLOCK) AS t
+		USING (SELECT @p1 AS maLichKham, @p2 AS ngayGioKham, @p3 AS moc) AS src
+		ON t.maLichKham = src.maLichKham AND t.ngayGioKham = src.ngayGioKham AND t.moc = src.moc
+		WHEN NOT MATCHED THEN
+			INSERT (maLichKham, ngayGioKham, moc, ngayGui) VALUES (src.maLichKham, src.ngayGioKham, src.moc, GETDATE());
+	`, a.maLichKham, a.ngayGioKham, minutes)
+	if err != nil {
+		return fmt.Errorf("error claiming reminder: %v", err)
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		return nil
+	}
+
+	err = s.notifier.Notify(ctx, notification.Message{
+		UserID:   a.maCustomer,
+		Template: notification.TemplateAppointmentReminder,
+		Data: map[string]string{
+			"thoi_gian":      a.ngayGioKham.Format("15:04 02/01/2006"),
+			"ten_bac_si":     a.tenBacSi,
+			"ten_phong_kham": a.tenPhongKham,
+			"link_xac_nhan":  s.link(a, ReminderActionConfirm),
+			"link_huy":       s.link(a, ReminderActionCancel),
+		},
+		Reference: a.maLichKham,
+	})
+	if err == nil || err == notification.ErrNoChannel {
+		return nil
+	}
+
+	// Release the claim so the next run tries again.
+	_, delErr := s.db.Exec(`
+		DELETE FROM NHACLICH WHERE maLichKham = @p1 AND ngayGioKham = @p2 AND moc = @p3
+	`, a.maLichKham, a.ngayGioKham, minutes)
+	if delErr != nil {
+		log.Printf("reminder scheduler: appointment %s: error releasing claim: %v", a.maLichKham, delErr)
+	}
+	return err
+}
+
+func (s *ReminderScheduler) link(a upcomingAppointment, action string) string {
+	return s.baseURL + "/api/v1/appointment-links/" + ReminderToken(s.linkSecret, a.maLichKham, action, a.ngayGioKham)
+}
+
+// ReminderToken signs an action on an appointment for use in a link. The
+// token is valid until the appointment starts.
+func ReminderToken(secret, appointmentID, action string, expiresAt time.Time) string {
+	return utils.SignToken(secret, "appointment:"+appointmentID+":"+action, expiresAt)
+}
+
+// ParseReminderToken verifies a token made by ReminderToken and returns the
+// appointment and action it carries.
+func ParseReminderToken(secret, token string) (appointmentID, action string, err error) {
+	payload, err := utils.VerifyToken(secret, token)
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 || parts[0] != "appointment" ||
+		(parts[2] != ReminderActionConfirm && parts[2] != ReminderActionCancel) {
+		return "", "", utils.ErrInvalidToken
+	}
+	return parts[1], parts[2], nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+// Appointment statuses.
+const (
+	AppointmentScheduled = "SCHEDULED"
+	AppointmentConfirmed = "CONFIRMED"
+	AppointmentCompleted = "COMPLETED"
+	AppointmentCancelled = "CANCELLED"
+	AppointmentNoShow    = "NO_SHOW"
+)
+
+// AppointmentSorts are the sorts appointment lists accept.
+var AppointmentSorts = map[string]SortKey{
+	"ngay_gio_kham": {Expr: "l.ngayGioKham", Kind: SortTime},
+	"ngay_dat":      {Expr: "ISNULL(l.createdAt, '1900-01-01')", Kind: SortTime},
+	"trang_thai":    {Expr: "l.trangThai", Kind: SortText},
+}
+
+// AppointmentFilter selects the appointments of a list. Empty fields match
+// any; Dates applies to the appointment time.
+type AppointmentFilter struct {
+	Status     string
+	DoctorID   string
+	ClinicID   string
+	CustomerID string
+	RoomID     string
+	Dates      DateRange
+}
+
+// AppointmentChanges are the fields of an appointment to change; nil
+// fields are kept. An empty GhiChu or MaPhong clears it.
+type AppointmentChanges struct {
+	NgayGioKham *time.Time
+	TrangThai   *string
+	GhiChu      *string
+	MaPhong     *string
+}
+
+// AppointmentRepository stores appointments (LICHKHAM).
+type AppointmentRepository interface {
+	// Get returns ErrNotFound if there is no such appointment.
+	Get(id string) (*models.AppointmentDetail, error)
+	// List returns one page of the appointments matching f, with one more
+	// when another page follows, and the number of all of them unless
+	// params has a cursor.
+	List(f AppointmentFilter, params utils.ListParams) ([]models.AppointmentDetail, int, error)
+	// SlotTaken reports whether the doctor has an appointment at the time
+	// at that is neither cancelled nor completed.
+	SlotTaken(doctorID string, at time.Time) (bool, error)
+	// RoomBooking returns an appointment other than excludeID booked in the
+	// room at the time at and neither cancelled nor missed, or "" if there
+	// is none.
+	RoomBooking(roomID string, at time.Time, excludeID string) (string, error)
+	// Create stores a new appointment, booked now.
+	Create(a models.Appointment) error
+	// AssignScheduledRoom puts an appointment in the room of the doctor's
+	// work schedule covering it, unless that room is already booked then.
+	AssignScheduledRoom(id string) error
+	// Update stores the time, status, note and room of an appointment.
+	Update(a models.Appointment) error
+	SetStatus(id, status string) error
+}
+
+// AppointmentService applies the rules on who sees and changes an
+// appointment: customers and doctors only their own, staff any.
+type AppointmentService struct {
+	appointments AppointmentRepository
+	schedules    ScheduleRepository
+	clinics      ClinicRepository
+}
+
+func NewAppointmentService(appointments AppointmentRepository, schedules ScheduleRepository, clinics ClinicRepository) *AppointmentService {
+	return &AppointmentService{appointments: appointments, schedules: schedules, clinics: clinics}
+}
+
+// List returns one page of the appointments the viewer may see.
+func (s *AppointmentService) List(v Viewer, f AppointmentFilter, params utils.ListParams) ([]models.AppointmentDetail, int, error) {
+	switch v.Role {
+	case RoleCustomer:
+		if !restrict(&f.CustomerID, v.UserID) {
+			return nil, 0, nil
+		}
+	case RoleDoctor:
+		if !restrict(&f.DoctorID, v.UserID) {
+			return nil, 0, nil
+		}
+	}
+	return s.appointments.List(f, params)
+}
+
+// Book books an appointment of the viewer, a customer, with a doctor and
+// returns its ID. The appointment takes place in the room of the doctor's
+// work schedule when that room is free.
+func (s *AppointmentService) Book(v Viewer, doctorID, clinicID string, at time.Time, note string) (string, error) {
+	if v.Role != RoleCustomer {
+		return "", forbidden("Only customers can book appointments")
+	}
+
+	taken, err := s.appointments.SlotTaken(doctorID, at)
+	if err != nil {
+		return "", fmt.Errorf("error checking availability: %v", err)
+	}
+	if taken {
+		return "", conflict("Time slot is not available")
+	}
+
+	id, err := utils.GenerateAppointmentID()
+	if err != nil {
+		return "", err
+	}
+	appointment := models.Appointment{
+		MaLichKham:  id,
+		MaCustomer:  v.UserID,
+		MaBacSi:     doctorID,
+		MaPhongKham: clinicID,
+		NgayGioKham: at,
+		TrangThai:   AppointmentScheduled,
+		GhiChu:      &note,
+	}
+	if err := s.appointments.Create(appointment); err != nil {
+		return "", err
+	}
+	if err := s.appointments.AssignScheduledRoom(appointment.MaLichKham); err != nil {
+		log.Printf("Failed to assign room to appointment %s: %v", appointment.MaLichKham, err)
+	}
+	return appointment.MaLichKham, nil
+}
+
+// Get returns an appointment the viewer may see. Other people's
+// appointments are reported as not found.
+func (s *AppointmentService) Get(v Viewer, id string) (*models.AppointmentDetail, error) {
+	appointment, err := s.appointments.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("Appointment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (v.Role == RoleCustomer && appointment.MaCustomer != v.UserID) ||
+		(v.Role == RoleDoctor && appointment.MaBacSi != v.UserID) {
+		return nil, notFound("Appointment not found")
+	}
+	return appointment, nil
+}
+
+// Cancel cancels an appointment. Customers may only cancel their own.
+func (s *AppointmentService) Cancel(v Viewer, id string) error {
+	appointment, err := s.appointments.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return notFound("Appointment not found")
+	}
+	if err != nil {
+		return err
+	}
+	if v.Role == RoleCustomer && appointment.MaCustomer != v.UserID {
+		return forbidden("You can only cancel your own appointments")
+	}
+	return s.appointments.SetStatus(id, AppointmentCancelled)
+}
+
+// Update changes an appointment. Customers and doctors may change their
+// own, and only staff may assign rooms. A booked room must still be free
+// after a change of time or room.
+func (s *AppointmentService) Update(v Viewer, id string, changes AppointmentChanges) error {
+	current, err := s.appointments.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return notFound("Appointment not found")
+	}
+	if err != nil {
+		return err
+	}
+	if (v.Role == RoleCustomer && current.MaCustomer != v.UserID) ||
+		(v.Role == RoleDoctor && current.MaBacSi != v.UserID) {
+		return forbidden("You can only update your own appointments")
+	}
+	if changes.MaPhong != nil && v.Role == RoleCustomer {
+		return forbidden("Only clinic staff can assign rooms")
+	}
+
+	appointment := current.Appointment
+	if changes.NgayGioKham != nil {
+		appointment.NgayGioKham = *changes.NgayGioKham
+	}
+	if changes.TrangThai != nil {
+		appointment.TrangThai = *changes.TrangThai
+	}
+	if changes.GhiChu != nil {
+		appointment.GhiChu = nil
+		if *changes.GhiChu != "" {
+			appointment.GhiChu = changes.GhiChu
+		}
+	}
+	if changes.MaPhong != nil {
+		appointment.MaPhong = nil
+		if *changes.MaPhong != "" {
+			appointment.MaPhong = changes.MaPhong
+		}
+	}
+
+	active := appointment.TrangThai != AppointmentCancelled && appointment.TrangThai != AppointmentNoShow &&
+		appointment.TrangThai != AppointmentCompleted
+	if appointment.MaPhong != nil && active &&
+		(changes.NgayGioKham != nil || changes.MaPhong != nil || changes.TrangThai != nil) {
+		if err := s.checkRoom(appointment); err != nil {
+			return err
+		}
+	}
+	return s.appointments.Update(appointment)
+}
+
+// checkRoom checks that the room of an appointment is an active room of
+// its clinic, that no other appointment is booked in it at the same time
+// and that it is not reserved by another doctor's work schedule then.
+func (s *AppointmentService) checkRoom(appointment models.Appointment) error {
+	roomID := *appointment.MaPhong
+	if err := checkRoom(s.clinics, roomID, appointment.MaPhongKham); err != nil {
+		return err
+	}
+
+	other, err := s.appointments.RoomBooking(roomID, appointment.NgayGioKham, appointment.MaLichKham)
+	if err != nil {
+		return fmt.Errorf("error checking room availability: %v", err)
+	}
+	if other != "" {
+		return conflict(fmt.Sprintf("Room is already booked for appointment %s at this time", other))
+	}
+
+	other, err = s.schedules.RoomReservation(roomID, appointment.NgayGioKham, appointment.MaBacSi)
+	if err != nil {
+		return fmt.Errorf("error checking room availability: %v", err)
+	}
+	if other != "" {
+		return conflict(fmt.Sprintf("Room is reserved for another doctor's schedule %s at this time", other))
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+type sqlAppointmentRepository struct {
+	db *sql.DB
+}
+
+// NewSQLAppointmentRepository stores appointments in SQL Server.
+func NewSQLAppointmentRepository(db *sql.DB) AppointmentRepository {
+	return &sqlAppointmentRepository{db: db}
+}
+
+func (r *sqlAppointmentRepository) Get(id string) (*models.AppointmentDetail, error) {
+	var a models.AppointmentDetail
+	var ghiChu, maPhong, tenKhachHang, tenBacSi, tenPhongKham, tenPhong sql.NullString
+	var ngayDat sql.NullTime
+
+	err := r.db.QueryRow(`
+		SELECT l.maLichKham, l.maCustomer, l.maBacSi, l.maPhongKham, l.maPhong,
+		       l.ngayGioKham, l.trangThai, l.ghiChu, l.createdAt,
+		       uc.hoTen, ud.hoTen, p.tenPhongKham, ph.tenPhong
+		FROM LICHKHAM l
+		LEFT JOIN [USER] uc ON l.maCustomer = uc.userID
+		LEFT JOIN [USER] ud ON l.maBacSi = ud.userID
+		LEFT JOIN PHONGKHAM p ON l.maPhongKham = p.maPhongKham
+		LEFT JOIN PHONG ph ON l.maPhong = ph.maPhong
+		WHERE l.maLichKham = @p1
+	`, id).Scan(
+		&a.MaLichKham, &a.MaCustomer, &a.MaBacSi, &a.MaPhongKham, &maPhong,
+		&a.NgayGioKham, &a.TrangThai, &ghiChu, &ngayDat,
+		&tenKhachHang, &tenBacSi, &tenPhongKham, &tenPhong,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.MaPhong = stringPtr(maPhong)
+	a.GhiChu = stringPtr(ghiChu)
+	a.NgayDat = timePtr(ngayDat)
+	a.TenKhachHang = tenKhachHang.String
+	a.TenBacSi = tenBacSi.String
+	a.TenPhongKham = tenPhongKham.String
+	a.TenPhong = stringPtr(tenPhong)
+	return &a, nil
+}
+
+func (r *sqlAppointmentRepository) SetStatus(id, status string) error {
+	result, err := r.db.Exec("UPDATE LICHKHAM SET trangThai = @p1 WHERE maLichKham = @p2", status, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+var appointmentList = ListQuery{Sorts: AppointmentSorts, IDColumn: "l.maLichKham"}
+
+func (r *sqlAppointmentRepository) List(f AppointmentFilter, params utils.ListParams) ([]models.AppointmentDetail, int, error) {
+	columns := `
+		l.maLichKham, l.maCustomer, l.maBacSi, l.maPhongKham,
+		l.trangThai, l.ghiChu, l.createdAt, l.ngayGioKham,
+		uc.hoTen as TenKhachHang, ud.hoTen as TenBacSi, p.tenPhongKham, l.maPhong, ph.tenPhong
+	`
+	from := `
+		FROM LICHKHAM l
+		JOIN [USER] uc ON l.maCustomer = uc.userID
+		JOIN [USER] ud ON l.maBacSi = ud.userID
+		JOIN PHONGKHAM p ON l.maPhongKham = p.maPhongKham
+		LEFT JOIN PHONG ph ON l.maPhong = ph.maPhong
+		WHERE 1=1
+	`
+	where, args := whereEqual([]columnValue{
+		{"l.trangThai", f.Status},
+		{"l.maBacSi", f.DoctorID},
+		{"l.maPhongKham", f.ClinicID},
+		{"l.maCustomer", f.CustomerID},
+		{"l.maPhong", f.RoomID},
+	}, nil)
+	dates, args := f.Dates.Where("l.ngayGioKham", args)
+	from += where + dates
+
+	var appointments []models.AppointmentDetail
+	total, err := listRows(r.db, appointmentList, columns, from, args, params, func(rows *sql.Rows) error {
+		var a models.AppointmentDetail
+		var ghiChu, tenKhachHang, tenBacSi, tenPhongKham, maPhong, tenPhong sql.NullString
+		var ngayDat sql.NullTime
+
+		err := rows.Scan(&a.MaLichKham, &a.MaCustomer, &a.MaBacSi, &a.MaPhongKham,
+			&a.TrangThai, &ghiChu, &ngayDat, &a.NgayGioKham, &tenKhachHang, &tenBacSi, &tenPhongKham, &maPhong, &tenPhong)
+		if err != nil {
+			return err
+		}
+
+		a.GhiChu = stringPtr(ghiChu)
+		a.NgayDat = timePtr(ngayDat)
+		a.TenKhachHang = tenKhachHang.String
+		a.TenBacSi = tenBacSi.String
+		a.TenPhongKham = tenPhongKham.String
+		a.MaPhong = stringPtr(maPhong)
+		a.TenPhong = stringPtr(tenPhong)
+		appointments = append(appointments, a)
+		return nil
+	})
+	return appointments, total, err
+}
+
+func (r *sqlAppointmentRepository) SlotTaken(doctorID string, at time.Time) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM LICHKHAM
+		WHERE maBacSi = @p1 AND ngayGioKham = @p2 AND trangThai NOT IN ('CANCELLED', 'COMPLETED')
+	`, doctorID, at).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlAppointmentRepository) RoomBooking(roomID string, at time.Time, excludeID string) (string, error) {
+	var other string
+	err := r.db.QueryRow(`
+		SELECT TOP 1 maLichKham FROM LICHKHAM
+		WHERE maPhong = @p1 AND ngayGioKham = @p2 AND maLichKham <> @p3
+		  AND trangThai NOT IN ('CANCELLED', 'NO_SHOW')
+	`, roomID, at, excludeID).Scan(&other)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return other, err
+}
+
+func (r *sqlAppointmentRepository) Create(a models.Appointment) error {
+	_, err := r.db.Exec(`
+		INSERT INTO LICHKHAM (maLichKham, maCustomer, maBacSi, maPhongKham, ngayGioKham, trangThai, ghiChu, createdAt)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, GETDATE())
+	`, a.MaLichKham, a.MaCustomer, a.MaBacSi, a.MaPhongKham, a.NgayGioKham, a.TrangThai, a.GhiChu)
+	return err
+}
+
+func (r *sqlAppointmentRepository) AssignScheduledRoom(id string) error {
+	_, err := r.db.Exec(`
+		UPDATE l SET maPhong = ll.maPhong
+		FROM LICHKHAM l
+		JOIN LICHLAMVIEC ll ON ll.maBacSi = l.maBacSi AND ll.maPhongKham = l.maPhongKham
+		  AND ll.ngayLamViec = CAST(l.ngayGioKham AS DATE)
+		  AND CAST(ll.gioBatDau AS TIME) <= CAST(l.ngayGioKham AS TIME)
+		  AND CAST(ll.gioKetThuc AS TIME) > CAST(l.ngayGioKham AS TIME)
+		JOIN PHONG ph ON ph.maPhong = ll.maPhong AND ph.trangThai = 'ACTIVE'
+		WHERE l.maLichKham = @p1
+		  AND NOT EXISTS (
+			SELECT 1 FROM LICHKHAM o
+			WHERE o.maPhong = ll.maPhong AND o.ngayGioKham = l.ngayGioKham AND o.maLichKham <> l.maLichKham
+			  AND o.trangThai NOT IN ('CANCELLED', 'NO_SHOW')
+		  )
+	`, id)
+	return err
+}
+
+func (r *sqlAppointmentRepository) Update(a models.Appointment) error {
+	result, err := r.db.Exec(`
+		UPDATE LICHKHAM SET ngayGioKham = @p1, trangThai = @p2, ghiChu = @p3, maPhong = @p4
+		WHERE maLichKham = @p5
+	`, a.NgayGioKham, a.TrangThai, a.GhiChu, a.MaPhong, a.MaLichKham)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
@@ -0,0 +1,212 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+	"clinic-management/internal/utils"
+)
+
+func newAppointmentStore() *memory.Store {
+	store := memory.NewStore()
+	store.Appointments["LK001"] = models.AppointmentDetail{
+		Appointment: models.Appointment{
+			MaLichKham:  "LK001",
+			MaCustomer:  "CUS001",
+			MaBacSi:     "BS001",
+			MaPhongKham: "PK001",
+			NgayGioKham: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+			TrangThai:   services.AppointmentScheduled,
+		},
+		TenKhachHang: "Nguyen Van A",
+		TenBacSi:     "Tran Thi B",
+	}
+	return store
+}
+
+func newAppointmentService(store *memory.Store) *services.AppointmentService {
+	return services.NewAppointmentService(store.AppointmentRepository(), store.ScheduleRepository(), store.ClinicRepository())
+}
+
+func TestAppointmentGetVisibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		id     string
+		want   error
+	}{
+		{"own customer", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, "LK001", nil},
+		{"other customer", services.Viewer{UserID: "CUS002", Role: services.RoleCustomer}, "LK001", services.ErrNotFound},
+		{"own doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, "LK001", nil},
+		{"other doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, "LK001", services.ErrNotFound},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, "LK001", nil},
+		{"missing", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, "LK999", services.ErrNotFound},
+	}
+
+	svc := newAppointmentService(newAppointmentStore())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointment, err := svc.Get(tt.viewer, tt.id)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("Get() error = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if appointment.MaLichKham != tt.id || appointment.TenBacSi != "Tran Thi B" {
+				t.Errorf("Get() = %+v", appointment)
+			}
+		})
+	}
+}
+
+func TestAppointmentCancel(t *testing.T) {
+	store := newAppointmentStore()
+	svc := newAppointmentService(store)
+
+	err := svc.Cancel(services.Viewer{UserID: "CUS002", Role: services.RoleCustomer}, "LK001")
+	if !errors.Is(err, services.ErrForbidden) {
+		t.Fatalf("Cancel() by another customer error = %v, want ErrForbidden", err)
+	}
+	if status := store.Appointments["LK001"].TrangThai; status != services.AppointmentScheduled {
+		t.Fatalf("status after refused cancel = %s", status)
+	}
+
+	if err := svc.Cancel(services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, "LK001"); err != nil {
+		t.Fatalf("Cancel() by owner error = %v", err)
+	}
+	if status := store.Appointments["LK001"].TrangThai; status != services.AppointmentCancelled {
+		t.Errorf("status = %s, want %s", status, services.AppointmentCancelled)
+	}
+
+	err = svc.Cancel(services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, "LK999")
+	if !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("Cancel() of missing appointment error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAppointmentListVisibility(t *testing.T) {
+	store := newAppointmentStore()
+	store.Appointments["LK002"] = models.AppointmentDetail{Appointment: models.Appointment{
+		MaLichKham: "LK002", MaCustomer: "CUS002", MaBacSi: "BS002", MaPhongKham: "PK001",
+		NgayGioKham: time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC), TrangThai: services.AppointmentScheduled,
+	}}
+	svc := newAppointmentService(store)
+	params := utils.ListParams{Page: 1, PageSize: 20, SortField: "ngay_gio_kham"}
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		filter services.AppointmentFilter
+		want   []string
+	}{
+		{"customer", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, services.AppointmentFilter{}, []string{"LK001"}},
+		{"customer asking for another", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, services.AppointmentFilter{CustomerID: "CUS002"}, nil},
+		{"doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, services.AppointmentFilter{}, []string{"LK002"}},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, services.AppointmentFilter{}, []string{"LK001", "LK002"}},
+		{"receptionist by doctor", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, services.AppointmentFilter{DoctorID: "BS001"}, []string{"LK001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appointments, _, err := svc.List(tt.viewer, tt.filter, params)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []string
+			for _, a := range appointments {
+				got = append(got, a.MaLichKham)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppointmentBook(t *testing.T) {
+	store := newAppointmentStore()
+	store.Rooms["P101"] = models.Room{MaPhong: "P101", MaPhongKham: "PK001", TrangThai: services.RoomActive}
+	room := "P101"
+	store.Schedules["LLV001"] = models.WorkScheduleDetail{WorkSchedule: models.WorkSchedule{
+		MaLichLamViec: "LLV001", MaBacSi: "BS001", MaPhongKham: "PK001", MaPhong: &room,
+		NgayLamViec: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), GioBatDau: "08:00", GioKetThuc: "12:00",
+		TrangThai: services.ScheduleAvailable,
+	}}
+	svc := newAppointmentService(store)
+	customer := services.Viewer{UserID: "CUS002", Role: services.RoleCustomer}
+
+	_, err := svc.Book(services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, "BS001", "PK001", time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), "")
+	if !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Book() by receptionist error = %v, want ErrForbidden", err)
+	}
+
+	_, err = svc.Book(customer, "BS001", "PK001", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), "")
+	if !errors.Is(err, services.ErrConflict) {
+		t.Errorf("Book() of a taken slot error = %v, want ErrConflict", err)
+	}
+
+	id, err := svc.Book(customer, "BS001", "PK001", time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), "Headache")
+	if err != nil {
+		t.Fatalf("Book() error = %v", err)
+	}
+	booked := store.Appointments[id]
+	if booked.MaCustomer != "CUS002" || booked.TrangThai != services.AppointmentScheduled {
+		t.Errorf("booked appointment = %+v", booked.Appointment)
+	}
+	if booked.MaPhong == nil || *booked.MaPhong != "P101" {
+		t.Errorf("room = %v, want the scheduled room P101", booked.MaPhong)
+	}
+}
+
+func TestAppointmentUpdateRoom(t *testing.T) {
+	store := newAppointmentStore()
+	store.Rooms["P101"] = models.Room{MaPhong: "P101", MaPhongKham: "PK001", TrangThai: services.RoomActive}
+	store.Rooms["P102"] = models.Room{MaPhong: "P102", MaPhongKham: "PK001", TrangThai: "MAINTENANCE"}
+	store.Rooms["P201"] = models.Room{MaPhong: "P201", MaPhongKham: "PK002", TrangThai: services.RoomActive}
+	room := "P101"
+	store.Appointments["LK002"] = models.AppointmentDetail{Appointment: models.Appointment{
+		MaLichKham: "LK002", MaCustomer: "CUS002", MaBacSi: "BS002", MaPhongKham: "PK001", MaPhong: &room,
+		NgayGioKham: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), TrangThai: services.AppointmentScheduled,
+	}}
+	svc := newAppointmentService(store)
+	receptionist := services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		room   string
+		want   error
+	}{
+		{"customer", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, "P101", services.ErrForbidden},
+		{"room of another clinic", receptionist, "P201", services.ErrInvalid},
+		{"room out of service", receptionist, "P102", services.ErrConflict},
+		{"room booked at the same time", receptionist, "P101", services.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := tt.room
+			err := svc.Update(tt.viewer, "LK001", services.AppointmentChanges{MaPhong: &room})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Update() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	later := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	room = "P101"
+	if err := svc.Update(receptionist, "LK001", services.AppointmentChanges{NgayGioKham: &later, MaPhong: &room}); err != nil {
+		t.Fatalf("Update() to a free time error = %v", err)
+	}
+	updated := store.Appointments["LK001"]
+	if !updated.NgayGioKham.Equal(later) || updated.MaPhong == nil || *updated.MaPhong != "P101" {
+		t.Errorf("updated appointment = %+v", updated.Appointment)
+	}
+}
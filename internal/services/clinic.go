@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"clinic-management/internal/models"
+)
+
+// Room statuses.
+const RoomActive = "ACTIVE"
+
+// OpeningHours are a clinic's hours on one day of the week. Opens and
+// Closes are HH:MM, empty when not set.
+type OpeningHours struct {
+	Closed bool
+	Opens  string
+	Closes string
+}
+
+// ClinicRepository reads the opening hours and rooms of clinics
+// (GIOMOCUA, PHONG).
+type ClinicRepository interface {
+	// OpeningHours returns the hours of a clinic on a weekday, 1 for Monday
+	// to 7 for Sunday, or ErrNotFound if none are set.
+	OpeningHours(clinicID string, weekday int) (*OpeningHours, error)
+	// Room returns ErrNotFound if there is no such room.
+	Room(id string) (*models.Room, error)
+}
+
+// checkOpeningHours checks that a work schedule from start to end on day
+// falls within the clinic's opening hours. Days without opening hours are
+// not restricted.
+func checkOpeningHours(clinics ClinicRepository, clinicID string, day time.Time, start, end string) error {
+	weekday := int(day.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+
+	hours, err := clinics.OpeningHours(clinicID, weekday)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading opening hours: %v", err)
+	}
+
+	if hours.Closed {
+		return invalid("The clinic is closed on this day")
+	}
+	if hours.Opens != "" && clockMinutes(start) < clockMinutes(hours.Opens) ||
+		hours.Closes != "" && clockMinutes(end) > clockMinutes(hours.Closes) {
+		return invalid(fmt.Sprintf("Schedule is outside the clinic's opening hours (%s-%s)", hours.Opens, hours.Closes))
+	}
+	return nil
+}
+
+// checkRoom checks that roomID is an active room of the clinic.
+func checkRoom(clinics ClinicRepository, roomID, clinicID string) error {
+	room, err := clinics.Room(roomID)
+	if errors.Is(err, ErrNotFound) || err == nil && room.MaPhongKham != clinicID {
+		return invalid("Room not found in this clinic")
+	}
+	if err != nil {
+		return fmt.Errorf("error reading room: %v", err)
+	}
+	if room.TrangThai != RoomActive {
+		return conflict(fmt.Sprintf("Room is not in service (%s)", room.TrangThai))
+	}
+	return nil
+}
+
+// clockMinutes converts an H:MM or HH:MM time to minutes after midnight.
+func clockMinutes(clock string) int {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0
+	}
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"database/sql"
+
+	"clinic-management/internal/models"
+)
+
+type sqlClinicRepository struct {
+	db *sql.DB
+}
+
+// NewSQLClinicRepository reads opening hours and rooms from SQL Server.
+func NewSQLClinicRepository(db *sql.DB) ClinicRepository {
+	return &sqlClinicRepository{db: db}
+}
+
+func (r *sqlClinicRepository) OpeningHours(clinicID string, weekday int) (*OpeningHours, error) {
+	var hours OpeningHours
+	var opens, closes sql.NullString
+	err := r.db.QueryRow(`
+		SELECT nghi, CONVERT(VARCHAR(5), gioMo, 108), CONVERT(VARCHAR(5), gioDong, 108)
+		FROM GIOMOCUA WHERE maPhongKham = @p1 AND thu = @p2
+	`, clinicID, weekday).Scan(&hours.Closed, &opens, &closes)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	hours.Opens = opens.String
+	hours.Closes = closes.String
+	return &hours, nil
+}
+
+func (r *sqlClinicRepository) Room(id string) (*models.Room, error) {
+	var room models.Room
+	var sucChua sql.NullInt32
+	var ghiChu sql.NullString
+	err := r.db.QueryRow(`
+		SELECT maPhong, maPhongKham, tenPhong, loaiPhong, sucChua, trangThai, ghiChu
+		FROM PHONG WHERE maPhong = @p1
+	`, id).Scan(&room.MaPhong, &room.MaPhongKham, &room.TenPhong, &room.LoaiPhong, &sucChua, &room.TrangThai, &ghiChu)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sucChua.Valid {
+		capacity := int(sucChua.Int32)
+		room.SucChua = &capacity
+	}
+	room.GhiChu = stringPtr(ghiChu)
+	return &room, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"clinic-management/internal/utils"
+)
+
+// Kinds of sort values, which decide how a cursor value is compared.
+const (
+	SortText   = "text"
+	SortTime   = "time"
+	SortNumber = "number"
+)
+
+// SortKey is a sort a list accepts. Expr is its SQL expression and must
+// never be NULL, so nullable columns are wrapped in ISNULL with an empty
+// string, 1900-01-01 or 0 by Kind. Value reads the sort value from a list
+// item and defaults to the item field whose JSON name is the sort key.
+type SortKey struct {
+	Expr  string
+	Kind  string
+	Value func(item interface{}) interface{}
+}
+
+// ListQuery pages SQL rows by one of Sorts and then by IDColumn, so every
+// row has a stable position for cursors.
+type ListQuery struct {
+	Sorts    map[string]SortKey
+	IDColumn string
+}
+
+// Build builds the query for one page of the rows selected by columns from
+// from ("FROM ... WHERE ..." with args), and the query counting all of
+// them. One row more than the page size is fetched to tell whether another
+// page follows.
+func (q ListQuery) Build(columns, from string, args []interface{}, params utils.ListParams) (string, []interface{}, string) {
+	key := q.Sorts[params.SortField]
+	direction, compare := "ASC", ">"
+	if params.SortDesc {
+		direction, compare = "DESC", "<"
+	}
+
+	count := "SELECT COUNT(*) " + from
+	query := "SELECT " + columns + " " + from
+	pageArgs := append([]interface{}{}, args...)
+
+	if params.Cursor != nil {
+		value := fmt.Sprintf("@p%d", len(pageArgs)+1)
+		switch key.Kind {
+		case SortTime:
+			value = "CAST(" + value + " AS DATETIME2)"
+		case SortNumber:
+			value = "CAST(" + value + " AS DECIMAL(19, 4))"
+		}
+		id := fmt.Sprintf("@p%d", len(pageArgs)+2)
+		query += fmt.Sprintf(" AND (%s %s %s OR (%s = %s AND %s %s %s))",
+			key.Expr, compare, value, key.Expr, value, q.IDColumn, compare, id)
+		pageArgs = append(pageArgs, params.Cursor.Value, params.Cursor.ID)
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s %s, %s %s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY",
+		key.Expr, direction, q.IDColumn, direction, params.Offset(), params.PageSize+1)
+	return query, pageArgs, count
+}
+
+// SortValue returns the value item is sorted by under the sort key field.
+func SortValue(sorts map[string]SortKey, field string, item interface{}) interface{} {
+	if key := sorts[field]; key.Value != nil {
+		return key.Value(item)
+	}
+	return JSONField(item, field)
+}
+
+// JSONField returns the field of a struct, or of a struct it embeds, that
+// is named name in JSON. Pointers are followed; nil gives nil.
+func JSONField(item interface{}, name string) interface{} {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			if value := JSONField(v.Field(i).Interface(), name); value != nil {
+				return value
+			}
+			continue
+		}
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			value := v.Field(i)
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					return nil
+				}
+				value = value.Elem()
+			}
+			return value.Interface()
+		}
+	}
+	return nil
+}
+
+// CursorValue formats a sort value the way SQL Server reads it back, with
+// NULL replaced like the ISNULL of the sort expression.
+func CursorValue(value interface{}, kind string) string {
+	switch v := value.(type) {
+	case nil:
+	case time.Time:
+		// Scanning NULL into sql.NullTime leaves the zero time.
+		if !v.IsZero() {
+			return v.Format("2006-01-02T15:04:05.9999999")
+		}
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		if kind == SortTime {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t.Format("2006-01-02T15:04:05.9999999")
+			}
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+
+	switch kind {
+	case SortTime:
+		return "1900-01-01T00:00:00"
+	case SortNumber:
+		return "0"
+	}
+	return ""
+}
+
+// DateRange restricts a list to the days From to To, both inclusive. Zero
+// bounds are open.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether t falls within the range.
+func (r DateRange) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	return r.To.IsZero() || t.Before(r.To.AddDate(0, 0, 1))
+}
+
+// Where restricts column to the range, numbering its arguments after args.
+func (r DateRange) Where(column string, args []interface{}) (string, []interface{}) {
+	var clause string
+	if !r.From.IsZero() {
+		clause += fmt.Sprintf(" AND %s >= @p%d", column, len(args)+1)
+		args = append(args, r.From)
+	}
+	if !r.To.IsZero() {
+		clause += fmt.Sprintf(" AND %s < @p%d", column, len(args)+1)
+		args = append(args, r.To.AddDate(0, 0, 1))
+	}
+	return clause, args
+}
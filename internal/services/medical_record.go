@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+// MedicalRecordSorts are the sorts medical record lists accept.
+var MedicalRecordSorts = map[string]SortKey{
+	"ngay_kham":     {Expr: "h.NgayKham", Kind: SortTime},
+	"ngay_tai_kham": {Expr: "ISNULL(h.NgayTaiKham, '1900-01-01')", Kind: SortTime},
+}
+
+// MedicalRecordFilter selects the records of a list. Empty fields match
+// any; ICD10 is a prefix of the primary code, Dates applies to the visit
+// date and Patients limits the records to the patients of a scope.
+type MedicalRecordFilter struct {
+	CustomerID string
+	DoctorID   string
+	ClinicID   string
+	ICD10      string
+	Dates      DateRange
+	Patients   PatientScope
+}
+
+// MedicalRecordRepository stores medical records (HOSO) and their
+// versions (HOSO_PHIENBAN).
+type MedicalRecordRepository interface {
+	// Get returns ErrNotFound if there is no such record.
+	Get(id string) (*models.MedicalRecordDetail, error)
+	// Version returns the current version of a record and whether it was
+	// signed.
+	Version(id string) (int, bool, error)
+	// List returns one page of the records matching f, with one more when
+	// another page follows, and the number of all of them unless params
+	// has a cursor.
+	List(f MedicalRecordFilter, params utils.ListParams) ([]models.MedicalRecordDetail, int, error)
+	// Create stores a new record, visited now, as its version 1.
+	Create(r models.MedicalRecord) error
+	// Amend stores the clinical content of r as the next version of the
+	// record if it is still at version, and reports whether it was.
+	Amend(r models.MedicalRecord, version int, by, reason string) (bool, error)
+}
+
+// MedicalRecordService applies the rules on who sees and writes a medical
+// record: the records of a patient are seen by those in whose
+// PatientScope the patient is, and only the record's doctor amends it
+// until it is locked.
+type MedicalRecordService struct {
+	records    MedicalRecordRepository
+	patients   *UserService
+	lockWindow time.Duration
+}
+
+// NewMedicalRecordService creates the service. Records become read-only
+// lockWindow after the visit; a zero window disables time-based locking.
+func NewMedicalRecordService(records MedicalRecordRepository, users UserRepository, lockWindow time.Duration) *MedicalRecordService {
+	return &MedicalRecordService{records: records, patients: NewUserService(users), lockWindow: lockWindow}
+}
+
+// Get returns a record the viewer may see. Records of other patients are
+// reported as not found.
+func (s *MedicalRecordService) Get(v Viewer, id string) (*models.MedicalRecordDetail, error) {
+	record, err := s.records.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("Medical record not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.patients.CanViewPatient(v, record.MaCustomer); err != nil {
+		if errors.Is(err, ErrForbidden) {
+			return nil, notFound("Medical record not found")
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// List returns one page of the records the viewer may see.
+func (s *MedicalRecordService) List(v Viewer, f MedicalRecordFilter, params utils.ListParams) ([]models.MedicalRecordDetail, int, error) {
+	scope, err := s.patients.PatientScope(v)
+	if err != nil {
+		return nil, 0, err
+	}
+	f.Patients = scope
+	return s.records.List(f, params)
+}
+
+// Create adds a record written by the viewer, a doctor, and returns its
+// ID.
+func (s *MedicalRecordService) Create(v Viewer, record models.MedicalRecord) (string, error) {
+	if v.Role != RoleDoctor {
+		return "", forbidden("Only doctors can create medical records")
+	}
+	id, err := utils.GenerateMedicalRecordID()
+	if err != nil {
+		return "", err
+	}
+	record.MaHoSo = id
+	record.MaBacSi = v.UserID
+	if err := s.records.Create(record); err != nil {
+		return "", err
+	}
+	return record.MaHoSo, nil
+}
+
+// Editable returns a record the viewer, its doctor, may still amend, with
+// its current version.
+func (s *MedicalRecordService) Editable(v Viewer, id string) (*models.MedicalRecordDetail, int, error) {
+	if v.Role != RoleDoctor {
+		return nil, 0, forbidden("Only doctors can update medical records")
+	}
+	record, err := s.records.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, 0, notFound("Medical record not found")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if record.MaBacSi != v.UserID {
+		return nil, 0, forbidden("You can only update your own medical records")
+	}
+
+	version, signed, err := s.records.Version(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if signed || (s.lockWindow > 0 && time.Now().After(record.NgayKham.Add(s.lockWindow))) {
+		return nil, 0, conflict("Medical record is locked; append an addendum instead")
+	}
+	return record, version, nil
+}
+
+// Amend saves the symptoms, diagnoses, treatment and follow-up date of
+// record as the version after version and returns the new version. It
+// fails with ErrConflict if the record changed since version was read.
+func (s *MedicalRecordService) Amend(v Viewer, record models.MedicalRecord, version int, reason string) (int, error) {
+	if strings.TrimSpace(reason) == "" {
+		return 0, invalid("An amendment reason (ly_do) is required")
+	}
+	current, currentVersion, err := s.Editable(v, record.MaHoSo)
+	if err != nil {
+		return 0, err
+	}
+	if currentVersion != version {
+		return 0, conflict("Medical record was modified by someone else, reload and try again")
+	}
+
+	next := current.MedicalRecord
+	next.TrieuChung = record.TrieuChung
+	next.ChanDoan = record.ChanDoan
+	next.HuongDanDieuTri = record.HuongDanDieuTri
+	next.MaICD10 = record.MaICD10
+	next.MaICD10Phu = record.MaICD10Phu
+	next.NgayTaiKham = record.NgayTaiKham
+
+	amended, err := s.records.Amend(next, version, v.UserID, reason)
+	if err != nil {
+		return 0, err
+	}
+	if !amended {
+		return 0, conflict("Medical record was modified by someone else, reload and try again")
+	}
+	return version + 1, nil
+}
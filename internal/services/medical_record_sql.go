@@ -0,0 +1,302 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+var medicalRecordList = ListQuery{Sorts: MedicalRecordSorts, IDColumn: "h.MaHoSo"}
+
+type sqlMedicalRecordRepository struct {
+	db *sql.DB
+}
+
+// NewSQLMedicalRecordRepository stores medical records in SQL Server.
+func NewSQLMedicalRecordRepository(db *sql.DB) MedicalRecordRepository {
+	return &sqlMedicalRecordRepository{db: db}
+}
+
+func (r *sqlMedicalRecordRepository) Get(id string) (*models.MedicalRecordDetail, error) {
+	var m models.MedicalRecordDetail
+	var trieuChung, chanDoan, huongDanDieuTri, maICD10 sql.NullString
+	var tenKhachHang, tenBacSi, tenPhongKham sql.NullString
+	var ngayTaiKham sql.NullTime
+
+	err := r.db.QueryRow(`
+		SELECT h.maHoSo, h.maCustomer, h.maBacSi, h.maPhongKham,
+		       h.ngayKham, h.trieuChung, h.chanDoan, h.huongDanDieuTri,
+		       h.maICD10, h.ngayTaiKham,
+		       uc.hoTen, ud.hoTen, p.tenPhongKham
+		FROM HOSO h
+		LEFT JOIN [USER] uc ON h.maCustomer = uc.userID
+		LEFT JOIN [USER] ud ON h.maBacSi = ud.userID
+		LEFT JOIN PHONGKHAM p ON h.maPhongKham = p.maPhongKham
+		WHERE h.maHoSo = @p1
+	`, id).Scan(
+		&m.MaHoSo, &m.MaCustomer, &m.MaBacSi, &m.MaPhongKham,
+		&m.NgayKham, &trieuChung, &chanDoan, &huongDanDieuTri,
+		&maICD10, &ngayTaiKham,
+		&tenKhachHang, &tenBacSi, &tenPhongKham,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.TrieuChung = stringPtr(trieuChung)
+	m.ChanDoan = stringPtr(chanDoan)
+	m.HuongDanDieuTri = stringPtr(huongDanDieuTri)
+	m.MaICD10 = stringPtr(maICD10)
+	m.NgayTaiKham = timePtr(ngayTaiKham)
+	m.TenKhachHang = tenKhachHang.String
+	m.TenBacSi = tenBacSi.String
+	m.TenPhongKham = tenPhongKham.String
+
+	m.MaICD10Phu, err = secondaryCodes(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *sqlMedicalRecordRepository) Version(id string) (int, bool, error) {
+	var version int
+	var signed bool
+	err := r.db.QueryRow("SELECT phienBan, daKy FROM HOSO WHERE MaHoSo = @p1", id).Scan(&version, &signed)
+	if err == sql.ErrNoRows {
+		return 0, false, ErrNotFound
+	}
+	return version, signed, err
+}
+
+func (r *sqlMedicalRecordRepository) List(f MedicalRecordFilter, params utils.ListParams) ([]models.MedicalRecordDetail, int, error) {
+	columns := `
+		h.MaHoSo, h.MaCustomer, h.MaBacSi, h.MaPhongKham,
+		h.NgayKham, h.TrieuChung, h.ChanDoan, h.HuongDanDieuTri,
+		h.MaICD10, h.NgayTaiKham,
+		uc.HoTen as TenKhachHang, ud.HoTen as TenBacSi, p.TenPhongKham
+	`
+	from := `
+		FROM HOSO h
+		JOIN [USER] uc ON h.MaCustomer = uc.userID
+		JOIN [USER] ud ON h.MaBacSi = ud.userID
+		JOIN PHONGKHAM p ON h.MaPhongKham = p.MaPhongKham
+		WHERE 1=1
+	`
+	where, args := whereEqual([]columnValue{
+		{"h.MaCustomer", f.CustomerID},
+		{"h.MaBacSi", f.DoctorID},
+		{"h.MaPhongKham", f.ClinicID},
+	}, nil)
+	from += where
+	if f.ICD10 != "" {
+		from += fmt.Sprintf(" AND h.MaICD10 LIKE @p%d", len(args)+1)
+		args = append(args, f.ICD10+"%")
+	}
+	dates, args := f.Dates.Where("h.NgayKham", args)
+	from += dates
+	patients, args := f.Patients.Where("h.MaCustomer", args)
+	from += patients
+
+	var records []models.MedicalRecordDetail
+	total, err := listRows(r.db, medicalRecordList, columns, from, args, params, func(rows *sql.Rows) error {
+		var m models.MedicalRecordDetail
+		var trieuChung, chanDoan, huongDanDieuTri, maICD10 sql.NullString
+		var tenKhachHang, tenBacSi, tenPhongKham sql.NullString
+		var ngayTaiKham sql.NullTime
+
+		err := rows.Scan(&m.MaHoSo, &m.MaCustomer, &m.MaBacSi, &m.MaPhongKham,
+			&m.NgayKham, &trieuChung, &chanDoan, &huongDanDieuTri, &maICD10, &ngayTaiKham,
+			&tenKhachHang, &tenBacSi, &tenPhongKham)
+		if err != nil {
+			return err
+		}
+
+		m.TrieuChung = stringPtr(trieuChung)
+		m.ChanDoan = stringPtr(chanDoan)
+		m.HuongDanDieuTri = stringPtr(huongDanDieuTri)
+		m.MaICD10 = stringPtr(maICD10)
+		m.NgayTaiKham = timePtr(ngayTaiKham)
+		m.TenKhachHang = tenKhachHang.String
+		m.TenBacSi = tenBacSi.String
+		m.TenPhongKham = tenPhongKham.String
+		records = append(records, m)
+		return nil
+	})
+	return records, total, err
+}
+
+func (r *sqlMedicalRecordRepository) Create(m models.MedicalRecord) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO HOSO (MaHoSo, MaCustomer, MaBacSi, MaPhongKham, NgayKham,
+		                  TrieuChung, ChanDoan, HuongDanDieuTri, MaICD10, NgayTaiKham)
+		VALUES (@p1, @p2, @p3, @p4, GETDATE(), @p5, @p6, @p7, @p8, @p9)
+	`, m.MaHoSo, m.MaCustomer, m.MaBacSi, m.MaPhongKham,
+		m.TrieuChung, m.ChanDoan, m.HuongDanDieuTri, m.MaICD10, m.NgayTaiKham)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceSecondaryCodes(tx, m.MaHoSo, m.MaICD10, m.MaICD10Phu); err != nil {
+		return err
+	}
+	if err := insertRecordVersion(tx, m, 1, m.MaBacSi, "Initial record"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlMedicalRecordRepository) Amend(m models.MedicalRecord, version int, by, reason string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := ensureInitialVersion(tx, m.MaHoSo); err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE HOSO
+		SET TrieuChung = @p1, ChanDoan = @p2, HuongDanDieuTri = @p3, MaICD10 = @p4,
+		    NgayTaiKham = @p5, phienBan = phienBan + 1
+		WHERE MaHoSo = @p6 AND phienBan = @p7
+	`, m.TrieuChung, m.ChanDoan, m.HuongDanDieuTri, m.MaICD10,
+		m.NgayTaiKham, m.MaHoSo, version)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := replaceSecondaryCodes(tx, m.MaHoSo, m.MaICD10, m.MaICD10Phu); err != nil {
+		return false, err
+	}
+	if err := insertRecordVersion(tx, m, version+1, by, reason); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// rowsQueryer is satisfied by both *sql.DB and *sql.Tx.
+type rowsQueryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// secondaryCodes returns the secondary ICD-10 codes of a record in order.
+func secondaryCodes(q rowsQueryer, recordID string) ([]string, error) {
+	rows, err := q.Query("SELECT maICD10 FROM HOSO_ICD10PHU WHERE maHoSo = @p1 ORDER BY thuTu", recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// replaceSecondaryCodes stores the secondary codes of a record in order,
+// skipping duplicates and the primary code.
+func replaceSecondaryCodes(tx *sql.Tx, recordID string, primary *string, codes []string) error {
+	if _, err := tx.Exec("DELETE FROM HOSO_ICD10PHU WHERE maHoSo = @p1", recordID); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	if primary != nil {
+		seen[*primary] = true
+	}
+
+	order := 0
+	for _, code := range codes {
+		code = utils.NormalizeICD10Code(code)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		order++
+
+		_, err := tx.Exec(`
+			INSERT INTO HOSO_ICD10PHU (maHoSo, maICD10, thuTu)
+			VALUES (@p1, @p2, @p3)
+		`, recordID, code, order)
+		if err != nil {
+			return fmt.Errorf("failed to add secondary diagnosis %s: %v", code, err)
+		}
+	}
+	return nil
+}
+
+func insertRecordVersion(tx *sql.Tx, m models.MedicalRecord, version int, authorID, reason string) error {
+	var secondary interface{}
+	if len(m.MaICD10Phu) > 0 {
+		secondary = strings.Join(m.MaICD10Phu, ",")
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO HOSO_PHIENBAN (maHoSo, phienBan, trieuChung, chanDoan, huongDanDieuTri,
+		                           maICD10, maICD10Phu, ngayTaiKham, nguoiSua, thoiGian, lyDo)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9, GETDATE(), @p10)
+	`, m.MaHoSo, version, m.TrieuChung, m.ChanDoan, m.HuongDanDieuTri,
+		m.MaICD10, secondary, m.NgayTaiKham, authorID, reason)
+	return err
+}
+
+// ensureInitialVersion captures the current content of records created
+// before versioning existed, so the first amendment does not lose it.
+func ensureInitialVersion(tx *sql.Tx, recordID string) error {
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM HOSO_PHIENBAN WHERE maHoSo = @p1", recordID).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var m models.MedicalRecord
+	var trieuChung, chanDoan, huongDanDieuTri, maICD10 sql.NullString
+	var ngayTaiKham sql.NullTime
+	var version int
+	err := tx.QueryRow(`
+		SELECT MaHoSo, MaBacSi, TrieuChung, ChanDoan, HuongDanDieuTri, MaICD10, NgayTaiKham, phienBan
+		FROM HOSO WHERE MaHoSo = @p1
+	`, recordID).Scan(&m.MaHoSo, &m.MaBacSi, &trieuChung, &chanDoan, &huongDanDieuTri,
+		&maICD10, &ngayTaiKham, &version)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	m.TrieuChung = stringPtr(trieuChung)
+	m.ChanDoan = stringPtr(chanDoan)
+	m.HuongDanDieuTri = stringPtr(huongDanDieuTri)
+	m.MaICD10 = stringPtr(maICD10)
+	m.NgayTaiKham = timePtr(ngayTaiKham)
+
+	if m.MaICD10Phu, err = secondaryCodes(tx, recordID); err != nil {
+		return err
+	}
+	return insertRecordVersion(tx, m, version, m.MaBacSi, "Original version")
+}
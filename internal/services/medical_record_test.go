@@ -0,0 +1,190 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+	"clinic-management/internal/utils"
+)
+
+func TestMedicalRecordGetVisibility(t *testing.T) {
+	store := memory.NewStore()
+	store.MedicalRecords["HS001"] = models.MedicalRecordDetail{MedicalRecord: models.MedicalRecord{
+		MaHoSo: "HS001", MaCustomer: "CUS001", MaBacSi: "BS001", MaPhongKham: "PK001",
+	}}
+	store.Appointments["LK001"] = models.AppointmentDetail{Appointment: models.Appointment{
+		MaLichKham: "LK001", MaCustomer: "CUS001", MaBacSi: "BS003", MaPhongKham: "PK002",
+		TrangThai: services.AppointmentScheduled,
+	}}
+	store.ManagedClinics["QL001"] = "PK001"
+	store.ManagedClinics["QL002"] = "PK003"
+	svc := services.NewMedicalRecordService(store.MedicalRecordRepository(), store.UserRepository(), 0)
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		want   error
+	}{
+		{"the patient", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, nil},
+		{"another customer", services.Viewer{UserID: "CUS002", Role: services.RoleCustomer}, services.ErrNotFound},
+		{"the doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, nil},
+		{"another doctor of the patient", services.Viewer{UserID: "BS003", Role: services.RoleDoctor}, nil},
+		{"a doctor who never saw the patient", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, services.ErrNotFound},
+		{"manager of the clinic", services.Viewer{UserID: "QL001", Role: services.RoleClinicManager}, nil},
+		{"manager of another clinic", services.Viewer{UserID: "QL002", Role: services.RoleClinicManager}, services.ErrNotFound},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, services.ErrNotFound},
+		{"accountant", services.Viewer{UserID: "KT001", Role: services.RoleAccountant}, services.ErrNotFound},
+		{"operation manager", services.Viewer{UserID: "QV001", Role: services.RoleOperationManager}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Get(tt.viewer, "HS001")
+			if (tt.want == nil && err != nil) || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Get() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMedicalRecordListVisibility(t *testing.T) {
+	store := memory.NewStore()
+	code := "J06.9"
+	store.MedicalRecords["HS001"] = models.MedicalRecordDetail{MedicalRecord: models.MedicalRecord{
+		MaHoSo: "HS001", MaCustomer: "CUS001", MaBacSi: "BS001", MaICD10: &code,
+		NgayKham: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+	}}
+	store.MedicalRecords["HS002"] = models.MedicalRecordDetail{MedicalRecord: models.MedicalRecord{
+		MaHoSo: "HS002", MaCustomer: "CUS002", MaBacSi: "BS002",
+		NgayKham: time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC),
+	}}
+	svc := services.NewMedicalRecordService(store.MedicalRecordRepository(), store.UserRepository(), 0)
+	params := utils.ListParams{Page: 1, PageSize: 20, SortField: "ngay_kham", SortDesc: true}
+	staff := services.Viewer{UserID: "QV001", Role: services.RoleOperationManager}
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		filter services.MedicalRecordFilter
+		want   []string
+	}{
+		{"patient", services.Viewer{UserID: "CUS002", Role: services.RoleCustomer}, services.MedicalRecordFilter{}, []string{"HS002"}},
+		{"doctor asking for another doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, services.MedicalRecordFilter{DoctorID: "BS002"}, nil},
+		{"doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, services.MedicalRecordFilter{}, []string{"HS002"}},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, services.MedicalRecordFilter{}, nil},
+		{"accountant", services.Viewer{UserID: "KT001", Role: services.RoleAccountant}, services.MedicalRecordFilter{}, nil},
+		{"staff", staff, services.MedicalRecordFilter{}, []string{"HS002", "HS001"}},
+		{"staff by ICD-10 chapter", staff, services.MedicalRecordFilter{ICD10: "J"}, []string{"HS001"}},
+		{"staff by date", staff, services.MedicalRecordFilter{Dates: services.DateRange{
+			From: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+		}}, []string{"HS001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, _, err := svc.List(tt.viewer, tt.filter, params)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []string
+			for _, r := range records {
+				got = append(got, r.MaHoSo)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMedicalRecordCreate(t *testing.T) {
+	store := memory.NewStore()
+	svc := services.NewMedicalRecordService(store.MedicalRecordRepository(), store.UserRepository(), 0)
+	record := models.MedicalRecord{MaCustomer: "CUS001", MaPhongKham: "PK001"}
+
+	if _, err := svc.Create(services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, record); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Create() by receptionist error = %v, want ErrForbidden", err)
+	}
+
+	id, err := svc.Create(services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, record)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created := store.MedicalRecords[id]; created.MaBacSi != "BS001" {
+		t.Errorf("created record doctor = %s, want BS001", created.MaBacSi)
+	}
+	if versions := store.RecordVersions[id]; len(versions) != 1 || versions[0].Version != 1 {
+		t.Errorf("versions = %+v, want the initial version", versions)
+	}
+}
+
+func TestMedicalRecordAmend(t *testing.T) {
+	doctor := services.Viewer{UserID: "BS001", Role: services.RoleDoctor}
+	diagnosis := "Viêm họng cấp"
+
+	newStore := func(visited time.Time) *memory.Store {
+		store := memory.NewStore()
+		store.MedicalRecords["HS001"] = models.MedicalRecordDetail{MedicalRecord: models.MedicalRecord{
+			MaHoSo: "HS001", MaCustomer: "CUS001", MaBacSi: "BS001", NgayKham: visited,
+		}}
+		return store
+	}
+
+	t.Run("amends and keeps the original", func(t *testing.T) {
+		store := newStore(time.Now())
+		svc := services.NewMedicalRecordService(store.MedicalRecordRepository(), store.UserRepository(), 24*time.Hour)
+
+		_, version, err := svc.Editable(doctor, "HS001")
+		if err != nil {
+			t.Fatalf("Editable() error = %v", err)
+		}
+		next, err := svc.Amend(doctor, models.MedicalRecord{MaHoSo: "HS001", ChanDoan: &diagnosis}, version, "Bổ sung chẩn đoán")
+		if err != nil {
+			t.Fatalf("Amend() error = %v", err)
+		}
+		if next != 2 {
+			t.Errorf("Amend() version = %d, want 2", next)
+		}
+		versions := store.RecordVersions["HS001"]
+		if len(versions) != 2 || versions[0].Reason != "Original version" || versions[1].By != "BS001" {
+			t.Errorf("versions = %+v", versions)
+		}
+		if got := store.MedicalRecords["HS001"]; got.ChanDoan == nil || *got.ChanDoan != diagnosis || got.MaCustomer != "CUS001" {
+			t.Errorf("amended record = %+v", got.MedicalRecord)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		viewer  services.Viewer
+		visited time.Time
+		signed  bool
+		version int
+		reason  string
+		want    error
+	}{
+		{"no reason", doctor, time.Now(), false, 1, " ", services.ErrInvalid},
+		{"another doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, time.Now(), false, 1, "Sửa", services.ErrForbidden},
+		{"stale version", doctor, time.Now(), false, 0, "Sửa", services.ErrConflict},
+		{"signed", doctor, time.Now(), true, 1, "Sửa", services.ErrConflict},
+		{"past the edit window", doctor, time.Now().Add(-48 * time.Hour), false, 1, "Sửa", services.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(tt.visited)
+			store.SignedRecords["HS001"] = tt.signed
+			svc := services.NewMedicalRecordService(store.MedicalRecordRepository(), store.UserRepository(), 24*time.Hour)
+
+			_, err := svc.Amend(tt.viewer, models.MedicalRecord{MaHoSo: "HS001", ChanDoan: &diagnosis}, tt.version, tt.reason)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Amend() error = %v, want %v", err, tt.want)
+			}
+			if got := store.MedicalRecords["HS001"]; got.ChanDoan != nil {
+				t.Errorf("refused amendment changed the record: %+v", got.MedicalRecord)
+			}
+		})
+	}
+}
@@ -0,0 +1,696 @@
+// Package memory implements the service repositories in memory, so handler
+// and business-rule tests run without SQL Server.
+package memory
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/utils"
+)
+
+// Cancellation is who cancelled a prescription, when and why.
+type Cancellation struct {
+	By     string
+	Reason string
+	At     time.Time
+}
+
+// ClinicDay is a weekday of a clinic, 1 for Monday to 7 for Sunday.
+type ClinicDay struct {
+	ClinicID string
+	Weekday  int
+}
+
+// RecordVersion is a saved version of a medical record.
+type RecordVersion struct {
+	Version int
+	Record  models.MedicalRecord
+	By      string
+	Reason  string
+}
+
+// PrescriptionWrite is who last wrote a prescription's lines and why any
+// warnings were overridden.
+type PrescriptionWrite struct {
+	By        string
+	LyDoBoQua string
+	Warnings  []services.OverriddenWarning
+}
+
+// Store holds the data of every repository. Fill its maps before handing
+// the repositories to services; the repositories then read and change them
+// under a lock.
+type Store struct {
+	mu sync.Mutex
+
+	Appointments   map[string]models.AppointmentDetail
+	Schedules      map[string]models.WorkScheduleDetail
+	MedicalRecords map[string]models.MedicalRecordDetail
+	Prescriptions  map[string]models.Prescription
+	Cancellations  map[string]Cancellation
+	// ManagedClinics maps clinic managers to the clinic they run.
+	ManagedClinics map[string]string
+	Rooms          map[string]models.Room
+	OpeningHours   map[ClinicDay]services.OpeningHours
+	// RecordVersions are the saved versions of each record, oldest first.
+	// Records without versions are at version 1.
+	RecordVersions map[string][]RecordVersion
+	SignedRecords  map[string]bool
+	// Medicines is the catalogue prescription lines are named from.
+	Medicines          map[string]models.Medicine
+	PrescriptionLines  map[string][]services.PrescriptionLine
+	PrescriptionWrites map[string]PrescriptionWrite
+}
+
+func NewStore() *Store {
+	return &Store{
+		Appointments:       map[string]models.AppointmentDetail{},
+		Schedules:          map[string]models.WorkScheduleDetail{},
+		MedicalRecords:     map[string]models.MedicalRecordDetail{},
+		Prescriptions:      map[string]models.Prescription{},
+		Cancellations:      map[string]Cancellation{},
+		ManagedClinics:     map[string]string{},
+		Rooms:              map[string]models.Room{},
+		OpeningHours:       map[ClinicDay]services.OpeningHours{},
+		RecordVersions:     map[string][]RecordVersion{},
+		SignedRecords:      map[string]bool{},
+		Medicines:          map[string]models.Medicine{},
+		PrescriptionLines:  map[string][]services.PrescriptionLine{},
+		PrescriptionWrites: map[string]PrescriptionWrite{},
+	}
+}
+
+func (s *Store) AppointmentRepository() services.AppointmentRepository {
+	return appointments{s}
+}
+
+func (s *Store) ScheduleRepository() services.ScheduleRepository {
+	return schedules{s}
+}
+
+func (s *Store) MedicalRecordRepository() services.MedicalRecordRepository {
+	return medicalRecords{s}
+}
+
+func (s *Store) PrescriptionRepository() services.PrescriptionRepository {
+	return prescriptions{s}
+}
+
+func (s *Store) UserRepository() services.UserRepository {
+	return users{s}
+}
+
+func (s *Store) ClinicRepository() services.ClinicRepository {
+	return clinics{s}
+}
+
+// page orders items like the SQL lists do, by the sort of params and then
+// by ID, and returns the page params asks for with one more item when
+// another page follows, and the number of all items unless params has a
+// cursor.
+func page[T any](items []T, sorts map[string]services.SortKey, params utils.ListParams, id func(T) string) ([]T, int) {
+	kind := sorts[params.SortField].Kind
+	value := func(item T) string {
+		return services.CursorValue(services.SortValue(sorts, params.SortField, item), kind)
+	}
+	compare := func(aValue, aID, bValue, bID string) int {
+		if c := compareValues(aValue, bValue, kind); c != 0 {
+			return c
+		}
+		return strings.Compare(aID, bID)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		c := compare(value(items[i]), id(items[i]), value(items[j]), id(items[j]))
+		if params.SortDesc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	total := len(items)
+	if params.Cursor != nil {
+		total = 0
+		var after []T
+		for _, item := range items {
+			c := compare(value(item), id(item), params.Cursor.Value, params.Cursor.ID)
+			if (params.SortDesc && c < 0) || (!params.SortDesc && c > 0) {
+				after = append(after, item)
+			}
+		}
+		items = after
+	}
+
+	start := params.Offset()
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + params.PageSize + 1
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end], total
+}
+
+// compareValues compares two cursor values of a sort kind.
+func compareValues(a, b, kind string) int {
+	if kind == services.SortNumber {
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// sameDay reports whether two times fall on the same date.
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+// minutes converts an HH:MM or HH:MM:SS time to minutes after midnight.
+func minutes(clock string) int {
+	parts := strings.SplitN(clock, ":", 3)
+	if len(parts) < 2 {
+		return 0
+	}
+	h, _ := strconv.Atoi(parts[0])
+	m, _ := strconv.Atoi(parts[1])
+	return h*60 + m
+}
+
+// overlaps reports whether the hours start to end overlap from to to.
+func overlaps(start, end, from, to string) bool {
+	return minutes(start) < minutes(to) && minutes(end) > minutes(from)
+}
+
+// activeAppointment reports whether an appointment still holds its room.
+func activeAppointment(status string) bool {
+	return status != services.AppointmentCancelled && status != services.AppointmentNoShow
+}
+
+type appointments struct{ *Store }
+
+func (r appointments) Get(id string) (*models.AppointmentDetail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.Appointments[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r appointments) List(f services.AppointmentFilter, params utils.ListParams) ([]models.AppointmentDetail, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var items []models.AppointmentDetail
+	for _, a := range r.Appointments {
+		if matches(f.Status, a.TrangThai) && matches(f.DoctorID, a.MaBacSi) &&
+			matches(f.ClinicID, a.MaPhongKham) && matches(f.CustomerID, a.MaCustomer) &&
+			matches(f.RoomID, stringValue(a.MaPhong)) && f.Dates.Contains(a.NgayGioKham) {
+			items = append(items, a)
+		}
+	}
+	items, total := page(items, services.AppointmentSorts, params, func(a models.AppointmentDetail) string { return a.MaLichKham })
+	return items, total, nil
+}
+
+func (r appointments) SlotTaken(doctorID string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.Appointments {
+		if a.MaBacSi == doctorID && a.NgayGioKham.Equal(at) &&
+			a.TrangThai != services.AppointmentCancelled && a.TrangThai != services.AppointmentCompleted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r appointments) RoomBooking(roomID string, at time.Time, excludeID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.roomBooking(roomID, at, excludeID), nil
+}
+
+func (r appointments) roomBooking(roomID string, at time.Time, excludeID string) string {
+	for id, a := range r.Appointments {
+		if id != excludeID && stringValue(a.MaPhong) == roomID && a.NgayGioKham.Equal(at) && activeAppointment(a.TrangThai) {
+			return id
+		}
+	}
+	return ""
+}
+
+func (r appointments) Create(a models.Appointment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	a.NgayDat = &now
+	r.Appointments[a.MaLichKham] = models.AppointmentDetail{Appointment: a}
+	return nil
+}
+
+func (r appointments) AssignScheduledRoom(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.Appointments[id]
+	if !ok {
+		return nil
+	}
+	clock := a.NgayGioKham.Format("15:04")
+	for _, s := range r.Schedules {
+		if s.MaPhong == nil || s.MaBacSi != a.MaBacSi || s.MaPhongKham != a.MaPhongKham ||
+			!sameDay(s.NgayLamViec, a.NgayGioKham) ||
+			minutes(s.GioBatDau) > minutes(clock) || minutes(s.GioKetThuc) <= minutes(clock) {
+			continue
+		}
+		if room, ok := r.Rooms[*s.MaPhong]; !ok || room.TrangThai != services.RoomActive {
+			continue
+		}
+		if r.roomBooking(*s.MaPhong, a.NgayGioKham, id) != "" {
+			continue
+		}
+		roomID := *s.MaPhong
+		a.MaPhong = &roomID
+		r.Appointments[id] = a
+		return nil
+	}
+	return nil
+}
+
+func (r appointments) Update(a models.Appointment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.Appointments[a.MaLichKham]
+	if !ok {
+		return services.ErrNotFound
+	}
+	current.NgayGioKham = a.NgayGioKham
+	current.TrangThai = a.TrangThai
+	current.GhiChu = a.GhiChu
+	current.MaPhong = a.MaPhong
+	r.Appointments[a.MaLichKham] = current
+	return nil
+}
+
+func (r appointments) SetStatus(id, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.Appointments[id]
+	if !ok {
+		return services.ErrNotFound
+	}
+	a.TrangThai = status
+	r.Appointments[id] = a
+	return nil
+}
+
+type schedules struct{ *Store }
+
+func (r schedules) Get(id string) (*models.WorkScheduleDetail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.Schedules[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r schedules) List(f services.ScheduleFilter, params utils.ListParams) ([]models.WorkScheduleDetail, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var items []models.WorkScheduleDetail
+	for _, s := range r.Schedules {
+		if matches(f.DoctorID, s.MaBacSi) && matches(f.ClinicID, s.MaPhongKham) &&
+			matches(f.RoomID, stringValue(s.MaPhong)) && matches(f.Status, s.TrangThai) &&
+			f.Dates.Contains(s.NgayLamViec) {
+			items = append(items, s)
+		}
+	}
+	items, total := page(items, services.ScheduleSorts, params, func(s models.WorkScheduleDetail) string { return s.MaLichLamViec })
+	return items, total, nil
+}
+
+func (r schedules) Overlaps(doctorID string, day time.Time, start, end string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.Schedules {
+		if s.MaBacSi == doctorID && sameDay(s.NgayLamViec, day) && overlaps(s.GioBatDau, s.GioKetThuc, start, end) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r schedules) RoomSchedule(roomID string, day time.Time, start, end, excludeID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, s := range r.Schedules {
+		if id != excludeID && stringValue(s.MaPhong) == roomID && sameDay(s.NgayLamViec, day) &&
+			overlaps(s.GioBatDau, s.GioKetThuc, start, end) {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+func (r schedules) RoomReservation(roomID string, at time.Time, doctorID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clock := minutes(at.Format("15:04"))
+	for id, s := range r.Schedules {
+		if stringValue(s.MaPhong) == roomID && sameDay(s.NgayLamViec, at) && s.MaBacSi != doctorID &&
+			s.TrangThai == services.ScheduleAvailable &&
+			minutes(s.GioBatDau) <= clock && minutes(s.GioKetThuc) > clock {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+func (r schedules) Create(s models.WorkSchedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Schedules[s.MaLichLamViec] = models.WorkScheduleDetail{WorkSchedule: s}
+	return nil
+}
+
+func (r schedules) Update(s models.WorkSchedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.Schedules[s.MaLichLamViec]
+	if !ok {
+		return services.ErrNotFound
+	}
+	current.WorkSchedule = s
+	r.Schedules[s.MaLichLamViec] = current
+	return nil
+}
+
+func (r schedules) HasActiveAppointments(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.Schedules[id]
+	if !ok {
+		return false, nil
+	}
+	for _, a := range r.Appointments {
+		if a.MaBacSi == s.MaBacSi &&
+			a.TrangThai != services.AppointmentCancelled && a.TrangThai != services.AppointmentCompleted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r schedules) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.Schedules[id]; !ok {
+		return services.ErrNotFound
+	}
+	delete(r.Schedules, id)
+	return nil
+}
+
+type medicalRecords struct{ *Store }
+
+func (r medicalRecords) Get(id string) (*models.MedicalRecordDetail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.MedicalRecords[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (r medicalRecords) Version(id string) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.MedicalRecords[id]; !ok {
+		return 0, false, services.ErrNotFound
+	}
+	return r.version(id), r.SignedRecords[id], nil
+}
+
+func (r medicalRecords) version(id string) int {
+	versions := r.RecordVersions[id]
+	if len(versions) == 0 {
+		return 1
+	}
+	return versions[len(versions)-1].Version
+}
+
+func (r medicalRecords) List(f services.MedicalRecordFilter, params utils.ListParams) ([]models.MedicalRecordDetail, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var items []models.MedicalRecordDetail
+	for _, m := range r.MedicalRecords {
+		if matches(f.CustomerID, m.MaCustomer) && matches(f.DoctorID, m.MaBacSi) &&
+			matches(f.ClinicID, m.MaPhongKham) && strings.HasPrefix(stringValue(m.MaICD10), f.ICD10) &&
+			f.Dates.Contains(m.NgayKham) && r.inScope(f.Patients, m.MaCustomer) {
+			items = append(items, m)
+		}
+	}
+	items, total := page(items, services.MedicalRecordSorts, params, func(m models.MedicalRecordDetail) string { return m.MaHoSo })
+	return items, total, nil
+}
+
+func (r medicalRecords) Create(m models.MedicalRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m.NgayKham = time.Now()
+	r.MedicalRecords[m.MaHoSo] = models.MedicalRecordDetail{MedicalRecord: m}
+	r.RecordVersions[m.MaHoSo] = []RecordVersion{{Version: 1, Record: m, By: m.MaBacSi, Reason: "Initial record"}}
+	return nil
+}
+
+func (r medicalRecords) Amend(m models.MedicalRecord, version int, by, reason string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.MedicalRecords[m.MaHoSo]
+	if !ok || r.version(m.MaHoSo) != version {
+		return false, nil
+	}
+	if len(r.RecordVersions[m.MaHoSo]) == 0 {
+		r.RecordVersions[m.MaHoSo] = []RecordVersion{{Version: version, Record: current.MedicalRecord, By: current.MaBacSi, Reason: "Original version"}}
+	}
+
+	current.TrieuChung = m.TrieuChung
+	current.ChanDoan = m.ChanDoan
+	current.HuongDanDieuTri = m.HuongDanDieuTri
+	current.MaICD10 = m.MaICD10
+	current.MaICD10Phu = m.MaICD10Phu
+	current.NgayTaiKham = m.NgayTaiKham
+	r.MedicalRecords[m.MaHoSo] = current
+	r.RecordVersions[m.MaHoSo] = append(r.RecordVersions[m.MaHoSo],
+		RecordVersion{Version: version + 1, Record: current.MedicalRecord, By: by, Reason: reason})
+	return true, nil
+}
+
+type prescriptions struct{ *Store }
+
+func (r prescriptions) Get(id string) (*models.Prescription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.Prescriptions[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r prescriptions) List(f services.PrescriptionFilter, params utils.ListParams) ([]models.PrescriptionSummary, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var items []models.PrescriptionSummary
+	for _, p := range r.Prescriptions {
+		if f.ExcludeDrafts && p.TrangThai == services.PrescriptionDraft {
+			continue
+		}
+		if matches(f.RecordID, p.MaHoSo) && matches(f.Status, p.TrangThai) &&
+			matches(f.CustomerID, p.MaCustomer) && matches(f.DoctorID, p.MaBacSi) &&
+			(p.NgayKeDon == nil || f.Dates.Contains(*p.NgayKeDon)) {
+			items = append(items, models.PrescriptionSummary{Prescription: p})
+		}
+	}
+	items, total := page(items, services.PrescriptionSorts, params, func(p models.PrescriptionSummary) string { return p.MaDonThuoc })
+	return items, total, nil
+}
+
+func (r prescriptions) Medicines(id string) ([]services.PrescribedMedicine, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	medicines := []services.PrescribedMedicine{}
+	for _, line := range r.PrescriptionLines[id] {
+		medicine := services.PrescribedMedicine{PrescriptionLine: line}
+		if m, ok := r.Store.Medicines[line.MaThuoc]; ok {
+			medicine.TenThuoc = m.TenThuoc
+			medicine.Gia = m.Gia
+			medicine.CongDung = stringValue(m.CongDung)
+			medicine.LieuLuong = stringValue(m.LieuLuong)
+		}
+		medicines = append(medicines, medicine)
+	}
+	return medicines, nil
+}
+
+func (r prescriptions) Create(id string, draft services.DraftPrescription, by string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.MedicalRecords[draft.MaHoSo]
+	now := time.Now()
+	r.Prescriptions[id] = models.Prescription{
+		MaDonThuoc: id,
+		MaHoSo:     draft.MaHoSo,
+		NgayKeDon:  &now,
+		GhiChu:     &draft.GhiChu,
+		TrangThai:  services.PrescriptionDraft,
+		MaCustomer: record.MaCustomer,
+		MaBacSi:    record.MaBacSi,
+	}
+	r.write(id, draft, by)
+	return nil
+}
+
+func (r prescriptions) Update(id string, draft services.DraftPrescription, by string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.Prescriptions[id]
+	if !ok || p.TrangThai != services.PrescriptionDraft {
+		return false, nil
+	}
+	p.GhiChu = &draft.GhiChu
+	r.Prescriptions[id] = p
+	r.write(id, draft, by)
+	return true, nil
+}
+
+func (r prescriptions) write(id string, draft services.DraftPrescription, by string) {
+	r.PrescriptionLines[id] = append([]services.PrescriptionLine(nil), draft.Lines...)
+	r.PrescriptionWrites[id] = PrescriptionWrite{By: by, LyDoBoQua: draft.LyDoBoQua, Warnings: draft.Warnings}
+}
+
+func (r prescriptions) Cancel(id, from, by, reason string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.Prescriptions[id]
+	if !ok || p.TrangThai != from {
+		return false, nil
+	}
+	p.TrangThai = services.PrescriptionCancelled
+	r.Prescriptions[id] = p
+	r.Cancellations[id] = Cancellation{By: by, Reason: reason, At: at}
+	return true, nil
+}
+
+type users struct{ *Store }
+
+func (r users) ManagedClinic(userID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clinicID, ok := r.ManagedClinics[userID]
+	if !ok {
+		return "", services.ErrNotFound
+	}
+	return clinicID, nil
+}
+
+func (r users) HasTreated(doctorID, customerID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hasTreated(doctorID, customerID), nil
+}
+
+func (r users) HasVisitedClinic(clinicID, customerID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hasVisited(clinicID, customerID), nil
+}
+
+// hasTreated and hasVisited answer HasTreated and HasVisitedClinic for
+// callers already holding the lock.
+func (s *Store) hasTreated(doctorID, customerID string) bool {
+	for _, m := range s.MedicalRecords {
+		if m.MaBacSi == doctorID && m.MaCustomer == customerID {
+			return true
+		}
+	}
+	for _, a := range s.Appointments {
+		if a.MaBacSi == doctorID && a.MaCustomer == customerID && a.TrangThai != services.AppointmentCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) hasVisited(clinicID, customerID string) bool {
+	for _, m := range s.MedicalRecords {
+		if m.MaPhongKham == clinicID && m.MaCustomer == customerID {
+			return true
+		}
+	}
+	for _, a := range s.Appointments {
+		if a.MaPhongKham == clinicID && a.MaCustomer == customerID && a.TrangThai != services.AppointmentCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope reports whether the customer is one of the patients of p.
+func (s *Store) inScope(p services.PatientScope, customerID string) bool {
+	return p.All || (p.CustomerID != "" && p.CustomerID == customerID) ||
+		(p.TreatedBy != "" && s.hasTreated(p.TreatedBy, customerID)) ||
+		(p.VisitedClinic != "" && s.hasVisited(p.VisitedClinic, customerID))
+}
+
+type clinics struct{ *Store }
+
+func (r clinics) OpeningHours(clinicID string, weekday int) (*services.OpeningHours, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hours, ok := r.Store.OpeningHours[ClinicDay{ClinicID: clinicID, Weekday: weekday}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &hours, nil
+}
+
+func (r clinics) Room(id string) (*models.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	room, ok := r.Rooms[id]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	return &room, nil
+}
+
+// matches reports whether value passes a filter; an empty filter passes
+// any value.
+func matches(filter, value string) bool {
+	return filter == "" || filter == value
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
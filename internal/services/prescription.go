@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+// Prescription statuses. A prescription is created as a draft, frozen when
+// the doctor signs it and then either dispensed or cancelled.
+const (
+	PrescriptionDraft     = "DRAFT"
+	PrescriptionSigned    = "SIGNED"
+	PrescriptionDispensed = "DISPENSED"
+	PrescriptionCancelled = "CANCELLED"
+)
+
+// PrescriptionSorts are the sorts prescription lists accept.
+var PrescriptionSorts = map[string]SortKey{
+	"ngay_ke_don": {Expr: "dt.ngayKeDon", Kind: SortTime},
+	"trang_thai":  {Expr: "ISNULL(dt.trangThai, '')", Kind: SortText},
+}
+
+// PrescriptionFilter selects the prescriptions of a list. Empty fields
+// match any; Dates applies to the prescription date.
+type PrescriptionFilter struct {
+	RecordID      string
+	Status        string
+	CustomerID    string
+	DoctorID      string
+	ExcludeDrafts bool
+	Dates         DateRange
+}
+
+// Dose is the structured dose of a prescription line.
+type Dose struct {
+	Lieu         float64
+	DonVi        string
+	DuongDung    string
+	SoLanMoiNgay int
+	ThoiDiem     string
+	SoNgay       int
+}
+
+// PrescriptionLine is one medicine of a prescription (CHITIETDONTHUOC).
+// Dose is nil for lines written as free text.
+type PrescriptionLine struct {
+	MaThuoc  string
+	SoLuong  int
+	CachDung string
+	GhiChu   string
+	Dose     *Dose
+}
+
+// PrescribedMedicine is a prescription line with its catalogue entry.
+type PrescribedMedicine struct {
+	PrescriptionLine
+	TenThuoc  string
+	Gia       float64
+	CongDung  string
+	LieuLuong string
+}
+
+// OverriddenWarning is a safety warning the doctor chose to override.
+type OverriddenWarning struct {
+	Loai    string
+	MucDo   string
+	MaThuoc []string
+	NoiDung string
+}
+
+// DraftPrescription is what a doctor writes on a draft prescription, with
+// the warnings overridden and why.
+type DraftPrescription struct {
+	MaHoSo    string
+	GhiChu    string
+	Lines     []PrescriptionLine
+	Warnings  []OverriddenWarning
+	LyDoBoQua string
+}
+
+// PrescriptionRepository stores prescriptions (DONTHUOC), their lines and
+// overridden warnings.
+type PrescriptionRepository interface {
+	// Get returns ErrNotFound if there is no such prescription.
+	Get(id string) (*models.Prescription, error)
+	// List returns one page of the prescriptions matching f, with one more
+	// when another page follows, and the number of all of them unless
+	// params has a cursor.
+	List(f PrescriptionFilter, params utils.ListParams) ([]models.PrescriptionSummary, int, error)
+	// Medicines returns the lines of a prescription.
+	Medicines(id string) ([]PrescribedMedicine, error)
+	// Create stores a draft prescription written now, valid for three
+	// months, and the warnings by overrode.
+	Create(id string, draft DraftPrescription, by string) error
+	// Update replaces the note and lines of a prescription if it is still
+	// a draft, adds the warnings by overrode, and reports whether it did.
+	Update(id string, draft DraftPrescription, by string) (bool, error)
+	// Cancel cancels the prescription if it still has status from, and
+	// reports whether it did.
+	Cancel(id, from, by, reason string, at time.Time) (bool, error)
+}
+
+// PrescriptionService applies the rules of a prescription's lifecycle:
+// only the doctor of a record prescribes on it and manages the
+// prescription, only drafts are edited, only drafts and signed
+// prescriptions may be cancelled, and patients never see drafts.
+type PrescriptionService struct {
+	prescriptions PrescriptionRepository
+	records       MedicalRecordRepository
+}
+
+func NewPrescriptionService(prescriptions PrescriptionRepository, records MedicalRecordRepository) *PrescriptionService {
+	return &PrescriptionService{prescriptions: prescriptions, records: records}
+}
+
+// List returns one page of the prescriptions the viewer may see.
+func (s *PrescriptionService) List(v Viewer, f PrescriptionFilter, params utils.ListParams) ([]models.PrescriptionSummary, int, error) {
+	switch v.Role {
+	case RoleCustomer:
+		if !restrict(&f.CustomerID, v.UserID) {
+			return nil, 0, nil
+		}
+		f.ExcludeDrafts = true
+	case RoleDoctor:
+		if !restrict(&f.DoctorID, v.UserID) {
+			return nil, 0, nil
+		}
+	}
+	return s.prescriptions.List(f, params)
+}
+
+// Medicines returns the lines of a prescription.
+func (s *PrescriptionService) Medicines(id string) ([]PrescribedMedicine, error) {
+	return s.prescriptions.Medicines(id)
+}
+
+// RecordToPrescribe returns a record the viewer, its doctor, may prescribe
+// on.
+func (s *PrescriptionService) RecordToPrescribe(v Viewer, recordID string) (*models.MedicalRecordDetail, error) {
+	if v.Role != RoleDoctor {
+		return nil, forbidden("Only doctors can create prescriptions")
+	}
+	record, err := s.records.Get(recordID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("Medical record not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.MaBacSi != v.UserID {
+		return nil, forbidden("You can only create prescriptions for your own patients")
+	}
+	return record, nil
+}
+
+// Create writes a draft prescription of the viewer and returns its ID.
+func (s *PrescriptionService) Create(v Viewer, draft DraftPrescription) (string, error) {
+	if _, err := s.RecordToPrescribe(v, draft.MaHoSo); err != nil {
+		return "", err
+	}
+	id, err := utils.GeneratePrescriptionID()
+	if err != nil {
+		return "", err
+	}
+	if err := s.prescriptions.Create(id, draft, v.UserID); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Editable returns a draft prescription the viewer, its doctor, may edit.
+func (s *PrescriptionService) Editable(v Viewer, id string) (*models.Prescription, error) {
+	if v.Role != RoleDoctor {
+		return nil, forbidden("Only doctors can update prescriptions")
+	}
+	prescription, err := s.prescriptions.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("Prescription not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prescription.MaBacSi != v.UserID {
+		return nil, forbidden("You can only update your own prescriptions")
+	}
+	if prescription.TrangThai != PrescriptionDraft {
+		return nil, conflict("Only draft prescriptions can be edited")
+	}
+	return prescription, nil
+}
+
+// Update replaces the note and lines of a draft prescription of the
+// viewer.
+func (s *PrescriptionService) Update(v Viewer, id string, draft DraftPrescription) error {
+	if _, err := s.Editable(v, id); err != nil {
+		return err
+	}
+	updated, err := s.prescriptions.Update(id, draft, v.UserID)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return conflict("Only draft prescriptions can be edited")
+	}
+	return nil
+}
+
+// Owned returns a prescription the viewer, a doctor, wrote.
+func (s *PrescriptionService) Owned(v Viewer, id string) (*models.Prescription, error) {
+	if v.Role != RoleDoctor {
+		return nil, forbidden("Only doctors can sign or cancel prescriptions")
+	}
+	prescription, err := s.prescriptions.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("Prescription not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prescription.MaBacSi != v.UserID {
+		return nil, forbidden("You can only manage your own prescriptions")
+	}
+	return prescription, nil
+}
+
+// Cancel withdraws a draft or signed prescription of the viewer.
+func (s *PrescriptionService) Cancel(v Viewer, id, reason string, at time.Time) error {
+	prescription, err := s.Owned(v, id)
+	if err != nil {
+		return err
+	}
+	if prescription.TrangThai != PrescriptionDraft && prescription.TrangThai != PrescriptionSigned {
+		return conflict("Only draft or signed prescriptions can be cancelled")
+	}
+	cancelled, err := s.prescriptions.Cancel(id, prescription.TrangThai, v.UserID, reason, at)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return conflict("Only draft or signed prescriptions can be cancelled")
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+var prescriptionList = ListQuery{Sorts: PrescriptionSorts, IDColumn: "dt.maDonThuoc"}
+
+type sqlPrescriptionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLPrescriptionRepository stores prescriptions in SQL Server.
+func NewSQLPrescriptionRepository(db *sql.DB) PrescriptionRepository {
+	return &sqlPrescriptionRepository{db: db}
+}
+
+func (r *sqlPrescriptionRepository) Get(id string) (*models.Prescription, error) {
+	var p models.Prescription
+	var ghiChu, trangThai sql.NullString
+	var ngayKeDon sql.NullTime
+
+	err := r.db.QueryRow(`
+		SELECT dt.maDonThuoc, dt.maHoSo, dt.ngayKeDon, dt.ghiChu, dt.trangThai,
+		       h.maCustomer, h.maBacSi
+		FROM DONTHUOC dt
+		JOIN HOSO h ON dt.maHoSo = h.maHoSo
+		WHERE dt.maDonThuoc = @p1
+	`, id).Scan(&p.MaDonThuoc, &p.MaHoSo, &ngayKeDon, &ghiChu, &trangThai, &p.MaCustomer, &p.MaBacSi)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.NgayKeDon = timePtr(ngayKeDon)
+	p.GhiChu = stringPtr(ghiChu)
+	p.TrangThai = trangThai.String
+	return &p, nil
+}
+
+func (r *sqlPrescriptionRepository) Cancel(id, from, by, reason string, at time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE DONTHUOC SET trangThai = 'CANCELLED', ngayHuy = @p1, nguoiHuy = @p2, lyDoHuy = @p3
+		WHERE maDonThuoc = @p4 AND trangThai = @p5
+	`, at, by, reason, id, from)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *sqlPrescriptionRepository) List(f PrescriptionFilter, params utils.ListParams) ([]models.PrescriptionSummary, int, error) {
+	columns := `
+		dt.maDonThuoc, dt.maHoSo, dt.ngayKeDon, dt.ghiChu,
+		dt.trangThai, dt.maXacThuc, h.maCustomer, h.maBacSi,
+		uc.hoTen as tenKhachHang, ud.hoTen as tenBacSi
+	`
+	from := `
+		FROM DONTHUOC dt
+		JOIN HOSO h ON dt.maHoSo = h.maHoSo
+		JOIN [USER] uc ON h.maCustomer = uc.userID
+		JOIN [USER] ud ON h.maBacSi = ud.userID
+		WHERE 1=1
+	`
+	if f.ExcludeDrafts {
+		from += " AND dt.trangThai <> 'DRAFT'"
+	}
+	where, args := whereEqual([]columnValue{
+		{"dt.maHoSo", f.RecordID},
+		{"dt.trangThai", f.Status},
+		{"h.maCustomer", f.CustomerID},
+		{"h.maBacSi", f.DoctorID},
+	}, nil)
+	from += where
+	dates, args := f.Dates.Where("dt.ngayKeDon", args)
+	from += dates
+
+	var prescriptions []models.PrescriptionSummary
+	total, err := listRows(r.db, prescriptionList, columns, from, args, params, func(rows *sql.Rows) error {
+		var p models.PrescriptionSummary
+		var ghiChu, trangThai, maXacThuc, tenKhachHang, tenBacSi sql.NullString
+		var ngayKeDon sql.NullTime
+
+		err := rows.Scan(&p.MaDonThuoc, &p.MaHoSo, &ngayKeDon, &ghiChu,
+			&trangThai, &maXacThuc, &p.MaCustomer, &p.MaBacSi, &tenKhachHang, &tenBacSi)
+		if err != nil {
+			return err
+		}
+
+		p.NgayKeDon = timePtr(ngayKeDon)
+		p.GhiChu = stringPtr(ghiChu)
+		p.TrangThai = trangThai.String
+		p.MaXacThuc = maXacThuc.String
+		p.TenKhachHang = tenKhachHang.String
+		p.TenBacSi = tenBacSi.String
+		prescriptions = append(prescriptions, p)
+		return nil
+	})
+	return prescriptions, total, err
+}
+
+func (r *sqlPrescriptionRepository) Medicines(id string) ([]PrescribedMedicine, error) {
+	rows, err := r.db.Query(`
+		SELECT ct.maThuoc, ct.soLuong, ct.cachDung, ct.ghiChu,
+		       t.tenThuoc, t.gia, t.congDung, t.lieuLuong,
+		       ct.lieuDung, ct.donViLieu, ct.duongDung, ct.soLanMoiNgay, ct.thoiDiemDung, ct.soNgayDung
+		FROM CHITIETDONTHUOC ct
+		JOIN THUOC t ON ct.maThuoc = t.maThuoc
+		WHERE ct.maDonThuoc = @p1
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	medicines := []PrescribedMedicine{}
+	for rows.Next() {
+		var maThuoc, tenThuoc, cachDung, ghiChu, congDung, lieuLuong sql.NullString
+		var soLuong sql.NullInt32
+		var gia sql.NullFloat64
+
+		var lieuDung sql.NullFloat64
+		var donViLieu, duongDung, thoiDiemDung sql.NullString
+		var soLanMoiNgay, soNgayDung sql.NullInt32
+
+		err := rows.Scan(&maThuoc, &soLuong, &cachDung, &ghiChu,
+			&tenThuoc, &gia, &congDung, &lieuLuong,
+			&lieuDung, &donViLieu, &duongDung, &soLanMoiNgay, &thoiDiemDung, &soNgayDung)
+		if err != nil {
+			return nil, err
+		}
+
+		medicine := PrescribedMedicine{
+			PrescriptionLine: PrescriptionLine{
+				MaThuoc:  maThuoc.String,
+				SoLuong:  int(soLuong.Int32),
+				CachDung: cachDung.String,
+				GhiChu:   ghiChu.String,
+			},
+			TenThuoc:  tenThuoc.String,
+			Gia:       gia.Float64,
+			CongDung:  congDung.String,
+			LieuLuong: lieuLuong.String,
+		}
+		if lieuDung.Valid {
+			medicine.Dose = &Dose{
+				Lieu:         lieuDung.Float64,
+				DonVi:        donViLieu.String,
+				DuongDung:    duongDung.String,
+				SoLanMoiNgay: int(soLanMoiNgay.Int32),
+				ThoiDiem:     thoiDiemDung.String,
+				SoNgay:       int(soNgayDung.Int32),
+			}
+		}
+		medicines = append(medicines, medicine)
+	}
+	return medicines, rows.Err()
+}
+
+func (r *sqlPrescriptionRepository) Create(id string, draft DraftPrescription, by string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO DONTHUOC (maDonThuoc, maHoSo, ngayKeDon, ngayHeHan, ghiChu, trangThai)
+		VALUES (@p1, @p2, GETDATE(), DATEADD(month, 3, GETDATE()), @p3, 'DRAFT')
+	`, id, draft.MaHoSo, draft.GhiChu)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPrescriptionLines(tx, id, draft, by); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlPrescriptionRepository) Update(id string, draft DraftPrescription, by string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// The status is checked again in case the prescription was signed
+	// meanwhile.
+	result, err := tx.Exec("UPDATE DONTHUOC SET ghiChu = @p1 WHERE maDonThuoc = @p2 AND trangThai = 'DRAFT'", draft.GhiChu, id)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM CHITIETDONTHUOC WHERE maDonThuoc = @p1", id); err != nil {
+		return false, err
+	}
+	if err := insertPrescriptionLines(tx, id, draft, by); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// insertPrescriptionLines adds the lines of a draft to a prescription with
+// the warnings by overrode.
+func insertPrescriptionLines(tx *sql.Tx, id string, draft DraftPrescription, by string) error {
+	for _, line := range draft.Lines {
+		_, err := tx.Exec(`
+			INSERT INTO CHITIETDONTHUOC (maDonThuoc, maThuoc, soLuong, cachDung, ghiChu,
+				lieuDung, donViLieu, duongDung, soLanMoiNgay, thoiDiemDung, soNgayDung)
+			VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8, @p9, @p10, @p11)
+		`, append([]interface{}{id, line.MaThuoc, line.SoLuong, line.CachDung, line.GhiChu}, DoseValues(line.Dose)...)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, w := range draft.Warnings {
+		_, err := tx.Exec(`
+			INSERT INTO CANHBAODONTHUOC (maDonThuoc, loai, mucDo, maThuoc, noiDung, lyDoBoQua, nguoiBoQua, thoiGian)
+			VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8)
+		`, id, w.Loai, w.MucDo, strings.Join(w.MaThuoc, ","), w.NoiDung, strings.TrimSpace(draft.LyDoBoQua), by, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DoseValues returns the structured dose columns of a prescription or
+// template line, all NULL for free-text lines.
+func DoseValues(dose *Dose) []interface{} {
+	if dose == nil {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{dose.Lieu, dose.DonVi, dose.DuongDung, dose.SoLanMoiNgay, NullIfEmpty(dose.ThoiDiem), dose.SoNgay}
+}
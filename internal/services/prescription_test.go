@@ -0,0 +1,199 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+	"clinic-management/internal/utils"
+)
+
+func TestPrescriptionOwned(t *testing.T) {
+	store := memory.NewStore()
+	store.Prescriptions["DT001"] = models.Prescription{MaDonThuoc: "DT001", MaBacSi: "BS001", TrangThai: services.PrescriptionDraft}
+	svc := services.NewPrescriptionService(store.PrescriptionRepository(), store.MedicalRecordRepository())
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		id     string
+		want   error
+	}{
+		{"prescribing doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, "DT001", nil},
+		{"other doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, "DT001", services.ErrForbidden},
+		{"not a doctor", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, "DT001", services.ErrForbidden},
+		{"missing", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, "DT999", services.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Owned(tt.viewer, tt.id)
+			if (tt.want == nil && err != nil) || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Owned() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrescriptionCancel(t *testing.T) {
+	doctor := services.Viewer{UserID: "BS001", Role: services.RoleDoctor}
+	at := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		status string
+		want   error
+	}{
+		{services.PrescriptionDraft, nil},
+		{services.PrescriptionSigned, nil},
+		{services.PrescriptionDispensed, services.ErrConflict},
+		{services.PrescriptionCancelled, services.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			store := memory.NewStore()
+			store.Prescriptions["DT001"] = models.Prescription{MaDonThuoc: "DT001", MaBacSi: "BS001", TrangThai: tt.status}
+			svc := services.NewPrescriptionService(store.PrescriptionRepository(), store.MedicalRecordRepository())
+
+			err := svc.Cancel(doctor, "DT001", "Sai liều", at)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("Cancel() error = %v, want %v", err, tt.want)
+				}
+				if _, cancelled := store.Cancellations["DT001"]; cancelled {
+					t.Error("Cancel() recorded a cancellation it refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Cancel() error = %v", err)
+			}
+			want := memory.Cancellation{By: "BS001", Reason: "Sai liều", At: at}
+			if got := store.Cancellations["DT001"]; got != want {
+				t.Errorf("cancellation = %+v, want %+v", got, want)
+			}
+			if status := store.Prescriptions["DT001"].TrangThai; status != services.PrescriptionCancelled {
+				t.Errorf("status = %s, want %s", status, services.PrescriptionCancelled)
+			}
+		})
+	}
+}
+
+func TestPrescriptionListHidesDraftsFromPatients(t *testing.T) {
+	store := memory.NewStore()
+	store.Prescriptions["DT001"] = models.Prescription{MaDonThuoc: "DT001", MaCustomer: "CUS001", MaBacSi: "BS001", TrangThai: services.PrescriptionDraft}
+	store.Prescriptions["DT002"] = models.Prescription{MaDonThuoc: "DT002", MaCustomer: "CUS001", MaBacSi: "BS001", TrangThai: services.PrescriptionSigned}
+	store.Prescriptions["DT003"] = models.Prescription{MaDonThuoc: "DT003", MaCustomer: "CUS002", MaBacSi: "BS002", TrangThai: services.PrescriptionSigned}
+	svc := services.NewPrescriptionService(store.PrescriptionRepository(), store.MedicalRecordRepository())
+	params := utils.ListParams{Page: 1, PageSize: 20, SortField: "trang_thai"}
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		want   []string
+	}{
+		{"patient", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, []string{"DT002"}},
+		{"doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, []string{"DT001", "DT002"}},
+		{"operation manager", services.Viewer{UserID: "QV001", Role: services.RoleOperationManager}, []string{"DT001", "DT002", "DT003"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prescriptions, _, err := svc.List(tt.viewer, services.PrescriptionFilter{}, params)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []string
+			for _, p := range prescriptions {
+				got = append(got, p.MaDonThuoc)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrescriptionCreate(t *testing.T) {
+	store := memory.NewStore()
+	store.MedicalRecords["HS001"] = models.MedicalRecordDetail{MedicalRecord: models.MedicalRecord{
+		MaHoSo: "HS001", MaCustomer: "CUS001", MaBacSi: "BS001",
+	}}
+	svc := services.NewPrescriptionService(store.PrescriptionRepository(), store.MedicalRecordRepository())
+	draft := services.DraftPrescription{
+		MaHoSo: "HS001",
+		Lines:  []services.PrescriptionLine{{MaThuoc: "T001", SoLuong: 10, CachDung: "Uống 1 viên x 2 lần/ngày"}},
+	}
+
+	_, err := svc.Create(services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, draft)
+	if !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Create() on another doctor's record error = %v, want ErrForbidden", err)
+	}
+	missing := draft
+	missing.MaHoSo = "HS999"
+	_, err = svc.Create(services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, missing)
+	if !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("Create() on a missing record error = %v, want ErrNotFound", err)
+	}
+
+	id, err := svc.Create(services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, draft)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	created := store.Prescriptions[id]
+	if created.TrangThai != services.PrescriptionDraft || created.MaCustomer != "CUS001" {
+		t.Errorf("created prescription = %+v", created)
+	}
+	if lines := store.PrescriptionLines[id]; len(lines) != 1 || lines[0].MaThuoc != "T001" {
+		t.Errorf("lines = %+v", lines)
+	}
+}
+
+func TestPrescriptionUpdateOnlyDrafts(t *testing.T) {
+	doctor := services.Viewer{UserID: "BS001", Role: services.RoleDoctor}
+	draft := services.DraftPrescription{
+		GhiChu:    "Tái khám sau 5 ngày",
+		Lines:     []services.PrescriptionLine{{MaThuoc: "T002", SoLuong: 5, CachDung: "Uống khi sốt"}},
+		Warnings:  []services.OverriddenWarning{{Loai: "DI_UNG", MucDo: "HIGH", MaThuoc: []string{"T002"}}},
+		LyDoBoQua: "Đã thử trước đây",
+	}
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		status string
+		want   error
+	}{
+		{"other doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, services.PrescriptionDraft, services.ErrForbidden},
+		{"signed", doctor, services.PrescriptionSigned, services.ErrConflict},
+		{"draft", doctor, services.PrescriptionDraft, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+			store.Prescriptions["DT001"] = models.Prescription{MaDonThuoc: "DT001", MaBacSi: "BS001", TrangThai: tt.status}
+			svc := services.NewPrescriptionService(store.PrescriptionRepository(), store.MedicalRecordRepository())
+
+			err := svc.Update(tt.viewer, "DT001", draft)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("Update() error = %v, want %v", err, tt.want)
+				}
+				if _, written := store.PrescriptionLines["DT001"]; written {
+					t.Error("Update() wrote lines it refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if got := store.PrescriptionWrites["DT001"]; got.By != "BS001" || len(got.Warnings) != 1 {
+				t.Errorf("write = %+v", got)
+			}
+			if note := store.Prescriptions["DT001"].GhiChu; note == nil || *note != draft.GhiChu {
+				t.Errorf("note = %v, want %q", note, draft.GhiChu)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+// Work schedule statuses.
+const (
+	ScheduleAvailable   = "AVAILABLE"
+	ScheduleUnavailable = "UNAVAILABLE"
+)
+
+// ScheduleSorts are the sorts schedule lists accept.
+var ScheduleSorts = map[string]SortKey{
+	// Work date and start time together, in the order of the day.
+	"ngay_lam_viec": {
+		Expr: "DATEADD(MINUTE, DATEDIFF(MINUTE, CAST('00:00' AS TIME), CAST(ll.gioBatDau AS TIME)), CAST(ll.ngayLamViec AS DATETIME2))",
+		Kind: SortTime,
+		Value: func(item interface{}) interface{} {
+			return scheduleStart(item.(models.WorkScheduleDetail).WorkSchedule)
+		},
+	},
+	"ma_bac_si": {Expr: "ll.maBacSi", Kind: SortText},
+}
+
+// ScheduleFilter selects the schedules of a list. Empty fields match any.
+type ScheduleFilter struct {
+	DoctorID string
+	ClinicID string
+	RoomID   string
+	Status   string
+	Dates    DateRange
+}
+
+// ScheduleChanges are the fields of a work schedule to change; nil fields
+// are kept. An empty MaPhong takes the schedule out of its room.
+type ScheduleChanges struct {
+	NgayLamViec *time.Time
+	GioBatDau   *string
+	GioKetThuc  *string
+	TrangThai   *string
+	MaPhongKham *string
+	MaPhong     *string
+}
+
+// ScheduleRepository stores doctors' work schedules (LICHLAMVIEC).
+type ScheduleRepository interface {
+	// Get returns ErrNotFound if there is no such schedule.
+	Get(id string) (*models.WorkScheduleDetail, error)
+	// List returns one page of the schedules matching f, with one more
+	// when another page follows, and the number of all of them unless
+	// params has a cursor.
+	List(f ScheduleFilter, params utils.ListParams) ([]models.WorkScheduleDetail, int, error)
+	// Overlaps reports whether the doctor has a schedule on day that
+	// overlaps start to end.
+	Overlaps(doctorID string, day time.Time, start, end string) (bool, error)
+	// RoomSchedule returns a schedule other than excludeID that uses the
+	// room on day and overlaps start to end, or "" if there is none.
+	RoomSchedule(roomID string, day time.Time, start, end, excludeID string) (string, error)
+	// RoomReservation returns an available schedule of a doctor other than
+	// doctorID that holds the room at the time at, or "" if there is none.
+	RoomReservation(roomID string, at time.Time, doctorID string) (string, error)
+	Create(s models.WorkSchedule) error
+	// Update stores the date, hours, status, clinic and room of a schedule.
+	Update(s models.WorkSchedule) error
+	// HasActiveAppointments reports whether the schedule's doctor has
+	// appointments that are neither cancelled nor completed.
+	HasActiveAppointments(id string) (bool, error)
+	Delete(id string) error
+}
+
+// ScheduleService applies the rules on who sees and changes a work
+// schedule: doctors their own, clinic managers those of their clinic and
+// operation managers any.
+type ScheduleService struct {
+	schedules ScheduleRepository
+	users     UserRepository
+	clinics   ClinicRepository
+}
+
+func NewScheduleService(schedules ScheduleRepository, users UserRepository, clinics ClinicRepository) *ScheduleService {
+	return &ScheduleService{schedules: schedules, users: users, clinics: clinics}
+}
+
+// List returns one page of the schedules the viewer may see.
+func (s *ScheduleService) List(v Viewer, f ScheduleFilter, params utils.ListParams) ([]models.WorkScheduleDetail, int, error) {
+	switch v.Role {
+	case RoleDoctor:
+		if !restrict(&f.DoctorID, v.UserID) {
+			return nil, 0, nil
+		}
+	case RoleClinicManager:
+		clinicID, err := s.users.ManagedClinic(v.UserID)
+		if errors.Is(err, ErrNotFound) {
+			return nil, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if !restrict(&f.ClinicID, clinicID) {
+			return nil, 0, nil
+		}
+	}
+	return s.schedules.List(f, params)
+}
+
+// Get returns a schedule the viewer may see. Schedules of other doctors,
+// or for clinic managers of other clinics, are reported as not found.
+func (s *ScheduleService) Get(v Viewer, id string) (*models.WorkScheduleDetail, error) {
+	schedule, err := s.schedules.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, notFound("Schedule not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.Role {
+	case RoleDoctor:
+		if schedule.MaBacSi != v.UserID {
+			return nil, notFound("Schedule not found")
+		}
+	case RoleClinicManager:
+		clinicID, err := s.users.ManagedClinic(v.UserID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if clinicID != schedule.MaPhongKham {
+			return nil, notFound("Schedule not found")
+		}
+	}
+	return schedule, nil
+}
+
+// Delete removes a schedule unless its doctor still has active
+// appointments.
+func (s *ScheduleService) Delete(v Viewer, id string) error {
+	schedule, err := s.schedules.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return notFound("Schedule not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch v.Role {
+	case RoleDoctor:
+		if schedule.MaBacSi != v.UserID {
+			return forbidden("You can only delete your own schedules")
+		}
+	case RoleClinicManager:
+		clinicID, err := s.users.ManagedClinic(v.UserID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if clinicID != schedule.MaPhongKham {
+			return forbidden("You can only delete schedules for your clinic")
+		}
+	case RoleOperationManager:
+	default:
+		return forbidden("Insufficient permissions to delete schedule")
+	}
+
+	active, err := s.schedules.HasActiveAppointments(id)
+	if err != nil {
+		return err
+	}
+	if active {
+		return conflict("Cannot delete schedule with active appointments")
+	}
+	return s.schedules.Delete(id)
+}
+
+// Create adds a work schedule and returns its ID. Doctors may only add
+// their own; clinic and operation managers any doctor's.
+func (s *ScheduleService) Create(v Viewer, schedule models.WorkSchedule) (string, error) {
+	switch v.Role {
+	case RoleDoctor:
+		if schedule.MaBacSi != v.UserID {
+			return "", forbidden("You can only create schedules for yourself")
+		}
+	case RoleClinicManager, RoleOperationManager:
+	default:
+		return "", forbidden("Only doctors, clinic managers, or operation managers can create schedules")
+	}
+
+	if schedule.TrangThai == "" {
+		schedule.TrangThai = ScheduleAvailable
+	}
+	if err := s.check(schedule, ""); err != nil {
+		return "", err
+	}
+
+	overlaps, err := s.schedules.Overlaps(schedule.MaBacSi, schedule.NgayLamViec, schedule.GioBatDau, schedule.GioKetThuc)
+	if err != nil {
+		return "", fmt.Errorf("error checking for schedule conflicts: %v", err)
+	}
+	if overlaps {
+		return "", conflict("Schedule conflicts with existing schedule")
+	}
+
+	schedule.MaLichLamViec, err = utils.GenerateScheduleID()
+	if err != nil {
+		return "", err
+	}
+	if err := s.schedules.Create(schedule); err != nil {
+		return "", err
+	}
+	return schedule.MaLichLamViec, nil
+}
+
+// Update changes a work schedule. Doctors may change their own, clinic
+// managers those of their clinic and operation managers any.
+func (s *ScheduleService) Update(v Viewer, id string, changes ScheduleChanges) error {
+	current, err := s.schedules.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return notFound("Schedule not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch v.Role {
+	case RoleDoctor:
+		if current.MaBacSi != v.UserID {
+			return forbidden("You can only update your own schedules")
+		}
+	case RoleClinicManager:
+		clinicID, err := s.users.ManagedClinic(v.UserID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if clinicID != current.MaPhongKham {
+			return forbidden("You can only update schedules for your clinic")
+		}
+	case RoleOperationManager:
+	default:
+		return forbidden("Insufficient permissions to update schedule")
+	}
+
+	schedule := current.WorkSchedule
+	if changes.NgayLamViec != nil {
+		schedule.NgayLamViec = *changes.NgayLamViec
+	}
+	if changes.GioBatDau != nil {
+		schedule.GioBatDau = *changes.GioBatDau
+	}
+	if changes.GioKetThuc != nil {
+		schedule.GioKetThuc = *changes.GioKetThuc
+	}
+	if changes.TrangThai != nil {
+		schedule.TrangThai = *changes.TrangThai
+	}
+	if changes.MaPhongKham != nil {
+		schedule.MaPhongKham = *changes.MaPhongKham
+	}
+	if changes.MaPhong != nil {
+		schedule.MaPhong = nil
+		if *changes.MaPhong != "" {
+			schedule.MaPhong = changes.MaPhong
+		}
+	}
+
+	if changes.TrangThai != nil && !validScheduleStatus(schedule.TrangThai) {
+		return invalid("Invalid status. Use AVAILABLE or UNAVAILABLE")
+	}
+	// The schedule is checked as a whole when its time or place changed.
+	if changes.NgayLamViec != nil || changes.GioBatDau != nil || changes.GioKetThuc != nil ||
+		changes.MaPhongKham != nil || changes.MaPhong != nil {
+		if err := s.check(schedule, id); err != nil {
+			return err
+		}
+	}
+	return s.schedules.Update(schedule)
+}
+
+// check validates the status and hours of a schedule, and that it falls
+// within the clinic's opening hours and its room is free. excludeID is the
+// schedule being changed, if any.
+func (s *ScheduleService) check(schedule models.WorkSchedule, excludeID string) error {
+	if !validScheduleStatus(schedule.TrangThai) {
+		return invalid("Invalid status. Use AVAILABLE or UNAVAILABLE")
+	}
+	if clockMinutes(schedule.GioBatDau) >= clockMinutes(schedule.GioKetThuc) {
+		return invalid("End time must be after start time")
+	}
+	if err := checkOpeningHours(s.clinics, schedule.MaPhongKham, schedule.NgayLamViec, schedule.GioBatDau, schedule.GioKetThuc); err != nil {
+		return err
+	}
+	if schedule.MaPhong == nil {
+		return nil
+	}
+
+	if err := checkRoom(s.clinics, *schedule.MaPhong, schedule.MaPhongKham); err != nil {
+		return err
+	}
+	other, err := s.schedules.RoomSchedule(*schedule.MaPhong, schedule.NgayLamViec, schedule.GioBatDau, schedule.GioKetThuc, excludeID)
+	if err != nil {
+		return fmt.Errorf("error checking room availability: %v", err)
+	}
+	if other != "" {
+		return conflict(fmt.Sprintf("Room is already used by schedule %s at this time", other))
+	}
+	return nil
+}
+
+func validScheduleStatus(status string) bool {
+	return status == ScheduleAvailable || status == ScheduleUnavailable
+}
+
+// scheduleStart is the time a work schedule starts.
+func scheduleStart(schedule models.WorkSchedule) time.Time {
+	day := schedule.NgayLamViec
+	minutes := clockMinutes(schedule.GioBatDau)
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, time.UTC)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/utils"
+)
+
+type sqlScheduleRepository struct {
+	db *sql.DB
+}
+
+// NewSQLScheduleRepository stores work schedules in SQL Server.
+func NewSQLScheduleRepository(db *sql.DB) ScheduleRepository {
+	return &sqlScheduleRepository{db: db}
+}
+
+func (r *sqlScheduleRepository) Get(id string) (*models.WorkScheduleDetail, error) {
+	var s models.WorkScheduleDetail
+	var maPhong, tenBacSi, tenPhongKham, tenPhong sql.NullString
+
+	err := r.db.QueryRow(`
+		SELECT ll.maLichLamViec, ll.maBacSi, ll.maPhongKham, ll.maPhong, ll.ngayLamViec,
+		       CONVERT(VARCHAR(5), ll.gioBatDau, 108), CONVERT(VARCHAR(5), ll.gioKetThuc, 108), ll.status,
+		       u.hoTen, p.tenPhongKham, ph.tenPhong
+		FROM LICHLAMVIEC ll
+		LEFT JOIN [USER] u ON ll.maBacSi = u.userID
+		LEFT JOIN PHONGKHAM p ON ll.maPhongKham = p.maPhongKham
+		LEFT JOIN PHONG ph ON ll.maPhong = ph.maPhong
+		WHERE ll.maLichLamViec = @p1
+	`, id).Scan(
+		&s.MaLichLamViec, &s.MaBacSi, &s.MaPhongKham, &maPhong, &s.NgayLamViec,
+		&s.GioBatDau, &s.GioKetThuc, &s.TrangThai,
+		&tenBacSi, &tenPhongKham, &tenPhong,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.MaPhong = stringPtr(maPhong)
+	s.TenBacSi = tenBacSi.String
+	s.TenPhongKham = tenPhongKham.String
+	s.TenPhong = stringPtr(tenPhong)
+	return &s, nil
+}
+
+func (r *sqlScheduleRepository) HasActiveAppointments(id string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM LICHKHAM l
+		JOIN LICHLAMVIEC ll ON l.maBacSi = ll.maBacSi
+		WHERE ll.maLichLamViec = @p1 AND l.trangThai NOT IN ('CANCELLED', 'COMPLETED')
+	`, id).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlScheduleRepository) Delete(id string) error {
+	result, err := r.db.Exec("DELETE FROM LICHLAMVIEC WHERE maLichLamViec = @p1", id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+var scheduleList = ListQuery{Sorts: ScheduleSorts, IDColumn: "ll.maLichLamViec"}
+
+func (r *sqlScheduleRepository) List(f ScheduleFilter, params utils.ListParams) ([]models.WorkScheduleDetail, int, error) {
+	columns := `
+		ll.maLichLamViec, ll.maBacSi, ll.maPhongKham, ll.ngayLamViec,
+		CONVERT(VARCHAR(5), ll.gioBatDau, 108), CONVERT(VARCHAR(5), ll.gioKetThuc, 108), ll.status,
+		u.hoTen as tenBacSi, p.tenPhongKham, ll.maPhong, ph.tenPhong
+	`
+	from := `
+		FROM LICHLAMVIEC ll
+		JOIN [USER] u ON ll.maBacSi = u.userID
+		JOIN PHONGKHAM p ON ll.maPhongKham = p.maPhongKham
+		LEFT JOIN PHONG ph ON ll.maPhong = ph.maPhong
+		WHERE 1=1
+	`
+	where, args := whereEqual([]columnValue{
+		{"ll.maBacSi", f.DoctorID},
+		{"ll.maPhongKham", f.ClinicID},
+		{"ll.maPhong", f.RoomID},
+		{"ll.status", f.Status},
+	}, nil)
+	dates, args := f.Dates.Where("ll.ngayLamViec", args)
+	from += where + dates
+
+	var schedules []models.WorkScheduleDetail
+	total, err := listRows(r.db, scheduleList, columns, from, args, params, func(rows *sql.Rows) error {
+		var s models.WorkScheduleDetail
+		var tenBacSi, tenPhongKham, maPhong, tenPhong sql.NullString
+		err := rows.Scan(&s.MaLichLamViec, &s.MaBacSi, &s.MaPhongKham, &s.NgayLamViec,
+			&s.GioBatDau, &s.GioKetThuc, &s.TrangThai, &tenBacSi, &tenPhongKham, &maPhong, &tenPhong)
+		if err != nil {
+			return err
+		}
+
+		s.TenBacSi = tenBacSi.String
+		s.TenPhongKham = tenPhongKham.String
+		s.MaPhong = stringPtr(maPhong)
+		s.TenPhong = stringPtr(tenPhong)
+		schedules = append(schedules, s)
+		return nil
+	})
+	return schedules, total, err
+}
+
+func (r *sqlScheduleRepository) Overlaps(doctorID string, day time.Time, start, end string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM LICHLAMVIEC
+		WHERE maBacSi = @p1 AND ngayLamViec = @p2
+		AND CAST(gioBatDau AS TIME) < CAST(@p4 AS TIME) AND CAST(gioKetThuc AS TIME) > CAST(@p3 AS TIME)
+	`, doctorID, day, start, end).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlScheduleRepository) RoomSchedule(roomID string, day time.Time, start, end, excludeID string) (string, error) {
+	var other string
+	err := r.db.QueryRow(`
+		SELECT TOP 1 maLichLamViec FROM LICHLAMVIEC
+		WHERE maPhong = @p1 AND ngayLamViec = @p2 AND maLichLamViec <> @p3
+		  AND CAST(gioBatDau AS TIME) < CAST(@p5 AS TIME) AND CAST(gioKetThuc AS TIME) > CAST(@p4 AS TIME)
+	`, roomID, day, excludeID, start, end).Scan(&other)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return other, err
+}
+
+func (r *sqlScheduleRepository) RoomReservation(roomID string, at time.Time, doctorID string) (string, error) {
+	var other string
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	err := r.db.QueryRow(`
+		SELECT TOP 1 maLichLamViec FROM LICHLAMVIEC
+		WHERE maPhong = @p1 AND ngayLamViec = @p2 AND maBacSi <> @p3 AND status = 'AVAILABLE'
+		  AND CAST(gioBatDau AS TIME) <= CAST(@p4 AS TIME) AND CAST(gioKetThuc AS TIME) > CAST(@p4 AS TIME)
+	`, roomID, day, doctorID, at.Format("15:04")).Scan(&other)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return other, err
+}
+
+func (r *sqlScheduleRepository) Create(s models.WorkSchedule) error {
+	_, err := r.db.Exec(`
+		INSERT INTO LICHLAMVIEC (maLichLamViec, maBacSi, maPhongKham, ngayLamViec, gioBatDau, gioKetThuc, status, maPhong)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8)
+	`, s.MaLichLamViec, s.MaBacSi, s.MaPhongKham, s.NgayLamViec, s.GioBatDau, s.GioKetThuc, s.TrangThai, s.MaPhong)
+	return err
+}
+
+func (r *sqlScheduleRepository) Update(s models.WorkSchedule) error {
+	result, err := r.db.Exec(`
+		UPDATE LICHLAMVIEC
+		SET ngayLamViec = @p1, gioBatDau = @p2, gioKetThuc = @p3, status = @p4, maPhongKham = @p5, maPhong = @p6
+		WHERE maLichLamViec = @p7
+	`, s.NgayLamViec, s.GioBatDau, s.GioKetThuc, s.TrangThai, s.MaPhongKham, s.MaPhong, s.MaLichLamViec)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
@@ -0,0 +1,224 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+	"clinic-management/internal/utils"
+)
+
+func newScheduleStore() *memory.Store {
+	store := memory.NewStore()
+	store.Schedules["LLV001"] = models.WorkScheduleDetail{
+		WorkSchedule: models.WorkSchedule{
+			MaLichLamViec: "LLV001",
+			MaBacSi:       "BS001",
+			MaPhongKham:   "PK001",
+			NgayLamViec:   time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			GioBatDau:     "08:00",
+			GioKetThuc:    "12:00",
+			TrangThai:     "AVAILABLE",
+		},
+	}
+	store.ManagedClinics["QL001"] = "PK001"
+	store.ManagedClinics["QL002"] = "PK002"
+	return store
+}
+
+func TestScheduleGetVisibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		want   error
+	}{
+		{"own doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, nil},
+		{"other doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, services.ErrNotFound},
+		{"manager of the clinic", services.Viewer{UserID: "QL001", Role: services.RoleClinicManager}, nil},
+		{"manager of another clinic", services.Viewer{UserID: "QL002", Role: services.RoleClinicManager}, services.ErrNotFound},
+		{"manager without clinic", services.Viewer{UserID: "QL003", Role: services.RoleClinicManager}, services.ErrNotFound},
+		{"operation manager", services.Viewer{UserID: "QV001", Role: services.RoleOperationManager}, nil},
+	}
+
+	store := newScheduleStore()
+	svc := services.NewScheduleService(store.ScheduleRepository(), store.UserRepository(), store.ClinicRepository())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Get(tt.viewer, "LLV001")
+			if (tt.want == nil && err != nil) || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Get() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		active bool
+		want   error
+	}{
+		{"other doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, false, services.ErrForbidden},
+		{"manager of another clinic", services.Viewer{UserID: "QL002", Role: services.RoleClinicManager}, false, services.ErrForbidden},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, false, services.ErrForbidden},
+		{"active appointments", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, true, services.ErrConflict},
+		{"own doctor", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, false, nil},
+		{"manager of the clinic", services.Viewer{UserID: "QL001", Role: services.RoleClinicManager}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newScheduleStore()
+			if tt.active {
+				store.Appointments["LK001"] = models.AppointmentDetail{Appointment: models.Appointment{
+					MaLichKham: "LK001",
+					MaBacSi:    "BS001",
+					TrangThai:  services.AppointmentConfirmed,
+				}}
+			}
+			svc := services.NewScheduleService(store.ScheduleRepository(), store.UserRepository(), store.ClinicRepository())
+
+			err := svc.Delete(tt.viewer, "LLV001")
+			_, kept := store.Schedules["LLV001"]
+			if tt.want == nil {
+				if err != nil || kept {
+					t.Errorf("Delete() error = %v, schedule kept = %v", err, kept)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || !kept {
+				t.Errorf("Delete() error = %v, want %v, schedule kept = %v", err, tt.want, kept)
+			}
+		})
+	}
+}
+
+func TestScheduleListVisibility(t *testing.T) {
+	store := newScheduleStore()
+	store.Schedules["LLV002"] = models.WorkScheduleDetail{WorkSchedule: models.WorkSchedule{
+		MaLichLamViec: "LLV002", MaBacSi: "BS002", MaPhongKham: "PK002",
+		NgayLamViec: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), GioBatDau: "08:00", GioKetThuc: "12:00",
+		TrangThai: services.ScheduleAvailable,
+	}}
+	svc := services.NewScheduleService(store.ScheduleRepository(), store.UserRepository(), store.ClinicRepository())
+	params := utils.ListParams{Page: 1, PageSize: 20, SortField: "ngay_lam_viec"}
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		want   []string
+	}{
+		{"doctor", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, []string{"LLV002"}},
+		{"manager of a clinic", services.Viewer{UserID: "QL001", Role: services.RoleClinicManager}, []string{"LLV001"}},
+		{"manager without clinic", services.Viewer{UserID: "QL003", Role: services.RoleClinicManager}, nil},
+		{"operation manager", services.Viewer{UserID: "QV001", Role: services.RoleOperationManager}, []string{"LLV001", "LLV002"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedules, _, err := svc.List(tt.viewer, services.ScheduleFilter{}, params)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []string
+			for _, s := range schedules {
+				got = append(got, s.MaLichLamViec)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleCreate(t *testing.T) {
+	room := "P101"
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	doctor := services.Viewer{UserID: "BS002", Role: services.RoleDoctor}
+
+	tests := []struct {
+		name     string
+		viewer   services.Viewer
+		schedule models.WorkSchedule
+		want     error
+	}{
+		{"doctor for another doctor", doctor,
+			models.WorkSchedule{MaBacSi: "BS003", MaPhongKham: "PK001", NgayLamViec: monday, GioBatDau: "13:00", GioKetThuc: "17:00"}, services.ErrForbidden},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist},
+			models.WorkSchedule{MaBacSi: "BS002", MaPhongKham: "PK001", NgayLamViec: monday, GioBatDau: "13:00", GioKetThuc: "17:00"}, services.ErrForbidden},
+		{"end before start", doctor,
+			models.WorkSchedule{MaBacSi: "BS002", MaPhongKham: "PK001", NgayLamViec: monday, GioBatDau: "17:00", GioKetThuc: "13:00"}, services.ErrInvalid},
+		{"before opening", doctor,
+			models.WorkSchedule{MaBacSi: "BS002", MaPhongKham: "PK001", NgayLamViec: monday, GioBatDau: "06:00", GioKetThuc: "10:00"}, services.ErrInvalid},
+		{"closed day", doctor,
+			models.WorkSchedule{MaBacSi: "BS002", MaPhongKham: "PK001", NgayLamViec: monday.AddDate(0, 0, 6), GioBatDau: "13:00", GioKetThuc: "17:00"}, services.ErrInvalid},
+		{"overlapping own schedule", services.Viewer{UserID: "BS001", Role: services.RoleDoctor},
+			models.WorkSchedule{MaBacSi: "BS001", MaPhongKham: "PK001", NgayLamViec: monday, GioBatDau: "11:00", GioKetThuc: "15:00"}, services.ErrConflict},
+		{"room in use", doctor,
+			models.WorkSchedule{MaBacSi: "BS002", MaPhongKham: "PK001", MaPhong: &room, NgayLamViec: monday, GioBatDau: "10:00", GioKetThuc: "14:00"}, services.ErrConflict},
+		{"free room", doctor,
+			models.WorkSchedule{MaBacSi: "BS002", MaPhongKham: "PK001", MaPhong: &room, NgayLamViec: monday, GioBatDau: "13:00", GioKetThuc: "17:00"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newScheduleStore()
+			booked := "P101"
+			existing := store.Schedules["LLV001"]
+			existing.MaPhong = &booked
+			store.Schedules["LLV001"] = existing
+			store.Rooms["P101"] = models.Room{MaPhong: "P101", MaPhongKham: "PK001", TrangThai: services.RoomActive}
+			store.OpeningHours[memory.ClinicDay{ClinicID: "PK001", Weekday: 1}] = services.OpeningHours{Opens: "07:00", Closes: "18:00"}
+			store.OpeningHours[memory.ClinicDay{ClinicID: "PK001", Weekday: 7}] = services.OpeningHours{Closed: true}
+			svc := services.NewScheduleService(store.ScheduleRepository(), store.UserRepository(), store.ClinicRepository())
+
+			id, err := svc.Create(tt.viewer, tt.schedule)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Create() error = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if created := store.Schedules[id]; created.TrangThai != services.ScheduleAvailable || created.MaBacSi != "BS002" {
+				t.Errorf("created schedule = %+v", created.WorkSchedule)
+			}
+		})
+	}
+}
+
+func TestScheduleUpdate(t *testing.T) {
+	store := newScheduleStore()
+	svc := services.NewScheduleService(store.ScheduleRepository(), store.UserRepository(), store.ClinicRepository())
+
+	unavailable := services.ScheduleUnavailable
+	err := svc.Update(services.Viewer{UserID: "QL002", Role: services.RoleClinicManager}, "LLV001", services.ScheduleChanges{TrangThai: &unavailable})
+	if !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Update() by manager of another clinic error = %v, want ErrForbidden", err)
+	}
+
+	unknown := "BUSY"
+	err = svc.Update(services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, "LLV001", services.ScheduleChanges{TrangThai: &unknown})
+	if !errors.Is(err, services.ErrInvalid) {
+		t.Errorf("Update() to an unknown status error = %v, want ErrInvalid", err)
+	}
+
+	end := "07:00"
+	err = svc.Update(services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, "LLV001", services.ScheduleChanges{GioKetThuc: &end})
+	if !errors.Is(err, services.ErrInvalid) {
+		t.Errorf("Update() ending before the start error = %v, want ErrInvalid", err)
+	}
+
+	if err := svc.Update(services.Viewer{UserID: "QL001", Role: services.RoleClinicManager}, "LLV001", services.ScheduleChanges{TrangThai: &unavailable}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if status := store.Schedules["LLV001"].TrangThai; status != services.ScheduleUnavailable {
+		t.Errorf("status = %s, want %s", status, services.ScheduleUnavailable)
+	}
+}
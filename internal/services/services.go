@@ -0,0 +1,74 @@
+// Package services holds the business rules of the clinic: who may see or
+// change appointments, medical records, prescriptions and schedules, and
+// which changes are allowed. Data is reached through one repository
+// interface per aggregate, with SQL Server implementations in this package
+// and in-memory ones in services/memory, so the rules can be tested
+// without a database.
+package services
+
+import "errors"
+
+// Kinds of rule violations. Errors returned by services wrap one of them,
+// so callers can tell them apart with errors.Is.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+	ErrConflict  = errors.New("conflict")
+	ErrInvalid   = errors.New("invalid")
+)
+
+// Roles of the users calling a service.
+const (
+	RoleCustomer         = "CUSTOMER"
+	RoleDoctor           = "DOCTOR"
+	RoleReceptionist     = "RECEPTIONIST"
+	RoleAccountant       = "ACCOUNTANT"
+	RoleClinicManager    = "CLINIC_MANAGER"
+	RoleOperationManager = "OPERATION_MANAGER"
+)
+
+// Viewer is the authenticated user a service acts for.
+type Viewer struct {
+	UserID string
+	Role   string
+}
+
+// Error is a rule violation with a message meant for the API client.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func notFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+func conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func invalid(message string) error {
+	return &Error{Kind: ErrInvalid, Message: message}
+}
+
+// restrict narrows a filter field to value. It reports false when the
+// filter already asks for another value, which nothing can match.
+func restrict(field *string, value string) bool {
+	if *field != "" && *field != value {
+		return false
+	}
+	*field = value
+	return true
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"clinic-management/internal/utils"
+)
+
+func stringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// requireRow reports ErrNotFound when a statement changed no row.
+func requireRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// columnValue restricts a column to a value; see whereEqual.
+type columnValue struct {
+	column string
+	value  string
+}
+
+// whereEqual restricts each column to its value, skipping empty values,
+// and numbers the arguments after args.
+func whereEqual(filters []columnValue, args []interface{}) (string, []interface{}) {
+	var clause string
+	for _, f := range filters {
+		if f.value != "" {
+			clause += fmt.Sprintf(" AND %s = @p%d", f.column, len(args)+1)
+			args = append(args, f.value)
+		}
+	}
+	return clause, args
+}
+
+// listRows runs one page of a list query built by q and scans each row
+// with scan. It returns the number of rows of all pages, or 0 in cursor
+// mode, which skips counting.
+func listRows(db *sql.DB, q ListQuery, columns, from string, args []interface{}, params utils.ListParams, scan func(*sql.Rows) error) (int, error) {
+	query, pageArgs, countQuery := q.Build(columns, from, args, params)
+
+	var total int
+	if params.Cursor == nil {
+		if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+			return 0, fmt.Errorf("error counting rows: %v", err)
+		}
+	}
+
+	rows, err := db.Query(query, pageArgs...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return 0, err
+		}
+	}
+	return total, rows.Err()
+}
+
+// NullIfEmpty stores a blank optional field as NULL and trims any other.
+func NullIfEmpty(s string) interface{} {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return strings.TrimSpace(s)
+}
+
+// stringValue is the value of an optional string, empty if nil.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,86 @@
+package services
+
+import "errors"
+
+// UserRepository answers questions about users and the clinics and
+// patients they are linked to.
+type UserRepository interface {
+	// ManagedClinic returns the clinic a clinic manager runs, or ErrNotFound
+	// if the user manages none.
+	ManagedClinic(userID string) (string, error)
+	// HasTreated reports whether the doctor has a medical record or an
+	// appointment that is not cancelled with the customer.
+	HasTreated(doctorID, customerID string) (bool, error)
+	// HasVisitedClinic reports whether the customer has a medical record or
+	// an appointment that is not cancelled at the clinic.
+	HasVisitedClinic(clinicID, customerID string) (bool, error)
+}
+
+// UserService applies the rules on access between users.
+type UserService struct {
+	users UserRepository
+}
+
+func NewUserService(users UserRepository) *UserService {
+	return &UserService{users: users}
+}
+
+// PatientScope is the set of patients whose medical information a viewer
+// may see. All covers everyone; otherwise each non-empty field admits the
+// patients it names, and an empty scope admits no one.
+type PatientScope struct {
+	All bool
+	// CustomerID is the viewer, a customer.
+	CustomerID string
+	// TreatedBy admits the patients of this doctor, as in HasTreated.
+	TreatedBy string
+	// VisitedClinic admits the patients of this clinic, as in
+	// HasVisitedClinic.
+	VisitedClinic string
+}
+
+// PatientScope returns the patients the viewer may see: a customer
+// themself, a doctor the patients they treat, a clinic manager those who
+// visit their clinic, and an operation manager everyone. Receptionists and
+// accountants have no clinical access.
+func (s *UserService) PatientScope(v Viewer) (PatientScope, error) {
+	switch v.Role {
+	case RoleCustomer:
+		return PatientScope{CustomerID: v.UserID}, nil
+	case RoleDoctor:
+		return PatientScope{TreatedBy: v.UserID}, nil
+	case RoleClinicManager:
+		clinicID, err := s.users.ManagedClinic(v.UserID)
+		if errors.Is(err, ErrNotFound) {
+			return PatientScope{}, nil
+		}
+		return PatientScope{VisitedClinic: clinicID}, err
+	case RoleOperationManager:
+		return PatientScope{All: true}, nil
+	}
+	return PatientScope{}, nil
+}
+
+// CanViewPatient returns nil if the customer is in the viewer's
+// PatientScope.
+func (s *UserService) CanViewPatient(v Viewer, customerID string) error {
+	scope, err := s.PatientScope(v)
+	if err != nil {
+		return err
+	}
+	allowed := scope.All || (scope.CustomerID != "" && scope.CustomerID == customerID)
+	if !allowed && scope.TreatedBy != "" {
+		if allowed, err = s.users.HasTreated(scope.TreatedBy, customerID); err != nil {
+			return err
+		}
+	}
+	if !allowed && scope.VisitedClinic != "" {
+		if allowed, err = s.users.HasVisitedClinic(scope.VisitedClinic, customerID); err != nil {
+			return err
+		}
+	}
+	if !allowed {
+		return forbidden("You do not have access to this patient's records")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type sqlUserRepository struct {
+	db *sql.DB
+}
+
+// NewSQLUserRepository reads users from SQL Server.
+func NewSQLUserRepository(db *sql.DB) UserRepository {
+	return &sqlUserRepository{db: db}
+}
+
+func (r *sqlUserRepository) ManagedClinic(userID string) (string, error) {
+	var clinicID string
+	err := r.db.QueryRow("SELECT maPhongKham FROM QUANLYPHONGKHAM WHERE maUser = @p1", userID).Scan(&clinicID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return clinicID, err
+}
+
+func (r *sqlUserRepository) HasTreated(doctorID, customerID string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM HOSO WHERE maCustomer = @p1 AND maBacSi = @p2)
+		     + (SELECT COUNT(*) FROM LICHKHAM WHERE maCustomer = @p1 AND maBacSi = @p2
+		                                        AND trangThai <> 'CANCELLED')
+	`, customerID, doctorID).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlUserRepository) HasVisitedClinic(clinicID, customerID string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM HOSO WHERE maCustomer = @p1 AND maPhongKham = @p2)
+		     + (SELECT COUNT(*) FROM LICHKHAM WHERE maCustomer = @p1 AND maPhongKham = @p2
+		                                        AND trangThai <> 'CANCELLED')
+	`, customerID, clinicID).Scan(&count)
+	return count > 0, err
+}
+
+// Where restricts column, a customer ID, to the patients of the scope and
+// numbers the arguments after args. An empty scope matches no row.
+func (p PatientScope) Where(column string, args []interface{}) (string, []interface{}) {
+	if p.All {
+		return "", args
+	}
+	var conditions []string
+	if p.CustomerID != "" {
+		args = append(args, p.CustomerID)
+		conditions = append(conditions, fmt.Sprintf("%s = @p%d", column, len(args)))
+	}
+	if p.TreatedBy != "" {
+		args = append(args, p.TreatedBy)
+		conditions = append(conditions, fmt.Sprintf(`%[1]s IN (
+			SELECT maCustomer FROM HOSO WHERE maBacSi = @p%[2]d
+			UNION SELECT maCustomer FROM LICHKHAM WHERE maBacSi = @p%[2]d AND trangThai <> 'CANCELLED')`,
+			column, len(args)))
+	}
+	if p.VisitedClinic != "" {
+		args = append(args, p.VisitedClinic)
+		conditions = append(conditions, fmt.Sprintf(`%[1]s IN (
+			SELECT maCustomer FROM HOSO WHERE maPhongKham = @p%[2]d
+			UNION SELECT maCustomer FROM LICHKHAM WHERE maPhongKham = @p%[2]d AND trangThai <> 'CANCELLED')`,
+			column, len(args)))
+	}
+	if len(conditions) == 0 {
+		return " AND 1=0", args
+	}
+	return " AND (" + strings.Join(conditions, " OR ") + ")", args
+}
@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"clinic-management/internal/models"
+	"clinic-management/internal/services"
+	"clinic-management/internal/services/memory"
+)
+
+func TestCanViewPatient(t *testing.T) {
+	store := memory.NewStore()
+	store.MedicalRecords["HS001"] = models.MedicalRecordDetail{MedicalRecord: models.MedicalRecord{
+		MaHoSo: "HS001", MaCustomer: "CUS001", MaBacSi: "BS001", MaPhongKham: "PK001",
+	}}
+	store.Appointments["LK001"] = models.AppointmentDetail{Appointment: models.Appointment{
+		MaLichKham: "LK001", MaCustomer: "CUS001", MaBacSi: "BS002", MaPhongKham: "PK002",
+		TrangThai: services.AppointmentScheduled,
+	}}
+	store.Appointments["LK002"] = models.AppointmentDetail{Appointment: models.Appointment{
+		MaLichKham: "LK002", MaCustomer: "CUS001", MaBacSi: "BS003", MaPhongKham: "PK003",
+		TrangThai: services.AppointmentCancelled,
+	}}
+	store.ManagedClinics["QL001"] = "PK001"
+	store.ManagedClinics["QL002"] = "PK002"
+	store.ManagedClinics["QL003"] = "PK003"
+	store.ManagedClinics["QL004"] = "PK004"
+	svc := services.NewUserService(store.UserRepository())
+
+	tests := []struct {
+		name   string
+		viewer services.Viewer
+		allow  bool
+	}{
+		{"the patient", services.Viewer{UserID: "CUS001", Role: services.RoleCustomer}, true},
+		{"another customer", services.Viewer{UserID: "CUS002", Role: services.RoleCustomer}, false},
+		{"doctor with a record", services.Viewer{UserID: "BS001", Role: services.RoleDoctor}, true},
+		{"doctor with an appointment", services.Viewer{UserID: "BS002", Role: services.RoleDoctor}, true},
+		{"doctor with a cancelled appointment", services.Viewer{UserID: "BS003", Role: services.RoleDoctor}, false},
+		{"receptionist", services.Viewer{UserID: "LT001", Role: services.RoleReceptionist}, false},
+		{"accountant", services.Viewer{UserID: "KT001", Role: services.RoleAccountant}, false},
+		{"manager of a clinic with a record", services.Viewer{UserID: "QL001", Role: services.RoleClinicManager}, true},
+		{"manager of a clinic with an appointment", services.Viewer{UserID: "QL002", Role: services.RoleClinicManager}, true},
+		{"manager of a clinic with a cancelled appointment", services.Viewer{UserID: "QL003", Role: services.RoleClinicManager}, false},
+		{"manager of another clinic", services.Viewer{UserID: "QL004", Role: services.RoleClinicManager}, false},
+		{"manager without a clinic", services.Viewer{UserID: "QL005", Role: services.RoleClinicManager}, false},
+		{"operation manager", services.Viewer{UserID: "OPM001", Role: services.RoleOperationManager}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.CanViewPatient(tt.viewer, "CUS001")
+			if tt.allow && err != nil {
+				t.Errorf("CanViewPatient() error = %v, want access", err)
+			}
+			if !tt.allow && !errors.Is(err, services.ErrForbidden) {
+				t.Errorf("CanViewPatient() error = %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// Package session tracks issued tokens server-side. Every login creates a
+// row in PHIENDANGNHAP whose ID is the jti claim of the tokens; revoking
+// the row invalidates them before they expire.
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Reasons recorded when a session is revoked.
+const (
+	RevokedLogout         = "LOGOUT"
+	RevokedByUser         = "REVOKED_BY_USER"
+	RevokedPasswordReset  = "PASSWORD_RESET"
+	RevokedPasswordChange = "PASSWORD_CHANGE"
+	RevokedRefreshReuse   = "REFRESH_REUSE"
+	RevokedDeactivated    = "USER_DEACTIVATED"
+)
+
+// Session is one logged-in device.
+type Session struct {
+	ID           string    `json:"ma_phien"`
+	UserID       string    `json:"ma_user"`
+	Device       string    `json:"thiet_bi"`
+	IP           string    `json:"dia_chi_ip"`
+	CreatedAt    time.Time `json:"ngay_tao"`
+	LastActiveAt time.Time `json:"lan_cuoi_hoat_dong"`
+	ExpiresAt    time.Time `json:"het_han_luc"`
+}
+
+type cacheEntry struct {
+	userID    string
+	valid     bool
+	checkedAt time.Time
+}
+
+// Store creates and checks sessions. Validity is cached for cacheTTL, so
+// a revocation made on another server instance takes effect within that
+// time; revocations made through this Store apply immediately.
+type Store struct {
+	db       *sql.DB
+	lifetime time.Duration
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewStore keeps sessions alive for lifetime after the last refresh.
+func NewStore(db *sql.DB, lifetime, cacheTTL time.Duration) *Store {
+	return &Store{db: db, lifetime: lifetime, cacheTTL: cacheTTL, cache: map[string]cacheEntry{}}
+}
+
+// Lifetime is how long a refresh token of a session stays valid.
+func (s *Store) Lifetime() time.Duration {
+	return s.lifetime
+}
+
+// Create starts a session for a device.
+func (s *Store) Create(userID, device, ip string) (Session, error) {
+	id, err := newID()
+	if err != nil {
+		return Session{}, err
+	}
+	if len(device) > 500 {
+		device = device[:500]
+	}
+
+	now := time.Now()
+	sess := Session{ID: id, UserID: userID, Device: device, IP: ip, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(s.lifetime)}
+	_, err = s.db.Exec(`
+		INSERT INTO PHIENDANGNHAP (maPhien, maUser, thietBi, diaChiIP, ngayTao, lanCuoiHoatDong, hetHanLuc)
+		VALUES (@p1, @p2, @p3, @p4, @p5, @p5, @p6)
+	`, sess.ID, userID, device, ip, now, sess.ExpiresAt)
+	if err != nil {
+		return Session{}, fmt.Errorf("error creating session: %v", err)
+	}
+	return sess, nil
+}
+
+// SetRefreshToken records the refresh token currently issued for a
+// session and extends the session.
+func (s *Store) SetRefreshToken(sessionID, refreshToken string) error {
+	_, err := s.db.Exec(`
+		UPDATE PHIENDANGNHAP SET maRefreshBam = @p1, hetHanLuc = @p2, lanCuoiHoatDong = GETDATE()
+		WHERE maPhien = @p3 AND thuHoiLuc IS NULL
+	`, hashToken(refreshToken), time.Now().Add(s.lifetime), sessionID)
+	return err
+}
+
+// RotateRefreshToken replaces the session's refresh token if presented
+// matches the current one. A mismatch means an old refresh token was
+// replayed, so the session is revoked as possibly stolen.
+func (s *Store) RotateRefreshToken(sessionID, userID, presented, next string) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE p SET maRefreshBam = @p1, hetHanLuc = @p2, lanCuoiHoatDong = GETDATE()
+		FROM PHIENDANGNHAP p JOIN [USER] u ON p.maUser = u.userID
+		WHERE p.maPhien = @p3 AND p.maUser = @p4 AND p.maRefreshBam = @p5
+		  AND p.thuHoiLuc IS NULL AND p.hetHanLuc > GETDATE() AND u.status = 'ACTIVE'
+	`, hashToken(next), time.Now().Add(s.lifetime), sessionID, userID, hashToken(presented))
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 1 {
+		return true, nil
+	}
+
+	var current sql.NullString
+	err = s.db.QueryRow(`
+		SELECT maRefreshBam FROM PHIENDANGNHAP WHERE maPhien = @p1 AND maUser = @p2 AND thuHoiLuc IS NULL
+	`, sessionID, userID).Scan(&current)
+	if err == nil && current.Valid && current.String != hashToken(presented) {
+		if _, err := s.Revoke(sessionID, userID, RevokedRefreshReuse); err != nil {
+			return false, err
+		}
+	} else if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	return false, nil
+}
+
+// Valid reports whether a session is still usable: not revoked, not
+// expired and its user still active.
+func (s *Store) Valid(sessionID, userID string) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[sessionID]
+	s.mu.Unlock()
+	if ok && entry.userID == userID && time.Since(entry.checkedAt) < s.cacheTTL {
+		return entry.valid, nil
+	}
+
+	var revokedAt sql.NullTime
+	var expiresAt time.Time
+	var status string
+	err := s.db.QueryRow(`
+		SELECT p.thuHoiLuc, p.hetHanLuc, u.status
+		FROM PHIENDANGNHAP p JOIN [USER] u ON p.maUser = u.userID
+		WHERE p.maPhien = @p1 AND p.maUser = @p2
+	`, sessionID, userID).Scan(&revokedAt, &expiresAt, &status)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	valid := err == nil && !revokedAt.Valid && expiresAt.After(time.Now()) && status == "ACTIVE"
+
+	if valid {
+		// Activity is recorded once per cache period, not on every request.
+		if _, err := s.db.Exec("UPDATE PHIENDANGNHAP SET lanCuoiHoatDong = GETDATE() WHERE maPhien = @p1", sessionID); err != nil {
+			return false, err
+		}
+	}
+	s.remember(sessionID, userID, valid)
+	return valid, nil
+}
+
+// List returns the user's active sessions, most recently used first.
+func (s *Store) List(userID string) ([]Session, error) {
+	rows, err := s.db.Query(`
+		SELECT maPhien, maUser, thietBi, diaChiIP, ngayTao, lanCuoiHoatDong, hetHanLuc
+		FROM PHIENDANGNHAP
+		WHERE maUser = @p1 AND thuHoiLuc IS NULL AND hetHanLuc > GETDATE()
+		ORDER BY lanCuoiHoatDong DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var sess Session
+		var device sql.NullString
+		if err := rows.Scan(&sess.ID, &sess.UserID, &device, &sess.IP, &sess.CreatedAt, &sess.LastActiveAt, &sess.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sess.Device = device.String
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one session of a user. It reports false if there was no
+// such active session.
+func (s *Store) Revoke(sessionID, userID, reason string) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE PHIENDANGNHAP SET thuHoiLuc = GETDATE(), lyDoThuHoi = @p1
+		WHERE maPhien = @p2 AND maUser = @p3 AND thuHoiLuc IS NULL
+	`, reason, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	s.remember(sessionID, userID, false)
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// RevokeUser ends all sessions of a user except keep, which may be empty.
+func (s *Store) RevokeUser(userID, keep, reason string) (int64, error) {
+	return s.revokeUser(s.db, userID, keep, reason)
+}
+
+// RevokeUserTx is RevokeUser as part of tx, so the sessions end if and
+// only if the change that requires it, such as a new password, commits.
+// This instance treats them as revoked at once; after a rollback they are
+// valid again once their cache entries expire.
+func (s *Store) RevokeUserTx(tx *sql.Tx, userID, keep, reason string) (int64, error) {
+	return s.revokeUser(tx, userID, keep, reason)
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *Store) revokeUser(db execer, userID, keep, reason string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE PHIENDANGNHAP SET thuHoiLuc = GETDATE(), lyDoThuHoi = @p1
+		WHERE maUser = @p2 AND maPhien <> @p3 AND thuHoiLuc IS NULL
+	`, reason, userID, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	for id, entry := range s.cache {
+		if entry.userID == userID && id != keep {
+			entry.valid = false
+			s.cache[id] = entry
+		}
+	}
+	s.mu.Unlock()
+
+	return result.RowsAffected()
+}
+
+func (s *Store) remember(sessionID, userID string, valid bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	// Drop stale entries now and then so the cache stays bounded by the
+	// number of sessions active within one cache period.
+	if len(s.cache) > 10000 {
+		for id, entry := range s.cache {
+			if now.Sub(entry.checkedAt) >= s.cacheTTL {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[sessionID] = cacheEntry{userID: userID, valid: valid, checkedAt: now}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
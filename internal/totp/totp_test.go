@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 secret of the RFC 6238 appendix B test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// RFC 6238 appendix B, SHA-1, truncated to the 6 digits authenticator apps
+// show.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v.code {
+			t.Errorf("Code at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCodeAcceptsUnpaddedLowercaseSecret(t *testing.T) {
+	secret := " " + strings.ToLower(strings.TrimRight(rfcSecret, "=")) + " "
+	got, err := Code(secret, Step(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Errorf("Code = %s, %v; want 287082", got, err)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	// 287082 is the code of step 1, which covers 30s to 59s.
+	step := Step(time.Unix(59, 0))
+	tests := []struct {
+		unix int64
+		ok   bool
+	}{
+		{59, true},
+		{30, true},
+		{0, true},    // one step early
+		{89, true},   // one step late
+		{90, false},  // two steps late
+		{-30, false}, // two steps early
+	}
+	for _, tt := range tests {
+		got, ok := Validate(rfcSecret, "287082", time.Unix(tt.unix, 0))
+		if ok != tt.ok {
+			t.Errorf("Validate at %d = %v, want %v", tt.unix, ok, tt.ok)
+		}
+		if ok && got != step {
+			t.Errorf("Validate at %d matched step %d, want %d", tt.unix, got, step)
+		}
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	if _, ok := Validate(rfcSecret, "287 082", now); !ok {
+		t.Error("Validate rejected a code typed with a space")
+	}
+	for _, code := range []string{"", "28708", "2870820", "94287082", "287083"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Pagination is the metadata of one page of a list response. Page mode
+// (page, page_size) reports the total; cursor mode (cursor, page_size) only
+// reports whether there is more. next_cursor continues after the last item
+// in either mode.
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Total      *int   `json:"total,omitempty"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort"`
+}
+
+// ListParams are the paging and sort parameters of a list request.
+type ListParams struct {
+	Page     int
+	PageSize int
+	Cursor   *Cursor
+	// Sort is the requested sort key, prefixed with "-" for descending.
+	Sort      string
+	SortField string
+	SortDesc  bool
+}
+
+// Cursor marks the last item of a page: its sort value and ID. It is tied
+// to the sort it was issued for.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// ParseListParams reads page, page_size, cursor and sort. sortable lists
+// the accepted sort keys; defaultSort is used when sort is not given.
+func ParseListParams(c *gin.Context, sortable []string, defaultSort string) (ListParams, error) {
+	params := ListParams{Page: 1, PageSize: DefaultPageSize, Sort: defaultSort}
+
+	if value := c.Query("page_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > MaxPageSize {
+			return params, fmt.Errorf("page_size must be between 1 and %d", MaxPageSize)
+		}
+		params.PageSize = size
+	}
+	if value := c.Query("sort"); value != "" {
+		params.Sort = value
+	}
+	params.SortField = strings.TrimPrefix(params.Sort, "-")
+	params.SortDesc = strings.HasPrefix(params.Sort, "-")
+	if !containsField(sortable, params.SortField) {
+		return params, fmt.Errorf("sort must be one of %s, optionally prefixed with -", strings.Join(sortable, ", "))
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		if c.Query("page") != "" {
+			return params, errors.New("use either page or cursor, not both")
+		}
+		cursor, err := decodeCursor(value)
+		if err != nil || cursor.ID == "" {
+			return params, errors.New("invalid cursor")
+		}
+		if cursor.Sort != params.Sort {
+			return params, errors.New("cursor was issued for a different sort")
+		}
+		params.Cursor = &cursor
+		return params, nil
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return params, errors.New("page must be a positive number")
+		}
+		params.Page = page
+	}
+	return params, nil
+}
+
+// Offset is the number of rows skipped in page mode.
+func (p ListParams) Offset() int {
+	if p.Cursor != nil {
+		return 0
+	}
+	return (p.Page - 1) * p.PageSize
+}
+
+// EncodeCursor returns the cursor continuing after an item with the given
+// sort value and ID.
+func EncodeCursor(sort, value, id string) string {
+	data, _ := json.Marshal(Cursor{Sort: sort, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// NewPagination builds the metadata for a page. total is only known, and
+// only reported, in page mode.
+func NewPagination(params ListParams, hasMore bool, nextCursor string, total int) *Pagination {
+	pagination := &Pagination{
+		PageSize:   params.PageSize,
+		HasMore:    hasMore,
+		NextCursor: nextCursor,
+		Sort:       params.Sort,
+	}
+	if params.Cursor == nil {
+		pages := (total + params.PageSize - 1) / params.PageSize
+		pagination.Page = params.Page
+		pagination.Total = &total
+		pagination.TotalPages = &pages
+	}
+	return pagination
+}
+
+// PaginatedResponse writes one page of a list with its pagination metadata.
+func PaginatedResponse(c *gin.Context, message string, data interface{}, pagination *Pagination) {
+	c.JSON(http.StatusOK, APIResponse{
+		Success:    true,
+		Message:    message,
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
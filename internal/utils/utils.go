@@ -1,10 +1,18 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -23,17 +31,63 @@ func CheckPasswordHash(password, hash string) bool {
 
 // ID generation functions following database patterns
 
-// Counter for sequential ID generation (in production, this should be managed by database)
-var idCounters = make(map[string]int)
+// Counter for sequential ID generation, started past the stored IDs by
+// database.InitIDs. Handlers and the schedulers generate IDs concurrently,
+// so the counters are only used under idMu.
+var (
+	idMu       sync.Mutex
+	idCounters = make(map[string]int)
+)
+
+// idSource hands out numbers shared by every server using the database.
+// Without one, the counters above are used.
+var idSource func(prefix string) (int, error)
+
+// SetIDSource makes the generators below take their numbers from next.
+func SetIDSource(next func(prefix string) (int, error)) {
+	idSource = next
+}
+
+// RaiseIDCounter makes the next ID of prefix greater than n.
+func RaiseIDCounter(prefix string, n int) {
+	idMu.Lock()
+	defer idMu.Unlock()
+	if idCounters[prefix] < n {
+		idCounters[prefix] = n
+	}
+}
+
+// IDCounters returns the last number handed out for each prefix.
+func IDCounters() map[string]int {
+	idMu.Lock()
+	defer idMu.Unlock()
+	counters := make(map[string]int, len(idCounters))
+	for prefix, n := range idCounters {
+		counters[prefix] = n
+	}
+	return counters
+}
 
-// Generate sequential ID with prefix and padding
-func generateSequentialID(prefix string, padding int) string {
+// Generate sequential ID with prefix and padding. When the shared source
+// fails its error is returned: a local counter could repeat a number
+// another server has already used.
+func generateSequentialID(prefix string, padding int) (string, error) {
+	if idSource != nil {
+		n, err := idSource(prefix)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate %s ID: %v", prefix, err)
+		}
+		return fmt.Sprintf("%s%0*d", prefix, padding, n), nil
+	}
+	idMu.Lock()
 	idCounters[prefix]++
-	return fmt.Sprintf("%s%0*d", prefix, padding, idCounters[prefix])
+	n := idCounters[prefix]
+	idMu.Unlock()
+	return fmt.Sprintf("%s%0*d", prefix, padding, n), nil
 }
 
 // User ID generators based on roles
-func GenerateUserID(role string) string {
+func GenerateUserID(role string) (string, error) {
 	switch strings.ToUpper(role) {
 	case "CUSTOMER":
 		return generateSequentialID("CUS", 6) // CUS000001
@@ -53,68 +107,104 @@ func GenerateUserID(role string) string {
 }
 
 // Legacy function for backward compatibility
-func GenerateCustomerID() string {
+func GenerateCustomerID() (string, error) {
 	return GenerateUserID("CUSTOMER")
 }
 
 // Clinic and facility ID generators
-func GenerateClinicID() string {
+func GenerateClinicID() (string, error) {
 	return generateSequentialID("PK", 3) // PK001 (PhongKham)
 }
 
-func GenerateWorkScheduleID() string {
+func GenerateRoomID() (string, error) {
+	return generateSequentialID("PH", 5) // PH00001 (Phong)
+}
+
+func GenerateEquipmentID() (string, error) {
+	return generateSequentialID("TB", 6) // TB000001 (ThietBi)
+}
+
+func GenerateWorkScheduleID() (string, error) {
 	return generateSequentialID("LLV", 6) // LLV000001 (LichLamViec)
 }
 
 // Medical record and appointment ID generators
-func GenerateAppointmentID() string {
+func GenerateAppointmentID() (string, error) {
 	return generateSequentialID("LK", 6) // LK000001 (LichKham)
 }
 
-func GenerateMedicalRecordID() string {
+func GenerateMedicalRecordID() (string, error) {
 	return generateSequentialID("HS", 6) // HS000001 (HoSo)
 }
 
-func GeneratePrescriptionID() string {
+func GenerateAddendumID() (string, error) {
+	return generateSequentialID("BS", 6) // BS000001 (BoSung)
+}
+
+func GenerateAllergyID() (string, error) {
+	return generateSequentialID("DU", 6) // DU000001 (DiUng)
+}
+
+func GenerateChronicConditionID() (string, error) {
+	return generateSequentialID("BM", 6) // BM000001 (BenhManTinh)
+}
+
+func GenerateFollowUpID() (string, error) {
+	return generateSequentialID("TK", 6) // TK000001 (TaiKham)
+}
+
+func GeneratePrescriptionID() (string, error) {
 	return generateSequentialID("DT", 6) // DT000001 (DonThuoc)
 }
 
-func GenerateTestResultID() string {
+func GeneratePrescriptionTemplateID() (string, error) {
+	return generateSequentialID("MDT", 6) // MDT000001 (MauDonThuoc)
+}
+
+func GenerateTestResultID() (string, error) {
 	return generateSequentialID("XN", 6) // XN000001 (XetNghiem)
 }
 
-func GenerateLabTestID() string {
+func GenerateLabTestID() (string, error) {
 	return generateSequentialID("XN", 6) // XN000001 (XetNghiem)
 }
 
-func GenerateScheduleID() string {
+func GenerateScheduleID() (string, error) {
 	return generateSequentialID("LLV", 6) // LLV000001 (LichLamViec)
 }
 
-func GenerateMedicalImageID() string {
+func GenerateMedicalImageID() (string, error) {
 	return generateSequentialID("HA", 6) // HA000001 (HinhAnhKham)
 }
 
 // Medicine ID generator
-func GenerateMedicineID() string {
+func GenerateMedicineID() (string, error) {
 	return generateSequentialID("MED", 3) // MED001
 }
 
+func GenerateSupplierID() (string, error) {
+	return generateSequentialID("NCC", 4) // NCC0001 (NhaCungCap)
+}
+
+func GenerateGoodsReceiptID() (string, error) {
+	return generateSequentialID("PN", 6) // PN000001 (PhieuNhap)
+}
+
 // Financial ID generators
-func GeneratePaymentID() string {
+func GeneratePaymentID() (string, error) {
 	return generateSequentialID("TT", 6) // TT000001 (ThanhToan)
 }
 
-func GenerateSalaryID() string {
+func GenerateSalaryID() (string, error) {
 	return generateSequentialID("LG", 6) // LG000001 (Luong)
 }
 
-func GenerateReportID() string {
+func GenerateReportID() (string, error) {
 	return generateSequentialID("BC", 6) // BC000001 (BaoCao)
 }
 
 // Time slot ID generator (for appointment scheduling)
-func GenerateTimeSlotID() string {
+func GenerateTimeSlotID() (string, error) {
 	return generateSequentialID("GK", 3) // GK001 (GioKham)
 }
 
@@ -130,6 +220,68 @@ func ValidatePhoneNumber(phone string) bool {
 	return phoneRegex.MatchString(phone)
 }
 
+// ICD-10 helpers
+var icd10Regex = regexp.MustCompile(`^[A-Z][0-9][0-9A-Z](\.[0-9A-Z]{1,4})?$`)
+
+// NormalizeICD10Code upper-cases a code and inserts the dot after the
+// category, so "j459" and "J45.9" are stored the same way.
+func NormalizeICD10Code(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) > 3 && !strings.Contains(code, ".") {
+		code = code[:3] + "." + code[3:]
+	}
+	return code
+}
+
+func ValidateICD10Format(code string) bool {
+	return icd10Regex.MatchString(code)
+}
+
+var vietnameseReplacer = strings.NewReplacer(
+	"à", "a", "á", "a", "ạ", "a", "ả", "a", "ã", "a",
+	"â", "a", "ầ", "a", "ấ", "a", "ậ", "a", "ẩ", "a", "ẫ", "a",
+	"ă", "a", "ằ", "a", "ắ", "a", "ặ", "a", "ẳ", "a", "ẵ", "a",
+	"è", "e", "é", "e", "ẹ", "e", "ẻ", "e", "ẽ", "e",
+	"ê", "e", "ề", "e", "ế", "e", "ệ", "e", "ể", "e", "ễ", "e",
+	"ì", "i", "í", "i", "ị", "i", "ỉ", "i", "ĩ", "i",
+	"ò", "o", "ó", "o", "ọ", "o", "ỏ", "o", "õ", "o",
+	"ô", "o", "ồ", "o", "ố", "o", "ộ", "o", "ổ", "o", "ỗ", "o",
+	"ơ", "o", "ờ", "o", "ớ", "o", "ợ", "o", "ở", "o", "ỡ", "o",
+	"ù", "u", "ú", "u", "ụ", "u", "ủ", "u", "ũ", "u",
+	"ư", "u", "ừ", "u", "ứ", "u", "ự", "u", "ử", "u", "ữ", "u",
+	"ỳ", "y", "ý", "y", "ỵ", "y", "ỷ", "y", "ỹ", "y",
+	"đ", "d",
+)
+
+// NormalizeSearchText lower-cases text and strips Vietnamese diacritics so
+// searches match with or without accents.
+func NormalizeSearchText(text string) string {
+	return strings.Join(strings.Fields(vietnameseReplacer.Replace(strings.ToLower(text))), " ")
+}
+
+// EditDistance returns the Levenshtein distance between a and b: the number
+// of characters to insert, delete or replace to turn one into the other.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
 func ValidateUserRole(role string) bool {
 	validRoles := []string{"CUSTOMER", "DOCTOR", "RECEPTIONIST", "ACCOUNTANT", "CLINIC_MANAGER", "OPERATION_MANAGER"}
 	roleUpper := strings.ToUpper(role)
@@ -152,7 +304,7 @@ func ValidateGender(gender string) bool {
 }
 
 func ValidateAppointmentStatus(status string) bool {
-	validStatuses := []string{"SCHEDULED", "COMPLETED", "CANCELLED", "NO_SHOW"}
+	validStatuses := []string{"PENDING", "SCHEDULED", "CONFIRMED", "COMPLETED", "CANCELLED", "NO_SHOW"}
 	statusUpper := strings.ToUpper(status)
 	for _, validStatus := range validStatuses {
 		if statusUpper == validStatus {
@@ -241,10 +393,178 @@ func GenerateResetCode() string {
 	return fmt.Sprintf("%06d", code%1000000)
 }
 
-func GeneratePasswordResetID() string {
+// prescriptionCodeAlphabet is Crockford base32: no I, L, O or U, so codes
+// read back from paper are not confused.
+const prescriptionCodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// GeneratePrescriptionCode returns a random e-prescription code such as
+// "7KQ2-M9XD-4HT8", whose last character is a Luhn mod 32 check digit.
+func GeneratePrescriptionCode() string {
+	randomBytes := make([]byte, 11)
+	rand.Read(randomBytes)
+
+	code := make([]byte, 0, 12)
+	for _, b := range randomBytes {
+		code = append(code, prescriptionCodeAlphabet[int(b)%32])
+	}
+	code = append(code, prescriptionCheckChar(string(code)))
+
+	return string(code[0:4]) + "-" + string(code[4:8]) + "-" + string(code[8:12])
+}
+
+// NormalizePrescriptionCode upper-cases a code, drops separators and maps
+// look-alike letters onto their digits. It returns "" when the code is
+// malformed or its check digit does not match.
+func NormalizePrescriptionCode(code string) string {
+	code = strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1").Replace(strings.ToUpper(code))
+	if len(code) != 12 {
+		return ""
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(prescriptionCodeAlphabet, r) {
+			return ""
+		}
+	}
+	if prescriptionCheckChar(code[:11]) != code[11] {
+		return ""
+	}
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12]
+}
+
+func prescriptionCheckChar(payload string) byte {
+	const n = len(prescriptionCodeAlphabet)
+	factor, sum := 2, 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(prescriptionCodeAlphabet, payload[i])
+		addend = addend/n + addend%n
+		sum += addend
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return prescriptionCodeAlphabet[(n-sum%n)%n]
+}
+
+func GeneratePasswordResetID() (string, error) {
 	return generateSequentialID("PWR", 6) // PWR000001
 }
 
+// Signed tokens carry a payload in links sent to users, e.g. the confirm
+// and cancel links of appointment reminders. The payload is readable, not
+// encrypted, so it must not hold secrets.
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// SignToken returns a URL-safe token for payload that VerifyToken accepts
+// until expiresAt.
+func SignToken(secret, payload string, expiresAt time.Time) string {
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload + "|" + strconv.FormatInt(expiresAt.Unix(), 10)))
+	return body + "." + tokenSignature(secret, body)
+}
+
+// VerifyToken checks the signature and expiry of a token made by SignToken
+// and returns its payload.
+func VerifyToken(secret, token string) (string, error) {
+	dot := strings.LastIndexByte(token, '.')
+	if dot < 0 {
+		return "", ErrInvalidToken
+	}
+	body, sig := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(sig), []byte(tokenSignature(secret, body))) {
+		return "", ErrInvalidToken
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	sep := strings.LastIndexByte(string(decoded), '|')
+	if sep < 0 {
+		return "", ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(string(decoded[sep+1:]), 10, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if time.Now().Unix() > expires {
+		return "", ErrTokenExpired
+	}
+	return string(decoded[:sep]), nil
+}
+
+func tokenSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptSecret seals a value stored at rest, such as a TOTP secret, with
+// AES-256-GCM under a key derived from key.
+func EncryptSecret(key, plaintext string) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret opens a value made by EncryptSecret.
+func DecryptSecret(key, ciphertext string) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("invalid encrypted value")
+	}
+	return string(plaintext), nil
+}
+
+func secretCipher(key string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateRecoveryCode returns a one-time 2FA recovery code such as
+// "7KQ2M-9XD4H", using the same unambiguous alphabet as prescription codes.
+func GenerateRecoveryCode() string {
+	randomBytes := make([]byte, 10)
+	rand.Read(randomBytes)
+
+	code := make([]byte, 0, 11)
+	for i, b := range randomBytes {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, prescriptionCodeAlphabet[int(b)%32])
+	}
+	return string(code)
+}
+
+// NormalizeRecoveryCode upper-cases a recovery code and drops separators
+// so it can be compared however the user typed it.
+func NormalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1").Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
 func FormatCurrency(amount float64) string {
 	return fmt.Sprintf("%.0f VND", amount)
 }
@@ -262,10 +582,12 @@ func GetYearRange(year int) (time.Time, time.Time) {
 	return start, end
 }
 
-// Initialize counters (should be called once at application startup)
+// Initialize counters (should be called once at application startup).
+// These are the lowest starting points; database.InitIDs raises them past
+// the IDs already stored.
 func InitializeCounters() {
-	// In production, these values should be loaded from database
-	// This is just for demonstration
+	idMu.Lock()
+	defer idMu.Unlock()
 	idCounters["CUS"] = 50000
 	idCounters["DOC"] = 50
 	idCounters["REC"] = 20
@@ -273,12 +595,21 @@ func InitializeCounters() {
 	idCounters["CLM"] = 0
 	idCounters["OPM"] = 0
 	idCounters["PK"] = 10
+	idCounters["PH"] = 0
+	idCounters["TB"] = 0
 	idCounters["LK"] = 40000 // Set to higher than existing data
 	idCounters["HS"] = 20000 // Set to higher than existing data
 	idCounters["DT"] = 20000 // Set to higher than existing data
-	idCounters["XN"] = 4000  // Set to higher than existing data
-	idCounters["HA"] = 2000  // Set to higher than existing data
+	idCounters["MDT"] = 0
+	idCounters["BS"] = 0
+	idCounters["TK"] = 0
+	idCounters["DU"] = 0
+	idCounters["BM"] = 0
+	idCounters["XN"] = 4000 // Set to higher than existing data
+	idCounters["HA"] = 2000 // Set to higher than existing data
 	idCounters["MED"] = 100
+	idCounters["NCC"] = 0
+	idCounters["PN"] = 0
 	idCounters["TT"] = 25000 // Set to higher than existing data
 	idCounters["LG"] = 5000  // Set to higher than existing data
 	idCounters["BC"] = 100   // Set to higher than existing data
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The schedulers and the handlers generate IDs at the same time.
+func TestGenerateIDsConcurrently(t *testing.T) {
+	InitializeCounters()
+
+	const workers, each = 8, 200
+	ids := make(chan string, workers*each)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				id, err := GenerateFollowUpID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("%s generated twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != workers*each {
+		t.Errorf("generated %d IDs, want %d", len(seen), workers*each)
+	}
+}
+
+// A failing shared source must not fall back to the local counter, which
+// another server may already have used.
+func TestGenerateIDSourceFailure(t *testing.T) {
+	InitializeCounters()
+	SetIDSource(func(prefix string) (int, error) {
+		return 0, errors.New("sequence unavailable")
+	})
+	defer SetIDSource(nil)
+
+	if id, err := GenerateAppointmentID(); err == nil {
+		t.Fatalf("generated %s without the shared source", id)
+	}
+}
+
+func TestPrescriptionCodeRoundTrip(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		code := GeneratePrescriptionCode()
+		if len(code) != 14 || code[4] != '-' || code[9] != '-' {
+			t.Fatalf("code %q is not formatted XXXX-XXXX-XXXX", code)
+		}
+		for _, input := range []string{code, strings.ToLower(code), strings.ReplaceAll(code, "-", ""), " " + strings.ReplaceAll(code, "-", " ")} {
+			if got := NormalizePrescriptionCode(input); got != code {
+				t.Fatalf("NormalizePrescriptionCode(%q) = %q, want %q", input, got, code)
+			}
+		}
+	}
+}
+
+func TestNormalizePrescriptionCodeLookAlikes(t *testing.T) {
+	payload := "10A0B1CD0EF"
+	code := payload[0:4] + "-" + payload[4:8] + "-" + payload[8:11] + string(prescriptionCheckChar(payload))
+
+	tests := []string{
+		"IOA0-B1CD-0EF" + code[13:],
+		"lOAo-BICD-OEF" + code[13:],
+		"10AO-BLCD-0EF" + code[13:],
+	}
+	for _, input := range tests {
+		if got := NormalizePrescriptionCode(input); got != code {
+			t.Errorf("NormalizePrescriptionCode(%q) = %q, want %q", input, got, code)
+		}
+	}
+}
+
+// A Luhn mod 32 check digit catches every single mistyped character.
+func TestNormalizePrescriptionCodeRejectsChangedCharacter(t *testing.T) {
+	code := strings.ReplaceAll(GeneratePrescriptionCode(), "-", "")
+	for i := 0; i < len(code); i++ {
+		for _, r := range prescriptionCodeAlphabet {
+			if byte(r) == code[i] {
+				continue
+			}
+			changed := code[:i] + string(r) + code[i+1:]
+			if got := NormalizePrescriptionCode(changed); got != "" {
+				t.Fatalf("NormalizePrescriptionCode(%q) = %q, want rejected (original %s)", changed, got, code)
+			}
+		}
+	}
+}
+
+func TestNormalizePrescriptionCodeRejectsMalformed(t *testing.T) {
+	code := GeneratePrescriptionCode()
+	tests := []string{
+		"",
+		code[:13],
+		code + "0",
+		"U" + code[1:],
+		"*" + code[1:],
+	}
+	for _, input := range tests {
+		if got := NormalizePrescriptionCode(input); got != "" {
+			t.Errorf("NormalizePrescriptionCode(%q) = %q, want rejected", input, got)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "duong", 5},
+		{"duong", "duong", 0},
+		{"duog", "duong", 1},
+		{"duongg", "duong", 1},
+		{"dupng", "duong", 1},
+		{"hypertensoin", "hypertension", 2},
+		{"kitten", "sitting", 3},
+		{"đường", "đuong", 2},
+	}
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := EditDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"clinic-management/internal/config"
 	"clinic-management/internal/database"
+	"clinic-management/internal/keys"
+	"clinic-management/internal/middleware"
+	"clinic-management/internal/notification"
 	"clinic-management/internal/routes"
+	"clinic-management/internal/scheduler"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
 
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -19,11 +31,165 @@ func main() {
 	}
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if cfg.AutoMigrate {
+		applied, err := database.MigrateUp(db)
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		for _, m := range applied {
+			log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+		}
+	}
+	if err := database.VerifySchema(db); err != nil {
+		log.Fatal("Database schema check failed: ", err)
+	}
+	if err := database.InitIDs(db); err != nil {
+		log.Fatal("Failed to initialize IDs: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A retired signing key is kept as long as the longest lived token it
+	// may have signed.
+	verifyFor := cfg.SessionLifetime
+	if verifyFor < middleware.AccessTokenTTL {
+		verifyFor = middleware.AccessTokenTTL
+	}
+	signingKeys, err := keys.NewManager(db, cfg.JWTAlgorithm, cfg.JWTKeyRotation, verifyFor, cfg.JWTKeyEncryptionKey)
+	if err != nil {
+		log.Fatal("Failed to set up signing keys:", err)
+	}
+	if err := signingKeys.Refresh(); err != nil {
+		log.Fatal("Failed to load signing keys:", err)
+	}
+	go signingKeys.Run(ctx, cfg.JWTKeyRefreshInterval)
+
+	notifier, err := newNotificationService(cfg, db)
+	if err != nil {
+		log.Fatal("Failed to set up notifications:", err)
+	}
+	if cfg.NotificationInterval > 0 {
+		go notifier.Run(ctx, cfg.NotificationInterval)
+	}
+
+	if cfg.FollowUpInterval > 0 {
+		followUps := scheduler.NewFollowUpScheduler(db, notifier, cfg.FollowUpLookaheadDays)
+		go followUps.Run(ctx, cfg.FollowUpInterval)
+	}
+
+	if cfg.ReminderInterval > 0 {
+		reminders := scheduler.NewReminderScheduler(db, notifier, cfg.ReminderOffsets, cfg.PublicBaseURL, cfg.LinkSigningSecret)
+		go reminders.Run(ctx, cfg.ReminderInterval)
+	}
+
 	router := gin.Default()
-	routes.SetupRoutes(router, db, cfg.JWTSecret)
+	if err := routes.SetupRoutes(router, db, cfg, signingKeys, notifier); err != nil {
+		log.Fatal("Failed to set up routes:", err)
+	}
 
 	log.Printf("Server starting on port %s", cfg.Port)
 	if err := router.Run(":" + cfg.Port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// runMigrate runs "migrate up", "migrate down [steps]" (one step by
+// default) or "migrate status".
+func runMigrate(db *sql.DB, args []string) error {
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate down: steps must be a positive number, got %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := database.MigrateDown(db, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (file changed since)"
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("usage: %s migrate up|down [steps]|status", os.Args[0])
+}
+
+// newNotificationService uses the SMTP server and SMS gateway when they are
+// configured and falls back to writing messages to the log file or stdout.
+func newNotificationService(cfg *config.Config, db *sql.DB) (*notification.Service, error) {
+	// Validate requires both providers outside development; the console
+	// and log file would expose reset codes and signed links.
+	fallback := func(channel string) (notification.Provider, error) {
+		if !cfg.IsDevelopment() {
+			return nil, fmt.Errorf("no %s provider configured", channel)
+		}
+		if cfg.NotificationLogFile != "" {
+			return notification.NewFileProvider(channel, cfg.NotificationLogFile)
+		}
+		return notification.NewConsoleProvider(channel), nil
+	}
+
+	var email, sms notification.Provider
+	var err error
+	if cfg.SMTPHost != "" {
+		email = &notification.SMTPProvider{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}
+	} else if email, err = fallback(notification.ChannelEmail); err != nil {
+		return nil, err
+	}
+	if cfg.SMSGatewayURL != "" {
+		sms = &notification.SMSGatewayProvider{
+			URL:    cfg.SMSGatewayURL,
+			APIKey: cfg.SMSGatewayAPIKey,
+			Sender: cfg.SMSSender,
+		}
+	} else if sms, err = fallback(notification.ChannelSMS); err != nil {
+		return nil, err
+	}
+
+	return notification.NewService(db, cfg.NotificationMaxAttempts, email, sms), nil
+}